@@ -0,0 +1,184 @@
+// Package anomaly flags services whose response times depart from what is usual for them at that
+// hour of the day, even while their checks pass
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"slices"
+	"time"
+)
+
+// Fingerprint identifies the latency anomalies among the external alerts of services
+const Fingerprint = "latency-anomaly"
+
+const (
+	historyDays     = 28   // seasonal history a baseline is learnt from
+	minHistory      = 7    // days of history at the hour before a baseline is trusted
+	recentMinutes   = 15   // window of the response times compared with the baseline
+	minRecentChecks = 5    // passing checks in the window before it is compared
+	alpha           = 0.3  // weight of the most recent day in the baseline
+	minRatio        = 1.5  // anomalies are at least this many times the baseline
+	minDeviation    = 50.0 // and this many milliseconds above it
+)
+
+// Baseline is the usual response time of a service at an hour of the day: an exponentially
+// weighted mean and standard deviation of the hour's average over the past weeks, recent days
+// weighing most
+type Baseline struct {
+	Mean    float64
+	StdDev  float64
+	Samples int
+}
+
+// Learn computes the baseline of a service from its seasonal history, oldest first. Hours the
+// service was mostly down are left out, as failed checks distort their average.
+func Learn(history []models.HealthcheckRollup) Baseline {
+	var b Baseline
+	var variance float64
+	for _, h := range history {
+		if h.TotalChecks == 0 || h.UpChecks*2 < h.TotalChecks {
+			continue
+		}
+		if b.Samples == 0 {
+			b.Mean = h.AvgResponseTime
+		} else {
+			diff := h.AvgResponseTime - b.Mean
+			b.Mean += alpha * diff
+			variance = (1 - alpha) * (variance + alpha*diff*diff)
+		}
+		b.Samples++
+	}
+	b.StdDev = math.Sqrt(variance)
+	return b
+}
+
+// Anomalous reports whether a recent average response time departs from the baseline by more
+// than threshold standard deviations. The deviation is at least a tenth of the mean, so that very
+// steady services do not flag every blip, and must be large both relatively and absolutely.
+func (b Baseline) Anomalous(recent, threshold float64) bool {
+	if b.Samples < minHistory {
+		return false
+	}
+	deviation := math.Max(b.StdDev, b.Mean/10)
+	return recent > b.Mean+threshold*deviation && recent >= b.Mean*minRatio && recent-b.Mean >= minDeviation
+}
+
+// Detector periodically compares the recent response times of services with their baselines and
+// marks the anomalous ones degraded, through the same external alerts as Alertmanager's: the
+// scheduler applies them with the next checks, and the status changes alert as usual
+type Detector struct {
+	repo      *repository.Repository
+	interval  time.Duration
+	threshold float64
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func NewDetector(repo *repository.Repository, interval time.Duration, threshold float64) *Detector {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Detector{
+		repo:      repo,
+		interval:  interval,
+		threshold: threshold,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+func (d *Detector) Start() {
+	go d.run()
+}
+
+func (d *Detector) Stop() {
+	d.cancel()
+}
+
+// Detect returns a latency anomaly for every service whose recent response times depart from
+// its baseline
+func (d *Detector) Detect(now time.Time) ([]models.ExternalAlert, error) {
+	recent, err := d.repo.GetRecentResponseTimes(d.ctx, recentMinutes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent response times: %w", err)
+	}
+	history, err := d.repo.GetSeasonalRollups(d.ctx, historyDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rollups: %w", err)
+	}
+
+	anomalies := []models.ExternalAlert{}
+	for id, bucket := range recent {
+		if bucket.Count < minRecentChecks {
+			continue
+		}
+		baseline := Learn(history[id])
+		if !baseline.Anomalous(bucket.AvgResponseTime, d.threshold) {
+			continue
+		}
+		startsAt := now
+		anomalies = append(anomalies, models.ExternalAlert{
+			Fingerprint: Fingerprint,
+			ServiceID:   id,
+			Name:        "Latency anomaly",
+			Severity:    "warning",
+			Status:      models.StatusDegraded,
+			Summary: fmt.Sprintf("response time of %.0f ms over the last %d minutes, %.1fx the usual %.0f ms at this hour",
+				bucket.AvgResponseTime, recentMinutes, bucket.AvgResponseTime/baseline.Mean, baseline.Mean),
+			StartsAt: &startsAt,
+		})
+	}
+	slices.SortFunc(anomalies, func(a, b models.ExternalAlert) int { return a.ServiceID - b.ServiceID })
+	return anomalies, nil
+}
+
+// Run detects anomalies and stores them when the services they concern changed. Anomalies that
+// last keep their start time.
+func (d *Detector) Run(now time.Time) error {
+	anomalies, err := d.Detect(now)
+	if err != nil {
+		return err
+	}
+	alerts, err := d.repo.GetExternalAlerts(d.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get external alerts: %w", err)
+	}
+	current := make(map[int]models.ExternalAlert)
+	for _, alert := range alerts {
+		if alert.Fingerprint == Fingerprint {
+			current[alert.ServiceID] = alert
+		}
+	}
+	changed := len(current) != len(anomalies)
+	for i, anomaly := range anomalies {
+		if previous, ok := current[anomaly.ServiceID]; ok {
+			anomalies[i].StartsAt = previous.StartsAt
+			continue
+		}
+		changed = true
+		log.Printf("Latency anomaly on service %d: %s", anomaly.ServiceID, anomaly.Summary)
+	}
+	if !changed {
+		return nil
+	}
+	return d.repo.ApplyExternalAlerts(d.ctx, []string{Fingerprint}, anomalies)
+}
+
+func (d *Detector) run() {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := d.Run(time.Now()); err != nil {
+				log.Printf("Error detecting latency anomalies: %v", err)
+			}
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/models"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AlertmanagerLabels maps the labels of Alertmanager alerts to the fields of the services they
+// concern; an alert applies to every service matching any of them
+var AlertmanagerLabels = []models.AlertLabelMapping{
+	{Label: "sw_service_id", Field: "id"},
+	{Label: "service", Field: "name"},
+}
+
+// alertLabelFields are the service fields alert labels can be matched against
+var alertLabelFields = map[string]bool{"id": true, "name": true, "host": true, "tag": true}
+
+// ParseAlertLabelMapping parses the ALERTMANAGER_LABELS format ("label=field,label=field"), where
+// field is id, name, host or tag
+func ParseAlertLabelMapping(value string) ([]models.AlertLabelMapping, error) {
+	var mapping []models.AlertLabelMapping
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		label, field, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(label) == "" {
+			return nil, fmt.Errorf("invalid label mapping %q", pair)
+		}
+		field = strings.TrimSpace(field)
+		if !alertLabelFields[field] {
+			return nil, fmt.Errorf("unknown service field %q in label mapping, expected id, name, host or tag", field)
+		}
+		mapping = append(mapping, models.AlertLabelMapping{Label: strings.TrimSpace(label), Field: field})
+	}
+	return mapping, nil
+}
+
+// alertStatus is the status a firing alert gives its services: dead for critical alerts,
+// degraded for the others
+func alertStatus(labels map[string]string) models.ServiceStatus {
+	if strings.EqualFold(labels["severity"], "critical") {
+		return models.StatusDead
+	}
+	return models.StatusDegraded
+}
+
+// alertFingerprint identifies an alert by its labels when Alertmanager did not send a fingerprint
+func alertFingerprint(alert models.AlertmanagerAlert) string {
+	if alert.Fingerprint != "" {
+		return alert.Fingerprint
+	}
+	names := make([]string, 0, len(alert.Labels))
+	for name := range alert.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(hash, "%s=%s\x00", name, alert.Labels[name])
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// ReceiveAlertmanager is a Prometheus Alertmanager webhook receiver. Firing alerts mark the
+// services their labels match, per AlertmanagerLabels, as degraded, or dead for critical alerts,
+// until Alertmanager reports them resolved; the services' own healthchecks still apply when they
+// find worse. Statuses change with the next checks, which run right away.
+func (h *Handlers) ReceiveAlertmanager(c *gin.Context) {
+	var payload models.AlertmanagerWebhook
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := models.AlertmanagerResult{Unmatched: []string{}}
+	fingerprints := make([]string, 0, len(payload.Alerts))
+	var firing []models.ExternalAlert
+	for _, alert := range payload.Alerts {
+		fingerprint := alertFingerprint(alert)
+		fingerprints = append(fingerprints, fingerprint)
+		if alert.Status == "resolved" {
+			result.Resolved++
+			continue
+		}
+
+		ids, err := h.repo.MatchAlertServices(c.Request.Context(), alert.Labels, AlertmanagerLabels)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(ids) == 0 {
+			result.Unmatched = append(result.Unmatched, alert.Labels["alertname"])
+			continue
+		}
+		result.Firing++
+
+		summary := alert.Annotations["summary"]
+		if summary == "" {
+			summary = alert.Annotations["description"]
+		}
+		for _, id := range ids {
+			external := models.ExternalAlert{
+				Fingerprint: fingerprint,
+				ServiceID:   id,
+				Name:        alert.Labels["alertname"],
+				Severity:    alert.Labels["severity"],
+				Status:      alertStatus(alert.Labels),
+				Summary:     summary,
+			}
+			if !alert.StartsAt.IsZero() {
+				startsAt := alert.StartsAt
+				external.StartsAt = &startsAt
+			}
+			firing = append(firing, external)
+		}
+	}
+
+	if err := h.repo.ApplyExternalAlerts(c.Request.Context(), fingerprints, firing); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLog returns audit log entries, newest first (admin only).
+// Filters: ?user_id=, ?entity_type=, ?entity_id=, ?action=create|update|delete, ?from=, ?to=,
+// plus ?limit=&offset= pagination.
+func (h *Handlers) GetAuditLog(c *gin.Context) {
+	var filter repository.AuditFilter
+	var err error
+	filter.Limit, filter.Offset, err = parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if value := c.Query("user_id"); value != "" {
+		if filter.UserID, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+	}
+	if value := c.Query("entity_id"); value != "" {
+		if filter.EntityID, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entity ID"})
+			return
+		}
+	}
+	filter.EntityType = c.Query("entity_type")
+
+	switch action := models.AuditAction(c.Query("action")); action {
+	case "", models.AuditCreate, models.AuditUpdate, models.AuditDelete:
+		filter.Action = string(action)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be create, update or delete"})
+		return
+	}
+
+	if value := c.Query("from"); value != "" {
+		if filter.From, err = parseTime(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if filter.To, err = parseTime(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	entries, total, err := h.repo.GetAuditEntries(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"total":   total,
+		"limit":   filter.Limit,
+		"offset":  filter.Offset,
+	})
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"service-weaver/internal/repository"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetBackup streams a full logical backup of the database as a gzipped tar archive (admin only).
+// Secrets are left out unless include_secrets=true.
+func (h *Handlers) GetBackup(c *gin.Context) {
+	includeSecrets := c.Query("include_secrets") == "true"
+	filename := fmt.Sprintf("service-weaver-backup-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+	if _, err := h.repo.WriteBackup(c.Request.Context(), c.Writer, includeSecrets); err != nil {
+		// the archive is already streaming, so the client sees a truncated download
+		log.Printf("Backup failed: %v", err)
+		c.Abort()
+	}
+}
+
+// RestoreBackup replaces all data with an uploaded backup archive (admin only). The archive is
+// either the request body or the "file" field of a multipart form. Restoring over an instance
+// that already has diagrams requires force=true. Every session ends, including the caller's.
+func (h *Handlers) RestoreBackup(c *gin.Context) {
+	var src io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A backup archive is required in the file field"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+		src = file
+	}
+
+	manifest, err := h.repo.RestoreBackup(c.Request.Context(), src, c.Query("force") == "true")
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrBackupInvalid), errors.Is(err, repository.ErrBackupSchemaMismatch):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrInstanceNotEmpty):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Backup restored; sign in again to continue",
+		"manifest": manifest,
+	})
+}
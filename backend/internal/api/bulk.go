@@ -0,0 +1,311 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"service-weaver/internal/assertion"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxBulkItems = 500
+
+// bulkItemError reports why one entry of a bulk request was rejected
+type bulkItemError struct {
+	List   string              `json:"list,omitempty"` // services or connections, for requests with both
+	Index  int                 `json:"index"`
+	Error  string              `json:"error"`
+	Fields []models.FieldError `json:"fields,omitempty"` // The fields at fault in an invalid healthcheck configuration
+}
+
+// itemError describes why the item at index of a bulk request failed validation
+func itemError(list string, index int, err error) bulkItemError {
+	item := bulkItemError{List: list, Index: index, Error: err.Error()}
+	var problems healthcheckError
+	if errors.As(err, &problems) {
+		item.Fields = problems
+	}
+	return item
+}
+
+// CreateServicesBulk creates every service in the request body (a JSON array) in one transaction.
+// Either all services are created or none are; rejected entries are reported by index.
+func (h *Handlers) CreateServicesBulk(c *gin.Context) {
+	h.applyServicesBulk(c, false)
+}
+
+// UpdateServicesBulk updates every service in the request body (a JSON array, each with an id) in one transaction
+func (h *Handlers) UpdateServicesBulk(c *gin.Context) {
+	h.applyServicesBulk(c, true)
+}
+
+func (h *Handlers) applyServicesBulk(c *gin.Context, update bool) {
+	var services []models.Service
+	if err := c.ShouldBindJSON(&services); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(services) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one service is required"})
+		return
+	}
+	if len(services) > maxBulkItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d services can be submitted at once", maxBulkItems)})
+		return
+	}
+
+	// Validate everything up front so callers see all problems at once
+	var itemErrors []bulkItemError
+	for i := range services {
+		if err := validateBulkService(&services[i], update); err != nil {
+			itemErrors = append(itemErrors, itemError("", i, err))
+		} else if update && !h.inOrganization(c, "services", services[i].ID) {
+			itemErrors = append(itemErrors, bulkItemError{Index: i, Error: "Service not found"})
+		} else if !update && !h.inOrganization(c, "diagrams", services[i].DiagramID) {
+			itemErrors = append(itemErrors, bulkItemError{Index: i, Error: "Diagram not found"})
+		} else if !h.ownerInOrganization(c, services[i].OwnerUserID) {
+			itemErrors = append(itemErrors, bulkItemError{Index: i, Error: "Owner is not a member of the organization"})
+		} else if !h.environmentExists(c, services[i].Environment) {
+			itemErrors = append(itemErrors, bulkItemError{Index: i, Error: "Unknown environment"})
+		}
+	}
+	if len(itemErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "errors": itemErrors})
+		return
+	}
+
+	var err error
+	if update {
+		err = h.repo.UpdateServices(c.Request.Context(), services)
+	} else {
+		err = h.repo.CreateServices(c.Request.Context(), services)
+	}
+
+	var itemErr *repository.BulkItemError
+	if errors.As(err, &itemErr) {
+		message := itemErr.Err.Error()
+		if errors.Is(itemErr.Err, sql.ErrNoRows) {
+			message = "Service not found"
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "No services were saved",
+			"errors": []bulkItemError{{Index: itemErr.Index, Error: message}},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := make([]int, len(services))
+	for i, service := range services {
+		ids[i] = service.ID
+	}
+	diagramIDs, err := h.repo.GetServiceDiagramIDs(c.Request.Context(), ids)
+	if err != nil {
+		log.Printf("Error resolving diagrams of bulk services: %v", err)
+	}
+	for _, diagramID := range diagramIDs {
+		if update {
+			h.recordVersion(c, diagramID, "Bulk edited services")
+		} else {
+			h.recordVersion(c, diagramID, "Bulk added services")
+		}
+	}
+
+	status := http.StatusCreated
+	if update {
+		status = http.StatusOK
+	}
+	c.JSON(status, services)
+}
+
+// DeleteServicesBulk moves every service in service_ids to the trash in one transaction
+func (h *Handlers) DeleteServicesBulk(c *gin.Context) {
+	services, ok := h.applyServiceIDsBulk(c, h.repo.DeleteServices)
+	if !ok {
+		return
+	}
+
+	removed := make(map[int]int)
+	for _, service := range services {
+		removed[service.DiagramID]++
+		h.broadcastEvent(models.MessageServiceDeleted, service.DiagramID, models.DeletedEntity{ID: service.ID})
+	}
+	for diagramID, n := range removed {
+		h.recordVersion(c, diagramID, fmt.Sprintf("Bulk removed %d services", n))
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Services moved to trash", "deleted": len(services)})
+}
+
+// PauseServicesBulk stops checking every service in service_ids, in one transaction
+func (h *Handlers) PauseServicesBulk(c *gin.Context) {
+	h.setPausedBulk(c, true)
+}
+
+// ResumeServicesBulk resumes checking every service in service_ids, in one transaction
+func (h *Handlers) ResumeServicesBulk(c *gin.Context) {
+	h.setPausedBulk(c, false)
+}
+
+func (h *Handlers) setPausedBulk(c *gin.Context, paused bool) {
+	services, ok := h.applyServiceIDsBulk(c, func(ctx context.Context, ids []int) ([]models.Service, error) {
+		return h.repo.SetServicesPaused(ctx, ids, paused)
+	})
+	if !ok {
+		return
+	}
+
+	for _, service := range services {
+		h.broadcastEvent(models.MessageServiceUpdated, service.DiagramID, service)
+	}
+	c.JSON(http.StatusOK, services)
+}
+
+// applyServiceIDsBulk binds a models.ServiceIDsRequest and applies apply to its services once they
+// are all found in the organization, reporting failures by index. It returns false once it has
+// written an error response.
+func (h *Handlers) applyServiceIDsBulk(c *gin.Context, apply func(ctx context.Context, ids []int) ([]models.Service, error)) ([]models.Service, bool) {
+	var req models.ServiceIDsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err := validateServiceIDs(req.ServiceIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	var itemErrors []bulkItemError
+	for i, id := range req.ServiceIDs {
+		if !h.inOrganization(c, "services", id) {
+			itemErrors = append(itemErrors, bulkItemError{Index: i, Error: "Service not found"})
+		}
+	}
+	if len(itemErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "errors": itemErrors})
+		return nil, false
+	}
+
+	services, err := apply(c.Request.Context(), req.ServiceIDs)
+	var itemErr *repository.BulkItemError
+	if errors.As(err, &itemErr) {
+		message := itemErr.Err.Error()
+		if errors.Is(itemErr.Err, sql.ErrNoRows) {
+			message = "Service not found"
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "No services were changed",
+			"errors": []bulkItemError{{Index: itemErr.Index, Error: message}},
+		})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return services, true
+}
+
+// validateServiceIDs checks the service IDs of a bulk request: at least one, at most maxBulkItems,
+// none listed twice
+func validateServiceIDs(ids []int) error {
+	if len(ids) == 0 {
+		return errors.New("At least one service ID is required")
+	}
+	if len(ids) > maxBulkItems {
+		return fmt.Errorf("At most %d services can be submitted at once", maxBulkItems)
+	}
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("Service %d is listed more than once", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// validateBulkService checks the fields the database cannot and applies the same defaults as single-service requests
+func validateBulkService(service *models.Service, update bool) error {
+	if update && service.ID <= 0 {
+		return errors.New("id is required")
+	}
+	if !update && service.DiagramID <= 0 {
+		return errors.New("diagram_id is required")
+	}
+	return validateServiceFields(service)
+}
+
+// validateServiceFields checks a service's own fields and applies the defaults of single-service requests
+func validateServiceFields(service *models.Service) error {
+	if service.Name == "" {
+		return errors.New("name is required")
+	}
+	if service.ServiceType == "" {
+		return errors.New("service_type is required")
+	}
+	if service.SLOTarget > 100 {
+		return errors.New("slo_target must not exceed 100")
+	}
+	if err := validateOverrides(service.Overrides); err != nil {
+		return err
+	}
+	if err := validateRunbook(service); err != nil {
+		return err
+	}
+	if err := validateAssertion(service); err != nil {
+		return err
+	}
+	if err := validateHealthcheck(service); err != nil {
+		return err
+	}
+
+	if service.SLOTarget <= 0 {
+		service.SLOTarget = models.DefaultSLOTarget
+	}
+	return nil
+}
+
+// maxNotesLength caps the notes of a service, which every alert about it carries
+const maxNotesLength = 16 << 10
+
+// validateRunbook checks the remediation context of a service: an http(s) runbook URL and notes
+// of reasonable length
+func validateRunbook(service *models.Service) error {
+	if service.RunbookURL != "" {
+		u, err := url.Parse(service.RunbookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("runbook_url must be an http or https URL")
+		}
+	}
+	if len(service.Notes) > maxNotesLength {
+		return fmt.Errorf("notes must not exceed %d bytes", maxNotesLength)
+	}
+	return nil
+}
+
+// validateAssertion checks that the assertion of a service compiles and that its check has a
+// response to assert against
+func validateAssertion(service *models.Service) error {
+	if strings.TrimSpace(service.Assertion) == "" {
+		service.Assertion = ""
+		return nil
+	}
+	if service.HealthcheckMethod != "HTTP" && service.HealthcheckMethod != "HTTPS" {
+		return errors.New("assertion only applies to the HTTP and HTTPS healthcheck methods")
+	}
+	if _, err := assertion.Compile(service.Assertion); err != nil {
+		return fmt.Errorf("invalid assertion: %v", err)
+	}
+	return nil
+}
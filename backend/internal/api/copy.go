@@ -0,0 +1,222 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CopyService duplicates a service into the diagram given in the request body
+func (h *Handlers) CopyService(c *gin.Context) {
+	h.transferService(c, false)
+}
+
+// MoveService moves a service into the diagram given in the request body
+func (h *Handlers) MoveService(c *gin.Context) {
+	h.transferService(c, true)
+}
+
+// CopyServicesBulk duplicates every service in service_ids in one transaction
+func (h *Handlers) CopyServicesBulk(c *gin.Context) {
+	h.transferServicesBulk(c, false)
+}
+
+// MoveServicesBulk moves every service in service_ids in one transaction
+func (h *Handlers) MoveServicesBulk(c *gin.Context) {
+	h.transferServicesBulk(c, true)
+}
+
+func (h *Handlers) transferService(c *gin.Context, move bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	var req models.ServiceCopyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	services, err := h.transferServices(c, []int{id}, req, move)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(transferErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusCreated
+	if move {
+		status = http.StatusOK
+	}
+	c.JSON(status, services[0])
+}
+
+func (h *Handlers) transferServicesBulk(c *gin.Context, move bool) {
+	var req models.ServiceCopyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateServiceIDs(req.ServiceIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	services, err := h.transferServices(c, req.ServiceIDs, req, move)
+	var itemErr *repository.BulkItemError
+	if errors.As(err, &itemErr) {
+		message := itemErr.Err.Error()
+		if errors.Is(itemErr.Err, sql.ErrNoRows) {
+			message = "Service not found"
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "No services were saved",
+			"errors": []bulkItemError{{Index: itemErr.Index, Error: message}},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(transferErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusCreated
+	if move {
+		status = http.StatusOK
+	}
+	c.JSON(status, services)
+}
+
+// cloneOffset is how far a clone placed in its original's diagram is moved, so it does not hide it
+const cloneOffset = 40
+
+// CloneService creates a copy of a service with its full healthcheck configuration, optionally in
+// another diagram and with another name, host, port or healthcheck URL, such as for another
+// replica of the same component. Connections are not cloned.
+func (h *Handlers) CloneService(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	var req models.ServiceCloneRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if !h.inOrganization(c, "services", id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+	service, err := h.repo.GetServiceByID(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	service.Name += " (copy)"
+	if req.DiagramID != nil && *req.DiagramID != service.DiagramID {
+		if !h.inOrganization(c, "diagrams", *req.DiagramID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Diagram not found"})
+			return
+		}
+		service.DiagramID = *req.DiagramID
+	} else {
+		service.PositionX += cloneOffset
+		service.PositionY += cloneOffset
+	}
+	if req.Name != nil {
+		service.Name = *req.Name
+	}
+	if req.Host != nil {
+		service.Host = *req.Host
+	}
+	if req.Port != nil {
+		service.Port = *req.Port
+	}
+	if req.HealthcheckURL != nil {
+		service.HealthcheckURL = *req.HealthcheckURL
+	}
+	if err := validateServiceFields(service); err != nil {
+		c.JSON(http.StatusBadRequest, validationResponse(err))
+		return
+	}
+
+	if err := h.repo.CreateService(c.Request.Context(), service); err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordVersion(c, service.DiagramID, fmt.Sprintf("Cloned service %s", service.Name))
+	h.broadcastEvent(models.MessageServiceCreated, service.DiagramID, *service)
+	c.JSON(http.StatusCreated, service)
+}
+
+// transferServices copies or moves services and records a version of every diagram it changed
+func (h *Handlers) transferServices(c *gin.Context, ids []int, req models.ServiceCopyRequest, move bool) ([]models.Service, error) {
+	if !h.inOrganization(c, "diagrams", req.DiagramID) {
+		return nil, repository.ErrTargetDiagramNotFound
+	}
+	for i, id := range ids {
+		if !h.inOrganization(c, "services", id) {
+			return nil, &repository.BulkItemError{Index: i, Err: sql.ErrNoRows}
+		}
+	}
+
+	var sourceDiagrams []int
+	if move {
+		var err error
+		if sourceDiagrams, err = h.repo.GetServiceDiagramIDs(c.Request.Context(), ids); err != nil {
+			log.Printf("Error resolving diagrams of moved services: %v", err)
+		}
+	}
+
+	services, err := h.repo.CopyServices(c.Request.Context(), ids, req.DiagramID, move, req.Connections)
+	if err != nil {
+		return nil, err
+	}
+
+	verb := "Copied"
+	if move {
+		verb = "Moved"
+	}
+	summary := fmt.Sprintf("%s %d services", verb, len(services))
+	if len(services) == 1 {
+		summary = fmt.Sprintf("%s service %s", verb, services[0].Name)
+	}
+	for _, diagramID := range sourceDiagrams {
+		if diagramID != req.DiagramID {
+			h.recordVersion(c, diagramID, fmt.Sprintf("%s to diagram %d", summary, req.DiagramID))
+		}
+	}
+	h.recordVersion(c, req.DiagramID, summary)
+	return services, nil
+}
+
+// transferErrorStatus is the HTTP status for an error copying or moving services
+func transferErrorStatus(err error) int {
+	if errors.Is(err, repository.ErrTargetDiagramNotFound) {
+		return http.StatusBadRequest
+	}
+	return serviceErrorStatus(err)
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"service-weaver/internal/discovery"
+	"service-weaver/internal/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Discovery source handlers (admin only)
+func (h *Handlers) CreateDiscoverySource(c *gin.Context) {
+	var source models.DiscoverySource
+	if err := c.ShouldBindJSON(&source); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.validateDiscoverySource(c, &source) {
+		return
+	}
+
+	if err := h.repo.CreateDiscoverySource(c.Request.Context(), &source); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, source)
+}
+
+func (h *Handlers) GetDiscoverySources(c *gin.Context) {
+	sources, err := h.repo.GetDiscoverySources(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sources)
+}
+
+func (h *Handlers) UpdateDiscoverySource(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid discovery source ID"})
+		return
+	}
+
+	var source models.DiscoverySource
+	if err := c.ShouldBindJSON(&source); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.validateDiscoverySource(c, &source) {
+		return
+	}
+
+	source.ID = id
+	if err := h.repo.UpdateDiscoverySource(c.Request.Context(), &source); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, source)
+}
+
+func (h *Handlers) DeleteDiscoverySource(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid discovery source ID"})
+		return
+	}
+
+	if err := h.repo.DeleteDiscoverySource(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Discovery source deleted"})
+}
+
+// PreviewDiscoverySource lists what a source currently discovers without importing anything
+func (h *Handlers) PreviewDiscoverySource(c *gin.Context) {
+	source, ok := h.loadDiscoverySource(c)
+	if !ok {
+		return
+	}
+
+	discovered, err := h.syncer.Preview(*source)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, discovered)
+}
+
+// SyncDiscoverySource imports a source into its diagram immediately.
+// ?force=true also overwrites nodes that were edited by hand since the last sync.
+func (h *Handlers) SyncDiscoverySource(c *gin.Context) {
+	source, ok := h.loadDiscoverySource(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.syncer.Sync(*source, c.Query("force") == "true")
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handlers) loadDiscoverySource(c *gin.Context) (*models.DiscoverySource, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid discovery source ID"})
+		return nil, false
+	}
+
+	source, err := h.repo.GetDiscoverySource(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Discovery source not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return source, true
+}
+
+// validateDiscoverySource checks the target diagram exists and the provider config is usable
+func (h *Handlers) validateDiscoverySource(c *gin.Context, source *models.DiscoverySource) bool {
+	if source.SyncInterval < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sync_interval must not be negative"})
+		return false
+	}
+	if source.Config == nil {
+		source.Config = models.JSON{}
+	}
+
+	if _, err := h.repo.GetDiagram(c.Request.Context(), source.DiagramID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Diagram not found"})
+		return false
+	}
+
+	if _, err := discovery.NewProvider(*source); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"regexp"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// environmentNamePattern keeps environment names short identifiers, as they appear in URLs and labels
+var environmentNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// environmentFromRequest validates and normalizes an environment request
+func environmentFromRequest(c *gin.Context) (models.Environment, bool) {
+	var environment models.Environment
+	if err := c.ShouldBindJSON(&environment); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return environment, false
+	}
+	environment.Name = strings.ToLower(strings.TrimSpace(environment.Name))
+	if !environmentNamePattern.MatchString(environment.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Environment names are lowercase letters, digits, - and _"})
+		return environment, false
+	}
+	if environment.MaxSeverity == "" {
+		environment.MaxSeverity = models.SeverityCritical
+	}
+	return environment, true
+}
+
+// environmentExists reports whether the environment a request labels a diagram or service with,
+// if any, is defined
+func (h *Handlers) environmentExists(c *gin.Context, name string) bool {
+	if name == "" {
+		return true
+	}
+	_, err := h.repo.GetEnvironmentByName(c.Request.Context(), name)
+	return err == nil
+}
+
+// GetEnvironments lists the environments diagrams and services can be labelled with
+func (h *Handlers) GetEnvironments(c *gin.Context) {
+	environments, err := h.repo.GetEnvironments(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, environments)
+}
+
+// CreateEnvironment defines an environment
+func (h *Handlers) CreateEnvironment(c *gin.Context) {
+	environment, ok := environmentFromRequest(c)
+	if !ok {
+		return
+	}
+
+	err := h.repo.CreateEnvironment(c.Request.Context(), &environment)
+	if errors.Is(err, repository.ErrEnvironmentNameTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, environment)
+}
+
+// UpdateEnvironment replaces an environment; renaming it relabels its diagrams and services
+func (h *Handlers) UpdateEnvironment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid environment ID"})
+		return
+	}
+	environment, ok := environmentFromRequest(c)
+	if !ok {
+		return
+	}
+	environment.ID = id
+
+	err = h.repo.UpdateEnvironment(c.Request.Context(), &environment)
+	if errors.Is(err, repository.ErrEnvironmentNameTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Environment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, environment)
+}
+
+// DeleteEnvironment deletes an environment no diagram or service is labelled with
+func (h *Handlers) DeleteEnvironment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid environment ID"})
+		return
+	}
+
+	err = h.repo.DeleteEnvironment(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrEnvironmentInUse) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Environment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Environment deleted"})
+}
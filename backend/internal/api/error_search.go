@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultErrorSearchWindow is how far back errors are searched without ?from=
+const defaultErrorSearchWindow = 7 * 24 * time.Hour
+
+// SearchErrors finds recurring failure causes across services: it searches the errors of
+// healthcheck results for ?q= and groups them by signature, with occurrence counts. ?service=
+// takes comma-separated service IDs; ?from= and ?to= default to the last week.
+func (h *Handlers) SearchErrors(c *gin.Context) {
+	userRole, _ := c.Get("user_role")
+	filter := repository.ErrorFilter{
+		OrganizationID: currentOrganizationID(c),
+		PublicOnly:     userRole != models.RoleAdmin,
+		Query:          c.Query("q"),
+	}
+	for _, value := range parseList(c.Query("service")) {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+			return
+		}
+		filter.ServiceIDs = append(filter.ServiceIDs, id)
+	}
+
+	var err error
+	filter.From, filter.To, err = parseTimeRange(c, defaultErrorSearchWindow)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Limit, filter.Offset, err = parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	signatures, total, err := h.repo.SearchErrors(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"errors": signatures,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetServiceEvents returns the status transition log of a service, newest first.
+// Supports ?limit=&offset= pagination and ?from=&to= time bounds.
+func (h *Handlers) GetServiceEvents(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	if _, err := h.repo.GetServiceByID(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+
+	h.respondWithEvents(c, repository.EventFilter{ServiceID: id})
+}
+
+// GetDiagramEvents returns the status transition log of every service in a diagram, newest first.
+// ?environment= narrows it down to the services in an environment.
+func (h *Handlers) GetDiagramEvents(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+
+	if _, err := h.repo.GetDiagram(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Diagram not found"})
+		return
+	}
+
+	h.respondWithEvents(c, repository.EventFilter{DiagramID: id, Environment: c.Query("environment")})
+}
+
+func (h *Handlers) respondWithEvents(c *gin.Context, filter repository.EventFilter) {
+	var err error
+	filter.Limit, filter.Offset, err = parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if value := c.Query("from"); value != "" {
+		if filter.From, err = parseTime(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if filter.To, err = parseTime(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	events, total, err := h.repo.GetStatusEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// GetDiagramActivity returns what happened in a diagram, newest first: structural edits, the
+// status changes and alerts of its services and its maintenance windows. Supports ?type=
+// (comma-separated), ?from=&to= time bounds and ?limit=&offset= pagination.
+func (h *Handlers) GetDiagramActivity(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+
+	if _, err := h.repo.GetDiagram(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Diagram not found"})
+		return
+	}
+
+	filter := repository.ActivityFilter{DiagramID: id}
+	for _, value := range parseList(c.Query("type")) {
+		valid := false
+		for _, t := range models.ActivityTypes {
+			valid = valid || value == string(t)
+		}
+		if !valid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown activity type %q", value)})
+			return
+		}
+		filter.Types = append(filter.Types, models.ActivityType(value))
+	}
+	filter.Limit, filter.Offset, err = parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if value := c.Query("from"); value != "" {
+		if filter.From, err = parseTime(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if filter.To, err = parseTime(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	activity, total, err := h.repo.GetDiagramActivity(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"activity": activity,
+		"total":    total,
+		"limit":    filter.Limit,
+		"offset":   filter.Offset,
+	})
+}
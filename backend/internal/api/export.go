@@ -0,0 +1,362 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordWriter streams a sequence of records in a specific export format
+type recordWriter interface {
+	// Write emits one record; row is used for CSV output and value for JSON output
+	Write(row []string, value interface{}) error
+	Close() error
+}
+
+type csvRecordWriter struct {
+	w *csv.Writer
+}
+
+func (cw *csvRecordWriter) Write(row []string, _ interface{}) error {
+	return cw.w.Write(row)
+}
+
+func (cw *csvRecordWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// jsonRecordWriter writes a JSON array one element at a time
+type jsonRecordWriter struct {
+	w       io.Writer
+	enc     *json.Encoder
+	written bool
+}
+
+func (jw *jsonRecordWriter) Write(_ []string, value interface{}) error {
+	sep := ","
+	if !jw.written {
+		sep = "["
+		jw.written = true
+	}
+	if _, err := io.WriteString(jw.w, sep); err != nil {
+		return err
+	}
+	return jw.enc.Encode(value)
+}
+
+func (jw *jsonRecordWriter) Close() error {
+	if !jw.written {
+		_, err := io.WriteString(jw.w, "[]")
+		return err
+	}
+	_, err := io.WriteString(jw.w, "]")
+	return err
+}
+
+// startExport validates ?format=, writes the download headers and returns a writer for the records.
+// For CSV output the header row is written immediately.
+func startExport(c *gin.Context, name string, header []string) (recordWriter, bool) {
+	format := c.DefaultQuery("format", "json")
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().UTC().Format("20060102-150405"), format)
+
+	switch format {
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Status(http.StatusOK)
+		w := &csvRecordWriter{w: csv.NewWriter(c.Writer)}
+		if err := w.Write(header, nil); err != nil {
+			return nil, false
+		}
+		return w, true
+	case "json":
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Status(http.StatusOK)
+		return &jsonRecordWriter{w: c.Writer, enc: json.NewEncoder(c.Writer)}, true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be 'csv' or 'json'"})
+		return nil, false
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ExportResults streams raw healthcheck results.
+// Query parameters: service_id, environment, from, to, status, format (csv|json).
+func (h *Handlers) ExportResults(c *gin.Context) {
+	var filter repository.ResultFilter
+	var err error
+	if value := c.Query("service_id"); value != "" {
+		if filter.ServiceID, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+			return
+		}
+		if !h.inOrganization(c, "services", filter.ServiceID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+			return
+		}
+	}
+	filter.From, filter.To, err = parseTimeRange(c, 30*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.Statuses, err = parseStatusFilter(c.Query("status")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Environment = c.Query("environment")
+	filter.OrganizationID = currentOrganizationID(c)
+
+	w, ok := startExport(c, "healthcheck-results", []string{"id", "service_id", "status", "status_code", "response_time_ms", "error", "checked_at"})
+	if !ok {
+		return
+	}
+
+	err = h.repo.StreamHealthcheckResults(c.Request.Context(), filter, func(r models.HealthcheckResult) error {
+		row := []string{
+			strconv.Itoa(r.ID),
+			strconv.Itoa(r.ServiceID),
+			string(r.Status),
+			strconv.Itoa(r.StatusCode),
+			strconv.Itoa(r.ResponseTime),
+			r.Error,
+			formatTime(&r.CheckedAt),
+		}
+		return w.Write(row, r)
+	})
+	if err != nil {
+		// Headers are already sent, so the truncated body is the only signal left
+		c.Error(err)
+		return
+	}
+	if err := w.Close(); err != nil {
+		c.Error(err)
+	}
+}
+
+// ExportUptime exports per-service availability for a diagram, or all diagrams of the organization when
+// diagram_id is omitted.
+// Query parameters: diagram_id, environment, window (default 30d), format (csv|json).
+func (h *Handlers) ExportUptime(c *gin.Context) {
+	window, err := parseWindow(c.DefaultQuery("window", defaultUptimeWindow))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var diagrams []models.Diagram
+	if value := c.Query("diagram_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+			return
+		}
+		diagram, err := h.repo.GetDiagram(c.Request.Context(), id)
+		if err != nil || diagram.OrganizationID != currentOrganizationID(c) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Diagram not found"})
+			return
+		}
+		diagrams = append(diagrams, *diagram)
+	} else {
+		diagrams, _, err = h.repo.ListDiagrams(c.Request.Context(), repository.DiagramFilter{OrganizationID: currentOrganizationID(c)})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	to := time.Now()
+	from := to.Add(-window)
+
+	type uptimeRow struct {
+		DiagramID   int    `json:"diagram_id"`
+		DiagramName string `json:"diagram_name"`
+		models.UptimeSummary
+	}
+
+	w, ok := startExport(c, "uptime", []string{"diagram_id", "diagram_name", "service_id", "service_name", "from", "to", "total_checks", "up_checks", "down_checks", "uptime_percent", "slo_target", "slo_met", "error_budget_remaining"})
+	if !ok {
+		return
+	}
+
+	environment := c.Query("environment")
+	for _, d := range diagrams {
+		summaries, err := h.repo.GetDiagramUptime(c.Request.Context(), d.ID, from, to)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		var included map[int]bool
+		if environment != "" {
+			services, err := h.repo.GetServices(c.Request.Context(), d.ID)
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			if services, _, err = h.inEnvironment(c.Request.Context(), &d, services, nil, environment); err != nil {
+				c.Error(err)
+				return
+			}
+			if summaries, err = h.overlayUptime(c.Request.Context(), services, summaries, from, to); err != nil {
+				c.Error(err)
+				return
+			}
+			included = make(map[int]bool, len(services))
+			for _, s := range services {
+				included[s.ID] = true
+			}
+		}
+		for _, u := range summaries {
+			if included != nil && !included[u.ServiceID] {
+				continue
+			}
+			u.ApplySLO(u.SLOTarget)
+			row := []string{
+				strconv.Itoa(d.ID),
+				d.Name,
+				strconv.Itoa(u.ServiceID),
+				u.ServiceName,
+				formatTime(&u.From),
+				formatTime(&u.To),
+				strconv.Itoa(u.TotalChecks),
+				strconv.Itoa(u.UpChecks),
+				strconv.Itoa(u.DownChecks),
+				strconv.FormatFloat(u.UptimePercent, 'f', 4, 64),
+				strconv.FormatFloat(u.SLOTarget, 'f', 3, 64),
+				strconv.FormatBool(u.SLOMet),
+				strconv.FormatFloat(u.ErrorBudgetRemaining, 'f', 2, 64),
+			}
+			if err := w.Write(row, uptimeRow{DiagramID: d.ID, DiagramName: d.Name, UptimeSummary: u}); err != nil {
+				c.Error(err)
+				return
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		c.Error(err)
+	}
+}
+
+// ExportDiagram exports a diagram's services (CSV) or the complete diagram with connections (JSON).
+// ?environment= exports the diagram as the environment sees it, overlays included.
+func (h *Handlers) ExportDiagram(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+
+	diagram, err := h.repo.GetDiagram(c.Request.Context(), id)
+	if err != nil || diagram.OrganizationID != currentOrganizationID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Diagram not found"})
+		return
+	}
+	services, err := h.repo.GetServices(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	connections, err := h.repo.GetConnections(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	services, connections, err = h.inEnvironment(c.Request.Context(), diagram, services, connections, c.Query("environment"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.DefaultQuery("format", "json") == "json" {
+		if _, ok := startExport(c, fmt.Sprintf("diagram-%d", id), nil); !ok {
+			return
+		}
+		if err := writeDiagramExport(c.Writer, diagram, services, connections); err != nil {
+			c.Error(err)
+		}
+		return
+	}
+
+	w, ok := startExport(c, fmt.Sprintf("diagram-%d-services", id), []string{"id", "name", "service_type", "host", "port", "tags", "healthcheck_method", "healthcheck_url", "polling_interval", "current_status", "last_checked"})
+	if !ok {
+		return
+	}
+	for _, s := range services {
+		row := []string{
+			strconv.Itoa(s.ID),
+			s.Name,
+			s.ServiceType,
+			s.Host,
+			strconv.Itoa(s.Port),
+			s.Tags,
+			s.HealthcheckMethod,
+			s.HealthcheckURL,
+			strconv.Itoa(s.PollingInterval),
+			string(s.CurrentStatus),
+			formatTime(s.LastChecked),
+		}
+		if err := w.Write(row, s); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+	if err := w.Close(); err != nil {
+		c.Error(err)
+	}
+}
+
+// writeDiagramExport streams a models.DiagramExport, writing its services and connections one at a
+// time like the other JSON exports
+func writeDiagramExport(w io.Writer, diagram *models.Diagram, services []models.Service, connections []models.Connection) error {
+	enc := json.NewEncoder(w)
+	if _, err := io.WriteString(w, `{"diagram":`); err != nil {
+		return err
+	}
+	if err := enc.Encode(diagram); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, `,"services":`); err != nil {
+		return err
+	}
+	list := &jsonRecordWriter{w: w, enc: enc}
+	for _, s := range services {
+		if err := list.Write(nil, s); err != nil {
+			return err
+		}
+	}
+	if err := list.Close(); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, `,"connections":`); err != nil {
+		return err
+	}
+	list = &jsonRecordWriter{w: w, enc: enc}
+	for _, conn := range connections {
+		if err := list.Write(nil, conn); err != nil {
+			return err
+		}
+	}
+	if err := list.Close(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "}")
+	return err
+}
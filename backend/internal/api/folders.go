@@ -0,0 +1,191 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// GetFolders lists the folders of the current organization; the tree is rebuilt by clients from parent_id
+func (h *Handlers) GetFolders(c *gin.Context) {
+	folders, err := h.repo.GetFolders(c.Request.Context(), currentOrganizationID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, folders)
+}
+
+// Folder management handlers (admin only)
+func (h *Handlers) CreateFolder(c *gin.Context) {
+	var folder models.Folder
+	if err := c.ShouldBindJSON(&folder); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if folder.ParentID != nil && !h.inOrganization(c, "folders", *folder.ParentID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parent folder not found"})
+		return
+	}
+
+	folder.OrganizationID = currentOrganizationID(c)
+	if err := h.repo.CreateFolder(c.Request.Context(), &folder); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, folder)
+}
+
+func (h *Handlers) UpdateFolder(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	var folder models.Folder
+	if err := c.ShouldBindJSON(&folder); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if folder.ParentID != nil && !h.inOrganization(c, "folders", *folder.ParentID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parent folder not found"})
+		return
+	}
+
+	folder.ID = id
+	err = h.repo.UpdateFolder(c.Request.Context(), &folder)
+	if errors.Is(err, repository.ErrFolderCycle) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+// DeleteFolder removes an empty folder
+func (h *Handlers) DeleteFolder(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	err = h.repo.DeleteFolder(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrFolderNotEmpty) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Folder deleted"})
+}
+
+// MoveDiagram files a diagram under a folder, or at the top level when folder_id is null
+func (h *Handlers) MoveDiagram(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+
+	var req models.DiagramFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.FolderID != nil && !h.inOrganization(c, "folders", *req.FolderID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Folder not found"})
+		return
+	}
+
+	err = h.repo.MoveDiagram(c.Request.Context(), id, req.FolderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Diagram not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	diagram, err := h.repo.GetDiagram(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, diagram)
+}
+
+// bindNewDiagram binds a diagram creation request. Diagrams created in a folder take the
+// folder's default visibility unless the request sets public explicitly.
+func (h *Handlers) bindNewDiagram(c *gin.Context, diagram *models.Diagram) bool {
+	if err := c.ShouldBindBodyWith(diagram, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if diagram.FolderID == nil {
+		return true
+	}
+
+	folder, err := h.repo.GetFolder(c.Request.Context(), *diagram.FolderID)
+	if err != nil || folder.OrganizationID != currentOrganizationID(c) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Folder not found"})
+		return false
+	}
+	var fields map[string]json.RawMessage
+	if err := c.ShouldBindBodyWith(&fields, binding.JSON); err == nil {
+		if _, ok := fields["public"]; !ok {
+			diagram.Public = folder.DefaultPublic
+		}
+	}
+	return true
+}
+
+// parseFolderFilter reads ?folder_id= (an ID, or "none" for diagrams outside any folder) and
+// ?recursive=true, which also matches diagrams in nested folders
+func parseFolderFilter(c *gin.Context, filter *repository.DiagramFilter) error {
+	value := c.Query("folder_id")
+	if value == "" {
+		return nil
+	}
+
+	id := 0
+	if value != "none" {
+		var err error
+		if id, err = strconv.Atoi(value); err != nil || id <= 0 {
+			return fmt.Errorf("invalid folder_id %q", value)
+		}
+	}
+	filter.FolderID = &id
+	filter.IncludeSubfolders = c.Query("recursive") == "true"
+	return nil
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"service-weaver/internal/models"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// certificateWarningDays is how close to expiry a certificate is flagged
+	certificateWarningDays = 14
+	// latencyTrendDays is how many daily averages the latency trend is fitted to
+	latencyTrendDays = 30
+	// latencyWarningPercent is the projected 30-day increase of the response time that is flagged
+	latencyWarningPercent = 25
+)
+
+// burnRateWindows are the windows burn rates are measured over, with the rates that exhaust the
+// budget of a 30-day period alarmingly fast (those of multiwindow burn rate alerts)
+var burnRateWindows = []struct {
+	name   string
+	window time.Duration
+	alarm  float64
+}{
+	{"1h", time.Hour, 14.4},
+	{"6h", 6 * time.Hour, 6},
+	{"24h", 24 * time.Hour, 3},
+	{"7d", 7 * 24 * time.Hour, 1},
+}
+
+// GetServiceForecast projects when a service's TLS certificate expires and whether its renewal is
+// late, when its error budget runs out at the current burn rate, and where its response time is
+// heading, so problems are flagged before they become outages. ?period= sets the SLO period of
+// the error budget (default 30d).
+func (h *Handlers) GetServiceForecast(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+	period, err := parseWindow(c.DefaultQuery("period", defaultUptimeWindow))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	service, err := h.repo.GetServiceByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	forecast := models.ServiceForecast{ServiceID: id, Warnings: []string{}, GeneratedAt: now}
+
+	certs, err := h.repo.GetServiceCertificates(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	forecast.Certificate = forecastCertificate(certs, now)
+
+	if forecast.ErrorBudget, err = h.forecastErrorBudget(ctx, service, period, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rollups, err := h.repo.GetRollups(ctx, id, models.RollupDaily, now.AddDate(0, 0, -latencyTrendDays), now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	forecast.Latency = forecastLatency(rollups)
+
+	forecast.Warnings = forecastWarnings(forecast, now)
+	c.JSON(http.StatusOK, forecast)
+}
+
+// forecastCertificate reports on the certificate a service serves now, the last it was seen
+// serving. A renewal is overdue once fewer days are left than the previous certificates had when
+// they were replaced, give or take a day.
+func forecastCertificate(certs []models.ServiceCertificate, now time.Time) *models.CertificateForecast {
+	if len(certs) == 0 {
+		return nil
+	}
+	current := certs[len(certs)-1]
+	forecast := &models.CertificateForecast{
+		Subject:   current.Subject,
+		Issuer:    current.Issuer,
+		ExpiresAt: current.NotAfter,
+		DaysLeft:  roundTo(current.NotAfter.Sub(now).Hours()/24, 1),
+		Renewals:  len(certs) - 1,
+	}
+
+	margins := make([]float64, 0, len(certs)-1)
+	for i := 1; i < len(certs); i++ {
+		margins = append(margins, certs[i-1].NotAfter.Sub(certs[i].FirstSeen).Hours()/24)
+	}
+	if len(margins) > 0 {
+		sort.Float64s(margins)
+		median := margins[len(margins)/2]
+		if len(margins)%2 == 0 {
+			median = (margins[len(margins)/2-1] + median) / 2
+		}
+		median = roundTo(median, 1)
+		forecast.RenewedDaysBefore = &median
+		forecast.RenewalOverdue = median > 0 && forecast.DaysLeft < median-1
+	}
+	return forecast
+}
+
+// forecastErrorBudget measures the burn rate of the service's error budget over recent windows
+// and projects when the budget of the period runs out at the rate of the last day
+func (h *Handlers) forecastErrorBudget(ctx context.Context, service *models.Service, period time.Duration, now time.Time) (models.ErrorBudgetForecast, error) {
+	summary, err := h.repo.GetServiceUptime(ctx, service.ID, now.Add(-period), now)
+	if err != nil {
+		return models.ErrorBudgetForecast{}, err
+	}
+	summary.ApplySLO(service.SLOTarget)
+	forecast := models.ErrorBudgetForecast{
+		SLOTarget:  summary.SLOTarget,
+		PeriodDays: int(period.Hours() / 24),
+		Remaining:  roundTo(summary.ErrorBudgetRemaining, 2),
+		BurnRates:  []models.BurnRate{},
+	}
+	allowed := 100 - summary.SLOTarget
+	if allowed <= 0 {
+		return forecast, nil
+	}
+
+	var dailyRate float64
+	for _, w := range burnRateWindows {
+		window, err := h.repo.GetServiceUptime(ctx, service.ID, now.Add(-w.window), now)
+		if err != nil {
+			return forecast, err
+		}
+		if window.TotalChecks == 0 {
+			continue
+		}
+		window.ApplySLO(summary.SLOTarget)
+		rate := (100 - window.UptimePercent) / allowed
+		forecast.BurnRates = append(forecast.BurnRates, models.BurnRate{
+			Window:        w.name,
+			UptimePercent: roundTo(window.UptimePercent, 3),
+			Rate:          roundTo(rate, 2),
+		})
+		if w.name == "24h" {
+			dailyRate = rate
+		}
+	}
+
+	// At a burn rate of 1 the whole budget lasts the period
+	switch {
+	case summary.ErrorBudgetRemaining <= 0:
+		forecast.ExhaustedAt = &now
+	case dailyRate > 0:
+		left := time.Duration(summary.ErrorBudgetRemaining / 100 * float64(period) / dailyRate)
+		if left < period {
+			exhaustedAt := now.Add(left).Truncate(time.Minute)
+			forecast.ExhaustedAt = &exhaustedAt
+		}
+	}
+	return forecast, nil
+}
+
+// forecastLatency fits a least-squares line to the daily average response times of a service and
+// extrapolates it; it needs three days of history
+func forecastLatency(rollups []models.HealthcheckRollup) models.LatencyForecast {
+	var xs, ys []float64
+	for _, r := range rollups {
+		if r.TotalChecks == 0 {
+			continue
+		}
+		xs = append(xs, r.BucketStart.Sub(rollups[0].BucketStart).Hours()/24)
+		ys = append(ys, r.AvgResponseTime)
+	}
+	forecast := models.LatencyForecast{Days: len(xs)}
+	if len(xs) == 0 {
+		return forecast
+	}
+	forecast.Current = roundTo(ys[len(ys)-1], 1)
+	if len(xs) < 3 {
+		return forecast
+	}
+
+	n := float64(len(xs))
+	var sumX, sumY, sumXY, sumXX float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+		sumXY += xs[i] * ys[i]
+		sumXX += xs[i] * xs[i]
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return forecast
+	}
+	slope := (n*sumXY - sumX*sumY) / denominator
+	intercept := (sumY - slope*sumX) / n
+	last := xs[len(xs)-1]
+	fitted := intercept + slope*last
+
+	forecast.SlopePerDay = roundTo(slope, 2)
+	forecast.Projected7d = roundTo(math.Max(0, fitted+slope*7), 1)
+	forecast.Projected30d = roundTo(math.Max(0, fitted+slope*30), 1)
+	if fitted > 0 {
+		forecast.ChangePercent = roundTo((forecast.Projected30d-fitted)/fitted*100, 1)
+	}
+	return forecast
+}
+
+// forecastWarnings lists what in a forecast needs attention
+func forecastWarnings(forecast models.ServiceForecast, now time.Time) []string {
+	warnings := []string{}
+	if cert := forecast.Certificate; cert != nil {
+		switch {
+		case cert.DaysLeft <= 0:
+			warnings = append(warnings, fmt.Sprintf("TLS certificate expired on %s", cert.ExpiresAt.UTC().Format("2006-01-02")))
+		case cert.RenewalOverdue:
+			warnings = append(warnings, fmt.Sprintf("TLS certificate expires in %.0f days but was renewed %.0f days before expiry so far", cert.DaysLeft, *cert.RenewedDaysBefore))
+		case cert.DaysLeft <= certificateWarningDays:
+			warnings = append(warnings, fmt.Sprintf("TLS certificate expires in %.0f days", cert.DaysLeft))
+		}
+	}
+
+	budget := forecast.ErrorBudget
+	for _, rate := range budget.BurnRates {
+		for _, w := range burnRateWindows {
+			if w.name == rate.Window && rate.Rate >= w.alarm {
+				warnings = append(warnings, fmt.Sprintf("error budget burning %.1fx faster than sustainable over the last %s", rate.Rate, rate.Window))
+			}
+		}
+	}
+	switch {
+	case budget.Remaining <= 0:
+		warnings = append(warnings, fmt.Sprintf("error budget of the last %d days is exhausted", budget.PeriodDays))
+	case budget.ExhaustedAt != nil:
+		warnings = append(warnings, fmt.Sprintf("error budget runs out in %s at the burn rate of the last day", budget.ExhaustedAt.Sub(now).Round(time.Hour)))
+	}
+
+	if latency := forecast.Latency; latency.ChangePercent >= latencyWarningPercent {
+		warnings = append(warnings, fmt.Sprintf("response time trending up %.0f%% over the next 30 days, to %.0f ms", latency.ChangePercent, latency.Projected30d))
+	}
+	return warnings
+}
+
+// roundTo rounds a value to the given number of decimals
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxGrafanaAnnotations caps the status changes returned for one annotation query
+const maxGrafanaAnnotations = 1000
+
+// grafanaMetric is a series Grafana can graph for every service, computed from a metrics bucket
+type grafanaMetric struct {
+	name  string
+	label string
+	value func(b models.MetricsBucket) float64
+}
+
+var grafanaMetrics = []grafanaMetric{
+	{"uptime", "uptime %", func(b models.MetricsBucket) float64 {
+		return float64(b.AliveCount+b.DegradedCount) / float64(b.Count) * 100
+	}},
+	{"latency", "latency (avg ms)", func(b models.MetricsBucket) float64 { return b.AvgResponseTime }},
+	{"latency_p50", "latency (p50 ms)", func(b models.MetricsBucket) float64 { return b.P50ResponseTime }},
+	{"latency_p95", "latency (p95 ms)", func(b models.MetricsBucket) float64 { return b.P95ResponseTime }},
+	{"latency_p99", "latency (p99 ms)", func(b models.MetricsBucket) float64 { return b.P99ResponseTime }},
+}
+
+// parseGrafanaTarget splits a target such as latency_p95:12 into its metric and service ID
+func parseGrafanaTarget(target string) (grafanaMetric, int, error) {
+	name, value, _ := strings.Cut(target, ":")
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return grafanaMetric{}, 0, fmt.Errorf("invalid target %q, expected <metric>:<service ID>", target)
+	}
+	for _, metric := range grafanaMetrics {
+		if metric.name == name {
+			return metric, id, nil
+		}
+	}
+	return grafanaMetric{}, 0, fmt.Errorf("unknown metric %q in target %q", name, target)
+}
+
+// grafanaStep picks the bucket width of a panel: its interval, widened so the series stays within
+// its maximum number of points and the bucket limit of the metrics API
+func grafanaStep(req models.GrafanaQueryRequest) time.Duration {
+	span := req.Range.To.Sub(req.Range.From)
+	step := time.Duration(req.IntervalMs) * time.Millisecond
+	if req.MaxDataPoints > 0 && span/time.Duration(req.MaxDataPoints) > step {
+		step = span / time.Duration(req.MaxDataPoints)
+	}
+	if step < minMetricsStep {
+		step = minMetricsStep
+	}
+	if span/step > maxMetricsBuckets {
+		step = span / maxMetricsBuckets
+	}
+	return step
+}
+
+// GrafanaTest answers the connection test of a Grafana JSON datasource
+func (h *Handlers) GrafanaTest(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// GrafanaSearch lists the metrics of every service whose description contains the target, for
+// the query editor of a Grafana JSON datasource
+func (h *Handlers) GrafanaSearch(c *gin.Context) {
+	var req models.GrafanaSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	services, err := h.repo.GetAllServices(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	diagrams, err := h.repo.GetDiagrams(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	diagramNames := make(map[int]string, len(diagrams))
+	for _, d := range diagrams {
+		diagramNames[d.ID] = d.Name
+	}
+
+	filter := strings.ToLower(strings.TrimSpace(req.Target))
+	found := []models.GrafanaMetric{}
+	for _, service := range services {
+		for _, metric := range grafanaMetrics {
+			text := fmt.Sprintf("%s / %s %s", diagramNames[service.DiagramID], service.Name, metric.label)
+			value := fmt.Sprintf("%s:%d", metric.name, service.ID)
+			if filter != "" && !strings.Contains(strings.ToLower(text), filter) && !strings.Contains(value, filter) {
+				continue
+			}
+			found = append(found, models.GrafanaMetric{Text: text, Value: value})
+		}
+	}
+	c.JSON(http.StatusOK, found)
+}
+
+// GrafanaQuery answers the targets of a Grafana panel with the uptime or response times of a
+// service over the panel's range, bucketed by its interval. Targets of type table come back as a
+// table of time and value.
+func (h *Handlers) GrafanaQuery(c *gin.Context) {
+	var req models.GrafanaQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.Range.To.After(req.Range.From) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid range"})
+		return
+	}
+	step := grafanaStep(req)
+
+	// Targets of the same service share its buckets
+	buckets := make(map[int][]models.MetricsBucket)
+	names := make(map[int]string)
+	response := make([]interface{}, 0, len(req.Targets))
+	for _, target := range req.Targets {
+		if target.Target == "" {
+			continue
+		}
+		metric, id, err := parseGrafanaTarget(target.Target)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, ok := buckets[id]; !ok {
+			service, err := h.repo.GetServiceByID(c.Request.Context(), id)
+			if errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Service %d not found", id)})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			series, err := h.repo.GetResponseTimeMetrics(c.Request.Context(), id, req.Range.From, req.Range.To, step)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			buckets[id], names[id] = series, service.Name
+		}
+
+		name := names[id] + " " + metric.label
+		if target.Type == "table" {
+			table := models.GrafanaTable{
+				Type:    "table",
+				RefID:   target.RefID,
+				Columns: []models.GrafanaColumn{{Text: "Time", Type: "time"}, {Text: name, Type: "number"}},
+				Rows:    make([][]interface{}, 0, len(buckets[id])),
+			}
+			for _, b := range buckets[id] {
+				table.Rows = append(table.Rows, []interface{}{b.Timestamp.UnixMilli(), metric.value(b)})
+			}
+			response = append(response, table)
+			continue
+		}
+		series := models.GrafanaSeries{Target: name, RefID: target.RefID, Datapoints: make([][2]float64, 0, len(buckets[id]))}
+		for _, b := range buckets[id] {
+			series.Datapoints = append(series.Datapoints, [2]float64{metric.value(b), float64(b.Timestamp.UnixMilli())})
+		}
+		response = append(response, series)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GrafanaAnnotations marks the status changes of services within a dashboard's range: every
+// service, or those of service:<id> or diagram:<id> given as the annotation query. Outages and
+// degradations that ended span the time they lasted.
+func (h *Handlers) GrafanaAnnotations(c *gin.Context) {
+	var req models.GrafanaAnnotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := repository.EventFilter{From: req.Range.From, To: req.Range.To, Limit: maxGrafanaAnnotations}
+	if query := strings.TrimSpace(req.Annotation.Query); query != "" {
+		kind, value, _ := strings.Cut(query, ":")
+		id, err := strconv.Atoi(strings.TrimSpace(value))
+		switch {
+		case err != nil:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid annotation query, expected service:<id> or diagram:<id>"})
+			return
+		case kind == "service":
+			filter.ServiceID = id
+		case kind == "diagram":
+			filter.DiagramID = id
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid annotation query, expected service:<id> or diagram:<id>"})
+			return
+		}
+	}
+
+	events, _, err := h.repo.GetStatusEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	services, err := h.repo.GetAllServices(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	names := make(map[int]string, len(services))
+	for _, service := range services {
+		names[service.ID] = service.Name
+	}
+
+	annotations := make([]models.GrafanaAnnotation, 0, len(events))
+	for _, e := range events {
+		annotation := models.GrafanaAnnotation{
+			Annotation: req.Annotation,
+			Time:       e.OccurredAt.UnixMilli(),
+			Title:      fmt.Sprintf("%s is %s", names[e.ServiceID], e.ToStatus),
+			Tags:       []string{names[e.ServiceID], string(e.ToStatus)},
+			Text:       fmt.Sprintf("%s → %s", e.FromStatus, e.ToStatus),
+		}
+		if e.EndedAt != nil && e.ToStatus != models.StatusAlive {
+			annotation.TimeEnd, annotation.IsRegion = e.EndedAt.UnixMilli(), true
+			annotation.Text += fmt.Sprintf(" for %s", (time.Duration(e.DurationSeconds) * time.Second).String())
+		}
+		annotations = append(annotations, annotation)
+	}
+	c.JSON(http.StatusOK, annotations)
+}
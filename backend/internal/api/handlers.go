@@ -2,17 +2,28 @@ package api
 
 import (
 	"bytes"
+	"database/sql"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"image"
 	"image/jpeg"
 	"image/png"
+	"log"
 	"net/http"
+	"service-weaver/internal/blobstore"
+	"service-weaver/internal/discovery"
 	"service-weaver/internal/middleware"
 	"service-weaver/internal/models"
 	"service-weaver/internal/monitoring"
+	"service-weaver/internal/notification"
+	"service-weaver/internal/oidc"
+	"service-weaver/internal/reports"
 	"service-weaver/internal/repository"
+	"service-weaver/internal/secrets"
+	"service-weaver/internal/webhooks"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -21,58 +32,52 @@ import (
 )
 
 type Handlers struct {
-	repo      *repository.Repository
+	repo      Store
 	scheduler *monitoring.HealthcheckScheduler
+	notifier  *notification.Dispatcher
+	pruner    *repository.RetentionPruner
+	reporter  *reports.Scheduler
+	syncer    *discovery.Syncer
+	webhooks  *webhooks.Dispatcher
+	sso       *oidc.Provider // nil when single sign-on is disabled
 	upgrader  websocket.Upgrader
 }
 
-func NewHandlers(repo *repository.Repository, scheduler *monitoring.HealthcheckScheduler) *Handlers {
+func NewHandlers(repo Store, scheduler *monitoring.HealthcheckScheduler, notifier *notification.Dispatcher, pruner *repository.RetentionPruner, reporter *reports.Scheduler, syncer *discovery.Syncer, webhookDispatcher *webhooks.Dispatcher, sso *oidc.Provider) *Handlers {
 	return &Handlers{
 		repo:      repo,
 		scheduler: scheduler,
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true // Allow all origins in development
-			},
-		},
-	}
-}
-
-// WebSocket handler
-func (h *Handlers) HandleWebSocket(c *gin.Context) {
-	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upgrade connection"})
-		return
-	}
-
-	h.scheduler.AddClient(conn)
-
-	// Handle client disconnection
-	defer h.scheduler.RemoveClient(conn)
-
-	// Keep connection alive
-	for {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			break
-		}
+		notifier:  notifier,
+		pruner:    pruner,
+		reporter:  reporter,
+		syncer:    syncer,
+		webhooks:  webhookDispatcher,
+		sso:       sso,
 	}
 }
 
 // Diagram handlers
 func (h *Handlers) CreateDiagram(c *gin.Context) {
 	var diagram models.Diagram
-	if err := c.ShouldBindJSON(&diagram); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !h.bindNewDiagram(c, &diagram) {
+		return
+	}
+	diagram.OrganizationID = currentOrganizationID(c)
+	if !h.ownerInOrganization(c, diagram.OwnerUserID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Owner is not a member of the organization"})
+		return
+	}
+	if !h.environmentExists(c, diagram.Environment) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown environment"})
 		return
 	}
 
-	if err := h.repo.CreateDiagram(&diagram); err != nil {
+	if err := h.repo.CreateDiagram(c.Request.Context(), &diagram); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.recordVersion(c, diagram.ID, "Created diagram")
 	c.JSON(http.StatusCreated, diagram)
 }
 
@@ -83,29 +88,52 @@ func (h *Handlers) GetDiagrams(c *gin.Context) {
 		return
 	}
 
-	var diagrams []models.Diagram
-	var err error
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if userRole == models.RoleAdmin {
-		diagrams, err = h.repo.GetDiagrams()
-	} else {
-		// For non-admin users, fetch all diagrams and filter public ones on the backend
-		// Alternatively, create a GetPublicDiagrams method in the repo
-		allDiagrams, err := h.repo.GetDiagrams()
-		if err == nil {
-			for _, d := range allDiagrams {
-				if d.Public {
-					diagrams = append(diagrams, d)
-				}
-			}
+	filter := repository.DiagramFilter{OrganizationID: currentOrganizationID(c), Name: c.Query("q"), ListOptions: opts}
+	if value := c.Query("public"); value != "" {
+		public, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid public filter"})
+			return
 		}
+		filter.Public = &public
+	}
+	if err := parseFolderFilter(c, &filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.OwnerUserID, filter.OwnerTeam, err = parseOwnerFilter(c); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Environment = c.Query("environment")
+	if err := parseDiagramListFilter(c, &filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Non-admin users only ever see public diagrams
+	if userRole != models.RoleAdmin {
+		public := true
+		filter.Public = &public
 	}
 
+	diagrams, total, err := h.repo.ListDiagrams(c.Request.Context(), filter)
+	if errors.Is(err, repository.ErrInvalidSort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.Header("X-Total-Count", strconv.Itoa(total))
 	c.JSON(http.StatusOK, diagrams)
 }
 
@@ -116,20 +144,25 @@ func (h *Handlers) GetDiagram(c *gin.Context) {
 		return
 	}
 
-	diagram, err := h.repo.GetDiagram(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Diagram not found"})
+	diagram := h.viewableDiagram(c, id)
+	if diagram == nil {
 		return
 	}
+	h.recordDiagramView(c, id)
 
 	// Get services and connections for this diagram
-	services, err := h.repo.GetServices(id)
+	services, err := h.repo.GetServices(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	connections, err := h.repo.GetConnections(id)
+	connections, err := h.repo.GetConnections(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	services, connections, err = h.inEnvironment(c.Request.Context(), diagram, services, connections, c.Query("environment"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -158,11 +191,20 @@ func (h *Handlers) UpdateDiagram(c *gin.Context) {
 	}
 
 	diagram.ID = id
-	if err := h.repo.UpdateDiagram(&diagram); err != nil {
+	if !h.ownerInOrganization(c, diagram.OwnerUserID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Owner is not a member of the organization"})
+		return
+	}
+	if !h.environmentExists(c, diagram.Environment) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown environment"})
+		return
+	}
+	if err := h.repo.UpdateDiagram(c.Request.Context(), &diagram); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.recordVersion(c, id, "Edited diagram details")
 	c.JSON(http.StatusOK, diagram)
 }
 
@@ -173,12 +215,12 @@ func (h *Handlers) DeleteDiagram(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.DeleteDiagram(id); err != nil {
+	if err := h.repo.DeleteDiagram(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Diagram deleted"})
+	c.JSON(http.StatusOK, gin.H{"message": "Diagram moved to trash"})
 }
 
 // Service handlers
@@ -189,11 +231,45 @@ func (h *Handlers) CreateService(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.CreateService(&service); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if service.SLOTarget <= 0 {
+		service.SLOTarget = models.DefaultSLOTarget
+	}
+	if err := validateOverrides(service.Overrides); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateRunbook(&service); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateAssertion(&service); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateHealthcheck(&service); err != nil {
+		c.JSON(http.StatusBadRequest, validationResponse(err))
+		return
+	}
+	if !h.inOrganization(c, "diagrams", service.DiagramID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Diagram not found"})
+		return
+	}
+	if !h.ownerInOrganization(c, service.OwnerUserID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Owner is not a member of the organization"})
+		return
+	}
+	if !h.environmentExists(c, service.Environment) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown environment"})
+		return
+	}
+
+	if err := h.repo.CreateService(c.Request.Context(), &service); err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
+	h.recordVersion(c, service.DiagramID, fmt.Sprintf("Added service %s", service.Name))
+	h.broadcastEvent(models.MessageServiceCreated, service.DiagramID, service)
 	c.JSON(http.StatusCreated, service)
 }
 
@@ -203,13 +279,42 @@ func (h *Handlers) GetServices(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
 		return
 	}
+	if h.viewableDiagram(c, diagramID) == nil {
+		return
+	}
+
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ownerUserID, ownerTeam, err := parseOwnerFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	services, err := h.repo.GetServices(diagramID)
+	services, total, err := h.repo.ListServices(c.Request.Context(), repository.ServiceFilter{
+		DiagramID:   diagramID,
+		Name:        c.Query("q"),
+		Statuses:    parseList(c.Query("status")),
+		Types:       parseList(c.Query("type")),
+		Tag:         c.Query("tag"),
+		OwnerUserID: ownerUserID,
+		OwnerTeam:   ownerTeam,
+		Environment: c.Query("environment"),
+		ListOptions: opts,
+	})
+	if errors.Is(err, repository.ErrInvalidSort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.Header("X-Total-Count", strconv.Itoa(total))
 	c.JSON(http.StatusOK, services)
 }
 
@@ -227,14 +332,59 @@ func (h *Handlers) UpdateService(c *gin.Context) {
 	}
 
 	service.ID = id
-	if err := h.repo.UpdateService(&service); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if service.SLOTarget <= 0 {
+		service.SLOTarget = models.DefaultSLOTarget
+	}
+	if err := validateOverrides(service.Overrides); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateRunbook(&service); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateAssertion(&service); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateHealthcheck(&service); err != nil {
+		c.JSON(http.StatusBadRequest, validationResponse(err))
+		return
+	}
+	if !h.ownerInOrganization(c, service.OwnerUserID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Owner is not a member of the organization"})
+		return
+	}
+	if !h.environmentExists(c, service.Environment) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown environment"})
+		return
+	}
+	if err := h.repo.UpdateService(c.Request.Context(), &service); err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
+	if updated, err := h.repo.GetServiceByID(c.Request.Context(), id); err == nil {
+		h.recordVersion(c, updated.DiagramID, fmt.Sprintf("Edited service %s", updated.Name))
+		h.broadcastEvent(models.MessageServiceUpdated, updated.DiagramID, *updated)
+	}
 	c.JSON(http.StatusOK, service)
 }
 
+// serviceErrorStatus is the HTTP status for an error saving a service; composite nodes pointing
+// at a missing diagram or forming a cycle, and credentials without an encryption key to store
+// them, are client errors, and overlays are edited through their service
+func serviceErrorStatus(err error) int {
+	if errors.Is(err, repository.ErrServiceIsOverlay) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, repository.ErrChildDiagramNotFound) || errors.Is(err, repository.ErrCompositeCycle) ||
+		errors.Is(err, secrets.ErrNoKey) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 func (h *Handlers) DeleteService(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -242,12 +392,17 @@ func (h *Handlers) DeleteService(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.DeleteService(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	existing, lookupErr := h.repo.GetServiceByID(c.Request.Context(), id)
+	if err := h.repo.DeleteService(c.Request.Context(), id); err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Service deleted"})
+	if lookupErr == nil {
+		h.recordVersion(c, existing.DiagramID, fmt.Sprintf("Removed service %s", existing.Name))
+		h.broadcastEvent(models.MessageServiceDeleted, existing.DiagramID, models.DeletedEntity{ID: id})
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Service moved to trash"})
 }
 
 // Connection handlers
@@ -258,11 +413,22 @@ func (h *Handlers) CreateConnection(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.CreateConnection(&connection); err != nil {
+	if err := validateConnection(&connection); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.inOrganization(c, "diagrams", connection.DiagramID) || !h.endpointsInOrganization(c, &connection) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Diagram or service not found"})
+		return
+	}
+
+	if err := h.repo.CreateConnection(c.Request.Context(), &connection); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.recordVersion(c, connection.DiagramID, "Added connection")
+	h.broadcastEvent(models.MessageConnectionCreated, connection.DiagramID, connection)
 	c.JSON(http.StatusCreated, connection)
 }
 
@@ -272,8 +438,11 @@ func (h *Handlers) GetConnections(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
 		return
 	}
+	if h.viewableDiagram(c, diagramID) == nil {
+		return
+	}
 
-	connections, err := h.repo.GetConnections(diagramID)
+	connections, err := h.repo.GetConnections(c.Request.Context(), diagramID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -289,11 +458,16 @@ func (h *Handlers) DeleteConnection(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.DeleteConnection(id); err != nil {
+	existing, lookupErr := h.repo.GetConnection(c.Request.Context(), id)
+	if err := h.repo.DeleteConnection(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if lookupErr == nil {
+		h.recordVersion(c, existing.DiagramID, "Removed connection")
+		h.broadcastEvent(models.MessageConnectionDeleted, existing.DiagramID, models.DeletedEntity{ID: id})
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Connection deleted"})
 }
 
@@ -310,15 +484,45 @@ func (h *Handlers) UpdateConnection(c *gin.Context) {
 		return
 	}
 
+	if err := validateConnection(&connection); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.endpointsInOrganization(c, &connection) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Service not found"})
+		return
+	}
+
 	connection.ID = id
-	if err := h.repo.UpdateConnection(&connection); err != nil {
+	if err := h.repo.UpdateConnection(c.Request.Context(), &connection); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if updated, err := h.repo.GetConnection(c.Request.Context(), id); err == nil {
+		h.recordVersion(c, updated.DiagramID, "Edited connection")
+		h.broadcastEvent(models.MessageConnectionUpdated, updated.DiagramID, *updated)
+	}
 	c.JSON(http.StatusOK, connection)
 }
 
+// endpointsInOrganization reports whether both services of a connection belong to the request's organization
+func (h *Handlers) endpointsInOrganization(c *gin.Context, connection *models.Connection) bool {
+	return h.inOrganization(c, "services", connection.SourceID) && h.inOrganization(c, "services", connection.TargetID)
+}
+
+// validateConnection rejects unknown directions and protocols. Protocols are compared in lower case.
+func validateConnection(connection *models.Connection) error {
+	if !connection.Direction.Valid() {
+		return fmt.Errorf("direction must be forward, backward, bidirectional or none")
+	}
+	connection.Protocol = strings.ToLower(strings.TrimSpace(connection.Protocol))
+	if !models.ValidConnectionProtocol(connection.Protocol) {
+		return fmt.Errorf("unknown protocol %q", connection.Protocol)
+	}
+	return nil
+}
+
 // SavePositions handles the saving of service positions for a diagram.
 func (h *Handlers) SavePositions(c *gin.Context) {
 	diagramID, err := strconv.Atoi(c.Param("id"))
@@ -336,7 +540,7 @@ func (h *Handlers) SavePositions(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.SaveServicePositions(diagramID, requestBody.Positions); err != nil {
+	if err := h.repo.SaveServicePositions(c.Request.Context(), diagramID, requestBody.Positions); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -353,7 +557,7 @@ func (h *Handlers) Login(c *gin.Context) {
 	}
 
 	// Check if this is first run (no users exist)
-	isFirstRun, err := h.repo.CheckFirstRun()
+	isFirstRun, err := h.repo.CheckFirstRun(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check system status"})
 		return
@@ -365,31 +569,52 @@ func (h *Handlers) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.repo.GetUserByUsername(req.Username)
+	user, err := h.repo.GetUserByUsername(c.Request.Context(), req.Username)
 	if err != nil {
+		h.recordSecurityEvent(c, models.SecurityLoginFailed, nil, req.Username, "unknown user")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
+	// A locked account rejects even the right password until the lockout ends
+	remaining, err := h.repo.GetLoginLockout(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check account status"})
+		return
+	}
+	if remaining > 0 {
+		h.recordSecurityEvent(c, models.SecurityLoginFailed, &user.ID, user.Username, "account locked")
+		respondLocked(c, remaining)
+		return
+	}
+
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		h.failLogin(c, user)
 		return
 	}
+	if err := h.repo.ClearFailedLogins(c.Request.Context(), user.ID); err != nil {
+		log.Printf("Error clearing failed logins of user %d: %v", user.ID, err)
+	}
 
-	var token string
-	// Check if remember me is requested
+	// Remember me keeps the session renewable for longer
+	sessionTTL := middleware.RefreshTokenTTL
 	if req.RememberMe {
-		token, err = middleware.GenerateRefreshToken(*user)
-	} else {
-		token, err = middleware.GenerateJWT(*user)
+		sessionTTL = middleware.RememberMeTTL
 	}
 
+	token, refreshToken, err := h.issueTokens(c.Request.Context(), *user, sessionTTL, sessionClient(c, req.Device))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
+	h.recordSecurityEvent(c, models.SecurityLoginSucceeded, &user.ID, user.Username, "")
 
-	c.JSON(http.StatusOK, models.LoginResponse{Token: token, User: *user})
+	c.JSON(http.StatusOK, models.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(middleware.AccessTokenTTL.Seconds()),
+		User:         *user,
+	})
 }
 
 // FirstRunAdmin handles the first-run admin setup
@@ -401,7 +626,7 @@ func (h *Handlers) FirstRunAdmin(c *gin.Context) {
 	}
 
 	// Check if this is actually first run
-	isFirstRun, err := h.repo.CheckFirstRun()
+	isFirstRun, err := h.repo.CheckFirstRun(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check system status"})
 		return
@@ -413,23 +638,25 @@ func (h *Handlers) FirstRunAdmin(c *gin.Context) {
 	}
 
 	// Create the first admin user
-	user, err := h.repo.CreateFirstRunAdmin(req.Username, req.Password, req.Email)
+	user, err := h.repo.CreateFirstRunAdmin(c.Request.Context(), req.Username, req.Password, req.Email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create admin user"})
 		return
 	}
+	h.recordSecurityEvent(c, models.SecurityUserCreated, &user.ID, user.Username, "first-run admin")
 
-	// Generate token for the new admin
-	token, err := middleware.GenerateJWT(*user)
+	// Generate tokens for the new admin
+	token, refreshToken, err := h.issueTokens(c.Request.Context(), *user, middleware.RefreshTokenTTL, sessionClient(c, ""))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, models.FirstRunAdminResponse{
-		Message: "Admin user created successfully",
-		User:    *user,
-		Token:   token,
+		Message:      "Admin user created successfully",
+		User:         *user,
+		Token:        token,
+		RefreshToken: refreshToken,
 	})
 }
 
@@ -441,7 +668,7 @@ func (h *Handlers) Register(c *gin.Context) {
 	}
 
 	// Check if user already exists
-	if _, err := h.repo.GetUserByUsername(req.Username); err == nil {
+	if _, err := h.repo.GetUserByUsername(c.Request.Context(), req.Username); err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
 		return
 	}
@@ -460,7 +687,7 @@ func (h *Handlers) Register(c *gin.Context) {
 		Role:         req.Role,
 	}
 
-	if err := h.repo.CreateUser(&user); err != nil {
+	if err := h.repo.CreateUser(c.Request.Context(), &user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -470,7 +697,7 @@ func (h *Handlers) Register(c *gin.Context) {
 
 // GetUsers returns all users (admin only)
 func (h *Handlers) GetUsers(c *gin.Context) {
-	users, err := h.repo.GetUsers()
+	users, err := h.repo.GetUsers(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -503,12 +730,13 @@ func (h *Handlers) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.repo.GetUserByID(id)
+	user, err := h.repo.GetUserByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
+	previousRole := user.Role
 	user.Email = req.Email
 	user.Role = req.Role
 
@@ -522,10 +750,16 @@ func (h *Handlers) UpdateUser(c *gin.Context) {
 		user.PasswordHash = string(hashedPassword)
 	}
 
-	if err := h.repo.UpdateUser(user); err != nil {
+	if err := h.repo.UpdateUser(c.Request.Context(), user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Password != "" {
+		h.recordSecurityEvent(c, models.SecurityPasswordChanged, &user.ID, user.Username, "")
+	}
+	if user.Role != previousRole {
+		h.recordSecurityEvent(c, models.SecurityRoleChanged, &user.ID, user.Username, fmt.Sprintf("%s -> %s", previousRole, user.Role))
+	}
 
 	// Don't return the password hash
 	user.PasswordHash = ""
@@ -558,10 +792,16 @@ func (h *Handlers) DeleteUser(c *gin.Context) {
 		}
 	}
 
-	if err := h.repo.DeleteUser(id); err != nil {
+	// Security events outlive the user, so they keep the name
+	username := ""
+	if user, err := h.repo.GetUserByID(c.Request.Context(), id); err == nil {
+		username = user.Username
+	}
+	if err := h.repo.DeleteUser(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.recordSecurityEvent(c, models.SecurityUserDeleted, &id, username, "")
 
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
@@ -575,7 +815,7 @@ func (h *Handlers) CreateUser(c *gin.Context) {
 	}
 
 	// Check if user already exists
-	if _, err := h.repo.GetUserByUsername(req.Username); err == nil {
+	if _, err := h.repo.GetUserByUsername(c.Request.Context(), req.Username); err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
 		return
 	}
@@ -594,11 +834,22 @@ func (h *Handlers) CreateUser(c *gin.Context) {
 		Role:         req.Role,
 	}
 
-	if err := h.repo.CreateUser(&user); err != nil {
+	if err := h.repo.CreateUser(c.Request.Context(), &user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	// New users join the organization the admin is working in
+	role := models.OrgRoleMember
+	if user.Role == models.RoleAdmin {
+		role = models.OrgRoleAdmin
+	}
+	if err := h.repo.SetOrganizationMember(c.Request.Context(), currentOrganizationID(c), user.ID, role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordSecurityEvent(c, models.SecurityUserCreated, &user.ID, user.Username, string(user.Role))
+
 	// Don't return the password hash
 	user.PasswordHash = ""
 	c.JSON(http.StatusCreated, user)
@@ -626,7 +877,7 @@ func (h *Handlers) GetCurrentUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.repo.GetUserByID(id)
+	user, err := h.repo.GetUserByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
@@ -646,7 +897,7 @@ func (h *Handlers) UploadServiceIcon(c *gin.Context) {
 	}
 
 	// Get the service from the database
-	service, err := h.repo.GetServiceByID(serviceID)
+	service, err := h.repo.GetServiceByID(c.Request.Context(), serviceID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
 		return
@@ -691,19 +942,49 @@ func (h *Handlers) UploadServiceIcon(c *gin.Context) {
 	// Convert the processed image to base64
 	iconBase64 := "data:image/png;base64," + processedImage
 
-	// Update the service icon in the database
+	// Update the service icon; the repository moves the image to blob storage and replaces it
+	// with the URL it is served from
 	service.Icon = iconBase64
-	if err := h.repo.UpdateService(service); err != nil {
+	if err := h.repo.UpdateService(c.Request.Context(), service); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update service icon"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Icon uploaded successfully",
-		"icon":    iconBase64,
+		"icon":    service.Icon,
 	})
 }
 
+// GetServiceIcon serves the uploaded icon of a service. The icon URL in services carries the hash
+// of the image as v, so responses to it may be cached for good.
+func (h *Handlers) GetServiceIcon(c *gin.Context) {
+	serviceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	data, contentType, err := h.repo.GetServiceIcon(c.Request.Context(), serviceID)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, blobstore.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Icon not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading icon of service %d: %v", serviceID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load icon"})
+		return
+	}
+
+	if c.Query("v") != "" {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "no-cache")
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, contentType, data)
+}
+
 // processImage decodes, scales down, and encodes an image
 func (h *Handlers) processImage(fileData []byte) (string, error) {
 	// Decode the image
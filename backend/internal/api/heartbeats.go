@@ -0,0 +1,123 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"service-weaver/internal/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPingBody caps how much of a ping's body is read, like healthchecks.io
+const maxPingBody = 100 << 10
+
+// pingURL is where the job of a heartbeat service reports in, relative when PublicURL is not set
+func pingURL(key string) string {
+	return publicBaseURL() + "/ping/" + key
+}
+
+// GetServiceHeartbeat returns the ping URL of a heartbeat service and what its job last reported
+func (h *Handlers) GetServiceHeartbeat(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	hb, err := h.repo.GetHeartbeat(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Heartbeat not configured"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	hb.PingURL = pingURL(hb.PingKey)
+	c.JSON(http.StatusOK, hb)
+}
+
+// SetServiceHeartbeat sets how often the job of a service with the HEARTBEAT healthcheck method
+// runs. The first time, the service gets the ping URL its job is to call.
+func (h *Handlers) SetServiceHeartbeat(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+	var req models.HeartbeatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	service, err := h.repo.GetServiceByID(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if service.HealthcheckMethod != "HEARTBEAT" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only services with the HEARTBEAT healthcheck method are pinged"})
+		return
+	}
+
+	hb, err := h.repo.SetHeartbeat(c.Request.Context(), id, req.Period, req.Grace)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	hb.PingURL = pingURL(hb.PingKey)
+	c.JSON(http.StatusOK, hb)
+}
+
+// Ping records a signal of the job of a heartbeat service, compatible with the healthchecks.io
+// protocol so existing cron job wrappers only need the URL changed: /ping/:key reports success,
+// /ping/:key/start the start of a run, /ping/:key/fail a failure, /ping/:key/log only the body,
+// and /ping/:key/:status an exit status, failed unless 0. Any method works, and the body, such as
+// the output of the job, is kept with the signal.
+func (h *Handlers) Ping(c *gin.Context) {
+	signal := models.HeartbeatSuccess
+	var exitStatus *int
+	switch value := c.Param("signal"); value {
+	case "":
+	case "start":
+		signal = models.HeartbeatStart
+	case "fail":
+		signal = models.HeartbeatFail
+	case "log":
+		signal = models.HeartbeatLog
+	default:
+		status, err := strconv.Atoi(value)
+		if err != nil || status < 0 || status > 255 {
+			c.String(http.StatusBadRequest, "invalid signal")
+			return
+		}
+		exitStatus = &status
+		if status != 0 {
+			signal = models.HeartbeatFail
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPingBody))
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid body")
+		return
+	}
+	err = h.repo.RecordPing(c.Request.Context(), c.Param("key"), signal, exitStatus, string(body))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.String(http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, "error")
+		return
+	}
+	c.String(http.StatusOK, "OK")
+}
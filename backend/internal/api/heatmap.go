@@ -0,0 +1,130 @@
+package api
+
+import (
+	"net/http"
+	"service-weaver/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultHeatmapDays = 90
+	maxHeatmapDays     = 365
+	maxHeatmapHours    = 24 * 14
+	// heatmapDegradedFloor is the uptime below which a bucket that missed the SLO is shown as down
+	heatmapDegradedFloor = 95.0
+)
+
+// GetServiceHeatmap returns one availability bucket per day (or hour) for the trailing period,
+// the classic status-page bar. Query parameters: granularity (day|hour, default day),
+// days (default 90) or hours (default 24).
+func (h *Handlers) GetServiceHeatmap(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	granularity := models.RollupGranularity(c.DefaultQuery("granularity", string(models.RollupDaily)))
+	var count, maxCount int
+	var step time.Duration
+	var countParam string
+	switch granularity {
+	case models.RollupDaily:
+		countParam, count, maxCount, step = "days", defaultHeatmapDays, maxHeatmapDays, 24*time.Hour
+	case models.RollupHourly:
+		countParam, count, maxCount, step = "hours", 24, maxHeatmapHours, time.Hour
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be 'hour' or 'day'"})
+		return
+	}
+	if value := c.Query(countParam); value != "" {
+		count, err = strconv.Atoi(value)
+		if err != nil || count <= 0 || count > maxCount {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + countParam + ": must be between 1 and " + strconv.Itoa(maxCount)})
+			return
+		}
+	}
+
+	service, err := h.repo.GetServiceByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+
+	// Buckets are aligned to UTC midnight (or the top of the hour); the last one is still in progress
+	current := time.Now().UTC().Truncate(step)
+	from := current.Add(-time.Duration(count-1) * step)
+
+	rollups, err := h.repo.GetRollups(c.Request.Context(), id, granularity, from, current.Add(step))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Fill buckets the rollup job has not reached yet from raw results
+	since := from
+	if len(rollups) > 0 {
+		since = rollups[len(rollups)-1].BucketStart.Add(step)
+	}
+	if !since.After(current) {
+		recent, err := h.repo.GetUnrolledBuckets(c.Request.Context(), id, granularity, since)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		rollups = append(rollups, recent...)
+	}
+
+	slo := service.SLOTarget
+	if slo <= 0 {
+		slo = models.DefaultSLOTarget
+	}
+	c.JSON(http.StatusOK, buildHeatmap(id, granularity, slo, rollups, from, step, count))
+}
+
+// buildHeatmap lays rollups out on a dense grid of count buckets starting at from
+func buildHeatmap(serviceID int, granularity models.RollupGranularity, slo float64, rollups []models.HealthcheckRollup, from time.Time, step time.Duration, count int) models.Heatmap {
+	byStart := make(map[int64]models.HealthcheckRollup, len(rollups))
+	for _, ru := range rollups {
+		byStart[ru.BucketStart.UTC().Truncate(step).Unix()] = ru
+	}
+
+	heatmap := models.Heatmap{
+		ServiceID:     serviceID,
+		Granularity:   granularity,
+		SLOTarget:     slo,
+		UptimePercent: 100,
+		Buckets:       make([]models.HeatmapBucket, 0, count),
+	}
+
+	var total, up int
+	for i := 0; i < count; i++ {
+		start := from.Add(time.Duration(i) * step)
+		bucket := models.HeatmapBucket{Start: start, Status: "no_data"}
+		if ru, ok := byStart[start.Unix()]; ok && ru.TotalChecks > 0 {
+			uptime := float64(ru.UpChecks) / float64(ru.TotalChecks) * 100
+			bucket.TotalChecks = ru.TotalChecks
+			bucket.UpChecks = ru.UpChecks
+			bucket.UptimePercent = &uptime
+			switch {
+			case uptime >= slo:
+				bucket.Status = "up"
+			case uptime >= heatmapDegradedFloor:
+				bucket.Status = "degraded"
+			default:
+				bucket.Status = "down"
+			}
+			total += ru.TotalChecks
+			up += ru.UpChecks
+		}
+		heatmap.Buckets = append(heatmap.Buckets, bucket)
+	}
+
+	if total > 0 {
+		heatmap.UptimePercent = float64(up) / float64(total) * 100
+	}
+	return heatmap
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxImportItems = 5000
+
+// ImportDiagram creates a diagram from the JSON export of another one, in one transaction: either
+// the diagram, its services and its connections are all created or nothing is. Services get new
+// IDs, and the connections are re-created between them. The folder is kept when it belongs to the
+// organization, and environments when they are defined; links to child diagrams are dropped.
+func (h *Handlers) ImportDiagram(c *gin.Context) {
+	var req models.DiagramExport
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Services) > maxImportItems || len(req.Connections) > maxImportItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d services and %d connections can be imported at once", maxImportItems, maxImportItems)})
+		return
+	}
+
+	diagram := req.Diagram
+	diagram.OrganizationID = currentOrganizationID(c)
+	if diagram.FolderID != nil {
+		if folder, err := h.repo.GetFolder(c.Request.Context(), *diagram.FolderID); err != nil || folder.OrganizationID != diagram.OrganizationID {
+			diagram.FolderID = nil
+		}
+	}
+	// Environments not defined here are dropped, like folders of other organizations
+	if !h.environmentExists(c, diagram.Environment) {
+		diagram.Environment = ""
+	}
+	for i := range req.Services {
+		if !h.environmentExists(c, req.Services[i].Environment) {
+			req.Services[i].Environment = ""
+		}
+	}
+
+	// Validate everything up front so callers see all problems at once
+	var itemErrors []bulkItemError
+	ids := make(map[int]bool, len(req.Services))
+	for i := range req.Services {
+		service := &req.Services[i]
+		if err := validateServiceFields(service); err != nil {
+			itemErrors = append(itemErrors, itemError("services", i, err))
+		} else if service.ID != 0 && ids[service.ID] {
+			itemErrors = append(itemErrors, bulkItemError{List: "services", Index: i, Error: "Duplicate id"})
+		}
+		if service.ID != 0 {
+			ids[service.ID] = true
+		}
+	}
+	for i := range req.Connections {
+		connection := &req.Connections[i]
+		if err := validateConnection(connection); err != nil {
+			itemErrors = append(itemErrors, bulkItemError{List: "connections", Index: i, Error: err.Error()})
+		} else if !ids[connection.SourceID] || !ids[connection.TargetID] {
+			itemErrors = append(itemErrors, bulkItemError{List: "connections", Index: i, Error: repository.ErrUnknownEndpoint.Error()})
+		}
+	}
+	if len(itemErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "errors": itemErrors})
+		return
+	}
+
+	err := h.repo.ImportDiagram(c.Request.Context(), &diagram, req.Services, req.Connections)
+	var itemErr *repository.ImportItemError
+	if errors.As(err, &itemErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Nothing was imported",
+			"errors": []bulkItemError{{List: itemErr.List, Index: itemErr.Index, Error: itemErr.Err.Error()}},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordVersion(c, diagram.ID, "Imported diagram")
+	c.JSON(http.StatusCreated, models.DiagramExport{Diagram: diagram, Services: req.Services, Connections: req.Connections})
+}
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"service-weaver/internal/middleware"
+	"service-weaver/internal/models"
+	"service-weaver/internal/notification"
+	"service-weaver/internal/repository"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PublicURL is where users open the frontend, such as https://weaver.example.com, for the links in
+// invitation emails and the ping URLs of heartbeat services; main sets it at startup. Links are
+// never built from the Host or Origin of a request, which clients choose: without PublicURL,
+// invitations are refused and ping URLs are relative.
+var PublicURL string
+
+// publicBaseURL is where users reach Service Weaver, for links leaving the application; empty
+// when PublicURL is not set
+func publicBaseURL() string {
+	return strings.TrimSuffix(PublicURL, "/")
+}
+
+// invitationLink is the frontend page accepting an invitation with its token
+func invitationLink(token string) string {
+	return publicBaseURL() + "/accept-invitation?token=" + url.QueryEscape(token)
+}
+
+// requirePublicURL answers 409 and returns false when PublicURL is not set, as invitation links
+// could not be built
+func requirePublicURL(c *gin.Context) bool {
+	if publicBaseURL() == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "PUBLIC_URL must be set to send invitations"})
+		return false
+	}
+	return true
+}
+
+// canEmail reports whether an email channel is enabled to deliver invitations through
+func (h *Handlers) canEmail(c *gin.Context) (bool, error) {
+	channels, err := h.repo.GetEnabledNotificationChannels(c.Request.Context())
+	if err != nil {
+		return false, err
+	}
+	for _, channel := range channels {
+		if channel.Type == models.ChannelEmail {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// sendInvitation emails the invitation link to the invitee
+func (h *Handlers) sendInvitation(c *gin.Context, inv *models.Invitation, token string) {
+	organization := "Service Weaver"
+	if org, err := h.repo.GetOrganization(c.Request.Context(), inv.OrganizationID); err == nil {
+		organization = org.Name
+	}
+	h.notifier.NotifyUser(inv.Email, notification.Message{
+		Title: fmt.Sprintf("You have been invited to %s on Service Weaver", organization),
+		Body: fmt.Sprintf("You have been invited to join %s on Service Weaver as %s with the username %s.\n"+
+			"Open the link below to choose your password and activate your account. It expires on %s.\n\n%s",
+			organization, inv.Role, inv.Username, inv.ExpiresAt.Format("2006-01-02 15:04 MST"), invitationLink(token)),
+		Tags: []string{"invitation"},
+	})
+}
+
+// CreateInvitation invites a user to the current organization by email in place of creating them
+// with a password (admin only). The account is created once they accept the emailed link.
+func (h *Handlers) CreateInvitation(c *gin.Context) {
+	var req models.InvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !requirePublicURL(c) {
+		return
+	}
+	ok, err := h.canEmail(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "An enabled email notification channel is required to send invitations"})
+		return
+	}
+
+	token, hash, err := middleware.NewRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invitation token"})
+		return
+	}
+	inv := models.Invitation{
+		OrganizationID: currentOrganizationID(c),
+		Email:          req.Email,
+		Username:       req.Username,
+		Role:           req.Role,
+		InvitedBy:      currentUserID(c),
+	}
+	err = h.repo.CreateInvitation(c.Request.Context(), &inv, hash, middleware.InvitationTTL)
+	if errors.Is(err, repository.ErrUserExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username or email already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.sendInvitation(c, &inv, token)
+	h.recordSecurityEvent(c, models.SecurityUserInvited, nil, inv.Username, fmt.Sprintf("%s as %s", inv.Email, inv.Role))
+	c.JSON(http.StatusCreated, inv)
+}
+
+// GetInvitations lists the pending invitations of the current organization (admin only)
+func (h *Handlers) GetInvitations(c *gin.Context) {
+	invitations, err := h.repo.GetInvitations(c.Request.Context(), currentOrganizationID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, invitations)
+}
+
+// ResendInvitation emails a pending invitation again with a new link, valid for another full
+// period, replacing the one sent before (admin only)
+func (h *Handlers) ResendInvitation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	if !requirePublicURL(c) {
+		return
+	}
+	token, hash, err := middleware.NewRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invitation token"})
+		return
+	}
+	inv, err := h.repo.RenewInvitation(c.Request.Context(), currentOrganizationID(c), id, hash, middleware.InvitationTTL)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.sendInvitation(c, inv, token)
+	c.JSON(http.StatusOK, inv)
+}
+
+// DeleteInvitation revokes a pending invitation (admin only)
+func (h *Handlers) DeleteInvitation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	err = h.repo.DeleteInvitation(c.Request.Context(), currentOrganizationID(c), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation deleted successfully"})
+}
+
+// GetInvitation shows the invitation of ?token= to the invitee before they accept it
+func (h *Handlers) GetInvitation(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	inv, err := h.repo.GetInvitationByToken(c.Request.Context(), middleware.HashRefreshToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or expired"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	organization := ""
+	if org, err := h.repo.GetOrganization(c.Request.Context(), inv.OrganizationID); err == nil {
+		organization = org.Name
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"username":     inv.Username,
+		"email":        inv.Email,
+		"role":         inv.Role,
+		"organization": organization,
+		"expires_at":   inv.ExpiresAt,
+	})
+}
+
+// AcceptInvitation creates the invited account with the password the invitee chose. Holding the
+// emailed token verifies their address, so they are logged in right away.
+func (h *Handlers) AcceptInvitation(c *gin.Context) {
+	var req models.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	user, err := h.repo.AcceptInvitation(c.Request.Context(), middleware.HashRefreshToken(req.Token), string(hashedPassword))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or expired"})
+		return
+	}
+	if errors.Is(err, repository.ErrUserExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username or email already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordSecurityEvent(c, models.SecurityInviteAccepted, &user.ID, user.Username, "")
+
+	token, refreshToken, err := h.issueTokens(c.Request.Context(), *user, middleware.RefreshTokenTTL, sessionClient(c, ""))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusCreated, models.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(middleware.AccessTokenTTL.Seconds()),
+		User:         *user,
+	})
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"service-weaver/internal/middleware"
+	"service-weaver/internal/models"
+	"service-weaver/internal/notification"
+	"service-weaver/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// failLogin counts a wrong password and locks the account once the lockout threshold is reached,
+// telling the user by email
+func (h *Handlers) failLogin(c *gin.Context, user *models.User) {
+	locked, err := h.repo.RecordFailedLogin(c.Request.Context(), user.ID, repository.LockoutPolicy{
+		Threshold:   middleware.LockoutThreshold,
+		Window:      middleware.LockoutWindow,
+		Duration:    middleware.LockoutDuration,
+		MaxDuration: middleware.LockoutMaxDuration,
+	})
+	if err != nil {
+		log.Printf("Error recording failed login of user %d: %v", user.ID, err)
+	}
+	h.recordSecurityEvent(c, models.SecurityLoginFailed, &user.ID, user.Username, "wrong password")
+	if locked == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+	h.recordSecurityEvent(c, models.SecurityAccountLocked, &user.ID, user.Username, fmt.Sprintf("locked for %s", locked.Round(time.Second)))
+
+	h.notifier.NotifyUser(user.Email, notification.Message{
+		Title: "Your Service Weaver account has been locked",
+		Body: fmt.Sprintf("Your account %s was locked for %s after %d failed login attempts from %s.\n"+
+			"If this was not you, ask an administrator to unlock the account and change your password.",
+			user.Username, locked.Round(time.Second), middleware.LockoutThreshold, c.ClientIP()),
+		Priority: notification.PriorityHigh,
+		Tags:     []string{"security"},
+	})
+	respondLocked(c, locked)
+}
+
+// respondLocked rejects a login to a locked account, telling the client when to retry
+func respondLocked(c *gin.Context, remaining time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+	c.JSON(http.StatusLocked, gin.H{"error": fmt.Sprintf("Account locked after too many failed logins, try again in %s", remaining.Round(time.Second))})
+}
+
+// UnlockUser lifts a login lockout before it expires (admin only)
+func (h *Handlers) UnlockUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	err = h.repo.UnlockUser(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordSecurityEvent(c, models.SecurityAccountUnlocked, &id, "", "")
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+}
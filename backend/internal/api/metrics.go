@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMetricsRange = 24 * time.Hour
+	minMetricsStep      = time.Minute
+	maxMetricsBuckets   = 1000
+	targetMetricsPoints = 120
+)
+
+// GetServiceMetrics returns time-bucketed response time percentiles and status counts.
+// Query parameters: from, to (RFC3339 or Unix seconds) and step (e.g. 5m, 1h, 1d).
+func (h *Handlers) GetServiceMetrics(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	from, to, err := parseTimeRange(c, defaultMetricsRange)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Pick a step that yields a chart-friendly number of points unless one was requested
+	step := to.Sub(from) / targetMetricsPoints
+	if value := c.Query("step"); value != "" {
+		step, err = parseWindow(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if step < minMetricsStep {
+		step = minMetricsStep
+	}
+	if to.Sub(from)/step > maxMetricsBuckets {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Step too small for the requested range"})
+		return
+	}
+
+	if _, err := h.repo.GetServiceByID(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+
+	buckets, err := h.repo.GetResponseTimeMetrics(c.Request.Context(), id, from, to, step)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service_id": id,
+		"from":       from,
+		"to":         to,
+		"step":       int(step.Seconds()),
+		"buckets":    buckets,
+	})
+}
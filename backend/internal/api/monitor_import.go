@@ -0,0 +1,103 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/importers"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportUptimeKuma creates services on a diagram from the monitors of an Uptime Kuma backup,
+// mapping monitor types to healthcheck methods. Monitors without an equivalent are reported as
+// skipped; the others are all created or none are.
+func (h *Handlers) ImportUptimeKuma(c *gin.Context) {
+	var req models.UptimeKumaImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	services, skipped, err := importers.UptimeKuma(req.Backup)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.importMonitors(c, req.DiagramID, "Uptime Kuma", services, skipped)
+}
+
+// ImportUptimeRobot creates services on a diagram from the monitors of the Uptime Robot account
+// of an API key, like ImportUptimeKuma
+func (h *Handlers) ImportUptimeRobot(c *gin.Context) {
+	var req models.UptimeRobotImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Checked before calling out to Uptime Robot, and again by importMonitors
+	if !h.inOrganization(c, "diagrams", req.DiagramID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Diagram not found"})
+		return
+	}
+	services, skipped, err := importers.UptimeRobot(c.Request.Context(), req.APIKey)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	h.importMonitors(c, req.DiagramID, "Uptime Robot", services, skipped)
+}
+
+// importMonitors places the services converted from the monitors of another tool below the
+// existing nodes of the diagram and creates them in one transaction. Services failing validation
+// are skipped along with the monitors that could not be converted.
+func (h *Handlers) importMonitors(c *gin.Context, diagramID int, tool string, services []models.Service, skipped []models.SkippedMonitor) {
+	if !h.inOrganization(c, "diagrams", diagramID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Diagram not found"})
+		return
+	}
+	if len(services) > maxImportItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d services can be imported at once", maxImportItems)})
+		return
+	}
+	existing, err := h.repo.GetServices(c.Request.Context(), diagramID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	valid := make([]models.Service, 0, len(services))
+	for _, service := range services {
+		service.DiagramID = diagramID
+		if err := validateServiceFields(&service); err != nil {
+			skipped = append(skipped, models.SkippedMonitor{Name: service.Name, Reason: err.Error()})
+			continue
+		}
+		valid = append(valid, service)
+	}
+	importers.Place(valid, len(existing))
+
+	result := models.MonitorImportResult{Created: valid, Skipped: skipped}
+	if len(valid) == 0 {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	err = h.repo.CreateServices(c.Request.Context(), valid)
+	var itemErr *repository.BulkItemError
+	if errors.As(err, &itemErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "No services were imported",
+			"errors": []bulkItemError{{Index: itemErr.Index, Error: fmt.Sprintf("%s: %v", valid[itemErr.Index].Name, itemErr.Err)}},
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordVersion(c, diagramID, fmt.Sprintf("Imported %d services from %s", len(valid), tool))
+	c.JSON(http.StatusCreated, result)
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/notification"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Notification channel handlers (admin only)
+func (h *Handlers) CreateNotificationChannel(c *gin.Context) {
+	var channel models.NotificationChannel
+	if err := c.ShouldBindJSON(&channel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Reject configs that could never be delivered
+	if _, err := notification.NewSender(channel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.repo.CreateNotificationChannel(c.Request.Context(), &channel); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, channel)
+}
+
+func (h *Handlers) GetNotificationChannels(c *gin.Context) {
+	channels, err := h.repo.GetNotificationChannels(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, channels)
+}
+
+func (h *Handlers) UpdateNotificationChannel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification channel ID"})
+		return
+	}
+
+	var channel models.NotificationChannel
+	if err := c.ShouldBindJSON(&channel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := notification.NewSender(channel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	channel.ID = id
+	if err := h.repo.UpdateNotificationChannel(c.Request.Context(), &channel); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+func (h *Handlers) DeleteNotificationChannel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification channel ID"})
+		return
+	}
+
+	if err := h.repo.DeleteNotificationChannel(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification channel deleted"})
+}
+
+// TestNotificationChannel sends a test message through the channel synchronously
+func (h *Handlers) TestNotificationChannel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification channel ID"})
+		return
+	}
+
+	channel, err := h.repo.GetNotificationChannel(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification channel not found"})
+		return
+	}
+
+	if err := h.notifier.SendTest(*channel); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send test notification: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Test notification sent"})
+}
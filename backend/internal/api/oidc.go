@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"service-weaver/internal/middleware"
+	"service-weaver/internal/models"
+	"service-weaver/internal/oidc"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcCookie keeps the sealed login request between the redirect to the provider and the callback
+const oidcCookie = "sw_oidc"
+
+// GetOIDCConfig tells the login page whether single sign-on is available
+func (h *Handlers) GetOIDCConfig(c *gin.Context) {
+	if h.sso == nil {
+		c.JSON(http.StatusOK, models.OIDCConfig{Enabled: false})
+		return
+	}
+	c.JSON(http.StatusOK, models.OIDCConfig{
+		Enabled:     true,
+		DisplayName: h.sso.DisplayName(),
+		LoginURL:    "/api/auth/oidc/login",
+	})
+}
+
+// OIDCLogin redirects the browser to the identity provider
+func (h *Handlers) OIDCLogin(c *gin.Context) {
+	if h.sso == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+
+	req, err := oidc.NewAuthRequest()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	authURL, err := h.sso.AuthCodeURL(c.Request.Context(), req)
+	if err != nil {
+		log.Printf("OIDC login failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		return
+	}
+	sealed, err := req.Seal(middleware.JwtKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Lax, not Strict: the callback is a top-level navigation coming from the provider's site
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcCookie, sealed, 600, "/api/auth/oidc", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback completes the login: it verifies the provider's response, provisions or updates the
+// user and sends the browser to the frontend with a token pair in the URL fragment
+func (h *Handlers) OIDCCallback(c *gin.Context) {
+	if h.sso == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+
+	sealed, _ := c.Cookie(oidcCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcCookie, "", -1, "/api/auth/oidc", "", c.Request.TLS != nil, true)
+
+	if providerError := c.Query("error"); providerError != "" {
+		h.oidcRedirect(c, url.Values{"error": {strings.TrimSpace(providerError + " " + c.Query("error_description"))}})
+		return
+	}
+	req, err := oidc.OpenAuthRequest(sealed, middleware.JwtKey, c.Query("state"))
+	if err != nil {
+		h.oidcRedirect(c, url.Values{"error": {err.Error()}})
+		return
+	}
+
+	identity, err := h.sso.Exchange(c.Request.Context(), c.Query("code"), req)
+	if err != nil {
+		log.Printf("OIDC callback failed: %v", err)
+		h.oidcRedirect(c, url.Values{"error": {err.Error()}})
+		return
+	}
+
+	user, err := h.oidcUser(c.Request.Context(), identity)
+	if err != nil {
+		log.Printf("OIDC login of %s failed: %v", identity.Subject, err)
+		h.oidcRedirect(c, url.Values{"error": {err.Error()}})
+		return
+	}
+
+	token, refreshToken, err := h.issueTokens(c.Request.Context(), *user, middleware.RefreshTokenTTL, sessionClient(c, ""))
+	if err != nil {
+		h.oidcRedirect(c, url.Values{"error": {"Failed to generate token"}})
+		return
+	}
+	h.recordSecurityEvent(c, models.SecurityLoginSucceeded, &user.ID, user.Username, "single sign-on")
+	h.oidcRedirect(c, url.Values{
+		"token":         {token},
+		"refresh_token": {refreshToken},
+		"expires_in":    {strconv.Itoa(int(middleware.AccessTokenTTL.Seconds()))},
+	})
+}
+
+// oidcUser finds, links or provisions the user of an identity and applies the mapped role
+func (h *Handlers) oidcUser(ctx context.Context, identity *oidc.Identity) (*models.User, error) {
+	issuer := h.sso.Issuer()
+	user, err := h.repo.GetUserByIdentity(ctx, issuer, identity.Subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, lookupErr := h.repo.GetUserByEmail(ctx, identity.Email)
+		switch {
+		case lookupErr == nil && h.sso.LinkExistingUsers() && identity.EmailVerified:
+			if err := h.repo.LinkUserIdentity(ctx, existing.ID, issuer, identity.Subject); err != nil {
+				return nil, err
+			}
+			user, err = existing, nil
+		case lookupErr == nil:
+			return nil, errors.New("an account with this email address already exists")
+		case errors.Is(lookupErr, sql.ErrNoRows):
+			user = &models.User{Username: identity.Username, Email: identity.Email, Role: identity.Role}
+			if err := h.repo.CreateIdentityUser(ctx, user, issuer, identity.Subject); err != nil {
+				return nil, err
+			}
+			return user, nil
+		default:
+			return nil, lookupErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if identity.SyncRole && user.Role != identity.Role {
+		if err := h.repo.UpdateUserRole(ctx, user.ID, identity.Role); err != nil {
+			return nil, err
+		}
+		user.Role = identity.Role
+	}
+	return user, nil
+}
+
+// oidcRedirect sends the browser back to the frontend with the result in the URL fragment, which
+// never reaches server logs
+func (h *Handlers) oidcRedirect(c *gin.Context, result url.Values) {
+	c.Redirect(http.StatusFound, h.sso.PostLoginURL()+"#"+result.Encode())
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentOrganizationID returns the organization the request acts in, set by the Organization middleware
+func currentOrganizationID(c *gin.Context) int {
+	return c.GetInt("organization_id")
+}
+
+// inOrganization reports whether an entity referenced by a request body belongs to the request's
+// organization. Missing entities and lookup failures count as outside it.
+func (h *Handlers) inOrganization(c *gin.Context, entityType string, id int) bool {
+	orgID, err := h.repo.GetEntityOrganization(c.Request.Context(), entityType, id)
+	return err == nil && orgID == currentOrganizationID(c)
+}
+
+// ownerInOrganization reports whether the owner a request assigns, if any, may act in the
+// request's organization. Lookup failures count as outside it.
+func (h *Handlers) ownerInOrganization(c *gin.Context, ownerUserID *int) bool {
+	if ownerUserID == nil {
+		return true
+	}
+	_, err := h.repo.GetOrganizationRole(c.Request.Context(), *ownerUserID, currentOrganizationID(c))
+	return err == nil
+}
+
+// GetOrganizations lists the organizations of the current user with their role in each
+func (h *Handlers) GetOrganizations(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	orgs, err := h.repo.GetOrganizations(c.Request.Context(), *userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, orgs)
+}
+
+// CreateOrganization creates an organization with the current user as its admin (platform admin only)
+func (h *Handlers) CreateOrganization(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var org models.Organization
+	if err := c.ShouldBindJSON(&org); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.repo.CreateOrganization(c.Request.Context(), &org, *userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, org)
+}
+
+// UpdateOrganization renames an organization (organization admin only)
+func (h *Handlers) UpdateOrganization(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var org models.Organization
+	if err := c.ShouldBindJSON(&org); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org.ID = id
+	err = h.repo.UpdateOrganization(c.Request.Context(), &org)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// DeleteOrganization removes an organization without diagrams or folders (platform admin only)
+func (h *Handlers) DeleteOrganization(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	err = h.repo.DeleteOrganization(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrOrganizationNotEmpty) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Organization deleted"})
+}
+
+// GetOrganizationMembers lists the members of an organization the current user belongs to
+func (h *Handlers) GetOrganizationMembers(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	members, err := h.repo.GetOrganizationMembers(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// SetOrganizationMember adds a user to an organization or changes their role (organization admin only)
+func (h *Handlers) SetOrganizationMember(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.OrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.repo.GetUserByID(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	err = h.repo.SetOrganizationMember(c.Request.Context(), id, userID, req.Role)
+	if errors.Is(err, repository.ErrLastOrganizationAdmin) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	members, err := h.repo.GetOrganizationMembers(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, m := range members {
+		if m.UserID == userID {
+			c.JSON(http.StatusOK, m)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+}
+
+// RemoveOrganizationMember removes a user from an organization (organization admin only)
+func (h *Handlers) RemoveOrganizationMember(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	err = h.repo.RemoveOrganizationMember(c.Request.Context(), id, userID)
+	if errors.Is(err, repository.ErrLastOrganizationAdmin) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// inEnvironment returns the services of a diagram as an environment sees them: those in the
+// environment, with the overlays for it standing in for their services at their nodes. Connections
+// are rewired to the overlays, and those to services left out are dropped. An empty environment
+// leaves the services and connections as they are.
+func (h *Handlers) inEnvironment(ctx context.Context, diagram *models.Diagram, services []models.Service, connections []models.Connection, environment string) ([]models.Service, []models.Connection, error) {
+	if environment == "" {
+		return services, connections, nil
+	}
+	overlays, err := h.repo.GetOverlays(ctx, diagram.ID, environment)
+	if err != nil {
+		return nil, nil, err
+	}
+	overlayOf := make(map[int]models.Service, len(overlays))
+	for _, o := range overlays {
+		overlayOf[*o.OverlayOf] = o
+	}
+
+	ids := make(map[int]int, len(services))
+	var included []models.Service
+	for _, s := range services {
+		if o, ok := overlayOf[s.ID]; ok {
+			o.Icon, o.IconKey, o.PositionX, o.PositionY = s.Icon, s.IconKey, s.PositionX, s.PositionY
+			s = o
+		} else if models.EnvironmentOf(s, diagram) != environment {
+			continue
+		}
+		ids[s.ID] = s.ID
+		if s.OverlayOf != nil {
+			ids[*s.OverlayOf] = s.ID
+		}
+		included = append(included, s)
+	}
+
+	var rewired []models.Connection
+	for _, conn := range connections {
+		source, sourceOK := ids[conn.SourceID]
+		target, targetOK := ids[conn.TargetID]
+		if !sourceOK || !targetOK {
+			continue
+		}
+		conn.SourceID, conn.TargetID = source, target
+		rewired = append(rewired, conn)
+	}
+	return included, rewired, nil
+}
+
+// overlayUptime adds the uptime of the overlays among services to the uptime of a diagram's nodes,
+// which leaves them out
+func (h *Handlers) overlayUptime(ctx context.Context, services []models.Service, uptimes []models.UptimeSummary, from, to time.Time) ([]models.UptimeSummary, error) {
+	for _, s := range services {
+		if s.OverlayOf == nil {
+			continue
+		}
+		u, err := h.repo.GetServiceUptime(ctx, s.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		uptimes = append(uptimes, *u)
+	}
+	return uptimes, nil
+}
+
+// serviceForOverlay loads the service an overlay request is for, responding with an error and
+// returning nil when it does not exist or is an overlay itself
+func (h *Handlers) serviceForOverlay(c *gin.Context) *models.Service {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return nil
+	}
+	service, err := h.repo.GetServiceByID(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil
+	}
+	if service.OverlayOf != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": repository.ErrServiceIsOverlay.Error()})
+		return nil
+	}
+	return service
+}
+
+// GetServiceOverlays lists the environment overlays of a service
+func (h *Handlers) GetServiceOverlays(c *gin.Context) {
+	service := h.serviceForOverlay(c)
+	if service == nil {
+		return
+	}
+	overlays, err := h.repo.GetServiceOverlays(c.Request.Context(), service.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, overlays)
+}
+
+// GetDiagramOverlays lists the environment overlays of a diagram's services, ?environment= those
+// for one environment
+func (h *Handlers) GetDiagramOverlays(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+	overlays, err := h.repo.GetOverlays(c.Request.Context(), id, c.Query("environment"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, overlays)
+}
+
+// SetServiceOverlay creates or replaces the overlay of a service for the :environment: the host,
+// port, healthcheck URL and credentials it is checked with there. The overlay shares everything
+// else with the service, which it follows on every change, and is checked and alerted on as a
+// service of its own in that environment.
+func (h *Handlers) SetServiceOverlay(c *gin.Context) {
+	service := h.serviceForOverlay(c)
+	if service == nil {
+		return
+	}
+	var request models.ServiceOverlayRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	environment := c.Param("environment")
+	if _, err := h.repo.GetEnvironmentByName(ctx, environment); errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown environment"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if service.ChildDiagramID != nil || slices.Contains([]string{"HEARTBEAT", "SCRIPT"}, service.HealthcheckMethod) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Composite, heartbeat and script services have no overlays"})
+		return
+	}
+	diagram, err := h.repo.GetDiagram(ctx, service.DiagramID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if models.EnvironmentOf(*service, diagram) == environment {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The service is already in %s", environment)})
+		return
+	}
+	// The overlay is checked with the service's settings at its own address
+	checked := *service
+	checked.Host, checked.Port, checked.HealthcheckURL = request.Host, request.Port, request.HealthcheckURL
+	if err := validateHealthcheck(&checked); err != nil {
+		c.JSON(http.StatusBadRequest, validationResponse(err))
+		return
+	}
+
+	overlay, created, err := h.repo.SetServiceOverlay(ctx, service.ID, environment, request)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+	if errors.Is(err, repository.ErrServiceIsOverlay) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, overlay)
+}
+
+// DeleteServiceOverlay deletes the overlay of a service for the :environment, with its history
+func (h *Handlers) DeleteServiceOverlay(c *gin.Context) {
+	service := h.serviceForOverlay(c)
+	if service == nil {
+		return
+	}
+
+	err := h.repo.DeleteServiceOverlay(c.Request.Context(), service.ID, c.Param("environment"))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Overlay not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Overlay deleted"})
+}
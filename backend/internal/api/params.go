@@ -0,0 +1,175 @@
+package api
+
+import (
+	"fmt"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseWindow parses durations such as "30d", "2w" or any value accepted by time.ParseDuration
+func parseWindow(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, fmt.Errorf("window is required")
+	}
+
+	var d time.Duration
+	var err error
+	switch {
+	case strings.HasSuffix(value, "d"), strings.HasSuffix(value, "w"):
+		unit := 24 * time.Hour
+		if strings.HasSuffix(value, "w") {
+			unit = 7 * 24 * time.Hour
+		}
+		var n int
+		n, err = strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(value, "d"), "w"))
+		d = time.Duration(n) * unit
+	default:
+		d, err = time.ParseDuration(value)
+	}
+
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q", value)
+	}
+	return d, nil
+}
+
+// parseTime accepts RFC3339 timestamps or Unix seconds
+func parseTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", value)
+	}
+	return t, nil
+}
+
+// parseTimeRange reads ?from= and ?to=, defaulting to the trailing defaultWindow ending now
+func parseTimeRange(c *gin.Context, defaultWindow time.Duration) (time.Time, time.Time, error) {
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		t, err := parseTime(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultWindow)
+	if value := c.Query("from"); value != "" {
+		t, err := parseTime(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		from = t
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+	return from, to, nil
+}
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// parsePagination reads ?limit= and ?offset=, clamping limit to maxPageSize
+func parsePagination(c *gin.Context) (int, int, error) {
+	limit := defaultPageSize
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return 0, 0, fmt.Errorf("invalid limit %q", value)
+		}
+		limit = n
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	offset := 0
+	if value := c.Query("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("invalid offset %q", value)
+		}
+		offset = n
+	}
+	return limit, offset, nil
+}
+
+// parseList splits a comma separated query parameter, dropping empty entries
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseStatusFilter reads a comma separated ?status= filter of healthcheck result statuses
+func parseStatusFilter(value string) ([]string, error) {
+	statuses := parseList(value)
+	for _, status := range statuses {
+		switch models.ServiceStatus(status) {
+		case models.StatusAlive, models.StatusDead, models.StatusDegraded, models.StatusUnknown:
+		default:
+			return nil, fmt.Errorf("invalid status filter %q", status)
+		}
+	}
+	return statuses, nil
+}
+
+// parseOwnerFilter reads ?owner_user_id= (an ID, or "me" for the authenticated user) and ?owner_team=
+func parseOwnerFilter(c *gin.Context) (int, string, error) {
+	team := strings.TrimSpace(c.Query("owner_team"))
+	switch value := c.Query("owner_user_id"); value {
+	case "":
+		return 0, team, nil
+	case "me":
+		id := currentUserID(c)
+		if id == nil {
+			return 0, "", fmt.Errorf("owner_user_id=me requires authentication")
+		}
+		return *id, team, nil
+	default:
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			return 0, "", fmt.Errorf("invalid owner_user_id %q", value)
+		}
+		return id, team, nil
+	}
+}
+
+// parseListOptions reads ?limit=, ?offset=, ?sort= and ?order=asc|desc for list endpoints.
+// Unlike parsePagination, omitting limit returns the full list so existing clients keep working.
+func parseListOptions(c *gin.Context) (repository.ListOptions, error) {
+	var opts repository.ListOptions
+	if c.Query("limit") != "" || c.Query("offset") != "" {
+		limit, offset, err := parsePagination(c)
+		if err != nil {
+			return opts, err
+		}
+		opts.Limit, opts.Offset = limit, offset
+	}
+
+	opts.Sort = c.Query("sort")
+	switch strings.ToLower(c.DefaultQuery("order", "asc")) {
+	case "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		return opts, fmt.Errorf("invalid order %q: must be asc or desc", c.Query("order"))
+	}
+	return opts, nil
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"service-weaver/internal/models"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPatchBody caps the size of a merge patch
+const maxPatchBody = 1 << 20
+
+// mergePatch applies a JSON Merge Patch (RFC 7396) to target: members of an object patch replace
+// those of the target, recursively for objects, and null members remove them. Any other patch
+// replaces the target as a whole.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
+
+// patch applies the merge patch in the request body to current, the stored state of the entity,
+// and hands the result to update as the body of a full replacement, so partial updates go through
+// the same validation as PUT. adjust, if set, may amend the result knowing the changes.
+func patch(c *gin.Context, current interface{}, adjust func(changes, merged map[string]interface{}), update gin.HandlerFunc) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPatchBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid body"})
+		return
+	}
+	var changes interface{}
+	if err := json.Unmarshal(body, &changes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	changed, ok := changes.(map[string]interface{})
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The patch must be a JSON object"})
+		return
+	}
+
+	stored, err := json.Marshal(current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var target interface{}
+	if err := json.Unmarshal(stored, &target); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	result := mergePatch(target, changed).(map[string]interface{})
+	if adjust != nil {
+		adjust(changed, result)
+	}
+	merged, err := json.Marshal(result)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(merged))
+	c.Request.ContentLength = int64(len(merged))
+	update(c)
+}
+
+// PatchService changes only the fields of a service present in the body, a JSON Merge Patch: null
+// clears a field, and objects such as headers are merged key by key. Credentials are kept unless
+// the patch sets them; {} removes them.
+func (h *Handlers) PatchService(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+	service, err := h.repo.GetServiceByID(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	patch(c, service, overridePatched, h.UpdateService)
+}
+
+// overridePatched adds the fields a service takes from its diagram's defaults that a patch sets to
+// the service's overrides, so the new values hold against the defaults, unless the patch sets the
+// overrides itself
+func overridePatched(changes, merged map[string]interface{}) {
+	if _, ok := changes["overrides"]; ok {
+		return
+	}
+	overrides, _ := merged["overrides"].([]interface{})
+	for _, field := range models.ServiceDefaultFields {
+		if _, ok := changes[field]; ok && !slices.Contains(overrides, interface{}(field)) {
+			overrides = append(overrides, field)
+		}
+	}
+	merged["overrides"] = overrides
+}
+
+// PatchDiagram changes only the fields of a diagram present in the body, a JSON Merge Patch
+func (h *Handlers) PatchDiagram(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+	diagram, err := h.repo.GetDiagram(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Diagram not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	patch(c, diagram, nil, h.UpdateDiagram)
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/middleware"
+	"service-weaver/internal/models"
+	"service-weaver/internal/notification"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// UpdateProfile lets the current user change their own email address
+func (h *Handlers) UpdateProfile(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.repo.GetUserByID(c.Request.Context(), *userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	existing, err := h.repo.GetUserByEmail(c.Request.Context(), req.Email)
+	if err == nil && existing.ID != user.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already in use"})
+		return
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.repo.UpdateUserEmail(c.Request.Context(), user.ID, req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	user.Email = req.Email
+	user.PasswordHash = ""
+	c.JSON(http.StatusOK, user)
+}
+
+// ChangePassword lets the current user change their own password after confirming the current
+// one. Every session of the user is logged out, and the response carries a fresh token pair for
+// the client that made the change.
+func (h *Handlers) ChangePassword(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.repo.GetUserByID(c.Request.Context(), *userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		h.recordSecurityEvent(c, models.SecurityLoginFailed, &user.ID, user.Username, "wrong current password on password change")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	if err := h.repo.UpdateUserPassword(c.Request.Context(), user.ID, string(hashedPassword)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordSecurityEvent(c, models.SecurityPasswordChanged, &user.ID, user.Username, "")
+
+	// Whoever knew the old password may still hold a session
+	if err := h.repo.RevokeUserTokens(c.Request.Context(), user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	token, refreshToken, err := h.issueTokens(c.Request.Context(), *user, middleware.RefreshTokenTTL, sessionClient(c, ""))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	user.PasswordHash = ""
+	c.JSON(http.StatusOK, models.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(middleware.AccessTokenTTL.Seconds()),
+		User:         *user,
+	})
+}
+
+// GetNotificationPreferences returns how the alerts of the services the current user owns reach
+// them personally
+func (h *Handlers) GetNotificationPreferences(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	prefs, err := h.repo.GetNotificationPreferences(c.Request.Context(), *userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdateNotificationPreferences replaces the notification preferences of the current user
+func (h *Handlers) UpdateNotificationPreferences(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var prefs models.NotificationPreferences
+	if err := c.ShouldBindJSON(&prefs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateNotificationPreferences(&prefs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.repo.SetNotificationPreferences(c.Request.Context(), *userID, &prefs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// validateNotificationPreferences checks what binding cannot: the settings each personal channel
+// needs, the quiet hours and the time zone, which defaults to UTC
+func validateNotificationPreferences(prefs *models.NotificationPreferences) error {
+	if prefs.Channels == nil {
+		prefs.Channels = []models.NotificationChannelType{}
+	}
+	for _, channel := range prefs.Channels {
+		if channel == models.ChannelSlack && prefs.SlackWebhookURL == "" {
+			return errors.New("slack_webhook_url is required to be alerted on Slack")
+		}
+		if channel == models.ChannelNtfy && prefs.NtfyTopic == "" {
+			return errors.New("ntfy_topic is required to be alerted on ntfy")
+		}
+	}
+
+	if (prefs.QuietHoursStart == "") != (prefs.QuietHoursEnd == "") {
+		return errors.New("quiet_hours_start and quiet_hours_end must be set together")
+	}
+	if prefs.QuietHoursStart != "" {
+		if _, err := notification.ParseClock(prefs.QuietHoursStart); err != nil {
+			return fmt.Errorf("quiet_hours_start: %w", err)
+		}
+		if _, err := notification.ParseClock(prefs.QuietHoursEnd); err != nil {
+			return fmt.Errorf("quiet_hours_end: %w", err)
+		}
+	}
+
+	if prefs.Timezone == "" {
+		prefs.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(prefs.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", prefs.Timezone)
+	}
+	return nil
+}
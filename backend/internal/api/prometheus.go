@@ -0,0 +1,190 @@
+package api
+
+import (
+	"net/http"
+	"service-weaver/internal/metrics"
+	"service-weaver/internal/models"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusValues are exported as one-hot sw_service_status series so every state can be alerted on
+var statusValues = []models.ServiceStatus{
+	models.StatusAlive,
+	models.StatusDegraded,
+	models.StatusDead,
+	models.StatusUnknown,
+	models.StatusChecking,
+}
+
+// Metrics exposes service statuses and scheduler internals in the Prometheus text format
+func (h *Handlers) Metrics(c *gin.Context) {
+	services, err := h.repo.GetAllServices(c.Request.Context())
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to load services: %v", err)
+		return
+	}
+	diagrams, err := h.repo.GetDiagrams(c.Request.Context())
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to load diagrams: %v", err)
+		return
+	}
+	diagramNames := make(map[int]string, len(diagrams))
+	for _, d := range diagrams {
+		diagramNames[d.ID] = d.Name
+	}
+	lastResults := h.scheduler.LastResults()
+	stats := h.scheduler.Stats()
+
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", metrics.ContentType)
+	w := metrics.NewWriter(c.Writer)
+
+	labelsFor := func(s models.Service) metrics.Labels {
+		return metrics.Labels{
+			"service_id": strconv.Itoa(s.ID),
+			"service":    s.Name,
+			"diagram":    diagramNames[s.DiagramID],
+			"type":       s.HealthcheckMethod,
+		}
+	}
+
+	w.Family("sw_service_up", "Whether the service is up (1 when alive or degraded, 0 otherwise).", "gauge")
+	for _, s := range services {
+		up := 0.0
+		if s.CurrentStatus == models.StatusAlive || s.CurrentStatus == models.StatusDegraded {
+			up = 1
+		}
+		w.Sample("sw_service_up", labelsFor(s), up)
+	}
+
+	w.Family("sw_service_status", "Current status of the service, one series per possible status.", "gauge")
+	for _, s := range services {
+		for _, status := range statusValues {
+			labels := labelsFor(s)
+			labels["status"] = string(status)
+			value := 0.0
+			if s.CurrentStatus == status {
+				value = 1
+			}
+			w.Sample("sw_service_status", labels, value)
+		}
+	}
+
+	w.Family("sw_service_response_time_ms", "Response time of the most recent healthcheck in milliseconds.", "gauge")
+	for _, s := range services {
+		if last, ok := lastResults[s.ID]; ok {
+			w.Sample("sw_service_response_time_ms", labelsFor(s), float64(last.ResponseTime))
+		}
+	}
+
+	w.Family("sw_service_last_checked_timestamp_seconds", "Unix time of the most recent healthcheck.", "gauge")
+	for _, s := range services {
+		if s.LastChecked != nil {
+			w.Sample("sw_service_last_checked_timestamp_seconds", labelsFor(s), float64(s.LastChecked.Unix()))
+		}
+	}
+
+	w.Family("sw_scheduler_checks_total", "Healthchecks executed since startup by resulting status.", "counter")
+	for _, status := range statusValues {
+		w.Sample("sw_scheduler_checks_total", metrics.Labels{"status": string(status)}, float64(stats.ChecksExecuted[status]))
+	}
+
+	w.Family("sw_scheduler_checks_in_flight", "Healthchecks currently executing.", "gauge")
+	w.Sample("sw_scheduler_checks_in_flight", nil, float64(stats.ChecksInFlight))
+
+	methods := make([]string, 0, len(stats.Methods))
+	for method := range stats.Methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	w.Family("sw_scheduler_check_duration_seconds", "Time healthchecks took by method.", "histogram")
+	for _, method := range methods {
+		m := stats.Methods[method]
+		for _, bucket := range m.Buckets {
+			labels := metrics.Labels{"method": method, "le": strconv.FormatFloat(bucket.UpperBound, 'g', -1, 64)}
+			w.Sample("sw_scheduler_check_duration_seconds_bucket", labels, float64(bucket.Count))
+		}
+		w.Sample("sw_scheduler_check_duration_seconds_bucket", metrics.Labels{"method": method, "le": "+Inf"}, float64(m.Checks))
+		w.Sample("sw_scheduler_check_duration_seconds_sum", metrics.Labels{"method": method}, m.DurationSeconds)
+		w.Sample("sw_scheduler_check_duration_seconds_count", metrics.Labels{"method": method}, float64(m.Checks))
+	}
+
+	w.Family("sw_scheduler_check_failures_total", "Healthchecks that found the service dead, by method.", "counter")
+	for _, method := range methods {
+		w.Sample("sw_scheduler_check_failures_total", metrics.Labels{"method": method}, float64(stats.Methods[method].Failures))
+	}
+
+	w.Family("sw_scheduler_leader", "Whether this instance runs the healthchecks (1) or stands by (0).", "gauge")
+	leader := 0.0
+	if stats.Leader {
+		leader = 1
+	}
+	w.Sample("sw_scheduler_leader", nil, leader)
+
+	w.Family("sw_scheduler_shards", "Instances sharing the healthchecks, 0 unless sharding.", "gauge")
+	w.Sample("sw_scheduler_shards", nil, float64(stats.Shards))
+
+	w.Family("sw_scheduler_services", "Services the scheduler checks.", "gauge")
+	w.Sample("sw_scheduler_services", nil, float64(stats.Queue.Scheduled))
+
+	w.Family("sw_scheduler_database_clients", "Clients kept open for database healthchecks.", "gauge")
+	w.Sample("sw_scheduler_database_clients", nil, float64(stats.DatabaseClients))
+
+	w.Family("sw_scheduler_workers", "Workers running healthchecks.", "gauge")
+	w.Sample("sw_scheduler_workers", nil, float64(stats.Queue.Workers))
+
+	w.Family("sw_scheduler_queue_depth", "Due healthchecks waiting for a worker or for their type's concurrency limit.", "gauge")
+	w.Sample("sw_scheduler_queue_depth", nil, float64(stats.Queue.Depth))
+
+	w.Family("sw_scheduler_queue_capacity", "Healthchecks the queue holds before due checks are postponed.", "gauge")
+	w.Sample("sw_scheduler_queue_capacity", nil, float64(stats.Queue.Capacity))
+
+	w.Family("sw_scheduler_queue_wait_seconds", "Time healthchecks spent queued before they started.", "summary")
+	w.Sample("sw_scheduler_queue_wait_seconds_sum", nil, stats.Queue.WaitSeconds)
+	w.Sample("sw_scheduler_queue_wait_seconds_count", nil, float64(stats.Queue.WaitCount))
+
+	w.Family("sw_scheduler_queue_full_total", "Due healthchecks postponed because the queue was full.", "counter")
+	w.Sample("sw_scheduler_queue_full_total", nil, float64(stats.Queue.Full))
+
+	w.Family("sw_scheduler_broadcast_dropped_total", "Status updates dropped for gRPC subscribers falling behind.", "counter")
+	w.Sample("sw_scheduler_broadcast_dropped_total", nil, float64(stats.BroadcastDropped))
+
+	w.Family("sw_websocket_clients", "Connected WebSocket clients.", "gauge")
+	w.Sample("sw_websocket_clients", nil, float64(stats.ConnectedClients))
+
+	w.Family("sw_websocket_clients_dropped_total", "WebSocket clients disconnected for falling behind.", "counter")
+	w.Sample("sw_websocket_clients_dropped_total", nil, float64(stats.ClientsDropped))
+
+	pools := h.repo.PoolStats()
+	w.Family("sw_db_pool_max_open_connections", "Maximum number of open database connections, 0 for no limit.", "gauge")
+	for name, pool := range pools {
+		w.Sample("sw_db_pool_max_open_connections", metrics.Labels{"pool": name}, float64(pool.MaxOpenConnections))
+	}
+	w.Family("sw_db_pool_connections", "Open database connections by state.", "gauge")
+	for name, pool := range pools {
+		w.Sample("sw_db_pool_connections", metrics.Labels{"pool": name, "state": "in_use"}, float64(pool.InUse))
+		w.Sample("sw_db_pool_connections", metrics.Labels{"pool": name, "state": "idle"}, float64(pool.Idle))
+	}
+	w.Family("sw_db_pool_wait_total", "Queries that waited for a free database connection.", "counter")
+	for name, pool := range pools {
+		w.Sample("sw_db_pool_wait_total", metrics.Labels{"pool": name}, float64(pool.WaitCount))
+	}
+	w.Family("sw_db_pool_wait_seconds_total", "Total time spent waiting for a free database connection.", "counter")
+	for name, pool := range pools {
+		w.Sample("sw_db_pool_wait_seconds_total", metrics.Labels{"pool": name}, pool.WaitDuration.Seconds())
+	}
+	w.Family("sw_db_pool_closed_total", "Database connections closed by the pool limits, by limit.", "counter")
+	for name, pool := range pools {
+		w.Sample("sw_db_pool_closed_total", metrics.Labels{"pool": name, "reason": "max_idle"}, float64(pool.MaxIdleClosed))
+		w.Sample("sw_db_pool_closed_total", metrics.Labels{"pool": name, "reason": "max_idle_time"}, float64(pool.MaxIdleTimeClosed))
+		w.Sample("sw_db_pool_closed_total", metrics.Labels{"pool": name, "reason": "max_lifetime"}, float64(pool.MaxLifetimeClosed))
+	}
+
+	if err := w.Flush(); err != nil {
+		c.Error(err)
+	}
+}
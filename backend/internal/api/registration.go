@@ -0,0 +1,193 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/middleware"
+	"service-weaver/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Registered nodes are laid out on a grid below the top-left corner, like discovered ones, until
+// someone moves them
+const (
+	registrationGridColumns  = 6
+	registrationGridSpacingX = 220
+	registrationGridSpacingY = 160
+)
+
+// CreateRegistrationToken creates a token deployment pipelines register their services in the
+// current organization with, limited to diagram_id when it is set (organization admins only). The
+// token is only returned in this response.
+func (h *Handlers) CreateRegistrationToken(c *gin.Context) {
+	var token models.RegistrationToken
+	if err := c.ShouldBindJSON(&token); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if token.DiagramID != nil && !h.inOrganization(c, "diagrams", *token.DiagramID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Diagram not found"})
+		return
+	}
+
+	secret, hash, err := middleware.NewRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate registration token"})
+		return
+	}
+	token.OrganizationID = currentOrganizationID(c)
+	token.CreatedBy = currentUserID(c)
+	if err := h.repo.CreateRegistrationToken(c.Request.Context(), &token, hash); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	token.Token = secret
+	c.JSON(http.StatusCreated, token)
+}
+
+// GetRegistrationTokens lists the registration tokens of the current organization, without the
+// tokens themselves (organization admins only)
+func (h *Handlers) GetRegistrationTokens(c *gin.Context) {
+	tokens, err := h.repo.GetRegistrationTokens(c.Request.Context(), currentOrganizationID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// DeleteRegistrationToken revokes a registration token (organization admins only)
+func (h *Handlers) DeleteRegistrationToken(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid registration token ID"})
+		return
+	}
+
+	err = h.repo.DeleteRegistrationToken(c.Request.Context(), currentOrganizationID(c), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registration token not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Registration token deleted"})
+}
+
+// RegisterService lets a deployment pipeline, authenticated with a registration token as bearer
+// token, upsert the node of the service it deploys: the node named like the request in the diagram
+// is created, or updated with the request's fields, and left alone when it already matches, so the
+// same request can run on every deploy. It answers 201 when the node was created.
+func (h *Handlers) RegisterService(c *gin.Context) {
+	secret, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || secret == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		return
+	}
+	token, err := h.repo.UseRegistrationToken(c.Request.Context(), middleware.HashRefreshToken(secret))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// The request acts in the token's organization
+	c.Set("organization_id", token.OrganizationID)
+
+	var req models.RegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if token.DiagramID != nil {
+		if req.DiagramID != 0 && req.DiagramID != *token.DiagramID {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("The token only registers services in diagram %d", *token.DiagramID)})
+			return
+		}
+		req.DiagramID = *token.DiagramID
+	}
+	if req.DiagramID == 0 || !h.inOrganization(c, "diagrams", req.DiagramID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Diagram not found"})
+		return
+	}
+	if !h.environmentExists(c, req.Environment) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown environment"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	nodes, err := h.repo.GetServices(ctx, req.DiagramID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	slot := len(nodes)
+	// New nodes get the same defaults the editor gives them
+	service := models.Service{
+		DiagramID:         req.DiagramID,
+		Name:              req.Name,
+		Description:       req.Description,
+		ServiceType:       req.ServiceType,
+		Icon:              req.ServiceType,
+		Host:              req.Host,
+		Port:              req.Port,
+		Tags:              req.Tags,
+		Environment:       req.Environment,
+		PositionX:         float64(100 + (slot%registrationGridColumns)*registrationGridSpacingX),
+		PositionY:         float64(100 + (slot/registrationGridColumns)*registrationGridSpacingY),
+		HealthcheckMethod: req.HealthcheckMethod,
+		HealthcheckURL:    req.HealthcheckURL,
+		UDPSendData:       req.UDPSendData,
+		PollingInterval:   req.PollingInterval,
+		RequestTimeout:    5,
+		ExpectedStatus:    200,
+		StatusMapping:     models.JSON{},
+		HTTPMethod:        "GET",
+		Headers:           models.Headers{},
+		SSLVerify:         true,
+		FollowRedirects:   true,
+		ICMPPacketCount:   3,
+		DNSQueryType:      "A",
+		SLOTarget:         models.DefaultSLOTarget,
+	}
+	if service.PollingInterval == 0 {
+		service.PollingInterval = 30
+	} else {
+		service.Overrides = []string{"polling_interval"}
+	}
+	if err := validateHealthcheck(&service); err != nil {
+		c.JSON(http.StatusBadRequest, validationResponse(err))
+		return
+	}
+
+	created, changed, err := h.repo.RegisterService(ctx, &service)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Diagram not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(serviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	switch {
+	case created:
+		status = http.StatusCreated
+		h.recordVersion(c, service.DiagramID, fmt.Sprintf("Registered service %s with token %s", service.Name, token.Name))
+		h.broadcastEvent(models.MessageServiceCreated, service.DiagramID, service)
+	case changed:
+		h.recordVersion(c, service.DiagramID, fmt.Sprintf("Updated registered service %s with token %s", service.Name, token.Name))
+		h.broadcastEvent(models.MessageServiceUpdated, service.DiagramID, service)
+	}
+	c.JSON(status, models.RegistrationResponse{Service: service, Created: created, Changed: changed})
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/reports"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Scheduled report handlers (admin only)
+func (h *Handlers) CreateReport(c *gin.Context) {
+	var report models.Report
+	if err := c.ShouldBindJSON(&report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := normalizeReport(&report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.repo.CreateReport(c.Request.Context(), &report); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
+
+func (h *Handlers) GetReports(c *gin.Context) {
+	list, err := h.repo.GetReports(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *Handlers) UpdateReport(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	var report models.Report
+	if err := c.ShouldBindJSON(&report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := normalizeReport(&report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report.ID = id
+	if err := h.repo.UpdateReport(c.Request.Context(), &report); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *Handlers) DeleteReport(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	if err := h.repo.DeleteReport(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Report deleted"})
+}
+
+// PreviewReport generates a report for the current window without delivering it
+func (h *Handlers) PreviewReport(c *gin.Context) {
+	report, ok := h.loadReport(c)
+	if !ok {
+		return
+	}
+
+	summary, err := h.reporter.Generate(*report, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") == "text" {
+		c.String(http.StatusOK, reports.Render(summary).Body)
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// SendReport delivers a report immediately without changing its schedule
+func (h *Handlers) SendReport(c *gin.Context) {
+	report, ok := h.loadReport(c)
+	if !ok {
+		return
+	}
+
+	if err := h.reporter.Send(*report, time.Now()); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Report sent"})
+}
+
+func (h *Handlers) loadReport(c *gin.Context) (*models.Report, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return nil, false
+	}
+
+	report, err := h.repo.GetReport(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return report, true
+}
+
+// normalizeReport validates the window and computes the next delivery time
+func normalizeReport(report *models.Report) error {
+	if report.WindowDays < 0 || report.WindowDays > 366 {
+		return errors.New("window_days must be between 0 and 366")
+	}
+	if report.ChannelIDs == nil {
+		report.ChannelIDs = []int64{}
+	}
+	report.NextRunAt = report.NextRun(time.Now())
+	return nil
+}
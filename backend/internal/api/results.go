@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"service-weaver/internal/repository"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetServiceResults returns a page of past healthcheck results for a service.
+// Supports ?limit=&offset= pagination, ?from=&to= time bounds and ?status=dead,degraded filters.
+func (h *Handlers) GetServiceResults(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := repository.ResultFilter{ServiceID: id, Limit: limit, Offset: offset}
+	if value := c.Query("from"); value != "" {
+		if filter.From, err = parseTime(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if filter.To, err = parseTime(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if filter.Statuses, err = parseStatusFilter(c.Query("status")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.repo.GetServiceByID(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+
+	results, total, err := h.repo.GetHealthcheckResults(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
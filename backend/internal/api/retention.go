@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetResultsStats reports the healthcheck results table size and retention settings (admin only)
+func (h *Handlers) GetResultsStats(c *gin.Context) {
+	stats, err := h.repo.GetResultsTableStats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	stats.DefaultRetention = h.pruner.RetentionDays()
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// PruneResults triggers an immediate retention cleanup (admin only)
+func (h *Handlers) PruneResults(c *gin.Context) {
+	deleted, err := h.pruner.Prune()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Healthcheck results pruned",
+		"deleted": deleted,
+	})
+}
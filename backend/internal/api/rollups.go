@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"service-weaver/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetServiceRollups returns hourly or daily rollups for long-term reporting.
+// Query parameters: granularity (hour|day, default day), from, to.
+func (h *Handlers) GetServiceRollups(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	granularity := models.RollupGranularity(c.DefaultQuery("granularity", string(models.RollupDaily)))
+	defaultRange := 90 * 24 * time.Hour
+	switch granularity {
+	case models.RollupDaily:
+	case models.RollupHourly:
+		defaultRange = 7 * 24 * time.Hour
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be 'hour' or 'day'"})
+		return
+	}
+
+	from, to, err := parseTimeRange(c, defaultRange)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rollups, err := h.repo.GetRollups(c.Request.Context(), id, granularity, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rollups)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"service-weaver/internal/rootcause"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// rootCauseLead is how long before an incident was opened the failures that led to it are looked for
+	rootCauseLead = 15 * time.Minute
+	// maxRootCauseEvents caps the status events an analysis reads
+	maxRootCauseEvents = 1000
+)
+
+// GetIncidentRootCause proposes the probable root cause of an incident: of the services of its
+// diagram that went dead or degraded from shortly before it started until it was resolved, the one
+// the connections show the others depend on and that failed first
+func (h *Handlers) GetIncidentRootCause(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid incident ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	incident, err := h.repo.GetIncident(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	analysis := models.RootCauseAnalysis{
+		DiagramID:  incident.DiagramID,
+		IncidentID: incident.ID,
+		From:       incident.StartedAt.Add(-rootCauseLead),
+		To:         time.Now(),
+	}
+	if incident.ResolvedAt != nil {
+		analysis.To = *incident.ResolvedAt
+	}
+
+	events, _, err := h.repo.GetStatusEvents(ctx, repository.EventFilter{
+		DiagramID: incident.DiagramID,
+		From:      analysis.From,
+		To:        analysis.To,
+		Limit:     maxRootCauseEvents,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var failures []rootcause.Failure
+	for _, e := range events {
+		if e.ToStatus == models.StatusDead || e.ToStatus == models.StatusDegraded {
+			failures = append(failures, rootcause.Failure{ServiceID: e.ServiceID, Status: e.ToStatus, At: e.OccurredAt})
+		}
+	}
+
+	services, err := h.repo.GetServices(ctx, incident.DiagramID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	connections, err := h.repo.GetConnections(ctx, incident.DiagramID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	analysis.Candidates = rootcause.Analyze(services, connections, failures)
+	analysis.Failed = len(analysis.Candidates)
+	if len(analysis.Candidates) > 0 {
+		analysis.RootCause = &analysis.Candidates[0]
+	}
+	c.JSON(http.StatusOK, analysis)
+}
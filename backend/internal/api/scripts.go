@@ -0,0 +1,197 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScriptInterpreters are the interpreters check scripts may use; none disables script checks
+var ScriptInterpreters []string
+
+// checkScriptFromRequest validates a check script request against the allowed interpreters
+func checkScriptFromRequest(req models.CheckScriptRequest) (models.CheckScript, error) {
+	if len(ScriptInterpreters) == 0 {
+		return models.CheckScript{}, fmt.Errorf("Script checks are disabled; allow interpreters with SCRIPT_INTERPRETERS")
+	}
+	if !slices.Contains(ScriptInterpreters, req.Interpreter) {
+		return models.CheckScript{}, fmt.Errorf("Interpreter %s is not allowed, expected one of %s", req.Interpreter, strings.Join(ScriptInterpreters, ", "))
+	}
+	return models.CheckScript{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Interpreter: req.Interpreter,
+		Content:     req.Content,
+	}, nil
+}
+
+// GetCheckScripts lists the check scripts services can run
+func (h *Handlers) GetCheckScripts(c *gin.Context) {
+	scripts, err := h.repo.GetCheckScripts(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, scripts)
+}
+
+// CreateCheckScript uploads a check script for services with the SCRIPT healthcheck method
+func (h *Handlers) CreateCheckScript(c *gin.Context) {
+	var req models.CheckScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	script, err := checkScriptFromRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	script.CreatedBy = currentUserID(c)
+
+	err = h.repo.CreateCheckScript(c.Request.Context(), &script)
+	if errors.Is(err, repository.ErrScriptNameTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, script)
+}
+
+// UpdateCheckScript replaces a check script; the services running it use the new version from
+// their next check
+func (h *Handlers) UpdateCheckScript(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid script ID"})
+		return
+	}
+	var req models.CheckScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	script, err := checkScriptFromRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	script.ID = id
+
+	err = h.repo.UpdateCheckScript(c.Request.Context(), &script)
+	if errors.Is(err, repository.ErrScriptNameTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Script not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, script)
+}
+
+// DeleteCheckScript deletes a check script no service runs
+func (h *Handlers) DeleteCheckScript(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid script ID"})
+		return
+	}
+
+	err = h.repo.DeleteCheckScript(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrScriptInUse) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Script not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Script deleted"})
+}
+
+// GetServiceScript returns the check script a service runs and its arguments
+func (h *Handlers) GetServiceScript(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	ss, err := h.repo.GetServiceScript(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Check script not set"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ss)
+}
+
+// SetServiceScript sets the check script a service with the SCRIPT healthcheck method runs, and
+// the arguments it is passed
+func (h *Handlers) SetServiceScript(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+	var req models.ServiceScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	service, err := h.repo.GetServiceByID(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if service.HealthcheckMethod != "SCRIPT" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only services with the SCRIPT healthcheck method run check scripts"})
+		return
+	}
+	if _, err := h.repo.GetCheckScript(c.Request.Context(), req.ScriptID); errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Script not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.repo.SetServiceScript(c.Request.Context(), id, req.ScriptID, req.Args); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ss, err := h.repo.GetServiceScript(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ss)
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"service-weaver/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	minSearchLength    = 2
+)
+
+// Search looks up diagrams and services by ?q= across names, descriptions, hosts and tags.
+// Results are limited to the current organization, and non-admin users only see results from
+// public diagrams.
+func (h *Handlers) Search(c *gin.Context) {
+	term := strings.TrimSpace(c.Query("q"))
+	if len([]rune(term)) < minSearchLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search term must be at least 2 characters"})
+		return
+	}
+
+	limit := defaultSearchLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	userRole, _ := c.Get("user_role")
+	results, err := h.repo.Search(c.Request.Context(), currentOrganizationID(c), term, userRole != models.RoleAdmin, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":   term,
+		"results": results,
+	})
+}
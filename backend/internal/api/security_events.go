@@ -0,0 +1,143 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordSecurityEvent stores an authentication or account event with the client of the request.
+// Failures are only logged so the request itself still succeeds.
+func (h *Handlers) recordSecurityEvent(c *gin.Context, eventType models.SecurityEventType, userID *int, username string, detail string) {
+	event := &models.SecurityEvent{
+		Type:      eventType,
+		UserID:    userID,
+		Username:  truncate(username, 255),
+		Detail:    detail,
+		IPAddress: c.ClientIP(),
+		UserAgent: truncate(c.Request.UserAgent(), 1024),
+	}
+	// Events caused by someone else, such as an admin, name them as the actor
+	if actor := currentUserID(c); actor != nil && (userID == nil || *actor != *userID) {
+		event.ActorID = actor
+	}
+	if err := h.repo.CreateSecurityEvent(c.Request.Context(), event); err != nil {
+		log.Printf("Error recording %s security event: %v", eventType, err)
+	}
+}
+
+// parseSecurityEventFilter reads the filters shared by listing and exporting security events
+func parseSecurityEventFilter(c *gin.Context) (repository.SecurityEventFilter, error) {
+	var filter repository.SecurityEventFilter
+	var err error
+	if value := c.Query("user_id"); value != "" {
+		if filter.UserID, err = strconv.Atoi(value); err != nil {
+			return filter, errors.New("Invalid user ID")
+		}
+	}
+	filter.Username = c.Query("username")
+	filter.IPAddress = c.Query("ip")
+
+	for _, value := range parseList(c.Query("type")) {
+		valid := false
+		for _, t := range models.SecurityEventTypes {
+			valid = valid || value == string(t)
+		}
+		if !valid {
+			return filter, fmt.Errorf("unknown event type %q", value)
+		}
+		filter.Types = append(filter.Types, value)
+	}
+
+	if value := c.Query("from"); value != "" {
+		if filter.From, err = parseTime(value); err != nil {
+			return filter, err
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if filter.To, err = parseTime(value); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+// GetSecurityEvents returns login and account security events, newest first (admin only).
+// Filters: ?user_id=, ?username=, ?type= (comma-separated), ?ip=, ?from=, ?to=,
+// plus ?limit=&offset= pagination.
+func (h *Handlers) GetSecurityEvents(c *gin.Context) {
+	filter, err := parseSecurityEventFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Limit, filter.Offset, err = parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, total, err := h.repo.GetSecurityEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// ExportSecurityEvents streams security events, oldest first (admin only).
+// Query parameters: the filters of GetSecurityEvents and format (csv|json).
+func (h *Handlers) ExportSecurityEvents(c *gin.Context) {
+	filter, err := parseSecurityEventFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	w, ok := startExport(c, "security-events", []string{"id", "type", "user_id", "username", "actor_id", "detail", "ip_address", "user_agent", "created_at"})
+	if !ok {
+		return
+	}
+
+	err = h.repo.StreamSecurityEvents(c.Request.Context(), filter, func(e models.SecurityEvent) error {
+		row := []string{
+			strconv.Itoa(e.ID),
+			string(e.Type),
+			formatID(e.UserID),
+			e.Username,
+			formatID(e.ActorID),
+			e.Detail,
+			e.IPAddress,
+			e.UserAgent,
+			formatTime(&e.CreatedAt),
+		}
+		return w.Write(row, e)
+	})
+	if err != nil {
+		// Headers are already sent, so the truncated body is the only signal left
+		c.Error(err)
+		return
+	}
+	if err := w.Close(); err != nil {
+		c.Error(err)
+	}
+}
+
+func formatID(id *int) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.Itoa(*id)
+}
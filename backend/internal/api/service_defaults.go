@@ -0,0 +1,62 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateServiceDefaults replaces the healthcheck settings inherited by the diagram's services.
+// Services that list a field in their overrides keep their own value for it.
+func (h *Handlers) UpdateServiceDefaults(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+
+	var defaults models.ServiceDefaults
+	if err := c.ShouldBindJSON(&defaults); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.repo.UpdateServiceDefaults(c.Request.Context(), id, defaults)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Diagram not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	diagram, err := h.repo.GetDiagram(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, diagram)
+}
+
+// validateOverrides checks that a service only overrides fields diagrams can default
+func validateOverrides(overrides []string) error {
+	for _, field := range overrides {
+		valid := false
+		for _, allowed := range models.ServiceDefaultFields {
+			if field == allowed {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("overrides: unknown field %q", field)
+		}
+	}
+	return nil
+}
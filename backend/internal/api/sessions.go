@@ -0,0 +1,156 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/middleware"
+	"service-weaver/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionClient describes the client of a request for the session list. Without a device name
+// from the client, one is derived from the user agent.
+func sessionClient(c *gin.Context, device string) models.SessionClient {
+	userAgent := c.Request.UserAgent()
+	if device == "" {
+		device = describeUserAgent(userAgent)
+	}
+	return models.SessionClient{
+		Device:    truncate(device, 255),
+		IPAddress: c.ClientIP(),
+		UserAgent: truncate(userAgent, 1024),
+	}
+}
+
+// describeUserAgent names the browser and operating system of a user agent, e.g. "Firefox on Linux"
+func describeUserAgent(userAgent string) string {
+	browser := ""
+	for _, b := range []struct{ token, name string }{
+		// Order matters: Edge and Opera also claim to be Chrome, and Chrome claims to be Safari
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"},
+		{"Safari/", "Safari"}, {"curl/", "curl"}, {"Go-http-client/", "Go client"},
+	} {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	system := ""
+	for _, o := range []struct{ token, name string }{
+		// Android and iOS user agents also mention Linux and Mac OS X
+		{"Android", "Android"}, {"iPhone", "iOS"}, {"iPad", "iPadOS"}, {"Windows", "Windows"},
+		{"Mac OS X", "macOS"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, o.token) {
+			system = o.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "Unknown device"
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// GetSessions lists the active sessions of the current user, marking the one of the request
+func (h *Handlers) GetSessions(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	h.respondSessions(c, *userID)
+}
+
+// RevokeSession logs the current user out of one of their sessions
+func (h *Handlers) RevokeSession(c *gin.Context) {
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+	h.revokeSession(c, *userID, id)
+}
+
+// GetUserSessions lists the active sessions of a user (admin only)
+func (h *Handlers) GetUserSessions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if _, err := h.repo.GetUserByID(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	h.respondSessions(c, id)
+}
+
+// RevokeUserSession ends one session of a user (admin only)
+func (h *Handlers) RevokeUserSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	sessionID, err := strconv.Atoi(c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+	h.revokeSession(c, id, sessionID)
+}
+
+func (h *Handlers) respondSessions(c *gin.Context, userID int) {
+	sessions, err := h.repo.GetSessions(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	value, _ := c.Get("token_claims")
+	if claims, ok := value.(*middleware.TokenClaims); ok && claims.SessionID != 0 {
+		for i := range sessions {
+			sessions[i].Current = sessions[i].ID == claims.SessionID
+		}
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (h *Handlers) revokeSession(c *gin.Context, userID, sessionID int) {
+	err := h.repo.RevokeSession(c.Request.Context(), userID, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordSecurityEvent(c, models.SecuritySessionRevoked, &userID, "", fmt.Sprintf("session %d", sessionID))
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
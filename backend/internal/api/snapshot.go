@@ -0,0 +1,126 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"service-weaver/internal/snapshot"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDiagramSnapshotSVG renders the diagram's topology and current statuses as an SVG image. Like
+// the diagram itself, private diagrams are only rendered for members of their organization.
+func (h *Handlers) GetDiagramSnapshotSVG(c *gin.Context) {
+	h.renderSnapshot(c, false)
+}
+
+// GetDiagramSnapshotPNG renders the diagram's topology and current statuses as a PNG image
+func (h *Handlers) GetDiagramSnapshotPNG(c *gin.Context) {
+	h.renderSnapshot(c, true)
+}
+
+func (h *Handlers) renderSnapshot(c *gin.Context, raster bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+
+	diagram := h.viewableDiagram(c, id)
+	if diagram == nil {
+		return
+	}
+	services, err := h.repo.GetServices(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	connections, err := h.repo.GetConnections(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Statuses change constantly; embedders should fetch a fresh image every time
+	c.Header("Cache-Control", "no-cache")
+	if !raster {
+		c.Data(http.StatusOK, "image/svg+xml", snapshot.SVG(diagram, services, connections))
+		return
+	}
+
+	data, err := snapshot.PNG(diagram, services, connections)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+// thumbnailFormats maps the formats of diagram thumbnails to their content types
+var thumbnailFormats = map[string]string{
+	"svg": "image/svg+xml",
+	"png": "image/png",
+}
+
+// GetDiagramThumbnail serves a small picture of the diagram's layout for the diagram list, SVG
+// unless ?format=png. Thumbnails are cached until services move or are connected differently, and
+// carry the hash of that layout as their ETag. Private diagrams only have thumbnails for members
+// of their organization.
+func (h *Handlers) GetDiagramThumbnail(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+	format := c.DefaultQuery("format", "svg")
+	contentType, ok := thumbnailFormats[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be svg or png"})
+		return
+	}
+
+	if h.viewableDiagram(c, id) == nil {
+		return
+	}
+	services, err := h.repo.GetServices(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	connections, err := h.repo.GetConnections(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	hash := snapshot.LayoutHash(services, connections)
+	etag := `"` + hash + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	data, err := h.repo.GetDiagramThumbnail(c.Request.Context(), id, format, hash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if data == nil {
+		if format == "png" {
+			data, err = snapshot.ThumbnailPNG(services, connections)
+		} else {
+			data = snapshot.ThumbnailSVG(services, connections)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := h.repo.SetDiagramThumbnail(c.Request.Context(), id, format, hash, data); err != nil {
+			log.Printf("Error caching thumbnail of diagram %d: %v", id, err)
+		}
+	}
+	c.Data(http.StatusOK, contentType, data)
+}
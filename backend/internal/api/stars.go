@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseDiagramListFilter reads ?filter=starred or ?filter=recent, which narrow a diagram list down
+// to the current user's favorites or the diagrams they viewed recently
+func parseDiagramListFilter(c *gin.Context, filter *repository.DiagramFilter) error {
+	value := c.Query("filter")
+	if value == "" {
+		return nil
+	}
+	userID := currentUserID(c)
+	if userID == nil {
+		return errors.New("filter requires a logged in user")
+	}
+	switch value {
+	case "starred":
+		filter.StarredBy = *userID
+	case "recent":
+		filter.ViewedBy = *userID
+	default:
+		return errors.New("filter must be starred or recent")
+	}
+	return nil
+}
+
+// recordDiagramView remembers that the current user, if any, opened a diagram. Failures are only
+// logged so the diagram is still shown.
+func (h *Handlers) recordDiagramView(c *gin.Context, diagramID int) {
+	userID := currentUserID(c)
+	if userID == nil {
+		return
+	}
+	if err := h.repo.RecordDiagramView(c.Request.Context(), *userID, diagramID); err != nil {
+		log.Printf("Error recording view of diagram %d: %v", diagramID, err)
+	}
+}
+
+// StarDiagram adds a diagram to the current user's favorites
+func (h *Handlers) StarDiagram(c *gin.Context) {
+	h.setDiagramStar(c, true)
+}
+
+// UnstarDiagram removes a diagram from the current user's favorites
+func (h *Handlers) UnstarDiagram(c *gin.Context) {
+	h.setDiagramStar(c, false)
+}
+
+func (h *Handlers) setDiagramStar(c *gin.Context, starred bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+	userID := currentUserID(c)
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Non-admin users only ever see public diagrams
+	diagram, err := h.repo.GetDiagram(c.Request.Context(), id)
+	userRole, _ := c.Get("user_role")
+	if err != nil || (userRole != models.RoleAdmin && !diagram.Public) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Diagram not found"})
+		return
+	}
+
+	if starred {
+		err = h.repo.StarDiagram(c.Request.Context(), *userID, id)
+	} else {
+		err = h.repo.UnstarDiagram(c.Request.Context(), *userID, id)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"diagram_id": id, "starred": starred})
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+	"service-weaver/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetServiceStats returns outage statistics (count, MTTR, MTBF, longest outage) for a service,
+// derived from its status transition log over ?window= (default 30d) or ?from=&to=
+func (h *Handlers) GetServiceStats(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	window, err := parseWindow(c.DefaultQuery("window", defaultUptimeWindow))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, to, err := parseTimeRange(c, window)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.repo.GetServiceByID(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+
+	initial, events, err := h.repo.GetStatusTimeline(c.Request.Context(), id, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats := computeOutageStats(initial, events, from, to)
+	stats.ServiceID = id
+	c.JSON(http.StatusOK, stats)
+}
+
+// computeOutageStats walks a status timeline and measures the periods spent dead. When there is no
+// status before the window, observation starts at the first event instead of at from.
+func computeOutageStats(initial models.ServiceStatus, events []models.StatusEvent, from, to time.Time) models.OutageStats {
+	stats := models.OutageStats{From: from, To: to}
+
+	status := initial
+	periodStart := from
+	if status == "" {
+		if len(events) == 0 {
+			return stats
+		}
+		status = events[0].FromStatus
+		periodStart = events[0].OccurredAt
+	}
+
+	var operating, recovered float64
+	var recoveries int
+	closePeriod := func(end time.Time, ongoing bool) {
+		duration := end.Sub(periodStart).Seconds()
+		if status != models.StatusDead {
+			operating += duration
+			return
+		}
+
+		stats.TotalDowntimeSeconds += duration
+		if duration > stats.LongestOutageSeconds {
+			start := periodStart
+			stats.LongestOutageSeconds = duration
+			stats.LongestOutageStart = &start
+		}
+		// An outage already underway at from has no observed start and so cannot count towards MTTR
+		if !ongoing && !(periodStart.Equal(from) && initial == models.StatusDead) {
+			recovered += duration
+			recoveries++
+		}
+	}
+
+	for _, event := range events {
+		if event.ToStatus == models.StatusDead && status != models.StatusDead {
+			stats.Outages++
+		}
+		if (event.ToStatus == models.StatusDead) != (status == models.StatusDead) {
+			closePeriod(event.OccurredAt, false)
+			periodStart = event.OccurredAt
+		}
+		status = event.ToStatus
+	}
+
+	end := to
+	if now := time.Now(); now.Before(end) {
+		end = now
+	}
+	stats.OngoingOutage = status == models.StatusDead
+	closePeriod(end, stats.OngoingOutage)
+
+	if recoveries > 0 {
+		stats.MTTRSeconds = recovered / float64(recoveries)
+	}
+	if stats.Outages > 0 {
+		stats.MTBFSeconds = operating / float64(stats.Outages)
+	}
+	return stats
+}
@@ -0,0 +1,308 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"regexp"
+	"service-weaver/internal/models"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var statusPageSlugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// canPublish reports whether the current user controls what a diagram shows the public, its
+// status page and incidents: its owner, admins of its organization, and admins do
+func canPublish(c *gin.Context, diagram *models.Diagram) bool {
+	if role, _ := c.Get("user_role"); role == models.RoleAdmin {
+		return true
+	}
+	if role, _ := c.Get("organization_role"); role == models.OrgRoleAdmin {
+		return true
+	}
+	userID := currentUserID(c)
+	return userID != nil && diagram.OwnerUserID != nil && *diagram.OwnerUserID == *userID
+}
+
+// mayPublish checks the diagram whose status page or incidents a request changes, responding
+// with an error and returning false when it does not exist or the user may not publish for it
+func (h *Handlers) mayPublish(c *gin.Context, id int) bool {
+	diagram, err := h.repo.GetDiagram(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Diagram not found"})
+		return false
+	}
+	if !canPublish(c, diagram) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the diagram's owner or an organization admin can change its status page and incidents"})
+		return false
+	}
+	return true
+}
+
+// UpdateStatusPage enables, disables or renames the public status page of a diagram (its owner and
+// organization admins only)
+func (h *Handlers) UpdateStatusPage(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+
+	var settings models.StatusPageSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if settings.Enabled && settings.Slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A slug is required to enable the status page"})
+		return
+	}
+	if settings.Slug != "" && !statusPageSlugPattern.MatchString(settings.Slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Slug may only contain lowercase letters, digits and dashes"})
+		return
+	}
+
+	if !h.mayPublish(c, id) {
+		return
+	}
+
+	if existing, err := h.repo.GetDiagramByStatusPageSlug(c.Request.Context(), settings.Slug); err == nil && existing.ID != id {
+		c.JSON(http.StatusConflict, gin.H{"error": "Slug is already in use"})
+		return
+	}
+
+	if err := h.repo.UpdateStatusPage(c.Request.Context(), id, settings); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetStatusPage serves the public, unauthenticated status page published under :slug.
+// ?environment= shows the diagram as an environment, such as prod, sees it, overlays included.
+func (h *Handlers) GetStatusPage(c *gin.Context) {
+	diagram, err := h.repo.GetDiagramByStatusPageSlug(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Status page not found"})
+		return
+	}
+
+	services, err := h.repo.GetServices(c.Request.Context(), diagram.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	services, _, err = h.inEnvironment(c.Request.Context(), diagram, services, nil, c.Query("environment"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	window, _ := parseWindow(defaultUptimeWindow)
+	to := time.Now()
+	uptimes, err := h.repo.GetDiagramUptime(c.Request.Context(), diagram.ID, to.Add(-window), to)
+	if err == nil {
+		uptimes, err = h.overlayUptime(c.Request.Context(), services, uptimes, to.Add(-window), to)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	uptimeByService := make(map[int]float64, len(uptimes))
+	for _, u := range uptimes {
+		u.ApplySLO(u.SLOTarget)
+		uptimeByService[u.ServiceID] = u.UptimePercent
+	}
+
+	incidents, err := h.repo.GetIncidents(c.Request.Context(), diagram.ID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if incidents == nil {
+		incidents = []models.Incident{}
+	}
+
+	page := models.StatusPage{
+		Title:         diagram.StatusPageTitle,
+		Description:   diagram.Description,
+		OverallStatus: models.StatusAlive,
+		UptimeWindow:  defaultUptimeWindow,
+		Groups:        []models.StatusPageGroup{},
+		Incidents:     incidents,
+		GeneratedAt:   to,
+	}
+	if page.Title == "" {
+		page.Title = diagram.Name
+	}
+
+	groupIndex := make(map[string]int)
+	for _, service := range services {
+		idx, ok := groupIndex[service.ServiceType]
+		if !ok {
+			idx = len(page.Groups)
+			groupIndex[service.ServiceType] = idx
+			page.Groups = append(page.Groups, models.StatusPageGroup{Name: service.ServiceType, Status: models.StatusAlive})
+		}
+
+		group := &page.Groups[idx]
+		group.Services = append(group.Services, models.StatusPageService{
+			ID:            service.ID,
+			Name:          service.Name,
+			Status:        service.CurrentStatus,
+			UptimePercent: uptimeByService[service.ID],
+			LastChecked:   service.LastChecked,
+		})
+		group.Status = models.WorseStatus(group.Status, service.CurrentStatus)
+		page.OverallStatus = models.WorseStatus(page.OverallStatus, service.CurrentStatus)
+	}
+	sort.Slice(page.Groups, func(i, j int) bool { return page.Groups[i].Name < page.Groups[j].Name })
+
+	c.JSON(http.StatusOK, page)
+}
+
+// Incident handlers; incidents show on the public status page, so only the diagram's owner and
+// organization admins post, edit and delete them
+func (h *Handlers) CreateIncident(c *gin.Context) {
+	diagramID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+
+	var incident models.Incident
+	if err := c.ShouldBindJSON(&incident); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.mayPublish(c, diagramID) {
+		return
+	}
+
+	incident.DiagramID = diagramID
+	normalizeIncident(&incident)
+
+	if err := h.repo.CreateIncident(c.Request.Context(), &incident); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, incident)
+}
+
+func (h *Handlers) GetIncidents(c *gin.Context) {
+	diagramID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+
+	incidents, err := h.repo.GetIncidents(c.Request.Context(), diagramID, c.Query("active") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, incidents)
+}
+
+func (h *Handlers) UpdateIncident(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid incident ID"})
+		return
+	}
+
+	existing, err := h.repo.GetIncident(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.mayPublish(c, existing.DiagramID) {
+		return
+	}
+
+	var incident models.Incident
+	if err := c.ShouldBindJSON(&incident); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	incident.ID = id
+	incident.DiagramID = existing.DiagramID
+	if incident.StartedAt.IsZero() {
+		incident.StartedAt = existing.StartedAt
+	}
+	if incident.ResolvedAt == nil && incident.Status == models.IncidentResolved {
+		incident.ResolvedAt = existing.ResolvedAt
+	}
+	normalizeIncident(&incident)
+
+	if err := h.repo.UpdateIncident(c.Request.Context(), &incident); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, incident)
+}
+
+func (h *Handlers) DeleteIncident(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid incident ID"})
+		return
+	}
+
+	existing, err := h.repo.GetIncident(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.mayPublish(c, existing.DiagramID) {
+		return
+	}
+
+	if err := h.repo.DeleteIncident(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Incident deleted"})
+}
+
+// normalizeIncident applies defaults and keeps resolved_at consistent with the status
+func normalizeIncident(incident *models.Incident) {
+	if incident.Status == "" {
+		incident.Status = models.IncidentInvestigating
+	}
+	if incident.Impact == "" {
+		incident.Impact = models.ImpactMinor
+	}
+	if incident.StartedAt.IsZero() {
+		incident.StartedAt = time.Now()
+	}
+
+	if incident.Status == models.IncidentResolved {
+		if incident.ResolvedAt == nil {
+			now := time.Now()
+			incident.ResolvedAt = &now
+		}
+	} else {
+		incident.ResolvedAt = nil
+	}
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"time"
+)
+
+// Store is the persistence the handlers need, implemented by *repository.Repository
+type Store interface {
+	AcceptInvitation(ctx context.Context, tokenHash, passwordHash string) (*models.User, error)
+	ApplyExternalAlerts(ctx context.Context, fingerprints []string, firing []models.ExternalAlert) error
+	ApplyTagAction(ctx context.Context, orgID int, tag string, publicOnly bool, action models.TagActionRequest) ([]models.Service, error)
+	CheckFirstRun(ctx context.Context) (bool, error)
+	ClearFailedLogins(ctx context.Context, userID int) error
+	CopyServices(ctx context.Context, ids []int, diagramID int, move, connections bool) ([]models.Service, error)
+	CreateCheckScript(ctx context.Context, script *models.CheckScript) error
+	CreateConnection(ctx context.Context, connection *models.Connection) error
+	CreateDiagram(ctx context.Context, diagram *models.Diagram) error
+	CreateDiscoverySource(ctx context.Context, source *models.DiscoverySource) error
+	CreateEnvironment(ctx context.Context, environment *models.Environment) error
+	CreateFirstRunAdmin(ctx context.Context, username, password, email string) (*models.User, error)
+	CreateFolder(ctx context.Context, folder *models.Folder) error
+	CreateIdentityUser(ctx context.Context, user *models.User, issuer, subject string) error
+	CreateIncident(ctx context.Context, incident *models.Incident) error
+	CreateInvitation(ctx context.Context, inv *models.Invitation, tokenHash string, ttl time.Duration) error
+	CreateMaintenanceWindow(ctx context.Context, window *models.MaintenanceWindow) error
+	CreateNotificationChannel(ctx context.Context, channel *models.NotificationChannel) error
+	CreateOrganization(ctx context.Context, org *models.Organization, adminID int) error
+	CreateRegistrationToken(ctx context.Context, token *models.RegistrationToken, tokenHash string) error
+	CreateReport(ctx context.Context, report *models.Report) error
+	CreateSecurityEvent(ctx context.Context, event *models.SecurityEvent) error
+	CreateService(ctx context.Context, service *models.Service) error
+	CreateServices(ctx context.Context, services []models.Service) error
+	CreateSession(ctx context.Context, userID int, tokenHash, familyID string, ttl time.Duration, client models.SessionClient) (int, error)
+	CreateUser(ctx context.Context, user *models.User) error
+	CreateWebhook(ctx context.Context, hook *models.Webhook) error
+	DeleteCheckScript(ctx context.Context, id int) error
+	DeleteConnection(ctx context.Context, id int) error
+	DeleteDiagram(ctx context.Context, id int) error
+	DeleteDiscoverySource(ctx context.Context, id int) error
+	DeleteEnvironment(ctx context.Context, id int) error
+	DeleteFolder(ctx context.Context, id int) error
+	DeleteIncident(ctx context.Context, id int) error
+	DeleteInvitation(ctx context.Context, orgID, id int) error
+	DeleteMaintenanceWindow(ctx context.Context, id int) error
+	DeleteNotificationChannel(ctx context.Context, id int) error
+	DeleteOrganization(ctx context.Context, id int) error
+	DeleteRegistrationToken(ctx context.Context, orgID, id int) error
+	DeleteReport(ctx context.Context, id int) error
+	DeleteService(ctx context.Context, id int) error
+	DeleteServiceOverlay(ctx context.Context, serviceID int, environment string) error
+	DeleteServices(ctx context.Context, ids []int) ([]models.Service, error)
+	DeleteUser(ctx context.Context, id int) error
+	DeleteWebhook(ctx context.Context, id int) error
+	GetAllServices(ctx context.Context) ([]models.Service, error)
+	GetAuditEntries(ctx context.Context, filter repository.AuditFilter) ([]models.AuditEntry, int, error)
+	GetCheckScript(ctx context.Context, id int) (*models.CheckScript, error)
+	GetCheckScripts(ctx context.Context) ([]models.CheckScript, error)
+	GetConnection(ctx context.Context, id int) (*models.Connection, error)
+	GetConnections(ctx context.Context, diagramID int) ([]models.Connection, error)
+	GetDiagram(ctx context.Context, id int) (*models.Diagram, error)
+	GetDiagramActivity(ctx context.Context, filter repository.ActivityFilter) ([]models.Activity, int, error)
+	GetDiagramByStatusPageSlug(ctx context.Context, slug string) (*models.Diagram, error)
+	GetDiagramThumbnail(ctx context.Context, diagramID int, format, layoutHash string) ([]byte, error)
+	GetDiagramUptime(ctx context.Context, diagramID int, from, to time.Time) ([]models.UptimeSummary, error)
+	GetDiagramVersion(ctx context.Context, diagramID, version int) (*models.DiagramVersion, error)
+	GetDiagramVersions(ctx context.Context, diagramID int) ([]models.DiagramVersion, error)
+	GetDiagrams(ctx context.Context) ([]models.Diagram, error)
+	GetDiscoverySource(ctx context.Context, id int) (*models.DiscoverySource, error)
+	GetDiscoverySources(ctx context.Context) ([]models.DiscoverySource, error)
+	GetEnabledNotificationChannels(ctx context.Context) ([]models.NotificationChannel, error)
+	GetEntityOrganization(ctx context.Context, entityType string, id int) (int, error)
+	GetEnvironmentByName(ctx context.Context, name string) (*models.Environment, error)
+	GetEnvironments(ctx context.Context) ([]models.Environment, error)
+	GetFolder(ctx context.Context, id int) (*models.Folder, error)
+	GetFolders(ctx context.Context, orgID int) ([]models.Folder, error)
+	GetHealthcheckResults(ctx context.Context, filter repository.ResultFilter) ([]models.HealthcheckResult, int, error)
+	GetHeartbeat(ctx context.Context, serviceID int) (*models.Heartbeat, error)
+	GetIncident(ctx context.Context, id int) (*models.Incident, error)
+	GetIncidents(ctx context.Context, diagramID int, activeOnly bool) ([]models.Incident, error)
+	GetInvitationByToken(ctx context.Context, tokenHash string) (*models.Invitation, error)
+	GetInvitations(ctx context.Context, orgID int) ([]models.Invitation, error)
+	GetLoginLockout(ctx context.Context, userID int) (time.Duration, error)
+	GetMaintenanceWindows(ctx context.Context, orgID, serviceID, diagramID int) ([]models.MaintenanceWindow, error)
+	GetNotificationChannel(ctx context.Context, id int) (*models.NotificationChannel, error)
+	GetNotificationChannels(ctx context.Context) ([]models.NotificationChannel, error)
+	GetNotificationPreferences(ctx context.Context, userID int) (*models.NotificationPreferences, error)
+	GetOrganization(ctx context.Context, id int) (*models.Organization, error)
+	GetOrganizationMembers(ctx context.Context, orgID int) ([]models.OrganizationMember, error)
+	GetOrganizationRole(ctx context.Context, userID, orgID int) (models.OrganizationRole, error)
+	GetOrganizations(ctx context.Context, userID int) ([]models.Organization, error)
+	GetOverlays(ctx context.Context, diagramID int, environment string) ([]models.Service, error)
+	GetRegistrationTokens(ctx context.Context, orgID int) ([]models.RegistrationToken, error)
+	GetReport(ctx context.Context, id int) (*models.Report, error)
+	GetReports(ctx context.Context) ([]models.Report, error)
+	GetResponseTimeMetrics(ctx context.Context, serviceID int, from, to time.Time, step time.Duration) ([]models.MetricsBucket, error)
+	GetResultsTableStats(ctx context.Context) (*models.ResultsTableStats, error)
+	GetRollups(ctx context.Context, serviceID int, granularity models.RollupGranularity, from, to time.Time) ([]models.HealthcheckRollup, error)
+	GetSecurityEvents(ctx context.Context, filter repository.SecurityEventFilter) ([]models.SecurityEvent, int, error)
+	GetServiceByID(ctx context.Context, id int) (*models.Service, error)
+	GetServiceCertificates(ctx context.Context, serviceID int) ([]models.ServiceCertificate, error)
+	GetServiceDiagramIDs(ctx context.Context, serviceIDs []int) ([]int, error)
+	GetServiceIcon(ctx context.Context, id int) ([]byte, string, error)
+	GetServiceOverlays(ctx context.Context, serviceID int) ([]models.Service, error)
+	GetServiceScript(ctx context.Context, serviceID int) (*models.ServiceScript, error)
+	GetServiceUptime(ctx context.Context, serviceID int, from, to time.Time) (*models.UptimeSummary, error)
+	GetServices(ctx context.Context, diagramID int) ([]models.Service, error)
+	GetSessions(ctx context.Context, userID int) ([]models.Session, error)
+	GetStatusEvents(ctx context.Context, filter repository.EventFilter) ([]models.StatusEvent, int, error)
+	GetStatusTimeline(ctx context.Context, serviceID int, from, to time.Time) (models.ServiceStatus, []models.StatusEvent, error)
+	GetTrash(ctx context.Context, orgID int) (*models.Trash, error)
+	GetUnrolledBuckets(ctx context.Context, serviceID int, granularity models.RollupGranularity, since time.Time) ([]models.HealthcheckRollup, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByID(ctx context.Context, id int) (*models.User, error)
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUsers(ctx context.Context) ([]models.User, error)
+	GetWebhook(ctx context.Context, id int) (*models.Webhook, error)
+	GetWebhooks(ctx context.Context) ([]models.Webhook, error)
+	ImportDiagram(ctx context.Context, diagram *models.Diagram, services []models.Service, connections []models.Connection) error
+	LinkUserIdentity(ctx context.Context, userID int, issuer, subject string) error
+	ListDiagrams(ctx context.Context, filter repository.DiagramFilter) ([]models.Diagram, int, error)
+	ListServices(ctx context.Context, filter repository.ServiceFilter) ([]models.Service, int, error)
+	ListTags(ctx context.Context, orgID int, prefix string, publicOnly bool, limit int) ([]models.TagCount, error)
+	MatchAlertServices(ctx context.Context, labels map[string]string, mapping []models.AlertLabelMapping) ([]int, error)
+	MoveDiagram(ctx context.Context, diagramID int, folderID *int) error
+	Ping(timeout time.Duration) map[string]error
+	PoolStats() map[string]sql.DBStats
+	RecordDiagramVersion(ctx context.Context, diagramID int, userID *int, summary string) (*models.DiagramVersion, error)
+	RecordDiagramView(ctx context.Context, userID, diagramID int) error
+	RecordFailedLogin(ctx context.Context, userID int, policy repository.LockoutPolicy) (time.Duration, error)
+	RecordPing(ctx context.Context, pingKey string, signal models.HeartbeatSignal, exitStatus *int, body string) error
+	RegisterService(ctx context.Context, service *models.Service) (created, changed bool, err error)
+	RemoveOrganizationMember(ctx context.Context, orgID, userID int) error
+	RenewInvitation(ctx context.Context, orgID, id int, tokenHash string, ttl time.Duration) (*models.Invitation, error)
+	RestoreBackup(ctx context.Context, src io.Reader, force bool) (*repository.BackupManifest, error)
+	RestoreDiagram(ctx context.Context, id int) error
+	RestoreService(ctx context.Context, id int) error
+	RevokeRefreshToken(ctx context.Context, userID int, tokenHash string) error
+	RevokeSession(ctx context.Context, userID, sessionID int) error
+	RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	RevokeUserTokens(ctx context.Context, userID int) error
+	RollbackDiagram(ctx context.Context, diagramID, version int, userID *int) (*models.DiagramVersion, error)
+	RotateRefreshToken(ctx context.Context, tokenHash, newTokenHash string, client models.SessionClient) (int, int, error)
+	SaveServicePositions(ctx context.Context, diagramID int, positions []models.ServicePosition) error
+	SchemaVersion(ctx context.Context) (int, error)
+	Search(ctx context.Context, orgID int, term string, publicOnly bool, limit int) ([]models.SearchResult, error)
+	SearchErrors(ctx context.Context, filter repository.ErrorFilter) ([]models.ErrorSignature, int, error)
+	SetDiagramThumbnail(ctx context.Context, diagramID int, format, layoutHash string, data []byte) error
+	SetHeartbeat(ctx context.Context, serviceID, period, grace int) (*models.Heartbeat, error)
+	SetNotificationPreferences(ctx context.Context, userID int, prefs *models.NotificationPreferences) error
+	SetOrganizationMember(ctx context.Context, orgID, userID int, role models.OrganizationRole) error
+	SetServiceOverlay(ctx context.Context, serviceID int, environment string, request models.ServiceOverlayRequest) (*models.Service, bool, error)
+	SetServiceScript(ctx context.Context, serviceID, scriptID int, args []string) error
+	SetServicesPaused(ctx context.Context, ids []int, paused bool) ([]models.Service, error)
+	StarDiagram(ctx context.Context, userID, diagramID int) error
+	StreamHealthcheckResults(ctx context.Context, filter repository.ResultFilter, fn func(models.HealthcheckResult) error) error
+	StreamSecurityEvents(ctx context.Context, filter repository.SecurityEventFilter, fn func(models.SecurityEvent) error) error
+	UnlockUser(ctx context.Context, userID int) error
+	UnstarDiagram(ctx context.Context, userID, diagramID int) error
+	UpdateCheckScript(ctx context.Context, script *models.CheckScript) error
+	UpdateConnection(ctx context.Context, connection *models.Connection) error
+	UpdateDiagram(ctx context.Context, diagram *models.Diagram) error
+	UpdateDiscoverySource(ctx context.Context, source *models.DiscoverySource) error
+	UpdateEnvironment(ctx context.Context, environment *models.Environment) error
+	UpdateFolder(ctx context.Context, folder *models.Folder) error
+	UpdateIncident(ctx context.Context, incident *models.Incident) error
+	UpdateNotificationChannel(ctx context.Context, channel *models.NotificationChannel) error
+	UpdateOrganization(ctx context.Context, org *models.Organization) error
+	UpdateReport(ctx context.Context, report *models.Report) error
+	UpdateService(ctx context.Context, service *models.Service) error
+	UpdateServiceDefaults(ctx context.Context, diagramID int, defaults models.ServiceDefaults) error
+	UpdateServices(ctx context.Context, services []models.Service) error
+	UpdateStatusPage(ctx context.Context, diagramID int, settings models.StatusPageSettings) error
+	UpdateUser(ctx context.Context, user *models.User) error
+	UpdateUserEmail(ctx context.Context, id int, email string) error
+	UpdateUserPassword(ctx context.Context, id int, passwordHash string) error
+	UpdateUserRole(ctx context.Context, id int, role models.UserRole) error
+	UpdateWebhook(ctx context.Context, hook *models.Webhook) error
+	UseRegistrationToken(ctx context.Context, tokenHash string) (*models.RegistrationToken, error)
+	WriteBackup(ctx context.Context, w io.Writer, includeSecrets bool) (*repository.BackupManifest, error)
+	IsTokenRevoked(ctx context.Context, tokenID string, userID, sessionID int, issuedAt time.Time) (bool, error)
+}
+
+var _ Store = (*repository.Repository)(nil)
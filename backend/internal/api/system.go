@@ -0,0 +1,134 @@
+package api
+
+import (
+	"net/http"
+	"runtime"
+	"service-weaver/internal/models"
+	"service-weaver/internal/monitoring"
+	"service-weaver/internal/repository"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pingTimeout bounds the database round trip of the readiness and system health checks, so a
+// hanging database fails the probe instead of stalling it
+const pingTimeout = 2 * time.Second
+
+var startedAt = time.Now()
+
+// Healthz reports that the process is up. It checks nothing else, so a liveness probe only
+// restarts a backend that stopped serving requests altogether.
+func (h *Handlers) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz reports whether the backend can serve traffic: the database answers, and the healthcheck
+// scheduler and the WebSocket hub are running. It answers 503 with the failing checks otherwise.
+func (h *Handlers) Readyz(c *gin.Context) {
+	checks := gin.H{"database": "ok", "scheduler": "ok", "websocket_hub": "ok"}
+	ready := true
+	if err := h.repo.Ping(pingTimeout)["primary"]; err != nil {
+		checks["database"] = err.Error()
+		ready = false
+	}
+	if !h.scheduler.SchedulerStatus().Running {
+		checks["scheduler"] = "stalled"
+		ready = false
+	}
+	if !h.scheduler.HubStatus().Running {
+		checks["websocket_hub"] = "stalled"
+		ready = false
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "checks": checks})
+}
+
+// GetSystemHealth reports the state of the backend and each of its components (admin only)
+func (h *Handlers) GetSystemHealth(c *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	health := models.SystemHealth{
+		Healthy:       true,
+		StartedAt:     startedAt,
+		UptimeSeconds: time.Since(startedAt).Seconds(),
+		GoVersion:     runtime.Version(),
+		Goroutines:    runtime.NumGoroutine(),
+		HeapBytes:     mem.HeapAlloc,
+	}
+
+	pools := h.repo.PoolStats()
+	pings := h.repo.Ping(pingTimeout)
+	for _, name := range []string{"primary", "replica"} {
+		pool, ok := pools[name]
+		if !ok {
+			continue
+		}
+		err := pings[name]
+		component := models.ComponentHealth{
+			Name:    "database_" + name,
+			Healthy: err == nil,
+			Details: map[string]interface{}{
+				"open_connections": pool.OpenConnections,
+				"in_use":           pool.InUse,
+				"idle":             pool.Idle,
+				"wait_count":       pool.WaitCount,
+			},
+		}
+		if err != nil {
+			component.Error = err.Error()
+		}
+		if name == "primary" && err == nil {
+			version, err := h.repo.SchemaVersion(c.Request.Context())
+			component.Details["schema_version"] = version
+			component.Details["latest_schema_version"] = repository.LatestSchemaVersion()
+			if err != nil {
+				component.Healthy, component.Error = false, err.Error()
+			}
+		}
+		health.Components = append(health.Components, component)
+	}
+
+	stats := h.scheduler.Stats()
+	scheduler := h.scheduler.SchedulerStatus()
+	hub := h.scheduler.HubStatus()
+	health.Components = append(health.Components,
+		loopComponent("scheduler", scheduler, map[string]interface{}{
+			"checks_in_flight": stats.ChecksInFlight,
+			"checks_executed":  stats.ChecksExecuted,
+			"queue":            stats.Queue,
+			"leader":           stats.Leader,
+			"shard":            stats.Shard,
+			"shards":           stats.Shards,
+		}),
+		loopComponent("websocket_hub", hub, map[string]interface{}{
+			"connected_clients": stats.ConnectedClients,
+			"broadcast_dropped": stats.BroadcastDropped,
+			"clients_dropped":   stats.ClientsDropped,
+		}),
+	)
+
+	for _, component := range health.Components {
+		health.Healthy = health.Healthy && component.Healthy
+	}
+	c.JSON(http.StatusOK, health)
+}
+
+// GetSchedulerStats returns the healthcheck scheduler's internal counters, for debugging: checks per
+// second, durations and failures by method, the worker queue and dropped broadcasts
+func (h *Handlers) GetSchedulerStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.scheduler.Stats())
+}
+
+func loopComponent(name string, status monitoring.LoopStatus, details map[string]interface{}) models.ComponentHealth {
+	details["last_heartbeat"] = status.LastHeartbeat
+	component := models.ComponentHealth{Name: name, Healthy: status.Running, Details: details}
+	if !status.Running {
+		component.Error = "stalled"
+	}
+	return component
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTagLimit = 20
+	maxTagLimit     = 100
+)
+
+// ListServices lists the services of every diagram of the current organization, filtered by
+// ?q=, ?status=, ?type=, ?tag=, ?environment= and owner like GetServices. Non-admin users only see the services of
+// public diagrams.
+func (h *Handlers) ListServices(c *gin.Context) {
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ownerUserID, ownerTeam, err := parseOwnerFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userRole, _ := c.Get("user_role")
+	services, total, err := h.repo.ListServices(c.Request.Context(), repository.ServiceFilter{
+		OrganizationID: currentOrganizationID(c),
+		PublicOnly:     userRole != models.RoleAdmin,
+		Name:           c.Query("q"),
+		Statuses:       parseList(c.Query("status")),
+		Types:          parseList(c.Query("type")),
+		Tag:            c.Query("tag"),
+		OwnerUserID:    ownerUserID,
+		OwnerTeam:      ownerTeam,
+		Environment:    c.Query("environment"),
+		ListOptions:    opts,
+	})
+	if errors.Is(err, repository.ErrInvalidSort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, services)
+}
+
+// GetTags suggests the tags of the current organization's services starting with ?q=, the most
+// used first, for autocompletion
+func (h *Handlers) GetTags(c *gin.Context) {
+	limit := defaultTagLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = min(n, maxTagLimit)
+	}
+
+	userRole, _ := c.Get("user_role")
+	tags, err := h.repo.ListTags(c.Request.Context(), currentOrganizationID(c), strings.TrimSpace(c.Query("q")), userRole != models.RoleAdmin, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+// ApplyTagAction pauses, resumes or sets the polling interval of every service carrying a tag.
+// Non-admin users only reach the services of public diagrams.
+func (h *Handlers) ApplyTagAction(c *gin.Context) {
+	tag := strings.TrimSpace(c.Param("tag"))
+	if tag == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag"})
+		return
+	}
+
+	var action models.TagActionRequest
+	if err := c.ShouldBindJSON(&action); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if action.Action == models.TagActionSetInterval && action.PollingInterval < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "polling_interval must be at least 1 second"})
+		return
+	}
+
+	userRole, _ := c.Get("user_role")
+	services, err := h.repo.ApplyTagAction(c.Request.Context(), currentOrganizationID(c), tag, userRole != models.RoleAdmin, action)
+	if errors.Is(err, repository.ErrUnknownTagAction) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be pause, resume or set_interval"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, service := range services {
+		h.broadcastEvent(models.MessageServiceUpdated, service.DiagramID, service)
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": len(services)})
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/middleware"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RefreshToken exchanges a refresh token for a new access token and a new refresh token. Each
+// refresh token is single-use; presenting one twice revokes the whole session.
+func (h *Handlers) RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refreshToken, hash, err := middleware.NewRefreshToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	userID, sessionID, err := h.repo.RotateRefreshToken(c.Request.Context(), middleware.HashRefreshToken(req.RefreshToken), hash, sessionClient(c, ""))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+	if errors.Is(err, repository.ErrRefreshTokenReused) {
+		h.recordSecurityEvent(c, models.SecurityTokenReused, &userID, "", "session revoked")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token was already used; the session has been revoked"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.repo.GetUserByID(c.Request.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := middleware.GenerateJWT(*user, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	h.recordSecurityEvent(c, models.SecurityTokenRefreshed, &user.ID, user.Username, fmt.Sprintf("session %d", sessionID))
+
+	c.JSON(http.StatusOK, models.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(middleware.AccessTokenTTL.Seconds()),
+		User:         *user,
+	})
+}
+
+// issueTokens starts a new session for the user on a client, returning an access token and the
+// first refresh token of the session, which stays renewable for sessionTTL
+func (h *Handlers) issueTokens(ctx context.Context, user models.User, sessionTTL time.Duration, client models.SessionClient) (string, string, error) {
+	refreshToken, hash, err := middleware.NewRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+	familyID, err := middleware.NewTokenFamily()
+	if err != nil {
+		return "", "", err
+	}
+	sessionID, err := h.repo.CreateSession(ctx, user.ID, hash, familyID, sessionTTL, client)
+	if err != nil {
+		return "", "", err
+	}
+	token, err := middleware.GenerateJWT(user, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+	return token, refreshToken, nil
+}
+
+// Logout revokes the access token of the request and its session, or the session of the given
+// refresh token for access tokens issued before sessions were tracked.
+// With all_sessions every token of the user is revoked.
+func (h *Handlers) Logout(c *gin.Context) {
+	var req models.LogoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userID := currentUserID(c)
+	value, _ := c.Get("token_claims")
+	claims, ok := value.(*middleware.TokenClaims)
+	if userID == nil || !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if req.AllSessions {
+		if err := h.repo.RevokeUserTokens(c.Request.Context(), *userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		h.recordSecurityEvent(c, models.SecurityLogout, userID, claims.Username, "all sessions")
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions"})
+		return
+	}
+
+	if claims.ID != "" {
+		if err := h.repo.RevokeToken(c.Request.Context(), claims.ID, claims.ExpiresAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if claims.SessionID != 0 {
+		err := h.repo.RevokeSession(c.Request.Context(), *userID, claims.SessionID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.RefreshToken != "" {
+		if err := h.repo.RevokeRefreshToken(c.Request.Context(), *userID, middleware.HashRefreshToken(req.RefreshToken)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	h.recordSecurityEvent(c, models.SecurityLogout, userID, claims.Username, "")
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// RevokeUserSessions revokes every token of a user, e.g. after their credentials leaked (admin only)
+func (h *Handlers) RevokeUserSessions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	err = h.repo.RevokeUserTokens(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordSecurityEvent(c, models.SecuritySessionRevoked, &id, "", "all sessions")
+	c.JSON(http.StatusOK, gin.H{"message": "User sessions revoked successfully"})
+}
+
+// JWKS publishes the public keys access tokens are signed with. The set is empty while tokens
+// are signed with a shared HS256 secret.
+func (h *Handlers) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, middleware.Keys.JWKS())
+}
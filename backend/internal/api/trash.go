@@ -0,0 +1,81 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/repository"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTrash lists deleted diagrams and services that can still be restored
+func (h *Handlers) GetTrash(c *gin.Context) {
+	trash, err := h.repo.GetTrash(c.Request.Context(), currentOrganizationID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, trash)
+}
+
+// RestoreDiagram brings a diagram back from the trash together with the services deleted with it
+func (h *Handlers) RestoreDiagram(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+
+	err = h.repo.RestoreDiagram(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Diagram not found in trash"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	diagram, err := h.repo.GetDiagram(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, diagram)
+}
+
+// RestoreService brings a service back from the trash. Services deleted along with their
+// diagram are restored by restoring the diagram.
+func (h *Handlers) RestoreService(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	err = h.repo.RestoreService(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found in trash"})
+		return
+	}
+	if errors.Is(err, repository.ErrDiagramInTrash) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	service, err := h.repo.GetServiceByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordVersion(c, service.DiagramID, fmt.Sprintf("Restored service %s", service.Name))
+	c.JSON(http.StatusOK, service)
+}
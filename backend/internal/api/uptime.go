@@ -0,0 +1,192 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultUptimeWindow = "30d"
+
+// parseSLOOverride reads an optional ?slo= query parameter, returning 0 when absent
+func parseSLOOverride(c *gin.Context) (float64, error) {
+	value := c.Query("slo")
+	if value == "" {
+		return 0, nil
+	}
+	slo, err := strconv.ParseFloat(value, 64)
+	if err != nil || slo <= 0 || slo > 100 {
+		return 0, fmt.Errorf("invalid slo %q", value)
+	}
+	return slo, nil
+}
+
+// GetServiceUptime returns availability and error budget for a service over ?window= (default 30d)
+func (h *Handlers) GetServiceUptime(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service ID"})
+		return
+	}
+
+	window, err := parseWindow(c.DefaultQuery("window", defaultUptimeWindow))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slo, err := parseSLOOverride(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	to := time.Now()
+	summary, err := h.repo.GetServiceUptime(c.Request.Context(), id, to.Add(-window), to)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if slo == 0 {
+		slo = summary.SLOTarget
+	}
+	summary.ApplySLO(slo)
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetDiagramUptime returns per-service availability plus an aggregate across the whole diagram
+func (h *Handlers) GetDiagramUptime(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+
+	window, err := parseWindow(c.DefaultQuery("window", defaultUptimeWindow))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slo, err := parseSLOOverride(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.repo.GetDiagram(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Diagram not found"})
+		return
+	}
+
+	to := time.Now()
+	from := to.Add(-window)
+	services, err := h.repo.GetDiagramUptime(c.Request.Context(), id, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"aggregate": aggregateUptime(services, from, to, slo),
+		"services":  services,
+	})
+}
+
+// aggregateUptime applies each service's SLO and combines them into a diagram-level summary.
+// Without an explicit target the aggregate is held to the strictest service SLO.
+func aggregateUptime(services []models.UptimeSummary, from, to time.Time, slo float64) models.UptimeSummary {
+	aggregate := models.UptimeSummary{From: from, To: to}
+	strictest := 0.0
+	for i := range services {
+		target := services[i].SLOTarget
+		if slo > 0 {
+			target = slo
+		}
+		services[i].ApplySLO(target)
+		if services[i].SLOTarget > strictest {
+			strictest = services[i].SLOTarget
+		}
+
+		aggregate.TotalChecks += services[i].TotalChecks
+		aggregate.UpChecks += services[i].UpChecks
+		aggregate.DownChecks += services[i].DownChecks
+	}
+
+	if slo == 0 {
+		slo = strictest
+	}
+	aggregate.ApplySLO(slo)
+	return aggregate
+}
+
+// Maintenance window handlers
+func (h *Handlers) CreateMaintenanceWindow(c *gin.Context) {
+	var window models.MaintenanceWindow
+	if err := c.ShouldBindJSON(&window); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if window.ServiceID == nil && window.DiagramID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either service_id or diagram_id is required"})
+		return
+	}
+	if !window.EndsAt.After(window.StartsAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be after starts_at"})
+		return
+	}
+	if window.ServiceID != nil && !h.inOrganization(c, "services", *window.ServiceID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Service not found"})
+		return
+	}
+	if window.DiagramID != nil && !h.inOrganization(c, "diagrams", *window.DiagramID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Diagram not found"})
+		return
+	}
+
+	if err := h.repo.CreateMaintenanceWindow(c.Request.Context(), &window); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, window)
+}
+
+func (h *Handlers) GetMaintenanceWindows(c *gin.Context) {
+	serviceID, _ := strconv.Atoi(c.Query("service_id"))
+	diagramID, _ := strconv.Atoi(c.Query("diagram_id"))
+
+	windows, err := h.repo.GetMaintenanceWindows(c.Request.Context(), currentOrganizationID(c), serviceID, diagramID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, windows)
+}
+
+func (h *Handlers) DeleteMaintenanceWindow(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid maintenance window ID"})
+		return
+	}
+
+	if err := h.repo.DeleteMaintenanceWindow(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Maintenance window deleted"})
+}
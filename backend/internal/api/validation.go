@@ -0,0 +1,40 @@
+package api
+
+import (
+	"errors"
+	"service-weaver/internal/models"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// healthcheckError lists the problems with the healthcheck configuration of a service, field by field
+type healthcheckError []models.FieldError
+
+func (e healthcheckError) Error() string {
+	problems := make([]string, len(e))
+	for i, problem := range e {
+		problems[i] = problem.Field + " " + problem.Message
+	}
+	return strings.Join(problems, "; ")
+}
+
+// validateHealthcheck rejects the healthcheck configurations of a service its checks would fail
+// on, such as a UDP check without data to send, which would otherwise only show once the service
+// is checked. The problems are returned as a healthcheckError.
+func validateHealthcheck(service *models.Service) error {
+	if problems := models.ValidateHealthcheck(service); len(problems) > 0 {
+		return healthcheckError(problems)
+	}
+	return nil
+}
+
+// validationResponse is the body of the 400 response to a service that failed validation: the
+// error, and the fields at fault when its healthcheck configuration is
+func validationResponse(err error) gin.H {
+	var problems healthcheckError
+	if errors.As(err, &problems) {
+		return gin.H{"error": "Invalid healthcheck configuration", "fields": []models.FieldError(problems)}
+	}
+	return gin.H{"error": err.Error()}
+}
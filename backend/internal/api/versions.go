@@ -0,0 +1,279 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"service-weaver/internal/models"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDiagramVersions lists the recorded versions of a diagram, newest first
+func (h *Handlers) GetDiagramVersions(c *gin.Context) {
+	diagramID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+
+	versions, err := h.repo.GetDiagramVersions(c.Request.Context(), diagramID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// GetDiagramVersion returns a single version with its snapshot
+func (h *Handlers) GetDiagramVersion(c *gin.Context) {
+	diagramID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+
+	version, ok := h.loadDiagramVersion(c, diagramID, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+// DiffDiagramVersions compares two versions given by ?from= and ?to=. to defaults to the latest
+// version and from to the version before it.
+func (h *Handlers) DiffDiagramVersions(c *gin.Context) {
+	diagramID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+
+	toParam := c.Query("to")
+	if toParam == "" {
+		versions, err := h.repo.GetDiagramVersions(c.Request.Context(), diagramID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(versions) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Diagram has no versions"})
+			return
+		}
+		toParam = strconv.Itoa(versions[0].Version)
+	}
+	to, ok := h.loadDiagramVersion(c, diagramID, toParam)
+	if !ok {
+		return
+	}
+
+	fromParam := c.Query("from")
+	if fromParam == "" {
+		fromParam = strconv.Itoa(to.Version - 1)
+	}
+	from, ok := h.loadDiagramVersion(c, diagramID, fromParam)
+	if !ok {
+		return
+	}
+
+	diff := diffSnapshots(from.Snapshot, to.Snapshot)
+	diff.FromVersion, diff.ToVersion = from.Version, to.Version
+	c.JSON(http.StatusOK, diff)
+}
+
+// RollbackDiagram restores a diagram to a previous version
+func (h *Handlers) RollbackDiagram(c *gin.Context) {
+	diagramID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid diagram ID"})
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	restored, err := h.repo.RollbackDiagram(c.Request.Context(), diagramID, version, currentUserID(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if restored == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Diagram already matches this version"})
+		return
+	}
+	c.JSON(http.StatusOK, restored)
+}
+
+func (h *Handlers) loadDiagramVersion(c *gin.Context, diagramID int, param string) (*models.DiagramVersion, bool) {
+	number, err := strconv.Atoi(param)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return nil, false
+	}
+
+	version, err := h.repo.GetDiagramVersion(c.Request.Context(), diagramID, number)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Version %d not found", number)})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return version, true
+}
+
+// recordVersion snapshots a diagram after a structural change. Failures are only logged so the
+// change itself still succeeds.
+func (h *Handlers) recordVersion(c *gin.Context, diagramID int, summary string) {
+	if _, err := h.repo.RecordDiagramVersion(c.Request.Context(), diagramID, currentUserID(c), summary); err != nil {
+		log.Printf("Error recording version of diagram %d: %v", diagramID, err)
+	}
+}
+
+// currentUserID returns the authenticated user's ID, or nil when it is not available
+func currentUserID(c *gin.Context) *int {
+	value, exists := c.Get("user_id")
+	if !exists {
+		return nil
+	}
+
+	var id int
+	switch v := value.(type) {
+	case float64:
+		id = int(v)
+	case uint:
+		id = int(v)
+	case int:
+		id = v
+	default:
+		return nil
+	}
+	return &id
+}
+
+// diffSnapshots compares two snapshots. Services are matched by ID and connections by their endpoints.
+func diffSnapshots(from, to *models.DiagramSnapshot) models.DiagramDiff {
+	diff := models.DiagramDiff{
+		DiagramChanges:     []models.FieldChange{},
+		ServicesAdded:      []models.Service{},
+		ServicesRemoved:    []models.Service{},
+		ServicesChanged:    []models.ServiceChange{},
+		ConnectionsAdded:   []models.Connection{},
+		ConnectionsRemoved: []models.Connection{},
+		ConnectionsChanged: []models.ConnectionChange{},
+	}
+
+	if from.Name != to.Name {
+		diff.DiagramChanges = append(diff.DiagramChanges, models.FieldChange{Field: "name", From: from.Name, To: to.Name})
+	}
+	if from.Description != to.Description {
+		diff.DiagramChanges = append(diff.DiagramChanges, models.FieldChange{Field: "description", From: from.Description, To: to.Description})
+	}
+
+	before := make(map[int]models.Service, len(from.Services))
+	for _, s := range from.Services {
+		before[s.ID] = s
+	}
+	after := make(map[int]bool, len(to.Services))
+	for _, s := range to.Services {
+		after[s.ID] = true
+		old, ok := before[s.ID]
+		if !ok {
+			diff.ServicesAdded = append(diff.ServicesAdded, s)
+			continue
+		}
+		if changes := diffFields(old, s); len(changes) > 0 {
+			diff.ServicesChanged = append(diff.ServicesChanged, models.ServiceChange{ID: s.ID, Name: s.Name, Changes: changes})
+		}
+	}
+	for _, s := range from.Services {
+		if !after[s.ID] {
+			diff.ServicesRemoved = append(diff.ServicesRemoved, s)
+		}
+	}
+
+	type edge struct{ source, target int }
+	fromEdges := make(map[edge]models.Connection, len(from.Connections))
+	for _, conn := range from.Connections {
+		fromEdges[edge{conn.SourceID, conn.TargetID}] = conn
+	}
+	toEdges := make(map[edge]bool, len(to.Connections))
+	for _, conn := range to.Connections {
+		toEdges[edge{conn.SourceID, conn.TargetID}] = true
+		old, ok := fromEdges[edge{conn.SourceID, conn.TargetID}]
+		if !ok {
+			diff.ConnectionsAdded = append(diff.ConnectionsAdded, conn)
+			continue
+		}
+		// IDs change whenever a rollback recreates connections, so only the attributes are compared.
+		// Snapshots recorded before connections had a direction or metadata get the defaults.
+		old.ID, conn.ID = 0, 0
+		for _, c := range []*models.Connection{&old, &conn} {
+			if c.Direction == "" {
+				c.Direction = models.DirectionForward
+			}
+			if c.Metadata == nil {
+				c.Metadata = models.JSON{}
+			}
+		}
+		if changes := diffFields(old, conn); len(changes) > 0 {
+			diff.ConnectionsChanged = append(diff.ConnectionsChanged, models.ConnectionChange{SourceID: conn.SourceID, TargetID: conn.TargetID, Changes: changes})
+		}
+	}
+	for _, conn := range from.Connections {
+		if !toEdges[edge{conn.SourceID, conn.TargetID}] {
+			diff.ConnectionsRemoved = append(diff.ConnectionsRemoved, conn)
+		}
+	}
+	return diff
+}
+
+// diffFields compares the JSON representation of two values field by field
+func diffFields(from, to interface{}) []models.FieldChange {
+	before, after := toFieldMap(from), toFieldMap(to)
+
+	fields := make([]string, 0, len(after))
+	for field := range after {
+		fields = append(fields, field)
+	}
+	for field := range before {
+		if _, ok := after[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	var changes []models.FieldChange
+	for _, field := range fields {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			changes = append(changes, models.FieldChange{Field: field, From: before[field], To: after[field]})
+		}
+	}
+	return changes
+}
+
+func toFieldMap(value interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	return fields
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"service-weaver/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// canViewDiagram reports whether the caller of a public route may read a diagram: anyone may read
+// public diagrams, and only members of its organization, or admins, the others. Callers are only
+// known on routes that run OptionalAuth.
+func (h *Handlers) canViewDiagram(c *gin.Context, diagram *models.Diagram) bool {
+	if diagram.Public {
+		return true
+	}
+	userID := currentUserID(c)
+	if userID == nil {
+		return false
+	}
+	if role, _ := c.Get("user_role"); role == models.RoleAdmin {
+		return true
+	}
+	_, err := h.repo.GetOrganizationRole(c.Request.Context(), *userID, diagram.OrganizationID)
+	return err == nil
+}
+
+// viewableDiagram loads a diagram for a public route, responding with an error and returning nil
+// when it does not exist or the caller may not read it. Diagrams hidden from the caller look
+// exactly like missing ones.
+func (h *Handlers) viewableDiagram(c *gin.Context, id int) *models.Diagram {
+	diagram, err := h.repo.GetDiagram(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !h.canViewDiagram(c, diagram)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Diagram not found"})
+		return nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil
+	}
+	return diagram
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Webhook handlers (admin only). Secrets are write-only: responses carry has_secret instead.
+func (h *Handlers) CreateWebhook(c *gin.Context) {
+	var hook models.Webhook
+	if err := c.ShouldBindJSON(&hook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.validateWebhook(c, &hook) {
+		return
+	}
+
+	if err := h.repo.CreateWebhook(c.Request.Context(), &hook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	hook.Secret = ""
+	c.JSON(http.StatusCreated, hook)
+}
+
+func (h *Handlers) GetWebhooks(c *gin.Context) {
+	hooks, err := h.repo.GetWebhooks(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+func (h *Handlers) GetWebhook(c *gin.Context) {
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	hook.Secret = ""
+	c.JSON(http.StatusOK, hook)
+}
+
+// UpdateWebhook replaces a webhook. Omitting secret keeps the current one.
+func (h *Handlers) UpdateWebhook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	var hook models.Webhook
+	if err := c.ShouldBindJSON(&hook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.validateWebhook(c, &hook) {
+		return
+	}
+
+	hook.ID = id
+	err = h.repo.UpdateWebhook(c.Request.Context(), &hook)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	hook.Secret = ""
+	c.JSON(http.StatusOK, hook)
+}
+
+func (h *Handlers) DeleteWebhook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	if err := h.repo.DeleteWebhook(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// TestWebhook sends a webhook.test event so the receiver and its signature check can be verified
+func (h *Handlers) TestWebhook(c *gin.Context) {
+	hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhooks.SendTest(*hook); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Test event delivered"})
+}
+
+func (h *Handlers) loadWebhook(c *gin.Context) (*models.Webhook, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return nil, false
+	}
+
+	hook, err := h.repo.GetWebhook(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return hook, true
+}
+
+// validateWebhook checks the subscribed events are known and the diagram filter exists
+func (h *Handlers) validateWebhook(c *gin.Context, hook *models.Webhook) bool {
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+	for _, event := range hook.Events {
+		if !isWebhookEvent(event) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown event %q", event)})
+			return false
+		}
+	}
+
+	if hook.DiagramID != nil {
+		if _, err := h.repo.GetDiagram(c.Request.Context(), *hook.DiagramID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Diagram not found"})
+			return false
+		}
+	}
+	return true
+}
+
+func isWebhookEvent(event string) bool {
+	for _, known := range models.WebhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"service-weaver/internal/middleware"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsAuthTimeout is how long a WebSocket client without a token in its URL has to send one
+	wsAuthTimeout = 10 * time.Second
+	// wsRecheckInterval is how often the token, memberships and visible diagrams of a WebSocket
+	// client are checked again
+	wsRecheckInterval = time.Minute
+)
+
+// AllowWebSocketOrigins sets the origins browsers may open WebSocket connections from, such as
+// "https://weaver.example.com". "*" allows any origin; without any, only pages served by this
+// host may connect. Clients that send no Origin header, which browsers always do, are allowed.
+func (h *Handlers) AllowWebSocketOrigins(origins []string) {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[strings.TrimSuffix(strings.TrimSpace(origin), "/")] = true
+	}
+	h.upgrader.CheckOrigin = func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || allowed["*"] || allowed[origin] {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+// wsViewer is the user a WebSocket client acts for and the organizations they belong to
+type wsViewer struct {
+	admin         bool
+	organizations map[int]bool
+}
+
+// canSee reports whether the viewer may receive the updates of a diagram: one of their
+// organizations', or a public one
+func (v wsViewer) canSee(diagram models.Diagram) bool {
+	return v.admin || diagram.Public || v.organizations[diagram.OrganizationID]
+}
+
+// wsSession is the authentication of a WebSocket client: its current token, which it renews with
+// auth messages, and the viewer that token was last checked as
+type wsSession struct {
+	mu     sync.Mutex
+	token  string
+	claims *middleware.TokenClaims
+	viewer wsViewer
+}
+
+func (s *wsSession) current() (string, *middleware.TokenClaims, wsViewer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token, s.claims, s.viewer
+}
+
+func (s *wsSession) renew(token string, claims *middleware.TokenClaims) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token, s.claims = token, claims
+}
+
+func (s *wsSession) setViewer(viewer wsViewer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.viewer = viewer
+}
+
+// HandleWebSocket streams the updates of the diagrams a client may see once it authenticates. The access token is passed
+// as the token query parameter or, to keep it out of URLs, in an auth message sent first. The connection is closed once
+// the token expires, unless the client sends a new one of the same user in another auth message, or is revoked.
+func (h *Handlers) HandleWebSocket(c *gin.Context) {
+	ctx := c.Request.Context()
+	token := c.Query("token")
+	var claims *middleware.TokenClaims
+	if token != "" {
+		var err error
+		if claims, err = middleware.VerifyToken(ctx, h.repo, token); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already answered the request
+		return
+	}
+
+	if claims == nil {
+		if token, claims, err = h.authenticateWebSocket(ctx, conn); err != nil {
+			closeWebSocket(conn, websocket.ClosePolicyViolation, err.Error())
+			return
+		}
+	}
+
+	viewer, visible, err := h.webSocketViewer(ctx, claims)
+	if err != nil {
+		log.Printf("Error loading the diagrams of WebSocket client %s: %v", claims.Username, err)
+		closeWebSocket(conn, websocket.CloseInternalServerErr, "failed to load diagrams")
+		return
+	}
+
+	h.scheduler.AddClient(conn, visible)
+
+	// Handle client disconnection
+	defer h.scheduler.RemoveClient(conn)
+
+	session := &wsSession{token: token, claims: claims, viewer: viewer}
+	renewed := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go h.superviseWebSocket(ctx, conn, session, renewed, done)
+
+	// Read subscription changes until the client disconnects; malformed messages are ignored
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		var message models.ClientMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			continue
+		}
+		switch message.Type {
+		case "auth":
+			renewal, err := middleware.VerifyToken(ctx, h.repo, message.Token)
+			if err != nil || renewal.UserID != claims.UserID {
+				closeWebSocket(conn, websocket.ClosePolicyViolation, "invalid or expired token")
+				return
+			}
+			session.renew(message.Token, renewal)
+			select {
+			case renewed <- struct{}{}:
+			default:
+			}
+		case "subscribe":
+			_, _, viewer := session.current()
+			h.scheduler.SubscribeClient(conn, h.visibleDiagrams(ctx, viewer, message.DiagramIDs))
+		case "unsubscribe":
+			h.scheduler.UnsubscribeClient(conn, message.DiagramIDs)
+		case "filter":
+			services, err := h.filteredServices(ctx, message.ServiceIDs, message.Tags)
+			if err != nil {
+				log.Printf("Error resolving the filter of WebSocket client %s: %v", claims.Username, err)
+				continue
+			}
+			h.scheduler.SetClientFilter(conn, services, message.ChangesOnly)
+		}
+	}
+}
+
+// superviseWebSocket closes the connection of a client once its token expires or is revoked, by
+// logging out or revoking the session. Every wsRecheckInterval, and whenever the client renews its
+// token, it also reloads the organizations of the user and the diagrams they may see, so a client
+// stops receiving the updates of diagrams it lost access to. It returns once done is closed.
+func (h *Handlers) superviseWebSocket(ctx context.Context, conn *websocket.Conn, session *wsSession, renewed, done <-chan struct{}) {
+	for {
+		_, claims, _ := session.current()
+		wait := wsRecheckInterval
+		if until := time.Until(claims.ExpiresAt); !claims.ExpiresAt.IsZero() && until < wait {
+			wait = until
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-renewed:
+		case <-timer.C:
+		}
+		timer.Stop()
+
+		token, _, _ := session.current()
+		claims, err := middleware.VerifyToken(ctx, h.repo, token)
+		if err != nil {
+			if _, parseErr := middleware.ParseToken(token); parseErr == nil && !errors.Is(err, middleware.ErrTokenRevoked) {
+				// The token is fine but revocation could not be checked; try again later
+				log.Printf("Error checking the token of WebSocket client: %v", err)
+				continue
+			}
+			closeWebSocket(conn, websocket.ClosePolicyViolation, "token expired or revoked")
+			return
+		}
+		viewer, visible, err := h.webSocketViewer(ctx, claims)
+		if err != nil {
+			log.Printf("Error loading the diagrams of WebSocket client %s: %v", claims.Username, err)
+			continue
+		}
+		session.setViewer(viewer)
+		h.scheduler.RestrictClient(conn, visible)
+	}
+}
+
+// closeWebSocket closes a connection with a close message telling the client why
+func closeWebSocket(conn *websocket.Conn, code int, text string) {
+	message := websocket.FormatCloseMessage(code, text)
+	conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+	conn.Close()
+}
+
+// authenticateWebSocket waits for the auth message of a client that connected without a token
+// and returns the token it sent
+func (h *Handlers) authenticateWebSocket(ctx context.Context, conn *websocket.Conn) (string, *middleware.TokenClaims, error) {
+	conn.SetReadDeadline(time.Now().Add(wsAuthTimeout))
+	var message models.ClientMessage
+	if err := conn.ReadJSON(&message); err != nil || message.Type != "auth" || message.Token == "" {
+		return "", nil, errors.New("authentication required")
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	claims, err := middleware.VerifyToken(ctx, h.repo, message.Token)
+	if err != nil {
+		return "", nil, errors.New("invalid or expired token")
+	}
+	return message.Token, claims, nil
+}
+
+// webSocketViewer returns who claims identify and the diagrams they may see, nil for all of them
+func (h *Handlers) webSocketViewer(ctx context.Context, claims *middleware.TokenClaims) (wsViewer, []int, error) {
+	viewer := wsViewer{admin: claims.Role == models.RoleAdmin, organizations: make(map[int]bool)}
+	if viewer.admin {
+		return viewer, nil, nil
+	}
+
+	orgs, err := h.repo.GetOrganizations(ctx, int(claims.UserID))
+	if err != nil {
+		return viewer, nil, err
+	}
+	for _, org := range orgs {
+		viewer.organizations[org.ID] = true
+	}
+
+	diagrams, err := h.repo.GetDiagrams(ctx)
+	if err != nil {
+		return viewer, nil, err
+	}
+	visible := []int{}
+	for _, diagram := range diagrams {
+		if viewer.canSee(diagram) {
+			visible = append(visible, diagram.ID)
+		}
+	}
+	return viewer, visible, nil
+}
+
+// visibleDiagrams returns the IDs among ids of the diagrams viewer may see
+func (h *Handlers) visibleDiagrams(ctx context.Context, viewer wsViewer, ids []int) []int {
+	var visible []int
+	for _, id := range ids {
+		diagram, err := h.repo.GetDiagram(ctx, id)
+		if err == nil && viewer.canSee(*diagram) {
+			visible = append(visible, id)
+		}
+	}
+	return visible
+}
+
+// filteredServices returns the services a filter selects: those in ids and those tagged with one
+// of tags, as tagged now; nil when it selects none in particular
+func (h *Handlers) filteredServices(ctx context.Context, ids []int, tags []string) ([]int, error) {
+	if len(ids) == 0 && len(tags) == 0 {
+		return nil, nil
+	}
+	services := append([]int{}, ids...)
+	for _, tag := range tags {
+		tagged, _, err := h.repo.ListServices(ctx, repository.ServiceFilter{Tag: tag})
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range tagged {
+			services = append(services, service.ID)
+		}
+	}
+	return services, nil
+}
+
+// broadcastEvent tells the WebSocket clients watching a diagram that one of its services or
+// connections changed
+func (h *Handlers) broadcastEvent(messageType string, diagramID int, data interface{}) {
+	h.scheduler.Broadcast(models.Envelope{Type: messageType, DiagramID: diagramID, Timestamp: time.Now(), Data: data})
+}
+
+// GetWebSocketMessageTypes lists the types of messages WebSocket clients receive, each with an
+// example of its data
+func (h *Handlers) GetWebSocketMessageTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, models.MessageTypes)
+}
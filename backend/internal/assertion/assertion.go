@@ -0,0 +1,91 @@
+// Package assertion evaluates the expressions services assert against the outcome of their checks,
+// written in CEL (https://github.com/google/cel-spec), such as `json.items.size() > 0 && latency < 500`
+package assertion
+
+import (
+	"fmt"
+	"service-weaver/internal/models"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+)
+
+// MaxLength caps the source of an expression
+const MaxLength = 2000
+
+// maxCost caps the work of one evaluation, in CEL cost units, so an expression iterating over a
+// large response cannot stall a check worker
+const maxCost = 1_000_000
+
+// environment declares the variables expressions see; it is built once and shared
+var environment = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("status_code", cel.IntType),
+		cel.Variable("headers", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("body", cel.StringType),
+		cel.Variable("json", cel.DynType),
+		cel.Variable("latency", cel.IntType),
+		cel.CrossTypeNumericComparisons(true),
+	)
+})
+
+// Program is a compiled expression
+type Program struct {
+	source  string
+	program cel.Program
+}
+
+// Compile parses and type-checks an expression, which must give a boolean or a string
+func Compile(source string) (*Program, error) {
+	if len(source) > MaxLength {
+		return nil, fmt.Errorf("expression is longer than %d characters", MaxLength)
+	}
+	env, err := environment()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(source)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	switch ast.OutputType() {
+	case cel.BoolType, cel.StringType, cel.DynType:
+	default:
+		return nil, fmt.Errorf("expression gives %s, expected a boolean or alive, degraded or dead", ast.OutputType())
+	}
+	program, err := env.Program(ast, cel.CostLimit(maxCost))
+	if err != nil {
+		return nil, err
+	}
+	return &Program{source: source, program: program}, nil
+}
+
+// String returns the source of the expression
+func (p *Program) String() string {
+	return p.source
+}
+
+// Status evaluates the expression as the status of a check: true is alive and false dead, and a
+// string names the status, alive, degraded or dead. vars holds the variables status_code and
+// latency (int), headers (map[string]string), body (string) and json (decoded JSON).
+func (p *Program) Status(vars map[string]any) (models.ServiceStatus, error) {
+	value, _, err := p.program.Eval(vars)
+	if err != nil {
+		return models.StatusDead, err
+	}
+	switch v := value.Value().(type) {
+	case bool:
+		if v {
+			return models.StatusAlive, nil
+		}
+		return models.StatusDead, fmt.Errorf("assertion failed: %s", p.source)
+	case string:
+		switch status := models.ServiceStatus(v); status {
+		case models.StatusAlive:
+			return status, nil
+		case models.StatusDegraded, models.StatusDead:
+			return status, fmt.Errorf("assertion is %s: %s", status, p.source)
+		}
+	}
+	return models.StatusDead, fmt.Errorf("assertion evaluated to %s %v, expected a boolean or alive, degraded or dead", value.Type().TypeName(), value)
+}
@@ -0,0 +1,156 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"service-weaver/internal/repository"
+	"sort"
+	"strings"
+	"time"
+)
+
+// filePrefix starts the name of every scheduled backup, so pruning only touches our own files
+const filePrefix = "service-weaver-backup-"
+
+// Target stores backup archives
+type Target interface {
+	// Store saves the archive read from file under name
+	Store(name string, file *os.File) error
+	String() string
+}
+
+// Dir stores backups in a local directory and keeps the newest Keep of them
+type Dir struct {
+	Path string
+	Keep int
+}
+
+func (d Dir) Store(name string, file *os.File) error {
+	if err := os.MkdirAll(d.Path, 0o700); err != nil {
+		return err
+	}
+	// write under a temporary name so a crash never leaves a truncated archive behind
+	tmp := filepath.Join(d.Path, "."+name+".tmp")
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, file); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(d.Path, name)); err != nil {
+		return err
+	}
+	return d.prune()
+}
+
+// prune removes all but the newest Keep backups. Names embed the UTC time, so they sort by age.
+func (d Dir) prune() error {
+	if d.Keep <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(d.Path)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), filePrefix) && strings.HasSuffix(entry.Name(), ".tar.gz") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	for len(names) > d.Keep {
+		if err := os.Remove(filepath.Join(d.Path, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+func (d Dir) String() string {
+	return d.Path
+}
+
+// Scheduler periodically writes a backup of the database to a target
+type Scheduler struct {
+	repo           *repository.Repository
+	target         Target
+	interval       time.Duration
+	includeSecrets bool
+	ctx            context.Context
+	cancel         context.CancelFunc
+}
+
+func NewScheduler(repo *repository.Repository, target Target, interval time.Duration, includeSecrets bool) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		repo:           repo,
+		target:         target,
+		interval:       interval,
+		includeSecrets: includeSecrets,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+func (s *Scheduler) Start() {
+	go s.run()
+}
+
+func (s *Scheduler) Stop() {
+	s.cancel()
+}
+
+// Backup writes a single backup to the target and returns its name
+func (s *Scheduler) Backup() (string, error) {
+	spool, err := os.CreateTemp("", filePrefix+"*.tar.gz")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	manifest, err := s.repo.WriteBackup(s.ctx, spool, s.includeSecrets)
+	if err != nil {
+		return "", err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	name := filePrefix + manifest.CreatedAt.Format("20060102-150405") + ".tar.gz"
+	if err := s.target.Store(name, spool); err != nil {
+		return "", fmt.Errorf("failed to store backup in %s: %w", s.target, err)
+	}
+	return name, nil
+}
+
+func (s *Scheduler) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			name, err := s.Backup()
+			if err != nil {
+				log.Printf("Error writing scheduled backup: %v", err)
+				continue
+			}
+			log.Printf("Wrote backup %s to %s", name, s.target)
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"service-weaver/internal/sigv4"
+	"strings"
+	"time"
+)
+
+// S3 uploads backups to an S3-compatible bucket with path-style requests signed with AWS
+// Signature Version 4. Old backups are not pruned; use a lifecycle rule on the bucket instead.
+type S3 struct {
+	Endpoint        string // e.g. https://s3.eu-west-1.amazonaws.com or a MinIO URL
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Client          *http.Client
+}
+
+func (s S3) Store(name string, file *os.File) error {
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	payloadHash := hex.EncodeToString(hash.Sum(nil))
+
+	key := strings.Trim(s.Prefix, "/")
+	if key != "" {
+		key += "/"
+	}
+	key += name
+	endpoint, err := sigv4.ObjectURL(s.Endpoint, s.Bucket, key)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, endpoint, io.NopCloser(file))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+	sigv4.Credentials{Region: s.Region, AccessKeyID: s.AccessKeyID, SecretAccessKey: s.SecretAccessKey}.Sign(req, payloadHash, time.Now())
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Minute}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("S3 returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (s S3) String() string {
+	return "s3://" + s.Bucket + "/" + strings.Trim(s.Prefix, "/")
+}
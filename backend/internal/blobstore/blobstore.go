@@ -0,0 +1,85 @@
+// Package blobstore keeps binary objects, such as the images of service icons, out of the
+// database: on local disk or in an S3-compatible bucket
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotFound is returned by Get when no object is stored under a key
+var ErrNotFound = errors.New("blob not found")
+
+// Store saves objects under slash-separated keys such as "icons/<hash>.png"
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get returns the object stored under key and its content type
+	Get(ctx context.Context, key string) ([]byte, string, error)
+	String() string
+}
+
+// Dir stores objects as files under a local directory; their content type follows from the
+// extension of the key
+type Dir struct {
+	Path string
+}
+
+func (d Dir) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// write under a temporary name so a crash never leaves a truncated object behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (d Dir) Get(ctx context.Context, key string) ([]byte, string, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", ErrNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return data, contentType, nil
+}
+
+// path maps key to a file under the directory, refusing keys that would escape it
+func (d Dir) path(key string) (string, error) {
+	name := filepath.FromSlash(key)
+	if !filepath.IsLocal(name) {
+		return "", errors.New("invalid blob key " + key)
+	}
+	return filepath.Join(d.Path, name), nil
+}
+
+func (d Dir) String() string {
+	return d.Path
+}
@@ -0,0 +1,93 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"service-weaver/internal/sigv4"
+	"strings"
+	"time"
+)
+
+// S3 stores objects in an S3-compatible bucket, such as MinIO, with path-style requests signed
+// with AWS Signature Version 4
+type S3 struct {
+	Endpoint        string // e.g. https://s3.eu-west-1.amazonaws.com or a MinIO URL
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Client          *http.Client
+}
+
+func (s S3) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return s.failure(resp)
+	}
+	return nil
+}
+
+func (s S3) Get(ctx context.Context, key string) ([]byte, string, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		return nil, "", s.failure(resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// do sends a signed request for the object stored under key
+func (s S3) do(ctx context.Context, method, key string, body []byte, contentType string) (*http.Response, error) {
+	if prefix := strings.Trim(s.Prefix, "/"); prefix != "" {
+		key = prefix + "/" + key
+	}
+	endpoint, err := sigv4.ObjectURL(s.Endpoint, s.Bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	hash := sha256.Sum256(body)
+	sigv4.Credentials{Region: s.Region, AccessKeyID: s.AccessKeyID, SecretAccessKey: s.SecretAccessKey}.Sign(req, hex.EncodeToString(hash[:]), time.Now())
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return client.Do(req)
+}
+
+func (s S3) failure(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("S3 returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
+
+func (s S3) String() string {
+	return "s3://" + s.Bucket + "/" + strings.Trim(s.Prefix, "/")
+}
@@ -0,0 +1,389 @@
+// Package config loads the optional configuration file. Every setting in it stands for one of the
+// environment variables the backend reads; an environment variable that is set, even to an empty
+// value, overrides the file, and settings missing from both keep their default.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Kind is the type a setting's value must parse as
+type Kind int
+
+const (
+	String Kind = iota
+	Int
+	Bool
+	Duration
+	List // a YAML or TOML list, or a string separated by Setting.Separator
+)
+
+// Setting describes one configuration value
+type Setting struct {
+	Section   string
+	Key       string
+	Env       string
+	Default   string
+	Kind      Kind
+	Separator string   // joins the items of a List
+	OneOf     []string // allowed values, empty for any
+	Secret    bool     // redacted when the configuration is shown
+}
+
+// Source tells where the effective value of a setting came from
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	// SourceSecretFile is a secret read from the file named by <ENV>_FILE, such as a mounted
+	// Docker or Kubernetes secret
+	SourceSecretFile Source = "secret_file"
+)
+
+// secretFiles holds the secrets read from <ENV>_FILE files by Load. They are not copied into the
+// process environment, so child processes and /proc/<pid>/environ never see them.
+var secretFiles = make(map[string]string)
+
+// LookupEnv is os.LookupEnv that also sees the secrets Load read from <ENV>_FILE files
+func LookupEnv(key string) (string, bool) {
+	if value, ok := secretFiles[key]; ok {
+		return value, true
+	}
+	return os.LookupEnv(key)
+}
+
+// Settings lists every setting of the configuration file by section. The defaults are the ones
+// applied where the environment variables are read.
+var Settings = []Setting{
+	{Section: "server", Key: "http_addr", Env: "HTTP_ADDR", Default: ":8080"},
+	{Section: "server", Key: "grpc_addr", Env: "GRPC_ADDR", Default: ":9090"},
+	{Section: "server", Key: "tls_cert_file", Env: "TLS_CERT_FILE"},
+	{Section: "server", Key: "tls_key_file", Env: "TLS_KEY_FILE"},
+	{Section: "server", Key: "tls_autocert_domains", Env: "TLS_AUTOCERT_DOMAINS", Kind: List, Separator: ","},
+	{Section: "server", Key: "tls_autocert_cache_dir", Env: "TLS_AUTOCERT_CACHE_DIR", Default: "data/autocert"},
+	{Section: "server", Key: "tls_autocert_email", Env: "TLS_AUTOCERT_EMAIL"},
+	{Section: "server", Key: "http_redirect_addr", Env: "HTTP_REDIRECT_ADDR"},
+	{Section: "server", Key: "public_url", Env: "PUBLIC_URL"},
+	{Section: "server", Key: "metrics_token", Env: "METRICS_TOKEN", Secret: true},
+	{Section: "server", Key: "ws_allowed_origins", Env: "WS_ALLOWED_ORIGINS", Kind: List, Separator: ","},
+	{Section: "server", Key: "rate_limit", Env: "RATE_LIMIT", Default: "300/m"},
+	{Section: "server", Key: "user_rate_limit", Env: "USER_RATE_LIMIT", Default: "600/m"},
+	{Section: "server", Key: "login_rate_limit", Env: "LOGIN_RATE_LIMIT", Default: "10/m"},
+	{Section: "server", Key: "rate_limit_redis_url", Env: "RATE_LIMIT_REDIS_URL", Secret: true},
+	{Section: "server", Key: "trusted_proxies", Env: "TRUSTED_PROXIES", Kind: List, Separator: ","},
+	{Section: "server", Key: "otlp_endpoint", Env: "OTEL_EXPORTER_OTLP_ENDPOINT"},
+	{Section: "server", Key: "otlp_headers", Env: "OTEL_EXPORTER_OTLP_HEADERS", Secret: true},
+	{Section: "server", Key: "otel_service_name", Env: "OTEL_SERVICE_NAME", Default: "service-weaver"},
+
+	{Section: "database", Key: "url", Env: "DATABASE_URL", Secret: true},
+	{Section: "database", Key: "host", Env: "DB_HOST", Default: "localhost"},
+	{Section: "database", Key: "port", Env: "DB_PORT", Default: "5430", Kind: Int},
+	{Section: "database", Key: "user", Env: "DB_USER", Default: "postgres"},
+	{Section: "database", Key: "password", Env: "DB_PASSWORD", Default: "password", Secret: true},
+	{Section: "database", Key: "name", Env: "DB_NAME", Default: "service_weaver"},
+	{Section: "database", Key: "sslmode", Env: "DB_SSLMODE", Default: "disable", OneOf: []string{"disable", "require", "verify-ca", "verify-full"}},
+	{Section: "database", Key: "sslrootcert", Env: "DB_SSLROOTCERT"},
+	{Section: "database", Key: "sslcert", Env: "DB_SSLCERT"},
+	{Section: "database", Key: "sslkey", Env: "DB_SSLKEY"},
+	{Section: "database", Key: "replica_url", Env: "DB_REPLICA_URL", Secret: true},
+	{Section: "database", Key: "max_open_conns", Env: "DB_MAX_OPEN_CONNS", Default: "25", Kind: Int},
+	{Section: "database", Key: "max_idle_conns", Env: "DB_MAX_IDLE_CONNS", Default: "10", Kind: Int},
+	{Section: "database", Key: "conn_max_lifetime", Env: "DB_CONN_MAX_LIFETIME", Default: "30m", Kind: Duration},
+	{Section: "database", Key: "conn_max_idle_time", Env: "DB_CONN_MAX_IDLE_TIME", Default: "5m", Kind: Duration},
+	{Section: "database", Key: "statement_timeout", Env: "DB_STATEMENT_TIMEOUT", Default: "30s", Kind: Duration},
+	{Section: "database", Key: "auto_migrate", Env: "DB_AUTO_MIGRATE", Default: "true", Kind: Bool},
+
+	{Section: "scheduler", Key: "leader_election", Env: "SCHEDULER_LEADER_ELECTION", Default: "true", Kind: Bool},
+	{Section: "scheduler", Key: "sharding", Env: "SCHEDULER_SHARDING", Default: "false", Kind: Bool},
+	{Section: "scheduler", Key: "workers", Env: "SCHEDULER_WORKERS", Default: "50", Kind: Int},
+	{Section: "scheduler", Key: "queue_size", Env: "SCHEDULER_QUEUE_SIZE", Default: "2000", Kind: Int},
+	{Section: "scheduler", Key: "type_limits", Env: "SCHEDULER_TYPE_LIMITS", Kind: List, Separator: ","},
+	{Section: "scheduler", Key: "broadcast_checking", Env: "SCHEDULER_BROADCAST_CHECKING", Default: "true", Kind: Bool},
+	{Section: "scheduler", Key: "host_limit", Env: "SCHEDULER_HOST_LIMIT", Default: "0", Kind: Int},
+	{Section: "scheduler", Key: "result_batch_size", Env: "RESULT_BATCH_SIZE", Default: "500", Kind: Int},
+	{Section: "scheduler", Key: "result_flush_interval", Env: "RESULT_FLUSH_INTERVAL", Default: "1s", Kind: Duration},
+	{Section: "scheduler", Key: "results_retention_days", Env: "RESULTS_RETENTION_DAYS", Default: "30", Kind: Int},
+	{Section: "scheduler", Key: "results_prune_interval", Env: "RESULTS_PRUNE_INTERVAL", Default: "1h", Kind: Duration},
+	{Section: "scheduler", Key: "trash_retention_days", Env: "TRASH_RETENTION_DAYS", Default: "30", Kind: Int},
+	{Section: "scheduler", Key: "trash_purge_interval", Env: "TRASH_PURGE_INTERVAL", Default: "1h", Kind: Duration},
+	{Section: "scheduler", Key: "script_interpreters", Env: "SCRIPT_INTERPRETERS", Kind: List, Separator: ","},
+	{Section: "scheduler", Key: "script_user", Env: "SCRIPT_USER", Default: "nobody"},
+	{Section: "scheduler", Key: "script_memory_limit", Env: "SCRIPT_MEMORY_LIMIT", Default: "256", Kind: Int},
+	{Section: "scheduler", Key: "discovery_check_interval", Env: "DISCOVERY_CHECK_INTERVAL", Default: "1m", Kind: Duration},
+
+	{Section: "backup", Key: "interval", Env: "BACKUP_INTERVAL", Kind: Duration},
+	{Section: "backup", Key: "dir", Env: "BACKUP_DIR", Default: "data/backups"},
+	{Section: "backup", Key: "keep", Env: "BACKUP_KEEP", Default: "7", Kind: Int},
+	{Section: "backup", Key: "include_secrets", Env: "BACKUP_INCLUDE_SECRETS", Default: "false", Kind: Bool},
+	{Section: "backup", Key: "s3_bucket", Env: "BACKUP_S3_BUCKET"},
+	{Section: "backup", Key: "s3_endpoint", Env: "BACKUP_S3_ENDPOINT"},
+	{Section: "backup", Key: "s3_region", Env: "BACKUP_S3_REGION", Default: "us-east-1"},
+	{Section: "backup", Key: "s3_prefix", Env: "BACKUP_S3_PREFIX"},
+	{Section: "backup", Key: "s3_access_key_id", Env: "BACKUP_S3_ACCESS_KEY_ID"},
+	{Section: "backup", Key: "s3_secret_access_key", Env: "BACKUP_S3_SECRET_ACCESS_KEY", Secret: true},
+	{Section: "icons", Key: "dir", Env: "ICON_DIR", Default: "data/icons"},
+	{Section: "icons", Key: "s3_bucket", Env: "ICON_S3_BUCKET"},
+	{Section: "icons", Key: "s3_endpoint", Env: "ICON_S3_ENDPOINT"},
+	{Section: "icons", Key: "s3_region", Env: "ICON_S3_REGION", Default: "us-east-1"},
+	{Section: "icons", Key: "s3_prefix", Env: "ICON_S3_PREFIX"},
+	{Section: "icons", Key: "s3_access_key_id", Env: "ICON_S3_ACCESS_KEY_ID"},
+	{Section: "icons", Key: "s3_secret_access_key", Env: "ICON_S3_SECRET_ACCESS_KEY", Secret: true},
+
+	{Section: "result_stream", Key: "kafka_brokers", Env: "RESULT_STREAM_KAFKA_BROKERS", Kind: List, Separator: ","},
+	{Section: "result_stream", Key: "kafka_username", Env: "RESULT_STREAM_KAFKA_USERNAME"},
+	{Section: "result_stream", Key: "kafka_password", Env: "RESULT_STREAM_KAFKA_PASSWORD", Secret: true},
+	{Section: "result_stream", Key: "kafka_tls", Env: "RESULT_STREAM_KAFKA_TLS", Default: "false", Kind: Bool},
+	{Section: "result_stream", Key: "nats_url", Env: "RESULT_STREAM_NATS_URL", Secret: true},
+	{Section: "result_stream", Key: "topic", Env: "RESULT_STREAM_TOPIC", Default: "service-weaver.results"},
+
+	{Section: "auth", Key: "jwt_secret", Env: "JWT_SECRET", Secret: true},
+	{Section: "auth", Key: "jwt_signing_key_file", Env: "JWT_SIGNING_KEY_FILE"},
+	{Section: "auth", Key: "jwt_previous_key_files", Env: "JWT_PREVIOUS_KEY_FILES", Kind: List, Separator: ","},
+	{Section: "auth", Key: "access_token_ttl", Env: "ACCESS_TOKEN_TTL", Default: "15m", Kind: Duration},
+	{Section: "auth", Key: "refresh_token_ttl", Env: "REFRESH_TOKEN_TTL", Default: "24h", Kind: Duration},
+	{Section: "auth", Key: "remember_me_ttl", Env: "REMEMBER_ME_TTL", Default: "720h", Kind: Duration},
+	{Section: "auth", Key: "invitation_ttl", Env: "INVITATION_TTL", Default: "72h", Kind: Duration},
+	{Section: "auth", Key: "lockout_threshold", Env: "LOCKOUT_THRESHOLD", Default: "5", Kind: Int},
+	{Section: "auth", Key: "lockout_window", Env: "LOCKOUT_WINDOW", Default: "15m", Kind: Duration},
+	{Section: "auth", Key: "lockout_duration", Env: "LOCKOUT_DURATION", Default: "15m", Kind: Duration},
+	{Section: "auth", Key: "lockout_max_duration", Env: "LOCKOUT_MAX_DURATION", Default: "24h", Kind: Duration},
+	{Section: "auth", Key: "oidc_issuer", Env: "OIDC_ISSUER"},
+	{Section: "auth", Key: "oidc_client_id", Env: "OIDC_CLIENT_ID"},
+	{Section: "auth", Key: "oidc_client_secret", Env: "OIDC_CLIENT_SECRET", Secret: true},
+	{Section: "auth", Key: "oidc_redirect_url", Env: "OIDC_REDIRECT_URL"},
+	{Section: "auth", Key: "oidc_post_login_url", Env: "OIDC_POST_LOGIN_URL", Default: "/"},
+	{Section: "auth", Key: "oidc_scopes", Env: "OIDC_SCOPES", Default: "openid profile email", Kind: List, Separator: " "},
+	{Section: "auth", Key: "oidc_display_name", Env: "OIDC_DISPLAY_NAME"},
+	{Section: "auth", Key: "oidc_username_claim", Env: "OIDC_USERNAME_CLAIM", Default: "preferred_username"},
+	{Section: "auth", Key: "oidc_role_claim", Env: "OIDC_ROLE_CLAIM"},
+	{Section: "auth", Key: "oidc_role_mapping", Env: "OIDC_ROLE_MAPPING"},
+	{Section: "auth", Key: "oidc_default_role", Env: "OIDC_DEFAULT_ROLE", Default: "viewer", OneOf: []string{"", "admin", "viewer"}},
+	{Section: "auth", Key: "oidc_link_existing_users", Env: "OIDC_LINK_EXISTING_USERS", Default: "false", Kind: Bool},
+
+	{Section: "secrets", Key: "key", Env: "SECRETS_KEY", Secret: true},
+	{Section: "secrets", Key: "previous_keys", Env: "SECRETS_PREVIOUS_KEYS", Kind: List, Separator: ",", Secret: true},
+	{Section: "secrets", Key: "kms", Env: "SECRETS_KMS", OneOf: []string{"", "vault-transit"}},
+	{Section: "secrets", Key: "vault_transit_key", Env: "SECRETS_VAULT_TRANSIT_KEY", Default: "service-weaver"},
+	{Section: "secrets", Key: "vault_transit_mount", Env: "SECRETS_VAULT_TRANSIT_MOUNT", Default: "transit"},
+	{Section: "secrets", Key: "vault_addr", Env: "VAULT_ADDR"},
+	{Section: "secrets", Key: "vault_token", Env: "VAULT_TOKEN", Secret: true},
+	{Section: "secrets", Key: "vault_namespace", Env: "VAULT_NAMESPACE"},
+
+	{Section: "alerting", Key: "reports_check_interval", Env: "REPORTS_CHECK_INTERVAL", Default: "5m", Kind: Duration},
+	{Section: "alerting", Key: "alert_group_window", Env: "ALERT_GROUP_WINDOW", Default: "0", Kind: Duration},
+	{Section: "alerting", Key: "anomaly_check_interval", Env: "ANOMALY_CHECK_INTERVAL", Default: "5m", Kind: Duration},
+	{Section: "alerting", Key: "anomaly_threshold", Env: "ANOMALY_THRESHOLD", Default: "3"},
+	{Section: "alerting", Key: "alertmanager_token", Env: "ALERTMANAGER_TOKEN", Secret: true},
+	{Section: "alerting", Key: "alertmanager_labels", Env: "ALERTMANAGER_LABELS", Default: "sw_service_id=id,service=name"},
+}
+
+// Value is the effective value of a setting
+type Value struct {
+	Setting
+	Value  string
+	Source Source
+}
+
+// Config is the resolved configuration
+type Config struct {
+	Path   string // the file it was loaded from, empty without one
+	Values []Value
+}
+
+// Load reads the YAML (.yaml, .yml) or TOML (.toml) file at path, if path is not empty, resolves
+// every setting against the environment and validates the result. All problems are reported at once.
+// Secret settings can also be read from the file named by <ENV>_FILE; read them with LookupEnv.
+func Load(path string) (*Config, error) {
+	file := make(map[string]map[string]interface{})
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(data, &file)
+		case ".toml":
+			err = toml.NewDecoder(bytes.NewReader(data)).Decode(&file)
+		default:
+			return nil, fmt.Errorf("%s: the configuration file must be .yaml, .yml or .toml", path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, s := range Settings {
+		known[s.Section+"."+s.Key] = true
+	}
+	var problems []string
+	for section, values := range file {
+		for key := range values {
+			if !known[section+"."+key] {
+				problems = append(problems, fmt.Sprintf("%s.%s: unknown setting", section, key))
+			}
+		}
+	}
+
+	cfg := &Config{Path: path}
+	for _, s := range Settings {
+		v := Value{Setting: s, Value: s.Default, Source: SourceDefault}
+		if raw, ok := file[s.Section][s.Key]; ok {
+			value, err := s.scalar(raw)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s.%s: %v", s.Section, s.Key, err))
+				continue
+			}
+			v.Value, v.Source = value, SourceFile
+		}
+		if value, ok := os.LookupEnv(s.Env); ok {
+			v.Value, v.Source = value, SourceEnv
+		}
+		if path, ok := os.LookupEnv(s.Env + "_FILE"); ok && s.Secret {
+			value, err := readSecretFile(path)
+			switch {
+			case v.Source == SourceEnv:
+				problems = append(problems, fmt.Sprintf("%s: set either %[1]s or %[1]s_FILE, not both", s.Env))
+				continue
+			case err != nil:
+				problems = append(problems, fmt.Sprintf("%s_FILE: %v", s.Env, err))
+				continue
+			}
+			v.Value, v.Source = value, SourceSecretFile
+			secretFiles[s.Env] = value
+		}
+		if err := s.validate(v.Value); err != nil {
+			name := s.Env
+			if v.Source == SourceFile {
+				name = s.Section + "." + s.Key
+			}
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+		cfg.Values = append(cfg.Values, v)
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, errors.New("invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+	return cfg, nil
+}
+
+// readSecretFile reads a secret, dropping the line break editors and `echo` leave at the end
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// scalar converts a value decoded from the file to the string form of the environment variable
+func (s Setting) scalar(raw interface{}) (string, error) {
+	switch raw := raw.(type) {
+	case nil:
+		return "", nil
+	case []interface{}:
+		if s.Kind != List {
+			return "", errors.New("must not be a list")
+		}
+		items := make([]string, 0, len(raw))
+		for _, item := range raw {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, s.Separator), nil
+	case map[string]interface{}:
+		return "", errors.New("must not be a table")
+	default:
+		return fmt.Sprint(raw), nil
+	}
+}
+
+func (s Setting) validate(value string) error {
+	if value == "" {
+		return nil
+	}
+	switch s.Kind {
+	case Int:
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return fmt.Errorf("%q is not a non-negative number", value)
+		}
+	case Bool:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+	case Duration:
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return fmt.Errorf("%q is not a duration such as 30s, 5m or 24h", value)
+		}
+	}
+	if len(s.OneOf) > 0 {
+		for _, allowed := range s.OneOf {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q must be one of %s", value, strings.Join(s.OneOf, ", "))
+	}
+	return nil
+}
+
+// Apply exports the settings taken from the file as environment variables, where the rest of the
+// backend reads its configuration
+func (c *Config) Apply() error {
+	for _, v := range c.Values {
+		if v.Source != SourceFile {
+			continue
+		}
+		if err := os.Setenv(v.Env, v.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Entry is how a setting is shown by Redacted
+type Entry struct {
+	Value  string `json:"value"`
+	Env    string `json:"env"`
+	Source Source `json:"source"`
+}
+
+// Redacted returns the configuration by section and key with secret values replaced, for display
+func (c *Config) Redacted() map[string]map[string]Entry {
+	sections := make(map[string]map[string]Entry)
+	for _, v := range c.Values {
+		entry := Entry{Value: v.Value, Env: v.Env, Source: v.Source}
+		if v.Secret && v.Value != "" {
+			entry.Value = "[redacted]"
+		}
+		if sections[v.Section] == nil {
+			sections[v.Section] = make(map[string]Entry)
+		}
+		sections[v.Section][v.Key] = entry
+	}
+	return sections
+}
+
+// Handler serves the effective configuration with secrets redacted
+func Handler(cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"file": cfg.Path, "settings": cfg.Redacted()})
+	}
+}
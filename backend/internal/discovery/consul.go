@@ -0,0 +1,187 @@
+package discovery
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"service-weaver/internal/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// consulProvider lists services from the Consul catalog API.
+//
+// Config keys:
+//   - address: Consul HTTP API address (default http://127.0.0.1:8500)
+//   - token: ACL token sent as X-Consul-Token
+//   - datacenter: datacenter to query instead of the agent's own
+//   - tag: only import services carrying this tag
+//   - exclude_services: services to skip (default consul)
+//   - per_instance: create one node per registered instance instead of one per service
+//   - dns_domain: when set, service nodes use <name>.service.<dns_domain> as host instead of the first instance address
+//   - http_path: path checked on HTTP services (default /)
+//   - insecure_skip_verify: skip TLS verification of the Consul API
+type consulProvider struct {
+	address     string
+	token       string
+	datacenter  string
+	tag         string
+	excluded    map[string]bool
+	perInstance bool
+	dnsDomain   string
+	httpPath    string
+	client      *http.Client
+}
+
+type consulCatalogEntry struct {
+	Node           string            `json:"Node"`
+	Address        string            `json:"Address"`
+	ServiceID      string            `json:"ServiceID"`
+	ServiceName    string            `json:"ServiceName"`
+	ServiceAddress string            `json:"ServiceAddress"`
+	ServicePort    int               `json:"ServicePort"`
+	ServiceTags    []string          `json:"ServiceTags"`
+	ServiceMeta    map[string]string `json:"ServiceMeta"`
+}
+
+func newConsulProvider(config models.JSON) (*consulProvider, error) {
+	address := strings.TrimRight(configString(config, "address", "http://127.0.0.1:8500"), "/")
+	if _, err := url.ParseRequestURI(address); err != nil {
+		return nil, fmt.Errorf("invalid consul address: %w", err)
+	}
+
+	p := &consulProvider{
+		address:     address,
+		token:       configString(config, "token", ""),
+		datacenter:  configString(config, "datacenter", ""),
+		tag:         configString(config, "tag", ""),
+		excluded:    make(map[string]bool),
+		perInstance: configBool(config, "per_instance", false),
+		dnsDomain:   strings.Trim(configString(config, "dns_domain", ""), "."),
+		httpPath:    configString(config, "http_path", "/"),
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: configBool(config, "insecure_skip_verify", false)},
+				Proxy:           http.ProxyFromEnvironment,
+			},
+		},
+	}
+	if !strings.HasPrefix(p.httpPath, "/") {
+		p.httpPath = "/" + p.httpPath
+	}
+
+	excluded := []string{"consul"}
+	if _, ok := config["exclude_services"]; ok {
+		excluded = configList(config, "exclude_services")
+	}
+	for _, name := range excluded {
+		p.excluded[name] = true
+	}
+	return p, nil
+}
+
+func (p *consulProvider) Discover(ctx context.Context) ([]DiscoveredService, error) {
+	var catalog map[string][]string
+	if err := p.get(ctx, "/v1/catalog/services", &catalog); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(catalog))
+	for name, tags := range catalog {
+		if !p.excluded[name] && (p.tag == "" || containsString(tags, p.tag)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var discovered []DiscoveredService
+	for _, name := range names {
+		var entries []consulCatalogEntry
+		if err := p.get(ctx, "/v1/catalog/service/"+url.PathEscape(name), &entries); err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].ServiceID < entries[j].ServiceID })
+
+		if !p.perInstance {
+			d := p.toDiscovered(entries[0])
+			d.Key = "consul/" + name
+			d.Name = name
+			d.Description = fmt.Sprintf("Consul service %s (%d instances)", name, len(entries))
+			if p.dnsDomain != "" {
+				d.Host = name + ".service." + p.dnsDomain
+			}
+			discovered = append(discovered, d)
+			continue
+		}
+
+		for _, entry := range entries {
+			d := p.toDiscovered(entry)
+			d.Key = "consul/" + name + "/" + entry.ServiceID
+			d.Name = entry.ServiceID
+			d.Description = fmt.Sprintf("Consul service %s on node %s", name, entry.Node)
+			discovered = append(discovered, d)
+		}
+	}
+	return discovered, nil
+}
+
+func (p *consulProvider) toDiscovered(entry consulCatalogEntry) DiscoveredService {
+	host := entry.ServiceAddress
+	if host == "" {
+		host = entry.Address
+	}
+
+	d := DiscoveredService{
+		ServiceType:       "service",
+		Host:              host,
+		Port:              entry.ServicePort,
+		Tags:              append([]string{"consul"}, entry.ServiceTags...),
+		HealthcheckMethod: guessMethod(strings.Join(entry.ServiceTags, " ")+" "+entry.ServiceMeta["protocol"], entry.ServicePort),
+	}
+	if d.HealthcheckMethod == "HTTP" || d.HealthcheckMethod == "HTTPS" {
+		d.HealthcheckURL = p.httpPath
+	}
+	return d
+}
+
+func (p *consulProvider) get(ctx context.Context, path string, out interface{}) error {
+	query := url.Values{}
+	if p.datacenter != "" {
+		query.Set("dc", p.datacenter)
+	}
+	if p.tag != "" && strings.HasPrefix(path, "/v1/catalog/service/") {
+		query.Set("tag", p.tag)
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.address+path, nil)
+	if err != nil {
+		return err
+	}
+	if p.token != "" {
+		req.Header.Set("X-Consul-Token", p.token)
+	}
+
+	if err := fetchJSON(p.client, req, out); err != nil {
+		return fmt.Errorf("consul request %s failed: %w", path, err)
+	}
+	return nil
+}
+
+func containsString(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,345 @@
+package discovery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DiscoveredService is a catalog entry normalized into the fields a diagram node needs.
+// Key must be stable across syncs so the entry maps back to the node it created.
+type DiscoveredService struct {
+	Key               string   `json:"key"`
+	Name              string   `json:"name"`
+	Description       string   `json:"description"`
+	ServiceType       string   `json:"service_type"`
+	Host              string   `json:"host"`
+	Port              int      `json:"port"`
+	Tags              []string `json:"tags"`
+	HealthcheckMethod string   `json:"healthcheck_method"`
+	HealthcheckURL    string   `json:"healthcheck_url"`
+}
+
+// Provider lists the services currently registered in an external catalog
+type Provider interface {
+	Discover(ctx context.Context) ([]DiscoveredService, error)
+}
+
+// NewProvider builds the provider matching the source type
+func NewProvider(source models.DiscoverySource) (Provider, error) {
+	switch source.Type {
+	case models.DiscoveryKubernetes:
+		return newKubernetesProvider(source.Config)
+	case models.DiscoveryConsul:
+		return newConsulProvider(source.Config)
+	case models.DiscoveryEureka:
+		return newEurekaProvider(source.Config)
+	default:
+		return nil, fmt.Errorf("unsupported discovery source type: %s", source.Type)
+	}
+}
+
+// discoveredTag marks every node managed by a discovery source
+const discoveredTag = "discovered"
+
+// Layout of newly discovered nodes, appended below the existing ones
+const (
+	gridColumns  = 6
+	gridSpacingX = 240
+	gridSpacingY = 160
+)
+
+// Syncer periodically imports discovery sources into their diagrams
+type Syncer struct {
+	repo     *repository.Repository
+	interval time.Duration
+	timeout  time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+func NewSyncer(repo *repository.Repository, interval time.Duration) *Syncer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Syncer{
+		repo:     repo,
+		interval: interval,
+		timeout:  time.Minute,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func (s *Syncer) Start() {
+	go s.run()
+}
+
+func (s *Syncer) Stop() {
+	s.cancel()
+}
+
+// Preview lists what a source would import without changing the diagram
+func (s *Syncer) Preview(source models.DiscoverySource) ([]DiscoveredService, error) {
+	provider, err := NewProvider(source)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, s.timeout)
+	defer cancel()
+	return provider.Discover(ctx)
+}
+
+// Sync imports a source into its diagram and records the outcome on the source.
+// Nodes edited by hand since the previous sync are left alone unless force is set.
+func (s *Syncer) Sync(source models.DiscoverySource, force bool) (*models.DiscoverySyncResult, error) {
+	result, err := s.sync(source, force)
+
+	syncErr := ""
+	if err != nil {
+		syncErr = err.Error()
+	}
+	if markErr := s.repo.MarkDiscoverySynced(s.ctx, source.ID, syncErr); markErr != nil {
+		log.Printf("Error recording sync of discovery source %d: %v", source.ID, markErr)
+	}
+	return result, err
+}
+
+func (s *Syncer) sync(source models.DiscoverySource, force bool) (*models.DiscoverySyncResult, error) {
+	discovered, err := s.Preview(source)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetDiscoveredServices(s.ctx, source.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load discovered services: %w", err)
+	}
+	diagramServices, err := s.repo.GetServices(s.ctx, source.DiagramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load diagram services: %w", err)
+	}
+
+	result := &models.DiscoverySyncResult{SourceID: source.ID, Discovered: len(discovered), SyncedAt: time.Now()}
+	var changes repository.DiscoveryChanges
+	seen := make(map[string]bool, len(discovered))
+	for _, d := range discovered {
+		if seen[d.Key] {
+			continue
+		}
+		seen[d.Key] = true
+
+		service, ok := existing[d.Key]
+		if !ok {
+			slot := len(diagramServices) + len(changes.Creates)
+			node := newDiscoveredNode(source.DiagramID, d)
+			node.PositionX = float64(100 + (slot%gridColumns)*gridSpacingX)
+			node.PositionY = float64(100 + (slot/gridColumns)*gridSpacingY)
+			changes.Creates = append(changes.Creates, node)
+			continue
+		}
+
+		if service.DiscoveryDeregisteredAt != nil {
+			changes.Restored = append(changes.Restored, service.ID)
+		}
+
+		// A fingerprint mismatch means someone changed the node since discovery last wrote it
+		if !force && service.DiscoveryHash != "" && service.DiscoveryHash != fingerprint(service) {
+			result.Skipped++
+			continue
+		}
+		if applyDiscovered(&service, d) || service.DiscoveryHash == "" {
+			changes.Updates = append(changes.Updates, service)
+		} else {
+			result.Unchanged++
+		}
+	}
+
+	for key, service := range existing {
+		if !seen[key] && service.DiscoveryDeregisteredAt == nil {
+			changes.Deregistered = append(changes.Deregistered, service.ID)
+		}
+	}
+
+	if err := s.repo.ApplyDiscovery(s.ctx, source.ID, changes); err != nil {
+		return nil, fmt.Errorf("failed to apply discovered services: %w", err)
+	}
+	result.Created = len(changes.Creates)
+	result.Updated = len(changes.Updates)
+	result.Deregistered = len(changes.Deregistered)
+
+	if result.Created > 0 || result.Updated > 0 {
+		summary := fmt.Sprintf("Synced from discovery source %s", source.Name)
+		if _, err := s.repo.RecordDiagramVersion(s.ctx, source.DiagramID, nil, summary); err != nil {
+			log.Printf("Error recording version of diagram %d: %v", source.DiagramID, err)
+		}
+	}
+	return result, nil
+}
+
+// newDiscoveredNode builds a node with the same defaults the editor uses for new services
+func newDiscoveredNode(diagramID int, d DiscoveredService) models.Service {
+	service := models.Service{
+		DiagramID:       diagramID,
+		Name:            d.Name,
+		Description:     d.Description,
+		ServiceType:     d.ServiceType,
+		Icon:            d.ServiceType,
+		PollingInterval: 30,
+		RequestTimeout:  5,
+		ExpectedStatus:  200,
+		StatusMapping:   models.JSON{},
+		HTTPMethod:      "GET",
+		Headers:         models.Headers{},
+		SSLVerify:       true,
+		FollowRedirects: true,
+		ICMPPacketCount: 3,
+		DNSQueryType:    "A",
+		SLOTarget:       models.DefaultSLOTarget,
+		DiscoveryKey:    d.Key,
+	}
+	applyDiscovered(&service, d)
+	return service
+}
+
+// applyDiscovered copies the discovery-owned fields onto a node, refreshes its fingerprint
+// and reports whether anything changed
+func applyDiscovered(service *models.Service, d DiscoveredService) bool {
+	tags := append([]string{discoveredTag}, d.Tags...)
+	sort.Strings(tags)
+	// Normalized as stored, so that an unchanged service compares equal
+	joined := strings.Join(models.SplitTags(strings.Join(tags, ",")), ",")
+
+	changed := service.Host != d.Host || service.Port != d.Port || service.HealthcheckMethod != d.HealthcheckMethod ||
+		service.HealthcheckURL != d.HealthcheckURL || service.Tags != joined
+	service.Host = d.Host
+	service.Port = d.Port
+	service.HealthcheckMethod = d.HealthcheckMethod
+	service.HealthcheckURL = d.HealthcheckURL
+	service.Tags = joined
+	service.DiscoveryHash = fingerprint(*service)
+	return changed
+}
+
+// fingerprint hashes the discovery-owned fields of a node
+func fingerprint(service models.Service) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		service.Host, strconv.Itoa(service.Port), service.HealthcheckMethod, service.HealthcheckURL, service.Tags,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// guessMethod picks a healthcheck method from a port number and free-form protocol hints such as port names or tags
+func guessMethod(hint string, port int) string {
+	hint = strings.ToLower(hint)
+	switch {
+	case strings.Contains(hint, "https"), port == 443, port == 8443:
+		return "HTTPS"
+	case strings.Contains(hint, "grpc"):
+		return "TCP"
+	case strings.Contains(hint, "http"), strings.Contains(hint, "web"), port == 80, port == 8080:
+		return "HTTP"
+	default:
+		return "TCP"
+	}
+}
+
+func (s *Syncer) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.syncDue()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Syncer) syncDue() {
+	sources, err := s.repo.GetDueDiscoverySources(s.ctx)
+	if err != nil {
+		log.Printf("Error loading discovery sources: %v", err)
+		return
+	}
+
+	for _, source := range sources {
+		result, err := s.Sync(source, false)
+		if err != nil {
+			log.Printf("Error syncing discovery source %d (%s): %v", source.ID, source.Name, err)
+			continue
+		}
+		if result.Created > 0 || result.Updated > 0 || result.Deregistered > 0 {
+			log.Printf("Discovery source %d (%s): %d created, %d updated, %d deregistered, %d skipped",
+				source.ID, source.Name, result.Created, result.Updated, result.Deregistered, result.Skipped)
+		}
+	}
+}
+
+// fetchJSON performs a catalog API request and decodes its JSON response
+func fetchJSON(client *http.Client, req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// configString reads an optional string value from a source config
+func configString(config models.JSON, key, defaultValue string) string {
+	if value, ok := config[key].(string); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// configBool reads an optional boolean value from a source config
+func configBool(config models.JSON, key string, defaultValue bool) bool {
+	if value, ok := config[key].(bool); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// configList reads a comma separated string or a JSON array of strings from a source config
+func configList(config models.JSON, key string) []string {
+	var raw []string
+	switch value := config[key].(type) {
+	case string:
+		raw = strings.Split(value, ",")
+	case []interface{}:
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	}
+
+	var items []string
+	for _, item := range raw {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
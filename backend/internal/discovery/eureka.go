@@ -0,0 +1,190 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"service-weaver/internal/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// eurekaProvider lists applications registered with a Eureka server.
+//
+// Config keys:
+//   - url: Eureka base URL including the context path, e.g. http://eureka:8761/eureka (required)
+//   - username / password: basic auth credentials
+//   - per_instance: create one node per instance instead of one per application
+//   - include_down: also import instances whose status is not UP
+//   - insecure_skip_verify: skip TLS verification of the Eureka API
+type eurekaProvider struct {
+	baseURL     string
+	username    string
+	password    string
+	perInstance bool
+	includeDown bool
+	client      *http.Client
+}
+
+type eurekaPort struct {
+	Port    int    `json:"$"`
+	Enabled string `json:"@enabled"`
+}
+
+type eurekaInstance struct {
+	InstanceID     string     `json:"instanceId"`
+	HostName       string     `json:"hostName"`
+	IPAddr         string     `json:"ipAddr"`
+	Status         string     `json:"status"`
+	Port           eurekaPort `json:"port"`
+	SecurePort     eurekaPort `json:"securePort"`
+	HealthCheckURL string     `json:"healthCheckUrl"`
+	VIPAddress     string     `json:"vipAddress"`
+}
+
+// Eureka serializes single-element lists as a bare object, so both levels are decoded lazily
+type eurekaApplications struct {
+	Applications struct {
+		Application json.RawMessage `json:"application"`
+	} `json:"applications"`
+}
+
+type eurekaApplication struct {
+	Name     string          `json:"name"`
+	Instance json.RawMessage `json:"instance"`
+}
+
+func newEurekaProvider(config models.JSON) (*eurekaProvider, error) {
+	baseURL := strings.TrimRight(configString(config, "url", ""), "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("eureka url is required")
+	}
+	if _, err := url.ParseRequestURI(baseURL); err != nil {
+		return nil, fmt.Errorf("invalid eureka url: %w", err)
+	}
+
+	return &eurekaProvider{
+		baseURL:     baseURL,
+		username:    configString(config, "username", ""),
+		password:    configString(config, "password", ""),
+		perInstance: configBool(config, "per_instance", false),
+		includeDown: configBool(config, "include_down", false),
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: configBool(config, "insecure_skip_verify", false)},
+				Proxy:           http.ProxyFromEnvironment,
+			},
+		},
+	}, nil
+}
+
+func (p *eurekaProvider) Discover(ctx context.Context) ([]DiscoveredService, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/apps", nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.username != "" {
+		req.SetBasicAuth(p.username, p.password)
+	}
+
+	var registry eurekaApplications
+	if err := fetchJSON(p.client, req, &registry); err != nil {
+		return nil, fmt.Errorf("eureka request failed: %w", err)
+	}
+
+	apps, err := oneOrMany[eurekaApplication](registry.Applications.Application)
+	if err != nil {
+		return nil, fmt.Errorf("invalid eureka response: %w", err)
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Name < apps[j].Name })
+
+	var discovered []DiscoveredService
+	for _, app := range apps {
+		instances, err := oneOrMany[eurekaInstance](app.Instance)
+		if err != nil {
+			return nil, fmt.Errorf("invalid eureka response for %s: %w", app.Name, err)
+		}
+
+		var active []eurekaInstance
+		for _, instance := range instances {
+			if p.includeDown || instance.Status == "UP" {
+				active = append(active, instance)
+			}
+		}
+		if len(active) == 0 {
+			continue
+		}
+		sort.Slice(active, func(i, j int) bool { return active[i].InstanceID < active[j].InstanceID })
+
+		name := strings.ToLower(app.Name)
+		if !p.perInstance {
+			d := eurekaToDiscovered(active[0])
+			d.Key = "eureka/" + name
+			d.Name = name
+			d.Description = fmt.Sprintf("Eureka application %s (%d instances)", app.Name, len(active))
+			discovered = append(discovered, d)
+			continue
+		}
+
+		for _, instance := range active {
+			d := eurekaToDiscovered(instance)
+			d.Key = "eureka/" + name + "/" + instance.InstanceID
+			d.Name = instance.InstanceID
+			d.Description = fmt.Sprintf("Eureka application %s", app.Name)
+			discovered = append(discovered, d)
+		}
+	}
+	return discovered, nil
+}
+
+func eurekaToDiscovered(instance eurekaInstance) DiscoveredService {
+	host := instance.HostName
+	if host == "" {
+		host = instance.IPAddr
+	}
+
+	d := DiscoveredService{
+		ServiceType:       "service",
+		Host:              host,
+		Port:              instance.Port.Port,
+		Tags:              []string{"eureka"},
+		HealthcheckMethod: "HTTP",
+		HealthcheckURL:    "/",
+	}
+	if instance.SecurePort.Enabled == "true" {
+		d.Port = instance.SecurePort.Port
+		d.HealthcheckMethod = "HTTPS"
+	}
+	if instance.VIPAddress != "" {
+		d.Tags = append(d.Tags, "vip:"+instance.VIPAddress)
+	}
+	if u, err := url.Parse(instance.HealthCheckURL); err == nil && u.Path != "" {
+		d.HealthcheckURL = u.Path
+	}
+	return d
+}
+
+// oneOrMany decodes a JSON value that is either a single object or an array of objects
+func oneOrMany[T any](raw json.RawMessage) ([]T, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	if raw[0] == '[' {
+		var items []T
+		err := json.Unmarshal(raw, &items)
+		return items, err
+	}
+
+	var item T
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return nil, err
+	}
+	return []T{item}, nil
+}
@@ -0,0 +1,411 @@
+package discovery
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"service-weaver/internal/models"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+var defaultExcludedNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// kubernetesProvider lists Services and Ingresses through the Kubernetes REST API.
+//
+// Config keys:
+//   - kubeconfig / kubeconfig_data: path to or inline content of a kubeconfig; in-cluster credentials are used when both are empty
+//   - context: kubeconfig context to use instead of current-context
+//   - namespaces: namespaces to import (all when empty); exclude_namespaces overrides the default system namespaces
+//   - label_selector: only import objects matching the selector
+//   - include_ingresses: also import one node per Ingress host
+//   - cluster_domain: DNS suffix used to build Service hosts (default cluster.local)
+//   - http_path: path checked on HTTP Services (default /)
+type kubernetesProvider struct {
+	server           string
+	token            string
+	client           *http.Client
+	namespaces       []string
+	excluded         map[string]bool
+	labelSelector    string
+	includeIngresses bool
+	clusterDomain    string
+	httpPath         string
+}
+
+func newKubernetesProvider(config models.JSON) (*kubernetesProvider, error) {
+	p := &kubernetesProvider{
+		namespaces:       configList(config, "namespaces"),
+		excluded:         make(map[string]bool),
+		labelSelector:    configString(config, "label_selector", ""),
+		includeIngresses: configBool(config, "include_ingresses", false),
+		clusterDomain:    strings.Trim(configString(config, "cluster_domain", "cluster.local"), "."),
+		httpPath:         configString(config, "http_path", "/"),
+	}
+	if !strings.HasPrefix(p.httpPath, "/") {
+		p.httpPath = "/" + p.httpPath
+	}
+
+	excluded := defaultExcludedNamespaces
+	if _, ok := config["exclude_namespaces"]; ok {
+		excluded = configList(config, "exclude_namespaces")
+	}
+	for _, ns := range excluded {
+		p.excluded[ns] = true
+	}
+
+	tlsConfig := &tls.Config{}
+	kubeconfigPath := configString(config, "kubeconfig", "")
+	kubeconfigData := configString(config, "kubeconfig_data", "")
+
+	var err error
+	if kubeconfigPath == "" && kubeconfigData == "" {
+		err = p.loadInCluster(tlsConfig)
+	} else {
+		data := []byte(kubeconfigData)
+		if kubeconfigPath != "" {
+			if data, err = os.ReadFile(kubeconfigPath); err != nil {
+				return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+			}
+		}
+		err = p.loadKubeconfig(data, configString(config, "context", ""), tlsConfig)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.server = strings.TrimRight(p.server, "/")
+	p.client = &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+	}
+	return p, nil
+}
+
+// loadInCluster uses the service account mounted into the pod running service-weaver
+func (p *kubernetesProvider) loadInCluster(tlsConfig *tls.Config) error {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return fmt.Errorf("no kubeconfig configured and not running inside a cluster")
+	}
+	p.server = "https://" + net.JoinHostPort(host, port)
+
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return fmt.Errorf("failed to read service account token: %w", err)
+	}
+	p.token = strings.TrimSpace(string(token))
+
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return fmt.Errorf("failed to read service account CA: %w", err)
+	}
+	return setRootCAs(tlsConfig, ca)
+}
+
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+			TLSServerName            string `yaml:"tls-server-name"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			TokenFile             string `yaml:"tokenFile"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// loadKubeconfig resolves the selected context of a kubeconfig; exec and auth-provider plugins are not supported
+func (p *kubernetesProvider) loadKubeconfig(data []byte, contextName string, tlsConfig *tls.Config) error {
+	var kc kubeconfig
+	if err := yaml.Unmarshal(data, &kc); err != nil {
+		return fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	if contextName == "" {
+		contextName = kc.CurrentContext
+	}
+
+	var clusterName, userName string
+	found := false
+	for _, ctx := range kc.Contexts {
+		if ctx.Name == contextName {
+			clusterName, userName, found = ctx.Context.Cluster, ctx.Context.User, true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("kubeconfig context %q not found", contextName)
+	}
+
+	found = false
+	for _, cluster := range kc.Clusters {
+		if cluster.Name != clusterName {
+			continue
+		}
+		found = true
+		p.server = cluster.Cluster.Server
+		tlsConfig.InsecureSkipVerify = cluster.Cluster.InsecureSkipTLSVerify
+		tlsConfig.ServerName = cluster.Cluster.TLSServerName
+
+		ca, err := inlineOrFile(cluster.Cluster.CertificateAuthorityData, cluster.Cluster.CertificateAuthority)
+		if err != nil {
+			return fmt.Errorf("failed to load cluster CA: %w", err)
+		}
+		if ca != nil {
+			if err := setRootCAs(tlsConfig, ca); err != nil {
+				return err
+			}
+		}
+		break
+	}
+	if !found || p.server == "" {
+		return fmt.Errorf("kubeconfig cluster %q not found", clusterName)
+	}
+
+	for _, user := range kc.Users {
+		if user.Name != userName {
+			continue
+		}
+		p.token = user.User.Token
+		if p.token == "" && user.User.TokenFile != "" {
+			token, err := os.ReadFile(user.User.TokenFile)
+			if err != nil {
+				return fmt.Errorf("failed to read token file: %w", err)
+			}
+			p.token = strings.TrimSpace(string(token))
+		}
+
+		cert, err := inlineOrFile(user.User.ClientCertificateData, user.User.ClientCertificate)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		key, err := inlineOrFile(user.User.ClientKeyData, user.User.ClientKey)
+		if err != nil {
+			return fmt.Errorf("failed to load client key: %w", err)
+		}
+		if cert != nil && key != nil {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return fmt.Errorf("invalid client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+		break
+	}
+	return nil
+}
+
+// inlineOrFile returns base64 decoded inline data, or the content of path when no inline data is set
+func inlineOrFile(data, path string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if path != "" {
+		return os.ReadFile(path)
+	}
+	return nil, nil
+}
+
+func setRootCAs(tlsConfig *tls.Config, ca []byte) error {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return fmt.Errorf("no valid certificates in cluster CA")
+	}
+	tlsConfig.RootCAs = pool
+	return nil
+}
+
+type kubeMetadata struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Labels    map[string]string `json:"labels"`
+}
+
+type kubeServiceList struct {
+	Items []struct {
+		Metadata kubeMetadata `json:"metadata"`
+		Spec     struct {
+			Type         string `json:"type"`
+			ExternalName string `json:"externalName"`
+			Ports        []struct {
+				Name        string `json:"name"`
+				Protocol    string `json:"protocol"`
+				Port        int    `json:"port"`
+				AppProtocol string `json:"appProtocol"`
+			} `json:"ports"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+type kubeIngressList struct {
+	Items []struct {
+		Metadata kubeMetadata `json:"metadata"`
+		Spec     struct {
+			TLS []struct {
+				Hosts []string `json:"hosts"`
+			} `json:"tls"`
+			Rules []struct {
+				Host string `json:"host"`
+				HTTP struct {
+					Paths []struct {
+						Path string `json:"path"`
+					} `json:"paths"`
+				} `json:"http"`
+			} `json:"rules"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+func (p *kubernetesProvider) Discover(ctx context.Context) ([]DiscoveredService, error) {
+	namespaces := p.namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+
+	var discovered []DiscoveredService
+	for _, ns := range namespaces {
+		var services kubeServiceList
+		if err := p.list(ctx, "/api/v1", ns, "services", &services); err != nil {
+			return nil, err
+		}
+		for _, item := range services.Items {
+			if p.excluded[item.Metadata.Namespace] || len(item.Spec.Ports) == 0 {
+				continue
+			}
+
+			port := item.Spec.Ports[0]
+			if port.Protocol != "" && port.Protocol != "TCP" && port.Protocol != "UDP" {
+				continue
+			}
+			d := DiscoveredService{
+				Key:               "service/" + item.Metadata.Namespace + "/" + item.Metadata.Name,
+				Name:              item.Metadata.Name,
+				Description:       fmt.Sprintf("Kubernetes service %s/%s", item.Metadata.Namespace, item.Metadata.Name),
+				ServiceType:       "service",
+				Host:              fmt.Sprintf("%s.%s.svc.%s", item.Metadata.Name, item.Metadata.Namespace, p.clusterDomain),
+				Port:              port.Port,
+				Tags:              kubeTags(item.Metadata),
+				HealthcheckMethod: guessMethod(port.AppProtocol+" "+port.Name, port.Port),
+			}
+			if port.Protocol == "UDP" {
+				d.HealthcheckMethod = "UDP"
+			}
+			if item.Spec.Type == "ExternalName" && item.Spec.ExternalName != "" {
+				d.Host = item.Spec.ExternalName
+			}
+			if d.HealthcheckMethod == "HTTP" || d.HealthcheckMethod == "HTTPS" {
+				d.HealthcheckURL = p.httpPath
+			}
+			discovered = append(discovered, d)
+		}
+
+		if !p.includeIngresses {
+			continue
+		}
+		var ingresses kubeIngressList
+		if err := p.list(ctx, "/apis/networking.k8s.io/v1", ns, "ingresses", &ingresses); err != nil {
+			return nil, err
+		}
+		for _, item := range ingresses.Items {
+			if p.excluded[item.Metadata.Namespace] {
+				continue
+			}
+
+			tlsHosts := make(map[string]bool)
+			for _, t := range item.Spec.TLS {
+				for _, host := range t.Hosts {
+					tlsHosts[host] = true
+				}
+			}
+			for _, rule := range item.Spec.Rules {
+				if rule.Host == "" || strings.HasPrefix(rule.Host, "*") {
+					continue
+				}
+				d := DiscoveredService{
+					Key:               "ingress/" + item.Metadata.Namespace + "/" + item.Metadata.Name + "/" + rule.Host,
+					Name:              rule.Host,
+					Description:       fmt.Sprintf("Kubernetes ingress %s/%s", item.Metadata.Namespace, item.Metadata.Name),
+					ServiceType:       "web",
+					Host:              rule.Host,
+					Port:              80,
+					Tags:              kubeTags(item.Metadata),
+					HealthcheckMethod: "HTTP",
+					HealthcheckURL:    "/",
+				}
+				if tlsHosts[rule.Host] {
+					d.Port = 443
+					d.HealthcheckMethod = "HTTPS"
+				}
+				if len(rule.HTTP.Paths) > 0 && strings.HasPrefix(rule.HTTP.Paths[0].Path, "/") {
+					d.HealthcheckURL = rule.HTTP.Paths[0].Path
+				}
+				discovered = append(discovered, d)
+			}
+		}
+	}
+	return discovered, nil
+}
+
+// list fetches a resource collection, cluster-wide when namespace is empty
+func (p *kubernetesProvider) list(ctx context.Context, apiPath, namespace, resource string, out interface{}) error {
+	path := apiPath + "/" + resource
+	if namespace != "" {
+		path = apiPath + "/namespaces/" + url.PathEscape(namespace) + "/" + resource
+	}
+	if p.labelSelector != "" {
+		path += "?labelSelector=" + url.QueryEscape(p.labelSelector)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.server+path, nil)
+	if err != nil {
+		return err
+	}
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	if err := fetchJSON(p.client, req, out); err != nil {
+		return fmt.Errorf("failed to list %s: %w", resource, err)
+	}
+	return nil
+}
+
+func kubeTags(meta kubeMetadata) []string {
+	tags := []string{"kubernetes", "namespace:" + meta.Namespace}
+	if app := meta.Labels["app.kubernetes.io/name"]; app != "" {
+		tags = append(tags, "app:"+app)
+	} else if app := meta.Labels["app"]; app != "" {
+		tags = append(tags, "app:"+app)
+	}
+	return tags
+}
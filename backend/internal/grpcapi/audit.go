@@ -0,0 +1,140 @@
+package grpcapi
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"service-weaver/internal/grpcapi/serviceweaverpb"
+	"service-weaver/internal/middleware"
+	"service-weaver/internal/models"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// auditInterceptor records Create*, Update* and Delete* calls in the audit log the same way
+// middleware.Audit records HTTP mutations. It must run after unaryAuth so the user is known.
+func auditInterceptor(store middleware.AuditStore, listeners []middleware.AuditListener) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+		action, entityType, ok := auditOperation(method)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		entityID := requestEntityID(req)
+		var before interface{}
+		if entityID != nil {
+			var err error
+			if before, err = store.GetAuditEntity(ctx, entityType, *entityID); err != nil {
+				log.Printf("Audit: failed to load %s %d: %v", entityType, *entityID, err)
+			}
+		}
+
+		resp, callErr := handler(ctx, req)
+
+		entry := models.AuditEntry{
+			Action:     action,
+			Method:     "GRPC",
+			Route:      info.FullMethod,
+			EntityType: entityType,
+			EntityID:   entityID,
+			Before:     middleware.AuditJSON(before),
+			StatusCode: httpStatus(status.Code(callErr)),
+			UserID:     userIDFrom(ctx),
+		}
+		if claims := claimsFrom(ctx); claims != nil {
+			entry.Username = claims.Username
+		}
+		if p, ok := peer.FromContext(ctx); ok {
+			entry.IPAddress = p.Addr.String()
+			if host, _, err := net.SplitHostPort(entry.IPAddress); err == nil {
+				entry.IPAddress = host
+			}
+		}
+
+		if callErr == nil && action != models.AuditDelete {
+			if entry.EntityID == nil {
+				// Creates carry the new ID in the response
+				if created, ok := resp.(interface{ GetId() int32 }); ok {
+					id := int(created.GetId())
+					entry.EntityID = &id
+				}
+			}
+			if entry.EntityID != nil {
+				after, err := store.GetAuditEntity(ctx, entityType, *entry.EntityID)
+				if err != nil {
+					log.Printf("Audit: failed to load %s %d: %v", entityType, *entry.EntityID, err)
+				}
+				entry.After = middleware.AuditJSON(after)
+			}
+		}
+
+		if err := store.CreateAuditEntry(ctx, &entry); err != nil {
+			log.Printf("Audit: failed to record %s: %v", info.FullMethod, err)
+			return resp, callErr
+		}
+		for _, listener := range listeners {
+			listener.AuditRecorded(entry)
+		}
+		return resp, callErr
+	}
+}
+
+// auditOperation maps a method such as UpdateService to its action and the API resource name
+func auditOperation(method string) (models.AuditAction, string, bool) {
+	for _, op := range []struct {
+		prefix string
+		action models.AuditAction
+	}{
+		{"Create", models.AuditCreate},
+		{"Update", models.AuditUpdate},
+		{"Delete", models.AuditDelete},
+	} {
+		if entity, ok := strings.CutPrefix(method, op.prefix); ok {
+			return op.action, strings.ToLower(entity) + "s", true
+		}
+	}
+	return "", "", false
+}
+
+func requestEntityID(req interface{}) *int {
+	var id int32
+	switch r := req.(type) {
+	case *serviceweaverpb.UpdateDiagramRequest:
+		id = r.GetDiagram().GetId()
+	case *serviceweaverpb.UpdateServiceRequest:
+		id = r.GetService().GetId()
+	case *serviceweaverpb.UpdateConnectionRequest:
+		id = r.GetConnection().GetId()
+	case interface{ GetId() int32 }:
+		id = r.GetId()
+	}
+	if id <= 0 {
+		return nil
+	}
+	value := int(id)
+	return &value
+}
+
+// httpStatus translates a gRPC code to the HTTP status recorded in the audit log
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
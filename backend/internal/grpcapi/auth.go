@@ -0,0 +1,123 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"service-weaver/internal/middleware"
+	"service-weaver/internal/models"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type claimsKey struct{}
+
+type organizationKey struct{}
+
+// organizationMetadata selects the organization of a call, like the X-Organization-ID HTTP header
+var organizationMetadata = strings.ToLower(middleware.OrganizationHeader)
+
+// authStore verifies tokens and resolves the organization of a call
+type authStore interface {
+	middleware.TokenStore
+	middleware.OrganizationStore
+}
+
+// callOrganization is the organization a call acts in and the caller's role there
+type callOrganization struct {
+	id   int
+	role models.OrganizationRole
+}
+
+// authenticate validates the "authorization: Bearer <token>" metadata, the same JWT the HTTP API
+// accepts, and resolves the organization named by the "x-organization-id" metadata
+func authenticate(ctx context.Context, store authStore) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata required")
+	}
+
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization format")
+	}
+	claims, err := middleware.VerifyToken(ctx, store, token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+
+	requested := ""
+	if values := md.Get(organizationMetadata); len(values) > 0 {
+		requested = values[0]
+	}
+	orgID, role, err := middleware.ResolveOrganization(ctx, store, int(claims.UserID), requested)
+	if errors.Is(err, middleware.ErrNoOrganization) {
+		return nil, status.Error(codes.PermissionDenied, "not a member of this organization")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	ctx = context.WithValue(ctx, claimsKey{}, claims)
+	return context.WithValue(ctx, organizationKey{}, callOrganization{id: orgID, role: role}), nil
+}
+
+// unaryAuth authenticates calls and keeps organization viewers from making changes
+func unaryAuth(store authStore) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, store)
+		if err != nil {
+			return nil, err
+		}
+		method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+		if _, _, mutation := auditOperation(method); mutation && organizationFrom(ctx).role == models.OrgRoleViewer {
+			return nil, status.Error(codes.PermissionDenied, "viewers cannot make changes in this organization")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamAuth(store authStore) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), store)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// claimsFrom returns the authenticated user of a call
+func claimsFrom(ctx context.Context) *middleware.TokenClaims {
+	claims, _ := ctx.Value(claimsKey{}).(*middleware.TokenClaims)
+	return claims
+}
+
+// userIDFrom returns the authenticated user's ID, or nil when it is not available
+func userIDFrom(ctx context.Context) *int {
+	claims := claimsFrom(ctx)
+	if claims == nil {
+		return nil
+	}
+	id := int(claims.UserID)
+	return &id
+}
+
+// organizationFrom returns the organization a call acts in
+func organizationFrom(ctx context.Context) callOrganization {
+	org, _ := ctx.Value(organizationKey{}).(callOrganization)
+	return org
+}
@@ -0,0 +1,213 @@
+package grpcapi
+
+import (
+	"log"
+	"service-weaver/internal/grpcapi/serviceweaverpb"
+	"service-weaver/internal/models"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func timestampToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func jsonToProto(value models.JSON) *structpb.Struct {
+	if value == nil {
+		return nil
+	}
+	s, err := structpb.NewStruct(value)
+	if err != nil {
+		log.Printf("gRPC: failed to convert JSON field: %v", err)
+		return nil
+	}
+	return s
+}
+
+func jsonFromProto(value *structpb.Struct) models.JSON {
+	if value == nil {
+		return nil
+	}
+	return value.AsMap()
+}
+
+func diagramToProto(d *models.Diagram) *serviceweaverpb.Diagram {
+	out := &serviceweaverpb.Diagram{
+		Id:                int32(d.ID),
+		Name:              d.Name,
+		Description:       d.Description,
+		Public:            d.Public,
+		StatusPageEnabled: d.StatusPageEnabled,
+		StatusPageTitle:   d.StatusPageTitle,
+		CreatedAt:         timestampToProto(d.CreatedAt),
+		UpdatedAt:         timestampToProto(d.UpdatedAt),
+	}
+	if d.StatusPageSlug != nil {
+		out.StatusPageSlug = *d.StatusPageSlug
+	}
+	if d.OwnerUserID != nil {
+		out.OwnerUserId = int32(*d.OwnerUserID)
+	}
+	out.OwnerTeam = d.OwnerTeam
+	return out
+}
+
+// diagramFromProto converts the writable fields of a diagram; status page settings have their own endpoint
+func diagramFromProto(d *serviceweaverpb.Diagram) models.Diagram {
+	out := models.Diagram{
+		ID:          int(d.GetId()),
+		Name:        d.GetName(),
+		Description: d.GetDescription(),
+		Public:      d.GetPublic(),
+		OwnerTeam:   d.GetOwnerTeam(),
+	}
+	if id := int(d.GetOwnerUserId()); id > 0 {
+		out.OwnerUserID = &id
+	}
+	return out
+}
+
+func serviceToProto(s *models.Service) *serviceweaverpb.Service {
+	out := &serviceweaverpb.Service{
+		Id:                int32(s.ID),
+		DiagramId:         int32(s.DiagramID),
+		Name:              s.Name,
+		Description:       s.Description,
+		ServiceType:       s.ServiceType,
+		Icon:              s.Icon,
+		Host:              s.Host,
+		Port:              int32(s.Port),
+		Tags:              s.Tags,
+		PositionX:         s.PositionX,
+		PositionY:         s.PositionY,
+		HealthcheckMethod: s.HealthcheckMethod,
+		HealthcheckUrl:    s.HealthcheckURL,
+		PollingInterval:   int32(s.PollingInterval),
+		RequestTimeout:    int32(s.RequestTimeout),
+		ExpectedStatus:    int32(s.ExpectedStatus),
+		StatusMapping:     jsonToProto(s.StatusMapping),
+		HttpMethod:        s.HTTPMethod,
+		Headers:           jsonToProto(s.Headers.Masked()),
+		Body:              s.Body,
+		SslVerify:         s.SSLVerify,
+		FollowRedirects:   s.FollowRedirects,
+		TcpSendData:       s.TCPSendData,
+		TcpExpectData:     s.TCPExpectData,
+		UdpSendData:       s.UDPSendData,
+		UdpExpectData:     s.UDPExpectData,
+		IcmpPacketCount:   int32(s.ICMPPacketCount),
+		DnsQueryType:      s.DNSQueryType,
+		DnsExpectedResult: s.DNSExpectedResult,
+		KafkaTopic:        s.KafkaTopic,
+		KafkaClientId:     s.KafkaClientID,
+		SloTarget:         s.SLOTarget,
+		RetentionDays:     int32(s.RetentionDays),
+		RunbookUrl:        s.RunbookURL,
+		Notes:             s.Notes,
+		OwnerTeam:         s.OwnerTeam,
+		FrontendHostUrl:   s.FrontendHostURL,
+		CurrentStatus:     string(s.CurrentStatus),
+		CreatedAt:         timestampToProto(s.CreatedAt),
+		UpdatedAt:         timestampToProto(s.UpdatedAt),
+	}
+	if s.LastChecked != nil {
+		out.LastChecked = timestamppb.New(*s.LastChecked)
+	}
+	if s.ChildDiagramID != nil {
+		out.ChildDiagramId = int32(*s.ChildDiagramID)
+	}
+	if s.OwnerUserID != nil {
+		out.OwnerUserId = int32(*s.OwnerUserID)
+	}
+	return out
+}
+
+// serviceFromProto converts the writable fields of a service
+func serviceFromProto(s *serviceweaverpb.Service) models.Service {
+	out := models.Service{
+		ID:                int(s.GetId()),
+		DiagramID:         int(s.GetDiagramId()),
+		Name:              s.GetName(),
+		Description:       s.GetDescription(),
+		ServiceType:       s.GetServiceType(),
+		Icon:              s.GetIcon(),
+		Host:              s.GetHost(),
+		Port:              int(s.GetPort()),
+		Tags:              s.GetTags(),
+		PositionX:         s.GetPositionX(),
+		PositionY:         s.GetPositionY(),
+		HealthcheckMethod: s.GetHealthcheckMethod(),
+		HealthcheckURL:    s.GetHealthcheckUrl(),
+		PollingInterval:   int(s.GetPollingInterval()),
+		RequestTimeout:    int(s.GetRequestTimeout()),
+		ExpectedStatus:    int(s.GetExpectedStatus()),
+		StatusMapping:     jsonFromProto(s.GetStatusMapping()),
+		HTTPMethod:        s.GetHttpMethod(),
+		Headers:           models.Headers(jsonFromProto(s.GetHeaders())),
+		Body:              s.GetBody(),
+		SSLVerify:         s.GetSslVerify(),
+		FollowRedirects:   s.GetFollowRedirects(),
+		TCPSendData:       s.GetTcpSendData(),
+		TCPExpectData:     s.GetTcpExpectData(),
+		UDPSendData:       s.GetUdpSendData(),
+		UDPExpectData:     s.GetUdpExpectData(),
+		ICMPPacketCount:   int(s.GetIcmpPacketCount()),
+		DNSQueryType:      s.GetDnsQueryType(),
+		DNSExpectedResult: s.GetDnsExpectedResult(),
+		KafkaTopic:        s.GetKafkaTopic(),
+		KafkaClientID:     s.GetKafkaClientId(),
+		SLOTarget:         s.GetSloTarget(),
+		RetentionDays:     int(s.GetRetentionDays()),
+		RunbookURL:        s.GetRunbookUrl(),
+		Notes:             s.GetNotes(),
+		OwnerTeam:         s.GetOwnerTeam(),
+	}
+	if id := int(s.GetChildDiagramId()); id > 0 {
+		out.ChildDiagramID = &id
+	}
+	if id := int(s.GetOwnerUserId()); id > 0 {
+		out.OwnerUserID = &id
+	}
+	return out
+}
+
+func connectionToProto(c *models.Connection) *serviceweaverpb.Connection {
+	return &serviceweaverpb.Connection{
+		Id:        int32(c.ID),
+		DiagramId: int32(c.DiagramID),
+		SourceId:  int32(c.SourceID),
+		TargetId:  int32(c.TargetID),
+		Direction: string(c.Direction),
+		Label:     c.Label,
+		Protocol:  c.Protocol,
+		Metadata:  jsonToProto(c.Metadata),
+		Status:    string(c.Status),
+		CreatedAt: timestampToProto(c.CreatedAt),
+	}
+}
+
+func connectionFromProto(c *serviceweaverpb.Connection) models.Connection {
+	return models.Connection{
+		ID:        int(c.GetId()),
+		DiagramID: int(c.GetDiagramId()),
+		SourceID:  int(c.GetSourceId()),
+		TargetID:  int(c.GetTargetId()),
+		Direction: models.ConnectionDirection(c.GetDirection()),
+		Label:     c.GetLabel(),
+		Protocol:  c.GetProtocol(),
+		Metadata:  jsonFromProto(c.GetMetadata()),
+	}
+}
+
+func statusUpdateToProto(u models.StatusUpdate) *serviceweaverpb.StatusUpdate {
+	return &serviceweaverpb.StatusUpdate{
+		ServiceId: int32(u.ServiceID),
+		Status:    string(u.Status),
+		Timestamp: timestampToProto(u.Timestamp),
+	}
+}
@@ -0,0 +1,500 @@
+// Package grpcapi serves the ServiceWeaver gRPC API defined in proto/serviceweaver/v1.
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"service-weaver/internal/grpcapi/serviceweaverpb"
+	"service-weaver/internal/middleware"
+	"service-weaver/internal/models"
+	"service-weaver/internal/monitoring"
+	"service-weaver/internal/repository"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=service-weaver --go-grpc_out=../.. --go-grpc_opt=module=service-weaver serviceweaver/v1/service_weaver.proto
+
+// statusBuffer is how far a WatchStatus stream may fall behind before updates are dropped
+const statusBuffer = 64
+
+// Server implements serviceweaverpb.ServiceWeaverServer on top of the repository
+type Server struct {
+	serviceweaverpb.UnimplementedServiceWeaverServer
+	repo      *repository.Repository
+	scheduler *monitoring.HealthcheckScheduler
+}
+
+// NewServer returns a gRPC server with the ServiceWeaver service registered. Every call is
+// authenticated with the JWTs issued by the HTTP API and mutations are written to the audit log,
+// then passed to the listeners.
+func NewServer(repo *repository.Repository, scheduler *monitoring.HealthcheckScheduler, listeners ...middleware.AuditListener) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryAuth(repo), auditInterceptor(repo, listeners)),
+		grpc.StreamInterceptor(streamAuth(repo)),
+	)
+	serviceweaverpb.RegisterServiceWeaverServer(server, &Server{repo: repo, scheduler: scheduler})
+	return server
+}
+
+// lookupError maps a missing row to NotFound and anything else to Internal
+func lookupError(err error, what string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return status.Errorf(codes.NotFound, "%s not found", what)
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// serviceError maps an error saving a service to a gRPC status
+func serviceError(err error) error {
+	if errors.Is(err, repository.ErrChildDiagramNotFound) || errors.Is(err, repository.ErrCompositeCycle) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// checkOrganization reports entities outside the caller's organization as missing
+func (s *Server) checkOrganization(ctx context.Context, entityType string, id int, what string) error {
+	orgID, err := s.repo.GetEntityOrganization(ctx, entityType, id)
+	if err != nil {
+		return lookupError(err, what)
+	}
+	if orgID != organizationFrom(ctx).id {
+		return status.Errorf(codes.NotFound, "%s not found", what)
+	}
+	return nil
+}
+
+// checkOwner fails unless the owner a request assigns, if any, may act in its organization
+func (s *Server) checkOwner(ctx context.Context, ownerUserID *int) error {
+	if ownerUserID == nil {
+		return nil
+	}
+	if _, err := s.repo.GetOrganizationRole(ctx, *ownerUserID, organizationFrom(ctx).id); err != nil {
+		return status.Error(codes.InvalidArgument, "owner is not a member of the organization")
+	}
+	return nil
+}
+
+func (s *Server) recordVersion(ctx context.Context, diagramID int, summary string) {
+	if _, err := s.repo.RecordDiagramVersion(ctx, diagramID, userIDFrom(ctx), summary); err != nil {
+		log.Printf("Error recording version of diagram %d: %v", diagramID, err)
+	}
+}
+
+// Diagrams
+
+func (s *Server) ListDiagrams(ctx context.Context, req *serviceweaverpb.ListDiagramsRequest) (*serviceweaverpb.ListDiagramsResponse, error) {
+	if req.GetLimit() < 0 || req.GetOffset() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit and offset must not be negative")
+	}
+
+	filter := repository.DiagramFilter{
+		OrganizationID: organizationFrom(ctx).id,
+		Name:           req.GetQuery(),
+		ListOptions:    repository.ListOptions{Limit: int(req.GetLimit()), Offset: int(req.GetOffset())},
+	}
+	// Non-admin users only ever see public diagrams
+	if claims := claimsFrom(ctx); claims == nil || claims.Role != models.RoleAdmin {
+		public := true
+		filter.Public = &public
+	}
+
+	diagrams, total, err := s.repo.ListDiagrams(ctx, filter)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &serviceweaverpb.ListDiagramsResponse{Total: int32(total)}
+	for i := range diagrams {
+		resp.Diagrams = append(resp.Diagrams, diagramToProto(&diagrams[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) GetDiagram(ctx context.Context, req *serviceweaverpb.GetDiagramRequest) (*serviceweaverpb.GetDiagramResponse, error) {
+	id := int(req.GetId())
+	if err := s.checkOrganization(ctx, "diagrams", id, "diagram"); err != nil {
+		return nil, err
+	}
+	diagram, err := s.repo.GetDiagram(ctx, id)
+	if err != nil {
+		return nil, lookupError(err, "diagram")
+	}
+	services, err := s.repo.GetServices(ctx, id)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	connections, err := s.repo.GetConnections(ctx, id)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &serviceweaverpb.GetDiagramResponse{Diagram: diagramToProto(diagram)}
+	for i := range services {
+		resp.Services = append(resp.Services, serviceToProto(&services[i]))
+	}
+	for i := range connections {
+		resp.Connections = append(resp.Connections, connectionToProto(&connections[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) CreateDiagram(ctx context.Context, req *serviceweaverpb.CreateDiagramRequest) (*serviceweaverpb.Diagram, error) {
+	if req.GetDiagram() == nil {
+		return nil, status.Error(codes.InvalidArgument, "diagram is required")
+	}
+
+	diagram := diagramFromProto(req.GetDiagram())
+	diagram.OrganizationID = organizationFrom(ctx).id
+	if err := s.checkOwner(ctx, diagram.OwnerUserID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateDiagram(ctx, &diagram); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	s.recordVersion(ctx, diagram.ID, "Created diagram")
+	return diagramToProto(&diagram), nil
+}
+
+func (s *Server) UpdateDiagram(ctx context.Context, req *serviceweaverpb.UpdateDiagramRequest) (*serviceweaverpb.Diagram, error) {
+	if req.GetDiagram().GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "diagram.id is required")
+	}
+	if err := s.checkOrganization(ctx, "diagrams", int(req.GetDiagram().GetId()), "diagram"); err != nil {
+		return nil, err
+	}
+
+	diagram := diagramFromProto(req.GetDiagram())
+	if err := s.checkOwner(ctx, diagram.OwnerUserID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateDiagram(ctx, &diagram); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	updated, err := s.repo.GetDiagram(ctx, diagram.ID)
+	if err != nil {
+		return nil, lookupError(err, "diagram")
+	}
+
+	s.recordVersion(ctx, diagram.ID, "Edited diagram details")
+	return diagramToProto(updated), nil
+}
+
+func (s *Server) DeleteDiagram(ctx context.Context, req *serviceweaverpb.DeleteDiagramRequest) (*serviceweaverpb.DeleteResponse, error) {
+	if err := s.checkOrganization(ctx, "diagrams", int(req.GetId()), "diagram"); err != nil {
+		return nil, err
+	}
+	if err := s.repo.DeleteDiagram(ctx, int(req.GetId())); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &serviceweaverpb.DeleteResponse{}, nil
+}
+
+// Services
+
+func (s *Server) ListServices(ctx context.Context, req *serviceweaverpb.ListServicesRequest) (*serviceweaverpb.ListServicesResponse, error) {
+	if err := s.checkOrganization(ctx, "diagrams", int(req.GetDiagramId()), "diagram"); err != nil {
+		return nil, err
+	}
+	services, err := s.repo.GetServices(ctx, int(req.GetDiagramId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &serviceweaverpb.ListServicesResponse{}
+	for i := range services {
+		resp.Services = append(resp.Services, serviceToProto(&services[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) GetService(ctx context.Context, req *serviceweaverpb.GetServiceRequest) (*serviceweaverpb.Service, error) {
+	if err := s.checkOrganization(ctx, "services", int(req.GetId()), "service"); err != nil {
+		return nil, err
+	}
+	service, err := s.repo.GetServiceByID(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, lookupError(err, "service")
+	}
+	return serviceToProto(service), nil
+}
+
+func (s *Server) CreateService(ctx context.Context, req *serviceweaverpb.CreateServiceRequest) (*serviceweaverpb.Service, error) {
+	if req.GetService().GetDiagramId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "service.diagram_id is required")
+	}
+	if err := s.checkOrganization(ctx, "diagrams", int(req.GetService().GetDiagramId()), "diagram"); err != nil {
+		return nil, err
+	}
+
+	service := serviceFromProto(req.GetService())
+	if service.SLOTarget <= 0 {
+		service.SLOTarget = models.DefaultSLOTarget
+	}
+	if err := validateHealthcheck(&service); err != nil {
+		return nil, err
+	}
+	if err := s.checkOwner(ctx, service.OwnerUserID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateService(ctx, &service); err != nil {
+		return nil, serviceError(err)
+	}
+	created, err := s.repo.GetServiceByID(ctx, service.ID)
+	if err != nil {
+		return nil, lookupError(err, "service")
+	}
+
+	s.recordVersion(ctx, created.DiagramID, fmt.Sprintf("Added service %s", created.Name))
+	return serviceToProto(created), nil
+}
+
+func (s *Server) UpdateService(ctx context.Context, req *serviceweaverpb.UpdateServiceRequest) (*serviceweaverpb.Service, error) {
+	if req.GetService().GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "service.id is required")
+	}
+	if err := s.checkOrganization(ctx, "services", int(req.GetService().GetId()), "service"); err != nil {
+		return nil, err
+	}
+
+	service := serviceFromProto(req.GetService())
+	if service.SLOTarget <= 0 {
+		service.SLOTarget = models.DefaultSLOTarget
+	}
+	if err := validateHealthcheck(&service); err != nil {
+		return nil, err
+	}
+	if err := s.checkOwner(ctx, service.OwnerUserID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateService(ctx, &service); err != nil {
+		return nil, serviceError(err)
+	}
+	updated, err := s.repo.GetServiceByID(ctx, service.ID)
+	if err != nil {
+		return nil, lookupError(err, "service")
+	}
+
+	s.recordVersion(ctx, updated.DiagramID, fmt.Sprintf("Edited service %s", updated.Name))
+	return serviceToProto(updated), nil
+}
+
+func (s *Server) DeleteService(ctx context.Context, req *serviceweaverpb.DeleteServiceRequest) (*serviceweaverpb.DeleteResponse, error) {
+	id := int(req.GetId())
+	if err := s.checkOrganization(ctx, "services", id, "service"); err != nil {
+		return nil, err
+	}
+	existing, lookupErr := s.repo.GetServiceByID(ctx, id)
+	if err := s.repo.DeleteService(ctx, id); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if lookupErr == nil {
+		s.recordVersion(ctx, existing.DiagramID, fmt.Sprintf("Removed service %s", existing.Name))
+	}
+	return &serviceweaverpb.DeleteResponse{}, nil
+}
+
+// Connections
+
+func (s *Server) ListConnections(ctx context.Context, req *serviceweaverpb.ListConnectionsRequest) (*serviceweaverpb.ListConnectionsResponse, error) {
+	if err := s.checkOrganization(ctx, "diagrams", int(req.GetDiagramId()), "diagram"); err != nil {
+		return nil, err
+	}
+	connections, err := s.repo.GetConnections(ctx, int(req.GetDiagramId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &serviceweaverpb.ListConnectionsResponse{}
+	for i := range connections {
+		resp.Connections = append(resp.Connections, connectionToProto(&connections[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) CreateConnection(ctx context.Context, req *serviceweaverpb.CreateConnectionRequest) (*serviceweaverpb.Connection, error) {
+	if req.GetConnection().GetDiagramId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "connection.diagram_id is required")
+	}
+	if err := s.checkOrganization(ctx, "diagrams", int(req.GetConnection().GetDiagramId()), "diagram"); err != nil {
+		return nil, err
+	}
+
+	connection := connectionFromProto(req.GetConnection())
+	if err := validateConnection(&connection); err != nil {
+		return nil, err
+	}
+	if err := s.checkEndpoints(ctx, &connection); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateConnection(ctx, &connection); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	s.recordVersion(ctx, connection.DiagramID, "Added connection")
+	return connectionToProto(&connection), nil
+}
+
+func (s *Server) UpdateConnection(ctx context.Context, req *serviceweaverpb.UpdateConnectionRequest) (*serviceweaverpb.Connection, error) {
+	if req.GetConnection().GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "connection.id is required")
+	}
+	if err := s.checkOrganization(ctx, "connections", int(req.GetConnection().GetId()), "connection"); err != nil {
+		return nil, err
+	}
+
+	connection := connectionFromProto(req.GetConnection())
+	if err := validateConnection(&connection); err != nil {
+		return nil, err
+	}
+	if err := s.checkEndpoints(ctx, &connection); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateConnection(ctx, &connection); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	updated, err := s.repo.GetConnection(ctx, connection.ID)
+	if err != nil {
+		return nil, lookupError(err, "connection")
+	}
+
+	s.recordVersion(ctx, updated.DiagramID, "Edited connection")
+	return connectionToProto(updated), nil
+}
+
+// checkEndpoints requires both services of a connection to belong to the caller's organization
+func (s *Server) checkEndpoints(ctx context.Context, connection *models.Connection) error {
+	if err := s.checkOrganization(ctx, "services", connection.SourceID, "source service"); err != nil {
+		return err
+	}
+	return s.checkOrganization(ctx, "services", connection.TargetID, "target service")
+}
+
+// validateHealthcheck applies the same healthcheck configuration rules as the HTTP API, listing the
+// fields at fault in the message
+func validateHealthcheck(service *models.Service) error {
+	problems := models.ValidateHealthcheck(service)
+	if len(problems) == 0 {
+		return nil
+	}
+	messages := make([]string, len(problems))
+	for i, problem := range problems {
+		messages[i] = "service." + problem.Field + " " + problem.Message
+	}
+	return status.Error(codes.InvalidArgument, strings.Join(messages, "; "))
+}
+
+// validateConnection applies the same direction and protocol rules as the HTTP API
+func validateConnection(connection *models.Connection) error {
+	if !connection.Direction.Valid() {
+		return status.Error(codes.InvalidArgument, "connection.direction must be forward, backward, bidirectional or none")
+	}
+	connection.Protocol = strings.ToLower(strings.TrimSpace(connection.Protocol))
+	if !models.ValidConnectionProtocol(connection.Protocol) {
+		return status.Errorf(codes.InvalidArgument, "unknown connection.protocol %q", connection.Protocol)
+	}
+	return nil
+}
+
+func (s *Server) DeleteConnection(ctx context.Context, req *serviceweaverpb.DeleteConnectionRequest) (*serviceweaverpb.DeleteResponse, error) {
+	id := int(req.GetId())
+	if err := s.checkOrganization(ctx, "connections", id, "connection"); err != nil {
+		return nil, err
+	}
+	existing, lookupErr := s.repo.GetConnection(ctx, id)
+	if err := s.repo.DeleteConnection(ctx, id); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if lookupErr == nil {
+		s.recordVersion(ctx, existing.DiagramID, "Removed connection")
+	}
+	return &serviceweaverpb.DeleteResponse{}, nil
+}
+
+// Status streaming
+
+// WatchStatus streams status updates of the caller's organization until the client cancels.
+// Updates are filtered by diagram and/or service when the request sets them.
+func (s *Server) WatchStatus(req *serviceweaverpb.WatchStatusRequest, stream serviceweaverpb.ServiceWeaver_WatchStatusServer) error {
+	ctx := stream.Context()
+	if req.GetDiagramId() != 0 {
+		if err := s.checkOrganization(ctx, "diagrams", int(req.GetDiagramId()), "diagram"); err != nil {
+			return err
+		}
+	}
+
+	filter := &statusFilter{
+		ctx:            ctx,
+		repo:           s.repo,
+		organizationID: organizationFrom(ctx).id,
+		diagramID:      int(req.GetDiagramId()),
+		diagrams:       make(map[int]int),
+	}
+	if len(req.GetServiceIds()) > 0 {
+		filter.services = make(map[int]bool)
+		for _, id := range req.GetServiceIds() {
+			filter.services[int(id)] = true
+		}
+	}
+
+	updates, unsubscribe := s.scheduler.Subscribe(statusBuffer)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			if !filter.matches(update.ServiceID) {
+				continue
+			}
+			if err := stream.Send(statusUpdateToProto(update)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// statusFilter selects the updates a WatchStatus stream asked for, caching the diagram of each
+// service; services of other organizations are cached with diagram 0
+type statusFilter struct {
+	ctx            context.Context
+	repo           *repository.Repository
+	organizationID int
+	diagramID      int
+	services       map[int]bool
+	diagrams       map[int]int
+}
+
+func (f *statusFilter) matches(serviceID int) bool {
+	if f.services != nil && !f.services[serviceID] {
+		return false
+	}
+
+	diagramID, ok := f.diagrams[serviceID]
+	if !ok {
+		service, err := f.repo.GetServiceByID(f.ctx, serviceID)
+		if err != nil {
+			return false
+		}
+		diagramID = service.DiagramID
+		if orgID, err := f.repo.GetEntityOrganization(f.ctx, "diagrams", diagramID); err != nil || orgID != f.organizationID {
+			diagramID = 0
+		}
+		f.diagrams[serviceID] = diagramID
+	}
+	return diagramID != 0 && (f.diagramID == 0 || diagramID == f.diagramID)
+}
@@ -0,0 +1,124 @@
+// Package importers converts the monitors of other uptime tools, Uptime Kuma and Uptime Robot, into
+// diagram services, to ease migrating to Service Weaver
+package importers
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"service-weaver/internal/models"
+	"strconv"
+	"strings"
+)
+
+// Layout of imported nodes, appended below the existing ones
+const (
+	gridColumns  = 6
+	gridSpacingX = 240
+	gridSpacingY = 160
+)
+
+// Place positions services on a grid after the existing nodes of their diagram
+func Place(services []models.Service, existing int) {
+	for i := range services {
+		slot := existing + i
+		services[i].PositionX = float64(100 + (slot%gridColumns)*gridSpacingX)
+		services[i].PositionY = float64(100 + (slot/gridColumns)*gridSpacingY)
+	}
+}
+
+// newService builds a service with the same defaults the editor uses for new services
+func newService(name, serviceType, method string) models.Service {
+	return models.Service{
+		Name:              name,
+		ServiceType:       serviceType,
+		Icon:              serviceType,
+		HealthcheckMethod: method,
+		PollingInterval:   30,
+		RequestTimeout:    5,
+		ExpectedStatus:    200,
+		StatusMapping:     models.JSON{},
+		HTTPMethod:        "GET",
+		Headers:           models.Headers{},
+		SSLVerify:         true,
+		FollowRedirects:   true,
+		ICMPPacketCount:   3,
+		DNSQueryType:      "A",
+		SLOTarget:         models.DefaultSLOTarget,
+	}
+}
+
+// defaultPorts are the ports of the healthcheck methods whose address may leave it out
+var defaultPorts = map[string]int{
+	"http":     80,
+	"https":    443,
+	"ftp":      21,
+	"smtp":     25,
+	"mysql":    3306,
+	"postgres": 5432,
+	"mongodb":  27017,
+	"redis":    6379,
+}
+
+// applyURL points an HTTP check at a URL: its scheme picks HTTP or HTTPS, and its path and query
+// become the healthcheck URL
+func applyURL(service *models.Service, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("invalid URL %q", raw)
+	}
+	service.HealthcheckMethod = strings.ToUpper(u.Scheme)
+	service.Host = u.Hostname()
+	service.Port = defaultPorts[u.Scheme]
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		service.Port = port
+	}
+	service.HealthcheckURL = u.RequestURI()
+	if u.User != nil {
+		password, _ := u.User.Password()
+		service.Credentials = &models.ServiceCredentials{Username: u.User.Username(), Password: password}
+	}
+	return nil
+}
+
+// applyAddress points a check at a host:port address, keeping defaultPort when it has none
+func applyAddress(service *models.Service, address string, defaultPort int) error {
+	host, portText, err := net.SplitHostPort(address)
+	if err != nil {
+		host, portText = address, ""
+	}
+	if host == "" {
+		return fmt.Errorf("invalid address %q", address)
+	}
+	service.Host = host
+	service.Port = defaultPort
+	if port, err := strconv.Atoi(portText); err == nil {
+		service.Port = port
+	}
+	return nil
+}
+
+// applyConnectionString points a database check at the server of a connection URL, such as
+// postgres://user:password@db:5432/app, along with its credentials
+func applyConnectionString(service *models.Service, scheme, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("invalid connection string")
+	}
+	service.Host = u.Hostname()
+	service.Port = defaultPorts[scheme]
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		service.Port = port
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		service.Credentials = &models.ServiceCredentials{Username: u.User.Username(), Password: password}
+	}
+	return nil
+}
+
+// paused marks a service converted from a monitor that was paused, so it is not checked either
+func paused(service *models.Service) {
+	p := true
+	service.Paused = &p
+}
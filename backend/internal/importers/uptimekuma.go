@@ -0,0 +1,206 @@
+package importers
+
+import (
+	"encoding/json"
+	"fmt"
+	"service-weaver/internal/models"
+	"strconv"
+	"strings"
+)
+
+// kumaBackup is the JSON file of Uptime Kuma's Settings > Backup > Export
+type kumaBackup struct {
+	Version     string        `json:"version"`
+	MonitorList []kumaMonitor `json:"monitorList"`
+}
+
+type kumaMonitor struct {
+	Name                     string          `json:"name"`
+	Description              string          `json:"description"`
+	Type                     string          `json:"type"`
+	Active                   *kumaBool       `json:"active"`
+	URL                      string          `json:"url"`
+	Method                   string          `json:"method"`
+	Body                     string          `json:"body"`
+	Headers                  string          `json:"headers"` // A JSON object, as typed into the form
+	Hostname                 string          `json:"hostname"`
+	Port                     int             `json:"port"`
+	Interval                 int             `json:"interval"` // Seconds
+	Timeout                  float64         `json:"timeout"`  // Seconds
+	AcceptedStatusCodes      []string        `json:"accepted_statuscodes"`
+	IgnoreTLS                kumaBool        `json:"ignoreTls"`
+	MaxRedirects             *int            `json:"maxredirects"`
+	DNSResolveType           string          `json:"dns_resolve_type"`
+	DatabaseConnectionString string          `json:"databaseConnectionString"`
+	GRPCURL                  string          `json:"grpcUrl"`
+	KafkaProducerBrokers     json.RawMessage `json:"kafkaProducerBrokers"` // A list, or a list encoded as a string
+	KafkaProducerTopic       string          `json:"kafkaProducerTopic"`
+	Tags                     []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"tags"`
+}
+
+// kumaBool reads the flags of monitors, which older versions of Uptime Kuma export as 0 or 1
+type kumaBool bool
+
+func (b *kumaBool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", "1":
+		*b = true
+	case "false", "0", "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid flag %s", data)
+	}
+	return nil
+}
+
+// kumaDNSTypes maps the record types Uptime Kuma resolves to those DNS checks query
+var kumaDNSTypes = map[string]string{
+	"A":     "A",
+	"AAAA":  "A",
+	"CNAME": "CNAME",
+	"MX":    "MX",
+	"NS":    "NS",
+	"TXT":   "TXT",
+}
+
+// kumaDatabases maps the database monitor types of Uptime Kuma to healthcheck methods
+var kumaDatabases = map[string]string{
+	"mysql":    "MYSQL",
+	"postgres": "POSTGRES",
+	"mongodb":  "MONGODB",
+	"redis":    "REDIS",
+}
+
+// UptimeKuma converts the monitors of an Uptime Kuma backup into services. Push, Docker, group
+// and other monitors without an equivalent healthcheck are skipped.
+func UptimeKuma(data []byte) ([]models.Service, []models.SkippedMonitor, error) {
+	var backup kumaBackup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, nil, fmt.Errorf("invalid Uptime Kuma backup: %w", err)
+	}
+	if backup.MonitorList == nil {
+		return nil, nil, fmt.Errorf("invalid Uptime Kuma backup: no monitorList")
+	}
+
+	services := []models.Service{}
+	skipped := []models.SkippedMonitor{}
+	for _, monitor := range backup.MonitorList {
+		service, err := convertKumaMonitor(monitor)
+		if err != nil {
+			skipped = append(skipped, models.SkippedMonitor{Name: monitor.Name, Type: monitor.Type, Reason: err.Error()})
+			continue
+		}
+		services = append(services, service)
+	}
+	return services, skipped, nil
+}
+
+func convertKumaMonitor(m kumaMonitor) (models.Service, error) {
+	var service models.Service
+	var err error
+	switch m.Type {
+	case "http", "keyword", "json-query":
+		service = newService(m.Name, "web", "HTTP")
+		err = applyURL(&service, m.URL)
+		if m.Method != "" {
+			service.HTTPMethod = strings.ToUpper(m.Method)
+		}
+		service.Body = m.Body
+		if m.Headers != "" {
+			if json.Unmarshal([]byte(m.Headers), &service.Headers) != nil {
+				return service, fmt.Errorf("headers are not a JSON object")
+			}
+		}
+		service.ExpectedStatus = kumaExpectedStatus(m.AcceptedStatusCodes)
+		service.SSLVerify = !bool(m.IgnoreTLS)
+		service.FollowRedirects = m.MaxRedirects == nil || *m.MaxRedirects > 0
+	case "port":
+		service = newService(m.Name, "service", "TCP")
+		service.Host, service.Port = m.Hostname, m.Port
+	case "ping":
+		service = newService(m.Name, "compute", "ICMP")
+		service.Host = m.Hostname
+	case "dns":
+		service = newService(m.Name, "service", "DNS")
+		service.Host, service.Port = m.Hostname, 53
+		queryType, ok := kumaDNSTypes[m.DNSResolveType]
+		if !ok && m.DNSResolveType != "" {
+			return service, fmt.Errorf("unsupported DNS record type %s", m.DNSResolveType)
+		}
+		if ok {
+			service.DNSQueryType = queryType
+		}
+	case "grpc-keyword":
+		service = newService(m.Name, "api", "GRPC")
+		err = applyAddress(&service, m.GRPCURL, 443)
+	case "mysql", "postgres", "mongodb", "redis":
+		serviceType := "database"
+		if m.Type == "redis" {
+			serviceType = "cache"
+		}
+		service = newService(m.Name, serviceType, kumaDatabases[m.Type])
+		err = applyConnectionString(&service, m.Type, m.DatabaseConnectionString)
+	case "kafka-producer":
+		service = newService(m.Name, "queue", "KAFKA")
+		brokers := stringList(m.KafkaProducerBrokers)
+		if len(brokers) == 0 {
+			return service, fmt.Errorf("no broker")
+		}
+		err = applyAddress(&service, brokers[0], 9092)
+		service.KafkaTopic = m.KafkaProducerTopic
+	default:
+		return service, fmt.Errorf("unsupported monitor type")
+	}
+	if err != nil {
+		return service, err
+	}
+	if service.Host == "" {
+		return service, fmt.Errorf("no hostname")
+	}
+
+	service.Description = m.Description
+	if m.Interval > 0 {
+		service.PollingInterval = m.Interval
+	}
+	if m.Timeout >= 1 {
+		service.RequestTimeout = int(m.Timeout)
+	}
+	tags := make([]string, 0, len(m.Tags))
+	for _, tag := range m.Tags {
+		tags = append(tags, tag.Name)
+	}
+	service.Tags = strings.Join(tags, ",")
+	if m.Active != nil && !bool(*m.Active) {
+		paused(&service)
+	}
+	return service, nil
+}
+
+// kumaExpectedStatus picks the status code a check expects from Uptime Kuma's accepted codes,
+// such as 200-299 or 301: the lowest of the first entry
+func kumaExpectedStatus(accepted []string) int {
+	if len(accepted) == 0 {
+		return 200
+	}
+	low, _, _ := strings.Cut(accepted[0], "-")
+	if code, err := strconv.Atoi(strings.TrimSpace(low)); err == nil && code >= 100 {
+		return code
+	}
+	return 200
+}
+
+// stringList reads a JSON list of strings, or such a list encoded as a JSON string
+func stringList(raw json.RawMessage) []string {
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		return list
+	}
+	var encoded string
+	if json.Unmarshal(raw, &encoded) == nil && json.Unmarshal([]byte(encoded), &list) == nil {
+		return list
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package importers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"service-weaver/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UptimeRobotAPI is the base URL of the Uptime Robot API
+var UptimeRobotAPI = "https://api.uptimerobot.com/v2"
+
+// robotPageSize is the most monitors getMonitors returns at once
+const robotPageSize = 50
+
+var robotClient = &http.Client{Timeout: 30 * time.Second}
+
+type robotResponse struct {
+	Stat  string `json:"stat"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Pagination struct {
+		Total int `json:"total"`
+	} `json:"pagination"`
+	Monitors []robotMonitor `json:"monitors"`
+}
+
+type robotMonitor struct {
+	FriendlyName string     `json:"friendly_name"`
+	URL          string     `json:"url"`
+	Type         int        `json:"type"`
+	SubType      robotInt   `json:"sub_type"`
+	Port         robotInt   `json:"port"`
+	Interval     int        `json:"interval"` // Seconds
+	Timeout      int        `json:"timeout"`  // Seconds
+	Status       int        `json:"status"`
+	HTTPMethod   int        `json:"http_method"`
+	HTTPUsername string     `json:"http_username"`
+	HTTPPassword string     `json:"http_password"`
+	Tags         []robotTag `json:"tags"`
+}
+
+type robotTag struct {
+	Name string `json:"name"`
+}
+
+// robotInt reads the numbers Uptime Robot sends as an empty string when unset
+type robotInt int
+
+func (n *robotInt) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		*n = 0
+		return nil
+	}
+	value, err := strconv.Atoi(text)
+	*n = robotInt(value)
+	return err
+}
+
+// Monitor types and the sub types of port monitors
+const (
+	robotHTTP      = 1
+	robotKeyword   = 2
+	robotPing      = 3
+	robotPort      = 4
+	robotHeartbeat = 5
+
+	robotPortHTTP  = 1
+	robotPortHTTPS = 2
+	robotPortFTP   = 3
+	robotPortSMTP  = 4
+)
+
+// robotPaused is the status of paused monitors
+const robotPaused = 0
+
+// robotMethods maps Uptime Robot's HTTP method codes
+var robotMethods = map[int]string{1: "HEAD", 2: "GET", 3: "POST", 4: "PUT", 5: "PATCH", 6: "DELETE", 7: "OPTIONS"}
+
+// robotTypeNames names the monitor types for skipped monitors
+var robotTypeNames = map[int]string{robotHTTP: "http", robotKeyword: "keyword", robotPing: "ping", robotPort: "port", robotHeartbeat: "heartbeat"}
+
+// UptimeRobot fetches the monitors of the Uptime Robot account of an API key and converts them
+// into services. Heartbeat monitors, which have no equivalent healthcheck, are skipped.
+func UptimeRobot(ctx context.Context, apiKey string) ([]models.Service, []models.SkippedMonitor, error) {
+	monitors, err := fetchRobotMonitors(ctx, apiKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	services := []models.Service{}
+	skipped := []models.SkippedMonitor{}
+	for _, monitor := range monitors {
+		service, err := convertRobotMonitor(monitor)
+		if err != nil {
+			skipped = append(skipped, models.SkippedMonitor{Name: monitor.FriendlyName, Type: robotTypeNames[monitor.Type], Reason: err.Error()})
+			continue
+		}
+		services = append(services, service)
+	}
+	return services, skipped, nil
+}
+
+// fetchRobotMonitors pages through getMonitors
+func fetchRobotMonitors(ctx context.Context, apiKey string) ([]robotMonitor, error) {
+	var monitors []robotMonitor
+	for {
+		form := url.Values{
+			"api_key": {apiKey},
+			"format":  {"json"},
+			"offset":  {strconv.Itoa(len(monitors))},
+			"limit":   {strconv.Itoa(robotPageSize)},
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, UptimeRobotAPI+"/getMonitors", strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		resp, err := robotClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reach Uptime Robot: %w", err)
+		}
+		var page robotResponse
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unexpected Uptime Robot response (HTTP %d): %w", resp.StatusCode, err)
+		}
+		if page.Stat != "ok" {
+			return nil, fmt.Errorf("Uptime Robot: %s", page.Error.Message)
+		}
+
+		monitors = append(monitors, page.Monitors...)
+		if len(page.Monitors) == 0 || len(monitors) >= page.Pagination.Total {
+			return monitors, nil
+		}
+	}
+}
+
+func convertRobotMonitor(m robotMonitor) (models.Service, error) {
+	var service models.Service
+	var err error
+	switch m.Type {
+	case robotHTTP, robotKeyword:
+		service = newService(m.FriendlyName, "web", "HTTP")
+		err = applyURL(&service, m.URL)
+		if method, ok := robotMethods[m.HTTPMethod]; ok {
+			service.HTTPMethod = method
+		}
+		if m.HTTPUsername != "" {
+			service.Credentials = &models.ServiceCredentials{Username: m.HTTPUsername, Password: m.HTTPPassword}
+		}
+	case robotPing:
+		service = newService(m.FriendlyName, "compute", "ICMP")
+		service.Host = m.URL
+	case robotPort:
+		switch m.SubType {
+		case robotPortHTTP:
+			service = newService(m.FriendlyName, "web", "HTTP")
+			service.HealthcheckURL = "/"
+		case robotPortHTTPS:
+			service = newService(m.FriendlyName, "web", "HTTPS")
+			service.HealthcheckURL = "/"
+		case robotPortFTP:
+			service = newService(m.FriendlyName, "service", "FTP")
+		case robotPortSMTP:
+			service = newService(m.FriendlyName, "service", "SMTP")
+		default:
+			service = newService(m.FriendlyName, "service", "TCP")
+		}
+		service.Host, service.Port = m.URL, int(m.Port)
+		if service.Port == 0 {
+			service.Port = defaultPorts[strings.ToLower(service.HealthcheckMethod)]
+		}
+	default:
+		return service, fmt.Errorf("unsupported monitor type")
+	}
+	if err != nil {
+		return service, err
+	}
+	if service.Host == "" {
+		return service, fmt.Errorf("no hostname")
+	}
+
+	if m.Interval > 0 {
+		service.PollingInterval = m.Interval
+	}
+	if m.Timeout > 0 {
+		service.RequestTimeout = m.Timeout
+	}
+	tags := make([]string, 0, len(m.Tags))
+	for _, tag := range m.Tags {
+		tags = append(tags, tag.Name)
+	}
+	service.Tags = strings.Join(tags, ",")
+	if m.Status == robotPaused {
+		paused(&service)
+	}
+	return service, nil
+}
@@ -0,0 +1,71 @@
+// Package metrics renders values in the Prometheus text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Labels are the label pairs attached to a sample
+type Labels map[string]string
+
+// Writer emits metric families in the Prometheus text format (version 0.0.4)
+type Writer struct {
+	w *bufio.Writer
+}
+
+// ContentType is the HTTP content type of the exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Family writes the HELP and TYPE lines that precede a metric's samples
+func (w *Writer) Family(name, help, metricType string) {
+	w.w.WriteString("# HELP " + name + " " + escapeHelp(help) + "\n")
+	w.w.WriteString("# TYPE " + name + " " + metricType + "\n")
+}
+
+// Sample writes a single sample line
+func (w *Writer) Sample(name string, labels Labels, value float64) {
+	w.w.WriteString(name)
+	if len(labels) > 0 {
+		keys := make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		w.w.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				w.w.WriteByte(',')
+			}
+			w.w.WriteString(k + `="` + escapeLabel(labels[k]) + `"`)
+		}
+		w.w.WriteByte('}')
+	}
+	w.w.WriteByte(' ')
+	w.w.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	w.w.WriteByte('\n')
+}
+
+// Flush writes any buffered output
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"service-weaver/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAuditBody caps how much of a response is kept to reconstruct the state of created entities
+const maxAuditBody = 64 * 1024
+
+// auditRedactedKeys are config keys whose values never reach the audit log
+var auditRedactedKeys = []string{"password", "token", "secret", "api_key", "apikey", "private", "webhook", "kubeconfig_data"}
+
+// AuditStore persists audit entries and loads entity state for them
+type AuditStore interface {
+	GetAuditEntity(ctx context.Context, entityType string, id int) (interface{}, error)
+	CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+}
+
+// AuditListener is notified of every audit entry after it has been stored
+type AuditListener interface {
+	AuditRecorded(entry models.AuditEntry)
+}
+
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.body.Len()+len(data) <= maxAuditBody {
+		w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	if w.body.Len()+len(s) <= maxAuditBody {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Audit records every POST, PUT, PATCH and DELETE request with the state of the affected entity
+// before and after the change, then passes the entry to the listeners. It must run after
+// AuthMiddleware so the user is known.
+func Audit(store AuditStore, listeners ...AuditListener) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		action, ok := auditAction(c.Request.Method)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		entityType, entityID := auditEntity(c)
+		var before interface{}
+		if entityID != nil {
+			var err error
+			if before, err = store.GetAuditEntity(c.Request.Context(), entityType, *entityID); err != nil {
+				log.Printf("Audit: failed to load %s %d: %v", entityType, *entityID, err)
+			}
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		entry := models.AuditEntry{
+			Action:     action,
+			Method:     c.Request.Method,
+			Route:      c.FullPath(),
+			EntityType: entityType,
+			EntityID:   entityID,
+			Before:     AuditJSON(before),
+			StatusCode: writer.Status(),
+			IPAddress:  c.ClientIP(),
+		}
+		if userID, exists := c.Get("user_id"); exists {
+			if id, ok := userID.(uint); ok {
+				value := int(id)
+				entry.UserID = &value
+			}
+		}
+		entry.Username = c.GetString("username")
+
+		if entry.StatusCode < http.StatusBadRequest && action != models.AuditDelete {
+			switch {
+			case entityID == nil:
+				// Creates have no ID in the route; the response body describes the new entity
+				entry.After, entry.EntityID = auditResponse(writer.body.Bytes())
+			case strings.HasSuffix(entry.Route, "/:id/copy"):
+				// Copies leave the source untouched; record the creation of the copy instead
+				entry.Before = nil
+				entry.After, entry.EntityID = auditResponse(writer.body.Bytes())
+			default:
+				after, err := store.GetAuditEntity(c.Request.Context(), entityType, *entityID)
+				if err != nil {
+					log.Printf("Audit: failed to load %s %d: %v", entityType, *entityID, err)
+				}
+				entry.After = AuditJSON(after)
+			}
+		}
+
+		if err := store.CreateAuditEntry(c.Request.Context(), &entry); err != nil {
+			log.Printf("Audit: failed to record %s %s: %v", entry.Method, entry.Route, err)
+			return
+		}
+		for _, listener := range listeners {
+			listener.AuditRecorded(entry)
+		}
+	}
+}
+
+func auditAction(method string) (models.AuditAction, bool) {
+	switch method {
+	case http.MethodPost:
+		return models.AuditCreate, true
+	case http.MethodPut, http.MethodPatch:
+		return models.AuditUpdate, true
+	case http.MethodDelete:
+		return models.AuditDelete, true
+	default:
+		return "", false
+	}
+}
+
+// auditEntity derives the resource name and ID from the matched route, e.g. /api/services/:id
+func auditEntity(c *gin.Context) (string, *int) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(c.FullPath(), "/api"), "/"), "/")
+	if len(parts) > 1 && parts[0] == "admin" {
+		parts = parts[1:]
+	}
+	if parts[0] == "" {
+		return "unknown", nil
+	}
+
+	if len(parts) > 1 && parts[1] == ":id" {
+		if id, err := strconv.Atoi(c.Param("id")); err == nil {
+			return parts[0], &id
+		}
+	}
+	return parts[0], nil
+}
+
+// auditResponse extracts a JSON response body and the ID of the entity it describes
+func auditResponse(body []byte) (json.RawMessage, *int) {
+	var value interface{}
+	if len(body) == 0 || json.Unmarshal(body, &value) != nil {
+		return nil, nil
+	}
+
+	var entityID *int
+	if object, ok := value.(map[string]interface{}); ok {
+		if id, ok := object["id"].(float64); ok {
+			value := int(id)
+			entityID = &value
+		}
+	}
+	return AuditJSON(value), entityID
+}
+
+// AuditJSON serializes an entity for the audit log with sensitive values redacted
+func AuditJSON(entity interface{}) json.RawMessage {
+	if entity == nil {
+		return nil
+	}
+	data, err := json.Marshal(entity)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+	data, err = json.Marshal(redact(value))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if isRedactedKey(key) {
+				v[key] = "[REDACTED]"
+			} else {
+				v[key] = redact(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	}
+	return value
+}
+
+func isRedactedKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, redacted := range auditRedactedKeys {
+		if strings.Contains(key, redacted) {
+			return true
+		}
+	}
+	return false
+}
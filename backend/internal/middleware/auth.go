@@ -1,6 +1,12 @@
 package middleware
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"log"
 	"net/http"
 	"service-weaver/internal/models"
@@ -11,8 +17,16 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// AuthMiddleware validates the JWT token and sets the user in the context
-func AuthMiddleware() gin.HandlerFunc {
+// ErrTokenRevoked is returned for tokens invalidated by a logout before they expired
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// TokenStore knows which tokens were revoked before their expiry
+type TokenStore interface {
+	IsTokenRevoked(ctx context.Context, tokenID string, userID, sessionID int, issuedAt time.Time) (bool, error)
+}
+
+// AuthMiddleware validates the JWT token, rejects revoked tokens and sets the user in the context
+func AuthMiddleware(store TokenStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.Println("AuthMiddleware: Checking for Authorization header...")
 		authHeader := c.GetHeader("Authorization")
@@ -33,53 +47,83 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 		log.Println("AuthMiddleware: Authorization format is valid Bearer token.")
 
-		tokenString := parts[1]
-		claims := &jwt.MapClaims{}
-
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			log.Println("AuthMiddleware: Parsing token with claims...")
-			return JwtKey, nil
-		})
-
+		claims, err := VerifyToken(c.Request.Context(), store, parts[1])
 		if err != nil {
 			log.Printf("AuthMiddleware: Error parsing token: %v", err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
-
-		if !token.Valid {
-			log.Println("AuthMiddleware: Token is not valid.")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
-		}
-		log.Println("AuthMiddleware: Token is valid.")
+		log.Printf("AuthMiddleware: UserID: %d, Username: %s, Role: %s", claims.UserID, claims.Username, claims.Role)
 
 		// Set user information in context
-		if claims, ok := token.Claims.(*jwt.MapClaims); ok && token.Valid {
-			log.Println("AuthMiddleware: Claims extracted successfully.")
-			userID := uint((*claims)["user_id"].(float64))
-			username := (*claims)["username"].(string)
-			role := models.UserRole((*claims)["role"].(string))
-
-			log.Printf("AuthMiddleware: UserID: %d, Username: %s, Role: %s", userID, username, role)
-
-			c.Set("user_id", userID)
-			c.Set("username", username)
-			c.Set("user_role", role)
-			log.Println("AuthMiddleware: User information set in context. Calling c.Next().")
-		} else {
-			log.Println("AuthMiddleware: Failed to cast claims or token invalid.")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
-		}
+		c.Set("user_id", claims.UserID)
+		c.Set("username", claims.Username)
+		c.Set("user_role", claims.Role)
+		c.Set("token_claims", claims)
 
 		c.Next()
 	}
 }
 
+// TokenClaims identifies the user a token was issued to
+type TokenClaims struct {
+	UserID    uint
+	Username  string
+	Role      models.UserRole
+	ID        string // empty for tokens issued before revocation was supported
+	SessionID int    // 0 for tokens issued before sessions were tracked
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// VerifyToken validates a JWT like ParseToken and additionally rejects tokens that were revoked
+func VerifyToken(ctx context.Context, store TokenStore, tokenString string) (*TokenClaims, error) {
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := store.IsTokenRevoked(ctx, claims.ID, int(claims.UserID), claims.SessionID, claims.IssuedAt)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// ParseToken validates a JWT and returns the user it identifies
+func ParseToken(tokenString string) (*TokenClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, Keys.verificationKey)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	username, usernameOK := claims["username"].(string)
+	role, roleOK := claims["role"].(string)
+	if !ok || !usernameOK || !roleOK {
+		return nil, errors.New("invalid token claims")
+	}
+	result := &TokenClaims{UserID: uint(userID), Username: username, Role: models.UserRole(role)}
+	result.ID, _ = claims["jti"].(string)
+	if sid, ok := claims["sid"].(float64); ok {
+		result.SessionID = int(sid)
+	}
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		result.IssuedAt = iat.Time
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		result.ExpiresAt = exp.Time
+	}
+	return result, nil
+}
+
 // min is a helper function to avoid panics with slicing
 func min(a, b int) int {
 	if a < b {
@@ -88,14 +132,22 @@ func min(a, b int) int {
 	return b
 }
 
-// GenerateJWT generates a new JWT token for a user
-func GenerateJWT(user models.User) (string, error) {
-	return GenerateJWTWithExpiration(user, 24*time.Hour) // Default 24 hours
+// GenerateJWT generates a new JWT token for a user's session
+func GenerateJWT(user models.User, sessionID int) (string, error) {
+	return GenerateJWTWithExpiration(user, sessionID, AccessTokenTTL)
 }
 
-// GenerateJWTWithExpiration generates a new JWT token for a user with custom expiration
-func GenerateJWTWithExpiration(user models.User, expiration time.Duration) (string, error) {
+// GenerateJWTWithExpiration generates a new JWT token for a user's session with custom expiration
+func GenerateJWTWithExpiration(user models.User, sessionID int, expiration time.Duration) (string, error) {
+	// The token ID lets a logout revoke this token alone
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
 	claims := jwt.MapClaims{
+		"jti":      hex.EncodeToString(id),
+		"sid":      sessionID,
 		"user_id":  user.ID,
 		"username": user.Username,
 		"role":     user.Role,
@@ -103,11 +155,30 @@ func GenerateJWTWithExpiration(user models.User, expiration time.Duration) (stri
 		"iat":      jwt.NewNumericDate(time.Now()), // Issued at
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(JwtKey)
+	return Keys.sign(claims)
 }
 
-// GenerateRefreshToken generates a refresh token for longer sessions
-func GenerateRefreshToken(user models.User) (string, error) {
-	return GenerateJWTWithExpiration(user, 30*24*time.Hour) // 30 days for remember me
+// NewRefreshToken generates an opaque refresh token and the hash it is stored under
+func NewRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the hash a refresh token is stored under; the token itself is never persisted
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewTokenFamily generates the identifier shared by all refresh tokens of one session
+func NewTokenFamily() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
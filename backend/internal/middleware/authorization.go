@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 // RequireRole is a middleware that checks if the user has the required role
@@ -60,7 +59,7 @@ func RequireAdmin() gin.HandlerFunc {
 
 // OptionalAuth is a middleware that checks for a token but doesn't require it
 // Useful for endpoints that can work both authenticated and unauthenticated
-func OptionalAuth() gin.HandlerFunc {
+func OptionalAuth(store TokenStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -75,28 +74,16 @@ func OptionalAuth() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := parts[1]
-		claims := &jwt.MapClaims{}
-
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return JwtKey, nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := VerifyToken(c.Request.Context(), store, parts[1])
+		if err != nil {
 			c.Next() // Invalid token, proceed without setting user context
 			return
 		}
 
 		// Set user information in context if token is valid
-		if claims, ok := token.Claims.(*jwt.MapClaims); ok && token.Valid {
-			userID := uint((*claims)["user_id"].(float64))
-			username := (*claims)["username"].(string)
-			role := models.UserRole((*claims)["role"].(string))
-
-			c.Set("user_id", userID)
-			c.Set("username", username)
-			c.Set("user_role", role)
-		}
+		c.Set("user_id", claims.UserID)
+		c.Set("username", claims.Username)
+		c.Set("user_role", claims.Role)
 
 		c.Next()
 	}
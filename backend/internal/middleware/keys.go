@@ -1,3 +1,21 @@
 package middleware
 
+import "time"
+
 var JwtKey = []byte("your_secret_key") // In production, use environment variables
+
+// Token lifetimes, overridable from the environment at startup
+var (
+	AccessTokenTTL  = 15 * time.Minute    // access tokens are short-lived and renewed with a refresh token
+	RefreshTokenTTL = 24 * time.Hour      // session length without remember me
+	RememberMeTTL   = 30 * 24 * time.Hour // session length with remember me
+	InvitationTTL   = 72 * time.Hour      // how long an emailed invitation link can be accepted
+)
+
+// Account lockout after repeated failed logins, overridable from the environment at startup
+var (
+	LockoutThreshold   = 5                // failed logins within LockoutWindow that lock an account; 0 disables lockout
+	LockoutWindow      = 15 * time.Minute // failed logins further apart are not counted together
+	LockoutDuration    = 15 * time.Minute // doubles with every further lockout until a successful login
+	LockoutMaxDuration = 24 * time.Hour
+)
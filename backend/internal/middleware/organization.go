@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"service-weaver/internal/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OrganizationHeader selects the organization a request acts in. Without it, requests act in the
+// user's oldest organization.
+const OrganizationHeader = "X-Organization-ID"
+
+// ErrNoOrganization is returned for users who belong to no organization
+var ErrNoOrganization = errors.New("not a member of this organization")
+
+// OrganizationStore resolves organizations and the organization owning an entity
+type OrganizationStore interface {
+	GetOrganizationRole(ctx context.Context, userID, orgID int) (models.OrganizationRole, error)
+	GetDefaultOrganizationID(ctx context.Context, userID int) (int, error)
+	GetEntityOrganization(ctx context.Context, entityType string, id int) (int, error)
+}
+
+// organizationFreeRoutes work for users who do not belong to any organization yet
+var organizationFreeRoutes = map[string]bool{
+	"/api/user/me":           true,
+	"/api/user/me/password":  true,
+	"/api/user/sessions":     true,
+	"/api/user/sessions/:id": true,
+	"/api/logout":            true,
+	"/api/organizations":     true,
+}
+
+// personalRoutes only change the user's own settings, which viewers may do too
+var personalRoutes = map[string]bool{
+	"/api/logout":                           true,
+	"/api/user/me":                          true,
+	"/api/user/me/password":                 true,
+	"/api/user/me/notification-preferences": true,
+	"/api/user/sessions":                    true,
+	"/api/user/sessions/:id":                true,
+	"/api/diagrams/:id/star":                true,
+}
+
+// ResolveOrganization returns the organization a user acts in and their role there. requested is
+// the organization named by the client, or empty for the user's default organization.
+func ResolveOrganization(ctx context.Context, store OrganizationStore, userID int, requested string) (int, models.OrganizationRole, error) {
+	var orgID int
+	if requested != "" {
+		id, err := strconv.Atoi(requested)
+		if err != nil {
+			return 0, "", ErrNoOrganization
+		}
+		orgID = id
+	} else {
+		id, err := store.GetDefaultOrganizationID(ctx, userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", ErrNoOrganization
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		orgID = id
+	}
+
+	role, err := store.GetOrganizationRole(ctx, userID, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", ErrNoOrganization
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	return orgID, role, nil
+}
+
+// Organization resolves the organization of the request and keeps every request inside it:
+// diagrams, folders, services, connections, incidents and maintenance windows addressed by the
+// route must belong to it, and viewers cannot change anything. Routes under /organizations/:id act
+// in that organization. It must run after AuthMiddleware.
+func Organization(store OrganizationStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := int(c.GetUint("user_id"))
+		entityType, entityID := auditEntity(c)
+
+		requested := c.GetHeader(OrganizationHeader)
+		if entityType == "organizations" && entityID != nil {
+			requested = strconv.Itoa(*entityID)
+		}
+		orgID, role, err := ResolveOrganization(c.Request.Context(), store, userID, requested)
+		if errors.Is(err, ErrNoOrganization) {
+			if organizationFreeRoutes[c.FullPath()] {
+				c.Next()
+				return
+			}
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this organization"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			log.Printf("Organization: failed to resolve organization of user %d: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve organization"})
+			c.Abort()
+			return
+		}
+
+		if entityID != nil && entityType != "organizations" && IsOrganizationScoped(entityType) {
+			owner, err := store.GetEntityOrganization(c.Request.Context(), entityType, *entityID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				c.Abort()
+				return
+			}
+			// Entities of other organizations look exactly like missing ones
+			if err == nil && owner != orgID {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+				c.Abort()
+				return
+			}
+		}
+
+		if role == models.OrgRoleViewer && c.Request.Method != http.MethodGet && !personalRoutes[c.FullPath()] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Viewers cannot make changes in this organization"})
+			c.Abort()
+			return
+		}
+
+		c.Set("organization_id", orgID)
+		c.Set("organization_role", role)
+		c.Next()
+	}
+}
+
+// RequireOrganizationAdmin only lets admins of the request's organization through. It must run
+// after Organization.
+func RequireOrganizationAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("organization_role")
+		if role != models.OrgRoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Organization admin access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// IsOrganizationScoped reports whether entities of the route resource belong to an organization
+func IsOrganizationScoped(entityType string) bool {
+	switch entityType {
+	case "diagrams", "folders", "services", "connections", "incidents", "maintenance-windows":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// rateLimitSweepInterval is how often the in-memory store forgets clients whose bucket refilled
+const rateLimitSweepInterval = time.Minute
+
+// RateLimit allows Requests per Per, in bursts of up to Requests
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseRateLimit reads a limit such as "300/m" (also /s and /h). An empty value disables limiting.
+func ParseRateLimit(value string) (*RateLimit, error) {
+	if value == "" {
+		return nil, nil
+	}
+	count, unit, ok := strings.Cut(value, "/")
+	if !ok {
+		return nil, fmt.Errorf("%q is not of the form <requests>/<s|m|h>", value)
+	}
+	requests, err := strconv.Atoi(count)
+	if err != nil || requests <= 0 {
+		return nil, fmt.Errorf("invalid request count %q", count)
+	}
+	limit := &RateLimit{Requests: requests}
+	switch unit {
+	case "s":
+		limit.Per = time.Second
+	case "m":
+		limit.Per = time.Minute
+	case "h":
+		limit.Per = time.Hour
+	default:
+		return nil, fmt.Errorf("invalid unit %q, must be s, m or h", unit)
+	}
+	return limit, nil
+}
+
+// rate is the number of tokens added to a bucket per second
+func (l RateLimit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// RateLimitStore keeps token buckets. Take removes a token from the bucket of key and, when it is
+// empty, reports how long until the next token is available.
+type RateLimitStore interface {
+	Take(key string, limit RateLimit) (bool, time.Duration, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  RateLimit
+}
+
+// refill adds the tokens earned since the last request
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.limit.Requests), b.tokens+now.Sub(b.last).Seconds()*b.limit.rate())
+	b.last = now
+}
+
+// MemoryRateLimitStore keeps buckets in process memory, for single-instance deployments
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*bucket), lastSweep: time.Now()}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > rateLimitSweepInterval {
+		for k, b := range s.buckets {
+			if b.refill(now); b.tokens >= float64(b.limit.Requests) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), last: now, limit: limit}
+		s.buckets[key] = b
+	}
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	wait := time.Duration((1 - b.tokens) / limit.rate() * float64(time.Second))
+	return false, wait, nil
+}
+
+// takeScript is the token bucket of RedisRateLimitStore. It uses the Redis clock so that every
+// instance refills buckets the same way.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - last) * rate)
+local allowed, wait = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = (1 - tokens) / rate
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000))
+return {allowed, tostring(wait)}
+`)
+
+// RedisRateLimitStore shares buckets between instances through Redis
+type RedisRateLimitStore struct {
+	client *redis.Client
+}
+
+// NewRedisRateLimitStore connects to the Redis server at a redis:// or rediss:// URL
+func NewRedisRateLimitStore(url string) (*RedisRateLimitStore, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(options)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &RedisRateLimitStore{client: client}, nil
+}
+
+func (s *RedisRateLimitStore) Take(key string, limit RateLimit) (bool, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	result, err := takeScript.Run(ctx, s.client, []string{"ratelimit:" + key}, limit.rate(), limit.Requests).Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(result) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit script result %v", result)
+	}
+	allowed, _ := result[0].(int64)
+	waitValue, _ := result[1].(string)
+	wait, err := strconv.ParseFloat(waitValue, 64)
+	if err != nil {
+		return false, 0, err
+	}
+	return allowed == 1, time.Duration(wait * float64(time.Second)), nil
+}
+
+func (s *RedisRateLimitStore) Close() error {
+	return s.client.Close()
+}
+
+// RateLimitByIP limits requests per client IP. Buckets are named after scope so that routes with
+// their own limit, such as login, do not share tokens with the rest of the API. A nil limit
+// disables it.
+func RateLimitByIP(store RateLimitStore, scope string, limit *RateLimit) gin.HandlerFunc {
+	return rateLimit(store, limit, func(c *gin.Context) string {
+		return scope + ":ip:" + c.ClientIP()
+	})
+}
+
+// RateLimitByUser limits requests per authenticated user, across all their IPs. It must run after
+// AuthMiddleware. A nil limit disables it.
+func RateLimitByUser(store RateLimitStore, scope string, limit *RateLimit) gin.HandlerFunc {
+	return rateLimit(store, limit, func(c *gin.Context) string {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			return ""
+		}
+		return fmt.Sprintf("%s:user:%v", scope, userID)
+	})
+}
+
+func rateLimit(store RateLimitStore, limit *RateLimit, key func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit == nil {
+			c.Next()
+			return
+		}
+		k := key(c)
+		if k == "" {
+			c.Next()
+			return
+		}
+
+		allowed, wait, err := store.Take(k, *limit)
+		if err != nil {
+			// An unavailable store must not take the API down with it
+			log.Printf("Rate limit: failed to check %s: %v", k, err)
+			c.Next()
+			return
+		}
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, try again later"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Keys signs and verifies access tokens. Until Load configures an RSA or Ed25519 key, tokens are
+// signed with HS256 and JwtKey.
+var Keys = &KeyRing{}
+
+// SigningKey is an asymmetric key tokens are signed or verified with
+type SigningKey struct {
+	ID      string // kid header, the RFC 7638 thumbprint of the public key
+	Method  jwt.SigningMethod
+	private crypto.Signer // nil for keys only accepted for verification
+	public  crypto.PublicKey
+}
+
+// KeyRing holds the key new tokens are signed with and the previous keys whose tokens are still
+// accepted, so keys can be rotated without logging anyone out
+type KeyRing struct {
+	mu      sync.RWMutex
+	current *SigningKey
+	keys    map[string]*SigningKey
+}
+
+// JSONWebKey is the public half of a signing key as published in the JWKS
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet is the document served at /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// Load replaces the keys with the PEM private key in currentFile and the PEM keys in
+// previousFiles, which are only used to verify tokens issued before a rotation. An empty
+// currentFile switches back to HS256.
+func (k *KeyRing) Load(currentFile string, previousFiles []string) error {
+	var current *SigningKey
+	keys := make(map[string]*SigningKey)
+	if currentFile != "" {
+		key, err := readSigningKey(currentFile)
+		if err != nil {
+			return err
+		}
+		if key.private == nil {
+			return fmt.Errorf("%s: the signing key must be a private key", currentFile)
+		}
+		current = key
+		keys[key.ID] = key
+	} else if len(previousFiles) > 0 {
+		return errors.New("previous keys require a current signing key")
+	}
+	for _, file := range previousFiles {
+		key, err := readSigningKey(file)
+		if err != nil {
+			return err
+		}
+		key.private = nil
+		if _, ok := keys[key.ID]; !ok {
+			keys[key.ID] = key
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.current = current
+	k.keys = keys
+	return nil
+}
+
+// sign signs a token with the current key, adding its kid header
+func (k *KeyRing) sign(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	current := k.current
+	k.mu.RUnlock()
+
+	if current == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(JwtKey)
+	}
+	token := jwt.NewWithClaims(current.Method, claims)
+	token.Header["kid"] = current.ID
+	return token.SignedString(current.private)
+}
+
+// verificationKey is the jwt.Keyfunc of access tokens. Tokens must name a known key and use its
+// algorithm; HS256 tokens without a kid are only accepted while no asymmetric key is configured.
+func (k *KeyRing) verificationKey(token *jwt.Token) (interface{}, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	kid, _ := token.Header["kid"].(string)
+	if k.current == nil {
+		if kid != "" || token.Method != jwt.SigningMethodHS256 {
+			return nil, errors.New("unexpected signing key")
+		}
+		return JwtKey, nil
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("signing method %s does not match key %q", token.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+// JWKS returns the public keys tokens are verified with; it is empty while tokens use HS256
+func (k *KeyRing) JWKS() JSONWebKeySet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	if k.current == nil {
+		return set
+	}
+	set.Keys = append(set.Keys, k.current.jwk())
+	var previous []JSONWebKey
+	for id, key := range k.keys {
+		if id != k.current.ID {
+			previous = append(previous, key.jwk())
+		}
+	}
+	sort.Slice(previous, func(i, j int) bool { return previous[i].Kid < previous[j].Kid })
+	set.Keys = append(set.Keys, previous...)
+	return set
+}
+
+func (key *SigningKey) jwk() JSONWebKey {
+	jwk := JSONWebKey{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// readSigningKey reads an RSA or Ed25519 key from a PEM file. Private keys may be PKCS #1 or
+// PKCS #8, public keys PKIX.
+func readSigningKey(file string) (*SigningKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", file)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", file, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	key := &SigningKey{}
+	switch parsed := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.private, key.public, key.Method = parsed, &parsed.PublicKey, jwt.SigningMethodRS256
+	case *rsa.PublicKey:
+		key.public, key.Method = parsed, jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		key.private, key.public, key.Method = parsed, parsed.Public(), jwt.SigningMethodEdDSA
+	case ed25519.PublicKey:
+		key.public, key.Method = parsed, jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("%s: only RSA and Ed25519 keys are supported", file)
+	}
+	if public, ok := key.public.(*rsa.PublicKey); ok && public.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("%s: RSA keys must be at least 2048 bits", file)
+	}
+	key.ID = thumbprint(key.jwk())
+	return key, nil
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint, which gives every key a stable ID
+func thumbprint(jwk JSONWebKey) string {
+	// The required members in lexicographic order, without whitespace
+	var members interface{}
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -17,6 +19,31 @@ const (
 	StatusChecking ServiceStatus = "checking"
 )
 
+// statusSeverity orders statuses so that the worst one in a group can be reported
+func statusSeverity(status ServiceStatus) int {
+	switch status {
+	case StatusDead:
+		return 3
+	case StatusDegraded:
+		return 2
+	case StatusUnknown:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// WorseStatus returns the more severe of two statuses
+func WorseStatus(a, b ServiceStatus) ServiceStatus {
+	if statusSeverity(b) > statusSeverity(a) {
+		return b
+	}
+	return a
+}
+
+// DefaultSLOTarget is the availability objective (in percent) applied when a service does not define one
+const DefaultSLOTarget = 99.9
+
 // JSON is a custom type for JSON fields
 type JSON map[string]interface{}
 
@@ -36,63 +63,370 @@ func (j *JSON) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, j)
 }
 
+// MaskedHeaderValue stands in for the value of every request header a service is serialized with
+const MaskedHeaderValue = "********"
+
+// Headers are the request headers of HTTP checks. They often carry API keys, so their values are
+// masked wherever a service is serialized: in API and gRPC responses, exports, version snapshots
+// and the audit log. Writing a masked value back keeps the stored one.
+type Headers map[string]interface{}
+
+// MarshalJSON lists the header names with masked values
+func (h Headers) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Masked())
+}
+
+// Masked returns the header names with masked values
+func (h Headers) Masked() JSON {
+	if h == nil {
+		return nil
+	}
+	masked := make(JSON, len(h))
+	for key := range h {
+		masked[key] = MaskedHeaderValue
+	}
+	return masked
+}
+
 // Diagram represents a system diagram
 type Diagram struct {
-	ID          int       `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	Description string    `json:"description" db:"description"`
-	Public      bool      `json:"public" db:"public"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID                int             `json:"id" db:"id"`
+	Name              string          `json:"name" db:"name"`
+	Description       string          `json:"description" db:"description"`
+	OrganizationID    int             `json:"organization_id" db:"organization_id"` // Set from the organization the diagram was created in
+	Public            bool            `json:"public" db:"public"`
+	FolderID          *int            `json:"folder_id" db:"folder_id"`         // nil keeps the diagram at the top level
+	OwnerUserID       *int            `json:"owner_user_id" db:"owner_user_id"` // Member of the organization responsible for the diagram's services
+	OwnerTeam         string          `json:"owner_team" db:"owner_team" binding:"max=100"`
+	Environment       string          `json:"environment" db:"environment" binding:"max=50"` // Name of an Environment, or empty
+	StatusPageEnabled bool            `json:"status_page_enabled" db:"status_page_enabled"`
+	StatusPageSlug    *string         `json:"status_page_slug,omitempty" db:"status_page_slug"`
+	StatusPageTitle   string          `json:"status_page_title" db:"status_page_title"`
+	ServiceDefaults   ServiceDefaults `json:"service_defaults" db:"service_defaults"`
+	DeletedAt         *time.Time      `json:"deleted_at,omitempty" db:"deleted_at"` // Set while the diagram is in the trash
+	CreatedAt         time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// ServiceDefaultFields are the healthcheck settings a service can inherit from its diagram
+var ServiceDefaultFields = []string{"polling_interval", "request_timeout", "ssl_verify", "headers"}
+
+// ServiceDefaults are healthcheck settings a diagram applies to its services. Unset fields are not
+// defaulted, and services keep their own value for fields listed in their overrides.
+type ServiceDefaults struct {
+	PollingInterval *int  `json:"polling_interval,omitempty" binding:"omitempty,min=1"`
+	RequestTimeout  *int  `json:"request_timeout,omitempty" binding:"omitempty,min=1"`
+	SSLVerify       *bool `json:"ssl_verify,omitempty"`
+	Headers         JSON  `json:"headers,omitempty"`
+}
+
+func (d ServiceDefaults) Value() (driver.Value, error) {
+	return json.Marshal(d)
+}
+
+func (d *ServiceDefaults) Scan(value interface{}) error {
+	*d = ServiceDefaults{}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, d)
+}
+
+// Apply sets the fields of a service that it does not override to the diagram's defaults
+func (d ServiceDefaults) Apply(s *Service) {
+	overridden := make(map[string]bool, len(s.Overrides))
+	for _, field := range s.Overrides {
+		overridden[field] = true
+	}
+	if d.PollingInterval != nil && !overridden["polling_interval"] {
+		s.PollingInterval = *d.PollingInterval
+	}
+	if d.RequestTimeout != nil && !overridden["request_timeout"] {
+		s.RequestTimeout = *d.RequestTimeout
+	}
+	if d.SSLVerify != nil && !overridden["ssl_verify"] {
+		s.SSLVerify = *d.SSLVerify
+	}
+	if d.Headers != nil && !overridden["headers"] {
+		s.Headers = Headers(d.Headers)
+	}
 }
 
 // Service represents a service node in the diagram
 type Service struct {
-	ID                int           `json:"id" db:"id"`
-	DiagramID         int           `json:"diagram_id" db:"diagram_id"`
-	Name              string        `json:"name" db:"name"`
-	Description       string        `json:"description" db:"description"`
-	ServiceType       string        `json:"service_type" db:"service_type"`
-	Icon              string        `json:"icon" db:"icon"`
-	Host              string        `json:"host" db:"host"`
-	Port              int           `json:"port" db:"port"`
-	Tags              string        `json:"tags" db:"tags"`
-	PositionX         float64       `json:"position_x" db:"position_x"`
-	PositionY         float64       `json:"position_y" db:"position_y"`
-	HealthcheckMethod string        `json:"healthcheck_method" db:"healthcheck_method"`
-	HealthcheckURL    string        `json:"healthcheck_url" db:"healthcheck_url"`
-	PollingInterval   int           `json:"polling_interval" db:"polling_interval"`
-	RequestTimeout    int           `json:"request_timeout" db:"request_timeout"`
-	ExpectedStatus    int           `json:"expected_status" db:"expected_status"`
-	StatusMapping     JSON          `json:"status_mapping" db:"status_mapping"`
-	HTTPMethod        string        `json:"http_method" db:"http_method"`
-	Headers           JSON          `json:"headers" db:"headers"`
-	Body              string        `json:"body" db:"body"`
-	SSLVerify         bool          `json:"ssl_verify" db:"ssl_verify"`
-	FollowRedirects   bool          `json:"follow_redirects" db:"follow_redirects"`
-	TCPSendData       string        `json:"tcp_send_data" db:"tcp_send_data"`
-	TCPExpectData     string        `json:"tcp_expect_data" db:"tcp_expect_data"`
-	UDPSendData       string        `json:"udp_send_data" db:"udp_send_data"`
-	UDPExpectData     string        `json:"udp_expect_data" db:"udp_expect_data"`
-	ICMPPacketCount   int           `json:"icmp_packet_count" db:"icmp_packet_count"`
-	DNSQueryType      string        `json:"dns_query_type" db:"dns_query_type"`
-	DNSExpectedResult string        `json:"dns_expected_result" db:"dns_expected_result"`
-	KafkaTopic        string        `json:"kafka_topic" db:"kafka_topic"`
-	KafkaClientID     string        `json:"kafka_client_id" db:"kafka_client_id"`
-	FrontendHostURL   string        `json:"frontend_host_url" db:"frontend_host_url"`
-	CurrentStatus     ServiceStatus `json:"current_status" db:"current_status"`
-	LastChecked       *time.Time    `json:"last_checked" db:"last_checked"`
-	CreatedAt         time.Time     `json:"created_at" db:"created_at"`
-	UpdatedAt         time.Time     `json:"updated_at" db:"updated_at"`
+	ID                      int                 `json:"id" db:"id"`
+	DiagramID               int                 `json:"diagram_id" db:"diagram_id"`
+	Name                    string              `json:"name" db:"name"`
+	Description             string              `json:"description" db:"description"`
+	ServiceType             string              `json:"service_type" db:"service_type"`
+	Icon                    string              `json:"icon" db:"icon"`  // An icon name, an image URL, or /api/services/:id/icon for an uploaded image
+	IconKey                 string              `json:"-" db:"icon_key"` // Where blob storage keeps the uploaded image
+	Host                    string              `json:"host" db:"host"`
+	Port                    int                 `json:"port" db:"port"`
+	Tags                    string              `json:"tags" db:"tags"`                                // Comma-separated; stored as a list, see SplitTags
+	RunbookURL              string              `json:"runbook_url" db:"runbook_url"`                  // Where on-call engineers find remediation steps
+	Notes                   string              `json:"notes" db:"notes"`                              // Markdown, sent along with alerts
+	OwnerUserID             *int                `json:"owner_user_id" db:"owner_user_id"`              // nil falls back to the owner of the diagram
+	OwnerTeam               string              `json:"owner_team" db:"owner_team" binding:"max=100"`  // Empty falls back to the team of the diagram
+	Environment             string              `json:"environment" db:"environment" binding:"max=50"` // Empty falls back to the environment of the diagram
+	PositionX               float64             `json:"position_x" db:"position_x"`
+	PositionY               float64             `json:"position_y" db:"position_y"`
+	HealthcheckMethod       string              `json:"healthcheck_method" db:"healthcheck_method"`
+	HealthcheckURL          string              `json:"healthcheck_url" db:"healthcheck_url"`
+	PollingInterval         int                 `json:"polling_interval" db:"polling_interval"`
+	RequestTimeout          int                 `json:"request_timeout" db:"request_timeout"`
+	ExpectedStatus          int                 `json:"expected_status" db:"expected_status"`
+	StatusMapping           JSON                `json:"status_mapping" db:"status_mapping"`
+	HTTPMethod              string              `json:"http_method" db:"http_method"`
+	Headers                 Headers             `json:"headers" db:"headers"` // Values are masked when serialized, see Headers
+	Body                    string              `json:"body" db:"body"`
+	SSLVerify               bool                `json:"ssl_verify" db:"ssl_verify"`
+	FollowRedirects         bool                `json:"follow_redirects" db:"follow_redirects"`
+	TCPSendData             string              `json:"tcp_send_data" db:"tcp_send_data"`
+	TCPExpectData           string              `json:"tcp_expect_data" db:"tcp_expect_data"`
+	UDPSendData             string              `json:"udp_send_data" db:"udp_send_data"`
+	UDPExpectData           string              `json:"udp_expect_data" db:"udp_expect_data"`
+	ICMPPacketCount         int                 `json:"icmp_packet_count" db:"icmp_packet_count"`
+	DNSQueryType            string              `json:"dns_query_type" db:"dns_query_type"`
+	DNSExpectedResult       string              `json:"dns_expected_result" db:"dns_expected_result"`
+	KafkaTopic              string              `json:"kafka_topic" db:"kafka_topic"`
+	KafkaClientID           string              `json:"kafka_client_id" db:"kafka_client_id"`
+	SLOTarget               float64             `json:"slo_target" db:"slo_target"`
+	RetentionDays           int                 `json:"retention_days" db:"retention_days"`           // 0 uses the deployment-wide retention
+	Assertion               string              `json:"assertion" db:"assertion"`                     // Expression over the HTTP response giving the final status, see the assertion package
+	DiscoverySourceID       *int                `json:"discovery_source_id" db:"discovery_source_id"` // Set on nodes created by a discovery source
+	DiscoveryKey            string              `json:"discovery_key,omitempty" db:"discovery_key"`
+	DiscoveryHash           string              `json:"-" db:"discovery_hash"`                                              // Fingerprint of the fields discovery last wrote, used to detect manual edits
+	DiscoveryDeregisteredAt *time.Time          `json:"discovery_deregistered_at,omitempty" db:"discovery_deregistered_at"` // Set while the node is missing from its catalog
+	Overrides               []string            `json:"overrides" db:"overrides"`                                           // Fields that keep the service's own value instead of the diagram's default
+	Paused                  *bool               `json:"paused" db:"paused"`                                                 // Paused services are not checked; nil keeps the stored value on updates
+	ChildDiagramID          *int                `json:"child_diagram_id" db:"child_diagram_id"`                             // Makes the node a composite whose status rolls up from this diagram
+	OverlayOf               *int                `json:"overlay_of,omitempty" db:"overlay_of"`                               // Set on environment overlays: the service whose node they stand in for
+	Credentials             *ServiceCredentials `json:"credentials,omitempty" db:"-"`                                       // Write-only: replaces the stored credentials when set, {} removes them
+	SealedCredentials       string              `json:"-" db:"credentials"`                                                 // Credentials as encrypted by the secrets keyring
+	HasCredentials          bool                `json:"has_credentials" db:"-"`
+	FrontendHostURL         string              `json:"frontend_host_url" db:"frontend_host_url"`
+	CurrentStatus           ServiceStatus       `json:"current_status" db:"current_status"`
+	LastChecked             *time.Time          `json:"last_checked" db:"last_checked"`
+	DeletedAt               *time.Time          `json:"deleted_at,omitempty" db:"deleted_at"` // Set while the service is in the trash
+	CreatedAt               time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt               time.Time           `json:"updated_at" db:"updated_at"`
+}
+
+// ServiceCredentials are the secrets a health check authenticates with. They are encrypted at rest
+// and never returned by the API. Any of them may instead reference a Vault secret, such as
+// "vault:secret/data/db#password", which is resolved at check time.
+type ServiceCredentials struct {
+	Username   string `json:"username,omitempty"`    // HTTP basic auth, database, Redis and Kafka SASL/PLAIN user
+	Password   string `json:"password,omitempty"`    // password of Username
+	Token      string `json:"token,omitempty"`       // bearer token for HTTP and gRPC checks
+	ClientCert string `json:"client_cert,omitempty"` // PEM certificate for TLS client authentication
+	ClientKey  string `json:"client_key,omitempty"`  // PEM private key of ClientCert
+}
+
+// HealthcheckMethods lists the methods services can be checked with
+var HealthcheckMethods = []string{"HTTP", "HTTPS", "TCP", "UDP", "ICMP", "DNS", "WEBSOCKET", "WSS", "GRPC", "SMTP", "FTP", "SSH", "REDIS", "MYSQL", "POSTGRES", "MONGODB", "KAFKA", "HEARTBEAT", "SCRIPT"}
+
+// URLHealthcheckMethods are the healthcheck methods that request a healthcheck URL of the host, a
+// path or, for gRPC, the name of the service whose health is asked for
+var URLHealthcheckMethods = []string{"HTTP", "HTTPS", "WEBSOCKET", "WSS", "GRPC"}
+
+// HTTPMethods lists the request methods HTTP checks can use
+var HTTPMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// DNSQueryTypes lists the record types DNS checks can query
+var DNSQueryTypes = []string{"A", "CNAME", "MX", "NS", "TXT"}
+
+// FieldError is a problem with one field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidateHealthcheck returns the problems with the healthcheck configuration of a service that its
+// checks would otherwise only report once they run, none when it is coherent. Like the editor, it
+// fills in HTTP as healthcheck method, GET as HTTP method, 200 as expected status and A as DNS
+// query type when they are empty. Composite nodes have no check of their own, so only the ranges of their fields are checked.
+func ValidateHealthcheck(s *Service) []FieldError {
+	var problems []FieldError
+	invalid := func(field, message string) {
+		problems = append(problems, FieldError{Field: field, Message: message})
+	}
+
+	if s.HealthcheckMethod == "" {
+		s.HealthcheckMethod = "HTTP"
+	}
+	if s.HTTPMethod == "" {
+		s.HTTPMethod = "GET"
+	}
+	if s.ExpectedStatus == 0 {
+		s.ExpectedStatus = 200
+	}
+	if s.DNSQueryType == "" {
+		s.DNSQueryType = "A"
+	}
+
+	if s.Port < 0 || s.Port > 65535 {
+		invalid("port", "must be between 0 and 65535")
+	}
+	if s.PollingInterval < 0 {
+		invalid("polling_interval", "must not be negative")
+	}
+	if s.RequestTimeout < 0 {
+		invalid("request_timeout", "must not be negative")
+	}
+	if s.RetentionDays < 0 {
+		invalid("retention_days", "must not be negative")
+	}
+	if s.ICMPPacketCount < 0 {
+		invalid("icmp_packet_count", "must not be negative")
+	}
+	if s.ExpectedStatus < 100 || s.ExpectedStatus > 599 {
+		invalid("expected_status", "must be an HTTP status code, between 100 and 599")
+	}
+
+	if !slices.Contains(HealthcheckMethods, s.HealthcheckMethod) {
+		invalid("healthcheck_method", "must be one of "+strings.Join(HealthcheckMethods, ", "))
+		return problems
+	}
+	if !slices.Contains(HTTPMethods, s.HTTPMethod) {
+		invalid("http_method", "must be one of "+strings.Join(HTTPMethods, ", "))
+	}
+	if s.ChildDiagramID != nil {
+		return problems
+	}
+	if slices.Contains(URLHealthcheckMethods, s.HealthcheckMethod) && s.HealthcheckURL == "" {
+		invalid("healthcheck_url", "is required for "+s.HealthcheckMethod+" checks")
+	}
+	switch s.HealthcheckMethod {
+	case "UDP":
+		if s.UDPSendData == "" {
+			invalid("udp_send_data", "is required for UDP checks, which only get a response to what they send")
+		}
+	case "DNS":
+		if !slices.Contains(DNSQueryTypes, s.DNSQueryType) {
+			invalid("dns_query_type", "must be one of "+strings.Join(DNSQueryTypes, ", "))
+		}
+	}
+	return problems
+}
+
+// SplitTags parses a comma-separated tag list into its trimmed, distinct entries, in order
+func SplitTags(tags string) []string {
+	split := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			split = append(split, tag)
+		}
+	}
+	return split
+}
+
+// TagCount is a tag used in an organization and the number of services carrying it
+type TagCount struct {
+	Tag      string `json:"tag"`
+	Services int    `json:"services"`
+}
+
+// Actions applied to every service carrying a tag
+const (
+	TagActionPause       = "pause"
+	TagActionResume      = "resume"
+	TagActionSetInterval = "set_interval"
+)
+
+// TagActionRequest applies Action to the services carrying a tag; set_interval sets their
+// PollingInterval, in seconds, overriding the diagram's default
+type TagActionRequest struct {
+	Action          string `json:"action" binding:"required"`
+	PollingInterval int    `json:"polling_interval,omitempty"`
+}
+
+// IsZero reports whether no credential is set
+func (c ServiceCredentials) IsZero() bool {
+	return c == ServiceCredentials{}
+}
+
+// Fields returns pointers to every credential, for code that treats them alike
+func (c *ServiceCredentials) Fields() []*string {
+	return []*string{&c.Username, &c.Password, &c.Token, &c.ClientCert, &c.ClientKey}
+}
+
+// ConnectionDirection describes which way traffic flows along a connection
+type ConnectionDirection string
+
+const (
+	DirectionForward       ConnectionDirection = "forward"       // source calls target
+	DirectionBackward      ConnectionDirection = "backward"      // target calls source
+	DirectionBidirectional ConnectionDirection = "bidirectional" // both ends call each other
+	DirectionNone          ConnectionDirection = "none"          // undirected line
+)
+
+// ConnectionProtocols lists the protocols a connection can be labelled with
+var ConnectionProtocols = []string{"http", "https", "grpc", "graphql", "websocket", "tcp", "udp", "kafka", "amqp", "mqtt", "nats", "redis", "sql", "other"}
+
+// Valid reports whether d is a known direction; empty is accepted and stored as forward
+func (d ConnectionDirection) Valid() bool {
+	switch d {
+	case "", DirectionForward, DirectionBackward, DirectionBidirectional, DirectionNone:
+		return true
+	}
+	return false
+}
+
+// ValidConnectionProtocol reports whether protocol is empty or one of ConnectionProtocols
+func ValidConnectionProtocol(protocol string) bool {
+	if protocol == "" {
+		return true
+	}
+	for _, known := range ConnectionProtocols {
+		if protocol == known {
+			return true
+		}
+	}
+	return false
 }
 
 // Connection represents a connection between two services
 type Connection struct {
-	ID        int       `json:"id" db:"id"`
-	DiagramID int       `json:"diagram_id" db:"diagram_id"`
-	SourceID  int       `json:"source_id" db:"source_id"`
-	TargetID  int       `json:"target_id" db:"target_id"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID        int                 `json:"id" db:"id"`
+	DiagramID int                 `json:"diagram_id" db:"diagram_id"`
+	SourceID  int                 `json:"source_id" db:"source_id"`
+	TargetID  int                 `json:"target_id" db:"target_id"`
+	Direction ConnectionDirection `json:"direction" db:"direction" binding:"omitempty,oneof=forward backward bidirectional none"` // Defaults to forward
+	Label     string              `json:"label" db:"label"`
+	Protocol  string              `json:"protocol" db:"protocol"`  // One of ConnectionProtocols, or empty
+	Metadata  JSON                `json:"metadata" db:"metadata"`  // Free-form details such as topic names or ports
+	Status    ConnectionStatus    `json:"status,omitempty" db:"-"` // Computed from the endpoint statuses
+	CreatedAt time.Time           `json:"created_at" db:"created_at"`
+}
+
+// ConnectionStatus is the health of a connection derived from the services at both ends
+type ConnectionStatus string
+
+const (
+	ConnectionHealthy ConnectionStatus = "healthy" // both ends alive
+	ConnectionAtRisk  ConnectionStatus = "at-risk" // an end is degraded
+	ConnectionBroken  ConnectionStatus = "broken"  // an end is dead
+	ConnectionUnknown ConnectionStatus = "unknown" // an end has not been checked yet
+)
+
+// ConnectionStatusOf derives a connection's status from the statuses of its endpoints
+func ConnectionStatusOf(source, target ServiceStatus) ConnectionStatus {
+	switch {
+	case source == StatusDead || target == StatusDead:
+		return ConnectionBroken
+	case source == StatusDegraded || target == StatusDegraded:
+		return ConnectionAtRisk
+	case source == StatusAlive && target == StatusAlive:
+		return ConnectionHealthy
+	default:
+		return ConnectionUnknown
+	}
 }
 
 // ServicePosition represents the position of a service in a diagram
@@ -110,16 +444,718 @@ type HealthcheckResult struct {
 	StatusCode   int           `json:"status_code" db:"status_code"`
 	ResponseTime int           `json:"response_time" db:"response_time"`
 	Error        string        `json:"error" db:"error"`
+	Output       string        `json:"output,omitempty" db:"output"` // What the check printed, such as the standard output of a script
 	CheckedAt    time.Time     `json:"checked_at" db:"checked_at"`
 }
 
+// StatusEvent records a change of a service's status. EndedAt is the time of the service's
+// following event, nil while the status is still current.
+type StatusEvent struct {
+	ID              int           `json:"id" db:"id"`
+	ServiceID       int           `json:"service_id" db:"service_id"`
+	FromStatus      ServiceStatus `json:"from_status" db:"from_status"`
+	ToStatus        ServiceStatus `json:"to_status" db:"to_status"`
+	ResultID        *int          `json:"result_id" db:"result_id"` // Healthcheck result that triggered the transition
+	OccurredAt      time.Time     `json:"occurred_at" db:"occurred_at"`
+	EndedAt         *time.Time    `json:"ended_at"`
+	DurationSeconds float64       `json:"duration_seconds"`
+}
+
+// ActivityType classifies an entry of a diagram's activity feed
+type ActivityType string
+
+const (
+	ActivityEdit         ActivityType = "edit"          // a structural change, recorded as a diagram version
+	ActivityStatusChange ActivityType = "status_change" // a service changed status
+	ActivityAlert        ActivityType = "alert"         // notification channels were alerted of a status change
+	ActivityMaintenance  ActivityType = "maintenance"   // a maintenance window started
+)
+
+// ActivityTypes lists every activity type, for validating filters
+var ActivityTypes = []ActivityType{ActivityEdit, ActivityStatusChange, ActivityAlert, ActivityMaintenance}
+
+// Activity is an entry of a diagram's activity feed. ID is that of the version, status event,
+// alert or maintenance window it stands for; the fields that do not apply to its type are empty.
+type Activity struct {
+	Type        ActivityType  `json:"type"`
+	ID          int           `json:"id"`
+	OccurredAt  time.Time     `json:"occurred_at"`
+	ServiceID   *int          `json:"service_id,omitempty"`
+	ServiceName string        `json:"service_name,omitempty"`
+	UserID      *int          `json:"user_id,omitempty"` // who made an edit
+	Username    string        `json:"username,omitempty"`
+	Summary     string        `json:"summary,omitempty"` // of an edit, or the reason for maintenance
+	Version     int           `json:"version,omitempty"`
+	FromStatus  ServiceStatus `json:"from_status,omitempty"`
+	ToStatus    ServiceStatus `json:"to_status,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	EndsAt      *time.Time    `json:"ends_at,omitempty"` // of a maintenance window
+}
+
+// OutageStats summarizes a service's outages (periods spent dead) over a time window.
+// Durations are in seconds; MTTR and MTBF are zero when there is nothing to average.
+type OutageStats struct {
+	ServiceID            int        `json:"service_id"`
+	From                 time.Time  `json:"from"`
+	To                   time.Time  `json:"to"`
+	Outages              int        `json:"outages"`
+	OngoingOutage        bool       `json:"ongoing_outage"`
+	TotalDowntimeSeconds float64    `json:"total_downtime_seconds"`
+	MTTRSeconds          float64    `json:"mttr_seconds"` // Mean time to recovery over outages that ended in the window
+	MTBFSeconds          float64    `json:"mtbf_seconds"` // Mean operating time between the start of outages
+	LongestOutageSeconds float64    `json:"longest_outage_seconds"`
+	LongestOutageStart   *time.Time `json:"longest_outage_start"`
+}
+
+// ServiceCertificate is a TLS certificate a service served to its HTTPS checks, recorded when it
+// was first seen
+type ServiceCertificate struct {
+	ServiceID int       `json:"service_id" db:"service_id"`
+	NotAfter  time.Time `json:"not_after" db:"not_after"`
+	Subject   string    `json:"subject" db:"subject"`
+	Issuer    string    `json:"issuer" db:"issuer"`
+	FirstSeen time.Time `json:"first_seen" db:"first_seen"`
+}
+
+// ServiceForecast projects a service's certificate expiry, error budget and latency from its
+// history. Warnings name what needs attention before it becomes an outage.
+type ServiceForecast struct {
+	ServiceID   int                  `json:"service_id"`
+	Certificate *CertificateForecast `json:"certificate"` // nil until an HTTPS check saw a certificate
+	ErrorBudget ErrorBudgetForecast  `json:"error_budget"`
+	Latency     LatencyForecast      `json:"latency"`
+	Warnings    []string             `json:"warnings"`
+	GeneratedAt time.Time            `json:"generated_at"`
+}
+
+// CertificateForecast tells when the current certificate of a service expires and whether it is
+// late for renewal, judging by how many days before expiry the previous ones were replaced
+type CertificateForecast struct {
+	Subject           string    `json:"subject"`
+	Issuer            string    `json:"issuer"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	DaysLeft          float64   `json:"days_left"`
+	Renewals          int       `json:"renewals"`            // Certificates replaced so far
+	RenewedDaysBefore *float64  `json:"renewed_days_before"` // Median days before expiry previous certificates were replaced
+	RenewalOverdue    bool      `json:"renewal_overdue"`
+}
+
+// BurnRate is how fast a window consumed the error budget: 1 spends exactly the budget over the
+// SLO period, higher exhausts it sooner
+type BurnRate struct {
+	Window        string  `json:"window"`
+	UptimePercent float64 `json:"uptime_percent"`
+	Rate          float64 `json:"rate"`
+}
+
+// ErrorBudgetForecast projects when the error budget of the SLO period runs out at the burn rate
+// of the last day
+type ErrorBudgetForecast struct {
+	SLOTarget   float64    `json:"slo_target"`
+	PeriodDays  int        `json:"period_days"`
+	Remaining   float64    `json:"remaining"` // Percentage of the period's budget still unused
+	BurnRates   []BurnRate `json:"burn_rates"`
+	ExhaustedAt *time.Time `json:"exhausted_at"` // nil when the budget lasts the period at the current rate
+}
+
+// LatencyForecast extrapolates the linear trend of a service's daily average response time
+type LatencyForecast struct {
+	Days          int     `json:"days"` // Daily averages the trend is fitted to
+	Current       float64 `json:"current"`
+	SlopePerDay   float64 `json:"slope_per_day"` // Milliseconds gained each day
+	Projected7d   float64 `json:"projected_7d"`
+	Projected30d  float64 `json:"projected_30d"`
+	ChangePercent float64 `json:"change_percent"` // Projected change over the next 30 days
+}
+
+// MaintenanceWindow represents a planned downtime period for a service or a whole diagram.
+// Healthcheck results recorded during a window are excluded from uptime calculations.
+type MaintenanceWindow struct {
+	ID        int       `json:"id" db:"id"`
+	ServiceID *int      `json:"service_id" db:"service_id"`
+	DiagramID *int      `json:"diagram_id" db:"diagram_id"`
+	Reason    string    `json:"reason" db:"reason"`
+	StartsAt  time.Time `json:"starts_at" db:"starts_at" binding:"required"`
+	EndsAt    time.Time `json:"ends_at" db:"ends_at" binding:"required"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// IncidentStatus represents the lifecycle stage of an incident
+type IncidentStatus string
+
+const (
+	IncidentInvestigating IncidentStatus = "investigating"
+	IncidentIdentified    IncidentStatus = "identified"
+	IncidentMonitoring    IncidentStatus = "monitoring"
+	IncidentResolved      IncidentStatus = "resolved"
+)
+
+// IncidentImpact represents how severely an incident affects a diagram
+type IncidentImpact string
+
+const (
+	ImpactMinor    IncidentImpact = "minor"
+	ImpactMajor    IncidentImpact = "major"
+	ImpactCritical IncidentImpact = "critical"
+)
+
+// Incident represents an operator-authored incident announced on a diagram's status page
+type Incident struct {
+	ID         int            `json:"id" db:"id"`
+	DiagramID  int            `json:"diagram_id" db:"diagram_id"`
+	Title      string         `json:"title" db:"title" binding:"required"`
+	Message    string         `json:"message" db:"message"`
+	Status     IncidentStatus `json:"status" db:"status" binding:"omitempty,oneof=investigating identified monitoring resolved"`
+	Impact     IncidentImpact `json:"impact" db:"impact" binding:"omitempty,oneof=minor major critical"`
+	StartedAt  time.Time      `json:"started_at" db:"started_at"`
+	ResolvedAt *time.Time     `json:"resolved_at" db:"resolved_at"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// RootCauseAnalysis ranks the services that failed together, by how likely their failure caused
+// the others', from the connections between them and the order they failed in
+type RootCauseAnalysis struct {
+	DiagramID  int                  `json:"diagram_id"`
+	IncidentID int                  `json:"incident_id,omitempty"`
+	From       time.Time            `json:"from"`
+	To         time.Time            `json:"to"`
+	Failed     int                  `json:"failed"` // Services that went dead or degraded in the window
+	RootCause  *RootCauseCandidate  `json:"root_cause"`
+	Candidates []RootCauseCandidate `json:"candidates"`
+}
+
+// RootCauseCandidate is a failed service that may have caused the failures of others
+type RootCauseCandidate struct {
+	ServiceID   int           `json:"service_id"`
+	ServiceName string        `json:"service_name"`
+	Status      ServiceStatus `json:"status"`
+	FailedAt    time.Time     `json:"failed_at"`
+	Impacted    []int         `json:"impacted"` // Failed services depending on this one, directly or not
+	Score       float64       `json:"score"`    // Between 0 and 1
+	Reasons     []string      `json:"reasons"`
+}
+
+// StatusPageSettings represents the public status page configuration of a diagram
+type StatusPageSettings struct {
+	Enabled bool   `json:"enabled"`
+	Slug    string `json:"slug"`
+	Title   string `json:"title"`
+}
+
+// StatusPageService is the public view of a single service; connection details are deliberately omitted
+type StatusPageService struct {
+	ID            int           `json:"id"`
+	Name          string        `json:"name"`
+	Status        ServiceStatus `json:"status"`
+	UptimePercent float64       `json:"uptime_percent"`
+	LastChecked   *time.Time    `json:"last_checked"`
+}
+
+// StatusPageGroup groups the services of a status page by service type
+type StatusPageGroup struct {
+	Name     string              `json:"name"`
+	Status   ServiceStatus       `json:"status"`
+	Services []StatusPageService `json:"services"`
+}
+
+// StatusPage is the read-only, unauthenticated summary of a diagram
+type StatusPage struct {
+	Title         string            `json:"title"`
+	Description   string            `json:"description"`
+	OverallStatus ServiceStatus     `json:"overall_status"`
+	UptimeWindow  string            `json:"uptime_window"`
+	Groups        []StatusPageGroup `json:"groups"`
+	Incidents     []Incident        `json:"incidents"`
+	GeneratedAt   time.Time         `json:"generated_at"`
+}
+
+// UptimeSummary represents availability over a time window measured against an SLO target
+type UptimeSummary struct {
+	ServiceID            int       `json:"service_id,omitempty"`
+	ServiceName          string    `json:"service_name,omitempty"`
+	From                 time.Time `json:"from"`
+	To                   time.Time `json:"to"`
+	TotalChecks          int       `json:"total_checks"`
+	UpChecks             int       `json:"up_checks"`
+	DownChecks           int       `json:"down_checks"`
+	UptimePercent        float64   `json:"uptime_percent"`
+	SLOTarget            float64   `json:"slo_target"`
+	ErrorBudgetRemaining float64   `json:"error_budget_remaining"` // Percentage of the allowed downtime still unused, negative when the SLO is breached
+	SLOMet               bool      `json:"slo_met"`
+}
+
+// ApplySLO fills in the derived uptime percentage and error budget for the given SLO target
+func (u *UptimeSummary) ApplySLO(target float64) {
+	if target <= 0 || target > 100 {
+		target = DefaultSLOTarget
+	}
+	u.SLOTarget = target
+
+	// With no data there is nothing to hold against the budget
+	if u.TotalChecks == 0 {
+		u.UptimePercent = 100
+		u.ErrorBudgetRemaining = 100
+		u.SLOMet = true
+		return
+	}
+
+	u.UptimePercent = float64(u.UpChecks) / float64(u.TotalChecks) * 100
+	u.SLOMet = u.UptimePercent >= target
+
+	allowedDowntime := 100 - target
+	if allowedDowntime == 0 {
+		if u.DownChecks == 0 {
+			u.ErrorBudgetRemaining = 100
+		} else {
+			u.ErrorBudgetRemaining = -100
+		}
+		return
+	}
+	consumed := 100 - u.UptimePercent
+	u.ErrorBudgetRemaining = (allowedDowntime - consumed) / allowedDowntime * 100
+}
+
+// MetricsBucket represents aggregated response times and status counts for one time bucket
+type MetricsBucket struct {
+	Timestamp       time.Time `json:"timestamp"`
+	Count           int       `json:"count"`
+	AvgResponseTime float64   `json:"avg_response_time"`
+	P50ResponseTime float64   `json:"p50_response_time"`
+	P95ResponseTime float64   `json:"p95_response_time"`
+	P99ResponseTime float64   `json:"p99_response_time"`
+	AliveCount      int       `json:"alive_count"`
+	DegradedCount   int       `json:"degraded_count"`
+	DeadCount       int       `json:"dead_count"`
+}
+
+// RollupGranularity is the bucket width of a healthcheck rollup
+type RollupGranularity string
+
+const (
+	RollupHourly RollupGranularity = "hour"
+	RollupDaily  RollupGranularity = "day"
+)
+
+// HealthcheckRollup summarizes a service's healthcheck results over one hour or day
+type HealthcheckRollup struct {
+	ServiceID       int               `json:"service_id" db:"service_id"`
+	Granularity     RollupGranularity `json:"granularity" db:"granularity"`
+	BucketStart     time.Time         `json:"bucket_start" db:"bucket_start"`
+	TotalChecks     int               `json:"total_checks" db:"total_checks"`
+	UpChecks        int               `json:"up_checks" db:"up_checks"`
+	FailureCount    int               `json:"failure_count" db:"failure_count"`
+	UptimePercent   float64           `json:"uptime_percent"`
+	AvgResponseTime float64           `json:"avg_response_time" db:"avg_response_time"`
+	P95ResponseTime float64           `json:"p95_response_time" db:"p95_response_time"`
+}
+
+// HeatmapBucket is one cell of an availability heatmap. UptimePercent is nil when no checks ran.
+type HeatmapBucket struct {
+	Start         time.Time `json:"start"`
+	TotalChecks   int       `json:"total_checks"`
+	UpChecks      int       `json:"up_checks"`
+	UptimePercent *float64  `json:"uptime_percent"`
+	Status        string    `json:"status"` // up, degraded, down or no_data
+}
+
+// Heatmap is a dense series of availability buckets for a service, oldest first
+type Heatmap struct {
+	ServiceID     int               `json:"service_id"`
+	Granularity   RollupGranularity `json:"granularity"`
+	SLOTarget     float64           `json:"slo_target"`
+	UptimePercent float64           `json:"uptime_percent"`
+	Buckets       []HeatmapBucket   `json:"buckets"`
+}
+
+// ResultsTableStats describes the storage used by healthcheck results
+type ResultsTableStats struct {
+	RowCount         int64      `json:"row_count"` // Estimated from the statistics Postgres keeps
+	TotalSizeBytes   int64      `json:"total_size_bytes"`
+	TotalSize        string     `json:"total_size"`
+	OldestResult     *time.Time `json:"oldest_result"`
+	DefaultRetention int        `json:"default_retention_days"`
+}
+
+// ComponentHealth is the state of one part of the backend, for GET /api/system/health
+type ComponentHealth struct {
+	Name    string                 `json:"name"`
+	Healthy bool                   `json:"healthy"`
+	Error   string                 `json:"error,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// SystemHealth describes the backend process and the components it depends on
+type SystemHealth struct {
+	Healthy       bool              `json:"healthy"`
+	StartedAt     time.Time         `json:"started_at"`
+	UptimeSeconds float64           `json:"uptime_seconds"`
+	GoVersion     string            `json:"go_version"`
+	Goroutines    int               `json:"goroutines"`
+	HeapBytes     uint64            `json:"heap_bytes"`
+	Components    []ComponentHealth `json:"components"`
+}
+
 // StatusUpdate represents a real-time status update
 type StatusUpdate struct {
 	ServiceID int           `json:"service_id"`
+	DiagramID int           `json:"diagram_id"`
 	Status    ServiceStatus `json:"status"`
 	Timestamp time.Time     `json:"timestamp"`
 }
 
+// ClientMessage is sent by WebSocket clients to authenticate and to choose the updates they
+// receive. Type is "auth", carrying Token, which is also sent to renew the token before it expires,
+// "subscribe" or "unsubscribe", carrying DiagramIDs, or "filter"; clients that never subscribe
+// receive the updates of all diagrams they may see. A filter narrows the updates of those diagrams down to the services in ServiceIDs or tagged with
+// one of Tags, and with ChangesOnly to status changes, leaving out checking statuses, unchanged
+// statuses and healthcheck results. Each filter replaces the previous one; empty fields don't
+// restrict anything.
+type ClientMessage struct {
+	Type        string   `json:"type"`
+	Token       string   `json:"token,omitempty"`
+	DiagramIDs  []int    `json:"diagram_ids"`
+	ServiceIDs  []int    `json:"service_ids,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	ChangesOnly bool     `json:"changes_only,omitempty"`
+}
+
+// ConnectionStatusUpdate is sent to WebSocket clients when a connection's computed status changes
+type ConnectionStatusUpdate struct {
+	ConnectionID int              `json:"connection_id"`
+	DiagramID    int              `json:"diagram_id"`
+	SourceID     int              `json:"source_id"`
+	TargetID     int              `json:"target_id"`
+	Status       ConnectionStatus `json:"status"`
+	Timestamp    time.Time        `json:"timestamp"`
+}
+
+// Types of the messages sent to WebSocket clients
+const (
+	MessageServiceStatus     = "service_status"
+	MessageConnectionStatus  = "connection_status"
+	MessageHealthcheckResult = "healthcheck_result"
+	MessageServiceCreated    = "service_created"
+	MessageServiceUpdated    = "service_updated"
+	MessageServiceDeleted    = "service_deleted"
+	MessageConnectionCreated = "connection_created"
+	MessageConnectionUpdated = "connection_updated"
+	MessageConnectionDeleted = "connection_deleted"
+	MessageAlert             = "alert"
+	MessageBatch             = "batch"
+)
+
+// Envelope wraps every message sent to WebSocket clients; the type of Data depends on Type.
+// Routine marks the messages that report no change, which filtered clients may leave out.
+type Envelope struct {
+	Type      string      `json:"type"`
+	DiagramID int         `json:"diagram_id"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+	Routine   bool        `json:"-"`
+}
+
+// MessageType describes one type of WebSocket message, with an example of its data
+type MessageType struct {
+	Type        string      `json:"type"`
+	Description string      `json:"description"`
+	Data        interface{} `json:"data"`
+}
+
+// MessageTypes lists every type of message WebSocket clients receive
+var MessageTypes = []MessageType{
+	{MessageServiceStatus, "A service's status changed, or a check of it started", StatusUpdate{}},
+	{MessageConnectionStatus, "A connection's computed status changed", ConnectionStatusUpdate{}},
+	{MessageHealthcheckResult, "A check of a service finished, with its response time and error", HealthcheckResult{}},
+	{MessageServiceCreated, "A service was added to the diagram", Service{}},
+	{MessageServiceUpdated, "A service was edited", Service{}},
+	{MessageServiceDeleted, "A service was moved to the trash", DeletedEntity{}},
+	{MessageConnectionCreated, "A connection was added to the diagram", Connection{}},
+	{MessageConnectionUpdated, "A connection was edited", Connection{}},
+	{MessageConnectionDeleted, "A connection was deleted", DeletedEntity{}},
+	{MessageAlert, "A status change was alerted on", AlertEvent{}},
+	{MessageBatch, "Messages queued while the previous one was being sent, in order; statuses superseded by a later one are left out", []Envelope{}},
+}
+
+// DeletedEntity identifies a deleted service or connection
+type DeletedEntity struct {
+	ID int `json:"id"`
+}
+
+// AlertEvent is a status change notification channels were alerted of
+type AlertEvent struct {
+	ServiceID   int           `json:"service_id"`
+	ServiceName string        `json:"service_name"`
+	From        ServiceStatus `json:"from"`
+	To          ServiceStatus `json:"to"`
+	Error       string        `json:"error,omitempty"`
+	RunbookURL  string        `json:"runbook_url,omitempty"`
+}
+
+// NotificationChannelType identifies the delivery mechanism of a notification channel
+type NotificationChannelType string
+
+const (
+	ChannelNtfy   NotificationChannelType = "ntfy"
+	ChannelGotify NotificationChannelType = "gotify"
+	ChannelEmail  NotificationChannelType = "email"
+	ChannelSlack  NotificationChannelType = "slack"
+)
+
+// NotificationChannel represents a configured destination for status alerts
+type NotificationChannel struct {
+	ID        int                     `json:"id" db:"id"`
+	Name      string                  `json:"name" db:"name" binding:"required"`
+	Type      NotificationChannelType `json:"type" db:"type" binding:"required,oneof=ntfy gotify email slack"`
+	Config    JSON                    `json:"config" db:"config"`
+	Enabled   bool                    `json:"enabled" db:"enabled"`
+	Team      string                  `json:"team" db:"team" binding:"max=100"` // Set, the channel only receives the alerts of services the team owns
+	CreatedAt time.Time               `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time               `json:"updated_at" db:"updated_at"`
+}
+
+// AlertSeverity ranks alerts: services recovering are info, degrading a warning and going down
+// critical
+type AlertSeverity string
+
+const (
+	SeverityInfo     AlertSeverity = "info"
+	SeverityWarning  AlertSeverity = "warning"
+	SeverityCritical AlertSeverity = "critical"
+)
+
+// NotificationPreferences decide how the alerts of the services a user owns reach them
+// personally. Alerts sent to shared and team channels are not affected.
+type NotificationPreferences struct {
+	// Channels are the personal channels alerts are delivered on: email to the user's address,
+	// slack to SlackWebhookURL and ntfy to NtfyTopic on the server of the first enabled ntfy channel
+	Channels        []NotificationChannelType `json:"channels" binding:"dive,oneof=email slack ntfy"`
+	SlackWebhookURL string                    `json:"slack_webhook_url" binding:"omitempty,url"`
+	NtfyTopic       string                    `json:"ntfy_topic" binding:"max=255"`
+	MinSeverity     AlertSeverity             `json:"min_severity" binding:"required,oneof=info warning critical"`
+	// Quiet hours, as HH:MM in Timezone, hold back alerts; they may span midnight
+	QuietHoursStart string `json:"quiet_hours_start"`
+	QuietHoursEnd   string `json:"quiet_hours_end"`
+	Timezone        string `json:"timezone"` // IANA name such as Europe/Berlin, UTC when empty
+	// QuietHoursAllowCritical lets critical alerts through quiet hours
+	QuietHoursAllowCritical bool      `json:"quiet_hours_allow_critical"`
+	UpdatedAt               time.Time `json:"updated_at"`
+}
+
+// DefaultNotificationPreferences apply to users who never set theirs: every alert by email
+func DefaultNotificationPreferences() NotificationPreferences {
+	return NotificationPreferences{
+		Channels:    []NotificationChannelType{ChannelEmail},
+		MinSeverity: SeverityInfo,
+		Timezone:    "UTC",
+	}
+}
+
+// ReportCadence is how often a scheduled report is delivered
+type ReportCadence string
+
+const (
+	ReportWeekly  ReportCadence = "weekly"
+	ReportMonthly ReportCadence = "monthly"
+)
+
+// Report represents a scheduled uptime/latency summary delivered through notification channels
+type Report struct {
+	ID         int           `json:"id" db:"id"`
+	Name       string        `json:"name" db:"name" binding:"required"`
+	DiagramID  *int          `json:"diagram_id" db:"diagram_id"` // nil covers every diagram
+	Cadence    ReportCadence `json:"cadence" db:"cadence" binding:"required,oneof=weekly monthly"`
+	WindowDays int           `json:"window_days" db:"window_days"` // 0 covers the cadence's week or calendar month
+	ChannelIDs []int64       `json:"channel_ids" db:"channel_ids"` // empty delivers to every enabled channel
+	Enabled    bool          `json:"enabled" db:"enabled"`
+	LastSentAt *time.Time    `json:"last_sent_at" db:"last_sent_at"`
+	NextRunAt  time.Time     `json:"next_run_at" db:"next_run_at"`
+	CreatedAt  time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// WindowStart returns the start of the reporting window ending at t: window_days before t or,
+// without window_days, the calendar month before t for monthly reports and the week before it for
+// weekly ones
+func (r *Report) WindowStart(t time.Time) time.Time {
+	switch {
+	case r.WindowDays > 0:
+		return t.AddDate(0, 0, -r.WindowDays)
+	case r.Cadence == ReportMonthly:
+		start := t.AddDate(0, -1, 0)
+		// AddDate overflows into t's month when the previous one is shorter, e.g. from March 31
+		if start.Day() != t.Day() {
+			start = start.AddDate(0, 0, -start.Day())
+		}
+		return start
+	default:
+		return t.AddDate(0, 0, -7)
+	}
+}
+
+// NextRun returns the next delivery time after t: Mondays for weekly reports and the
+// first of the month for monthly ones, both at 08:00 UTC
+func (r *Report) NextRun(t time.Time) time.Time {
+	t = t.UTC()
+	if r.Cadence == ReportMonthly {
+		next := time.Date(t.Year(), t.Month(), 1, 8, 0, 0, 0, time.UTC)
+		if !next.After(t) {
+			next = next.AddDate(0, 1, 0)
+		}
+		return next
+	}
+
+	daysUntilMonday := (int(time.Monday) - int(t.Weekday()) + 7) % 7
+	next := time.Date(t.Year(), t.Month(), t.Day()+daysUntilMonday, 8, 0, 0, 0, time.UTC)
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// ReportServiceSummary is one row of a rendered report
+type ReportServiceSummary struct {
+	DiagramName     string  `json:"diagram_name"`
+	ServiceID       int     `json:"service_id"`
+	ServiceName     string  `json:"service_name"`
+	UptimePercent   float64 `json:"uptime_percent"`
+	SLOTarget       float64 `json:"slo_target"`
+	SLOMet          bool    `json:"slo_met"`
+	DownChecks      int     `json:"down_checks"`
+	AvgResponseTime float64 `json:"avg_response_time"`
+	P95ResponseTime float64 `json:"p95_response_time"`
+}
+
+// ReportSummary is the generated content of a report for one window
+type ReportSummary struct {
+	ReportID    int                    `json:"report_id"`
+	Name        string                 `json:"name"`
+	From        time.Time              `json:"from"`
+	To          time.Time              `json:"to"`
+	Services    []ReportServiceSummary `json:"services"`
+	SLOsMissed  int                    `json:"slos_missed"`
+	GeneratedAt time.Time              `json:"generated_at"`
+}
+
+// SearchResult is a diagram or service matching a global search, with its diagram for context
+type SearchResult struct {
+	Type         string        `json:"type"` // diagram or service
+	ID           int           `json:"id"`
+	Name         string        `json:"name"`
+	DiagramID    int           `json:"diagram_id"`
+	DiagramName  string        `json:"diagram_name"`
+	MatchedField string        `json:"matched_field"`
+	Snippet      string        `json:"snippet"`
+	Status       ServiceStatus `json:"status,omitempty"`
+	ServiceType  string        `json:"service_type,omitempty"`
+}
+
+// ErrorSignature groups healthcheck errors that only differ in numbers and IDs, such as the
+// addresses, ports and durations in them
+type ErrorSignature struct {
+	Signature  string    `json:"signature"`
+	Example    string    `json:"example"` // the latest error as reported
+	Count      int       `json:"count"`
+	ServiceIDs []int64   `json:"service_ids"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+}
+
+// DiscoverySourceType identifies the catalog a discovery source imports services from
+type DiscoverySourceType string
+
+const (
+	DiscoveryKubernetes DiscoverySourceType = "kubernetes"
+	DiscoveryConsul     DiscoverySourceType = "consul"
+	DiscoveryEureka     DiscoverySourceType = "eureka"
+)
+
+// DiscoverySource represents an external service catalog whose entries are imported as nodes into a diagram
+type DiscoverySource struct {
+	ID           int                 `json:"id" db:"id"`
+	Name         string              `json:"name" db:"name" binding:"required"`
+	Type         DiscoverySourceType `json:"type" db:"type" binding:"required,oneof=kubernetes consul eureka"`
+	DiagramID    int                 `json:"diagram_id" db:"diagram_id" binding:"required"`
+	Config       JSON                `json:"config" db:"config"`
+	SyncInterval int                 `json:"sync_interval" db:"sync_interval"` // Seconds between automatic syncs, 0 syncs on demand only
+	Enabled      bool                `json:"enabled" db:"enabled"`
+	LastSyncedAt *time.Time          `json:"last_synced_at" db:"last_synced_at"`
+	LastError    string              `json:"last_error" db:"last_error"`
+	CreatedAt    time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at" db:"updated_at"`
+}
+
+// DiscoverySyncResult summarizes one synchronization of a discovery source
+type DiscoverySyncResult struct {
+	SourceID     int       `json:"source_id"`
+	Discovered   int       `json:"discovered"`
+	Created      int       `json:"created"`
+	Updated      int       `json:"updated"`
+	Unchanged    int       `json:"unchanged"`
+	Skipped      int       `json:"skipped"`      // Nodes edited manually since the last sync, left untouched
+	Deregistered int       `json:"deregistered"` // Nodes newly missing from the catalog
+	SyncedAt     time.Time `json:"synced_at"`
+}
+
+// HeartbeatSignal is what a job reported when it last pinged its heartbeat service
+type HeartbeatSignal string
+
+const (
+	HeartbeatStart   HeartbeatSignal = "start"
+	HeartbeatSuccess HeartbeatSignal = "success"
+	HeartbeatFail    HeartbeatSignal = "fail"
+	HeartbeatLog     HeartbeatSignal = "log" // Only attaches its body, without changing the status
+)
+
+// Heartbeat configures a service with the HEARTBEAT healthcheck method, such as a cron job, which
+// pings its URL when it runs instead of being polled. It is down once a ping is overdue by more
+// than the grace time, reports a failure, or a run started and did not finish within the grace time.
+type Heartbeat struct {
+	ServiceID      int             `json:"service_id" db:"service_id"`
+	PingKey        string          `json:"ping_key" db:"ping_key"`
+	PingURL        string          `json:"ping_url" db:"-"`
+	Period         int             `json:"period" db:"period"` // Seconds expected between runs
+	Grace          int             `json:"grace" db:"grace"`   // Seconds a run may be late or last
+	LastPingAt     *time.Time      `json:"last_ping_at" db:"last_ping_at"`
+	LastStartAt    *time.Time      `json:"last_start_at" db:"last_start_at"`
+	LastSignal     HeartbeatSignal `json:"last_signal" db:"last_signal"` // success or fail, how the last run finished
+	LastExitStatus *int            `json:"last_exit_status" db:"last_exit_status"`
+	LastBody       string          `json:"last_body" db:"last_body"` // What the last ping posted, such as the job's output
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+}
+
+// HeartbeatRequest sets how often the job of a heartbeat service runs
+type HeartbeatRequest struct {
+	Period int `json:"period" binding:"required,min=60"`
+	Grace  int `json:"grace" binding:"min=0"`
+}
+
+// UptimeKumaImportRequest converts the monitors of an Uptime Kuma backup into services of a diagram
+type UptimeKumaImportRequest struct {
+	DiagramID int             `json:"diagram_id" binding:"required"`
+	Backup    json.RawMessage `json:"backup" binding:"required"` // The JSON file of Settings > Backup > Export
+}
+
+// UptimeRobotImportRequest converts the monitors of an Uptime Robot account into services of a diagram
+type UptimeRobotImportRequest struct {
+	DiagramID int    `json:"diagram_id" binding:"required"`
+	APIKey    string `json:"api_key" binding:"required"` // A read-only or main API key of the account
+}
+
+// SkippedMonitor is a monitor of another tool that has no equivalent healthcheck
+type SkippedMonitor struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// MonitorImportResult lists the services created from the monitors of another tool and the
+// monitors left out
+type MonitorImportResult struct {
+	Created []Service        `json:"created"`
+	Skipped []SkippedMonitor `json:"skipped"`
+}
+
 // UserRole represents the role of a user
 type UserRole string
 
@@ -128,6 +1164,39 @@ const (
 	RoleViewer UserRole = "viewer"
 )
 
+// OrganizationRole is a user's role within an organization
+type OrganizationRole string
+
+const (
+	OrgRoleAdmin  OrganizationRole = "admin"  // manages members and folders
+	OrgRoleMember OrganizationRole = "member" // edits diagrams
+	OrgRoleViewer OrganizationRole = "viewer" // read-only access
+)
+
+// Organization is a tenant owning diagrams and folders. Users access it through memberships;
+// platform admins can act in every organization.
+type Organization struct {
+	ID        int              `json:"id" db:"id"`
+	Name      string           `json:"name" db:"name" binding:"required"`
+	Role      OrganizationRole `json:"role,omitempty" db:"-"` // The current user's role in the organization
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// OrganizationMember is a user's membership in an organization
+type OrganizationMember struct {
+	UserID    int              `json:"user_id" db:"user_id"`
+	Username  string           `json:"username" db:"username"`
+	Email     string           `json:"email" db:"email"`
+	Role      OrganizationRole `json:"role" db:"role"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+}
+
+// OrganizationMemberRequest adds a user to an organization or changes their role
+type OrganizationMemberRequest struct {
+	Role OrganizationRole `json:"role" binding:"required,oneof=admin member viewer"`
+}
+
 // User represents a user in the system
 type User struct {
 	ID           int       `json:"id" db:"id"`
@@ -144,12 +1213,64 @@ type LoginRequest struct {
 	Username   string `json:"username" binding:"required"`
 	Password   string `json:"password" binding:"required"`
 	RememberMe bool   `json:"remember_me"`
+	Device     string `json:"device"` // name of the session in the session list; derived from the user agent when empty
 }
 
 // LoginResponse represents a user login response
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // access token lifetime in seconds
+	User         User   `json:"user"`
+}
+
+// RefreshTokenRequest exchanges a refresh token for a new access token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Session is a login of a user on one device. It lasts as long as its refresh tokens can be renewed.
+type Session struct {
+	ID         int       `json:"id" db:"id"`
+	UserID     int       `json:"user_id" db:"user_id"`
+	Device     string    `json:"device" db:"device"`
+	IPAddress  string    `json:"ip_address" db:"ip_address"`
+	UserAgent  string    `json:"user_agent" db:"user_agent"`
+	Current    bool      `json:"current"` // the session of the access token used for the request
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"` // login or last token refresh
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// SessionClient describes the client a session is used from
+type SessionClient struct {
+	Device    string
+	IPAddress string
+	UserAgent string
+}
+
+// OIDCConfig describes the single sign-on option of the login page
+type OIDCConfig struct {
+	Enabled     bool   `json:"enabled"`
+	DisplayName string `json:"display_name,omitempty"`
+	LoginURL    string `json:"login_url,omitempty"`
+}
+
+// LogoutRequest ends the current session. The access token used for the request is always revoked.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"` // also revoke this session's refresh tokens
+	AllSessions  bool   `json:"all_sessions"`  // revoke every token of the user, on all devices
+}
+
+// UpdateProfileRequest is the body of PUT /api/user/me
+type UpdateProfileRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ChangePasswordRequest is the body of POST /api/user/me/password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
 }
 
 // RegisterRequest represents a user registration request
@@ -167,9 +1288,491 @@ type FirstRunAdminRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 }
 
+// Invitation asks someone by email to join an organization. Their account is only created when
+// they accept it with the token emailed to them, which verifies the address.
+type Invitation struct {
+	ID             int        `json:"id" db:"id"`
+	OrganizationID int        `json:"organization_id" db:"organization_id"`
+	Email          string     `json:"email" db:"email"`
+	Username       string     `json:"username" db:"username"`
+	Role           UserRole   `json:"role" db:"role"`
+	InvitedBy      *int       `json:"invited_by" db:"invited_by"`
+	ExpiresAt      time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at" db:"accepted_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// InvitationRequest invites a user in place of creating them with a password
+type InvitationRequest struct {
+	Username string   `json:"username" binding:"required"`
+	Email    string   `json:"email" binding:"required,email"`
+	Role     UserRole `json:"role" binding:"required,oneof=admin viewer"`
+}
+
+// AcceptInvitationRequest creates the invited account with the password of the invitee's choosing
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
 // FirstRunAdminResponse represents a first-run admin setup response
 type FirstRunAdminResponse struct {
-	Message string `json:"message"`
-	User    User   `json:"user"`
-	Token   string `json:"token"`
+	Message      string `json:"message"`
+	User         User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// DiagramSnapshot is the structure of a diagram captured by a version
+type DiagramSnapshot struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Services    []Service    `json:"services"`
+	Connections []Connection `json:"connections"`
+}
+
+// DiagramVersion is a recorded state of a diagram's structure. Snapshot is only loaded for single versions.
+type DiagramVersion struct {
+	ID              int              `json:"id" db:"id"`
+	DiagramID       int              `json:"diagram_id" db:"diagram_id"`
+	Version         int              `json:"version" db:"version"`
+	Summary         string           `json:"summary" db:"summary"`
+	ServiceCount    int              `json:"service_count"`
+	ConnectionCount int              `json:"connection_count"`
+	CreatedBy       *int             `json:"created_by" db:"created_by"`
+	CreatedAt       time.Time        `json:"created_at" db:"created_at"`
+	Snapshot        *DiagramSnapshot `json:"snapshot,omitempty" db:"snapshot"`
+}
+
+// FieldChange is a single field that differs between two versions
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// ServiceChange lists the fields of a service that differ between two versions
+type ServiceChange struct {
+	ID      int           `json:"id"`
+	Name    string        `json:"name"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// ConnectionChange lists the attributes that differ on a connection present in both versions
+type ConnectionChange struct {
+	SourceID int           `json:"source_id"`
+	TargetID int           `json:"target_id"`
+	Changes  []FieldChange `json:"changes"`
+}
+
+// DiagramDiff describes how a diagram's structure changed between two versions
+type DiagramDiff struct {
+	FromVersion        int                `json:"from_version"`
+	ToVersion          int                `json:"to_version"`
+	DiagramChanges     []FieldChange      `json:"diagram_changes"`
+	ServicesAdded      []Service          `json:"services_added"`
+	ServicesRemoved    []Service          `json:"services_removed"`
+	ServicesChanged    []ServiceChange    `json:"services_changed"`
+	ConnectionsAdded   []Connection       `json:"connections_added"`
+	ConnectionsRemoved []Connection       `json:"connections_removed"`
+	ConnectionsChanged []ConnectionChange `json:"connections_changed"`
+}
+
+// AuditAction classifies a mutating API request
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
+
+// AuditEntry records one mutating API request: who made it, what it touched and the entity state around it.
+// user_id is kept without a foreign key so entries survive the deletion of the user.
+type AuditEntry struct {
+	ID         int             `json:"id" db:"id"`
+	UserID     *int            `json:"user_id" db:"user_id"`
+	Username   string          `json:"username" db:"username"`
+	Action     AuditAction     `json:"action" db:"action"`
+	Method     string          `json:"method" db:"method"`
+	Route      string          `json:"route" db:"route"`
+	EntityType string          `json:"entity_type" db:"entity_type"`
+	EntityID   *int            `json:"entity_id" db:"entity_id"`
+	Before     json.RawMessage `json:"before" db:"before_state"`
+	After      json.RawMessage `json:"after" db:"after_state"`
+	StatusCode int             `json:"status_code" db:"status_code"`
+	IPAddress  string          `json:"ip_address" db:"ip_address"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+// SecurityEventType classifies an authentication or account security event
+type SecurityEventType string
+
+const (
+	SecurityLoginSucceeded  SecurityEventType = "login_succeeded"
+	SecurityLoginFailed     SecurityEventType = "login_failed"
+	SecurityAccountLocked   SecurityEventType = "account_locked"
+	SecurityAccountUnlocked SecurityEventType = "account_unlocked"
+	SecurityTokenRefreshed  SecurityEventType = "token_refreshed"
+	SecurityTokenReused     SecurityEventType = "token_reused" // a refresh token was presented twice, revoking its session
+	SecurityLogout          SecurityEventType = "logout"
+	SecuritySessionRevoked  SecurityEventType = "session_revoked"
+	SecurityPasswordChanged SecurityEventType = "password_changed"
+	SecurityRoleChanged     SecurityEventType = "role_changed"
+	SecurityUserCreated     SecurityEventType = "user_created"
+	SecurityUserDeleted     SecurityEventType = "user_deleted"
+	SecurityUserInvited     SecurityEventType = "user_invited"
+	SecurityInviteAccepted  SecurityEventType = "invitation_accepted"
+)
+
+// SecurityEventTypes lists every security event type, for validating filters
+var SecurityEventTypes = []SecurityEventType{
+	SecurityLoginSucceeded, SecurityLoginFailed, SecurityAccountLocked, SecurityAccountUnlocked,
+	SecurityTokenRefreshed, SecurityTokenReused, SecurityLogout, SecuritySessionRevoked,
+	SecurityPasswordChanged, SecurityRoleChanged, SecurityUserCreated, SecurityUserDeleted,
+	SecurityUserInvited, SecurityInviteAccepted,
+}
+
+// SecurityEvent records an authentication or account change for security review. UserID is the
+// account concerned and ActorID whoever caused the event when it was someone else, such as an
+// admin changing a role. Like audit entries, they have no foreign keys and survive user deletion.
+type SecurityEvent struct {
+	ID        int               `json:"id" db:"id"`
+	Type      SecurityEventType `json:"type" db:"event_type"`
+	UserID    *int              `json:"user_id" db:"user_id"`
+	Username  string            `json:"username" db:"username"` // as given at login, also for unknown accounts
+	ActorID   *int              `json:"actor_id" db:"actor_id"`
+	Detail    string            `json:"detail" db:"detail"`
+	IPAddress string            `json:"ip_address" db:"ip_address"`
+	UserAgent string            `json:"user_agent" db:"user_agent"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+}
+
+// Config change events delivered to webhooks, named <entity>.<past-tense action>
+var WebhookEvents = []string{
+	"diagram.created", "diagram.updated", "diagram.deleted", "diagram.restored",
+	"service.created", "service.updated", "service.deleted", "service.restored",
+	"connection.created", "connection.updated", "connection.deleted",
+}
+
+// Webhook is an HTTP endpoint notified when diagrams, services or connections change
+type Webhook struct {
+	ID              int        `json:"id" db:"id"`
+	Name            string     `json:"name" db:"name" binding:"required"`
+	URL             string     `json:"url" db:"url" binding:"required,url"`
+	Secret          string     `json:"secret,omitempty" db:"secret"` // Signs payloads with HMAC-SHA256; never returned by the API
+	HasSecret       bool       `json:"has_secret" db:"-"`
+	Events          []string   `json:"events" db:"events"`         // empty subscribes to every event
+	DiagramID       *int       `json:"diagram_id" db:"diagram_id"` // nil covers every diagram
+	Enabled         bool       `json:"enabled" db:"enabled"`
+	LastStatusCode  int        `json:"last_status_code" db:"last_status_code"`
+	LastError       string     `json:"last_error" db:"last_error"`
+	LastDeliveredAt *time.Time `json:"last_delivered_at" db:"last_delivered_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// ConfigChangeEvent is the payload POSTed to webhooks. ID is the audit log entry the event
+// was derived from and doubles as an idempotency key.
+type ConfigChangeEvent struct {
+	ID         int             `json:"id"`
+	Event      string          `json:"event"`
+	EntityType string          `json:"entity_type"`
+	EntityID   *int            `json:"entity_id"`
+	DiagramID  *int            `json:"diagram_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Username   string          `json:"username"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// Trash lists soft-deleted diagrams and services until they are restored or purged
+type Trash struct {
+	Diagrams []Diagram `json:"diagrams"`
+	Services []Service `json:"services"`
+}
+
+// Folder groups diagrams. Folders nest through ParentID.
+type Folder struct {
+	ID             int       `json:"id" db:"id"`
+	OrganizationID int       `json:"organization_id" db:"organization_id"`
+	Name           string    `json:"name" db:"name" binding:"required"`
+	ParentID       *int      `json:"parent_id" db:"parent_id"`
+	DefaultPublic  bool      `json:"default_public" db:"default_public"` // Visibility of diagrams created in the folder without an explicit public flag
+	DiagramCount   int       `json:"diagram_count" db:"-"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DiagramFolderRequest moves a diagram into a folder, or to the top level when FolderID is nil
+type DiagramFolderRequest struct {
+	FolderID *int `json:"folder_id"`
+}
+
+// DiagramExport is a complete diagram with its services and connections, as exported to JSON and
+// accepted by the import. Connections refer to services by the IDs in Services.
+type DiagramExport struct {
+	Diagram     Diagram      `json:"diagram"`
+	Services    []Service    `json:"services"`
+	Connections []Connection `json:"connections"`
+}
+
+// ServiceCopyRequest copies or moves services into another diagram. ServiceIDs is only read by the
+// bulk routes. Connections re-creates the connections among copied services; moved services always
+// keep the connections among themselves.
+type ServiceCopyRequest struct {
+	DiagramID   int   `json:"diagram_id" binding:"required"`
+	ServiceIDs  []int `json:"service_ids,omitempty"`
+	Connections bool  `json:"connections"`
+}
+
+// ServiceIDsRequest selects the services of a bulk operation
+type ServiceIDsRequest struct {
+	ServiceIDs []int `json:"service_ids"`
+}
+
+// ServiceCloneRequest clones a service, with its healthcheck configuration and credentials, into
+// DiagramID or, when nil, its own diagram. Set fields replace those of the clone; the name
+// otherwise gets a " (copy)" suffix.
+type ServiceCloneRequest struct {
+	DiagramID      *int    `json:"diagram_id,omitempty"`
+	Name           *string `json:"name,omitempty"`
+	Host           *string `json:"host,omitempty"`
+	Port           *int    `json:"port,omitempty" binding:"omitempty,min=0,max=65535"`
+	HealthcheckURL *string `json:"healthcheck_url,omitempty"`
+}
+
+// AlertmanagerWebhook is the payload Prometheus Alertmanager posts to webhook receivers (version 4)
+type AlertmanagerWebhook struct {
+	Version  string              `json:"version"`
+	GroupKey string              `json:"groupKey"`
+	Status   string              `json:"status"` // firing or resolved
+	Receiver string              `json:"receiver"`
+	Alerts   []AlertmanagerAlert `json:"alerts" binding:"required"`
+}
+
+// AlertmanagerAlert is one alert of an Alertmanager notification
+type AlertmanagerAlert struct {
+	Status       string            `json:"status"` // firing or resolved
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// AlertLabelMapping matches the value of an alert label against a field of services: id, name,
+// host or tag
+type AlertLabelMapping struct {
+	Label string
+	Field string
+}
+
+// ExternalAlert is an alert firing in Alertmanager for a service. Until it resolves, the service
+// is at least degraded, or dead for critical alerts, whatever its own healthcheck finds.
+type ExternalAlert struct {
+	Fingerprint string        `json:"fingerprint" db:"fingerprint"`
+	ServiceID   int           `json:"service_id" db:"service_id"`
+	Name        string        `json:"name" db:"name"` // The alertname label
+	Severity    string        `json:"severity" db:"severity"`
+	Status      ServiceStatus `json:"status" db:"status"`
+	Summary     string        `json:"summary" db:"summary"`
+	StartsAt    *time.Time    `json:"starts_at" db:"starts_at"`
+	ReceivedAt  time.Time     `json:"received_at" db:"received_at"`
+}
+
+// AlertmanagerResult reports how the alerts of a notification were matched to services
+type AlertmanagerResult struct {
+	Firing    int      `json:"firing"`    // Alerts now reflected on at least one service
+	Resolved  int      `json:"resolved"`  // Alerts cleared from their services
+	Unmatched []string `json:"unmatched"` // Names of firing alerts no service matched
+}
+
+// GrafanaRange is the time range of a Grafana panel
+type GrafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// GrafanaSearchRequest asks a Grafana JSON datasource for the metrics matching Target
+type GrafanaSearchRequest struct {
+	Target string `json:"target"`
+}
+
+// GrafanaMetric is a metric Grafana can query: Value is the target, Text what the editor shows
+type GrafanaMetric struct {
+	Text  string `json:"text"`
+	Value string `json:"value"`
+}
+
+// GrafanaTarget is a query of a Grafana panel. Target is a metric as listed by search, such as
+// latency_p95:12 for the 95th percentile response time of service 12.
+type GrafanaTarget struct {
+	Target string `json:"target"`
+	RefID  string `json:"refId"`
+	Type   string `json:"type"` // timeserie (the default) or table
+}
+
+// GrafanaQueryRequest asks a Grafana JSON datasource for the data of a panel
+type GrafanaQueryRequest struct {
+	Range         GrafanaRange    `json:"range" binding:"required"`
+	IntervalMs    int64           `json:"intervalMs"`
+	MaxDataPoints int             `json:"maxDataPoints"`
+	Targets       []GrafanaTarget `json:"targets" binding:"required"`
+}
+
+// GrafanaSeries is a time series answering a target: datapoints are [value, Unix milliseconds]
+type GrafanaSeries struct {
+	Target     string       `json:"target"`
+	RefID      string       `json:"refId,omitempty"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// GrafanaColumn is a column of a GrafanaTable
+type GrafanaColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"` // time, number or string
+}
+
+// GrafanaTable answers a target of type table
+type GrafanaTable struct {
+	Type    string          `json:"type"` // always table
+	RefID   string          `json:"refId,omitempty"`
+	Columns []GrafanaColumn `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// GrafanaAnnotationQuery is the annotation a Grafana dashboard asks for. Query narrows it down to
+// service:<id> or diagram:<id>; empty covers every service.
+type GrafanaAnnotationQuery struct {
+	Name       string `json:"name"`
+	Datasource string `json:"datasource,omitempty"`
+	Enable     bool   `json:"enable"`
+	IconColor  string `json:"iconColor,omitempty"`
+	Query      string `json:"query"`
+}
+
+// GrafanaAnnotationRequest asks a Grafana JSON datasource for the annotations of a time range
+type GrafanaAnnotationRequest struct {
+	Range      GrafanaRange           `json:"range" binding:"required"`
+	Annotation GrafanaAnnotationQuery `json:"annotation"`
+}
+
+// GrafanaAnnotation marks a status change of a service on Grafana graphs, spanning the time the
+// status lasted once it ended
+type GrafanaAnnotation struct {
+	Annotation GrafanaAnnotationQuery `json:"annotation"`
+	Time       int64                  `json:"time"` // Unix milliseconds
+	TimeEnd    int64                  `json:"timeEnd,omitempty"`
+	IsRegion   bool                   `json:"isRegion"`
+	Title      string                 `json:"title"`
+	Tags       []string               `json:"tags"`
+	Text       string                 `json:"text"`
+}
+
+// CheckScript is a check uploaded by an admin for the long tail of bespoke checks. Services with
+// the SCRIPT healthcheck method run it with one of the allowed interpreters as an unprivileged
+// user; exit status 0 means alive, 1 degraded, 2 dead and 3 unknown, as for Nagios plugins.
+type CheckScript struct {
+	ID          int       `json:"id" db:"id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	Interpreter string    `json:"interpreter" db:"interpreter"` // Such as sh or python3, from SCRIPT_INTERPRETERS
+	Content     string    `json:"content" db:"content"`
+	CreatedBy   *int      `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// CheckScriptRequest creates or replaces a check script
+type CheckScriptRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+	Interpreter string `json:"interpreter" binding:"required"`
+	Content     string `json:"content" binding:"required,max=65536"`
+}
+
+// ServiceScript is the check script a service with the SCRIPT healthcheck method runs, with the
+// arguments it is passed
+type ServiceScript struct {
+	ServiceID int          `json:"service_id" db:"service_id"`
+	ScriptID  int          `json:"script_id" db:"script_id"`
+	Args      []string     `json:"args" db:"args"`
+	Script    *CheckScript `json:"script,omitempty" db:"-"`
+}
+
+// ServiceScriptRequest sets the check script of a service
+type ServiceScriptRequest struct {
+	ScriptID int      `json:"script_id" binding:"required"`
+	Args     []string `json:"args" binding:"max=32"`
+}
+
+// Environment is a deployment stage, such as prod or staging, that admins define and diagrams and
+// services are labelled with. The alerts of its services are capped at MaxSeverity, so that
+// staging, say, never pages.
+type Environment struct {
+	ID          int           `json:"id" db:"id"`
+	Name        string        `json:"name" db:"name" binding:"required,max=50"`
+	Description string        `json:"description" db:"description"`
+	MaxSeverity AlertSeverity `json:"max_severity" db:"max_severity" binding:"omitempty,oneof=info warning critical"` // Defaults to critical
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// EnvironmentOf returns the environment of a service, falling back to that of its diagram
+func EnvironmentOf(service Service, diagram *Diagram) string {
+	if service.Environment == "" && diagram != nil {
+		return diagram.Environment
+	}
+	return service.Environment
+}
+
+// ServiceOverlayRequest sets the environment overlay of a service: the host, port, healthcheck URL
+// and credentials it is checked with in that environment. Everything else, its node and edges
+// included, is shared with the service.
+type ServiceOverlayRequest struct {
+	Host           string              `json:"host"`
+	Port           int                 `json:"port" binding:"min=0,max=65535"`
+	HealthcheckURL string              `json:"healthcheck_url"`
+	Credentials    *ServiceCredentials `json:"credentials,omitempty"` // nil keeps the credentials of the overlay, or copies those of the service when creating it
+}
+
+// RegistrationToken lets deployment pipelines register their services in an organization with
+// POST /api/register, in any of its diagrams or only in DiagramID. Only the token's hash is kept;
+// the token itself is returned once, when it is created.
+type RegistrationToken struct {
+	ID             int        `json:"id" db:"id"`
+	OrganizationID int        `json:"organization_id" db:"organization_id"`
+	DiagramID      *int       `json:"diagram_id" db:"diagram_id"`
+	Name           string     `json:"name" db:"name" binding:"required,max=100"`
+	Token          string     `json:"token,omitempty" db:"-"`
+	CreatedBy      *int       `json:"created_by" db:"created_by"`
+	LastUsedAt     *time.Time `json:"last_used_at" db:"last_used_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// RegistrationRequest registers a service node from a deployment pipeline. The node is matched by
+// name within the diagram, so registering again updates it instead of adding another; everything
+// the request doesn't set, such as its position and connections, is left as it is.
+type RegistrationRequest struct {
+	DiagramID         int    `json:"diagram_id"` // May be left out with a token limited to one diagram
+	Name              string `json:"name" binding:"required,max=255"`
+	Description       string `json:"description"`
+	ServiceType       string `json:"service_type"`
+	Host              string `json:"host"`
+	Port              int    `json:"port" binding:"min=0,max=65535"`
+	HealthcheckMethod string `json:"healthcheck_method" binding:"required"`
+	HealthcheckURL    string `json:"healthcheck_url"`
+	UDPSendData       string `json:"udp_send_data"`                              // Required by UDP checks
+	PollingInterval   int    `json:"polling_interval" binding:"omitempty,min=1"` // 0 keeps the interval, or the diagram's default for new nodes
+	Tags              string `json:"tags"`
+	Environment       string `json:"environment" binding:"max=50"`
+}
+
+// RegistrationResponse is the registered node, and whether the registration created or changed it
+type RegistrationResponse struct {
+	Service Service `json:"service"`
+	Created bool    `json:"created"`
+	Changed bool    `json:"changed"`
 }
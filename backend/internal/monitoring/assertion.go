@@ -0,0 +1,44 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"service-weaver/internal/assertion"
+	"service-weaver/internal/models"
+	"strings"
+)
+
+// maxAssertionBody caps the response body read for an assertion
+const maxAssertionBody = 1 << 20
+
+// assertResponse gives the status of an HTTP check from the assertion of its service, evaluated
+// with the variables status_code, headers (by lowercase name), body, json (the parsed body, null
+// when it is not JSON) and latency (in milliseconds)
+func assertResponse(service models.Service, resp *http.Response, latency int) (models.ServiceStatus, error) {
+	program, err := assertion.Compile(service.Assertion)
+	if err != nil {
+		return models.StatusUnknown, fmt.Errorf("invalid assertion: %w", err)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAssertionBody))
+	if err != nil {
+		return models.StatusDead, fmt.Errorf("failed to read the response: %w", err)
+	}
+
+	headers := make(map[string]string, len(resp.Header))
+	for name, values := range resp.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+	var parsed any
+	if json.Unmarshal(body, &parsed) != nil {
+		parsed = nil
+	}
+	return program.Status(map[string]any{
+		"status_code": resp.StatusCode,
+		"headers":     headers,
+		"body":        string(body),
+		"json":        parsed,
+		"latency":     latency,
+	})
+}
@@ -0,0 +1,35 @@
+package monitoring
+
+import (
+	"crypto/tls"
+	"log"
+	"service-weaver/internal/models"
+	"time"
+)
+
+// recordCertificate stores the certificate an HTTPS check was served the first time this instance
+// sees it for the service, so renewals can be traced and expiry forecast
+func (h *HealthcheckScheduler) recordCertificate(service models.Service, state *tls.ConnectionState) {
+	if len(state.PeerCertificates) == 0 {
+		return
+	}
+	leaf := state.PeerCertificates[0]
+	if last, ok := h.certificates.Load(service.ID); ok && last.(time.Time).Equal(leaf.NotAfter) {
+		return
+	}
+
+	cert := models.ServiceCertificate{
+		ServiceID: service.ID,
+		NotAfter:  leaf.NotAfter,
+		Subject:   leaf.Subject.CommonName,
+		Issuer:    leaf.Issuer.CommonName,
+	}
+	if cert.Subject == "" && len(leaf.DNSNames) > 0 {
+		cert.Subject = leaf.DNSNames[0]
+	}
+	if err := h.repo.RecordCertificate(h.ctx, cert); err != nil {
+		log.Printf("Error recording certificate of service %d: %v", service.ID, err)
+		return
+	}
+	h.certificates.Store(service.ID, leaf.NotAfter)
+}
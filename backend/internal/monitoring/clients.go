@@ -0,0 +1,113 @@
+package monitoring
+
+import (
+	"context"
+	"io"
+	"log"
+	"service-weaver/internal/models"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// clientIdleTimeout is how long a database client is kept open without being used, unless the
+// service is polled less often
+const clientIdleTimeout = 5 * time.Minute
+
+// checkClients keeps a client per service for the database checks, so a check reuses the
+// connection of the previous one instead of opening and tearing down its own. A client is
+// replaced when the service's connection settings change and closed once it goes unused.
+type checkClients struct {
+	mu      sync.Mutex
+	clients map[int]*checkClient
+}
+
+type checkClient struct {
+	key      string // the connection settings the client was opened with
+	client   io.Closer
+	idle     time.Duration // how long the client may go unused
+	lastUsed time.Time
+}
+
+func newCheckClients() *checkClients {
+	return &checkClients{clients: make(map[int]*checkClient)}
+}
+
+// get returns the client of service opened with the settings identified by key, calling open when
+// there is none or the settings changed. A service is checked by one worker at a time, so its
+// client is never opened twice.
+func (c *checkClients) get(service models.Service, key string, open func() (io.Closer, error)) (io.Closer, error) {
+	c.mu.Lock()
+	cached := c.clients[service.ID]
+	if cached != nil && cached.key == key {
+		cached.lastUsed = time.Now()
+		c.mu.Unlock()
+		return cached.client, nil
+	}
+	delete(c.clients, service.ID)
+	c.mu.Unlock()
+	if cached != nil {
+		cached.client.Close()
+	}
+
+	client, err := open()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.clients[service.ID] = &checkClient{
+		key:      key,
+		client:   client,
+		idle:     max(clientIdleTimeout, 2*pollingInterval(service)),
+		lastUsed: time.Now(),
+	}
+	c.mu.Unlock()
+	return client, nil
+}
+
+// expire closes the clients gone unused for longer than their idle timeout, including those of
+// services no longer checked here
+func (c *checkClients) expire(now time.Time) {
+	c.closeWhere(func(cached *checkClient) bool { return now.Sub(cached.lastUsed) > cached.idle })
+}
+
+// closeAll closes every client, when the scheduler stops
+func (c *checkClients) closeAll() {
+	c.closeWhere(func(*checkClient) bool { return true })
+}
+
+func (c *checkClients) closeWhere(drop func(*checkClient) bool) {
+	c.mu.Lock()
+	var closing []io.Closer
+	for id, cached := range c.clients {
+		if drop(cached) {
+			closing = append(closing, cached.client)
+			delete(c.clients, id)
+		}
+	}
+	c.mu.Unlock()
+	for _, client := range closing {
+		if err := client.Close(); err != nil {
+			log.Printf("Error closing database client: %v", err)
+		}
+	}
+}
+
+// size returns the number of clients open
+func (c *checkClients) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.clients)
+}
+
+// mongoClient adapts *mongo.Client, which disconnects rather than closes, to checkClients
+type mongoClient struct {
+	client *mongo.Client
+}
+
+func (c mongoClient) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), loopInterval)
+	defer cancel()
+	return c.client.Disconnect(ctx)
+}
@@ -0,0 +1,78 @@
+package monitoring
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/secrets"
+	"strings"
+)
+
+// openCredentials decrypts the credentials of a service for the duration of a check and resolves
+// the ones that reference Vault. Services without credentials get an empty set.
+func openCredentials(service models.Service) (models.ServiceCredentials, error) {
+	var credentials models.ServiceCredentials
+	if service.SealedCredentials == "" {
+		return credentials, nil
+	}
+	plaintext := []byte(service.SealedCredentials)
+	if secrets.IsSealed(service.SealedCredentials) {
+		var err error
+		if plaintext, err = secrets.Default.Open(service.SealedCredentials); err != nil {
+			return credentials, fmt.Errorf("failed to decrypt credentials: %v", err)
+		}
+	}
+	if err := json.Unmarshal(plaintext, &credentials); err != nil {
+		return credentials, fmt.Errorf("failed to decode credentials: %v", err)
+	}
+
+	for _, value := range credentials.Fields() {
+		if !secrets.IsVaultReference(*value) {
+			continue
+		}
+		if secrets.Vault == nil {
+			return credentials, secrets.ErrVaultNotConfigured
+		}
+		resolved, err := secrets.Vault.Resolve(*value)
+		if err != nil {
+			return credentials, err
+		}
+		*value = resolved
+	}
+	return credentials, nil
+}
+
+// authorizeRequest adds the bearer token or basic auth of the credentials to an HTTP request,
+// unless the service's headers already set an Authorization header
+func authorizeRequest(req *http.Request, credentials models.ServiceCredentials) {
+	if req.Header.Get("Authorization") != "" {
+		return
+	}
+	if credentials.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+credentials.Token)
+	} else if credentials.Username != "" {
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+}
+
+// checkTLSConfig is the TLS configuration of a check, presenting the client certificate of the
+// credentials when one is set
+func checkTLSConfig(service models.Service, credentials models.ServiceCredentials) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: !service.SSLVerify}
+	if credentials.ClientCert != "" {
+		cert, err := tls.X509KeyPair([]byte(credentials.ClientCert), []byte(credentials.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// quoteConnValue quotes a value of a PostgreSQL key/value connection string, so passwords may
+// contain spaces and quotes
+func quoteConnValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
@@ -0,0 +1,74 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"service-weaver/internal/models"
+	"time"
+)
+
+// defaultRequestTimeout bounds the checks of services without a request timeout
+const defaultRequestTimeout = 5 * time.Second
+
+// checkDeadline is the hard cap on a check of service: twice its request timeout, as a check may
+// spend it on connecting and again on the exchange, plus a second per packet ping sends
+func checkDeadline(service models.Service) time.Duration {
+	timeout := time.Duration(service.RequestTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	deadline := 2 * timeout
+	if checkType(service) == "ICMP" {
+		packets := service.ICMPPacketCount
+		if packets <= 0 {
+			packets = 3
+		}
+		deadline += time.Duration(packets) * time.Second
+	}
+	return deadline
+}
+
+// runCheck performs the check of service within ctx. Checks pass ctx down to their dialers and
+// clients; should a driver still hang, the check is abandoned when ctx ends, reported dead, and
+// its goroutine left to return into a result nobody reads.
+func (h *HealthcheckScheduler) runCheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+	type outcome struct {
+		status models.ServiceStatus
+		err    error
+		result models.HealthcheckResult
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		checked := *result
+		status, err := h.performCheck(ctx, service, &checked)
+		done <- outcome{status, err, checked}
+	}()
+
+	select {
+	case o := <-done:
+		*result = o.result
+		return o.status, o.err
+	case <-ctx.Done():
+		return models.StatusDead, fmt.Errorf("check exceeded its deadline of %s", checkDeadline(service))
+	}
+}
+
+// dialCheck connects to address within ctx. The connection is closed once ctx ends, unblocking
+// any read or write still waiting on it.
+func dialCheck(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	closeWith(ctx, conn)
+	return conn, nil
+}
+
+// closeWith closes c once ctx ends, for clients that take no context. Checks close their clients
+// themselves as well; the second close is harmless.
+func closeWith(ctx context.Context, c io.Closer) {
+	context.AfterFunc(ctx, func() { c.Close() })
+}
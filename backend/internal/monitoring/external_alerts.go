@@ -0,0 +1,63 @@
+package monitoring
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"service-weaver/internal/models"
+	"time"
+)
+
+// refreshExternalAlerts reloads the alerts firing in Alertmanager along with the service
+// definitions, and checks the services whose alerts changed right away so the diagram reflects
+// them without waiting for their polling interval
+func (h *HealthcheckScheduler) refreshExternalAlerts(now time.Time) {
+	alerts, err := h.repo.GetExternalAlerts(h.ctx)
+	if err != nil {
+		log.Printf("Error getting external alerts: %v", err)
+		return
+	}
+	external := make(map[int]models.ExternalAlert)
+	for _, alert := range alerts {
+		// The most severe alert of each service comes first
+		if _, ok := external[alert.ServiceID]; !ok {
+			external[alert.ServiceID] = alert
+		}
+	}
+
+	h.externalMu.Lock()
+	previous := h.external
+	h.external = external
+	h.externalMu.Unlock()
+
+	for id, alert := range external {
+		if before, ok := previous[id]; !ok || before.Status != alert.Status || before.Fingerprint != alert.Fingerprint {
+			h.queue.expedite(id, now)
+		}
+	}
+	for id := range previous {
+		if _, ok := external[id]; !ok {
+			h.queue.expedite(id, now)
+		}
+	}
+}
+
+// applyExternalAlert worsens the outcome of a check to that of an alert firing for the service,
+// so incidents detected outside color the diagram too
+func (h *HealthcheckScheduler) applyExternalAlert(service models.Service, status models.ServiceStatus, err error) (models.ServiceStatus, error) {
+	h.externalMu.RLock()
+	alert, ok := h.external[service.ID]
+	h.externalMu.RUnlock()
+	if !ok || models.WorseStatus(status, alert.Status) == status {
+		return status, err
+	}
+
+	message := "alert " + alert.Name + " firing"
+	if alert.Summary != "" {
+		message += ": " + alert.Summary
+	}
+	if err != nil {
+		return alert.Status, fmt.Errorf("%s (check: %v)", message, err)
+	}
+	return alert.Status, errors.New(message)
+}
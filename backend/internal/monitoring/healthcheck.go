@@ -11,140 +11,358 @@ import (
 	"net"
 	"net/http"
 	"net/smtp"
-	"os"
 	"os/exec"
-	"strconv"
-	"strings"
+	"service-weaver/internal/config"
 	"service-weaver/internal/models"
+	"service-weaver/internal/notification"
 	"service-weaver/internal/repository"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/ssh"
 	"google.golang.org/grpc"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
-	
+	"google.golang.org/grpc/metadata"
+
 	// Database drivers
+	"github.com/Shopify/sarama"
 	"github.com/go-redis/redis/v8"
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/Shopify/sarama"
 	_ "github.com/lib/pq"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Store persists check results and status changes, and is implemented by *repository.Repository
+type Store interface {
+	GetAllServices(ctx context.Context) ([]models.Service, error)
+	GetServiceStatuses(ctx context.Context) (map[int]models.ServiceStatus, error)
+	GetServiceConnections(ctx context.Context, serviceID int) ([]models.Connection, error)
+	GetDiagramStatuses(ctx context.Context, diagramID int) ([]models.ServiceStatus, error)
+	CreateHealthcheckResult(ctx context.Context, result *models.HealthcheckResult) error
+	CreateHealthcheckResults(ctx context.Context, results []models.HealthcheckResult) error
+	UpdateServiceStatus(ctx context.Context, serviceID int, status models.ServiceStatus) error
+	RecordStatusTransition(ctx context.Context, serviceID int, from, to models.ServiceStatus, resultID int) (bool, error)
+	RecordAlert(ctx context.Context, serviceID int, from, to models.ServiceStatus, checkErr string) error
+	GetHeartbeat(ctx context.Context, serviceID int) (*models.Heartbeat, error)
+	GetExternalAlerts(ctx context.Context) ([]models.ExternalAlert, error)
+	GetServiceScript(ctx context.Context, serviceID int) (*models.ServiceScript, error)
+	RecordCertificate(ctx context.Context, cert models.ServiceCertificate) error
+}
+
+var _ Store = (*repository.Repository)(nil)
+
+// tracer and checkDuration report to the global OpenTelemetry providers, which are no-ops unless
+// an OTLP endpoint is configured. The meter hands out a working instrument even with an error.
+var (
+	tracer           = otel.Tracer("service-weaver/internal/monitoring")
+	checkDuration, _ = otel.Meter("service-weaver/internal/monitoring").Float64Histogram("healthcheck.duration",
+		metric.WithUnit("ms"), metric.WithDescription("Duration of healthchecks"))
+)
+
 type HealthcheckScheduler struct {
-	repo      *repository.Repository
-	clients   map[*websocket.Conn]bool
-	clientsMu sync.RWMutex
-	broadcast chan models.StatusUpdate
-	ctx       context.Context
-	cancel    context.CancelFunc
+	repo          Store
+	notifier      *notification.Dispatcher
+	clients       map[*websocket.Conn]*wsClient
+	clientsMu     sync.RWMutex
+	subscribers   map[chan models.StatusUpdate]bool
+	subscribersMu sync.Mutex
+	stats         *schedulerStats
+	pool          *checkPool
+	queue         *serviceQueue
+	results       *resultWriter
+	dbClients     *checkClients
+	exporter      ResultExporter // nil unless results are streamed to a broker
+	scripts       *ScriptConfig  // nil unless check scripts are enabled
+	changed       chan struct{}
+	leader        Leader                       // nil when every instance runs healthchecks
+	sharder       Sharder                      // set when instances split the healthchecks
+	shard, shards int                          // this instance's share when sharding
+	observed      map[int]models.ServiceStatus // statuses seen on the previous pass, relayed by standbys
+	services      []models.Service             // service definitions, reloaded when the repository reports a change
+
+	// The most severe alert firing for each service, from Alertmanager or the anomaly detector
+	external   map[int]models.ExternalAlert
+	externalMu sync.RWMutex
+
+	// The expiry of the certificate each service last served, so each is recorded once
+	certificates sync.Map
+
+	// WebSocket clients indexed by the diagrams they subscribed to, and those receiving all updates
+	byDiagram  map[int]map[*websocket.Conn]bool
+	unfiltered map[*websocket.Conn]bool
+
+	// Whether clients see the checking status while a check runs
+	broadcastChecking bool
+
+	// Connection statuses last sent to clients, so only changes are broadcast
+	connectionStatuses   map[int]models.ConnectionStatus
+	connectionStatusesMu sync.Mutex
+	ctx                  context.Context
+	cancel               context.CancelFunc
 }
 
-func NewHealthcheckScheduler(repo *repository.Repository) *HealthcheckScheduler {
+// NewHealthcheckScheduler creates a scheduler running due healthchecks on a worker pool sized by pool
+// and writing their results in batches sized by batch. With a leader, only the instance holding it
+// runs healthchecks; nil runs them on every instance.
+func NewHealthcheckScheduler(repo Store, notifier *notification.Dispatcher, pool PoolConfig, batch BatchConfig, leader Leader) *HealthcheckScheduler {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &HealthcheckScheduler{
-		repo:      repo,
-		clients:   make(map[*websocket.Conn]bool),
-		broadcast: make(chan models.StatusUpdate, 100),
-		ctx:       ctx,
-		cancel:    cancel,
+	h := &HealthcheckScheduler{
+		repo:        repo,
+		notifier:    notifier,
+		clients:     make(map[*websocket.Conn]*wsClient),
+		subscribers: make(map[chan models.StatusUpdate]bool),
+		stats:       newSchedulerStats(),
+		queue:       newServiceQueue(),
+		results:     newResultWriter(repo, batch),
+		dbClients:   newCheckClients(),
+		changed:     make(chan struct{}, 1),
+		leader:      leader,
+		ctx:         ctx,
+		cancel:      cancel,
+
+		connectionStatuses: make(map[int]models.ConnectionStatus),
+
+		byDiagram:  make(map[int]map[*websocket.Conn]bool),
+		unfiltered: make(map[*websocket.Conn]bool),
+
+		broadcastChecking: true,
 	}
+	h.pool = newCheckPool(pool, h.runHealthcheck)
+	return h
 }
 
 func (h *HealthcheckScheduler) Start() {
+	h.stats.beat(&h.stats.schedulerBeat)
+	h.stats.beat(&h.stats.hubBeat)
+	h.pool.start(h.ctx)
+	go h.results.run(h.ctx)
 	go h.broadcastHandler()
 	go h.scheduleHealthchecks()
 }
 
 func (h *HealthcheckScheduler) Stop() {
 	h.cancel()
+	h.results.wait()
+	h.dbClients.closeAll()
+	ctx, cancel := context.WithTimeout(context.Background(), loopInterval)
+	defer cancel()
+	if h.leader != nil {
+		if err := h.leader.Release(ctx); err != nil {
+			log.Printf("Error releasing scheduler leadership: %v", err)
+		}
+	}
+	if h.sharder != nil {
+		if err := h.sharder.Leave(ctx); err != nil {
+			log.Printf("Error leaving the scheduler shards: %v", err)
+		}
+	}
 }
 
-func (h *HealthcheckScheduler) AddClient(conn *websocket.Conn) {
-	h.clientsMu.Lock()
-	h.clients[conn] = true
-	h.clientsMu.Unlock()
+// Subscribe returns a channel that receives every status update until unsubscribe is called.
+// Updates are dropped for subscribers that fall more than buffer updates behind.
+func (h *HealthcheckScheduler) Subscribe(buffer int) (<-chan models.StatusUpdate, func()) {
+	ch := make(chan models.StatusUpdate, buffer)
+	h.subscribersMu.Lock()
+	h.subscribers[ch] = true
+	h.subscribersMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.subscribersMu.Lock()
+			delete(h.subscribers, ch)
+			h.subscribersMu.Unlock()
+			close(ch)
+		})
+	}
 }
 
-func (h *HealthcheckScheduler) RemoveClient(conn *websocket.Conn) {
-	h.clientsMu.Lock()
-	delete(h.clients, conn)
-	h.clientsMu.Unlock()
-	conn.Close()
+func (h *HealthcheckScheduler) publish(update models.StatusUpdate) {
+	h.subscribersMu.Lock()
+	defer h.subscribersMu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- update:
+		default:
+			h.stats.broadcastDroppedInc()
+		}
+	}
 }
 
+// broadcastHandler reports the WebSocket hub as running. Messages do not pass through it: they
+// are queued for each client as they are broadcast and written by the client's own writer.
 func (h *HealthcheckScheduler) broadcastHandler() {
+	heartbeat := time.NewTicker(loopInterval)
+	defer heartbeat.Stop()
+
 	for {
 		select {
-		case update := <-h.broadcast:
-			h.clientsMu.RLock()
-			for client := range h.clients {
-				err := client.WriteJSON(update)
-				if err != nil {
-					log.Printf("Error broadcasting to client: %v", err)
-					client.Close()
-					delete(h.clients, client)
-				}
-			}
-			h.clientsMu.RUnlock()
+		case <-heartbeat.C:
+			h.stats.beat(&h.stats.hubBeat)
 		case <-h.ctx.Done():
 			return
 		}
 	}
 }
 
+// scheduleHealthchecks queues the checks of services as they fall due. Service definitions are
+// kept in memory and reloaded when the repository reports a change, made here or announced by
+// another instance, and every resyncInterval in case an announcement was missed. Housekeeping runs
+// every loopInterval: the heartbeat, leader election and, on standby instances, relaying the
+// leader's status changes.
 func (h *HealthcheckScheduler) scheduleHealthchecks() {
-	ticker := time.NewTicker(5 * time.Second) // Check every 5 seconds for services to check
-	defer ticker.Stop()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
 
+	var leading bool
+	var lastPass, lastLoad time.Time
+	stale := true
 	for {
 		select {
-		case <-ticker.C:
-			services, err := h.repo.GetAllServices()
-			if err != nil {
-				log.Printf("Error getting services: %v", err)
-				continue
+		case <-timer.C:
+		case <-h.changed:
+			stale = true
+		case <-h.ctx.Done():
+			return
+		}
+
+		now := time.Now()
+		var relay func(models.Service) bool
+		if now.Sub(lastPass) >= loopInterval {
+			lastPass = now
+			h.stats.beat(&h.stats.schedulerBeat)
+			h.dbClients.expire(now)
+			if h.sharder != nil {
+				if h.reshard() {
+					stale = true
+				}
+				leading = true
+				relay = h.owns
+			} else {
+				wasLeading := leading
+				leading = h.lead()
+				if !leading {
+					relay = func(models.Service) bool { return false }
+				} else if !wasLeading {
+					h.observed = nil
+					stale = true
+				}
 			}
+		}
 
-			for _, service := range services {
-				if h.shouldCheck(service) {
-					go h.performHealthcheck(service)
+		if stale || now.Sub(lastLoad) >= resyncInterval {
+			if services, err := h.repo.GetAllServices(h.ctx); err != nil {
+				log.Printf("Error getting services: %v", err)
+			} else {
+				h.services = services
+				if leading {
+					h.queue.load(services, func(service models.Service) bool {
+						return h.shouldCheck(service) && h.owns(service)
+					})
+					h.refreshExternalAlerts(now)
 				}
+				stale, lastLoad = false, now
 			}
-		case <-h.ctx.Done():
-			return
 		}
+		if relay != nil {
+			h.relay(relay)
+		}
+		if leading {
+			h.enqueueDue(now)
+		}
+
+		// Sleep until the next check is due, waking up for housekeeping at the latest
+		wait := loopInterval - time.Since(lastPass)
+		if next, ok := h.queue.next(); leading && ok && time.Until(next) < wait {
+			wait = time.Until(next)
+		}
+		timer.Reset(max(wait, 0))
+	}
+}
+
+// enqueueDue hands the services due at now to the worker pool
+func (h *HealthcheckScheduler) enqueueDue(now time.Time) {
+	skipped := 0
+	for _, service := range h.queue.pop(now) {
+		if !h.pool.enqueue(service) {
+			h.queue.retry(service.ID, now.Add(loopInterval))
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		log.Printf("Healthcheck queue full, %d due checks postponed", skipped)
+	}
+}
+
+// runHealthcheck checks a service on a worker and schedules its next check
+func (h *HealthcheckScheduler) runHealthcheck(service models.Service) {
+	defer h.queue.done(service.ID)
+	h.performHealthcheck(service)
+}
+
+// ServicesChanged makes the scheduler reload the service definitions. The repository calls it
+// after services were created, changed or deleted.
+func (h *HealthcheckScheduler) ServicesChanged() {
+	select {
+	case h.changed <- struct{}{}:
+	default:
 	}
 }
 
+// BroadcastChecking sets whether clients are sent the transient checking status of a service while
+// its check runs, and its unchanged status once the check is done. It is on by default; turning
+// it off leaves only status changes to broadcast. It must be called before Start.
+func (h *HealthcheckScheduler) BroadcastChecking(enabled bool) {
+	h.broadcastChecking = enabled
+}
+
+// shouldCheck reports whether a service has an endpoint to check and is not paused
 func (h *HealthcheckScheduler) shouldCheck(service models.Service) bool {
-	if service.Host == "" {
+	if service.Paused != nil && *service.Paused {
+		return false
+	}
+
+	// Composite nodes have no endpoint of their own, heartbeat services are pinged instead, and
+	// check scripts may need no host
+	if service.Host == "" && service.ChildDiagramID == nil && service.HealthcheckMethod != "HEARTBEAT" && service.HealthcheckMethod != "SCRIPT" {
 		return false
 	}
 
 	// HTTP/HTTPS methods require a URL
-	if (service.HealthcheckMethod == "HTTP" || service.HealthcheckMethod == "HTTPS" ||
+	if service.ChildDiagramID == nil && (service.HealthcheckMethod == "HTTP" || service.HealthcheckMethod == "HTTPS" ||
 		service.HealthcheckMethod == "WEBSOCKET" || service.HealthcheckMethod == "WSS" ||
 		service.HealthcheckMethod == "GRPC") && service.HealthcheckURL == "" {
 		return false
 	}
-
-	if service.LastChecked == nil {
-		return true
-	}
-
-	interval := time.Duration(service.PollingInterval) * time.Second
-	return time.Since(*service.LastChecked) >= interval
+	return true
 }
 
 func (h *HealthcheckScheduler) performHealthcheck(service models.Service) {
 	start := time.Now()
+	h.stats.checkStarted()
 
-	// Update status to checking
-	h.updateServiceStatus(service.ID, models.StatusChecking)
+	spanCtx, span := tracer.Start(h.ctx, "healthcheck "+service.HealthcheckMethod, trace.WithAttributes(
+		attribute.Int("service.id", service.ID),
+		attribute.String("service.name", service.Name),
+		attribute.String("healthcheck.method", service.HealthcheckMethod),
+		attribute.String("net.peer.name", service.Host),
+		attribute.Int("net.peer.port", service.Port),
+	))
+	defer span.End()
+
+	// Show the check in progress; the transient status is broadcast but never stored
+	if h.broadcastChecking {
+		h.broadcastStatus(service, models.StatusChecking)
+	}
 
 	responseTime := int(time.Since(start).Milliseconds())
 	result := &models.HealthcheckResult{
@@ -153,63 +371,158 @@ func (h *HealthcheckScheduler) performHealthcheck(service models.Service) {
 		CheckedAt:    time.Now(),
 	}
 
-	var status models.ServiceStatus
-	var err error
+	ctx, cancel := context.WithTimeout(spanCtx, checkDeadline(service))
+	defer cancel()
+	status, err := h.runCheck(ctx, service, result)
+	if h.ctx.Err() != nil {
+		// Shutting down interrupted the check, so its outcome says nothing about the service
+		h.stats.checkAbandoned()
+		return
+	}
+
+	status, err = h.applyExternalAlert(service, status, err)
+	result.Status = status
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	// Save result to database. Results changing the service's status are written right away, so
+	// the status event can refer to them; the others are batched.
+	if status != service.CurrentStatus || !h.results.add(*result) {
+		if err := h.repo.CreateHealthcheckResult(h.ctx, result); err != nil {
+			log.Printf("Error saving healthcheck result: %v", err)
+		}
+	}
+	h.stats.checkFinished(result, checkType(service), time.Since(start))
+	if h.exporter != nil {
+		h.exporter.ExportResult(service, *result)
+	}
+	h.Broadcast(models.Envelope{
+		Type:      models.MessageHealthcheckResult,
+		DiagramID: service.DiagramID,
+		Timestamp: result.CheckedAt,
+		Data:      *result,
+		Routine:   true,
+	})
+	span.SetAttributes(
+		attribute.String("healthcheck.status", string(status)),
+		attribute.Int("healthcheck.response_time_ms", result.ResponseTime),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	checkDuration.Record(h.ctx, float64(time.Since(start))/float64(time.Millisecond), metric.WithAttributes(
+		attribute.String("healthcheck.method", service.HealthcheckMethod),
+		attribute.String("healthcheck.status", string(status)),
+	))
+
+	// Only a changed status is stored and broadcast, along with the connections it affects. An
+	// unchanged one is broadcast only to replace the checking status; its last_checked moves with
+	// the batched result.
+	if status != service.CurrentStatus {
+		h.updateServiceStatus(service, status)
+		h.broadcastConnectionStatuses(service.ID)
+		h.recordTransition(service, status, result.ID)
+	} else if h.broadcastChecking {
+		h.broadcastStatus(service, status)
+	}
+
+	if h.isAlertableTransition(service.CurrentStatus, status) {
+		h.notifier.NotifyStatusChange(service, service.CurrentStatus, status, result.Error)
+		if err := h.repo.RecordAlert(h.ctx, service.ID, service.CurrentStatus, status, result.Error); err != nil {
+			log.Printf("Error recording alert: %v", err)
+		}
+		h.Broadcast(models.Envelope{
+			Type:      models.MessageAlert,
+			DiagramID: service.DiagramID,
+			Timestamp: time.Now(),
+			Data: models.AlertEvent{
+				ServiceID:   service.ID,
+				ServiceName: service.Name,
+				From:        service.CurrentStatus,
+				To:          status,
+				Error:       result.Error,
+				RunbookURL:  service.RunbookURL,
+			},
+		})
+	}
+}
 
-	switch service.HealthcheckMethod {
+// performCheck runs the check matching the service's healthcheck method within ctx
+func (h *HealthcheckScheduler) performCheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+	switch checkType(service) {
+	case "COMPOSITE":
+		return h.performCompositeHealthcheck(ctx, service, result)
 	case "HTTP", "HTTPS":
-		status, err = h.performHTTPHealthcheck(service, result)
+		return h.performHTTPHealthcheck(ctx, service, result)
 	case "TCP":
-		status, err = h.performTCPHealthcheck(service, result)
+		return h.performTCPHealthcheck(ctx, service, result)
 	case "UDP":
-		status, err = h.performUDPHealthcheck(service, result)
+		return h.performUDPHealthcheck(ctx, service, result)
 	case "ICMP":
-		status, err = h.performICMPHealthcheck(service, result)
+		return h.performICMPHealthcheck(ctx, service, result)
 	case "DNS":
-		status, err = h.performDNSHealthcheck(service, result)
+		return h.performDNSHealthcheck(ctx, service, result)
 	case "WEBSOCKET":
-		status, err = h.performWebSocketHealthcheck(service, result)
+		return h.performWebSocketHealthcheck(ctx, service, result)
 	case "GRPC":
-		status, err = h.performGRPCHealthcheck(service, result)
+		return h.performGRPCHealthcheck(ctx, service, result)
 	case "SMTP":
-		status, err = h.performSMTPHealthcheck(service, result)
+		return h.performSMTPHealthcheck(ctx, service, result)
 	case "FTP":
-		status, err = h.performFTPHealthcheck(service, result)
+		return h.performFTPHealthcheck(ctx, service, result)
 	case "SSH":
-		status, err = h.performSSHHealthcheck(service, result)
+		return h.performSSHHealthcheck(ctx, service, result)
 	case "REDIS":
-		status, err = h.performRedisHealthcheck(service, result)
+		return h.performRedisHealthcheck(ctx, service, result)
 	case "MYSQL":
-		status, err = h.performMySQLHealthcheck(service, result)
+		return h.performMySQLHealthcheck(ctx, service, result)
 	case "POSTGRES":
-		status, err = h.performPostgresHealthcheck(service, result)
+		return h.performPostgresHealthcheck(ctx, service, result)
 	case "MONGODB":
-		status, err = h.performMongoDBHealthcheck(service, result)
+		return h.performMongoDBHealthcheck(ctx, service, result)
 	case "KAFKA":
-		status, err = h.performKafkaHealthcheck(service, result)
+		return h.performKafkaHealthcheck(ctx, service, result)
+	case "HEARTBEAT":
+		return h.performHeartbeatHealthcheck(ctx, service)
+	case "SCRIPT":
+		return h.performScriptHealthcheck(ctx, service, result)
 	default:
-		status = models.StatusDead
-		err = fmt.Errorf("unsupported health check method: %s", service.HealthcheckMethod)
-		result.Error = err.Error()
+		return models.StatusDead, fmt.Errorf("unsupported health check method: %s", service.HealthcheckMethod)
 	}
+}
 
-	result.Status = status
+// recordTransition appends to the status event log when the check changed the service's status
+func (h *HealthcheckScheduler) recordTransition(service models.Service, status models.ServiceStatus, resultID int) {
+	from := service.CurrentStatus
+	if from == models.StatusChecking {
+		from = models.StatusUnknown
+	}
+	recorded, err := h.repo.RecordStatusTransition(h.ctx, service.ID, from, status, resultID)
 	if err != nil {
-		result.Error = err.Error()
+		log.Printf("Error recording status transition: %v", err)
 	}
-
-	// Save result to database
-	if err := h.repo.CreateHealthcheckResult(result); err != nil {
-		log.Printf("Error saving healthcheck result: %v", err)
+	if recorded && h.exporter != nil {
+		h.exporter.ExportTransition(service, from, status, time.Now())
 	}
+}
 
-	// Update service status
-	h.updateServiceStatus(service.ID, status)
+// isAlertableTransition reports whether moving from one status to another warrants a notification.
+// The first successful check after creation (unknown -> alive) is not considered an alert.
+func (h *HealthcheckScheduler) isAlertableTransition(from, to models.ServiceStatus) bool {
+	if h.notifier == nil || from == to || to == models.StatusChecking || from == models.StatusChecking {
+		return false
+	}
+	if from == models.StatusUnknown && to == models.StatusAlive {
+		return false
+	}
+	return true
 }
 
-func (h *HealthcheckScheduler) performHTTPHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+func (h *HealthcheckScheduler) performHTTPHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	// Build URL
 	protocol := "http"
 	if service.HealthcheckMethod == "HTTPS" {
@@ -217,30 +530,35 @@ func (h *HealthcheckScheduler) performHTTPHealthcheck(service models.Service, re
 	}
 	url := fmt.Sprintf("%s://%s:%d%s", protocol, service.Host, service.Port, service.HealthcheckURL)
 
+	credentials, err := openCredentials(service)
+	if err != nil {
+		return models.StatusDead, err
+	}
+
 	// Create HTTP client with custom timeout
 	client := &http.Client{
 		Timeout: time.Duration(service.RequestTimeout) * time.Second,
 	}
 
-	// Configure SSL verification
-	if service.HealthcheckMethod == "HTTPS" && !service.SSLVerify {
-		transport := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	// Configure SSL verification and client certificates
+	if service.HealthcheckMethod == "HTTPS" && (!service.SSLVerify || credentials.ClientCert != "") {
+		tlsConfig, err := checkTLSConfig(service, credentials)
+		if err != nil {
+			return models.StatusDead, err
 		}
-		client.Transport = transport
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
 	}
 
 	// Create request
 	var req *http.Request
-	var err error
-	
+
 	if service.Body != "" && (service.HTTPMethod == "POST" || service.HTTPMethod == "PUT") {
 		var body io.Reader = strings.NewReader(service.Body)
-		req, err = http.NewRequest(service.HTTPMethod, url, body)
+		req, err = http.NewRequestWithContext(ctx, service.HTTPMethod, url, body)
 	} else {
-		req, err = http.NewRequest(service.HTTPMethod, url, nil)
+		req, err = http.NewRequestWithContext(ctx, service.HTTPMethod, url, nil)
 	}
-	
+
 	if err != nil {
 		return models.StatusDead, err
 	}
@@ -253,6 +571,7 @@ func (h *HealthcheckScheduler) performHTTPHealthcheck(service models.Service, re
 			}
 		}
 	}
+	authorizeRequest(req, credentials)
 
 	// Set follow redirects
 	if !service.FollowRedirects {
@@ -270,33 +589,41 @@ func (h *HealthcheckScheduler) performHTTPHealthcheck(service models.Service, re
 
 	result.StatusCode = resp.StatusCode
 	result.ResponseTime = int(time.Since(start).Milliseconds())
+	if resp.TLS != nil {
+		h.recordCertificate(service, resp.TLS)
+	}
+
+	// An assertion decides the status instead of the status mapping or expected status
+	if service.Assertion != "" {
+		return assertResponse(service, resp, result.ResponseTime)
+	}
 
 	// Determine status based on status mapping or expected status
 	return h.determineStatus(resp.StatusCode, service), nil
 }
 
-func (h *HealthcheckScheduler) performTCPHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+func (h *HealthcheckScheduler) performTCPHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	address := fmt.Sprintf("%s:%d", service.Host, service.Port)
-	
+
 	// Set timeout
 	timeout := time.Duration(service.RequestTimeout) * time.Second
-	
+
 	// Attempt to connect
-	conn, err := net.DialTimeout("tcp", address, timeout)
+	conn, err := dialCheck(ctx, "tcp", address, timeout)
 	if err != nil {
 		return models.StatusDead, err
 	}
 	defer conn.Close()
-	
+
 	// If send data is provided, send it
 	if service.TCPSendData != "" {
 		_, err = conn.Write([]byte(service.TCPSendData))
 		if err != nil {
 			return models.StatusDead, err
 		}
-		
+
 		// If expect data is provided, read and check response
 		if service.TCPExpectData != "" {
 			buffer := make([]byte, 1024)
@@ -304,49 +631,49 @@ func (h *HealthcheckScheduler) performTCPHealthcheck(service models.Service, res
 			if err != nil {
 				return models.StatusDead, err
 			}
-			
+
 			response := string(buffer[:n])
 			if !strings.Contains(response, service.TCPExpectData) {
 				return models.StatusDead, fmt.Errorf("expected response '%s' not found in '%s'", service.TCPExpectData, response)
 			}
 		}
 	}
-	
+
 	result.ResponseTime = int(time.Since(start).Milliseconds())
 	return models.StatusAlive, nil
 }
 
-func (h *HealthcheckScheduler) performUDPHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+func (h *HealthcheckScheduler) performUDPHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	address := fmt.Sprintf("%s:%d", service.Host, service.Port)
-	
+
 	// Set timeout
 	timeout := time.Duration(service.RequestTimeout) * time.Second
-	
+
 	// Create connection
-	conn, err := net.DialTimeout("udp", address, timeout)
+	conn, err := dialCheck(ctx, "udp", address, timeout)
 	if err != nil {
 		return models.StatusDead, err
 	}
 	defer conn.Close()
-	
+
 	// Set read deadline
 	err = conn.SetReadDeadline(time.Now().Add(timeout))
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	// Send data
 	if service.UDPSendData == "" {
 		return models.StatusDead, fmt.Errorf("UDP send data is required")
 	}
-	
+
 	_, err = conn.Write([]byte(service.UDPSendData))
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	// If expect data is provided, read and check response
 	if service.UDPExpectData != "" {
 		buffer := make([]byte, 1024)
@@ -354,59 +681,59 @@ func (h *HealthcheckScheduler) performUDPHealthcheck(service models.Service, res
 		if err != nil {
 			return models.StatusDead, err
 		}
-		
+
 		response := string(buffer[:n])
 		if !strings.Contains(response, service.UDPExpectData) {
 			return models.StatusDead, fmt.Errorf("expected response '%s' not found in '%s'", service.UDPExpectData, response)
 		}
 	}
-	
+
 	result.ResponseTime = int(time.Since(start).Milliseconds())
 	return models.StatusAlive, nil
 }
 
-func (h *HealthcheckScheduler) performICMPHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+func (h *HealthcheckScheduler) performICMPHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	// Set timeout
 	timeout := time.Duration(service.RequestTimeout) * time.Second
-	
+
 	// Execute ping command
 	packetCount := service.ICMPPacketCount
 	if packetCount <= 0 {
 		packetCount = 3
 	}
-	
-	cmd := exec.Command("ping", "-c", strconv.Itoa(packetCount), "-W", strconv.Itoa(int(timeout.Seconds())), service.Host)
+
+	cmd := exec.CommandContext(ctx, "ping", "-c", strconv.Itoa(packetCount), "-W", strconv.Itoa(int(timeout.Seconds())), service.Host)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	// Parse output to check if ping was successful
 	outputStr := string(output)
 	if strings.Contains(outputStr, "0 received") {
 		return models.StatusDead, fmt.Errorf("ping failed: %s", outputStr)
 	}
-	
+
 	result.ResponseTime = int(time.Since(start).Milliseconds())
 	return models.StatusAlive, nil
 }
 
-func (h *HealthcheckScheduler) performDNSHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+func (h *HealthcheckScheduler) performDNSHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	// Set timeout
 	timeout := time.Duration(service.RequestTimeout) * time.Second
-	
+
 	// Create DNS resolver
 	resolver := &net.Resolver{
 		PreferGo: true,
 	}
-	
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	
+
 	// Perform DNS query based on query type
 	switch service.DNSQueryType {
 	case "A":
@@ -414,7 +741,7 @@ func (h *HealthcheckScheduler) performDNSHealthcheck(service models.Service, res
 		if err != nil {
 			return models.StatusDead, err
 		}
-		
+
 		// Check expected result if provided
 		if service.DNSExpectedResult != "" {
 			found := false
@@ -428,24 +755,24 @@ func (h *HealthcheckScheduler) performDNSHealthcheck(service models.Service, res
 				return models.StatusDead, fmt.Errorf("expected IP '%s' not found in DNS response", service.DNSExpectedResult)
 			}
 		}
-		
+
 	case "CNAME":
 		cname, err := resolver.LookupCNAME(ctx, service.Host)
 		if err != nil {
 			return models.StatusDead, err
 		}
-		
+
 		// Check expected result if provided
 		if service.DNSExpectedResult != "" && cname != service.DNSExpectedResult {
 			return models.StatusDead, fmt.Errorf("expected CNAME '%s' but got '%s'", service.DNSExpectedResult, cname)
 		}
-		
+
 	case "MX":
 		mxRecords, err := resolver.LookupMX(ctx, service.Host)
 		if err != nil {
 			return models.StatusDead, err
 		}
-		
+
 		// Check expected result if provided
 		if service.DNSExpectedResult != "" {
 			found := false
@@ -459,13 +786,13 @@ func (h *HealthcheckScheduler) performDNSHealthcheck(service models.Service, res
 				return models.StatusDead, fmt.Errorf("expected MX record '%s' not found", service.DNSExpectedResult)
 			}
 		}
-		
+
 	case "NS":
 		nsRecords, err := resolver.LookupNS(ctx, service.Host)
 		if err != nil {
 			return models.StatusDead, err
 		}
-		
+
 		// Check expected result if provided
 		if service.DNSExpectedResult != "" {
 			found := false
@@ -479,13 +806,13 @@ func (h *HealthcheckScheduler) performDNSHealthcheck(service models.Service, res
 				return models.StatusDead, fmt.Errorf("expected NS record '%s' not found", service.DNSExpectedResult)
 			}
 		}
-		
+
 	case "TXT":
 		txtRecords, err := resolver.LookupTXT(ctx, service.Host)
 		if err != nil {
 			return models.StatusDead, err
 		}
-		
+
 		// Check expected result if provided
 		if service.DNSExpectedResult != "" {
 			found := false
@@ -499,82 +826,91 @@ func (h *HealthcheckScheduler) performDNSHealthcheck(service models.Service, res
 				return models.StatusDead, fmt.Errorf("expected TXT record containing '%s' not found", service.DNSExpectedResult)
 			}
 		}
-		
+
 	default:
 		return models.StatusDead, fmt.Errorf("unsupported DNS query type: %s", service.DNSQueryType)
 	}
-	
+
 	result.ResponseTime = int(time.Since(start).Milliseconds())
 	return models.StatusAlive, nil
 }
 
-func (h *HealthcheckScheduler) performWebSocketHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+func (h *HealthcheckScheduler) performWebSocketHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	// Build WebSocket URL
 	protocol := "ws"
 	if service.HealthcheckMethod == "WSS" {
 		protocol = "wss"
 	}
 	url := fmt.Sprintf("%s://%s:%d%s", protocol, service.Host, service.Port, service.HealthcheckURL)
-	
+
 	// Set timeout
 	timeout := time.Duration(service.RequestTimeout) * time.Second
-	
+
 	// Create dialer with timeout
 	dialer := websocket.Dialer{
 		HandshakeTimeout: timeout,
 	}
-	
+
 	// Skip SSL verification if needed
 	if protocol == "wss" && !service.SSLVerify {
 		dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 	}
-	
+
 	// Connect to WebSocket
-	conn, _, err := dialer.Dial(url, nil)
+	conn, _, err := dialer.DialContext(ctx, url, nil)
 	if err != nil {
 		return models.StatusDead, err
 	}
 	defer conn.Close()
-	
+	closeWith(ctx, conn)
+
 	// Send a ping message
 	err = conn.WriteMessage(websocket.PingMessage, []byte{})
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	// Wait for pong response
 	_, _, err = conn.ReadMessage()
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	result.ResponseTime = int(time.Since(start).Milliseconds())
 	return models.StatusAlive, nil
 }
 
-func (h *HealthcheckScheduler) performGRPCHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+func (h *HealthcheckScheduler) performGRPCHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	// Set timeout
 	timeout := time.Duration(service.RequestTimeout) * time.Second
-	
+
+	credentials, err := openCredentials(service)
+	if err != nil {
+		return models.StatusDead, err
+	}
+
 	// Create gRPC connection
 	address := fmt.Sprintf("%s:%d", service.Host, service.Port)
-	conn, err := grpc.Dial(address, grpc.WithInsecure(), grpc.WithTimeout(timeout))
+	conn, err := grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithTimeout(timeout))
 	if err != nil {
 		return models.StatusDead, err
 	}
 	defer conn.Close()
-	
+
 	// Create health client
 	client := healthpb.NewHealthClient(conn)
-	
+
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	
+	if credentials.Token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+credentials.Token)
+	}
+
 	// Check health
 	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{
 		Service: service.HealthcheckURL,
@@ -582,86 +918,91 @@ func (h *HealthcheckScheduler) performGRPCHealthcheck(service models.Service, re
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	// Check response status
 	if resp.Status != healthpb.HealthCheckResponse_SERVING {
 		return models.StatusDegraded, fmt.Errorf("gRPC service status: %s", resp.Status)
 	}
-	
+
 	result.ResponseTime = int(time.Since(start).Milliseconds())
 	return models.StatusAlive, nil
 }
 
-func (h *HealthcheckScheduler) performSMTPHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+func (h *HealthcheckScheduler) performSMTPHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	// Create SMTP client
 	address := fmt.Sprintf("%s:%d", service.Host, service.Port)
-	client, err := smtp.Dial(address)
+	conn, err := dialCheck(ctx, "tcp", address, time.Duration(service.RequestTimeout)*time.Second)
+	if err != nil {
+		return models.StatusDead, err
+	}
+	client, err := smtp.NewClient(conn, service.Host)
 	if err != nil {
+		conn.Close()
 		return models.StatusDead, err
 	}
 	defer client.Close()
-	
+
 	// Send NOOP command to check if server is responsive
 	err = client.Noop()
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	result.ResponseTime = int(time.Since(start).Milliseconds())
 	return models.StatusAlive, nil
 }
 
-func (h *HealthcheckScheduler) performFTPHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+func (h *HealthcheckScheduler) performFTPHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	// Set timeout
 	timeout := time.Duration(service.RequestTimeout) * time.Second
-	
+
 	// Create FTP connection
 	address := fmt.Sprintf("%s:%d", service.Host, service.Port)
-	conn, err := net.DialTimeout("tcp", address, timeout)
+	conn, err := dialCheck(ctx, "tcp", address, timeout)
 	if err != nil {
 		return models.StatusDead, err
 	}
 	defer conn.Close()
-	
+
 	// Set read deadline
 	err = conn.SetReadDeadline(time.Now().Add(timeout))
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	// Read welcome message
 	reader := bufio.NewReader(conn)
 	_, err = reader.ReadString('\n')
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	// Send QUIT command
 	_, err = conn.Write([]byte("QUIT\r\n"))
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	// Read response
 	_, err = reader.ReadString('\n')
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	result.ResponseTime = int(time.Since(start).Milliseconds())
 	return models.StatusAlive, nil
 }
 
-func (h *HealthcheckScheduler) performSSHHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+func (h *HealthcheckScheduler) performSSHHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	// Set timeout
 	timeout := time.Duration(service.RequestTimeout) * time.Second
-	
+
 	// Create SSH client config
 	config := &ssh.ClientConfig{
 		User: "healthcheck",
@@ -669,113 +1010,154 @@ func (h *HealthcheckScheduler) performSSHHealthcheck(service models.Service, res
 			ssh.Password("healthcheck"),
 		},
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:        timeout,
 	}
-	
+
 	// Create SSH connection
 	address := fmt.Sprintf("%s:%d", service.Host, service.Port)
-	conn, err := ssh.Dial("tcp", address, config)
+	netConn, err := dialCheck(ctx, "tcp", address, timeout)
 	if err != nil {
 		return models.StatusDead, err
 	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, address, config)
+	if err != nil {
+		netConn.Close()
+		return models.StatusDead, err
+	}
+	conn := ssh.NewClient(sshConn, chans, reqs)
 	defer conn.Close()
-	
+
 	// Create session
 	session, err := conn.NewSession()
 	if err != nil {
 		return models.StatusDead, err
 	}
 	defer session.Close()
-	
+
 	// Run a simple command
 	output, err := session.Output("echo 'healthcheck'")
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	// Check output
 	if string(output) != "healthcheck\n" {
 		return models.StatusDead, fmt.Errorf("unexpected SSH output: %s", string(output))
 	}
-	
+
 	result.ResponseTime = int(time.Since(start).Milliseconds())
 	return models.StatusAlive, nil
 }
 
-func (h *HealthcheckScheduler) performRedisHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+func (h *HealthcheckScheduler) performRedisHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	// Set timeout
 	timeout := time.Duration(service.RequestTimeout) * time.Second
-	
-	// Create Redis client
+
+	credentials, err := openCredentials(service)
+	if err != nil {
+		return models.StatusDead, err
+	}
+
+	// Reuse the service's Redis client
 	address := fmt.Sprintf("%s:%d", service.Host, service.Port)
-	client := redis.NewClient(&redis.Options{
-		Addr:     address,
-		Password: "", // No password by default
-		DB:       0,  // Default DB
+	pooled, err := h.dbClients.get(service, "redis:"+address+":"+credentials.Username+":"+credentials.Password, func() (io.Closer, error) {
+		return redis.NewClient(&redis.Options{
+			Addr:        address,
+			Username:    credentials.Username, // ACL user, empty for the default user
+			Password:    credentials.Password, // No password by default
+			DB:          0,                    // Default DB
+			PoolSize:    1,
+			IdleTimeout: clientIdleTimeout,
+		}), nil
 	})
-	
+	if err != nil {
+		return models.StatusDead, err
+	}
+	client := pooled.(*redis.Client)
+
 	// Set context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	
+
 	// Ping Redis
-	_, err := client.Ping(ctx).Result()
+	_, err = client.Ping(ctx).Result()
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	result.ResponseTime = int(time.Since(start).Milliseconds())
 	return models.StatusAlive, nil
 }
 
-func (h *HealthcheckScheduler) performMySQLHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+func (h *HealthcheckScheduler) performMySQLHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	// Set timeout
 	timeout := time.Duration(service.RequestTimeout) * time.Second
-	
+
+	credentials, err := openCredentials(service)
+	if err != nil {
+		return models.StatusDead, err
+	}
+	user, password := "healthcheck", "healthcheck"
+	if credentials.Username != "" {
+		user, password = credentials.Username, credentials.Password
+	}
+
 	// Build DSN
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/", "healthcheck", "healthcheck", service.Host, service.Port)
-	
-	// Connect to MySQL
-	db, err := sql.Open("mysql", dsn)
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/", user, password, service.Host, service.Port)
+
+	// Reuse the service's MySQL connection
+	pooled, err := h.dbClients.get(service, "mysql:"+dsn, func() (io.Closer, error) {
+		db, err := sql.Open("mysql", dsn)
+		if err != nil {
+			return nil, err
+		}
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		db.SetConnMaxIdleTime(clientIdleTimeout)
+		return db, nil
+	})
 	if err != nil {
 		return models.StatusDead, err
 	}
-	defer db.Close()
-	
-	// Set connection timeout
-	db.SetMaxOpenConns(1)
-	db.SetMaxIdleConns(1)
-	db.SetConnMaxLifetime(timeout)
-	
+	db := pooled.(*sql.DB)
+
 	// Ping database
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	
+
 	err = db.PingContext(ctx)
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	result.ResponseTime = int(time.Since(start).Milliseconds())
 	return models.StatusAlive, nil
 }
 
-func (h *HealthcheckScheduler) performPostgresHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+func (h *HealthcheckScheduler) performPostgresHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	// Set timeout
 	timeout := time.Duration(service.RequestTimeout) * time.Second
-	
+
 	// Get database connection parameters from environment variables with defaults
 	dbUser := getEnv("DB_USER", "postgres")
 	dbPassword := getEnv("DB_PASSWORD", "password")
 	dbName := getEnv("DB_NAME", "service_weaver")
 	dbSSLMode := getEnv("DB_SSLMODE", "disable")
-	
+
+	// The service's own credentials take precedence over the environment
+	credentials, err := openCredentials(service)
+	if err != nil {
+		return models.StatusDead, err
+	}
+	if credentials.Username != "" {
+		dbUser, dbPassword = credentials.Username, credentials.Password
+	}
+
 	// Use frontend host URL if specified, otherwise use service host
 	host := service.Host
 	if service.FrontendHostURL != "" {
@@ -796,91 +1178,148 @@ func (h *HealthcheckScheduler) performPostgresHealthcheck(service models.Service
 		}
 		host = frontendURL
 	}
-	
+
 	// Build connection string with configurable parameters
 	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s connect_timeout=%d",
-		host, service.Port, dbUser, dbPassword, dbName, dbSSLMode, int(timeout.Seconds()))
-	
-	// Connect to PostgreSQL
-	db, err := sql.Open("postgres", connStr)
+		host, service.Port, quoteConnValue(dbUser), quoteConnValue(dbPassword), dbName, dbSSLMode, int(timeout.Seconds()))
+
+	// Reuse the service's PostgreSQL connection
+	pooled, err := h.dbClients.get(service, "postgres:"+connStr, func() (io.Closer, error) {
+		db, err := sql.Open("postgres", connStr)
+		if err != nil {
+			return nil, err
+		}
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		db.SetConnMaxIdleTime(clientIdleTimeout)
+		return db, nil
+	})
 	if err != nil {
 		return models.StatusDead, fmt.Errorf("failed to connect to PostgreSQL: %v", err)
 	}
-	defer db.Close()
-	
-	// Set connection timeouts
-	db.SetMaxOpenConns(1)
-	db.SetMaxIdleConns(1)
-	db.SetConnMaxLifetime(timeout)
-	
+	db := pooled.(*sql.DB)
+
 	// Ping database
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	
+
 	err = db.PingContext(ctx)
 	if err != nil {
 		return models.StatusDead, fmt.Errorf("PostgreSQL ping failed: %v", err)
 	}
-	
+
 	// Additionally, execute a simple query to verify the connection is fully functional
 	var version string
 	err = db.QueryRowContext(ctx, "SELECT version()").Scan(&version)
 	if err != nil {
 		return models.StatusDegraded, fmt.Errorf("PostgreSQL query failed: %v", err)
 	}
-	
+
 	result.ResponseTime = int(time.Since(start).Milliseconds())
 	return models.StatusAlive, nil
 }
 
-func (h *HealthcheckScheduler) performMongoDBHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+func (h *HealthcheckScheduler) performMongoDBHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	// Set timeout
 	timeout := time.Duration(service.RequestTimeout) * time.Second
-	
+
+	credentials, err := openCredentials(service)
+	if err != nil {
+		return models.StatusDead, err
+	}
+
 	// Build connection string
 	connStr := fmt.Sprintf("mongodb://%s:%d", service.Host, service.Port)
-	
+	clientOptions := options.Client().ApplyURI(connStr).SetMaxPoolSize(1).SetMaxConnIdleTime(clientIdleTimeout)
+	if credentials.Username != "" {
+		clientOptions.SetAuth(options.Credential{Username: credentials.Username, Password: credentials.Password})
+	}
+
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	
-	// Connect to MongoDB
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connStr))
+
+	// Reuse the service's MongoDB client
+	pooled, err := h.dbClients.get(service, "mongodb:"+connStr+":"+credentials.Username+":"+credentials.Password, func() (io.Closer, error) {
+		client, err := mongo.Connect(ctx, clientOptions)
+		if err != nil {
+			return nil, err
+		}
+		return mongoClient{client}, nil
+	})
 	if err != nil {
 		return models.StatusDead, err
 	}
-	defer client.Disconnect(ctx)
-	
+	client := pooled.(mongoClient).client
+
 	// Ping MongoDB
 	err = client.Ping(ctx, nil)
 	if err != nil {
 		return models.StatusDead, err
 	}
-	
+
 	result.ResponseTime = int(time.Since(start).Milliseconds())
 	return models.StatusAlive, nil
 }
 
-func (h *HealthcheckScheduler) performKafkaHealthcheck(service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+// performCompositeHealthcheck rolls up the services of a composite node's child diagram: the node
+// takes the worst of their statuses, or unknown when the diagram is empty
+func (h *HealthcheckScheduler) performCompositeHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+	statuses, err := h.repo.GetDiagramStatuses(ctx, *service.ChildDiagramID)
+	if err != nil {
+		return models.StatusUnknown, fmt.Errorf("failed to load child diagram %d: %v", *service.ChildDiagramID, err)
+	}
+	if len(statuses) == 0 {
+		return models.StatusUnknown, nil
+	}
+
+	status := models.StatusAlive
+	for _, s := range statuses {
+		status = models.WorseStatus(status, s)
+	}
+	return status, nil
+}
+
+func (h *HealthcheckScheduler) performKafkaHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
 	start := time.Now()
-	
+
 	// Set timeout
 	timeout := time.Duration(service.RequestTimeout) * time.Second
-	
+
 	// Create Kafka configuration
 	config := sarama.NewConfig()
 	config.ClientID = service.KafkaClientID
 	if config.ClientID == "" {
 		config.ClientID = "service-weaver-healthcheck"
 	}
-	
+
 	// Set timeouts
 	config.Net.DialTimeout = timeout
 	config.Net.ReadTimeout = timeout
 	config.Net.WriteTimeout = timeout
-	
+
+	// Authenticate with SASL/PLAIN and a client certificate when credentials are set
+	credentials, err := openCredentials(service)
+	if err != nil {
+		return models.StatusDead, err
+	}
+	if credentials.Username != "" {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		config.Net.SASL.User = credentials.Username
+		config.Net.SASL.Password = credentials.Password
+	}
+	if credentials.ClientCert != "" {
+		tlsConfig, err := checkTLSConfig(service, credentials)
+		if err != nil {
+			return models.StatusDead, err
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+
 	// Create Kafka client
 	brokers := []string{fmt.Sprintf("%s:%d", service.Host, service.Port)}
 	client, err := sarama.NewClient(brokers, config)
@@ -888,7 +1327,8 @@ func (h *HealthcheckScheduler) performKafkaHealthcheck(service models.Service, r
 		return models.StatusDead, err
 	}
 	defer client.Close()
-	
+	closeWith(ctx, client)
+
 	// Check if broker is connected
 	if !client.Closed() {
 		// Get controller to verify connection
@@ -896,20 +1336,20 @@ func (h *HealthcheckScheduler) performKafkaHealthcheck(service models.Service, r
 		if err != nil {
 			return models.StatusDead, err
 		}
-		
+
 		// Get broker metadata
 		brokers := client.Brokers()
 		if len(brokers) == 0 {
 			return models.StatusDead, fmt.Errorf("no brokers available")
 		}
-		
+
 		// If topic is specified, check if it exists
 		if service.KafkaTopic != "" {
 			topics, err := client.Topics()
 			if err != nil {
 				return models.StatusDead, err
 			}
-			
+
 			topicExists := false
 			for _, topic := range topics {
 				if topic == service.KafkaTopic {
@@ -917,17 +1357,17 @@ func (h *HealthcheckScheduler) performKafkaHealthcheck(service models.Service, r
 					break
 				}
 			}
-			
+
 			if !topicExists {
 				return models.StatusDegraded, fmt.Errorf("topic '%s' does not exist", service.KafkaTopic)
 			}
-			
+
 			// Get topic metadata
 			partitions, err := client.Partitions(service.KafkaTopic)
 			if err != nil {
 				return models.StatusDegraded, err
 			}
-			
+
 			// Check if topic has at least one partition
 			if len(partitions) == 0 {
 				return models.StatusDegraded, fmt.Errorf("topic '%s' has no partitions", service.KafkaTopic)
@@ -936,7 +1376,7 @@ func (h *HealthcheckScheduler) performKafkaHealthcheck(service models.Service, r
 	} else {
 		return models.StatusDead, fmt.Errorf("kafka client is closed")
 	}
-	
+
 	result.ResponseTime = int(time.Since(start).Milliseconds())
 	return models.StatusAlive, nil
 }
@@ -971,29 +1411,76 @@ func (h *HealthcheckScheduler) determineStatus(statusCode int, service models.Se
 	return models.StatusDead
 }
 
-func (h *HealthcheckScheduler) updateServiceStatus(serviceID int, status models.ServiceStatus) {
-	if err := h.repo.UpdateServiceStatus(serviceID, status); err != nil {
+// updateServiceStatus stores the new status of a service and broadcasts it
+func (h *HealthcheckScheduler) updateServiceStatus(service models.Service, status models.ServiceStatus) {
+	if err := h.repo.UpdateServiceStatus(h.ctx, service.ID, status); err != nil {
 		log.Printf("Error updating service status: %v", err)
 		return
 	}
+	h.queue.setStatus(service.ID, status)
+	h.broadcastStatus(service, status)
+}
 
-	// Broadcast status update
-	update := models.StatusUpdate{
-		ServiceID: serviceID,
+// broadcastStatus sends the status of service; a checking status, or the one it already had, is
+// routine
+func (h *HealthcheckScheduler) broadcastStatus(service models.Service, status models.ServiceStatus) {
+	h.sendStatus(models.StatusUpdate{
+		ServiceID: service.ID,
+		DiagramID: service.DiagramID,
 		Status:    status,
 		Timestamp: time.Now(),
+	}, status == models.StatusChecking || status == service.CurrentStatus)
+}
+
+// sendStatus sends a status update to WebSocket clients and gRPC subscribers
+func (h *HealthcheckScheduler) sendStatus(update models.StatusUpdate, routine bool) {
+	h.Broadcast(models.Envelope{
+		Type:      models.MessageServiceStatus,
+		DiagramID: update.DiagramID,
+		Timestamp: update.Timestamp,
+		Data:      update,
+		Routine:   routine,
+	})
+	h.publish(update)
+}
+
+// broadcastConnectionStatuses sends the connections of a service whose computed status changed
+// since it was last broadcast
+func (h *HealthcheckScheduler) broadcastConnectionStatuses(serviceID int) {
+	connections, err := h.repo.GetServiceConnections(h.ctx, serviceID)
+	if err != nil {
+		log.Printf("Error loading connections of service %d: %v", serviceID, err)
+		return
 	}
 
-	select {
-	case h.broadcast <- update:
-	default:
-		log.Printf("Broadcast channel full, dropping update")
+	h.connectionStatusesMu.Lock()
+	defer h.connectionStatusesMu.Unlock()
+	for _, conn := range connections {
+		if previous, ok := h.connectionStatuses[conn.ID]; ok && previous == conn.Status {
+			continue
+		}
+
+		update := models.ConnectionStatusUpdate{
+			ConnectionID: conn.ID,
+			DiagramID:    conn.DiagramID,
+			SourceID:     conn.SourceID,
+			TargetID:     conn.TargetID,
+			Status:       conn.Status,
+			Timestamp:    time.Now(),
+		}
+		h.Broadcast(models.Envelope{
+			Type:      models.MessageConnectionStatus,
+			DiagramID: conn.DiagramID,
+			Timestamp: update.Timestamp,
+			Data:      update,
+		})
+		h.connectionStatuses[conn.ID] = conn.Status
 	}
 }
 
 // Helper function to get environment variable with default value
 func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
+	if value, exists := config.LookupEnv(key); exists {
 		return value
 	}
 	return defaultValue
@@ -0,0 +1,56 @@
+package monitoring
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"service-weaver/internal/models"
+	"time"
+)
+
+// performHeartbeatHealthcheck judges a service by the pings of its job rather than by polling
+// it: it is alive while runs finish successfully on schedule, degraded while the next run is late
+// but within the grace time, and dead once it is overdue, a run started and did not finish within
+// the grace time, or the last run failed
+func (h *HealthcheckScheduler) performHeartbeatHealthcheck(ctx context.Context, service models.Service) (models.ServiceStatus, error) {
+	hb, err := h.repo.GetHeartbeat(ctx, service.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.StatusUnknown, fmt.Errorf("heartbeat not configured")
+	}
+	if err != nil {
+		return models.StatusDead, err
+	}
+	return heartbeatStatus(hb, time.Now())
+}
+
+func heartbeatStatus(hb *models.Heartbeat, now time.Time) (models.ServiceStatus, error) {
+	period := time.Duration(hb.Period) * time.Second
+	grace := time.Duration(hb.Grace) * time.Second
+
+	running := hb.LastStartAt != nil && (hb.LastPingAt == nil || hb.LastStartAt.After(*hb.LastPingAt))
+	if running && now.Sub(*hb.LastStartAt) > grace {
+		return models.StatusDead, fmt.Errorf("run started %s ago and did not finish", now.Sub(*hb.LastStartAt).Round(time.Second))
+	}
+	if hb.LastPingAt == nil {
+		if running {
+			return models.StatusAlive, nil
+		}
+		return models.StatusUnknown, fmt.Errorf("waiting for the first ping")
+	}
+
+	if hb.LastSignal == models.HeartbeatFail {
+		if hb.LastExitStatus != nil {
+			return models.StatusDead, fmt.Errorf("last run failed with exit status %d", *hb.LastExitStatus)
+		}
+		return models.StatusDead, fmt.Errorf("last run failed")
+	}
+	switch since := now.Sub(*hb.LastPingAt); {
+	case running || since <= period:
+		return models.StatusAlive, nil
+	case since <= period+grace:
+		return models.StatusDegraded, fmt.Errorf("no ping for %s, expected every %s", since.Round(time.Second), period)
+	default:
+		return models.StatusDead, fmt.Errorf("no ping for %s, expected every %s", since.Round(time.Second), period)
+	}
+}
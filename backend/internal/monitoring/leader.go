@@ -0,0 +1,147 @@
+package monitoring
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strconv"
+	"time"
+)
+
+// Leader elects the one instance, among several sharing a database, that runs healthchecks. It is
+// implemented by *repository.SessionLock.
+type Leader interface {
+	// TryAcquire reports whether this instance leads, taking over when no other instance does
+	TryAcquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context) error
+}
+
+var _ Leader = (*repository.SessionLock)(nil)
+
+// Sharder splits the healthchecks among all instances sharing a database, as an alternative to
+// electing one Leader. It is implemented by *repository.Membership.
+type Sharder interface {
+	// Heartbeat keeps this instance a member and returns the IDs of the live members, in an order
+	// all of them agree on, and this instance's own
+	Heartbeat(ctx context.Context) ([]string, string, error)
+	Leave(ctx context.Context) error
+}
+
+var _ Sharder = (*repository.Membership)(nil)
+
+// ShardWith makes this instance check only its share of the services, splitting them with the
+// other members of sharder instead of electing a leader. It must be called before Start.
+func (h *HealthcheckScheduler) ShardWith(sharder Sharder) {
+	h.sharder = sharder
+}
+
+// reshard renews this instance's membership and reports whether its share of the services changed
+func (h *HealthcheckScheduler) reshard() bool {
+	ctx, cancel := context.WithTimeout(h.ctx, loopInterval)
+	defer cancel()
+	members, self, err := h.sharder.Heartbeat(ctx)
+	if err != nil {
+		log.Printf("Error renewing scheduler membership: %v", err)
+		return false
+	}
+	shard := -1
+	for i, member := range members {
+		if member == self {
+			shard = i
+		}
+	}
+	if shard < 0 || (shard == h.shard && len(members) == h.shards) {
+		return false
+	}
+	h.shard, h.shards = shard, len(members)
+	log.Printf("This instance now checks shard %d of %d", shard+1, len(members))
+
+	h.stats.mu.Lock()
+	h.stats.leader = true
+	h.stats.shard, h.stats.shards = shard, len(members)
+	h.stats.mu.Unlock()
+	return true
+}
+
+// owns reports whether this instance checks service; without sharding it checks all services
+func (h *HealthcheckScheduler) owns(service models.Service) bool {
+	if h.sharder == nil {
+		return true
+	}
+	if h.shards == 0 {
+		return false
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(strconv.Itoa(service.ID)))
+	return int(hash.Sum32()%uint32(h.shards)) == h.shard
+}
+
+// Leading reports whether this instance runs the jobs only one of the instances sharing the
+// database should run: it leads the healthchecks or, when sharding, checks the first shard. Without
+// a Leader every instance leads.
+func (h *HealthcheckScheduler) Leading() bool {
+	h.stats.mu.Lock()
+	defer h.stats.mu.Unlock()
+	if h.sharder != nil {
+		return h.stats.leader && h.stats.shard == 0
+	}
+	return h.stats.leader
+}
+
+// lead reports whether this instance schedules healthchecks on this pass. Without a Leader every
+// instance does.
+func (h *HealthcheckScheduler) lead() bool {
+	leading := true
+	if h.leader != nil {
+		ctx, cancel := context.WithTimeout(h.ctx, loopInterval)
+		defer cancel()
+		var err error
+		if leading, err = h.leader.TryAcquire(ctx); err != nil {
+			log.Printf("Error acquiring scheduler leadership: %v", err)
+		}
+	}
+
+	h.stats.mu.Lock()
+	changed := h.stats.leader != leading
+	h.stats.leader = leading
+	h.stats.mu.Unlock()
+	if changed && h.leader != nil {
+		if leading {
+			log.Println("This instance now runs the healthchecks")
+		} else {
+			log.Println("Another instance runs the healthchecks; standing by")
+		}
+	}
+	return leading
+}
+
+// relay broadcasts the status changes other instances stored since the previous pass, so WebSocket
+// and gRPC clients of standby instances, or of any instance when sharding, still receive live
+// updates. Services this instance checks itself are skipped, as their changes were broadcast. Only
+// the statuses are read on every pass; the services they belong to come from the definitions the
+// scheduler reloads when services change.
+func (h *HealthcheckScheduler) relay(own func(models.Service) bool) {
+	statuses, err := h.repo.GetServiceStatuses(h.ctx)
+	if err != nil {
+		log.Printf("Error getting service statuses: %v", err)
+		return
+	}
+
+	seen := make(map[int]models.ServiceStatus, len(h.services))
+	for _, service := range h.services {
+		status, ok := statuses[service.ID]
+		if !ok {
+			continue
+		}
+		seen[service.ID] = status
+		previous, known := h.observed[service.ID]
+		if !known || previous == status || own(service) {
+			continue
+		}
+		h.sendStatus(models.StatusUpdate{ServiceID: service.ID, DiagramID: service.DiagramID, Status: status, Timestamp: time.Now()}, false)
+		h.broadcastConnectionStatuses(service.ID)
+	}
+	h.observed = seen
+}
@@ -0,0 +1,214 @@
+package monitoring
+
+import (
+	"context"
+	"service-weaver/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PoolConfig bounds how many healthchecks run at once. Due checks wait in a queue of QueueSize for
+// one of Workers; TypeLimits caps how many checks of a method, such as ICMP, run at the same time,
+// and HostLimit how many run against the same host. Zero limits leave checks unlimited.
+type PoolConfig struct {
+	Workers    int
+	QueueSize  int
+	TypeLimits map[string]int
+	HostLimit  int
+}
+
+// DefaultPoolConfig is used for zero values of PoolConfig
+var DefaultPoolConfig = PoolConfig{Workers: 50, QueueSize: 2000}
+
+type queuedCheck struct {
+	service  models.Service
+	enqueued time.Time
+}
+
+// checkPool runs healthchecks on a fixed number of workers. A service is queued at most once, so a
+// slow check is not queued again on every pass of the scheduling loop.
+type checkPool struct {
+	config PoolConfig
+	queue  chan queuedCheck
+	run    func(models.Service)
+
+	mu            sync.Mutex
+	pending       map[int]bool             // services queued, deferred or running
+	running       map[string]int           // running checks by limit key, see limitKeys
+	deferred      map[string][]queuedCheck // checks waiting for a limit key to drop below its limit
+	deferredCount int
+	waitTotal     time.Duration
+	waitCount     int64
+	queueFull     int64
+}
+
+func newCheckPool(config PoolConfig, run func(models.Service)) *checkPool {
+	if config.Workers <= 0 {
+		config.Workers = DefaultPoolConfig.Workers
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultPoolConfig.QueueSize
+	}
+	return &checkPool{
+		config:   config,
+		queue:    make(chan queuedCheck, config.QueueSize),
+		run:      run,
+		pending:  make(map[int]bool),
+		running:  make(map[string]int),
+		deferred: make(map[string][]queuedCheck),
+	}
+}
+
+func (p *checkPool) start(ctx context.Context) {
+	for i := 0; i < p.config.Workers; i++ {
+		go p.work(ctx)
+	}
+}
+
+// enqueue queues a due check unless the service is already queued or running. It reports false
+// when the queue is full; the check is then picked up again by a later pass.
+func (p *checkPool) enqueue(service models.Service) bool {
+	p.mu.Lock()
+	if p.pending[service.ID] {
+		p.mu.Unlock()
+		return true
+	}
+	p.pending[service.ID] = true
+	p.mu.Unlock()
+
+	select {
+	case p.queue <- queuedCheck{service: service, enqueued: time.Now()}:
+		return true
+	default:
+		p.mu.Lock()
+		delete(p.pending, service.ID)
+		p.queueFull++
+		p.mu.Unlock()
+		return false
+	}
+}
+
+func (p *checkPool) work(ctx context.Context) {
+	for {
+		select {
+		case job := <-p.queue:
+			p.process(job)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// process runs a check if it is below the limits of its type and host and defers it otherwise. A
+// worker finishing a check runs the next deferred check it made room for, so deferred checks never
+// wait for a worker.
+func (p *checkPool) process(job queuedCheck) {
+	p.mu.Lock()
+	if key := p.blockedOn(job.service); key != "" {
+		p.deferred[key] = append(p.deferred[key], job)
+		p.deferredCount++
+		p.mu.Unlock()
+		return
+	}
+	for {
+		p.acquire(job.service)
+		p.waitTotal += time.Since(job.enqueued)
+		p.waitCount++
+		p.mu.Unlock()
+
+		p.run(job.service)
+
+		p.mu.Lock()
+		delete(p.pending, job.service.ID)
+		p.release(job.service)
+		next, ok := p.nextDeferred(job.service)
+		if !ok {
+			p.mu.Unlock()
+			return
+		}
+		job = next
+	}
+}
+
+// limitKeys returns the keys a check counts against: its type and, unless it has none, its host
+func limitKeys(service models.Service) []string {
+	keys := []string{"type:" + checkType(service)}
+	if service.Host != "" && service.ChildDiagramID == nil {
+		keys = append(keys, "host:"+strings.ToLower(service.Host))
+	}
+	return keys
+}
+
+func (p *checkPool) limit(key string) int {
+	if kind, ok := strings.CutPrefix(key, "type:"); ok {
+		return p.config.TypeLimits[kind]
+	}
+	return p.config.HostLimit
+}
+
+// blockedOn returns the key whose limit keeps a check from running, or "" if it may run
+func (p *checkPool) blockedOn(service models.Service) string {
+	for _, key := range limitKeys(service) {
+		if limit := p.limit(key); limit > 0 && p.running[key] >= limit {
+			return key
+		}
+	}
+	return ""
+}
+
+func (p *checkPool) acquire(service models.Service) {
+	for _, key := range limitKeys(service) {
+		p.running[key]++
+	}
+}
+
+func (p *checkPool) release(service models.Service) {
+	for _, key := range limitKeys(service) {
+		if p.running[key]--; p.running[key] <= 0 {
+			delete(p.running, key)
+		}
+	}
+}
+
+// nextDeferred takes the first deferred check that may run now that finished no longer does. Checks
+// still blocked by another key move on to wait for that one.
+func (p *checkPool) nextDeferred(finished models.Service) (queuedCheck, bool) {
+	for _, key := range limitKeys(finished) {
+		for len(p.deferred[key]) > 0 {
+			job := p.deferred[key][0]
+			p.deferred[key] = p.deferred[key][1:]
+			if len(p.deferred[key]) == 0 {
+				delete(p.deferred, key)
+			}
+			if other := p.blockedOn(job.service); other != "" {
+				p.deferred[other] = append(p.deferred[other], job)
+				continue
+			}
+			p.deferredCount--
+			return job, true
+		}
+	}
+	return queuedCheck{}, false
+}
+
+func (p *checkPool) stats() QueueStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return QueueStats{
+		Workers:     p.config.Workers,
+		Capacity:    p.config.QueueSize,
+		Depth:       len(p.queue) + p.deferredCount,
+		WaitSeconds: p.waitTotal.Seconds(),
+		WaitCount:   p.waitCount,
+		Full:        p.queueFull,
+	}
+}
+
+// checkType is the method a service is checked with; composite nodes aggregate their child diagram
+func checkType(service models.Service) string {
+	if service.ChildDiagramID != nil {
+		return "COMPOSITE"
+	}
+	return service.HealthcheckMethod
+}
@@ -0,0 +1,183 @@
+package monitoring
+
+import (
+	"container/heap"
+	"service-weaver/internal/models"
+	"sync"
+	"time"
+)
+
+// dueEntry is a service waiting for its next check
+type dueEntry struct {
+	serviceID int
+	due       time.Time
+	index     int
+}
+
+// dueHeap orders entries by due time, earliest first
+type dueHeap []*dueEntry
+
+func (q dueHeap) Len() int           { return len(q) }
+func (q dueHeap) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+func (q dueHeap) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *dueHeap) Push(x interface{}) {
+	entry := x.(*dueEntry)
+	entry.index = len(*q)
+	*q = append(*q, entry)
+}
+
+func (q *dueHeap) Pop() interface{} {
+	old := *q
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	entry.index = -1
+	return entry
+}
+
+// serviceQueue keeps the definitions of the checkable services in memory, ordered by when each is
+// next due, so the scheduler neither scans nor queries all services to find the due ones. A
+// service leaves the queue while its check runs and returns when the check is done.
+type serviceQueue struct {
+	mu       sync.Mutex
+	services map[int]models.Service
+	entries  map[int]*dueEntry
+	inFlight map[int]bool
+	due      dueHeap
+}
+
+func newServiceQueue() *serviceQueue {
+	return &serviceQueue{
+		services: make(map[int]models.Service),
+		entries:  make(map[int]*dueEntry),
+		inFlight: make(map[int]bool),
+	}
+}
+
+// pollingInterval is how often a service is checked. Services without an interval are checked on
+// every pass of the scheduling loop, as before intervals below it were supported.
+func pollingInterval(service models.Service) time.Duration {
+	if service.PollingInterval <= 0 {
+		return loopInterval
+	}
+	return time.Duration(service.PollingInterval) * time.Second
+}
+
+// load replaces the service definitions with services, as read from the database. Services keep
+// their place in the queue unless their polling interval changed.
+func (q *serviceQueue) load(services []models.Service, checkable func(models.Service) bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	loaded := make(map[int]models.Service, len(services))
+	for _, service := range services {
+		if checkable(service) {
+			loaded[service.ID] = service
+		}
+	}
+	for id, entry := range q.entries {
+		previous := q.services[id]
+		if service, ok := loaded[id]; !ok || pollingInterval(service) != pollingInterval(previous) {
+			heap.Remove(&q.due, entry.index)
+			delete(q.entries, id)
+		}
+	}
+	q.services = loaded
+	for id, service := range loaded {
+		if q.entries[id] != nil || q.inFlight[id] {
+			continue
+		}
+		due := time.Now()
+		if service.LastChecked != nil {
+			due = service.LastChecked.Add(pollingInterval(service))
+		}
+		q.push(id, due)
+	}
+}
+
+func (q *serviceQueue) push(id int, due time.Time) {
+	entry := &dueEntry{serviceID: id, due: due}
+	heap.Push(&q.due, entry)
+	q.entries[id] = entry
+}
+
+// pop removes and returns the services due at now; they are in flight until done or retry
+func (q *serviceQueue) pop(now time.Time) []models.Service {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var due []models.Service
+	for len(q.due) > 0 && !q.due[0].due.After(now) {
+		entry := heap.Pop(&q.due).(*dueEntry)
+		delete(q.entries, entry.serviceID)
+		q.inFlight[entry.serviceID] = true
+		due = append(due, q.services[entry.serviceID])
+	}
+	return due
+}
+
+// next returns when the earliest service is due
+func (q *serviceQueue) next() (time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.due) == 0 {
+		return time.Time{}, false
+	}
+	return q.due[0].due, true
+}
+
+// done puts a checked service back, due again one polling interval from now, unless it was
+// deleted meanwhile
+func (q *serviceQueue) done(id int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.inFlight, id)
+	if service, ok := q.services[id]; ok && q.entries[id] == nil {
+		q.push(id, time.Now().Add(pollingInterval(service)))
+	}
+}
+
+// retry puts a service that could not be queued for checking back, due again at due
+func (q *serviceQueue) retry(id int, due time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.inFlight, id)
+	if _, ok := q.services[id]; ok && q.entries[id] == nil {
+		q.push(id, due)
+	}
+}
+
+// expedite makes a queued service due at now, unless its check is already running
+func (q *serviceQueue) expedite(id int, now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if entry := q.entries[id]; entry != nil && entry.due.After(now) {
+		entry.due = now
+		heap.Fix(&q.due, entry.index)
+	}
+}
+
+// setStatus records the status a check stored, so the next check of the service sees the
+// transition from it without reloading the service
+func (q *serviceQueue) setStatus(id int, status models.ServiceStatus) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if service, ok := q.services[id]; ok {
+		now := time.Now()
+		service.CurrentStatus = status
+		service.LastChecked = &now
+		q.services[id] = service
+	}
+}
+
+// size returns the number of services scheduled
+func (q *serviceQueue) size() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.services)
+}
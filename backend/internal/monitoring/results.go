@@ -0,0 +1,127 @@
+package monitoring
+
+import (
+	"context"
+	"log"
+	"service-weaver/internal/models"
+	"service-weaver/internal/stream"
+	"sync"
+	"time"
+)
+
+// BatchConfig controls how healthcheck results are written. Results are buffered and inserted
+// together once Size of them are waiting, and at least every Interval.
+type BatchConfig struct {
+	Size     int
+	Interval time.Duration
+}
+
+// DefaultBatchConfig is used for zero values of BatchConfig
+var DefaultBatchConfig = BatchConfig{Size: 500, Interval: time.Second}
+
+// ResultExporter receives every healthcheck result and every recorded status transition, to pass
+// them on to other systems. Its methods are called from the workers running checks and must not
+// block. It is implemented by *stream.Exporter.
+type ResultExporter interface {
+	ExportResult(service models.Service, result models.HealthcheckResult)
+	ExportTransition(service models.Service, from, to models.ServiceStatus, at time.Time)
+}
+
+var _ ResultExporter = (*stream.Exporter)(nil)
+
+// ExportTo passes results and transitions on to exporter; it must be called before Start
+func (h *HealthcheckScheduler) ExportTo(exporter ResultExporter) {
+	h.exporter = exporter
+}
+
+// resultWriter buffers healthcheck results and writes them in batches, as a row per check is the
+// bulk of the database load with many services. Once stopped, it no longer accepts results and
+// callers write them one by one instead.
+type resultWriter struct {
+	repo   Store
+	config BatchConfig
+	full   chan struct{}
+	done   chan struct{}
+
+	mu      sync.Mutex
+	buffer  []models.HealthcheckResult
+	stopped bool
+}
+
+func newResultWriter(repo Store, config BatchConfig) *resultWriter {
+	if config.Size <= 0 {
+		config.Size = DefaultBatchConfig.Size
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultBatchConfig.Interval
+	}
+	return &resultWriter{
+		repo:   repo,
+		config: config,
+		full:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// add buffers a result. It reports false once the writer stopped.
+func (w *resultWriter) add(result models.HealthcheckResult) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return false
+	}
+	w.buffer = append(w.buffer, result)
+	if len(w.buffer) >= w.config.Size {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// run flushes the buffer every Interval, or sooner when it fills up, until ctx is done. The results
+// still buffered then are written before run returns.
+func (w *resultWriter) run(ctx context.Context) {
+	defer close(w.done)
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.flush(ctx)
+		case <-w.full:
+			w.flush(ctx)
+		case <-ctx.Done():
+			w.mu.Lock()
+			w.stopped = true
+			w.mu.Unlock()
+
+			final, cancel := context.WithTimeout(context.Background(), loopInterval)
+			w.flush(final)
+			cancel()
+			return
+		}
+	}
+}
+
+// wait blocks until run returned
+func (w *resultWriter) wait() {
+	<-w.done
+}
+
+func (w *resultWriter) flush(ctx context.Context) {
+	w.mu.Lock()
+	batch := w.buffer
+	w.buffer = nil
+	w.mu.Unlock()
+
+	for len(batch) > 0 {
+		n := min(len(batch), w.config.Size)
+		if err := w.repo.CreateHealthcheckResults(ctx, batch[:n]); err != nil {
+			log.Printf("Error saving %d healthcheck results: %v", n, err)
+		}
+		batch = batch[n:]
+	}
+}
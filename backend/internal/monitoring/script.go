@@ -0,0 +1,177 @@
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"service-weaver/internal/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScriptConfig sandboxes the check scripts run by services with the SCRIPT healthcheck method
+type ScriptConfig struct {
+	Interpreters []string // Interpreters scripts may use, looked up in PATH; none disables script checks
+	User         string   // Account scripts run as when the backend runs as root
+	MemoryLimit  int      // Megabytes of address space a script may use
+}
+
+const (
+	// maxScriptOutput caps what is kept of the standard output and error of a script
+	maxScriptOutput = 4 << 10
+	// maxScriptFileSize caps the files a script writes, in blocks of 512 bytes
+	maxScriptFileSize = 20480
+	// scriptSandboxFailed is the exit status of the shell when the resource limits could not be applied
+	scriptSandboxFailed = 125
+)
+
+// scriptStatuses maps the exit statuses of check scripts, those of Nagios plugins
+var scriptStatuses = map[int]models.ServiceStatus{
+	0: models.StatusAlive,
+	1: models.StatusDegraded,
+	2: models.StatusDead,
+	3: models.StatusUnknown,
+}
+
+// RunScriptsWith enables check scripts, sandboxed by config. It must be called before Start.
+func (h *HealthcheckScheduler) RunScriptsWith(config ScriptConfig) {
+	h.scripts = &config
+}
+
+// cappedBuffer keeps the first max bytes written to it and discards the rest, so a chatty script
+// neither blocks nor grows the backend's memory
+type cappedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// firstLine returns the first non-empty line of a script's output, its status message by convention
+func firstLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// performScriptHealthcheck runs the check script of the service with its arguments, as an
+// unprivileged user in a process group of its own, killed at the service's request timeout and
+// limited in CPU time, memory and file size. The exit status gives the status and the standard
+// output is kept with the result, its first line reported as the error of failed checks.
+func (h *HealthcheckScheduler) performScriptHealthcheck(ctx context.Context, service models.Service, result *models.HealthcheckResult) (models.ServiceStatus, error) {
+	if h.scripts == nil || len(h.scripts.Interpreters) == 0 {
+		return models.StatusUnknown, fmt.Errorf("script checks are disabled")
+	}
+	ss, err := h.repo.GetServiceScript(ctx, service.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.StatusUnknown, fmt.Errorf("no check script set")
+	}
+	if err != nil {
+		return models.StatusDead, err
+	}
+	if !slices.Contains(h.scripts.Interpreters, ss.Script.Interpreter) {
+		return models.StatusUnknown, fmt.Errorf("interpreter %s is not allowed", ss.Script.Interpreter)
+	}
+	interpreter, err := exec.LookPath(ss.Script.Interpreter)
+	if err != nil {
+		return models.StatusUnknown, fmt.Errorf("interpreter %s not found", ss.Script.Interpreter)
+	}
+
+	// The script is written to a directory of its own, readable by the user it runs as
+	dir, err := os.MkdirTemp("", "sw-script-")
+	if err != nil {
+		return models.StatusUnknown, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "check")
+	if err := os.Chmod(dir, 0o755); err != nil {
+		return models.StatusUnknown, err
+	}
+	if err := os.WriteFile(path, []byte(ss.Script.Content), 0o644); err != nil {
+		return models.StatusUnknown, err
+	}
+
+	timeout := time.Duration(service.RequestTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The shell applies the resource limits, then replaces itself with the interpreter
+	limits := fmt.Sprintf(`ulimit -t %d && ulimit -v %d && ulimit -f %d || exit %d; exec "$@"`,
+		int(timeout.Seconds())+1, h.scripts.MemoryLimit*1024, maxScriptFileSize, scriptSandboxFailed)
+	args := append([]string{"-c", limits, "sh", interpreter, path}, ss.Args...)
+	cmd := exec.CommandContext(runCtx, "/bin/sh", args...)
+	cmd.Dir = dir
+	cmd.Env = []string{
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		"HOME=" + dir,
+		"LANG=C.UTF-8",
+		"SW_SERVICE_ID=" + strconv.Itoa(service.ID),
+		"SW_SERVICE_NAME=" + service.Name,
+		"SW_HOST=" + service.Host,
+		"SW_PORT=" + strconv.Itoa(service.Port),
+		"SW_URL=" + service.HealthcheckURL,
+		"SW_TIMEOUT=" + strconv.Itoa(int(timeout.Seconds())),
+	}
+	stdout := &cappedBuffer{max: maxScriptOutput}
+	stderr := &cappedBuffer{max: maxScriptOutput}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := sandbox(cmd, h.scripts); err != nil {
+		return models.StatusUnknown, err
+	}
+
+	start := time.Now()
+	err = cmd.Run()
+	result.ResponseTime = int(time.Since(start).Milliseconds())
+	result.Output = strings.ToValidUTF8(strings.ReplaceAll(stdout.String(), "\x00", ""), "")
+
+	if runCtx.Err() == context.DeadlineExceeded {
+		return models.StatusDead, fmt.Errorf("script timed out after %s", timeout)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		if err != nil {
+			return models.StatusDead, fmt.Errorf("failed to run script: %w", err)
+		}
+		return models.StatusAlive, nil
+	}
+
+	code := exitErr.ExitCode()
+	message := firstLine(stdout.String())
+	if message == "" {
+		message = firstLine(stderr.String())
+	}
+	switch {
+	case code == scriptSandboxFailed:
+		return models.StatusUnknown, fmt.Errorf("could not apply the resource limits of the script: %s", firstLine(stderr.String()))
+	case code < 0:
+		return models.StatusDead, fmt.Errorf("script was killed: %v", exitErr)
+	}
+
+	// Other exit statuses are failures of the script itself
+	status, ok := scriptStatuses[code]
+	if !ok {
+		status = models.StatusDead
+	}
+	if message == "" || !ok {
+		message = strings.TrimSuffix(fmt.Sprintf("script exited with status %d: %s", code, message), ": ")
+	}
+	return status, errors.New(message)
+}
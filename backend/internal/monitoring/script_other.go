@@ -0,0 +1,13 @@
+//go:build !unix
+
+package monitoring
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// sandbox refuses to run check scripts where they cannot be confined to an unprivileged user
+func sandbox(cmd *exec.Cmd, config *ScriptConfig) error {
+	return fmt.Errorf("script checks need a Unix host")
+}
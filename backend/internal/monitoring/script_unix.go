@@ -0,0 +1,43 @@
+//go:build unix
+
+package monitoring
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// sandbox runs a check script in a process group of its own, killed as a whole when the check
+// times out, and as the configured user without supplementary groups when the backend runs as root
+func sandbox(cmd *exec.Cmd, config *ScriptConfig) error {
+	attr := &syscall.SysProcAttr{Setpgid: true}
+	if os.Geteuid() == 0 {
+		account, err := user.Lookup(config.User)
+		if err != nil {
+			return fmt.Errorf("script user %s: %w", config.User, err)
+		}
+		uid, err := strconv.ParseUint(account.Uid, 10, 32)
+		if err != nil {
+			return fmt.Errorf("script user %s: %w", config.User, err)
+		}
+		gid, err := strconv.ParseUint(account.Gid, 10, 32)
+		if err != nil {
+			return fmt.Errorf("script user %s: %w", config.User, err)
+		}
+		if uid == 0 {
+			return fmt.Errorf("scripts must not run as root")
+		}
+		attr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: []uint32{}}
+	}
+	cmd.SysProcAttr = attr
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+	return nil
+}
@@ -0,0 +1,260 @@
+package monitoring
+
+import (
+	"service-weaver/internal/models"
+	"sync"
+	"time"
+)
+
+// SchedulerStats is a point-in-time snapshot of the scheduler's internal counters
+type SchedulerStats struct {
+	ChecksExecuted   map[models.ServiceStatus]int64 `json:"checks_executed"`
+	ChecksInFlight   int64                          `json:"checks_in_flight"`
+	ChecksPerSecond  float64                        `json:"checks_per_second"` // over the last minute
+	Methods          map[string]MethodStats         `json:"methods"`
+	BroadcastDropped int64                          `json:"broadcast_dropped"` // status updates gRPC subscribers fell too far behind for
+	ClientsDropped   int64                          `json:"clients_dropped"`   // WebSocket clients disconnected for falling behind
+	ConnectedClients int                            `json:"connected_clients"`
+	Leader           bool                           `json:"leader"` // whether this instance runs the healthchecks
+	Shard            int                            `json:"shard"`  // this instance's share, counted from 0, when sharding
+	Shards           int                            `json:"shards"` // instances sharing the healthchecks, 0 unless sharding
+	Queue            QueueStats                     `json:"queue"`
+	DatabaseClients  int                            `json:"database_clients"` // kept open for database checks
+	Loop             LoopStatus                     `json:"loop"`             // of the loop that starts healthchecks
+}
+
+// MethodStats counts the checks of one healthcheck method and how long they took
+type MethodStats struct {
+	Checks          int64            `json:"checks"`
+	Failures        int64            `json:"failures"` // checks that found the service dead
+	DurationSeconds float64          `json:"duration_seconds"`
+	Buckets         []DurationBucket `json:"buckets"` // cumulative, as in a Prometheus histogram
+}
+
+// DurationBucket counts the checks that took at most UpperBound seconds
+type DurationBucket struct {
+	UpperBound float64 `json:"le"`
+	Count      int64   `json:"count"`
+}
+
+// durationBuckets are the upper bounds, in seconds, of the check duration histograms
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// rateWindow is how many one-second slots the checks per second are averaged over
+const rateWindow = 60
+
+// QueueStats describes the worker pool running healthchecks and the checks waiting for it
+type QueueStats struct {
+	Scheduled   int     `json:"scheduled"` // services the scheduler checks
+	Workers     int     `json:"workers"`
+	Capacity    int     `json:"capacity"`
+	Depth       int     `json:"depth"`        // checks queued or waiting for their type's limit
+	WaitSeconds float64 `json:"wait_seconds"` // total time started checks spent queued
+	WaitCount   int64   `json:"wait_count"`   // checks started from the queue
+	Full        int64   `json:"full"`         // checks skipped because the queue was full
+}
+
+// LastResult is the most recent healthcheck outcome observed for a service
+type LastResult struct {
+	Status       models.ServiceStatus
+	ResponseTime int
+	CheckedAt    time.Time
+}
+
+// loopInterval is how often the scheduling loop and the WebSocket hub run, at the least
+const loopInterval = 5 * time.Second
+
+// resyncInterval is how often the scheduler reloads all service definitions, to pick up changes
+// it was not told about
+const resyncInterval = time.Minute
+
+// StallTimeout is how long a loop may go without running before it is reported as stalled
+const StallTimeout = 6 * loopInterval
+
+// LoopStatus reports whether one of the scheduler's background loops is still running
+type LoopStatus struct {
+	Running       bool      `json:"running"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+}
+
+// schedulerStats accumulates counters updated from concurrently running checks
+type schedulerStats struct {
+	mu               sync.Mutex
+	checksExecuted   map[models.ServiceStatus]int64
+	checksInFlight   int64
+	broadcastDropped int64
+	clientsDropped   int64
+	lastResults      map[int]LastResult
+	methods          map[string]*methodStats
+	perSecond        [rateWindow + 1]int64 // checks finished per second, a slot per second in turn
+	perSecondAt      [rateWindow + 1]int64 // the Unix second each slot counts
+	schedulerBeat    time.Time
+	hubBeat          time.Time
+	leader           bool
+	shard, shards    int
+}
+
+func newSchedulerStats() *schedulerStats {
+	return &schedulerStats{
+		checksExecuted: make(map[models.ServiceStatus]int64),
+		lastResults:    make(map[int]LastResult),
+		methods:        make(map[string]*methodStats),
+	}
+}
+
+type methodStats struct {
+	checks   int64
+	failures int64
+	duration time.Duration
+	buckets  []int64 // not cumulative; one more than durationBuckets for +Inf
+}
+
+func (s *schedulerStats) checkStarted() {
+	s.mu.Lock()
+	s.checksInFlight++
+	s.mu.Unlock()
+}
+
+func (s *schedulerStats) checkFinished(result *models.HealthcheckResult, method string, duration time.Duration) {
+	s.mu.Lock()
+	s.checksInFlight--
+	s.checksExecuted[result.Status]++
+
+	m := s.methods[method]
+	if m == nil {
+		m = &methodStats{buckets: make([]int64, len(durationBuckets)+1)}
+		s.methods[method] = m
+	}
+	m.checks++
+	if result.Status == models.StatusDead {
+		m.failures++
+	}
+	m.duration += duration
+	bucket := len(durationBuckets)
+	for i, bound := range durationBuckets {
+		if duration.Seconds() <= bound {
+			bucket = i
+			break
+		}
+	}
+	m.buckets[bucket]++
+
+	now := time.Now().Unix()
+	slot := now % int64(len(s.perSecond))
+	if s.perSecondAt[slot] != now {
+		s.perSecondAt[slot], s.perSecond[slot] = now, 0
+	}
+	s.perSecond[slot]++
+
+	s.lastResults[result.ServiceID] = LastResult{
+		Status:       result.Status,
+		ResponseTime: result.ResponseTime,
+		CheckedAt:    result.CheckedAt,
+	}
+	s.mu.Unlock()
+}
+
+// checkAbandoned accounts for a check that ended without a result
+func (s *schedulerStats) checkAbandoned() {
+	s.mu.Lock()
+	s.checksInFlight--
+	s.mu.Unlock()
+}
+
+// beat records that a loop ran; beat is schedulerBeat or hubBeat
+func (s *schedulerStats) beat(beat *time.Time) {
+	s.mu.Lock()
+	*beat = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *schedulerStats) broadcastDroppedInc() {
+	s.mu.Lock()
+	s.broadcastDropped++
+	s.mu.Unlock()
+}
+
+func (s *schedulerStats) clientDroppedInc() {
+	s.mu.Lock()
+	s.clientsDropped++
+	s.mu.Unlock()
+}
+
+// Stats returns a snapshot of the scheduler counters
+func (h *HealthcheckScheduler) Stats() SchedulerStats {
+	h.stats.mu.Lock()
+	snapshot := SchedulerStats{
+		ChecksExecuted:   make(map[models.ServiceStatus]int64, len(h.stats.checksExecuted)),
+		ChecksInFlight:   h.stats.checksInFlight,
+		BroadcastDropped: h.stats.broadcastDropped,
+		ClientsDropped:   h.stats.clientsDropped,
+		Leader:           h.stats.leader,
+		Shard:            h.stats.shard,
+		Shards:           h.stats.shards,
+		Loop:             h.loopStatus(h.stats.schedulerBeat),
+	}
+	for status, n := range h.stats.checksExecuted {
+		snapshot.ChecksExecuted[status] = n
+	}
+	snapshot.Methods = make(map[string]MethodStats, len(h.stats.methods))
+	for method, m := range h.stats.methods {
+		stats := MethodStats{Checks: m.checks, Failures: m.failures, DurationSeconds: m.duration.Seconds()}
+		var cumulative int64
+		for i, bound := range durationBuckets {
+			cumulative += m.buckets[i]
+			stats.Buckets = append(stats.Buckets, DurationBucket{UpperBound: bound, Count: cumulative})
+		}
+		snapshot.Methods[method] = stats
+	}
+	// The current second is still counting, so the rate covers the rateWindow seconds before it
+	now := time.Now().Unix()
+	var recent int64
+	for slot, at := range h.stats.perSecondAt {
+		if at < now && at >= now-rateWindow {
+			recent += h.stats.perSecond[slot]
+		}
+	}
+	snapshot.ChecksPerSecond = float64(recent) / rateWindow
+	h.stats.mu.Unlock()
+
+	h.clientsMu.RLock()
+	snapshot.ConnectedClients = len(h.clients)
+	h.clientsMu.RUnlock()
+
+	snapshot.Queue = h.pool.stats()
+	snapshot.Queue.Scheduled = h.queue.size()
+	snapshot.DatabaseClients = h.dbClients.size()
+
+	return snapshot
+}
+
+// LastResults returns the most recent result per service ID observed since startup
+func (h *HealthcheckScheduler) LastResults() map[int]LastResult {
+	h.stats.mu.Lock()
+	defer h.stats.mu.Unlock()
+
+	results := make(map[int]LastResult, len(h.stats.lastResults))
+	for id, r := range h.stats.lastResults {
+		results[id] = r
+	}
+	return results
+}
+
+// SchedulerStatus reports whether the loop that starts healthchecks is running
+func (h *HealthcheckScheduler) SchedulerStatus() LoopStatus {
+	h.stats.mu.Lock()
+	defer h.stats.mu.Unlock()
+	return h.loopStatus(h.stats.schedulerBeat)
+}
+
+// HubStatus reports whether the loop that broadcasts updates to WebSocket clients is running
+func (h *HealthcheckScheduler) HubStatus() LoopStatus {
+	h.stats.mu.Lock()
+	defer h.stats.mu.Unlock()
+	return h.loopStatus(h.stats.hubBeat)
+}
+
+func (h *HealthcheckScheduler) loopStatus(beat time.Time) LoopStatus {
+	running := !beat.IsZero() && time.Since(beat) < StallTimeout && h.ctx.Err() == nil
+	return LoopStatus{Running: running, LastHeartbeat: beat}
+}
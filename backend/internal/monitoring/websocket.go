@@ -0,0 +1,352 @@
+package monitoring
+
+import (
+	"log"
+	"service-weaver/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsWriteWait is how long a write to a WebSocket client may take
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is how long a client may go without answering a ping before it is dropped
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod is how often clients are pinged, leaving them time to answer within wsPongWait
+	wsPingPeriod = wsPongWait * 9 / 10
+	// wsSendBuffer is how many messages may wait for a client before it is dropped as unresponsive
+	wsSendBuffer = 256
+	// wsBatchSize is how many queued messages are written to a client at once, as a batch
+	wsBatchSize = 100
+	// wsMaxMessageSize limits the messages clients send
+	wsMaxMessageSize = 64 << 10
+)
+
+// wsClient is a connected WebSocket client, the messages waiting to be written to it and the
+// diagrams it subscribed to
+type wsClient struct {
+	conn     *websocket.Conn
+	send     chan models.Envelope
+	done     chan struct{} // closed when the client is removed
+	diagrams map[int]bool
+	implicit bool // subscribed to the diagrams it may see, until it chooses some
+	filter   wsFilter
+}
+
+// wsFilter narrows down the updates of the diagrams a client subscribed to
+type wsFilter struct {
+	services    map[int]bool // nil for every service
+	changesOnly bool         // leave out routine messages
+}
+
+// allows reports whether a message about services passes the filter. Messages about no service
+// in particular, such as the deletion of a connection, always do.
+func (f wsFilter) allows(message models.Envelope, services []int) bool {
+	if f.changesOnly && message.Routine {
+		return false
+	}
+	if f.services == nil || len(services) == 0 {
+		return true
+	}
+	for _, id := range services {
+		if f.services[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// AddClient registers a WebSocket client allowed to see diagrams, or every diagram when diagrams
+// is nil. It receives the updates of all of them until it subscribes to some. Messages are written
+// to the client, and it is pinged, from a goroutine of its own; the caller keeps reading from conn
+// until it fails, which happens once the client misses a ping, and then calls RemoveClient.
+func (h *HealthcheckScheduler) AddClient(conn *websocket.Conn, diagrams []int) {
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	client := &wsClient{
+		conn:     conn,
+		send:     make(chan models.Envelope, wsSendBuffer),
+		done:     make(chan struct{}),
+		diagrams: make(map[int]bool),
+		implicit: true,
+	}
+	h.clientsMu.Lock()
+	h.clients[conn] = client
+	if diagrams == nil {
+		h.unfiltered[conn] = true
+	} else {
+		h.subscribe(conn, diagrams)
+	}
+	h.clientsMu.Unlock()
+
+	go h.writeClient(client)
+}
+
+// RemoveClient unregisters a WebSocket client and closes its connection
+func (h *HealthcheckScheduler) RemoveClient(conn *websocket.Conn) {
+	h.clientsMu.Lock()
+	h.removeClient(conn)
+	h.clientsMu.Unlock()
+	conn.Close()
+}
+
+// writeClient writes the messages queued for client and pings it until it is removed. Messages
+// queued while the previous write was in progress are written together, as one batch. A client
+// that cannot be written to within wsWriteWait is dropped.
+func (h *HealthcheckScheduler) writeClient(client *wsClient) {
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	for {
+		var err error
+		select {
+		case message := <-client.send:
+			if batch := drain(client.send, message); len(batch) > 1 {
+				message = models.Envelope{Type: models.MessageBatch, Timestamp: time.Now(), Data: batch}
+			} else {
+				message = batch[0]
+			}
+			client.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			err = client.conn.WriteJSON(message)
+		case <-ping.C:
+			err = client.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+		case <-client.done:
+			return
+		}
+		if err != nil {
+			log.Printf("Error writing to WebSocket client, disconnecting: %v", err)
+			h.RemoveClient(client.conn)
+			return
+		}
+	}
+}
+
+// SubscribeClient adds diagrams to those a client receives updates for; from then on it receives
+// no others. The caller checks the client may see them.
+func (h *HealthcheckScheduler) SubscribeClient(conn *websocket.Conn, diagramIDs []int) {
+	h.clientsMu.Lock()
+	defer h.clientsMu.Unlock()
+	client := h.clients[conn]
+	if client == nil {
+		return
+	}
+	h.choose(conn, client)
+	h.subscribe(conn, diagramIDs)
+}
+
+// UnsubscribeClient stops sending a client the updates of diagrams. A client still receiving the
+// updates of every diagram receives none afterwards but those it subscribes to.
+func (h *HealthcheckScheduler) UnsubscribeClient(conn *websocket.Conn, diagramIDs []int) {
+	h.clientsMu.Lock()
+	defer h.clientsMu.Unlock()
+	client := h.clients[conn]
+	if client == nil {
+		return
+	}
+	client.implicit = false
+	delete(h.unfiltered, conn)
+	for _, id := range diagramIDs {
+		delete(client.diagrams, id)
+		h.unsubscribe(conn, id)
+	}
+}
+
+// RestrictClient limits a client to the diagrams it may see now, or every diagram when diagrams is
+// nil, as they change with its memberships and the visibility of diagrams: it stops receiving the
+// updates of the others, and a client that has not chosen any receives those of all it may see.
+func (h *HealthcheckScheduler) RestrictClient(conn *websocket.Conn, diagrams []int) {
+	h.clientsMu.Lock()
+	defer h.clientsMu.Unlock()
+	client := h.clients[conn]
+	if client == nil {
+		return
+	}
+	if diagrams == nil {
+		if client.implicit {
+			h.unfiltered[conn] = true
+		}
+		return
+	}
+
+	allowed := make(map[int]bool, len(diagrams))
+	for _, id := range diagrams {
+		allowed[id] = true
+	}
+	delete(h.unfiltered, conn)
+	for id := range client.diagrams {
+		if !allowed[id] {
+			delete(client.diagrams, id)
+			h.unsubscribe(conn, id)
+		}
+	}
+	if client.implicit {
+		h.subscribe(conn, diagrams)
+	}
+}
+
+// SetClientFilter replaces the filter of a client: it receives only the updates about serviceIDs,
+// or about any service when serviceIDs is nil, and with changesOnly no routine messages
+func (h *HealthcheckScheduler) SetClientFilter(conn *websocket.Conn, serviceIDs []int, changesOnly bool) {
+	filter := wsFilter{changesOnly: changesOnly}
+	if serviceIDs != nil {
+		filter.services = make(map[int]bool, len(serviceIDs))
+		for _, id := range serviceIDs {
+			filter.services[id] = true
+		}
+	}
+
+	h.clientsMu.Lock()
+	defer h.clientsMu.Unlock()
+	if client := h.clients[conn]; client != nil {
+		client.filter = filter
+	}
+}
+
+// choose drops the subscriptions a client had before choosing any itself; clientsMu must be held
+func (h *HealthcheckScheduler) choose(conn *websocket.Conn, client *wsClient) {
+	if !client.implicit {
+		return
+	}
+	client.implicit = false
+	delete(h.unfiltered, conn)
+	for id := range client.diagrams {
+		delete(client.diagrams, id)
+		h.unsubscribe(conn, id)
+	}
+}
+
+func (h *HealthcheckScheduler) subscribe(conn *websocket.Conn, diagramIDs []int) {
+	client := h.clients[conn]
+	for _, id := range diagramIDs {
+		client.diagrams[id] = true
+		if h.byDiagram[id] == nil {
+			h.byDiagram[id] = make(map[*websocket.Conn]bool)
+		}
+		h.byDiagram[id][conn] = true
+	}
+}
+
+// removeClient drops conn from every index and stops its writer; clientsMu must be held
+func (h *HealthcheckScheduler) removeClient(conn *websocket.Conn) {
+	client := h.clients[conn]
+	if client == nil {
+		return
+	}
+	for id := range client.diagrams {
+		h.unsubscribe(conn, id)
+	}
+	delete(h.clients, conn)
+	delete(h.unfiltered, conn)
+	close(client.done)
+}
+
+func (h *HealthcheckScheduler) unsubscribe(conn *websocket.Conn, diagramID int) {
+	delete(h.byDiagram[diagramID], conn)
+	if len(h.byDiagram[diagramID]) == 0 {
+		delete(h.byDiagram, diagramID)
+	}
+}
+
+// Broadcast queues message for the WebSocket clients subscribed to its diagram and those receiving
+// all updates, unless their filter leaves it out, without waiting for any of them. Clients whose
+// queue is full are disconnected, as they stopped reading or their connection is half dead; they
+// reload what they missed when they reconnect.
+func (h *HealthcheckScheduler) Broadcast(message models.Envelope) {
+	services := messageServices(message)
+	var stuck []*websocket.Conn
+	h.clientsMu.RLock()
+	for _, recipients := range []map[*websocket.Conn]bool{h.unfiltered, h.byDiagram[message.DiagramID]} {
+		for conn := range recipients {
+			client := h.clients[conn]
+			if !client.filter.allows(message, services) {
+				continue
+			}
+			select {
+			case client.send <- message:
+			default:
+				stuck = append(stuck, conn)
+			}
+		}
+	}
+	h.clientsMu.RUnlock()
+
+	for _, conn := range stuck {
+		log.Printf("WebSocket client not keeping up, disconnecting")
+		h.stats.clientDroppedInc()
+		h.RemoveClient(conn)
+	}
+}
+
+// messageServices returns the services a message is about: the service itself, or both ends of a
+// connection
+func messageServices(message models.Envelope) []int {
+	switch data := message.Data.(type) {
+	case models.StatusUpdate:
+		return []int{data.ServiceID}
+	case models.HealthcheckResult:
+		return []int{data.ServiceID}
+	case models.AlertEvent:
+		return []int{data.ServiceID}
+	case models.Service:
+		return []int{data.ID}
+	case models.ConnectionStatusUpdate:
+		return []int{data.SourceID, data.TargetID}
+	case models.Connection:
+		return []int{data.SourceID, data.TargetID}
+	case models.DeletedEntity:
+		if message.Type == models.MessageServiceDeleted {
+			return []int{data.ID}
+		}
+	}
+	return nil
+}
+
+// drain returns first and the messages queued behind it, up to wsBatchSize, keeping only the
+// latest status of each service and connection
+func drain(queue <-chan models.Envelope, first models.Envelope) []models.Envelope {
+	batch := []models.Envelope{first}
+	for len(batch) < wsBatchSize {
+		select {
+		case message := <-queue:
+			batch = append(batch, message)
+		default:
+			return coalesce(batch)
+		}
+	}
+	return coalesce(batch)
+}
+
+// coalesce drops the status messages of batch superseded by a later one
+func coalesce(batch []models.Envelope) []models.Envelope {
+	latest := make(map[string]int)
+	for i, message := range batch {
+		if key, ok := statusKey(message); ok {
+			latest[key] = i
+		}
+	}
+	kept := batch[:0]
+	for i, message := range batch {
+		if key, ok := statusKey(message); !ok || latest[key] == i {
+			kept = append(kept, message)
+		}
+	}
+	return kept
+}
+
+// statusKey identifies the service or connection a status message is about; a later status of
+// the same one supersedes it
+func statusKey(message models.Envelope) (string, bool) {
+	switch data := message.Data.(type) {
+	case models.StatusUpdate:
+		return "service:" + strconv.Itoa(data.ServiceID), true
+	case models.ConnectionStatusUpdate:
+		return "connection:" + strconv.Itoa(data.ConnectionID), true
+	}
+	return "", false
+}
@@ -0,0 +1,121 @@
+package notification
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"service-weaver/internal/models"
+	"strings"
+	"time"
+)
+
+// emailSender delivers messages over SMTP, upgrading to TLS via STARTTLS when the server offers it
+type emailSender struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func newEmailSender(config models.JSON) (*emailSender, error) {
+	host := configString(config, "smtp_host", "")
+	if host == "" {
+		return nil, fmt.Errorf("email channel requires an smtp_host")
+	}
+	from := configString(config, "from", "")
+	if from == "" {
+		return nil, fmt.Errorf("email channel requires a from address")
+	}
+
+	var to []string
+	for _, addr := range strings.Split(configString(config, "to", ""), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+	if len(to) == 0 {
+		return nil, fmt.Errorf("email channel requires at least one recipient in to")
+	}
+
+	return &emailSender{
+		host:     host,
+		port:     configString(config, "smtp_port", "587"),
+		username: configString(config, "username", ""),
+		password: configString(config, "password", ""),
+		from:     from,
+		to:       to,
+	}, nil
+}
+
+func (s *emailSender) Send(ctx context.Context, msg Message) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.host, s.port))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(s.from); err != nil {
+		return err
+	}
+	for _, addr := range s.to {
+		if err := client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.buildMessage(msg)); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (s *emailSender) buildMessage(msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", strings.NewReplacer("\r", "", "\n", " ").Replace(msg.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	if msg.Priority == PriorityHigh {
+		b.WriteString("X-Priority: 1\r\n")
+	}
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
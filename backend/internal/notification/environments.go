@@ -0,0 +1,61 @@
+package notification
+
+import (
+	"context"
+	"service-weaver/internal/models"
+)
+
+// severityPriority is the message priority of an alert severity
+func severityPriority(severity models.AlertSeverity) Priority {
+	switch severity {
+	case models.SeverityCritical:
+		return PriorityHigh
+	case models.SeverityWarning:
+		return PriorityNormal
+	default:
+		return PriorityLow
+	}
+}
+
+// environment returns the environment of a service, falling back to that of its diagram, or nil
+// when neither is labelled with a defined environment
+func (d *Dispatcher) environment(service models.Service) *models.Environment {
+	ctx := context.Background()
+	name := service.Environment
+	if name == "" {
+		diagram, err := d.repo.GetDiagram(ctx, service.DiagramID)
+		if err != nil {
+			return nil
+		}
+		name = diagram.Environment
+	}
+	if name == "" {
+		return nil
+	}
+	environment, err := d.repo.GetEnvironmentByName(ctx, name)
+	if err != nil {
+		return nil
+	}
+	return environment
+}
+
+// maxPriority is the highest priority the alerts of a service may have, as its environment allows
+func (d *Dispatcher) maxPriority(service models.Service) (Priority, string) {
+	environment := d.environment(service)
+	if environment == nil {
+		return PriorityHigh, ""
+	}
+	return severityPriority(environment.MaxSeverity), environment.Name
+}
+
+// applyEnvironment tags msg with the environment of a service and caps its priority at the
+// environment's maximum severity, so that staging, say, never pages
+func (d *Dispatcher) applyEnvironment(service models.Service, msg *Message) {
+	limit, name := d.maxPriority(service)
+	if name != "" {
+		msg.Tags = append(msg.Tags, name)
+	}
+	if msg.Priority > limit {
+		msg.Priority = limit
+	}
+}
@@ -0,0 +1,73 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/models"
+	"strings"
+)
+
+// gotifySender pushes messages to a Gotify server using an application token
+type gotifySender struct {
+	serverURL string
+	appToken  string
+	client    *http.Client
+}
+
+func newGotifySender(config models.JSON) (*gotifySender, error) {
+	serverURL := configString(config, "server_url", "")
+	if serverURL == "" {
+		return nil, fmt.Errorf("gotify channel requires a server_url")
+	}
+	appToken := configString(config, "app_token", "")
+	if appToken == "" {
+		return nil, fmt.Errorf("gotify channel requires an app_token")
+	}
+
+	return &gotifySender{
+		serverURL: strings.TrimRight(serverURL, "/"),
+		appToken:  appToken,
+		client:    &http.Client{},
+	}, nil
+}
+
+func (s *gotifySender) Send(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"title":    msg.Title,
+		"message":  msg.Body,
+		"priority": gotifyPriority(msg.Priority),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.serverURL+"/message", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", s.appToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+// gotifyPriority maps to Gotify's 0-10 scale, where 8+ triggers a loud notification on Android
+func gotifyPriority(p Priority) int {
+	switch p {
+	case PriorityHigh:
+		return 8
+	case PriorityNormal:
+		return 5
+	default:
+		return 2
+	}
+}
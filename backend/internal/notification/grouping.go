@@ -0,0 +1,163 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"service-weaver/internal/models"
+	"service-weaver/internal/rootcause"
+	"slices"
+	"strings"
+	"time"
+)
+
+// statusChange is a service status transition awaiting its alert
+type statusChange struct {
+	service  models.Service
+	from, to models.ServiceStatus
+	checkErr string
+	at       time.Time
+}
+
+// hold keeps a failure back until the group window of its diagram closes, and reports whether it
+// did. The first failure of a diagram opens the window, which stays open until it is flushed. A
+// service recovering within the window is dropped from the group, and its recovery is not sent
+// either, as its failure never was.
+func (d *Dispatcher) hold(change statusChange) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	diagramID := change.service.DiagramID
+	group, open := d.pending[diagramID]
+	for i, held := range group {
+		if held.service.ID != change.service.ID {
+			continue
+		}
+		if change.to == models.StatusAlive {
+			d.pending[diagramID] = append(group[:i], group[i+1:]...)
+		} else {
+			// The group keeps the time the service first failed
+			change.from, change.at = held.from, held.at
+			group[i] = change
+		}
+		return true
+	}
+	if change.to == models.StatusAlive {
+		return false
+	}
+
+	if !open {
+		time.AfterFunc(d.groupWindow, func() { d.flush(diagramID) })
+	}
+	d.pending[diagramID] = append(group, change)
+	return true
+}
+
+// flush sends the failures held for a diagram: a lone failure as usual, several as one alert
+// naming their probable root cause, to the channels and owners each of the services is routed to
+func (d *Dispatcher) flush(diagramID int) {
+	d.mu.Lock()
+	group := d.pending[diagramID]
+	delete(d.pending, diagramID)
+	d.mu.Unlock()
+
+	switch len(group) {
+	case 0:
+		return
+	case 1:
+		d.notify(group[0])
+		return
+	}
+
+	ctx := context.Background()
+	channels, err := d.repo.GetEnabledNotificationChannels(ctx)
+	if err != nil {
+		log.Printf("Error loading notification channels: %v", err)
+		return
+	}
+	connections, err := d.repo.GetConnections(ctx, diagramID)
+	if err != nil {
+		log.Printf("Error loading connections of diagram %d: %v", diagramID, err)
+	}
+	diagramName := fmt.Sprintf("diagram %d", diagramID)
+	if diagram, err := d.repo.GetDiagram(ctx, diagramID); err == nil {
+		diagramName = diagram.Name
+	}
+
+	services := make([]models.Service, len(group))
+	failures := make([]rootcause.Failure, len(group))
+	for i, change := range group {
+		services[i] = change.service
+		failures[i] = rootcause.Failure{ServiceID: change.service.ID, Status: change.to, At: change.at}
+	}
+	msg := outageMessage(diagramName, group, rootcause.Analyze(services, connections, failures))
+
+	// The group is as urgent as the environments of its services allow the most urgent alert
+	limit := PriorityLow
+	for _, change := range group {
+		priority, name := d.maxPriority(change.service)
+		limit = max(limit, priority)
+		if name != "" && !slices.Contains(msg.Tags, name) {
+			msg.Tags = append(msg.Tags, name)
+		}
+	}
+	msg.Priority = min(msg.Priority, limit)
+
+	var routed []models.NotificationChannel
+	seenChannels := make(map[int]bool)
+	seenOwners := make(map[int]bool)
+	for _, change := range group {
+		ownerUserID, serviceChannels := d.route(channels, change.service)
+		for _, channel := range serviceChannels {
+			if !seenChannels[channel.ID] {
+				seenChannels[channel.ID] = true
+				routed = append(routed, channel)
+			}
+		}
+		if ownerUserID != nil && !seenOwners[*ownerUserID] {
+			seenOwners[*ownerUserID] = true
+			d.alertUser(*ownerUserID, msg)
+		}
+	}
+	d.dispatchTo(routed, msg)
+}
+
+// outageMessage renders the alert for services of a diagram that failed together, listed from the
+// most probable root cause
+func outageMessage(diagramName string, group []statusChange, candidates []models.RootCauseCandidate) Message {
+	changes := make(map[int]statusChange, len(group))
+	for _, change := range group {
+		changes[change.service.ID] = change
+	}
+
+	msg := Message{
+		Title:    fmt.Sprintf("%d services failed on %s", len(group), diagramName),
+		Priority: PriorityNormal,
+		Tags:     []string{string(models.StatusDegraded)},
+	}
+	for _, change := range group {
+		if change.to == models.StatusDead {
+			msg.Priority = PriorityHigh
+			msg.Tags = []string{string(models.StatusDead)}
+		}
+	}
+
+	var body strings.Builder
+	if len(candidates) > 0 {
+		root := candidates[0]
+		fmt.Fprintf(&body, "Probable root cause: %s (%s): %s.", root.ServiceName, root.Status, strings.Join(root.Reasons, ", "))
+		if runbook := changes[root.ServiceID].service.RunbookURL; runbook != "" {
+			body.WriteString("\nRunbook: " + runbook)
+		}
+		body.WriteString("\n")
+	}
+	for _, candidate := range candidates {
+		change := changes[candidate.ServiceID]
+		fmt.Fprintf(&body, "\n- %s (%s:%d) changed from %s to %s", change.service.Name, change.service.Host, change.service.Port, change.from, change.to)
+		if change.checkErr != "" {
+			body.WriteString(": " + change.checkErr)
+		}
+	}
+	msg.Body = strings.TrimPrefix(body.String(), "\n")
+	return msg
+}
@@ -0,0 +1,310 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a channel-agnostic alert payload
+type Message struct {
+	Title    string
+	Body     string
+	Priority Priority
+	Tags     []string
+	// Preformatted marks bodies laid out as fixed-width text, such as report tables
+	Preformatted bool
+}
+
+// Priority expresses how urgently a message should be delivered
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+)
+
+// Sender delivers messages to a single notification channel
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender builds the sender matching the channel type
+func NewSender(channel models.NotificationChannel) (Sender, error) {
+	switch channel.Type {
+	case models.ChannelNtfy:
+		return newNtfySender(channel.Config)
+	case models.ChannelGotify:
+		return newGotifySender(channel.Config)
+	case models.ChannelEmail:
+		return newEmailSender(channel.Config)
+	case models.ChannelSlack:
+		return newSlackSender(channel.Config)
+	default:
+		return nil, fmt.Errorf("unsupported notification channel type: %s", channel.Type)
+	}
+}
+
+// Dispatcher fans status alerts out to the enabled notification channels
+type Dispatcher struct {
+	repo    *repository.Repository
+	timeout time.Duration
+	// groupWindow is how long failures on a diagram are held to be sent together; 0 sends each at once
+	groupWindow time.Duration
+
+	mu      sync.Mutex
+	pending map[int][]statusChange // failures held, by diagram
+}
+
+func NewDispatcher(repo *repository.Repository, groupWindow time.Duration) *Dispatcher {
+	return &Dispatcher{
+		repo:        repo,
+		timeout:     10 * time.Second,
+		groupWindow: groupWindow,
+		pending:     make(map[int][]statusChange),
+	}
+}
+
+// NotifyStatusChange sends an alert describing a service status transition to the channels of
+// the team owning the service, or, when the team has none or no team owns it, to the channels
+// assigned to no team. The owning user is alerted as well, as their notification preferences say.
+// With a group window, failures are held and sent together with the others of the diagram.
+func (d *Dispatcher) NotifyStatusChange(service models.Service, from, to models.ServiceStatus, checkErr string) {
+	change := statusChange{service: service, from: from, to: to, checkErr: checkErr, at: time.Now()}
+	if d.groupWindow > 0 && d.hold(change) {
+		return
+	}
+	d.notify(change)
+}
+
+func (d *Dispatcher) notify(change statusChange) {
+	channels, err := d.repo.GetEnabledNotificationChannels(context.Background())
+	if err != nil {
+		log.Printf("Error loading notification channels: %v", err)
+		return
+	}
+	msg := StatusChangeMessage(change.service, change.from, change.to, change.checkErr)
+	d.applyEnvironment(change.service, &msg)
+	ownerUserID, routed := d.route(channels, change.service)
+	d.dispatchTo(routed, msg)
+	if ownerUserID != nil {
+		d.alertUser(*ownerUserID, msg)
+	}
+}
+
+// route returns the user owning a service and the channels of the team owning it, or, when the
+// team has none or no team owns it, the channels assigned to no team
+func (d *Dispatcher) route(channels []models.NotificationChannel, service models.Service) (*int, []models.NotificationChannel) {
+	ownerUserID, team := d.owner(service)
+	var teamChannels, sharedChannels []models.NotificationChannel
+	for _, channel := range channels {
+		switch channel.Team {
+		case "":
+			sharedChannels = append(sharedChannels, channel)
+		case team:
+			teamChannels = append(teamChannels, channel)
+		}
+	}
+	if len(teamChannels) > 0 {
+		return ownerUserID, teamChannels
+	}
+	return ownerUserID, sharedChannels
+}
+
+// owner returns the user and team owning a service, falling back to those of its diagram
+func (d *Dispatcher) owner(service models.Service) (*int, string) {
+	ownerUserID, team := service.OwnerUserID, service.OwnerTeam
+	if ownerUserID != nil && team != "" {
+		return ownerUserID, team
+	}
+	diagram, err := d.repo.GetDiagram(context.Background(), service.DiagramID)
+	if err != nil {
+		return ownerUserID, team
+	}
+	if ownerUserID == nil {
+		ownerUserID = diagram.OwnerUserID
+	}
+	if team == "" {
+		team = diagram.OwnerTeam
+	}
+	return ownerUserID, team
+}
+
+// Dispatch delivers msg to every enabled channel without blocking the caller
+func (d *Dispatcher) Dispatch(msg Message) {
+	channels, err := d.repo.GetEnabledNotificationChannels(context.Background())
+	if err != nil {
+		log.Printf("Error loading notification channels: %v", err)
+		return
+	}
+	d.dispatchTo(channels, msg)
+}
+
+// dispatchTo delivers msg to channels without blocking the caller
+func (d *Dispatcher) dispatchTo(channels []models.NotificationChannel, msg Message) {
+	for _, channel := range channels {
+		go func(channel models.NotificationChannel) {
+			if err := d.send(channel, msg); err != nil {
+				log.Printf("Error sending notification via channel %d (%s): %v", channel.ID, channel.Name, err)
+			}
+		}(channel)
+	}
+}
+
+// NotifyUser emails msg to a single user through the first enabled email channel, without blocking
+// the caller. Nothing is sent when no email channel is enabled or the user has no address.
+func (d *Dispatcher) NotifyUser(email string, msg Message) {
+	if email == "" {
+		return
+	}
+	// Reuse the channel's SMTP settings with the user as the only recipient
+	channel := d.personalChannel(models.ChannelEmail, "to", email)
+	if channel == nil {
+		return
+	}
+	go func() {
+		if err := d.send(*channel, msg); err != nil {
+			log.Printf("Error emailing user via channel %d (%s): %v", channel.ID, channel.Name, err)
+		}
+	}()
+}
+
+// personalChannel returns the first enabled channel of a type with one setting replaced, such as
+// the recipient, to reach a single user through it; nil when there is none
+func (d *Dispatcher) personalChannel(channelType models.NotificationChannelType, setting, value string) *models.NotificationChannel {
+	channels, err := d.repo.GetEnabledNotificationChannels(context.Background())
+	if err != nil {
+		log.Printf("Error loading notification channels: %v", err)
+		return nil
+	}
+
+	for _, channel := range channels {
+		if channel.Type != channelType {
+			continue
+		}
+		config := make(models.JSON, len(channel.Config)+1)
+		for key, value := range channel.Config {
+			config[key] = value
+		}
+		config[setting] = value
+		channel.Config = config
+		return &channel
+	}
+	return nil
+}
+
+// Deliver synchronously sends msg to the given enabled channels, or to every enabled channel
+// when channelIDs is empty, and returns the combined delivery errors
+func (d *Dispatcher) Deliver(channelIDs []int64, msg Message) error {
+	channels, err := d.repo.GetEnabledNotificationChannels(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to load notification channels: %w", err)
+	}
+
+	wanted := make(map[int64]bool, len(channelIDs))
+	for _, id := range channelIDs {
+		wanted[id] = true
+	}
+
+	var errs []error
+	delivered := 0
+	for _, channel := range channels {
+		if len(wanted) > 0 && !wanted[int64(channel.ID)] {
+			continue
+		}
+		if err := d.send(channel, msg); err != nil {
+			errs = append(errs, fmt.Errorf("channel %d (%s): %w", channel.ID, channel.Name, err))
+			continue
+		}
+		delivered++
+	}
+
+	if delivered == 0 && len(errs) == 0 {
+		return fmt.Errorf("no enabled notification channels to deliver to")
+	}
+	return errors.Join(errs...)
+}
+
+// SendTest delivers a test message to a single channel and reports the outcome
+func (d *Dispatcher) SendTest(channel models.NotificationChannel) error {
+	return d.send(channel, Message{
+		Title:    "Service Weaver test notification",
+		Body:     fmt.Sprintf("Channel %q is configured correctly.", channel.Name),
+		Priority: PriorityNormal,
+		Tags:     []string{"test"},
+	})
+}
+
+func (d *Dispatcher) send(channel models.NotificationChannel, msg Message) error {
+	sender, err := NewSender(channel)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	return sender.Send(ctx, msg)
+}
+
+// maxAlertNotesLength caps the service notes quoted in an alert, which some channels, such as
+// ntfy, limit to a few kilobytes
+const maxAlertNotesLength = 2000
+
+// StatusChangeMessage renders the alert for a service status transition
+func StatusChangeMessage(service models.Service, from, to models.ServiceStatus, checkErr string) Message {
+	msg := Message{
+		Title: fmt.Sprintf("%s is %s", service.Name, to),
+		Body:  fmt.Sprintf("Service %s (%s:%d) changed from %s to %s.", service.Name, service.Host, service.Port, from, to),
+		Tags:  []string{string(to)},
+	}
+	if checkErr != "" {
+		msg.Body += "\nError: " + checkErr
+	}
+	// Remediation context is left out of recoveries, which need none
+	if to != models.StatusAlive {
+		if service.RunbookURL != "" {
+			msg.Body += "\nRunbook: " + service.RunbookURL
+		}
+		if notes := strings.TrimSpace(service.Notes); notes != "" {
+			if len(notes) > maxAlertNotesLength {
+				notes = strings.ToValidUTF8(notes[:maxAlertNotesLength], "") + "…"
+			}
+			msg.Body += "\n\n" + notes
+		}
+	}
+
+	switch to {
+	case models.StatusDead:
+		msg.Priority = PriorityHigh
+	case models.StatusDegraded:
+		msg.Priority = PriorityNormal
+	default:
+		msg.Priority = PriorityLow
+	}
+	return msg
+}
+
+// configString reads an optional string value from a channel config
+func configString(config models.JSON, key, defaultValue string) string {
+	if value, ok := config[key].(string); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// checkResponse turns a non-2xx response into an error
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+	return nil
+}
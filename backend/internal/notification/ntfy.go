@@ -0,0 +1,76 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/models"
+	"strings"
+)
+
+// ntfySender publishes messages to an ntfy topic (https://ntfy.sh or self-hosted)
+type ntfySender struct {
+	serverURL string
+	topic     string
+	token     string
+	username  string
+	password  string
+	client    *http.Client
+}
+
+func newNtfySender(config models.JSON) (*ntfySender, error) {
+	topic := configString(config, "topic", "")
+	if topic == "" {
+		return nil, fmt.Errorf("ntfy channel requires a topic")
+	}
+
+	return &ntfySender{
+		serverURL: strings.TrimRight(configString(config, "server_url", "https://ntfy.sh"), "/"),
+		topic:     topic,
+		token:     configString(config, "token", ""),
+		username:  configString(config, "username", ""),
+		password:  configString(config, "password", ""),
+		client:    &http.Client{},
+	}, nil
+}
+
+func (s *ntfySender) Send(ctx context.Context, msg Message) error {
+	url := fmt.Sprintf("%s/%s", s.serverURL, s.topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(msg.Body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Title", msg.Title)
+	req.Header.Set("Priority", ntfyPriority(msg.Priority))
+	if len(msg.Tags) > 0 {
+		req.Header.Set("Tags", strings.Join(msg.Tags, ","))
+	}
+
+	// Access tokens take precedence over basic auth
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	} else if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+// ntfyPriority maps to ntfy's 1 (min) - 5 (max) scale
+func ntfyPriority(p Priority) string {
+	switch p {
+	case PriorityHigh:
+		return "5"
+	case PriorityNormal:
+		return "3"
+	default:
+		return "2"
+	}
+}
@@ -0,0 +1,113 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"service-weaver/internal/models"
+	"time"
+)
+
+// severityRanks orders alert severities
+var severityRanks = map[models.AlertSeverity]int{
+	models.SeverityInfo:     0,
+	models.SeverityWarning:  1,
+	models.SeverityCritical: 2,
+}
+
+// prioritySeverity is the alert severity of a message priority
+func prioritySeverity(p Priority) models.AlertSeverity {
+	switch p {
+	case PriorityHigh:
+		return models.SeverityCritical
+	case PriorityNormal:
+		return models.SeverityWarning
+	default:
+		return models.SeverityInfo
+	}
+}
+
+// ParseClock reads a time of day given as HH:MM as minutes after midnight
+func ParseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// inQuietHours reports whether now falls in the quiet hours of prefs, in their time zone. Quiet
+// hours starting later than they end span midnight.
+func inQuietHours(prefs models.NotificationPreferences, now time.Time) bool {
+	start, err := ParseClock(prefs.QuietHoursStart)
+	if err != nil {
+		return false
+	}
+	end, err := ParseClock(prefs.QuietHoursEnd)
+	if err != nil || start == end {
+		return false
+	}
+	if location, err := time.LoadLocation(prefs.Timezone); err == nil {
+		now = now.In(location)
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// wants reports whether a user with prefs is to be alerted of msg at now
+func wants(prefs models.NotificationPreferences, msg Message, now time.Time) bool {
+	severity := prioritySeverity(msg.Priority)
+	if severityRanks[severity] < severityRanks[prefs.MinSeverity] {
+		return false
+	}
+	if inQuietHours(prefs, now) {
+		return prefs.QuietHoursAllowCritical && severity == models.SeverityCritical
+	}
+	return true
+}
+
+// alertUser delivers an alert to a user on the personal channels of their notification
+// preferences, unless it is below their minimum severity or held back by their quiet hours
+func (d *Dispatcher) alertUser(userID int, msg Message) {
+	ctx := context.Background()
+	user, err := d.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return
+	}
+	prefs, err := d.repo.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		log.Printf("Error loading notification preferences of user %d: %v", userID, err)
+		return
+	}
+	if !wants(*prefs, msg, time.Now()) {
+		return
+	}
+
+	var channels []models.NotificationChannel
+	for _, channelType := range prefs.Channels {
+		switch channelType {
+		case models.ChannelEmail:
+			d.NotifyUser(user.Email, msg)
+		case models.ChannelSlack:
+			if prefs.SlackWebhookURL != "" {
+				channels = append(channels, models.NotificationChannel{
+					Name:   "Slack of " + user.Username,
+					Type:   models.ChannelSlack,
+					Config: models.JSON{"webhook_url": prefs.SlackWebhookURL},
+				})
+			}
+		case models.ChannelNtfy:
+			// The topic is published to on the server of the first enabled ntfy channel
+			if prefs.NtfyTopic != "" {
+				if channel := d.personalChannel(models.ChannelNtfy, "topic", prefs.NtfyTopic); channel != nil {
+					channels = append(channels, *channel)
+				}
+			}
+		}
+	}
+	d.dispatchTo(channels, msg)
+}
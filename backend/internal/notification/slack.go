@@ -0,0 +1,65 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"service-weaver/internal/models"
+)
+
+// slackSender posts messages to a Slack incoming webhook
+type slackSender struct {
+	webhookURL string
+	client     *http.Client
+}
+
+func newSlackSender(config models.JSON) (*slackSender, error) {
+	webhookURL := configString(config, "webhook_url", "")
+	if webhookURL == "" {
+		return nil, fmt.Errorf("slack channel requires a webhook_url")
+	}
+
+	return &slackSender{
+		webhookURL: webhookURL,
+		client:     &http.Client{},
+	}, nil
+}
+
+func (s *slackSender) Send(ctx context.Context, msg Message) error {
+	text := fmt.Sprintf("%s *%s*\n%s", slackEmoji(msg.Priority), msg.Title, msg.Body)
+	if msg.Preformatted {
+		text = fmt.Sprintf("%s *%s*\n```%s```", slackEmoji(msg.Priority), msg.Title, msg.Body)
+	}
+
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+func slackEmoji(p Priority) string {
+	switch p {
+	case PriorityHigh:
+		return ":red_circle:"
+	case PriorityNormal:
+		return ":large_yellow_circle:"
+	default:
+		return ":large_green_circle:"
+	}
+}
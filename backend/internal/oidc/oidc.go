@@ -0,0 +1,362 @@
+// Package oidc implements OpenID Connect single sign-on with the authorization code flow and PKCE.
+// It talks to the provider with plain HTTP and verifies ID tokens against the provider's JWKS, so
+// any compliant identity provider (Keycloak, Okta, Auth0, Azure AD, ...) works without extra SDKs.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"service-weaver/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config describes the identity provider and how its users map to Service Weaver users
+type Config struct {
+	// Issuer is the provider's issuer URL, e.g. https://keycloak.example.com/realms/main.
+	// Empty disables single sign-on.
+	Issuer       string
+	ClientID     string
+	ClientSecret string // empty for public clients, which rely on PKCE alone
+	// RedirectURL is the callback registered with the provider, e.g. https://weaver.example.com/api/auth/oidc/callback
+	RedirectURL string
+	// PostLoginURL is where the browser is sent after the callback, with the tokens in the URL fragment
+	PostLoginURL string
+	Scopes       []string
+	DisplayName  string // label of the login button
+	// UsernameClaim names the claim used as username of provisioned users (default preferred_username)
+	UsernameClaim string
+	// RoleClaim is the claim holding the user's groups or roles, as a dotted path for nested claims
+	// (e.g. groups or realm_access.roles). Empty leaves roles to administrators.
+	RoleClaim string
+	// RoleMapping maps values of the role claim to roles; the most privileged match wins
+	RoleMapping map[string]models.UserRole
+	// DefaultRole is given to users without a mapped role; empty denies them access. Without a
+	// role claim it is the role of new users (viewer when empty).
+	DefaultRole models.UserRole
+	// LinkExistingUsers lets a verified email address sign in to the local account with that email
+	LinkExistingUsers bool
+}
+
+// Identity is a user authenticated by the provider
+type Identity struct {
+	Subject       string
+	Username      string
+	Email         string
+	EmailVerified bool
+	// Role is the role the user should have; empty when the role claim grants no access
+	Role models.UserRole
+	// SyncRole reports whether Role comes from the role claim and should replace the role of
+	// existing users; otherwise Role is only the default for new users
+	SyncRole bool
+}
+
+// ErrAccessDenied is returned for users whose role claim maps to no role
+var ErrAccessDenied = errors.New("access denied: no role is mapped to this user")
+
+// roleRank orders roles by privilege when several claim values match
+var roleRank = map[models.UserRole]int{
+	models.RoleViewer: 1,
+	models.RoleAdmin:  2,
+}
+
+// Provider performs the login flow against one identity provider
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *metadata
+	keys     *keySet
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// ParseRoleMapping parses the OIDC_ROLE_MAPPING format ("claim-value=role,claim-value=role")
+func ParseRoleMapping(value string) (map[string]models.UserRole, error) {
+	mapping := make(map[string]models.UserRole)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		claim, role, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(claim) == "" {
+			return nil, fmt.Errorf("invalid role mapping %q", pair)
+		}
+		userRole := models.UserRole(strings.TrimSpace(role))
+		if _, known := roleRank[userRole]; !known {
+			return nil, fmt.Errorf("unknown role %q in role mapping", role)
+		}
+		mapping[strings.TrimSpace(claim)] = userRole
+	}
+	return mapping, nil
+}
+
+// New returns a provider for the configuration, or nil when single sign-on is disabled. The
+// provider's discovery document is fetched on first use, so startup does not depend on it.
+func New(config Config) (*Provider, error) {
+	if config.Issuer == "" {
+		return nil, nil
+	}
+	if config.ClientID == "" {
+		return nil, errors.New("client ID is required")
+	}
+	if _, err := url.ParseRequestURI(config.RedirectURL); err != nil {
+		return nil, fmt.Errorf("invalid redirect URL: %w", err)
+	}
+	if config.DefaultRole != "" {
+		if _, known := roleRank[config.DefaultRole]; !known {
+			return nil, fmt.Errorf("unknown default role %q", config.DefaultRole)
+		}
+	}
+	config.Issuer = strings.TrimRight(config.Issuer, "/")
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile", "email"}
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = "preferred_username"
+	}
+	if config.PostLoginURL == "" {
+		config.PostLoginURL = "/"
+	}
+	if config.DisplayName == "" {
+		config.DisplayName = "Single sign-on"
+	}
+
+	return &Provider{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Issuer identifies the provider; together with the subject it identifies a user
+func (p *Provider) Issuer() string {
+	return p.config.Issuer
+}
+
+// DisplayName is the label of the login button
+func (p *Provider) DisplayName() string {
+	return p.config.DisplayName
+}
+
+// PostLoginURL is where the browser goes after the login
+func (p *Provider) PostLoginURL() string {
+	return p.config.PostLoginURL
+}
+
+// LinkExistingUsers reports whether verified emails may sign in to existing local accounts
+func (p *Provider) LinkExistingUsers() bool {
+	return p.config.LinkExistingUsers
+}
+
+// AuthCodeURL returns the provider URL the browser is sent to for logging in
+func (p *Provider) AuthCodeURL(ctx context.Context, req *AuthRequest) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(req.Verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {req.State},
+		"nonce":                 {req.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return meta.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange redeems the authorization code from the callback and returns the verified identity
+func (p *Provider) Exchange(ctx context.Context, code string, req *AuthRequest) (*Identity, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"code_verifier": {req.Verifier},
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		httpReq.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("token request returned status %d: %w", resp.StatusCode, err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("token request failed: %s %s", token.Error, token.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || token.IDToken == "" {
+		return nil, fmt.Errorf("token request returned status %d without an ID token", resp.StatusCode)
+	}
+
+	claims, err := p.verifyIDToken(ctx, meta, token.IDToken, req.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	return p.identity(claims)
+}
+
+// identity maps verified ID token claims to a user
+func (p *Provider) identity(claims map[string]interface{}) (*Identity, error) {
+	identity := &Identity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	if identity.Subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+	if identity.Email == "" {
+		return nil, errors.New("identity provider did not return an email address; request the email scope")
+	}
+
+	for _, candidate := range []interface{}{claimValue(claims, p.config.UsernameClaim), claims["email"], claims["sub"]} {
+		if username, ok := candidate.(string); ok && username != "" {
+			identity.Username = username
+			break
+		}
+	}
+
+	if p.config.RoleClaim == "" {
+		identity.Role = p.config.DefaultRole
+		if identity.Role == "" {
+			identity.Role = models.RoleViewer
+		}
+		return identity, nil
+	}
+
+	identity.SyncRole = true
+	identity.Role = p.config.DefaultRole
+	for _, value := range claimStrings(claimValue(claims, p.config.RoleClaim)) {
+		if role, ok := p.config.RoleMapping[value]; ok && roleRank[role] > roleRank[identity.Role] {
+			identity.Role = role
+		}
+	}
+	if identity.Role == "" {
+		return nil, ErrAccessDenied
+	}
+	return identity, nil
+}
+
+// claimValue resolves a dotted path such as realm_access.roles in the claims
+func claimValue(claims map[string]interface{}, path string) interface{} {
+	var value interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// claimStrings returns a string claim or the strings of a list claim
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(strings.ReplaceAll(v, ",", " "))
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// discover fetches and caches the provider's discovery document
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var meta metadata
+	if err := p.getJSON(ctx, p.config.Issuer+"/.well-known/openid-configuration", &meta); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if strings.TrimRight(meta.Issuer, "/") != p.config.Issuer {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %q, expected %q", meta.Issuer, p.config.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document is missing endpoints")
+	}
+	p.metadata = &meta
+	return p.metadata, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(target)
+}
+
+// randomString returns n random bytes, base64url encoded
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,66 @@
+package oidc
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// authRequestTTL is how long a user has to complete the login at the provider
+const authRequestTTL = 10 * time.Minute
+
+// AuthRequest holds the per-login secrets that bind the callback to the browser that started it
+type AuthRequest struct {
+	State    string
+	Nonce    string
+	Verifier string // PKCE code verifier
+}
+
+// NewAuthRequest generates the secrets for a new login
+func NewAuthRequest() (*AuthRequest, error) {
+	state, err := randomString(24)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomString(24)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthRequest{State: state, Nonce: nonce, Verifier: verifier}, nil
+}
+
+// Seal signs the request so it can be kept in a cookie until the callback
+func (r *AuthRequest) Seal(key []byte) (string, error) {
+	claims := jwt.MapClaims{
+		"state":    r.State,
+		"nonce":    r.Nonce,
+		"verifier": r.Verifier,
+		"exp":      jwt.NewNumericDate(time.Now().Add(authRequestTTL)),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+}
+
+// OpenAuthRequest verifies a sealed request and checks it belongs to the callback's state
+func OpenAuthRequest(sealed string, key []byte, state string) (*AuthRequest, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(sealed, claims, func(token *jwt.Token) (interface{}, error) {
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, errors.New("login request expired or invalid")
+	}
+
+	r := &AuthRequest{}
+	r.State, _ = claims["state"].(string)
+	r.Nonce, _ = claims["nonce"].(string)
+	r.Verifier, _ = claims["verifier"].(string)
+	if r.State == "" || r.State != state {
+		return nil, errors.New("login state mismatch")
+	}
+	return r, nil
+}
@@ -0,0 +1,583 @@
+package openapi
+
+import (
+	"net/http"
+	"service-weaver/internal/config"
+	"service-weaver/internal/discovery"
+	"service-weaver/internal/middleware"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+)
+
+// UpdateUserRequest is the body of PUT /api/users/:id
+type UpdateUserRequest struct {
+	Email    string          `json:"email" binding:"required,email"`
+	Role     models.UserRole `json:"role" binding:"required,oneof=admin viewer"`
+	Password string          `json:"password"`
+}
+
+// SavePositionsRequest is the body of POST /api/diagrams/:id/positions
+type SavePositionsRequest struct {
+	Positions []models.ServicePosition `json:"positions"`
+}
+
+// Shared query parameters
+var (
+	pagination = []Param{
+		{Name: "limit", Type: "integer", Description: "Maximum number of items to return"},
+		{Name: "offset", Type: "integer", Description: "Number of items to skip"},
+	}
+	timeRange = []Param{
+		{Name: "from", Type: "string", Description: "Start of the range, RFC3339 or Unix seconds"},
+		{Name: "to", Type: "string", Description: "End of the range, RFC3339 or Unix seconds"},
+	}
+	ownerFilter = []Param{
+		{Name: "owner_user_id", Type: "string", Description: "Owner user ID, or me"},
+		{Name: "owner_team", Type: "string", Description: "Owner team"},
+	}
+	listOptions = append(append([]Param{}, pagination...),
+		Param{Name: "sort", Type: "string", Description: "Field to sort by"},
+		Param{Name: "order", Type: "string", Description: "asc or desc"},
+	)
+	uptimeWindow = []Param{
+		{Name: "window", Type: "string", Description: "Lookback window, e.g. 24h, 7d or 30d"},
+		{Name: "slo", Type: "number", Description: "Uptime target in percent"},
+	}
+	securityEventFilters = []Param{
+		{Name: "user_id", Type: "integer", Description: "Events of or caused by the user"},
+		{Name: "username", Type: "string"},
+		{Name: "type", Type: "string", Description: "Comma-separated event types, e.g. login_failed,account_locked"},
+		{Name: "ip", Type: "string", Description: "Client IP address"},
+	}
+	exportFormat      = Param{Name: "format", Type: "string", Description: "json (default) or csv"}
+	environmentFilter = Param{Name: "environment", Type: "string", Description: "Environment of the services, or of their diagram for services without one"}
+)
+
+func withParams(groups ...[]Param) []Param {
+	var params []Param
+	for _, group := range groups {
+		params = append(params, group...)
+	}
+	return params
+}
+
+// Catalogue documents every route registered in main.go
+var Catalogue = []Operation{
+	// Documentation and infrastructure
+	{Method: http.MethodGet, Path: "/api/openapi.json", Summary: "OpenAPI document", Tag: "meta", Response: map[string]interface{}{}},
+	{Method: http.MethodGet, Path: "/ws", Summary: "Live status updates", Tag: "meta",
+		Description: "Upgrades to a WebSocket that streams Envelope messages as JSON; see /api/ws/message-types. Authenticate with ?token= or a first {\"type\": \"auth\", \"token\": \"...\"} message. Send {\"type\": \"subscribe\", \"diagram_ids\": [...]} to receive only the updates of those diagrams, and \"unsubscribe\" to drop some. {\"type\": \"filter\", \"service_ids\": [...], \"tags\": [...], \"changes_only\": true} narrows them down to some services, or to status changes.", Response: models.Envelope{}, Status: http.StatusSwitchingProtocols},
+	{Method: http.MethodGet, Path: "/api/ws/message-types", Summary: "WebSocket message types", Tag: "meta",
+		Description: "Every type of message sent over /ws, each with an example of its data.", Response: []models.MessageType{}},
+	{Method: http.MethodGet, Path: "/.well-known/jwks.json", Summary: "Token signing keys", Tag: "meta",
+		Description: "JSON Web Key Set for verifying RS256/EdDSA access tokens; empty while tokens use HS256.", Response: middleware.JSONWebKeySet{}},
+	{Method: http.MethodGet, Path: "/metrics", Summary: "Prometheus metrics", Tag: "meta", Auth: AuthMetrics,
+		Response: "", Produces: []string{"text/plain"}},
+	{Method: http.MethodGet, Path: "/grafana/", Summary: "Grafana datasource connection test", Tag: "metrics", Auth: AuthMetrics,
+		Description: "Base URL of a Grafana JSON (SimpleJSON) datasource; Infinity can query the same routes.", Response: Object{"status": ""}},
+	{Method: http.MethodPost, Path: "/grafana/search", Summary: "List Grafana metrics", Tag: "metrics", Auth: AuthMetrics,
+		Description: "Metrics of every service whose name, diagram or metric contains target. Values are <metric>:<service ID>, with uptime, latency, latency_p50, latency_p95 and latency_p99 as metrics.",
+		Request:     models.GrafanaSearchRequest{}, Response: []models.GrafanaMetric{}},
+	{Method: http.MethodPost, Path: "/grafana/query", Summary: "Query Grafana series", Tag: "metrics", Auth: AuthMetrics,
+		Description: "Uptime percentage or response times in milliseconds of services over the range, bucketed by intervalMs (at least a minute) within maxDataPoints. Targets of type table are answered as tables.",
+		Request:     models.GrafanaQueryRequest{}, Response: []models.GrafanaSeries{}},
+	{Method: http.MethodPost, Path: "/grafana/annotations", Summary: "Status changes as Grafana annotations", Tag: "metrics", Auth: AuthMetrics,
+		Description: "Status changes within the range, of every service or of the service:<id> or diagram:<id> given as the annotation query. Outages and degradations that ended are regions.",
+		Request:     models.GrafanaAnnotationRequest{}, Response: []models.GrafanaAnnotation{}},
+	{Method: http.MethodGet, Path: "/ping/:key", Summary: "Report a successful run of a heartbeat service", Tag: "heartbeats",
+		Description: "Compatible with healthchecks.io ping URLs; HEAD and POST work too, and a POSTed body, such as the job's output, is kept. The key is the service's ping_key. Answers 404 for unknown keys.",
+		Response:    "", Produces: []string{"text/plain"}},
+	{Method: http.MethodPost, Path: "/ping/:key", Summary: "Report a successful run of a heartbeat service with its output", Tag: "heartbeats",
+		Response: "", Produces: []string{"text/plain"}},
+	{Method: http.MethodGet, Path: "/ping/:key/:signal", Summary: "Report a signal of a heartbeat service", Tag: "heartbeats",
+		Description: "signal is start when a run begins, fail when it failed, log to only attach the body, or the exit status of the run, a failure unless 0. HEAD and POST work too.",
+		Response:    "", Produces: []string{"text/plain"}},
+	{Method: http.MethodPost, Path: "/ping/:key/:signal", Summary: "Report a signal of a heartbeat service with its output", Tag: "heartbeats",
+		Response: "", Produces: []string{"text/plain"}},
+	{Method: http.MethodPost, Path: "/api/alertmanager", Summary: "Receive Prometheus Alertmanager notifications", Tag: "alerts", Auth: AuthAlertmanager,
+		Description: "Webhook receiver for Alertmanager, enabled by ALERTMANAGER_TOKEN. Firing alerts make the services their labels match under ALERTMANAGER_LABELS degraded, or dead when the severity label is critical, until they are resolved.",
+		Request:     models.AlertmanagerWebhook{}, Response: models.AlertmanagerResult{}},
+	{Method: http.MethodPost, Path: "/api/register", Summary: "Register a service from a deployment pipeline", Tag: "registration", Auth: AuthRegistration,
+		Description: "Creates the node named name in diagram_id, which a token limited to one diagram may leave out, or updates the registered fields of the node already there; position, connections and other settings are left alone. Registering an unchanged service changes nothing, so pipelines can register on every deploy. Answers 201 when the node is created and 403 when the token is limited to another diagram.",
+		Request:     models.RegistrationRequest{}, Response: models.RegistrationResponse{}},
+	{Method: http.MethodGet, Path: "/healthz", Summary: "Liveness probe", Tag: "meta",
+		Description: "Answers as long as the process serves requests.", Response: Object{"status": ""}},
+	{Method: http.MethodGet, Path: "/readyz", Summary: "Readiness probe", Tag: "meta",
+		Description: "Answers 503 unless the database is reachable and the healthcheck scheduler and WebSocket hub are running.",
+		Response:    Object{"status": "", "checks": map[string]string{}}},
+	{Method: http.MethodGet, Path: "/api/system/health", Summary: "Backend and component health", Tag: "meta", Auth: AuthAdmin,
+		Response: models.SystemHealth{}},
+	{Method: http.MethodGet, Path: "/api/system/scheduler", Summary: "Healthcheck scheduler internals", Tag: "meta", Auth: AuthAdmin,
+		Description: "Checks per second over the last minute, check counts, failures and duration histograms by method, the worker queue (including checks postponed because it was full), dropped status updates and disconnected WebSocket clients.",
+		Response: Object{"checks_executed": map[string]int{}, "checks_in_flight": 0, "checks_per_second": 0.0,
+			"methods": map[string]Object{}, "queue": Object{}, "leader": false, "loop": Object{},
+			"broadcast_dropped": 0, "clients_dropped": 0, "connected_clients": 0}},
+	{Method: http.MethodGet, Path: "/api/system/config", Summary: "Effective configuration", Tag: "meta", Auth: AuthAdmin,
+		Description: "Every setting by section with its environment variable and source (default, file or env). Secrets are redacted.",
+		Response:    Object{"file": "", "settings": map[string]map[string]config.Entry{}}},
+
+	// Authentication
+	{Method: http.MethodPost, Path: "/api/login", Summary: "Log in", Tag: "auth",
+		Description: "Repeated failed logins lock the account; locked accounts answer 423 with Retry-After.", Request: models.LoginRequest{}, Response: models.LoginResponse{}},
+	{Method: http.MethodPost, Path: "/api/token/refresh", Summary: "Refresh the access token", Tag: "auth",
+		Description: "Exchanges a single-use refresh token for a new token pair. Reusing a refresh token revokes its whole session.", Request: models.RefreshTokenRequest{}, Response: models.LoginResponse{}},
+	{Method: http.MethodPost, Path: "/api/first-run-admin", Summary: "Create the first admin user", Tag: "auth",
+		Description: "Only allowed while no users exist.", Request: models.FirstRunAdminRequest{}, Response: models.FirstRunAdminResponse{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/invitations/accept", Summary: "Show an invitation", Tag: "auth",
+		Description: "For the page accepting the invitation; unknown, expired and accepted invitations answer 404.",
+		Query:       []Param{{Name: "token", Type: "string", Description: "Token from the invitation link"}},
+		Response:    Object{"username": "", "email": "", "role": "", "organization": "", "expires_at": ""}},
+	{Method: http.MethodPost, Path: "/api/invitations/accept", Summary: "Accept an invitation", Tag: "auth",
+		Description: "Creates the invited account with the chosen password, which verifies its email address, and logs it in.",
+		Request:     models.AcceptInvitationRequest{}, Response: models.LoginResponse{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/auth/oidc", Summary: "Single sign-on configuration", Tag: "auth", Response: models.OIDCConfig{}},
+	{Method: http.MethodGet, Path: "/api/auth/oidc/login", Summary: "Start a single sign-on login", Tag: "auth", Status: http.StatusFound,
+		Description: "Redirects the browser to the OpenID Connect provider (authorization code flow with PKCE)."},
+	{Method: http.MethodGet, Path: "/api/auth/oidc/callback", Summary: "Complete a single sign-on login", Tag: "auth", Status: http.StatusFound,
+		Description: "Redirect target of the provider. Sends the browser to OIDC_POST_LOGIN_URL with token, refresh_token and expires_in, or error, in the URL fragment.", Query: []Param{{Name: "code", Type: "string", Description: "Authorization code"}, {Name: "state", Type: "string", Description: "Login state"}}},
+	{Method: http.MethodGet, Path: "/api/user/me", Summary: "Current user", Tag: "auth", Auth: AuthUser, Response: models.User{}},
+	{Method: http.MethodPut, Path: "/api/user/me", Summary: "Update the current user's profile", Tag: "auth", Auth: AuthUser,
+		Request: models.UpdateProfileRequest{}, Response: models.User{}},
+	{Method: http.MethodPost, Path: "/api/user/me/password", Summary: "Change the current user's password", Tag: "auth", Auth: AuthUser,
+		Description: "Requires the current password. Logs out every session of the user and returns a new token pair for this client.",
+		Request:     models.ChangePasswordRequest{}, Response: models.LoginResponse{}},
+	{Method: http.MethodGet, Path: "/api/user/me/notification-preferences", Summary: "Notification preferences of the current user", Tag: "auth", Auth: AuthUser,
+		Description: "How the alerts of the services the user owns reach them personally; every alert by email until set.", Response: models.NotificationPreferences{}},
+	{Method: http.MethodPut, Path: "/api/user/me/notification-preferences", Summary: "Update the current user's notification preferences", Tag: "auth", Auth: AuthUser,
+		Description: "Alerts below min_severity are dropped, and alerts during the quiet hours (HH:MM in timezone) held back unless critical ones are allowed. Shared and team channels are not affected.",
+		Request:     models.NotificationPreferences{}, Response: models.NotificationPreferences{}},
+	{Method: http.MethodPost, Path: "/api/logout", Summary: "Log out", Tag: "auth", Auth: AuthUser,
+		Description: "Revokes the access token used for the request and its session. The body is optional.", Request: models.LogoutRequest{}},
+	{Method: http.MethodGet, Path: "/api/user/sessions", Summary: "Active sessions of the current user", Tag: "auth", Auth: AuthUser,
+		Description: "One entry per login, with the device, IP address and user agent it was last used from.", Response: []models.Session{}},
+	{Method: http.MethodDelete, Path: "/api/user/sessions/:id", Summary: "Revoke a session of the current user", Tag: "auth", Auth: AuthUser,
+		Description: "The session's refresh and access tokens stop working."},
+
+	// Users
+	{Method: http.MethodPost, Path: "/api/users", Summary: "Create a user", Tag: "users", Auth: AuthAdmin,
+		Request: models.RegisterRequest{}, Response: models.User{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/users", Summary: "List users", Tag: "users", Auth: AuthAdmin, Response: []models.User{}},
+	{Method: http.MethodPut, Path: "/api/users/:id", Summary: "Update a user", Tag: "users", Auth: AuthAdmin,
+		Request: UpdateUserRequest{}, Response: models.User{}},
+	{Method: http.MethodDelete, Path: "/api/users/:id", Summary: "Delete a user", Tag: "users", Auth: AuthAdmin},
+	{Method: http.MethodPost, Path: "/api/users/:id/revoke-sessions", Summary: "Revoke all sessions of a user", Tag: "users", Auth: AuthAdmin,
+		Description: "Every access and refresh token issued to the user so far stops working."},
+	{Method: http.MethodGet, Path: "/api/users/:id/sessions", Summary: "Active sessions of a user", Tag: "users", Auth: AuthAdmin, Response: []models.Session{}},
+	{Method: http.MethodDelete, Path: "/api/users/:id/sessions/:sessionId", Summary: "Revoke a session of a user", Tag: "users", Auth: AuthAdmin},
+	{Method: http.MethodPost, Path: "/api/users/:id/unlock", Summary: "Unlock a user locked out by failed logins", Tag: "users", Auth: AuthAdmin,
+		Description: "Also clears the user's failed login count."},
+	{Method: http.MethodPost, Path: "/api/invitations", Summary: "Invite a user", Tag: "users", Auth: AuthAdmin,
+		Description: "Emails a link to accept the invitation to the current organization through the first enabled email channel; answers 409 without one or without PUBLIC_URL, which the link is built from. The account is only created once accepted.",
+		Request:     models.InvitationRequest{}, Response: models.Invitation{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/invitations", Summary: "Pending invitations", Tag: "users", Auth: AuthAdmin, Response: []models.Invitation{}},
+	{Method: http.MethodPost, Path: "/api/invitations/:id/resend", Summary: "Resend an invitation", Tag: "users", Auth: AuthAdmin,
+		Description: "Emails a new link, valid for another INVITATION_TTL; the previous link stops working. Answers 409 without PUBLIC_URL.", Response: models.Invitation{}},
+	{Method: http.MethodDelete, Path: "/api/invitations/:id", Summary: "Revoke an invitation", Tag: "users", Auth: AuthAdmin},
+
+	// Organizations
+	{Method: http.MethodGet, Path: "/api/organizations", Summary: "List the current user's organizations", Tag: "organizations", Auth: AuthUser,
+		Description: "Each organization carries the user's role in it. Admins see every organization.", Response: []models.Organization{}},
+	{Method: http.MethodPost, Path: "/api/organizations", Summary: "Create an organization", Tag: "organizations", Auth: AuthAdmin,
+		Description: "The creating admin becomes the organization's first admin.", Request: models.Organization{}, Response: models.Organization{}, Status: http.StatusCreated},
+	{Method: http.MethodPut, Path: "/api/organizations/:id", Summary: "Rename an organization", Tag: "organizations", Auth: AuthOrgAdmin,
+		Request: models.Organization{}, Response: models.Organization{}},
+	{Method: http.MethodDelete, Path: "/api/organizations/:id", Summary: "Delete an empty organization", Tag: "organizations", Auth: AuthAdmin,
+		Description: "Fails with 409 while the organization owns diagrams or folders, including those in the trash."},
+	{Method: http.MethodGet, Path: "/api/organizations/:id/members", Summary: "List the members of an organization", Tag: "organizations", Auth: AuthUser,
+		Response: []models.OrganizationMember{}},
+	{Method: http.MethodPut, Path: "/api/organizations/:id/members/:userId", Summary: "Add a member or change their role", Tag: "organizations", Auth: AuthOrgAdmin,
+		Description: "Fails with 409 when it would leave the organization without an admin.", Request: models.OrganizationMemberRequest{}, Response: models.OrganizationMember{}},
+	{Method: http.MethodDelete, Path: "/api/organizations/:id/members/:userId", Summary: "Remove a member", Tag: "organizations", Auth: AuthOrgAdmin,
+		Description: "Fails with 409 when it would leave the organization without an admin."},
+
+	// Search
+	{Method: http.MethodGet, Path: "/api/search", Summary: "Search diagrams and services", Tag: "search", Auth: AuthUser,
+		Query:    []Param{{Name: "q", Type: "string", Description: "Search term", Required: true}, {Name: "limit", Type: "integer", Description: "Maximum number of results"}},
+		Response: Object{"query": "", "results": []models.SearchResult{}}},
+	{Method: http.MethodGet, Path: "/api/errors", Summary: "Search healthcheck errors by signature", Tag: "search", Auth: AuthUser,
+		Description: "Errors of healthcheck results grouped by signature, the error with numbers and IDs replaced by placeholders, the most frequent first. Non-admins only search the services of public diagrams. The range defaults to the last week.",
+		Query: withParams([]Param{
+			{Name: "q", Type: "string", Description: "Substring of the error, case insensitive"},
+			{Name: "service", Type: "string", Description: "Comma-separated service IDs"},
+		}, timeRange, pagination),
+		Response: Object{"errors": []models.ErrorSignature{}, "total": 0, "limit": 0, "offset": 0}},
+
+	// Diagrams
+	{Method: http.MethodPost, Path: "/api/diagrams", Summary: "Create a diagram", Tag: "diagrams", Auth: AuthUser,
+		Description: "Diagrams created in a folder take the folder's default_public unless public is given.",
+		Request:     models.Diagram{}, Response: models.Diagram{}, Status: http.StatusCreated},
+	{Method: http.MethodPost, Path: "/api/diagrams/import", Summary: "Import a diagram", Tag: "diagrams", Auth: AuthUser,
+		Description: "Creates a diagram from the JSON export of GET /api/export/diagrams/{id} in one transaction; nothing is created if any item fails. Services get new IDs and the connections are re-created between them. Validation failures are listed per item under errors in the 400 response.",
+		Request:     models.DiagramExport{}, Response: models.DiagramExport{}, Status: http.StatusCreated},
+	{Method: http.MethodPost, Path: "/api/import/uptime-kuma", Summary: "Import Uptime Kuma monitors", Tag: "services", Auth: AuthUser,
+		Description: "Creates services on diagram_id from the monitors of an Uptime Kuma backup, placed below its nodes. HTTP, keyword and JSON query monitors become HTTP(S) checks, port TCP, ping ICMP, and DNS, gRPC, database, Redis and Kafka monitors their equivalent checks. Paused monitors become paused services. Other monitors are listed under skipped with the reason; the rest are created in one transaction.",
+		Request:     models.UptimeKumaImportRequest{}, Response: models.MonitorImportResult{}, Status: http.StatusCreated},
+	{Method: http.MethodPost, Path: "/api/import/uptime-robot", Summary: "Import Uptime Robot monitors", Tag: "services", Auth: AuthUser,
+		Description: "Fetches the monitors of the Uptime Robot account of api_key and creates services on diagram_id from them, like the Uptime Kuma import. Heartbeat monitors are skipped. The key is not stored.",
+		Request:     models.UptimeRobotImportRequest{}, Response: models.MonitorImportResult{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/diagrams", Summary: "List diagrams", Tag: "diagrams", Auth: AuthUser,
+		Description: "Non-admin users only see public diagrams. The total number of matches is returned in the X-Total-Count header.",
+		Query: withParams([]Param{
+			{Name: "q", Type: "string", Description: "Name filter"},
+			{Name: "public", Type: "boolean"},
+			{Name: "folder_id", Type: "string", Description: "Folder ID, or none for diagrams outside any folder"},
+			{Name: "recursive", Type: "boolean", Description: "Include diagrams in nested folders"},
+			{Name: "filter", Type: "string", Description: "starred for the user's favorites, recent for the diagrams they viewed last, latest first"},
+			{Name: "environment", Type: "string", Description: "Environment the diagram is labelled with"},
+		}, ownerFilter, listOptions),
+		Response: []models.Diagram{}},
+	{Method: http.MethodGet, Path: "/api/diagrams/:id", Summary: "Get a diagram with its services and connections", Tag: "diagrams",
+		Description: "Public diagrams can be read without a token; private ones only with the token of a member of their organization, and are otherwise answered with 404. With a token, the diagram is added to the user's recently viewed diagrams. ?environment= returns the diagram as that environment sees it: its services there, with their overlays for it standing in for the others at their nodes and connections.",
+		Query:       []Param{{Name: "environment", Type: "string", Description: "Environment to view the diagram in"}},
+		Response:    Object{"diagram": models.Diagram{}, "services": []models.Service{}, "connections": []models.Connection{}}},
+	{Method: http.MethodGet, Path: "/api/diagrams/:id/snapshot.svg", Summary: "Render a diagram as SVG", Tag: "diagrams",
+		Description: "Nodes are colored by service status and edges by connection status, for embedding in wikis and chat. Public diagrams are rendered for anyone, private ones only with the token of a member of their organization.", Produces: []string{"image/svg+xml"}},
+	{Method: http.MethodGet, Path: "/api/diagrams/:id/snapshot.png", Summary: "Render a diagram as PNG", Tag: "diagrams",
+		Description: "Same picture as the SVG snapshot, rasterized.", Produces: []string{"image/png"}},
+	{Method: http.MethodGet, Path: "/api/diagrams/:id/thumbnail", Summary: "Get a thumbnail of a diagram's layout", Tag: "diagrams",
+		Description: "A small picture of the layout without labels or statuses, for the diagram list. It is rendered again once services move or are connected differently; its ETag is the hash of that layout. Private diagrams only have thumbnails for members of their organization.",
+		Query:       []Param{{Name: "format", Type: "string", Description: "svg (default) or png"}},
+		Produces:    []string{"image/svg+xml", "image/png"}},
+	{Method: http.MethodPut, Path: "/api/diagrams/:id/service-defaults", Summary: "Set healthcheck defaults for a diagram's services", Tag: "diagrams", Auth: AuthUser,
+		Description: "Services inherit polling_interval, request_timeout, ssl_verify and headers from these defaults unless they list the field in overrides. Omitted fields are not defaulted.", Request: models.ServiceDefaults{}, Response: models.Diagram{}},
+	{Method: http.MethodPut, Path: "/api/diagrams/:id", Summary: "Update a diagram", Tag: "diagrams", Auth: AuthUser,
+		Description: "Replaces the diagram; omitted fields are cleared. Use PATCH to change some of them.",
+		Request:     models.Diagram{}, Response: models.Diagram{}},
+	{Method: http.MethodPatch, Path: "/api/diagrams/:id", Summary: "Change some fields of a diagram", Tag: "diagrams", Auth: AuthUser,
+		Description: "The body is a JSON Merge Patch (RFC 7396) of the diagram: only the fields it has are changed, and null clears one. The result is validated like a PUT.",
+		Request:     models.Diagram{}, Response: models.Diagram{}},
+	{Method: http.MethodDelete, Path: "/api/diagrams/:id", Summary: "Move a diagram to the trash", Tag: "diagrams", Auth: AuthUser,
+		Description: "The diagram and its services can be restored until the trash is purged."},
+	{Method: http.MethodPost, Path: "/api/diagrams/:id/star", Summary: "Star a diagram", Tag: "diagrams", Auth: AuthUser,
+		Description: "Adds the diagram to the current user's favorites, listed by GET /api/diagrams?filter=starred.", Response: Object{"diagram_id": 0, "starred": true}},
+	{Method: http.MethodDelete, Path: "/api/diagrams/:id/star", Summary: "Unstar a diagram", Tag: "diagrams", Auth: AuthUser,
+		Response: Object{"diagram_id": 0, "starred": false}},
+	{Method: http.MethodPost, Path: "/api/diagrams/:id/positions", Summary: "Save service positions", Tag: "diagrams", Auth: AuthUser,
+		Request: SavePositionsRequest{}},
+	{Method: http.MethodGet, Path: "/api/diagrams/:id/uptime", Summary: "Diagram uptime", Tag: "uptime", Auth: AuthUser,
+		Query: uptimeWindow, Response: Object{"aggregate": models.UptimeSummary{}, "services": []models.UptimeSummary{}}},
+	{Method: http.MethodGet, Path: "/api/diagrams/:id/events", Summary: "Status changes of a diagram's services", Tag: "events", Auth: AuthUser,
+		Query: withParams([]Param{environmentFilter}, timeRange, pagination), Response: Object{"events": []models.StatusEvent{}, "total": 0, "limit": 0, "offset": 0}},
+	{Method: http.MethodGet, Path: "/api/diagrams/:id/activity", Summary: "Activity feed of a diagram", Tag: "events", Auth: AuthUser,
+		Description: "Structural edits, status changes and alerts of its services and started maintenance windows in one feed, newest first.",
+		Query:       withParams([]Param{{Name: "type", Type: "string", Description: "Comma-separated activity types: edit, status_change, alert, maintenance"}}, timeRange, pagination),
+		Response:    Object{"activity": []models.Activity{}, "total": 0, "limit": 0, "offset": 0}},
+
+	// Diagram versions
+	{Method: http.MethodGet, Path: "/api/diagrams/:id/versions", Summary: "List diagram versions", Tag: "versions", Auth: AuthUser,
+		Response: []models.DiagramVersion{}},
+	{Method: http.MethodGet, Path: "/api/diagrams/:id/versions/diff", Summary: "Compare two diagram versions", Tag: "versions", Auth: AuthUser,
+		Query: []Param{
+			{Name: "from", Type: "integer", Description: "Base version, defaults to the one before to"},
+			{Name: "to", Type: "integer", Description: "Target version, defaults to the latest"},
+		},
+		Response: models.DiagramDiff{}},
+	{Method: http.MethodGet, Path: "/api/diagrams/:id/versions/:version", Summary: "Get a diagram version", Tag: "versions", Auth: AuthUser,
+		Response: models.DiagramVersion{}},
+	{Method: http.MethodPost, Path: "/api/diagrams/:id/rollback/:version", Summary: "Restore a diagram version", Tag: "versions", Auth: AuthUser,
+		Description: "Restores the diagram to the given version and records the result as a new version.", Response: models.DiagramVersion{}},
+
+	// Status pages and incidents
+	{Method: http.MethodGet, Path: "/api/status-pages/:slug", Summary: "Public status page", Tag: "status-pages",
+		Query: []Param{environmentFilter}, Response: models.StatusPage{}},
+	{Method: http.MethodPut, Path: "/api/diagrams/:id/status-page", Summary: "Configure a diagram's status page", Tag: "status-pages", Auth: AuthUser,
+		Description: "Only the diagram's owner and organization admins can publish or change its status page.",
+		Request:     models.StatusPageSettings{}, Response: models.StatusPageSettings{}},
+	{Method: http.MethodPost, Path: "/api/diagrams/:id/incidents", Summary: "Create an incident", Tag: "incidents", Auth: AuthUser,
+		Description: "Incidents show on the diagram's status page, so only its owner and organization admins post, edit and delete them.",
+		Request:     models.Incident{}, Response: models.Incident{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/diagrams/:id/incidents", Summary: "List incidents", Tag: "incidents", Auth: AuthUser,
+		Query: []Param{{Name: "active", Type: "boolean", Description: "Only unresolved incidents"}}, Response: []models.Incident{}},
+	{Method: http.MethodPut, Path: "/api/incidents/:id", Summary: "Update an incident", Tag: "incidents", Auth: AuthUser,
+		Description: "Only the diagram's owner and organization admins can.",
+		Request:     models.Incident{}, Response: models.Incident{}},
+	{Method: http.MethodDelete, Path: "/api/incidents/:id", Summary: "Delete an incident", Tag: "incidents", Auth: AuthUser,
+		Description: "Only the diagram's owner and organization admins can."},
+	{Method: http.MethodGet, Path: "/api/incidents/:id/root-cause", Summary: "Probable root cause of an incident", Tag: "incidents", Auth: AuthUser,
+		Description: "Ranks the services of the incident's diagram that failed from 15 minutes before it started until it was resolved, the services the others depend on and that failed first ranking highest.",
+		Response:    models.RootCauseAnalysis{}},
+
+	// Services
+	{Method: http.MethodGet, Path: "/api/services/diagram/:diagramId", Summary: "List a diagram's services", Tag: "services",
+		Description: "Public diagrams can be read without a token, private ones only with a member's. The total number of matches is returned in the X-Total-Count header.",
+		Query: withParams([]Param{
+			{Name: "q", Type: "string", Description: "Name filter"},
+			{Name: "status", Type: "string", Description: "Comma-separated statuses"},
+			{Name: "type", Type: "string", Description: "Comma-separated service types"},
+			{Name: "tag", Type: "string", Description: "Exact tag"},
+			environmentFilter,
+		}, ownerFilter, listOptions),
+		Response: []models.Service{}},
+	{Method: http.MethodGet, Path: "/api/services", Summary: "List the organization's services", Tag: "services", Auth: AuthUser,
+		Description: "Lists the services of every diagram of the organization; non-admin users only see those of public diagrams. Owner filters match the diagram's owner for services without their own. The total number of matches is returned in the X-Total-Count header.",
+		Query: withParams([]Param{
+			{Name: "q", Type: "string", Description: "Name filter"},
+			{Name: "status", Type: "string", Description: "Comma-separated statuses"},
+			{Name: "type", Type: "string", Description: "Comma-separated service types"},
+			{Name: "tag", Type: "string", Description: "Exact tag"},
+			environmentFilter,
+		}, ownerFilter, listOptions),
+		Response: []models.Service{}},
+	{Method: http.MethodPost, Path: "/api/services", Summary: "Create a service", Tag: "services", Auth: AuthUser,
+		Description: "An incoherent healthcheck configuration, such as an HTTP check without healthcheck_url or a UDP check without udp_send_data, is rejected with a 400 response listing the fields at fault under fields, each with its field and message.",
+		Request:     models.Service{}, Response: models.Service{}, Status: http.StatusCreated},
+	{Method: http.MethodPost, Path: "/api/services/bulk", Summary: "Create services in one transaction", Tag: "services", Auth: AuthUser,
+		Description: "Validation failures are listed per item under errors in the 400 response, with the fields at fault in an invalid healthcheck configuration under fields.", Request: []models.Service{}, Response: []models.Service{}, Status: http.StatusCreated},
+	{Method: http.MethodPut, Path: "/api/services/bulk", Summary: "Update services in one transaction", Tag: "services", Auth: AuthUser,
+		Description: "Every item needs an id. Validation failures are listed per item under errors in the 400 response.", Request: []models.Service{}, Response: []models.Service{}},
+	{Method: http.MethodPost, Path: "/api/services/copy", Summary: "Copy services into another diagram", Tag: "services", Auth: AuthUser,
+		Description: "Copies every service in service_ids in one transaction. With connections, the connections among them are re-created between the copies.", Request: models.ServiceCopyRequest{}, Response: []models.Service{}, Status: http.StatusCreated},
+	{Method: http.MethodPost, Path: "/api/services/move", Summary: "Move services into another diagram", Tag: "services", Auth: AuthUser,
+		Description: "Moves every service in service_ids in one transaction. Connections among them move too; connections to services left behind are deleted.", Request: models.ServiceCopyRequest{}, Response: []models.Service{}},
+	{Method: http.MethodPost, Path: "/api/services/delete", Summary: "Move services to the trash in one transaction", Tag: "services", Auth: AuthUser,
+		Description: "Moves every service in service_ids to the trash, or none when one is missing; failures are listed per item under errors in the 400 response.", Request: models.ServiceIDsRequest{}, Response: Object{"message": "", "deleted": 0}},
+	{Method: http.MethodPost, Path: "/api/services/pause", Summary: "Pause the checks of services in one transaction", Tag: "services", Auth: AuthUser,
+		Description: "Paused services are not checked until resumed.", Request: models.ServiceIDsRequest{}, Response: []models.Service{}},
+	{Method: http.MethodPost, Path: "/api/services/resume", Summary: "Resume the checks of services in one transaction", Tag: "services", Auth: AuthUser,
+		Request: models.ServiceIDsRequest{}, Response: []models.Service{}},
+	{Method: http.MethodPut, Path: "/api/services/:id", Summary: "Update a service", Tag: "services", Auth: AuthUser,
+		Description: "Replaces the service; omitted fields are cleared, except credentials, overrides and paused, which are kept. Services are returned with their header values masked as ********; a header written back with that value keeps its stored one. Use PATCH to change some fields. The healthcheck configuration is validated as on creation.",
+		Request:     models.Service{}, Response: models.Service{}},
+	{Method: http.MethodPatch, Path: "/api/services/:id", Summary: "Change some fields of a service", Tag: "services", Auth: AuthUser,
+		Description: "The body is a JSON Merge Patch (RFC 7396) of the service, such as {\"polling_interval\": 60}: only the fields it has are changed, null clears one, and objects such as headers are merged key by key. Credentials are kept unless the patch sets them; {} removes them. The result is validated like a PUT.",
+		Request:     models.Service{}, Response: models.Service{}},
+	{Method: http.MethodDelete, Path: "/api/services/:id", Summary: "Move a service to the trash", Tag: "services", Auth: AuthUser},
+	{Method: http.MethodPost, Path: "/api/services/:id/copy", Summary: "Copy a service into another diagram", Tag: "services", Auth: AuthUser,
+		Request: models.ServiceCopyRequest{}, Response: models.Service{}, Status: http.StatusCreated},
+	{Method: http.MethodPost, Path: "/api/services/:id/clone", Summary: "Clone a service", Tag: "services", Auth: AuthUser,
+		Description: "Creates a copy with the full healthcheck configuration and credentials, in the same diagram or in diagram_id, optionally with another name, host, port or healthcheck URL. The body may be omitted. Connections are not cloned.",
+		Request:     models.ServiceCloneRequest{}, Response: models.Service{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/services/:id/heartbeat", Summary: "Get the ping URL of a heartbeat service", Tag: "heartbeats", Auth: AuthUser,
+		Description: "Answers 404 until the heartbeat is configured. last_signal tells how the last run finished.", Response: models.Heartbeat{}},
+	{Method: http.MethodPut, Path: "/api/services/:id/heartbeat", Summary: "Configure a heartbeat service", Tag: "heartbeats", Auth: AuthUser,
+		Description: "For services with the HEARTBEAT healthcheck method: the job is expected every period seconds and may be late, or run, for grace seconds more before the service is down. The first call creates the ping URL, which stays the same afterwards.",
+		Request:     models.HeartbeatRequest{}, Response: models.Heartbeat{}},
+	{Method: http.MethodGet, Path: "/api/services/:id/script", Summary: "Get the check script of a script service", Tag: "scripts", Auth: AuthUser,
+		Description: "Answers 404 until the check script is set.", Response: models.ServiceScript{}},
+	{Method: http.MethodPut, Path: "/api/services/:id/script", Summary: "Set the check script of a script service", Tag: "scripts", Auth: AuthUser,
+		Description: "For services with the SCRIPT healthcheck method: the script runs with args as its arguments at every check.",
+		Request:     models.ServiceScriptRequest{}, Response: models.ServiceScript{}},
+	{Method: http.MethodPost, Path: "/api/services/:id/move", Summary: "Move a service into another diagram", Tag: "services", Auth: AuthUser,
+		Description: "Connections to services left in the old diagram are deleted.", Request: models.ServiceCopyRequest{}, Response: models.Service{}},
+	{Method: http.MethodPost, Path: "/api/services/:id/icon", Summary: "Upload a service icon", Tag: "services", Auth: AuthUser,
+		Description: "The image is scaled down and kept in blob storage; icon is the URL it is served from.", Multipart: []string{"icon"}, Response: Object{"message": "", "icon": ""}},
+	{Method: http.MethodGet, Path: "/api/services/:id/icon", Summary: "Uploaded service icon", Tag: "services",
+		Description: "Responses to the URL found in the service's icon may be cached for good.", Query: []Param{{Name: "v", Type: "string", Description: "Hash of the image, changing along with it"}}, Produces: []string{"image/png", "image/jpeg", "image/gif", "image/webp"}},
+	{Method: http.MethodGet, Path: "/api/services/:id/uptime", Summary: "Service uptime", Tag: "uptime", Auth: AuthUser,
+		Query: uptimeWindow, Response: models.UptimeSummary{}},
+	{Method: http.MethodGet, Path: "/api/services/:id/metrics", Summary: "Response time and availability buckets", Tag: "metrics", Auth: AuthUser,
+		Query:    withParams(timeRange, []Param{{Name: "step", Type: "string", Description: "Bucket size, e.g. 5m, 1h or 1d"}}),
+		Response: Object{"service_id": 0, "from": "", "to": "", "step": "", "buckets": []models.MetricsBucket{}}},
+	{Method: http.MethodGet, Path: "/api/services/:id/results", Summary: "Raw healthcheck results", Tag: "metrics", Auth: AuthUser,
+		Query:    withParams(timeRange, []Param{{Name: "status", Type: "string", Description: "Comma-separated statuses"}}, pagination),
+		Response: Object{"results": []models.HealthcheckResult{}, "total": 0, "limit": 0, "offset": 0}},
+	{Method: http.MethodGet, Path: "/api/services/:id/rollups", Summary: "Hourly or daily healthcheck rollups", Tag: "metrics", Auth: AuthUser,
+		Query:    withParams([]Param{{Name: "granularity", Type: "string", Description: "hour or day (default)"}}, timeRange),
+		Response: []models.HealthcheckRollup{}},
+	{Method: http.MethodGet, Path: "/api/services/:id/heatmap", Summary: "Uptime heatmap", Tag: "metrics", Auth: AuthUser,
+		Query: []Param{
+			{Name: "granularity", Type: "string", Description: "day (default) or hour"},
+			{Name: "days", Type: "integer", Description: "Number of days for daily heatmaps"},
+			{Name: "hours", Type: "integer", Description: "Number of hours for hourly heatmaps"},
+			{Name: "slo", Type: "number", Description: "Uptime target in percent"},
+		},
+		Response: models.Heatmap{}},
+	{Method: http.MethodGet, Path: "/api/services/:id/events", Summary: "Status changes of a service", Tag: "events", Auth: AuthUser,
+		Query: withParams(timeRange, pagination), Response: Object{"events": []models.StatusEvent{}, "total": 0, "limit": 0, "offset": 0}},
+	{Method: http.MethodGet, Path: "/api/services/:id/stats", Summary: "Outage statistics", Tag: "events", Auth: AuthUser,
+		Query: []Param{{Name: "window", Type: "string", Description: "Lookback window, e.g. 7d"}}, Response: models.OutageStats{}},
+	{Method: http.MethodGet, Path: "/api/services/:id/forecast", Summary: "Forecast certificate expiry, error budget and latency", Tag: "events", Auth: AuthUser,
+		Description: "Projects when the TLS certificate seen by HTTPS checks expires and whether its renewal is late compared with past renewals, the error budget burn rate over 1h, 6h, 24h and 7d and when the budget runs out at the rate of the last day, and the linear trend of the daily average response time. warnings lists what needs attention.",
+		Query:       []Param{{Name: "period", Type: "string", Description: "SLO period of the error budget, e.g. 30d (the default)"}}, Response: models.ServiceForecast{}},
+
+	// Tags
+	{Method: http.MethodGet, Path: "/api/tags", Summary: "Suggest tags", Tag: "tags", Auth: AuthUser,
+		Description: "Tags of the organization's services starting with q, case-insensitively, the most used first.",
+		Query:       []Param{{Name: "q", Type: "string", Description: "Prefix"}, {Name: "limit", Type: "integer", Description: "Maximum number of tags"}},
+		Response:    []models.TagCount{}},
+	{Method: http.MethodPost, Path: "/api/tags/:tag/actions", Summary: "Act on every service carrying a tag", Tag: "tags", Auth: AuthUser,
+		Description: "action is pause, resume or set_interval, which takes polling_interval in seconds and overrides the diagram's default. Paused services are not checked.",
+		Request:     models.TagActionRequest{}, Response: Object{"updated": 0}},
+
+	// Check scripts
+	{Method: http.MethodGet, Path: "/api/check-scripts", Summary: "List check scripts", Tag: "scripts", Auth: AuthUser, Response: []models.CheckScript{}},
+	{Method: http.MethodPost, Path: "/api/check-scripts", Summary: "Upload a check script", Tag: "scripts", Auth: AuthAdmin,
+		Description: "interpreter must be one of SCRIPT_INTERPRETERS. Exit status 0 is alive, 1 degraded, 2 dead and 3 unknown; the first line of the output is the error of failed checks.",
+		Request:     models.CheckScriptRequest{}, Response: models.CheckScript{}, Status: http.StatusCreated},
+	{Method: http.MethodPut, Path: "/api/check-scripts/:id", Summary: "Replace a check script", Tag: "scripts", Auth: AuthAdmin,
+		Description: "Services running the script use the new version from their next check.",
+		Request:     models.CheckScriptRequest{}, Response: models.CheckScript{}},
+	{Method: http.MethodDelete, Path: "/api/check-scripts/:id", Summary: "Delete a check script", Tag: "scripts", Auth: AuthAdmin,
+		Description: "Answers 409 while services run the script."},
+
+	// Environments
+	{Method: http.MethodGet, Path: "/api/environments", Summary: "List environments", Tag: "environments", Auth: AuthUser, Response: []models.Environment{}},
+	{Method: http.MethodPost, Path: "/api/environments", Summary: "Define an environment", Tag: "environments", Auth: AuthAdmin,
+		Description: "name is lowercase letters, digits, - and _. Alerts of the services in the environment are sent with at most max_severity (critical by default), so that staging, say, never pages.",
+		Request:     models.Environment{}, Response: models.Environment{}, Status: http.StatusCreated},
+	{Method: http.MethodPut, Path: "/api/environments/:id", Summary: "Replace an environment", Tag: "environments", Auth: AuthAdmin,
+		Description: "Renaming an environment relabels its diagrams and services.",
+		Request:     models.Environment{}, Response: models.Environment{}},
+	{Method: http.MethodDelete, Path: "/api/environments/:id", Summary: "Delete an environment", Tag: "environments", Auth: AuthAdmin,
+		Description: "Answers 409 while diagrams or services are labelled with it."},
+
+	// Environment overlays
+	{Method: http.MethodGet, Path: "/api/diagrams/:id/overlays", Summary: "List the environment overlays of a diagram's services", Tag: "overlays", Auth: AuthUser,
+		Query: []Param{{Name: "environment", Type: "string", Description: "Only the overlays for this environment"}}, Response: []models.Service{}},
+	{Method: http.MethodGet, Path: "/api/services/:id/overlays", Summary: "List the environment overlays of a service", Tag: "overlays", Auth: AuthUser,
+		Response: []models.Service{}},
+	{Method: http.MethodPut, Path: "/api/services/:id/overlays/:environment", Summary: "Set the overlay of a service for an environment", Tag: "overlays", Auth: AuthUser,
+		Description: "An overlay is the service as deployed in another environment: it shares the service's node, connections and check settings, and follows every change to them, but is checked at its own host, port and healthcheck URL with its own credentials. Overlays are checked, alerted on and kept in history as services of their own, labelled with their environment. A new overlay keeps the service's credentials unless others are given. Answers 201 when the overlay is created.",
+		Request:     models.ServiceOverlayRequest{}, Response: models.Service{}},
+	{Method: http.MethodDelete, Path: "/api/services/:id/overlays/:environment", Summary: "Delete the overlay of a service for an environment", Tag: "overlays", Auth: AuthUser,
+		Description: "Its results and status history are deleted with it."},
+
+	// Connections
+	{Method: http.MethodGet, Path: "/api/connections/diagram/:diagramId", Summary: "List a diagram's connections", Tag: "connections",
+		Description: "Public diagrams can be read without a token, private ones only with a member's.", Response: []models.Connection{}},
+	{Method: http.MethodPost, Path: "/api/connections", Summary: "Create a connection", Tag: "connections", Auth: AuthUser,
+		Request: models.Connection{}, Response: models.Connection{}, Status: http.StatusCreated},
+	{Method: http.MethodPut, Path: "/api/connections/:id", Summary: "Update a connection", Tag: "connections", Auth: AuthUser,
+		Request: models.Connection{}, Response: models.Connection{}},
+	{Method: http.MethodDelete, Path: "/api/connections/:id", Summary: "Delete a connection", Tag: "connections", Auth: AuthUser},
+
+	// Registration tokens
+	{Method: http.MethodPost, Path: "/api/registration-tokens", Summary: "Create a registration token", Tag: "registration", Auth: AuthOrgAdmin,
+		Description: "A token deployment pipelines register their services in the organization with through POST /api/register, in any of its diagrams or only in diagram_id. The token is only returned in this response.",
+		Request:     models.RegistrationToken{}, Response: models.RegistrationToken{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/registration-tokens", Summary: "List registration tokens", Tag: "registration", Auth: AuthOrgAdmin,
+		Response: []models.RegistrationToken{}},
+	{Method: http.MethodDelete, Path: "/api/registration-tokens/:id", Summary: "Revoke a registration token", Tag: "registration", Auth: AuthOrgAdmin},
+
+	// Folders
+	{Method: http.MethodGet, Path: "/api/folders", Summary: "List folders", Tag: "folders", Auth: AuthUser,
+		Response: []models.Folder{}},
+	{Method: http.MethodPost, Path: "/api/folders", Summary: "Create a folder", Tag: "folders", Auth: AuthOrgAdmin,
+		Request: models.Folder{}, Response: models.Folder{}, Status: http.StatusCreated},
+	{Method: http.MethodPut, Path: "/api/folders/:id", Summary: "Update a folder", Tag: "folders", Auth: AuthOrgAdmin,
+		Request: models.Folder{}, Response: models.Folder{}},
+	{Method: http.MethodDelete, Path: "/api/folders/:id", Summary: "Delete an empty folder", Tag: "folders", Auth: AuthOrgAdmin},
+	{Method: http.MethodPut, Path: "/api/diagrams/:id/folder", Summary: "Move a diagram to a folder", Tag: "folders", Auth: AuthUser,
+		Request: models.DiagramFolderRequest{}, Response: models.Diagram{}},
+
+	// Trash
+	{Method: http.MethodGet, Path: "/api/trash", Summary: "List deleted diagrams and services", Tag: "trash", Auth: AuthUser,
+		Response: models.Trash{}},
+	{Method: http.MethodPost, Path: "/api/diagrams/:id/restore", Summary: "Restore a diagram from the trash", Tag: "trash", Auth: AuthUser,
+		Description: "Services deleted together with the diagram are restored with it.", Response: models.Diagram{}},
+	{Method: http.MethodPost, Path: "/api/services/:id/restore", Summary: "Restore a service from the trash", Tag: "trash", Auth: AuthUser,
+		Description: "Fails with 409 while the service's diagram is in the trash.", Response: models.Service{}},
+
+	// Exports
+	{Method: http.MethodGet, Path: "/api/export/results", Summary: "Export healthcheck results", Tag: "export", Auth: AuthUser,
+		Description: "Results of the services of the organization, or of service_id.",
+		Query: withParams([]Param{
+			{Name: "service_id", Type: "integer"},
+			{Name: "status", Type: "string", Description: "Comma-separated statuses: alive, degraded, dead or unknown"},
+			environmentFilter,
+			exportFormat,
+		}, timeRange),
+		Response: []models.HealthcheckResult{}, Produces: []string{"text/csv"}},
+	{Method: http.MethodGet, Path: "/api/export/uptime", Summary: "Export uptime summaries", Tag: "export", Auth: AuthUser,
+		Description: "Summaries of the services of diagram_id, or of every diagram of the organization.",
+		Query: []Param{
+			{Name: "diagram_id", Type: "integer"},
+			{Name: "window", Type: "string", Description: "Lookback window, e.g. 30d"},
+			environmentFilter,
+			exportFormat,
+		},
+		Response: []models.UptimeSummary{}, Produces: []string{"text/csv"}},
+	{Method: http.MethodGet, Path: "/api/export/diagrams/:id", Summary: "Export a diagram", Tag: "export", Auth: AuthUser,
+		Description: "With environment, only the services in the environment and the connections between them are exported.",
+		Query:       []Param{environmentFilter, exportFormat},
+		Response:    models.DiagramExport{}, Produces: []string{"text/csv"}},
+
+	// Maintenance windows
+	{Method: http.MethodPost, Path: "/api/maintenance-windows", Summary: "Schedule a maintenance window", Tag: "maintenance", Auth: AuthUser,
+		Request: models.MaintenanceWindow{}, Response: models.MaintenanceWindow{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/maintenance-windows", Summary: "List maintenance windows", Tag: "maintenance", Auth: AuthUser,
+		Query:    []Param{{Name: "service_id", Type: "integer"}, {Name: "diagram_id", Type: "integer"}},
+		Response: []models.MaintenanceWindow{}},
+	{Method: http.MethodDelete, Path: "/api/maintenance-windows/:id", Summary: "Delete a maintenance window", Tag: "maintenance", Auth: AuthUser},
+
+	// Notification channels
+	{Method: http.MethodPost, Path: "/api/notification-channels", Summary: "Create a notification channel", Tag: "notifications", Auth: AuthAdmin,
+		Request: models.NotificationChannel{}, Response: models.NotificationChannel{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/notification-channels", Summary: "List notification channels", Tag: "notifications", Auth: AuthAdmin,
+		Response: []models.NotificationChannel{}},
+	{Method: http.MethodPut, Path: "/api/notification-channels/:id", Summary: "Update a notification channel", Tag: "notifications", Auth: AuthAdmin,
+		Request: models.NotificationChannel{}, Response: models.NotificationChannel{}},
+	{Method: http.MethodDelete, Path: "/api/notification-channels/:id", Summary: "Delete a notification channel", Tag: "notifications", Auth: AuthAdmin},
+	{Method: http.MethodPost, Path: "/api/notification-channels/:id/test", Summary: "Send a test notification", Tag: "notifications", Auth: AuthAdmin},
+
+	// Result retention
+	{Method: http.MethodGet, Path: "/api/admin/results/stats", Summary: "Healthcheck results table statistics", Tag: "retention", Auth: AuthAdmin,
+		Response: models.ResultsTableStats{}},
+	{Method: http.MethodPost, Path: "/api/admin/results/prune", Summary: "Prune expired healthcheck results", Tag: "retention", Auth: AuthAdmin,
+		Response: Object{"message": "", "deleted": int64(0)}},
+
+	// Reports
+	{Method: http.MethodPost, Path: "/api/reports", Summary: "Create a scheduled report", Tag: "reports", Auth: AuthAdmin,
+		Request: models.Report{}, Response: models.Report{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/reports", Summary: "List scheduled reports", Tag: "reports", Auth: AuthAdmin, Response: []models.Report{}},
+	{Method: http.MethodPut, Path: "/api/reports/:id", Summary: "Update a scheduled report", Tag: "reports", Auth: AuthAdmin,
+		Request: models.Report{}, Response: models.Report{}},
+	{Method: http.MethodDelete, Path: "/api/reports/:id", Summary: "Delete a scheduled report", Tag: "reports", Auth: AuthAdmin},
+	{Method: http.MethodGet, Path: "/api/reports/:id/preview", Summary: "Preview a report", Tag: "reports", Auth: AuthAdmin,
+		Query:    []Param{{Name: "format", Type: "string", Description: "text renders the report body instead of JSON"}},
+		Response: models.ReportSummary{}, Produces: []string{"text/plain"}},
+	{Method: http.MethodPost, Path: "/api/reports/:id/send", Summary: "Send a report now", Tag: "reports", Auth: AuthAdmin},
+
+	// Audit log
+	{Method: http.MethodGet, Path: "/api/audit-log", Summary: "Audit log", Tag: "audit", Auth: AuthAdmin,
+		Query: withParams([]Param{
+			{Name: "user_id", Type: "integer"},
+			{Name: "entity_type", Type: "string", Description: "API resource name, e.g. services"},
+			{Name: "entity_id", Type: "integer"},
+			{Name: "action", Type: "string", Description: "create, update or delete"},
+		}, timeRange, pagination),
+		Response: Object{"entries": []models.AuditEntry{}, "total": 0, "limit": 0, "offset": 0}},
+	{Method: http.MethodGet, Path: "/api/security-events", Summary: "Login and account security events", Tag: "audit", Auth: AuthAdmin,
+		Query:    withParams(securityEventFilters, timeRange, pagination),
+		Response: Object{"events": []models.SecurityEvent{}, "total": 0, "limit": 0, "offset": 0}},
+	{Method: http.MethodGet, Path: "/api/security-events/export", Summary: "Export security events", Tag: "audit", Auth: AuthAdmin,
+		Query:    withParams(securityEventFilters, timeRange, []Param{exportFormat}),
+		Response: []models.SecurityEvent{}, Produces: []string{"text/csv"}},
+
+	// Backup
+	{Method: http.MethodGet, Path: "/api/backup", Summary: "Download a backup of all data", Tag: "backup", Auth: AuthAdmin,
+		Description: "A gzipped tar archive with a manifest and one JSON lines file per table. Sessions are never included.",
+		Query: []Param{{Name: "include_secrets", Type: "boolean",
+			Description: "include service credentials, webhook secrets and notification channel and discovery source configuration"}},
+		Produces: []string{"application/gzip"}},
+	{Method: http.MethodPost, Path: "/api/restore", Summary: "Restore a backup", Tag: "backup", Auth: AuthAdmin,
+		Description: "Replaces all data with the archive, which must match the instance's schema version. Also accepts the archive as the raw request body. Ends every session.",
+		Query:       []Param{{Name: "force", Type: "boolean", Description: "restore even though the instance already has diagrams"}},
+		Multipart:   []string{"file"}, Response: Object{"message": "", "manifest": repository.BackupManifest{}}},
+
+	// Discovery
+	{Method: http.MethodPost, Path: "/api/discovery-sources", Summary: "Create a discovery source", Tag: "discovery", Auth: AuthAdmin,
+		Request: models.DiscoverySource{}, Response: models.DiscoverySource{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/discovery-sources", Summary: "List discovery sources", Tag: "discovery", Auth: AuthAdmin,
+		Response: []models.DiscoverySource{}},
+	{Method: http.MethodPut, Path: "/api/discovery-sources/:id", Summary: "Update a discovery source", Tag: "discovery", Auth: AuthAdmin,
+		Request: models.DiscoverySource{}, Response: models.DiscoverySource{}},
+	{Method: http.MethodDelete, Path: "/api/discovery-sources/:id", Summary: "Delete a discovery source", Tag: "discovery", Auth: AuthAdmin},
+	{Method: http.MethodGet, Path: "/api/discovery-sources/:id/preview", Summary: "Preview discovered services", Tag: "discovery", Auth: AuthAdmin,
+		Response: []discovery.DiscoveredService{}},
+	{Method: http.MethodPost, Path: "/api/discovery-sources/:id/sync", Summary: "Sync a discovery source", Tag: "discovery", Auth: AuthAdmin,
+		Query:    []Param{{Name: "force", Type: "boolean", Description: "Re-apply services whose fingerprint is unchanged"}},
+		Response: models.DiscoverySyncResult{}},
+
+	// Webhooks
+	{Method: http.MethodPost, Path: "/api/webhooks", Summary: "Create a webhook", Tag: "webhooks", Auth: AuthAdmin,
+		Request: models.Webhook{}, Response: models.Webhook{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/webhooks", Summary: "List webhooks", Tag: "webhooks", Auth: AuthAdmin,
+		Response: []models.Webhook{}},
+	{Method: http.MethodGet, Path: "/api/webhooks/:id", Summary: "Get a webhook", Tag: "webhooks", Auth: AuthAdmin,
+		Response: models.Webhook{}},
+	{Method: http.MethodPut, Path: "/api/webhooks/:id", Summary: "Update a webhook", Tag: "webhooks", Auth: AuthAdmin,
+		Request: models.Webhook{}, Response: models.Webhook{}},
+	{Method: http.MethodDelete, Path: "/api/webhooks/:id", Summary: "Delete a webhook", Tag: "webhooks", Auth: AuthAdmin},
+	{Method: http.MethodPost, Path: "/api/webhooks/:id/test", Summary: "Send a test event to a webhook", Tag: "webhooks", Auth: AuthAdmin},
+}
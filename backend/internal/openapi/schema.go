@@ -0,0 +1,178 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schema is an OpenAPI 3.0 schema object
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// Object describes an ad-hoc response object such as gin.H{"results": ..., "total": ...}.
+// Values are example values whose types determine the property schemas.
+type Object map[string]interface{}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	objectType     = reflect.TypeOf(Object{})
+)
+
+// schemaRegistry converts Go types to schemas, collecting named structs as reusable components
+type schemaRegistry struct {
+	components map[string]*Schema
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{components: make(map[string]*Schema)}
+}
+
+// schemaFor returns the schema of an example value
+func (r *schemaRegistry) schemaFor(value interface{}) *Schema {
+	if object, ok := value.(Object); ok {
+		return r.objectSchema(object)
+	}
+	return r.schemaForType(reflect.TypeOf(value))
+}
+
+func (r *schemaRegistry) objectSchema(object Object) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for name, value := range object {
+		schema.Properties[name] = r.schemaFor(value)
+	}
+	return schema
+}
+
+func (r *schemaRegistry) schemaForType(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{Description: "Arbitrary JSON value"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := r.schemaForType(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: r.schemaForType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.additionalProperties(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		return r.structSchema(t)
+	default:
+		return &Schema{}
+	}
+}
+
+func (r *schemaRegistry) additionalProperties(t reflect.Type) interface{} {
+	if t.Kind() == reflect.Interface {
+		return true
+	}
+	return r.schemaForType(t)
+}
+
+// structSchema registers named structs as components and returns a reference to them
+func (r *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	name := t.Name()
+	if name != "" {
+		if _, ok := r.components[name]; ok {
+			return &Schema{Ref: "#/components/schemas/" + name}
+		}
+		// Register before walking the fields so self-references terminate
+		r.components[name] = &Schema{}
+	}
+
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldName, omit := jsonFieldName(field)
+		if omit {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			embedded := r.structSchema(field.Type)
+			if embedded.Ref != "" {
+				embedded = r.components[field.Type.Name()]
+			}
+			for k, v := range embedded.Properties {
+				schema.Properties[k] = v
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		property := r.schemaForType(field.Type)
+		binding := field.Tag.Get("binding")
+		for _, rule := range strings.Split(binding, ",") {
+			switch {
+			case rule == "required":
+				schema.Required = append(schema.Required, fieldName)
+			case strings.HasPrefix(rule, "oneof="):
+				if property.Ref == "" {
+					property.Enum = strings.Fields(strings.TrimPrefix(rule, "oneof="))
+				}
+			}
+		}
+		schema.Properties[fieldName] = property
+	}
+	sort.Strings(schema.Required)
+
+	if name == "" {
+		return schema
+	}
+	r.components[name] = schema
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// jsonFieldName returns the serialized name of a field and whether it is excluded from JSON
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		name = field.Name
+	}
+	return name, false
+}
@@ -0,0 +1,300 @@
+// Package openapi builds the OpenAPI 3 description of the HTTP API from the registered routes
+// and the operation catalogue in routes.go.
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	specVersion = "3.0.3"
+	apiTitle    = "Service Weaver API"
+	apiVersion  = "1.0.0"
+)
+
+// Auth is the authentication an operation requires
+type Auth int
+
+const (
+	AuthNone Auth = iota
+	AuthUser
+	AuthAdmin
+	AuthMetrics
+	AuthOrgAdmin // admin of the request's organization
+	AuthAlertmanager
+	AuthRegistration // registration token of a deployment pipeline
+)
+
+// Param documents a query parameter
+type Param struct {
+	Name        string
+	Type        string // string, integer, number or boolean
+	Description string
+	Required    bool
+}
+
+// Operation documents one route. Request and Response are example values whose types are
+// converted to schemas; a nil Response documents a {"message": "..."} body, unless Produces is set
+// in which case the route returns only those content types.
+type Operation struct {
+	Method      string
+	Path        string // Gin route path, e.g. /api/services/:id
+	Summary     string
+	Description string
+	Tag         string
+	Auth        Auth
+	Query       []Param
+	Request     interface{}
+	Multipart   []string // Form file fields for multipart uploads
+	Response    interface{}
+	Status      int      // Success status, 200 when zero
+	Produces    []string // Alternative success content types, e.g. text/csv
+}
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       map[string]string               `json:"info"`
+	Servers    []map[string]string             `json:"servers"`
+	Tags       []map[string]string             `json:"tags"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components components                      `json:"components"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema                `json:"schemas"`
+	SecuritySchemes map[string]map[string]interface{} `json:"securitySchemes"`
+}
+
+type operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *body                 `json:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type body struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+var pathParam = regexp.MustCompile(`[:*]([A-Za-z_]+)`)
+
+// Build creates the document for the given routes. Routes missing from the catalogue are still
+// listed with a generic operation so the document never silently omits an endpoint.
+func Build(routes gin.RoutesInfo, catalogue []Operation) *Document {
+	known := make(map[string]Operation, len(catalogue))
+	for _, op := range catalogue {
+		known[op.Method+" "+op.Path] = op
+	}
+
+	registry := newSchemaRegistry()
+	errorSchema := registry.schemaFor(ErrorResponse{})
+	messageSchema := registry.schemaFor(MessageResponse{})
+
+	doc := &Document{
+		OpenAPI: specVersion,
+		Info: map[string]string{
+			"title":       apiTitle,
+			"version":     apiVersion,
+			"description": "Service dependency diagrams with live health monitoring.",
+		},
+		Servers: []map[string]string{{"url": "/"}},
+		Paths:   make(map[string]map[string]operation),
+		Components: components{
+			SecuritySchemes: map[string]map[string]interface{}{
+				"bearerAuth":        {"type": "http", "scheme": "bearer", "bearerFormat": "JWT", "description": "Token returned by POST /api/login"},
+				"metricsToken":      {"type": "http", "scheme": "bearer", "description": "Static METRICS_TOKEN; routes using it are not served without one"},
+				"alertmanagerToken": {"type": "http", "scheme": "bearer", "description": "Static ALERTMANAGER_TOKEN"},
+				"registrationToken": {"type": "http", "scheme": "bearer", "description": "Token returned by POST /api/registration-tokens"},
+			},
+		},
+	}
+
+	sorted := append(gin.RoutesInfo(nil), routes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Method < sorted[j].Method
+	})
+
+	tags := make(map[string]bool)
+	for _, route := range sorted {
+		op, ok := known[route.Method+" "+route.Path]
+		if !ok {
+			op = Operation{Method: route.Method, Path: route.Path, Summary: handlerName(route.Handler), Tag: "other", Auth: AuthUser}
+		}
+		tags[op.Tag] = true
+
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]operation)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = buildOperation(registry, op, route, errorSchema, messageSchema)
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, map[string]string{"name": tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i]["name"] < doc.Tags[j]["name"] })
+
+	doc.Components.Schemas = registry.components
+	return doc
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+func buildOperation(registry *schemaRegistry, op Operation, route gin.RouteInfo, errorSchema, messageSchema *Schema) operation {
+	out := operation{
+		Summary:     op.Summary,
+		Description: op.Description,
+		OperationID: handlerName(route.Handler),
+		Tags:        []string{op.Tag},
+		Responses:   make(map[string]response),
+	}
+
+	for _, match := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+		name := match[1]
+		schema := &Schema{Type: "string"}
+		if strings.HasSuffix(strings.ToLower(name), "id") || name == "version" {
+			schema = &Schema{Type: "integer", Format: "int32"}
+		}
+		out.Parameters = append(out.Parameters, parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	for _, p := range op.Query {
+		out.Parameters = append(out.Parameters, parameter{
+			Name: p.Name, In: "query", Description: p.Description, Required: p.Required, Schema: &Schema{Type: p.Type},
+		})
+	}
+
+	switch {
+	case len(op.Multipart) > 0:
+		form := &Schema{Type: "object", Properties: make(map[string]*Schema), Required: op.Multipart}
+		for _, field := range op.Multipart {
+			form.Properties[field] = &Schema{Type: "string", Format: "binary"}
+		}
+		out.RequestBody = &body{Required: true, Content: map[string]mediaType{"multipart/form-data": {Schema: form}}}
+	case op.Request != nil:
+		out.RequestBody = &body{Required: true, Content: map[string]mediaType{"application/json": {Schema: registry.schemaFor(op.Request)}}}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := messageSchema
+	if op.Response != nil {
+		success = registry.schemaFor(op.Response)
+	}
+	content := make(map[string]mediaType)
+	redirect := status >= http.StatusMultipleChoices && status < http.StatusBadRequest // no body
+	if !redirect && (op.Response != nil || len(op.Produces) == 0) {
+		content["application/json"] = mediaType{Schema: success}
+	}
+	for _, contentType := range op.Produces {
+		schema := &Schema{Type: "string"}
+		if !strings.HasPrefix(contentType, "text/") {
+			schema.Format = "binary"
+		}
+		content[contentType] = mediaType{Schema: schema}
+	}
+	out.Responses[strconv.Itoa(status)] = response{Description: http.StatusText(status), Content: content}
+
+	errorContent := map[string]mediaType{"application/json": {Schema: errorSchema}}
+	if op.Request != nil || len(op.Query) > 0 || len(out.Parameters) > 0 {
+		out.Responses["400"] = response{Description: "Invalid request", Content: errorContent}
+	}
+	if strings.Contains(route.Path, ":") {
+		out.Responses["404"] = response{Description: "Not found", Content: errorContent}
+	}
+	out.Responses["500"] = response{Description: "Internal error", Content: errorContent}
+	if strings.HasPrefix(route.Path, "/api/") {
+		out.Responses["429"] = response{Description: "Rate limited; retry after the Retry-After header's seconds", Content: errorContent}
+	}
+
+	switch op.Auth {
+	case AuthUser, AuthAdmin, AuthOrgAdmin:
+		out.Security = []map[string][]string{{"bearerAuth": {}}}
+		out.Responses["401"] = response{Description: "Missing or invalid token", Content: errorContent}
+		out.Parameters = append(out.Parameters, parameter{
+			Name: "X-Organization-ID", In: "header", Description: "Organization to act in; defaults to the user's oldest organization",
+			Schema: &Schema{Type: "integer", Format: "int32"},
+		})
+	case AuthMetrics:
+		out.Security = []map[string][]string{{"metricsToken": {}}}
+		out.Responses["401"] = response{Description: "Missing or invalid token", Content: errorContent}
+	case AuthAlertmanager:
+		out.Security = []map[string][]string{{"alertmanagerToken": {}}}
+		out.Responses["401"] = response{Description: "Missing or invalid token", Content: errorContent}
+	case AuthRegistration:
+		out.Security = []map[string][]string{{"registrationToken": {}}}
+		out.Responses["401"] = response{Description: "Missing or invalid token", Content: errorContent}
+	}
+	if op.Auth == AuthAdmin {
+		out.Responses["403"] = response{Description: "Requires the admin role", Content: errorContent}
+		if out.Description == "" {
+			out.Description = "Requires the admin role."
+		}
+	}
+	if op.Auth == AuthOrgAdmin {
+		out.Responses["403"] = response{Description: "Requires the admin role in the organization", Content: errorContent}
+		if out.Description == "" {
+			out.Description = "Requires the admin role in the organization."
+		}
+	}
+	return out
+}
+
+// handlerName turns "service-weaver/internal/api.(*Handlers).GetDiagram-fm" into "GetDiagram"
+func handlerName(handler string) string {
+	name := handler[strings.LastIndex(handler, ".")+1:]
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// Handler serves the document for the engine's routes. It is built on first request so that
+// every route registered during startup is included.
+func Handler(routes func() gin.RoutesInfo) gin.HandlerFunc {
+	var once sync.Once
+	var doc *Document
+	return func(c *gin.Context) {
+		once.Do(func() {
+			doc = Build(routes(), Catalogue)
+		})
+		c.JSON(http.StatusOK, doc)
+	}
+}
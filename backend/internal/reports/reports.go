@@ -0,0 +1,178 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"service-weaver/internal/models"
+	"service-weaver/internal/notification"
+	"service-weaver/internal/repository"
+	"strings"
+	"time"
+)
+
+// Scheduler generates scheduled uptime/latency reports and delivers them through notification channels
+type Scheduler struct {
+	repo     *repository.Repository
+	notifier *notification.Dispatcher
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+func NewScheduler(repo *repository.Repository, notifier *notification.Dispatcher, interval time.Duration) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		repo:     repo,
+		notifier: notifier,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func (s *Scheduler) Start() {
+	go s.run()
+}
+
+func (s *Scheduler) Stop() {
+	s.cancel()
+}
+
+// Generate builds the summary of a report for the window ending at now
+func (s *Scheduler) Generate(report models.Report, now time.Time) (*models.ReportSummary, error) {
+	summary := &models.ReportSummary{
+		ReportID:    report.ID,
+		Name:        report.Name,
+		From:        report.WindowStart(now),
+		To:          now,
+		Services:    []models.ReportServiceSummary{},
+		GeneratedAt: now,
+	}
+
+	var diagrams []models.Diagram
+	if report.DiagramID != nil {
+		diagram, err := s.repo.GetDiagram(s.ctx, *report.DiagramID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load diagram %d: %w", *report.DiagramID, err)
+		}
+		diagrams = append(diagrams, *diagram)
+	} else {
+		all, err := s.repo.GetDiagrams(s.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load diagrams: %w", err)
+		}
+		diagrams = all
+	}
+
+	for _, diagram := range diagrams {
+		uptimes, err := s.repo.GetDiagramUptime(s.ctx, diagram.ID, summary.From, summary.To)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute uptime for diagram %d: %w", diagram.ID, err)
+		}
+		latencies, err := s.repo.GetDiagramResponseTimes(s.ctx, diagram.ID, summary.From, summary.To)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute response times for diagram %d: %w", diagram.ID, err)
+		}
+
+		for _, uptime := range uptimes {
+			uptime.ApplySLO(uptime.SLOTarget)
+			latency := latencies[uptime.ServiceID]
+			summary.Services = append(summary.Services, models.ReportServiceSummary{
+				DiagramName:     diagram.Name,
+				ServiceID:       uptime.ServiceID,
+				ServiceName:     uptime.ServiceName,
+				UptimePercent:   uptime.UptimePercent,
+				SLOTarget:       uptime.SLOTarget,
+				SLOMet:          uptime.SLOMet,
+				DownChecks:      uptime.DownChecks,
+				AvgResponseTime: latency.AvgResponseTime,
+				P95ResponseTime: latency.P95ResponseTime,
+			})
+			if !uptime.SLOMet {
+				summary.SLOsMissed++
+			}
+		}
+	}
+
+	return summary, nil
+}
+
+// Send generates a report and delivers it to the report's channels
+func (s *Scheduler) Send(report models.Report, now time.Time) error {
+	summary, err := s.Generate(report, now)
+	if err != nil {
+		return err
+	}
+	return s.notifier.Deliver(report.ChannelIDs, Render(summary))
+}
+
+// Render formats a report summary as a fixed-width text table
+func Render(summary *models.ReportSummary) notification.Message {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s to %s\n", summary.From.UTC().Format("2006-01-02"), summary.To.UTC().Format("2006-01-02"))
+	fmt.Fprintf(&b, "%d services, %d below SLO\n\n", len(summary.Services), summary.SLOsMissed)
+	fmt.Fprintf(&b, "%-40s %9s %8s %8s %8s\n", "Service", "Uptime", "SLO", "Avg ms", "p95 ms")
+	for _, svc := range summary.Services {
+		marker := " "
+		if !svc.SLOMet {
+			marker = "!"
+		}
+		name := truncate(svc.DiagramName+" / "+svc.ServiceName, 40)
+		fmt.Fprintf(&b, "%-40s %8.3f%%%s%7.2f%% %8.0f %8.0f\n", name, svc.UptimePercent, marker, svc.SLOTarget, svc.AvgResponseTime, svc.P95ResponseTime)
+	}
+
+	msg := notification.Message{
+		Title:        fmt.Sprintf("Service Weaver report: %s", summary.Name),
+		Body:         b.String(),
+		Priority:     notification.PriorityLow,
+		Tags:         []string{"report"},
+		Preformatted: true,
+	}
+	if summary.SLOsMissed > 0 {
+		msg.Priority = notification.PriorityNormal
+	}
+	return msg
+}
+
+// truncate shortens s to max characters, counting runes so multi-byte names are not cut mid-rune
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-3]) + "..."
+}
+
+func (s *Scheduler) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.sendDue()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Scheduler) sendDue() {
+	now := time.Now()
+	due, err := s.repo.GetDueReports(s.ctx, now)
+	if err != nil {
+		log.Printf("Error loading due reports: %v", err)
+		return
+	}
+
+	for _, report := range due {
+		if err := s.Send(report, now); err != nil {
+			log.Printf("Error sending report %d (%s): %v", report.ID, report.Name, err)
+		}
+		// Reschedule even after a failure so a broken channel does not resend every tick
+		if err := s.repo.MarkReportSent(s.ctx, report.ID, now, report.NextRun(now)); err != nil {
+			log.Printf("Error rescheduling report %d: %v", report.ID, err)
+		}
+	}
+}
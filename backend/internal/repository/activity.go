@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"service-weaver/internal/models"
+	"strings"
+	"time"
+)
+
+// RecordAlert stores a status change notification channels were alerted of
+func (r *Repository) RecordAlert(ctx context.Context, serviceID int, from, to models.ServiceStatus, checkErr string) error {
+	query := `INSERT INTO alerts (service_id, from_status, to_status, error) VALUES ($1, $2, $3, $4)`
+	_, err := r.db.ExecContext(ctx, query, serviceID, from, to, checkErr)
+	return err
+}
+
+// ActivityFilter narrows down a diagram's activity feed. Zero values disable the corresponding filter.
+type ActivityFilter struct {
+	DiagramID int
+	Types     []models.ActivityType
+	From      time.Time
+	To        time.Time
+	Limit     int
+	Offset    int
+}
+
+// activitySources select the entries of each activity type of diagram $1 with the same columns:
+// type, id, time, service, user, summary, version, statuses, error and end. Maintenance windows
+// count from when they start, so those yet to come are left out.
+var activitySources = map[models.ActivityType]string{
+	models.ActivityEdit: `SELECT 'edit', v.id, v.created_at, NULL::int, '', v.created_by, COALESCE(u.username, ''),
+			v.summary, v.version, '', '', '', NULL::timestamp
+		FROM diagram_versions v LEFT JOIN users u ON u.id = v.created_by
+		WHERE v.diagram_id = $1`,
+	models.ActivityStatusChange: `SELECT 'status_change', e.id, e.occurred_at, s.id, s.name, NULL::int, '',
+			'', 0, e.from_status, e.to_status, '', NULL::timestamp
+		FROM status_events e JOIN services s ON s.id = e.service_id
+		WHERE s.diagram_id = $1`,
+	models.ActivityAlert: `SELECT 'alert', a.id, a.occurred_at, s.id, s.name, NULL::int, '',
+			'', 0, a.from_status, a.to_status, a.error, NULL::timestamp
+		FROM alerts a JOIN services s ON s.id = a.service_id
+		WHERE s.diagram_id = $1`,
+	models.ActivityMaintenance: `SELECT 'maintenance', m.id, m.starts_at, s.id, COALESCE(s.name, ''), NULL::int, '',
+			COALESCE(m.reason, ''), 0, '', '', '', m.ends_at
+		FROM maintenance_windows m LEFT JOIN services s ON s.id = m.service_id
+		WHERE (m.diagram_id = $1 OR s.diagram_id = $1) AND m.starts_at <= LOCALTIMESTAMP`,
+}
+
+// GetDiagramActivity returns a page of the activity feed of a diagram, newest first, along with
+// the total number of entries: its structural edits, the status changes and alerts of its
+// services and its maintenance windows, or only those of filter.Types
+func (r *Repository) GetDiagramActivity(ctx context.Context, filter ActivityFilter) ([]models.Activity, int, error) {
+	types := filter.Types
+	if len(types) == 0 {
+		types = models.ActivityTypes
+	}
+	sources := make([]string, 0, len(types))
+	for _, activityType := range types {
+		if source, ok := activitySources[activityType]; ok {
+			sources = append(sources, source)
+		}
+	}
+
+	conditions := []string{"TRUE"}
+	args := []interface{}{filter.DiagramID}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("occurred_at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("occurred_at < $%d", len(args)))
+	}
+	feed := `(` + strings.Join(sources, "\nUNION ALL\n") + `) AS feed (type, id, occurred_at, service_id, service_name,
+		user_id, username, summary, version, from_status, to_status, error, ends_at)
+		WHERE ` + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.replica.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+feed, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`SELECT * FROM %s ORDER BY occurred_at DESC, type, id DESC LIMIT $%d OFFSET $%d`, feed, len(args)-1, len(args))
+	rows, err := r.replica.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	activity := []models.Activity{}
+	for rows.Next() {
+		var a models.Activity
+		err := rows.Scan(&a.Type, &a.ID, &a.OccurredAt, &a.ServiceID, &a.ServiceName, &a.UserID, &a.Username,
+			&a.Summary, &a.Version, &a.FromStatus, &a.ToStatus, &a.Error, &a.EndsAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		activity = append(activity, a)
+	}
+	return activity, total, rows.Err()
+}
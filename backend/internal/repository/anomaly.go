@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"service-weaver/internal/models"
+)
+
+// GetSeasonalRollups returns the hourly rollups of every active service for the current hour of
+// day over the past days, oldest first: the history a latency baseline for that hour is learnt from
+func (r *Repository) GetSeasonalRollups(ctx context.Context, days int) (map[int][]models.HealthcheckRollup, error) {
+	query := `SELECT h.service_id, h.granularity, h.bucket_start, h.total_checks, h.up_checks, h.failure_count, h.avg_response_time, h.p95_response_time
+		FROM healthcheck_rollups h
+		JOIN services s ON s.id = h.service_id AND s.deleted_at IS NULL AND NOT s.paused
+		WHERE h.granularity = 'hour'
+			AND h.bucket_start >= CURRENT_TIMESTAMP - make_interval(days => $1)
+			AND EXTRACT(HOUR FROM h.bucket_start) = EXTRACT(HOUR FROM CURRENT_TIMESTAMP::timestamp)
+		ORDER BY h.service_id, h.bucket_start`
+	rows, err := r.replica.QueryContext(ctx, query, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make(map[int][]models.HealthcheckRollup)
+	for rows.Next() {
+		var h models.HealthcheckRollup
+		if err := rows.Scan(&h.ServiceID, &h.Granularity, &h.BucketStart, &h.TotalChecks, &h.UpChecks, &h.FailureCount, &h.AvgResponseTime, &h.P95ResponseTime); err != nil {
+			return nil, err
+		}
+		history[h.ServiceID] = append(history[h.ServiceID], h)
+	}
+	return history, rows.Err()
+}
+
+// GetRecentResponseTimes returns the response times of the passing checks of every active service
+// over the last minutes. Failed checks are left out: their times say how long they took to fail.
+func (r *Repository) GetRecentResponseTimes(ctx context.Context, minutes int) (map[int]models.MetricsBucket, error) {
+	query := `SELECT r.service_id,
+			COUNT(*),
+			COALESCE(AVG(r.response_time), 0),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY r.response_time), 0),
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY r.response_time), 0),
+			MIN(r.checked_at)
+		FROM healthcheck_results r
+		JOIN services s ON s.id = r.service_id AND s.deleted_at IS NULL AND NOT s.paused
+		WHERE r.checked_at >= CURRENT_TIMESTAMP - make_interval(mins => $1)
+			AND r.status IN ('alive', 'degraded')
+		GROUP BY r.service_id`
+	rows, err := r.replica.QueryContext(ctx, query, minutes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := make(map[int]models.MetricsBucket)
+	for rows.Next() {
+		var serviceID int
+		var b models.MetricsBucket
+		if err := rows.Scan(&serviceID, &b.Count, &b.AvgResponseTime, &b.P50ResponseTime, &b.P95ResponseTime, &b.Timestamp); err != nil {
+			return nil, err
+		}
+		buckets[serviceID] = b
+	}
+	return buckets, rows.Err()
+}
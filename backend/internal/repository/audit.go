@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"service-weaver/internal/models"
+	"strings"
+	"time"
+)
+
+// CreateAuditEntry stores an audit log entry
+func (r *Repository) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	query := `INSERT INTO audit_log (user_id, username, action, method, route, entity_type, entity_id, before_state, after_state, status_code, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx, query, entry.UserID, entry.Username, entry.Action, entry.Method, entry.Route, entry.EntityType, entry.EntityID,
+		nullableJSON(entry.Before), nullableJSON(entry.After), entry.StatusCode, entry.IPAddress).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+func nullableJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
+// AuditFilter narrows down an audit log query. Zero values disable the corresponding filter.
+type AuditFilter struct {
+	UserID     int
+	EntityType string
+	EntityID   int
+	Action     string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// GetAuditEntries returns a page of audit log entries, newest first, along with the total number of matches
+func (r *Repository) GetAuditEntries(ctx context.Context, filter AuditFilter) ([]models.AuditEntry, int, error) {
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+
+	if filter.UserID != 0 {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if filter.EntityType != "" {
+		args = append(args, filter.EntityType)
+		conditions = append(conditions, fmt.Sprintf("entity_type = $%d", len(args)))
+	}
+	if filter.EntityID != 0 {
+		args = append(args, filter.EntityID)
+		conditions = append(conditions, fmt.Sprintf("entity_id = $%d", len(args)))
+	}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		conditions = append(conditions, fmt.Sprintf("action = $%d", len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`SELECT id, user_id, username, action, method, route, entity_type, entity_id, before_state, after_state, status_code, ip_address, created_at
+		FROM audit_log WHERE %s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var before, after []byte
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Username, &e.Action, &e.Method, &e.Route, &e.EntityType, &e.EntityID, &before, &after, &e.StatusCode, &e.IPAddress, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		e.Before, e.After = before, after
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
+
+// GetAuditEntity loads the current state of an entity for the audit log. entityType is the
+// API resource name; unknown types and missing entities return nil.
+func (r *Repository) GetAuditEntity(ctx context.Context, entityType string, id int) (interface{}, error) {
+	var entity interface{}
+	var err error
+	switch entityType {
+	case "diagrams":
+		entity, err = r.GetDiagram(ctx, id)
+	case "services":
+		entity, err = r.GetServiceByID(ctx, id)
+	case "connections":
+		entity, err = r.GetConnection(ctx, id)
+	case "users":
+		entity, err = r.GetUserByID(ctx, id)
+	case "notification-channels":
+		entity, err = r.GetNotificationChannel(ctx, id)
+	case "reports":
+		entity, err = r.GetReport(ctx, id)
+	case "incidents":
+		entity, err = r.GetIncident(ctx, id)
+	case "discovery-sources":
+		entity, err = r.GetDiscoverySource(ctx, id)
+	case "webhooks":
+		entity, err = r.GetWebhook(ctx, id)
+	case "folders":
+		entity, err = r.GetFolder(ctx, id)
+	case "organizations":
+		entity, err = r.GetOrganization(ctx, id)
+	default:
+		return nil, nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return entity, err
+}
@@ -0,0 +1,339 @@
+package repository
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// BackupFormat identifies Service Weaver backup archives
+const BackupFormat = "service-weaver-backup"
+
+// restoreBatchSize bounds the rows inserted per statement during a restore
+const restoreBatchSize = 500
+
+var (
+	// ErrBackupInvalid is returned when a restore is given something that is not a backup archive
+	ErrBackupInvalid = errors.New("not a valid Service Weaver backup")
+	// ErrBackupSchemaMismatch is returned when a backup was taken at a different schema version
+	ErrBackupSchemaMismatch = errors.New("backup was taken at a different schema version")
+	// ErrInstanceNotEmpty is returned when restoring over existing diagrams without forcing it
+	ErrInstanceNotEmpty = errors.New("the instance already has diagrams; restore into a fresh instance or force it")
+)
+
+// backupTable is a table included in backups. Tables are listed parents first, so restoring them
+// in order satisfies every foreign key.
+type backupTable struct {
+	name string
+	// order sorts the rows where a table references itself
+	order string
+	// secrets are the columns, with their blank values, cleared when secrets are excluded. JSONB
+	// columns are blanked with emptyObject rather than the string "{}".
+	secrets map[string]interface{}
+	// noID marks tables keyed by other columns, which have no id sequence to continue
+	noID bool
+}
+
+// Sessions, refresh tokens, the token revocation list and invitations are never backed up: a
+// restored instance starts with everyone signed out. Neither are recently viewed diagrams, nor
+// alerts firing in Alertmanager, which it sends again.
+var backupTables = []backupTable{
+	{name: "users"},
+	{name: "organizations"},
+	{name: "organization_members", noID: true},
+	{name: "environments"},
+	{name: "folders", order: "parent_id NULLS FIRST, id"},
+	{name: "diagrams"},
+	{name: "registration_tokens"},
+	{name: "discovery_sources", secrets: map[string]interface{}{"config": emptyObject}},
+	{name: "services", secrets: map[string]interface{}{"credentials": "", "headers": emptyObject}},
+	{name: "connections"},
+	{name: "heartbeats", noID: true},
+	{name: "check_scripts"},
+	{name: "service_scripts", noID: true},
+	{name: "service_certificates", noID: true},
+	{name: "diagram_versions"},
+	{name: "healthcheck_results"},
+	{name: "healthcheck_rollups"},
+	{name: "status_events"},
+	{name: "alerts"},
+	{name: "notification_channels", secrets: map[string]interface{}{"config": emptyObject}},
+	{name: "maintenance_windows"},
+	{name: "incidents"},
+	{name: "reports"},
+	{name: "audit_log"},
+	{name: "security_events"},
+	{name: "webhooks", secrets: map[string]interface{}{"secret": ""}},
+	{name: "user_identities"},
+	{name: "notification_preferences", secrets: map[string]interface{}{"slack_webhook_url": ""}, noID: true},
+	{name: "diagram_stars", noID: true},
+}
+
+// emptyObject blanks JSONB secrets in backups without secrets
+var emptyObject = struct{}{}
+
+// BackupManifest is the first entry of a backup archive
+type BackupManifest struct {
+	Format          string         `json:"format"`
+	SchemaVersion   int            `json:"schema_version"`
+	CreatedAt       time.Time      `json:"created_at"`
+	IncludesSecrets bool           `json:"includes_secrets"`
+	Tables          map[string]int `json:"tables"`
+}
+
+// WriteBackup writes a logical backup of every table as a gzipped tar archive: manifest.json
+// followed by one file of JSON lines per table. Without includeSecrets, service credentials and
+// request headers, webhook secrets, personal Slack webhooks and the configuration of notification channels and
+// discovery sources, which may hold tokens, are left out.
+//
+// Rows are read in one repeatable read transaction, so the archive is a consistent snapshot.
+func (r *Repository) WriteBackup(ctx context.Context, w io.Writer, includeSecrets bool) (*BackupManifest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY`); err != nil {
+		return nil, err
+	}
+	if err := withoutStatementTimeout(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	manifest := &BackupManifest{
+		Format:          BackupFormat,
+		CreatedAt:       time.Now().UTC(),
+		IncludesSecrets: includeSecrets,
+		Tables:          make(map[string]int),
+	}
+	if manifest.SchemaVersion, err = schemaVersion(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	for _, table := range backupTables {
+		var count int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table.name).Scan(&count); err != nil {
+			return nil, err
+		}
+		manifest.Tables[table.name] = count
+	}
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	header := &tar.Header{Name: "manifest.json", Mode: 0o600, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+	if err := archive.WriteHeader(header); err != nil {
+		return nil, err
+	}
+	if _, err := archive.Write(data); err != nil {
+		return nil, err
+	}
+
+	for _, table := range backupTables {
+		if err := writeBackupTable(ctx, tx, archive, table, includeSecrets, manifest.CreatedAt); err != nil {
+			return nil, fmt.Errorf("backup of %s failed: %w", table.name, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, gz.Close()
+}
+
+// writeBackupTable adds the rows of a table to the archive. They are spooled to a temporary file
+// first because tar headers carry the size up front, and the results table does not fit in memory.
+func writeBackupTable(ctx context.Context, q queryRunner, archive *tar.Writer, table backupTable, includeSecrets bool, modTime time.Time) error {
+	spool, err := os.CreateTemp("", "service-weaver-backup-*.jsonl")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	rows, err := q.QueryContext(ctx, table.selectQuery(includeSecrets))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	out := bufio.NewWriter(spool)
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			return err
+		}
+		out.Write(row)
+		out.WriteByte('\n')
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+
+	size, err := spool.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	header := &tar.Header{Name: "tables/" + table.name + ".jsonl", Mode: 0o600, Size: size, ModTime: modTime}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(archive, spool)
+	return err
+}
+
+func (t backupTable) selectQuery(includeSecrets bool) string {
+	row := "to_jsonb(t)"
+	if !includeSecrets && len(t.secrets) > 0 {
+		blank, _ := json.Marshal(t.secrets)
+		row = fmt.Sprintf("to_jsonb(t) || '%s'::jsonb", blank)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s t", row, t.name)
+	if t.order != "" {
+		query += " ORDER BY " + t.order
+	}
+	return query
+}
+
+// RestoreBackup replaces the contents of the database with a backup written by WriteBackup. The
+// backup must match this build's schema version, and unless force is set the instance must not
+// have any diagrams yet. The restore runs in a single transaction, so a failed restore leaves the
+// database untouched. Every session is ended.
+func (r *Repository) RestoreBackup(ctx context.Context, src io.Reader, force bool) (*BackupManifest, error) {
+	gz, err := gzip.NewReader(src)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBackupInvalid, err)
+	}
+	defer gz.Close()
+	archive := tar.NewReader(gz)
+
+	header, err := archive.Next()
+	if err != nil || header.Name != "manifest.json" {
+		return nil, fmt.Errorf("%w: the archive does not start with manifest.json", ErrBackupInvalid)
+	}
+	var manifest BackupManifest
+	if err := json.NewDecoder(archive).Decode(&manifest); err != nil || manifest.Format != BackupFormat {
+		return nil, fmt.Errorf("%w: unreadable manifest", ErrBackupInvalid)
+	}
+	if manifest.SchemaVersion != LatestSchemaVersion() {
+		return nil, fmt.Errorf("%w (backup %d, this instance %d)", ErrBackupSchemaMismatch, manifest.SchemaVersion, LatestSchemaVersion())
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err := withoutStatementTimeout(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	if !force {
+		var empty bool
+		if err := tx.QueryRowContext(ctx, `SELECT NOT EXISTS (SELECT 1 FROM diagrams)`).Scan(&empty); err != nil {
+			return nil, err
+		}
+		if !empty {
+			return nil, ErrInstanceNotEmpty
+		}
+	}
+
+	names := make([]string, 0, len(backupTables)+3)
+	known := make(map[string]bool)
+	for _, table := range backupTables {
+		names = append(names, table.name)
+		known[table.name] = true
+	}
+	names = append(names, "refresh_tokens", "sessions", "revoked_tokens")
+	if _, err := tx.ExecContext(ctx, `TRUNCATE `+strings.Join(names, ", ")+` RESTART IDENTITY CASCADE`); err != nil {
+		return nil, err
+	}
+
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBackupInvalid, err)
+		}
+		table := strings.TrimSuffix(path.Base(header.Name), ".jsonl")
+		if path.Dir(header.Name) != "tables" || !known[table] {
+			return nil, fmt.Errorf("%w: unexpected file %s", ErrBackupInvalid, header.Name)
+		}
+		if err := restoreTable(ctx, tx, table, archive); err != nil {
+			return nil, fmt.Errorf("restore of %s failed: %w", table, err)
+		}
+	}
+
+	// serial sequences continue after the restored ids
+	for _, table := range backupTables {
+		if table.noID {
+			continue
+		}
+		query := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %[1]s`, table.name)
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return nil, err
+		}
+	}
+
+	return &manifest, r.changed(tx.Commit())
+}
+
+// restoreTable inserts the JSON rows of one table in batches
+func restoreTable(ctx context.Context, q queryRunner, table string, src io.Reader) error {
+	query := fmt.Sprintf(`INSERT INTO %[1]s SELECT * FROM jsonb_populate_recordset(NULL::%[1]s, $1)`, table)
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	batch := make([]json.RawMessage, 0, restoreBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		rows, err := json.Marshal(batch)
+		if err != nil {
+			return err
+		}
+		batch = batch[:0]
+		_, err = q.ExecContext(ctx, query, string(rows))
+		return err
+	}
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return fmt.Errorf("%w: malformed row", ErrBackupInvalid)
+		}
+		batch = append(batch, json.RawMessage(append([]byte(nil), line...)))
+		if len(batch) == restoreBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"service-weaver/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// BulkItemError identifies the item of a bulk operation that caused it to be rolled back
+type BulkItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BulkItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *BulkItemError) Unwrap() error {
+	return e.Err
+}
+
+// CreateServices inserts all services in a single transaction. If any insert fails nothing
+// is written and a *BulkItemError identifying the offending service is returned.
+func (r *Repository) CreateServices(ctx context.Context, services []models.Service) error {
+	return r.changed(r.WithTx(ctx, func(tx *sql.Tx) error {
+		for i := range services {
+			if err := createService(ctx, tx, &services[i]); err != nil {
+				return &BulkItemError{Index: i, Err: err}
+			}
+		}
+		return nil
+	}))
+}
+
+// UpdateServices updates all services in a single transaction. If any update fails, or a
+// service does not exist, nothing is written and a *BulkItemError is returned.
+func (r *Repository) UpdateServices(ctx context.Context, services []models.Service) error {
+	return r.changed(r.WithTx(ctx, func(tx *sql.Tx) error {
+		for i := range services {
+			found, err := updateService(ctx, tx, &services[i])
+			if err != nil {
+				return &BulkItemError{Index: i, Err: err}
+			}
+			if !found {
+				return &BulkItemError{Index: i, Err: sql.ErrNoRows}
+			}
+		}
+		return nil
+	}))
+}
+
+// GetServiceDiagramIDs returns the distinct diagrams the given services belong to
+func (r *Repository) GetServiceDiagramIDs(ctx context.Context, serviceIDs []int) ([]int, error) {
+	query := `SELECT DISTINCT diagram_id FROM services WHERE id = ANY($1) ORDER BY diagram_id`
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(serviceIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// DeleteServices moves services to the trash in a single transaction and returns them. If a
+// service is missing or already in the trash nothing is written and a *BulkItemError is returned.
+func (r *Repository) DeleteServices(ctx context.Context, ids []int) ([]models.Service, error) {
+	return r.updateServices(ctx, ids, `deleted_at = CURRENT_TIMESTAMP`)
+}
+
+// SetServicesPaused pauses or resumes the checks of services in a single transaction and returns
+// them. If a service is missing nothing is written and a *BulkItemError is returned.
+func (r *Repository) SetServicesPaused(ctx context.Context, ids []int, paused bool) ([]models.Service, error) {
+	set := `paused = FALSE, updated_at = CURRENT_TIMESTAMP`
+	if paused {
+		set = `paused = TRUE, updated_at = CURRENT_TIMESTAMP`
+	}
+	return r.updateServices(ctx, ids, set)
+}
+
+// updateServices applies set to every service in ids that is not in the trash, all or nothing, and
+// to their overlays; overlays themselves count as missing
+func (r *Repository) updateServices(ctx context.Context, ids []int, set string) ([]models.Service, error) {
+	services := make([]models.Service, len(ids))
+	err := r.WithTx(ctx, func(tx *sql.Tx) error {
+		query := `UPDATE services SET ` + set + ` WHERE id = $1 AND deleted_at IS NULL AND overlay_of IS NULL RETURNING ` + serviceColumns
+		for i, id := range ids {
+			if err := scanService(tx.QueryRowContext(ctx, query, id), &services[i]); err != nil {
+				return &BulkItemError{Index: i, Err: err}
+			}
+		}
+		return syncOverlays(ctx, tx, ids)
+	})
+	if err := r.changed(err); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"service-weaver/internal/models"
+)
+
+// RecordCertificate stores a certificate a service served, keeping when it was first seen if it
+// was recorded before
+func (r *Repository) RecordCertificate(ctx context.Context, cert models.ServiceCertificate) error {
+	query := `INSERT INTO service_certificates (service_id, not_after, subject, issuer) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (service_id, not_after) DO NOTHING`
+	_, err := r.db.ExecContext(ctx, query, cert.ServiceID, cert.NotAfter, cert.Subject, cert.Issuer)
+	return err
+}
+
+// GetServiceCertificates returns the certificates a service served, in the order it served them
+func (r *Repository) GetServiceCertificates(ctx context.Context, serviceID int) ([]models.ServiceCertificate, error) {
+	query := `SELECT service_id, not_after, subject, issuer, first_seen FROM service_certificates
+		WHERE service_id = $1 ORDER BY first_seen, not_after`
+	rows, err := r.replica.QueryContext(ctx, query, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	certs := []models.ServiceCertificate{}
+	for rows.Next() {
+		var cert models.ServiceCertificate
+		if err := rows.Scan(&cert.ServiceID, &cert.NotAfter, &cert.Subject, &cert.Issuer, &cert.FirstSeen); err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, rows.Err()
+}
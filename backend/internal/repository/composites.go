@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"service-weaver/internal/models"
+)
+
+var (
+	// ErrChildDiagramNotFound is returned when a composite node references a missing or trashed diagram
+	ErrChildDiagramNotFound = errors.New("child diagram not found")
+	// ErrCompositeCycle is returned when a composite node would contain the diagram it belongs to
+	ErrCompositeCycle = errors.New("a composite node cannot reference its own diagram or a diagram that contains it")
+)
+
+// checkChildDiagram verifies that a node of diagramID may drill down into childDiagramID: the child
+// must exist in the same organization, and diagramID must not be reachable from it through other
+// composite nodes. Trashed nodes are followed too, since restoring them would close the cycle.
+func checkChildDiagram(ctx context.Context, q queryRunner, diagramID, childDiagramID int) error {
+	query := `WITH RECURSIVE reachable AS (
+			SELECT $1::int AS id
+			UNION
+			SELECT s.child_diagram_id FROM services s JOIN reachable r ON s.diagram_id = r.id
+			WHERE s.child_diagram_id IS NOT NULL
+		)
+		SELECT EXISTS (
+				SELECT 1 FROM diagrams c JOIN diagrams p ON p.id = $2
+				WHERE c.id = $1 AND c.deleted_at IS NULL AND c.organization_id = p.organization_id
+			),
+			EXISTS (SELECT 1 FROM reachable WHERE id = $2)`
+	var found, cycle bool
+	if err := q.QueryRowContext(ctx, query, childDiagramID, diagramID).Scan(&found, &cycle); err != nil {
+		return err
+	}
+	if !found {
+		return ErrChildDiagramNotFound
+	}
+	if cycle {
+		return ErrCompositeCycle
+	}
+	return nil
+}
+
+// GetDiagramStatuses returns the status of every live service in a diagram, for rolling it up
+// into a composite node
+func (r *Repository) GetDiagramStatuses(ctx context.Context, diagramID int) ([]models.ServiceStatus, error) {
+	query := `SELECT ` + effectiveStatus + `c.id) FROM services c WHERE c.diagram_id = $1 AND c.deleted_at IS NULL AND c.overlay_of IS NULL`
+	rows, err := r.db.QueryContext(ctx, query, diagramID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := []models.ServiceStatus{}
+	for rows.Next() {
+		var status models.ServiceStatus
+		if err := rows.Scan(&status); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, rows.Err()
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"service-weaver/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrTargetDiagramNotFound is returned when services are copied or moved to a missing or trashed diagram
+var ErrTargetDiagramNotFound = errors.New("target diagram not found")
+
+// CopyServices copies services into a diagram in a single transaction, or moves them when move is
+// set, and returns the resulting services in the order of ids. With connections, the connections
+// among the copied services are re-created between the copies. A move keeps the connections among
+// the moved services and drops those to services left behind, since connections cannot span
+// diagrams. If a service is missing nothing is written and a *BulkItemError is returned.
+func (r *Repository) CopyServices(ctx context.Context, ids []int, diagramID int, move, connections bool) ([]models.Service, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM diagrams WHERE id = $1 AND deleted_at IS NULL)`
+	if err := tx.QueryRowContext(ctx, query, diagramID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrTargetDiagramNotFound
+	}
+
+	// Maps each source service to its copy, or to itself when moving
+	copies := make(map[int]int, len(ids))
+	for i, id := range ids {
+		var s models.Service
+		query := `SELECT ` + serviceColumns + ` FROM services WHERE id = $1 AND deleted_at IS NULL AND overlay_of IS NULL FOR UPDATE`
+		if err := scanService(tx.QueryRowContext(ctx, query, id), &s); err != nil {
+			return nil, &BulkItemError{Index: i, Err: err}
+		}
+
+		if !move {
+			s.DiagramID = diagramID
+			if err := createService(ctx, tx, &s); err != nil {
+				return nil, &BulkItemError{Index: i, Err: err}
+			}
+			copies[id] = s.ID
+			continue
+		}
+
+		if s.ChildDiagramID != nil {
+			if err := checkChildDiagram(ctx, tx, diagramID, *s.ChildDiagramID); err != nil {
+				return nil, &BulkItemError{Index: i, Err: err}
+			}
+		}
+		query = `UPDATE services SET diagram_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+		if _, err := tx.ExecContext(ctx, query, diagramID, id); err != nil {
+			return nil, &BulkItemError{Index: i, Err: err}
+		}
+		if err := syncOverlays(ctx, tx, []int{id}); err != nil {
+			return nil, &BulkItemError{Index: i, Err: err}
+		}
+		copies[id] = id
+	}
+
+	if move {
+		query := `UPDATE connections SET diagram_id = $1 WHERE source_id = ANY($2) AND target_id = ANY($2)`
+		if _, err := tx.ExecContext(ctx, query, diagramID, pq.Array(ids)); err != nil {
+			return nil, err
+		}
+		query = `DELETE FROM connections WHERE (source_id = ANY($1) OR target_id = ANY($1))
+			AND NOT (source_id = ANY($1) AND target_id = ANY($1))`
+		if _, err := tx.ExecContext(ctx, query, pq.Array(ids)); err != nil {
+			return nil, err
+		}
+	} else if connections {
+		query := `SELECT ` + connectionColumns + ` FROM connections WHERE source_id = ANY($1) AND target_id = ANY($1) ORDER BY id`
+		rows, err := tx.QueryContext(ctx, query, pq.Array(ids))
+		if err != nil {
+			return nil, err
+		}
+		var existing []models.Connection
+		for rows.Next() {
+			var c models.Connection
+			if err := scanConnection(rows, &c); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			existing = append(existing, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		for _, c := range existing {
+			c.DiagramID = diagramID
+			c.SourceID = copies[c.SourceID]
+			c.TargetID = copies[c.TargetID]
+			if err := createConnection(ctx, tx, &c); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	services := make([]models.Service, len(ids))
+	for i, id := range ids {
+		query := `SELECT ` + serviceColumns + ` FROM services WHERE id = $1`
+		if err := scanService(tx.QueryRowContext(ctx, query, copies[id]), &services[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.changed(tx.Commit()); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"service-weaver/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const discoverySourceColumns = `id, name, type, diagram_id, config, sync_interval, enabled, last_synced_at, COALESCE(last_error, ''), created_at, updated_at`
+
+// Discovery source operations
+func (r *Repository) CreateDiscoverySource(ctx context.Context, source *models.DiscoverySource) error {
+	query := `INSERT INTO discovery_sources (name, type, diagram_id, config, sync_interval, enabled) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`
+	return r.db.QueryRowContext(ctx, query, source.Name, source.Type, source.DiagramID, source.Config, source.SyncInterval, source.Enabled).Scan(&source.ID, &source.CreatedAt, &source.UpdatedAt)
+}
+
+func (r *Repository) GetDiscoverySources(ctx context.Context) ([]models.DiscoverySource, error) {
+	query := `SELECT ` + discoverySourceColumns + ` FROM discovery_sources ORDER BY name`
+	return r.queryDiscoverySources(ctx, query)
+}
+
+// GetDueDiscoverySources returns the enabled sources with automatic sync whose interval has elapsed
+func (r *Repository) GetDueDiscoverySources(ctx context.Context) ([]models.DiscoverySource, error) {
+	query := `SELECT ` + discoverySourceColumns + ` FROM discovery_sources
+		WHERE enabled = TRUE AND sync_interval > 0
+			AND (last_synced_at IS NULL OR last_synced_at + sync_interval * INTERVAL '1 second' <= CURRENT_TIMESTAMP)
+		ORDER BY id`
+	return r.queryDiscoverySources(ctx, query)
+}
+
+func (r *Repository) GetDiscoverySource(ctx context.Context, id int) (*models.DiscoverySource, error) {
+	query := `SELECT ` + discoverySourceColumns + ` FROM discovery_sources WHERE id = $1`
+	var source models.DiscoverySource
+	if err := scanDiscoverySource(r.db.QueryRowContext(ctx, query, id), &source); err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+func (r *Repository) UpdateDiscoverySource(ctx context.Context, source *models.DiscoverySource) error {
+	query := `UPDATE discovery_sources SET name = $1, type = $2, diagram_id = $3, config = $4, sync_interval = $5, enabled = $6, updated_at = CURRENT_TIMESTAMP WHERE id = $7`
+	_, err := r.db.ExecContext(ctx, query, source.Name, source.Type, source.DiagramID, source.Config, source.SyncInterval, source.Enabled, source.ID)
+	return err
+}
+
+// DeleteDiscoverySource removes a source; nodes it created stay in the diagram as regular services
+func (r *Repository) DeleteDiscoverySource(ctx context.Context, id int) error {
+	query := `DELETE FROM discovery_sources WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// MarkDiscoverySynced records the outcome of a sync attempt
+func (r *Repository) MarkDiscoverySynced(ctx context.Context, id int, syncErr string) error {
+	query := `UPDATE discovery_sources SET last_synced_at = CURRENT_TIMESTAMP, last_error = $1 WHERE id = $2`
+	_, err := r.db.ExecContext(ctx, query, syncErr, id)
+	return err
+}
+
+func (r *Repository) queryDiscoverySources(ctx context.Context, query string, args ...interface{}) ([]models.DiscoverySource, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := []models.DiscoverySource{}
+	for rows.Next() {
+		var source models.DiscoverySource
+		if err := scanDiscoverySource(rows, &source); err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	return sources, rows.Err()
+}
+
+func scanDiscoverySource(row rowScanner, source *models.DiscoverySource) error {
+	return row.Scan(&source.ID, &source.Name, &source.Type, &source.DiagramID, &source.Config, &source.SyncInterval, &source.Enabled, &source.LastSyncedAt, &source.LastError, &source.CreatedAt, &source.UpdatedAt)
+}
+
+// GetDiscoveredServices returns the nodes previously created by a discovery source, keyed by discovery key.
+// Trashed nodes are included so that a sync does not recreate nodes a user deleted.
+func (r *Repository) GetDiscoveredServices(ctx context.Context, sourceID int) (map[string]models.Service, error) {
+	query := `SELECT ` + serviceColumns + ` FROM services WHERE discovery_source_id = $1`
+	rows, err := r.db.QueryContext(ctx, query, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	services := make(map[string]models.Service)
+	for rows.Next() {
+		var s models.Service
+		if err := scanService(rows, &s); err != nil {
+			return nil, err
+		}
+		services[s.DiscoveryKey] = s
+	}
+	return services, rows.Err()
+}
+
+// DiscoveryChanges is the set of node changes computed by one sync of a discovery source
+type DiscoveryChanges struct {
+	Creates      []models.Service
+	Updates      []models.Service
+	Deregistered []int // Nodes missing from the catalog
+	Restored     []int // Previously deregistered nodes that reappeared in the catalog
+}
+
+// ApplyDiscovery applies the changes of a discovery sync in a single transaction.
+// Updates only touch the fields discovery owns: host, port, healthcheck method/URL and tags.
+func (r *Repository) ApplyDiscovery(ctx context.Context, sourceID int, changes DiscoveryChanges) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i := range changes.Creates {
+		s := &changes.Creates[i]
+		if err := createService(ctx, tx, s); err != nil {
+			return &BulkItemError{Index: i, Err: err}
+		}
+		query := `UPDATE services SET discovery_source_id = $1, discovery_key = $2, discovery_hash = $3 WHERE id = $4`
+		if _, err := tx.ExecContext(ctx, query, sourceID, s.DiscoveryKey, s.DiscoveryHash, s.ID); err != nil {
+			return err
+		}
+		s.DiscoverySourceID = &sourceID
+	}
+
+	for _, s := range changes.Updates {
+		query := `UPDATE services SET host = $1, port = $2, healthcheck_method = $3, healthcheck_url = $4, tags = $5, discovery_hash = $6, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $7 AND discovery_source_id = $8`
+		if _, err := tx.ExecContext(ctx, query, s.Host, s.Port, s.HealthcheckMethod, s.HealthcheckURL, pq.Array(models.SplitTags(s.Tags)), s.DiscoveryHash, s.ID, sourceID); err != nil {
+			return err
+		}
+		if err := syncOverlays(ctx, tx, []int{s.ID}); err != nil {
+			return err
+		}
+	}
+
+	if len(changes.Deregistered) > 0 {
+		query := `UPDATE services SET discovery_deregistered_at = CURRENT_TIMESTAMP
+			WHERE id = ANY($1) AND discovery_source_id = $2 AND discovery_deregistered_at IS NULL`
+		if _, err := tx.ExecContext(ctx, query, pq.Array(changes.Deregistered), sourceID); err != nil {
+			return err
+		}
+	}
+
+	if len(changes.Restored) > 0 {
+		query := `UPDATE services SET discovery_deregistered_at = NULL WHERE id = ANY($1) AND discovery_source_id = $2`
+		if _, err := tx.ExecContext(ctx, query, pq.Array(changes.Restored), sourceID); err != nil {
+			return err
+		}
+	}
+
+	return r.changed(tx.Commit())
+}
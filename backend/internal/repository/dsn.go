@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sslModes are the sslmode values lib/pq supports
+var sslModes = []string{"disable", "require", "verify-ca", "verify-full"}
+
+// ConnectionConfig describes how to reach the application database: either a postgres:// URL or
+// the individual connection parameters. The TLS settings apply to both, but a URL's own query
+// parameters take precedence. StatementTimeout makes Postgres cancel any statement running longer,
+// so a slow database cannot hold handlers and schedulers indefinitely; zero keeps the server's
+// setting.
+type ConnectionConfig struct {
+	URL      string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+
+	SSLMode     string // disable, require, verify-ca or verify-full
+	SSLRootCert string // CA certificate verifying the server, for verify-ca and verify-full
+	SSLCert     string // client certificate and key, for servers that require them
+	SSLKey      string
+
+	StatementTimeout time.Duration
+}
+
+// ConnectionString validates the configuration and returns the data source name for lib/pq
+func (c ConnectionConfig) ConnectionString() (string, error) {
+	tls := map[string]string{
+		"sslmode":     c.SSLMode,
+		"sslrootcert": c.SSLRootCert,
+		"sslcert":     c.SSLCert,
+		"sslkey":      c.SSLKey,
+	}
+
+	if c.URL != "" {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return "", fmt.Errorf("invalid database URL: %w", err)
+		}
+		if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+			return "", fmt.Errorf("database URL must start with postgres://, got %q", u.Scheme+"://")
+		}
+		if u.Host == "" {
+			return "", errors.New("database URL has no host")
+		}
+		query := u.Query()
+		for key, value := range tls {
+			if query.Get(key) == "" && value != "" {
+				query.Set(key, value)
+			}
+		}
+		if query.Get("statement_timeout") == "" && c.StatementTimeout > 0 {
+			query.Set("statement_timeout", statementTimeout(c.StatementTimeout))
+		}
+		u.RawQuery = query.Encode()
+		if err := validateTLS(query.Get("sslmode"), query.Get("sslrootcert"), query.Get("sslcert"), query.Get("sslkey")); err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+
+	if c.Host == "" || c.User == "" || c.Name == "" {
+		return "", errors.New("the database host, user and name are required")
+	}
+	if err := validateTLS(c.SSLMode, c.SSLRootCert, c.SSLCert, c.SSLKey); err != nil {
+		return "", err
+	}
+	params := []string{
+		"host=" + quoteParam(c.Host),
+		"port=" + quoteParam(c.Port),
+		"user=" + quoteParam(c.User),
+		"password=" + quoteParam(c.Password),
+		"dbname=" + quoteParam(c.Name),
+	}
+	for _, key := range []string{"sslmode", "sslrootcert", "sslcert", "sslkey"} {
+		if tls[key] != "" {
+			params = append(params, key+"="+quoteParam(tls[key]))
+		}
+	}
+	if c.StatementTimeout > 0 {
+		params = append(params, "statement_timeout="+statementTimeout(c.StatementTimeout))
+	}
+	return strings.Join(params, " "), nil
+}
+
+// statementTimeout formats a timeout in milliseconds, the unit of Postgres' statement_timeout.
+// lib/pq passes parameters it does not know to the server as runtime settings.
+func statementTimeout(d time.Duration) string {
+	ms := d.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	return strconv.FormatInt(ms, 10)
+}
+
+func validateTLS(mode, rootCert, cert, key string) error {
+	if mode == "" {
+		mode = "require" // lib/pq's default
+	}
+	supported := false
+	for _, m := range sslModes {
+		supported = supported || mode == m
+	}
+	if !supported {
+		return fmt.Errorf("sslmode %q is not supported, must be one of %s", mode, strings.Join(sslModes, ", "))
+	}
+	if mode == "disable" && (rootCert != "" || cert != "" || key != "") {
+		return errors.New("TLS certificates are configured, but sslmode is disable")
+	}
+	if (cert == "") != (key == "") {
+		return errors.New("the client certificate and key must be configured together")
+	}
+
+	for name, path := range map[string]string{"sslrootcert": rootCert, "sslcert": cert, "sslkey": key} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		// lib/pq refuses keys other users can read
+		if name == "sslkey" && info.Mode().Perm()&0o077 != 0 {
+			return fmt.Errorf("sslkey %s must not be accessible by group or others (chmod 600)", path)
+		}
+	}
+	return nil
+}
+
+// quoteParam quotes a value of a key=value connection string
+func quoteParam(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"service-weaver/internal/models"
+)
+
+var (
+	// ErrEnvironmentNameTaken is returned when another environment already has the name
+	ErrEnvironmentNameTaken = errors.New("an environment with this name already exists")
+	// ErrEnvironmentInUse is returned when deleting an environment diagrams or services are labelled with
+	ErrEnvironmentInUse = errors.New("diagrams or services are in the environment; move them to another environment first")
+)
+
+const environmentColumns = `id, name, description, max_severity, created_at, updated_at`
+
+func scanEnvironment(row rowScanner, e *models.Environment) error {
+	return row.Scan(&e.ID, &e.Name, &e.Description, &e.MaxSeverity, &e.CreatedAt, &e.UpdatedAt)
+}
+
+// environmentServices is a subquery selecting the services in the environment given as argument
+// $arg: those labelled with it, and the unlabelled services of diagrams labelled with it
+func environmentServices(arg int) string {
+	return fmt.Sprintf(`(SELECT s.id FROM services s JOIN diagrams d ON d.id = s.diagram_id
+		WHERE COALESCE(NULLIF(s.environment, ''), d.environment) = $%d)`, arg)
+}
+
+// environmentNameTaken reports whether an environment other than id has the name
+func environmentNameTaken(ctx context.Context, tx *sql.Tx, name string, id int) (bool, error) {
+	var taken bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM environments WHERE name = $1 AND id <> $2)`, name, id).Scan(&taken)
+	return taken, err
+}
+
+// GetEnvironments returns every environment in the order they were defined
+func (r *Repository) GetEnvironments(ctx context.Context) ([]models.Environment, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+environmentColumns+` FROM environments ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	environments := []models.Environment{}
+	for rows.Next() {
+		var e models.Environment
+		if err := scanEnvironment(rows, &e); err != nil {
+			return nil, err
+		}
+		environments = append(environments, e)
+	}
+	return environments, rows.Err()
+}
+
+// GetEnvironmentByName returns an environment, sql.ErrNoRows when it does not exist
+func (r *Repository) GetEnvironmentByName(ctx context.Context, name string) (*models.Environment, error) {
+	var e models.Environment
+	if err := scanEnvironment(r.db.QueryRowContext(ctx, `SELECT `+environmentColumns+` FROM environments WHERE name = $1`, name), &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// CreateEnvironment stores a new environment, failing with ErrEnvironmentNameTaken for a taken name
+func (r *Repository) CreateEnvironment(ctx context.Context, environment *models.Environment) error {
+	return r.WithTx(ctx, func(tx *sql.Tx) error {
+		taken, err := environmentNameTaken(ctx, tx, environment.Name, 0)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrEnvironmentNameTaken
+		}
+		query := `INSERT INTO environments (name, description, max_severity) VALUES ($1, $2, $3) RETURNING ` + environmentColumns
+		return scanEnvironment(tx.QueryRowContext(ctx, query, environment.Name, environment.Description, environment.MaxSeverity), environment)
+	})
+}
+
+// UpdateEnvironment replaces an environment. Renaming it relabels its diagrams and services in the
+// same transaction, and the scheduler reloads them. It fails with sql.ErrNoRows when the
+// environment does not exist.
+func (r *Repository) UpdateEnvironment(ctx context.Context, environment *models.Environment) error {
+	return r.changed(r.WithTx(ctx, func(tx *sql.Tx) error {
+		taken, err := environmentNameTaken(ctx, tx, environment.Name, environment.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrEnvironmentNameTaken
+		}
+		var previous string
+		if err := tx.QueryRowContext(ctx, `SELECT name FROM environments WHERE id = $1 FOR UPDATE`, environment.ID).Scan(&previous); err != nil {
+			return err
+		}
+		query := `UPDATE environments SET name = $2, description = $3, max_severity = $4, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 RETURNING ` + environmentColumns
+		if err := scanEnvironment(tx.QueryRowContext(ctx, query, environment.ID, environment.Name, environment.Description, environment.MaxSeverity), environment); err != nil {
+			return err
+		}
+		if previous == environment.Name {
+			return nil
+		}
+		for _, table := range []string{"diagrams", "services"} {
+			if _, err := tx.ExecContext(ctx, `UPDATE `+table+` SET environment = $1 WHERE environment = $2`, environment.Name, previous); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// DeleteEnvironment deletes an environment no diagram or service is labelled with, failing with
+// ErrEnvironmentInUse otherwise. Diagrams and services in the trash count too.
+func (r *Repository) DeleteEnvironment(ctx context.Context, id int) error {
+	return r.WithTx(ctx, func(tx *sql.Tx) error {
+		var name string
+		err := tx.QueryRowContext(ctx, `SELECT name FROM environments WHERE id = $1 FOR UPDATE`, id).Scan(&name)
+		if err != nil {
+			return err
+		}
+		var inUse bool
+		query := `SELECT EXISTS (SELECT 1 FROM diagrams WHERE environment = $1) OR EXISTS (SELECT 1 FROM services WHERE environment = $1)`
+		if err := tx.QueryRowContext(ctx, query, name).Scan(&inUse); err != nil {
+			return err
+		}
+		if inUse {
+			return ErrEnvironmentInUse
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM environments WHERE id = $1`, id)
+		return err
+	})
+}
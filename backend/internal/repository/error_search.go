@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"service-weaver/internal/models"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// errorSignature is the SQL expression normalizing the error of a result r into its signature:
+// UUIDs and then any other run of digits are replaced with placeholders
+const errorSignature = `regexp_replace(
+		regexp_replace(r.error, '[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}', '<id>', 'g'),
+		'[0-9]+', '<n>', 'g')`
+
+// ErrorFilter narrows down the healthcheck errors searched. Zero values disable the corresponding filter.
+type ErrorFilter struct {
+	OrganizationID int
+	PublicOnly     bool
+	Query          string // substring of the error, case insensitive
+	ServiceIDs     []int64
+	From           time.Time
+	To             time.Time
+	Limit          int
+	Offset         int
+}
+
+// SearchErrors groups the errors of the healthcheck results of the organization's services by
+// signature, the most frequent first, and returns a page of them along with the number of
+// signatures. Only errors of public diagrams are searched when filter.PublicOnly is set.
+func (r *Repository) SearchErrors(ctx context.Context, filter ErrorFilter) ([]models.ErrorSignature, int, error) {
+	conditions := []string{"r.error IS NOT NULL", "r.error <> ''", "d.organization_id = $1"}
+	args := []interface{}{filter.OrganizationID}
+	if filter.PublicOnly {
+		conditions = append(conditions, "d.public")
+	}
+	if query := strings.TrimSpace(filter.Query); query != "" {
+		args = append(args, "%"+escapeLike(query)+"%")
+		conditions = append(conditions, fmt.Sprintf("r.error ILIKE $%d", len(args)))
+	}
+	if len(filter.ServiceIDs) > 0 {
+		args = append(args, pq.Array(filter.ServiceIDs))
+		conditions = append(conditions, fmt.Sprintf("r.service_id = ANY($%d)", len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("r.checked_at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("r.checked_at < $%d", len(args)))
+	}
+	matches := `WITH matches AS (
+			SELECT r.service_id, r.error, r.checked_at, ` + errorSignature + ` AS signature
+			FROM healthcheck_results r
+			JOIN services s ON s.id = r.service_id
+			JOIN diagrams d ON d.id = s.diagram_id
+			WHERE ` + strings.Join(conditions, " AND ") + `
+		)`
+
+	var total int
+	if err := r.replica.QueryRowContext(ctx, matches+` SELECT COUNT(DISTINCT signature) FROM matches`, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`%s
+		SELECT signature, (array_agg(error ORDER BY checked_at DESC))[1], COUNT(*),
+			array_agg(DISTINCT service_id ORDER BY service_id), MIN(checked_at), MAX(checked_at)
+		FROM matches
+		GROUP BY signature
+		ORDER BY COUNT(*) DESC, MAX(checked_at) DESC, signature
+		LIMIT $%d OFFSET $%d`, matches, len(args)-1, len(args))
+	rows, err := r.replica.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	signatures := []models.ErrorSignature{}
+	for rows.Next() {
+		var sig models.ErrorSignature
+		if err := rows.Scan(&sig.Signature, &sig.Example, &sig.Count, pq.Array(&sig.ServiceIDs), &sig.FirstSeen, &sig.LastSeen); err != nil {
+			return nil, 0, err
+		}
+		signatures = append(signatures, sig)
+	}
+	return signatures, total, rows.Err()
+}
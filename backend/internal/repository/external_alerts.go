@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"service-weaver/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// MatchAlertServices returns the services matching any of the labels of an alert under mapping.
+// Host labels may carry a port, as Prometheus instance labels do.
+func (r *Repository) MatchAlertServices(ctx context.Context, labels map[string]string, mapping []models.AlertLabelMapping) ([]int, error) {
+	var conditions []string
+	var args []interface{}
+	for _, m := range mapping {
+		value := strings.TrimSpace(labels[m.Label])
+		if value == "" {
+			continue
+		}
+		switch m.Field {
+		case "id":
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			args = append(args, id)
+			conditions = append(conditions, fmt.Sprintf("id = $%d", len(args)))
+		case "name":
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("LOWER(name) = LOWER($%d)", len(args)))
+		case "host":
+			if host, _, err := net.SplitHostPort(value); err == nil {
+				value = host
+			}
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("LOWER(host) = LOWER($%d)", len(args)))
+		case "tag":
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf("$%d = ANY(tags)", len(args)))
+		}
+	}
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	query := `SELECT id FROM services WHERE deleted_at IS NULL AND (` + strings.Join(conditions, " OR ") + `) ORDER BY id`
+	rows, err := r.replica.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ApplyExternalAlerts replaces the services of the alerts with the given fingerprints by firing:
+// resolved alerts are left out of firing and so cleared, and firing ones follow the services they
+// match now. The scheduler is told, so the statuses change with the next checks.
+func (r *Repository) ApplyExternalAlerts(ctx context.Context, fingerprints []string, firing []models.ExternalAlert) error {
+	return r.changed(r.WithTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM external_alerts WHERE fingerprint = ANY($1)`, pq.Array(fingerprints)); err != nil {
+			return err
+		}
+		query := `INSERT INTO external_alerts (fingerprint, service_id, name, severity, status, summary, starts_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (fingerprint, service_id) DO UPDATE SET name = EXCLUDED.name, severity = EXCLUDED.severity,
+				status = EXCLUDED.status, summary = EXCLUDED.summary, starts_at = EXCLUDED.starts_at, received_at = CURRENT_TIMESTAMP`
+		for _, alert := range firing {
+			if _, err := tx.ExecContext(ctx, query, alert.Fingerprint, alert.ServiceID, alert.Name, alert.Severity,
+				alert.Status, alert.Summary, alert.StartsAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// GetExternalAlerts returns the alerts firing for services, the most severe of each service first
+func (r *Repository) GetExternalAlerts(ctx context.Context) ([]models.ExternalAlert, error) {
+	query := `SELECT fingerprint, service_id, name, severity, status, summary, starts_at, received_at
+		FROM external_alerts
+		ORDER BY service_id, CASE status WHEN 'dead' THEN 0 ELSE 1 END, starts_at`
+	rows, err := r.replica.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []models.ExternalAlert{}
+	for rows.Next() {
+		var alert models.ExternalAlert
+		if err := rows.Scan(&alert.Fingerprint, &alert.ServiceID, &alert.Name, &alert.Severity, &alert.Status,
+			&alert.Summary, &alert.StartsAt, &alert.ReceivedAt); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
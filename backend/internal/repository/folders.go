@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"service-weaver/internal/models"
+)
+
+var (
+	// ErrFolderCycle is returned when a folder would be moved below itself
+	ErrFolderCycle = errors.New("a folder cannot be moved into itself or one of its subfolders")
+	// ErrFolderNotEmpty is returned when deleting a folder that still holds diagrams or subfolders
+	ErrFolderNotEmpty = errors.New("folder still contains diagrams or subfolders")
+)
+
+// folderTreeQuery selects the IDs of a folder and every folder nested under it; %d is the
+// placeholder number of the root folder ID
+const folderTreeQuery = `WITH RECURSIVE tree AS (
+		SELECT id FROM folders WHERE id = $%d
+		UNION ALL
+		SELECT f.id FROM folders f JOIN tree t ON f.parent_id = t.id
+	) SELECT id FROM tree`
+
+// folderColumns lists the columns read by scanFolder, in order
+const folderColumns = `id, COALESCE(organization_id, 0), name, parent_id, default_public,
+	(SELECT COUNT(*) FROM diagrams d WHERE d.folder_id = folders.id AND d.deleted_at IS NULL),
+	created_at, updated_at`
+
+func scanFolder(row rowScanner, f *models.Folder) error {
+	return row.Scan(&f.ID, &f.OrganizationID, &f.Name, &f.ParentID, &f.DefaultPublic, &f.DiagramCount, &f.CreatedAt, &f.UpdatedAt)
+}
+
+// Folder operations
+func (r *Repository) CreateFolder(ctx context.Context, folder *models.Folder) error {
+	query := `INSERT INTO folders (organization_id, name, parent_id, default_public) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`
+	return r.db.QueryRowContext(ctx, query, folder.OrganizationID, folder.Name, folder.ParentID, folder.DefaultPublic).Scan(&folder.ID, &folder.CreatedAt, &folder.UpdatedAt)
+}
+
+// GetFolders lists the folders of an organization
+func (r *Repository) GetFolders(ctx context.Context, orgID int) ([]models.Folder, error) {
+	query := `SELECT ` + folderColumns + ` FROM folders WHERE organization_id = $1 ORDER BY name, id`
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []models.Folder{}
+	for rows.Next() {
+		var f models.Folder
+		if err := scanFolder(rows, &f); err != nil {
+			return nil, err
+		}
+		folders = append(folders, f)
+	}
+	return folders, rows.Err()
+}
+
+func (r *Repository) GetFolder(ctx context.Context, id int) (*models.Folder, error) {
+	query := `SELECT ` + folderColumns + ` FROM folders WHERE id = $1`
+	var f models.Folder
+	if err := scanFolder(r.db.QueryRowContext(ctx, query, id), &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// UpdateFolder saves a folder. It returns ErrFolderCycle when the new parent is the folder itself
+// or one of its descendants.
+func (r *Repository) UpdateFolder(ctx context.Context, folder *models.Folder) error {
+	if folder.ParentID != nil {
+		var cycle bool
+		query := `SELECT $2 IN (` + fmt.Sprintf(folderTreeQuery, 1) + `)`
+		if err := r.db.QueryRowContext(ctx, query, folder.ID, *folder.ParentID).Scan(&cycle); err != nil {
+			return err
+		}
+		if cycle {
+			return ErrFolderCycle
+		}
+	}
+
+	query := `UPDATE folders SET name = $1, parent_id = $2, default_public = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4 RETURNING created_at, updated_at`
+	return r.db.QueryRowContext(ctx, query, folder.Name, folder.ParentID, folder.DefaultPublic, folder.ID).Scan(&folder.CreatedAt, &folder.UpdatedAt)
+}
+
+// DeleteFolder removes an empty folder. Diagrams in the trash fall back to the top level.
+func (r *Repository) DeleteFolder(ctx context.Context, id int) error {
+	var used bool
+	query := `SELECT EXISTS (SELECT 1 FROM folders WHERE parent_id = $1)
+		OR EXISTS (SELECT 1 FROM diagrams WHERE folder_id = $1 AND deleted_at IS NULL)`
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&used); err != nil {
+		return err
+	}
+	if used {
+		return ErrFolderNotEmpty
+	}
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM folders WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// MoveDiagram files a diagram under a folder, or at the top level when folderID is nil
+func (r *Repository) MoveDiagram(ctx context.Context, diagramID int, folderID *int) error {
+	query := `UPDATE diagrams SET folder_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND deleted_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, folderID, diagramID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
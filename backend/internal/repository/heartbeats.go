@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"service-weaver/internal/models"
+	"strings"
+)
+
+// maxPingBody caps what is kept of the body of a ping
+const maxPingBody = 10 << 10
+
+const heartbeatColumns = `service_id, ping_key, period, grace, last_ping_at, last_start_at, last_signal, last_exit_status, last_body, created_at`
+
+func scanHeartbeat(row rowScanner, hb *models.Heartbeat) error {
+	return row.Scan(&hb.ServiceID, &hb.PingKey, &hb.Period, &hb.Grace, &hb.LastPingAt, &hb.LastStartAt, &hb.LastSignal, &hb.LastExitStatus, &hb.LastBody, &hb.CreatedAt)
+}
+
+// newPingKey generates a random version 4 UUID, the form of healthchecks.io ping keys
+func newPingKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// GetHeartbeat returns the heartbeat of a service, sql.ErrNoRows until it is set
+func (r *Repository) GetHeartbeat(ctx context.Context, serviceID int) (*models.Heartbeat, error) {
+	var hb models.Heartbeat
+	if err := scanHeartbeat(r.db.QueryRowContext(ctx, `SELECT `+heartbeatColumns+` FROM heartbeats WHERE service_id = $1`, serviceID), &hb); err != nil {
+		return nil, err
+	}
+	return &hb, nil
+}
+
+// SetHeartbeat sets how often the job of a service runs, giving the service a ping key the first time
+func (r *Repository) SetHeartbeat(ctx context.Context, serviceID, period, grace int) (*models.Heartbeat, error) {
+	key, err := newPingKey()
+	if err != nil {
+		return nil, err
+	}
+	query := `INSERT INTO heartbeats (service_id, ping_key, period, grace) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (service_id) DO UPDATE SET period = EXCLUDED.period, grace = EXCLUDED.grace
+		RETURNING ` + heartbeatColumns
+	var hb models.Heartbeat
+	if err := scanHeartbeat(r.db.QueryRowContext(ctx, query, serviceID, key, period, grace), &hb); err != nil {
+		return nil, err
+	}
+	return &hb, nil
+}
+
+// RecordPing stores a ping to the heartbeat of a ping key. Start signals mark a run as started;
+// success and fail signals as finished. Log signals only keep the body. Unknown keys give
+// sql.ErrNoRows.
+func (r *Repository) RecordPing(ctx context.Context, pingKey string, signal models.HeartbeatSignal, exitStatus *int, body string) error {
+	if len(body) > maxPingBody {
+		body = body[:maxPingBody]
+	}
+	body = strings.ReplaceAll(strings.ToValidUTF8(body, ""), "\x00", "")
+
+	var query string
+	args := []interface{}{pingKey, body}
+	switch signal {
+	case models.HeartbeatStart:
+		query = `UPDATE heartbeats SET last_start_at = CURRENT_TIMESTAMP, last_body = $2 WHERE ping_key = $1`
+	case models.HeartbeatLog:
+		query = `UPDATE heartbeats SET last_body = $2 WHERE ping_key = $1`
+	default:
+		query = `UPDATE heartbeats SET last_ping_at = CURRENT_TIMESTAMP, last_signal = $3, last_exit_status = $4, last_body = $2
+			WHERE ping_key = $1`
+		args = append(args, signal, exitStatus)
+	}
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"service-weaver/internal/blobstore"
+	"strings"
+)
+
+// IconStore keeps the images of uploaded service icons; main configures it at startup. Until it
+// does, images stay embedded in the icon column as data URLs.
+var IconStore blobstore.Store
+
+// iconExtensions lists the image types stored as icons, by content type. Others, SVG in
+// particular, which could run scripts when opened from the API's origin, stay embedded.
+var iconExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// storedIconPattern matches the URLs iconURL returns, which copies of a service carry over
+var storedIconPattern = regexp.MustCompile(`^/api/services/\d+/icon\?v=([0-9a-f]{64}\.[a-z]+)$`)
+
+// iconURL is where clients load the image stored under key as the icon of service id. The key
+// names the image by its hash, so the URL changes along with it and can be cached for good.
+func iconURL(id int, key string) string {
+	return fmt.Sprintf("/api/services/%d/icon?v=%s", id, path.Base(key))
+}
+
+// storeIcon moves an image embedded in icon as a data URL to IconStore, and returns what remains
+// to be written to the icon and icon_key columns. Icons referring to a stored image keep it.
+func storeIcon(ctx context.Context, icon string) (string, string, error) {
+	if m := storedIconPattern.FindStringSubmatch(icon); m != nil {
+		return "", "icons/" + m[1], nil
+	}
+	if IconStore == nil || !strings.HasPrefix(icon, "data:") {
+		return icon, "", nil
+	}
+
+	header, encoded, ok := strings.Cut(strings.TrimPrefix(icon, "data:"), ",")
+	contentType, isBase64 := strings.CutSuffix(header, ";base64")
+	extension := iconExtensions[contentType]
+	if !ok || !isBase64 || extension == "" {
+		return icon, "", nil
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return icon, "", nil
+	}
+
+	sum := sha256.Sum256(data)
+	key := "icons/" + hex.EncodeToString(sum[:]) + extension
+	if err := IconStore.Put(ctx, key, data, contentType); err != nil {
+		return "", "", fmt.Errorf("storing icon: %w", err)
+	}
+	return "", key, nil
+}
+
+// GetServiceIcon returns the uploaded icon of a service and its content type. sql.ErrNoRows means
+// the service has none; blobstore.ErrNotFound that it went missing from storage.
+func (r *Repository) GetServiceIcon(ctx context.Context, id int) ([]byte, string, error) {
+	var key string
+	err := r.db.QueryRowContext(ctx, `SELECT icon_key FROM services WHERE id = $1 AND deleted_at IS NULL`, id).Scan(&key)
+	if err != nil {
+		return nil, "", err
+	}
+	if key == "" || IconStore == nil {
+		return nil, "", sql.ErrNoRows
+	}
+	return IconStore.Get(ctx, key)
+}
+
+// MoveEmbeddedIcons moves the images embedded in the icon column, as they were before blob
+// storage, to IconStore and returns how many services it changed
+func (r *Repository) MoveEmbeddedIcons(ctx context.Context) (int, error) {
+	if IconStore == nil {
+		return 0, nil
+	}
+	// The images are read one at a time, as there may be many megabytes of them
+	rows, err := r.db.QueryContext(ctx, `SELECT id FROM services WHERE icon LIKE 'data:%'`)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, id := range ids {
+		var icon string
+		if err := r.db.QueryRowContext(ctx, `SELECT icon FROM services WHERE id = $1`, id).Scan(&icon); err != nil {
+			return moved, err
+		}
+		remaining, key, err := storeIcon(ctx, icon)
+		if err != nil {
+			return moved, err
+		}
+		if key == "" {
+			continue
+		}
+		// The icon may have changed meanwhile, in which case it is left for the next startup
+		result, err := r.db.ExecContext(ctx, `UPDATE services SET icon = $1, icon_key = $2 WHERE id = $3 AND icon = $4`, remaining, key, id, icon)
+		if err != nil {
+			return moved, err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			moved++
+		}
+	}
+	return moved, nil
+}
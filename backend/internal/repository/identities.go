@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"service-weaver/internal/models"
+)
+
+// GetUserByIdentity returns the user linked to a subject of an external identity provider
+func (r *Repository) GetUserByIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	query := `SELECT u.id, u.username, u.password_hash, u.email, u.role, u.created_at, u.updated_at
+		FROM users u JOIN user_identities i ON i.user_id = u.id
+		WHERE i.issuer = $1 AND i.subject = $2`
+	var u models.User
+	err := r.db.QueryRowContext(ctx, query, issuer, subject).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Email, &u.Role, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetUserByEmail returns the user with the email address
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	query := `SELECT id, username, password_hash, email, role, created_at, updated_at FROM users WHERE LOWER(email) = LOWER($1)`
+	var u models.User
+	err := r.db.QueryRowContext(ctx, query, email).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Email, &u.Role, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// LinkUserIdentity lets an existing user sign in through an external identity provider
+func (r *Repository) LinkUserIdentity(ctx context.Context, userID int, issuer, subject string) error {
+	query := `INSERT INTO user_identities (user_id, issuer, subject) VALUES ($1, $2, $3)`
+	_, err := r.db.ExecContext(ctx, query, userID, issuer, subject)
+	return err
+}
+
+// CreateIdentityUser provisions a user for an external identity and adds them to the primary
+// organization as a member. Users without a password hash can only sign in through the provider.
+// A taken username gets a numeric suffix.
+func (r *Repository) CreateIdentityUser(ctx context.Context, user *models.User, issuer, subject string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	username := user.Username
+	for i := 2; ; i++ {
+		var taken bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE username = $1)`, username).Scan(&taken); err != nil {
+			return err
+		}
+		if !taken {
+			break
+		}
+		if i > 100 {
+			return fmt.Errorf("no free username for %q", user.Username)
+		}
+		username = fmt.Sprintf("%s-%d", user.Username, i)
+	}
+	user.Username = username
+
+	query := `INSERT INTO users (username, password_hash, email, role) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`
+	if err := tx.QueryRowContext(ctx, query, user.Username, user.PasswordHash, user.Email, user.Role).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return err
+	}
+	query = `INSERT INTO user_identities (user_id, issuer, subject) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, query, user.ID, issuer, subject); err != nil {
+		return err
+	}
+	query = `INSERT INTO organization_members (organization_id, user_id, role)
+		SELECT id, $1, $2 FROM organizations ORDER BY id LIMIT 1`
+	if _, err := tx.ExecContext(ctx, query, user.ID, models.OrgRoleMember); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateUserRole changes the role of a user
+func (r *Repository) UpdateUserRole(ctx context.Context, id int, role models.UserRole) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET role = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, role, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
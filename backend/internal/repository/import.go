@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"service-weaver/internal/models"
+)
+
+// ImportItemError identifies the service or connection that caused an import to be rolled back
+type ImportItemError struct {
+	List  string // "services" or "connections"
+	Index int
+	Err   error
+}
+
+func (e *ImportItemError) Error() string {
+	return fmt.Sprintf("%s %d: %v", e.List, e.Index, e.Err)
+}
+
+func (e *ImportItemError) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnknownEndpoint is returned when an imported connection refers to a service not in the import
+var ErrUnknownEndpoint = errors.New("source_id and target_id must refer to services of the import")
+
+// ImportDiagram creates a diagram with its services and connections in a single transaction, so a
+// failed import leaves nothing behind. Services get new IDs; connections refer to services by the
+// IDs they have in the import and are re-created between the new ones. Links to child diagrams are
+// dropped, as those diagrams are not part of the import, and so are owner users, who may belong to
+// another installation; owner teams are kept. On success the arguments hold the stored
+// rows; if an item fails a *ImportItemError identifying it is returned.
+func (r *Repository) ImportDiagram(ctx context.Context, diagram *models.Diagram, services []models.Service, connections []models.Connection) error {
+	return r.changed(r.WithTx(ctx, func(tx *sql.Tx) error {
+		query := `INSERT INTO diagrams (organization_id, name, description, public, folder_id, service_defaults, owner_team, environment) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING ` + diagramColumns
+		if err := scanDiagram(tx.QueryRowContext(ctx, query, diagram.OrganizationID, diagram.Name, diagram.Description, diagram.Public, diagram.FolderID, diagram.ServiceDefaults, diagram.OwnerTeam, diagram.Environment), diagram); err != nil {
+			return err
+		}
+
+		ids := make(map[int]int, len(services))
+		for i := range services {
+			oldID := services[i].ID
+			services[i].DiagramID = diagram.ID
+			services[i].ChildDiagramID = nil
+			services[i].OwnerUserID = nil
+			if err := createService(ctx, tx, &services[i]); err != nil {
+				return &ImportItemError{List: "services", Index: i, Err: err}
+			}
+			ids[oldID] = services[i].ID
+		}
+
+		for i := range connections {
+			source, okSource := ids[connections[i].SourceID]
+			target, okTarget := ids[connections[i].TargetID]
+			if !okSource || !okTarget {
+				return &ImportItemError{List: "connections", Index: i, Err: ErrUnknownEndpoint}
+			}
+			connections[i].DiagramID = diagram.ID
+			connections[i].SourceID = source
+			connections[i].TargetID = target
+			if err := createConnection(ctx, tx, &connections[i]); err != nil {
+				return &ImportItemError{List: "connections", Index: i, Err: err}
+			}
+		}
+		return nil
+	}))
+}
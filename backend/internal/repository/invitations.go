@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"service-weaver/internal/models"
+	"time"
+)
+
+// ErrUserExists is returned when inviting, or accepting the invitation of, a user whose username
+// or email is already taken
+var ErrUserExists = errors.New("username or email already in use")
+
+const invitationColumns = `id, organization_id, email, username, role, invited_by, expires_at, accepted_at, created_at`
+
+func scanInvitation(row rowScanner, inv *models.Invitation) error {
+	return row.Scan(&inv.ID, &inv.OrganizationID, &inv.Email, &inv.Username, &inv.Role, &inv.InvitedBy, &inv.ExpiresAt, &inv.AcceptedAt, &inv.CreatedAt)
+}
+
+// userExists reports whether a user already has the username or email
+func userExists(ctx context.Context, q queryRunner, username, email string) (bool, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE username = $1 OR LOWER(email) = LOWER($2))`, username, email).Scan(&exists)
+	return exists, err
+}
+
+// CreateInvitation stores an invitation to the organization valid for ttl under the hash of its
+// token, replacing the pending invitations of the same username or email there
+func (r *Repository) CreateInvitation(ctx context.Context, inv *models.Invitation, tokenHash string, ttl time.Duration) error {
+	return r.WithTx(ctx, func(tx *sql.Tx) error {
+		exists, err := userExists(ctx, tx, inv.Username, inv.Email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrUserExists
+		}
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM invitations
+			WHERE organization_id = $1 AND accepted_at IS NULL AND (username = $2 OR LOWER(email) = LOWER($3))`,
+			inv.OrganizationID, inv.Username, inv.Email)
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT INTO invitations (organization_id, email, username, role, token_hash, invited_by, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP + make_interval(secs => $7))
+			RETURNING ` + invitationColumns
+		return scanInvitation(tx.QueryRowContext(ctx, query, inv.OrganizationID, inv.Email, inv.Username, inv.Role, tokenHash, inv.InvitedBy, ttl.Seconds()), inv)
+	})
+}
+
+// GetInvitations returns the pending invitations of an organization, the newest first. Expired
+// ones are included until they are renewed or deleted.
+func (r *Repository) GetInvitations(ctx context.Context, orgID int) ([]models.Invitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM invitations
+		WHERE organization_id = $1 AND accepted_at IS NULL
+		ORDER BY created_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.Invitation{}
+	for rows.Next() {
+		var inv models.Invitation
+		if err := scanInvitation(rows, &inv); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+// GetInvitationByToken returns the pending invitation a token belongs to. Unknown, expired and
+// accepted invitations give sql.ErrNoRows.
+func (r *Repository) GetInvitationByToken(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM invitations
+		WHERE token_hash = $1 AND accepted_at IS NULL AND expires_at > CURRENT_TIMESTAMP`
+	var inv models.Invitation
+	if err := scanInvitation(r.db.QueryRowContext(ctx, query, tokenHash), &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// RenewInvitation replaces the token of a pending invitation of the organization, which makes the
+// one sent before unusable, and extends it by ttl
+func (r *Repository) RenewInvitation(ctx context.Context, orgID, id int, tokenHash string, ttl time.Duration) (*models.Invitation, error) {
+	query := `UPDATE invitations SET token_hash = $3, expires_at = CURRENT_TIMESTAMP + make_interval(secs => $4)
+		WHERE id = $1 AND organization_id = $2 AND accepted_at IS NULL
+		RETURNING ` + invitationColumns
+	var inv models.Invitation
+	if err := scanInvitation(r.db.QueryRowContext(ctx, query, id, orgID, tokenHash, ttl.Seconds()), &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// DeleteInvitation revokes a pending invitation of the organization
+func (r *Repository) DeleteInvitation(ctx context.Context, orgID, id int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM invitations WHERE id = $1 AND organization_id = $2 AND accepted_at IS NULL`, id, orgID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AcceptInvitation creates the invited user with the password hash and adds them to the inviting
+// organization, an admin of it if invited as one. Unknown, expired and accepted invitations give
+// sql.ErrNoRows; ErrUserExists means the username or email was taken since the invitation.
+func (r *Repository) AcceptInvitation(ctx context.Context, tokenHash, passwordHash string) (*models.User, error) {
+	var user models.User
+	err := r.WithTx(ctx, func(tx *sql.Tx) error {
+		query := `SELECT ` + invitationColumns + ` FROM invitations
+			WHERE token_hash = $1 AND accepted_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+			FOR UPDATE`
+		var inv models.Invitation
+		if err := scanInvitation(tx.QueryRowContext(ctx, query, tokenHash), &inv); err != nil {
+			return err
+		}
+		exists, err := userExists(ctx, tx, inv.Username, inv.Email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrUserExists
+		}
+
+		query = `INSERT INTO users (username, password_hash, email, role) VALUES ($1, $2, $3, $4)
+			RETURNING id, username, email, role, created_at, updated_at`
+		err = tx.QueryRowContext(ctx, query, inv.Username, passwordHash, inv.Email, inv.Role).
+			Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		role := models.OrgRoleMember
+		if inv.Role == models.RoleAdmin {
+			role = models.OrgRoleAdmin
+		}
+		query = `INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)`
+		if _, err := tx.ExecContext(ctx, query, inv.OrganizationID, user.ID, role); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE invitations SET accepted_at = CURRENT_TIMESTAMP WHERE id = $1`, inv.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// SchedulerLockID keys the advisory lock held by the instance that schedules healthchecks
+const SchedulerLockID = 73_612_905
+
+// SessionLock is a Postgres session-level advisory lock held on a connection of its own, so that
+// one of several instances can be elected to run a job. Postgres releases the lock when the
+// holder's connection is lost, letting another instance take over.
+type SessionLock struct {
+	db   *sql.DB
+	key  int64
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+// SessionLock returns the lock keyed by key; it is not acquired yet
+func (r *Repository) SessionLock(key int64) *SessionLock {
+	return &SessionLock{db: r.db, key: key}
+}
+
+// TryAcquire takes the lock unless another instance holds it, and reports whether this instance
+// holds it now. A holder checks that its connection is still alive, as the lock went with it.
+func (l *SessionLock) TryAcquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err == nil {
+			return true, nil
+		}
+		l.conn.Close()
+		l.conn = nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, l.key).Scan(&acquired); err != nil {
+		conn.Close()
+		return false, err
+	}
+	if !acquired {
+		conn.Close()
+		return false, nil
+	}
+	l.conn = conn
+	return true, nil
+}
+
+// Release gives up the lock if this instance holds it
+func (l *SessionLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+	_, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.key)
+	l.conn.Close()
+	l.conn = nil
+	return err
+}
+
+// Membership registers this instance among those sharing a job, such as the healthchecks in
+// sharding mode. Members that missed their heartbeat for longer than the ttl are dropped.
+type Membership struct {
+	db       *sql.DB
+	instance string
+	ttl      time.Duration
+}
+
+// Membership returns this instance's membership; it joins with its first heartbeat
+func (r *Repository) Membership(ttl time.Duration) *Membership {
+	return &Membership{db: r.db, instance: r.instance, ttl: ttl}
+}
+
+// Heartbeat records that this instance is alive, drops the members that are not and returns the
+// live members' IDs in order along with this instance's own
+func (m *Membership) Heartbeat(ctx context.Context) ([]string, string, error) {
+	query := `INSERT INTO scheduler_members (instance_id) VALUES ($1)
+		ON CONFLICT (instance_id) DO UPDATE SET heartbeat_at = CURRENT_TIMESTAMP`
+	if _, err := m.db.ExecContext(ctx, query, m.instance); err != nil {
+		return nil, "", err
+	}
+	query = `DELETE FROM scheduler_members WHERE heartbeat_at < CURRENT_TIMESTAMP - make_interval(secs => $1)`
+	if _, err := m.db.ExecContext(ctx, query, m.ttl.Seconds()); err != nil {
+		return nil, "", err
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT instance_id FROM scheduler_members ORDER BY instance_id`)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	var members []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, "", err
+		}
+		members = append(members, id)
+	}
+	return members, m.instance, rows.Err()
+}
+
+// Leave removes this instance, so the others take over its share without waiting for the ttl
+func (m *Membership) Leave(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `DELETE FROM scheduler_members WHERE instance_id = $1`, m.instance)
+	return err
+}
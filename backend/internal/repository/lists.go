@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"service-weaver/internal/models"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ErrInvalidSort is returned when a list is sorted by a column that is not allowed
+var ErrInvalidSort = errors.New("invalid sort column")
+
+// ListOptions carries the pagination and ordering shared by list queries. A zero Limit returns
+// every matching row. Sort must be one of the columns allowed by the individual list method.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	Sort   string
+	Desc   bool
+}
+
+// orderBy builds an ORDER BY clause from a whitelisted sort column, using fallback when no sort
+// was requested. id is always appended as a tie-breaker so pages are stable.
+func (o ListOptions) orderBy(allowed map[string]bool, fallback string, fallbackDesc bool) (string, error) {
+	column, desc := fallback, fallbackDesc
+	if o.Sort != "" {
+		if !allowed[o.Sort] {
+			return "", fmt.Errorf("%w: %s", ErrInvalidSort, o.Sort)
+		}
+		column, desc = o.Sort, o.Desc
+	}
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	if column == "id" {
+		return "ORDER BY id " + direction, nil
+	}
+	return fmt.Sprintf("ORDER BY %s %s NULLS LAST, id %s", column, direction, direction), nil
+}
+
+// limitOffset appends LIMIT/OFFSET placeholders when pagination was requested
+func (o ListOptions) limitOffset(args []interface{}) (string, []interface{}) {
+	if o.Limit <= 0 {
+		return "", args
+	}
+	args = append(args, o.Limit, o.Offset)
+	return fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args)), args
+}
+
+// DiagramFilter narrows down a diagram list. Zero values disable the corresponding filter.
+type DiagramFilter struct {
+	OrganizationID    int
+	Name              string // Case-insensitive substring of the name or description
+	Public            *bool
+	FolderID          *int // 0 selects diagrams outside any folder
+	IncludeSubfolders bool // Also match diagrams in folders nested under FolderID
+	OwnerUserID       int
+	OwnerTeam         string
+	Environment       string
+	StarredBy         int // Only the diagrams the user starred
+	ViewedBy          int // Only the diagrams the user viewed recently, the latest first unless sorted otherwise
+	ListOptions
+}
+
+var diagramSortColumns = map[string]bool{"id": true, "name": true, "created_at": true, "updated_at": true}
+
+// ListDiagrams returns the diagrams matching filter along with the total number of matches
+func (r *Repository) ListDiagrams(ctx context.Context, filter DiagramFilter) ([]models.Diagram, int, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	args := []interface{}{}
+
+	if filter.OrganizationID != 0 {
+		args = append(args, filter.OrganizationID)
+		conditions = append(conditions, fmt.Sprintf("organization_id = $%d", len(args)))
+	}
+	if filter.Name != "" {
+		args = append(args, "%"+escapeLike(filter.Name)+"%")
+		conditions = append(conditions, fmt.Sprintf("(name ILIKE $%d OR description ILIKE $%d)", len(args), len(args)))
+	}
+	if filter.Public != nil {
+		args = append(args, *filter.Public)
+		conditions = append(conditions, fmt.Sprintf("public = $%d", len(args)))
+	}
+	if filter.FolderID != nil {
+		switch {
+		case *filter.FolderID == 0:
+			conditions = append(conditions, "folder_id IS NULL")
+		case filter.IncludeSubfolders:
+			args = append(args, *filter.FolderID)
+			conditions = append(conditions, fmt.Sprintf("folder_id IN (%s)", fmt.Sprintf(folderTreeQuery, len(args))))
+		default:
+			args = append(args, *filter.FolderID)
+			conditions = append(conditions, fmt.Sprintf("folder_id = $%d", len(args)))
+		}
+	}
+	if filter.OwnerUserID != 0 {
+		args = append(args, filter.OwnerUserID)
+		conditions = append(conditions, fmt.Sprintf("owner_user_id = $%d", len(args)))
+	}
+	if filter.OwnerTeam != "" {
+		args = append(args, filter.OwnerTeam)
+		conditions = append(conditions, fmt.Sprintf("owner_team = $%d", len(args)))
+	}
+	if filter.Environment != "" {
+		args = append(args, filter.Environment)
+		conditions = append(conditions, fmt.Sprintf("environment = $%d", len(args)))
+	}
+	if filter.StarredBy != 0 {
+		args = append(args, filter.StarredBy)
+		conditions = append(conditions, fmt.Sprintf("id IN (SELECT diagram_id FROM diagram_stars WHERE user_id = $%d)", len(args)))
+	}
+	viewedBy := 0
+	if filter.ViewedBy != 0 {
+		args = append(args, filter.ViewedBy)
+		viewedBy = len(args)
+		conditions = append(conditions, fmt.Sprintf("id IN (SELECT diagram_id FROM diagram_views WHERE user_id = $%d)", viewedBy))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	order, err := filter.orderBy(diagramSortColumns, "updated_at", true)
+	if err != nil {
+		return nil, 0, err
+	}
+	if viewedBy != 0 && filter.Sort == "" {
+		order = fmt.Sprintf(`ORDER BY (SELECT viewed_at FROM diagram_views v WHERE v.user_id = $%d AND v.diagram_id = diagrams.id) DESC, id DESC`, viewedBy)
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM diagrams WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	page, args := filter.limitOffset(args)
+	query := `SELECT ` + diagramColumns + ` FROM diagrams WHERE ` + where + ` ` + order + page
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	diagrams := []models.Diagram{}
+	for rows.Next() {
+		var d models.Diagram
+		if err := scanDiagram(rows, &d); err != nil {
+			return nil, 0, err
+		}
+		diagrams = append(diagrams, d)
+	}
+	return diagrams, total, rows.Err()
+}
+
+// ServiceFilter narrows down a service list. Zero values disable the corresponding filter.
+type ServiceFilter struct {
+	OrganizationID int
+	PublicOnly     bool // Only services of public diagrams
+	DiagramID      int
+	Name           string // Case-insensitive substring of the name or host
+	Statuses       []string
+	Types          []string
+	Tag            string // Exact match against one of the tags
+	OwnerUserID    int    // Owner of the service, or of its diagram for services without one
+	OwnerTeam      string // Team of the service, or of its diagram for services without one
+	Environment    string // Environment of the service, or of its diagram for services without one; overlays for it stand in for their services
+	ListOptions
+}
+
+var serviceSortColumns = map[string]bool{"id": true, "name": true, "service_type": true, "current_status": true, "last_checked": true, "created_at": true, "updated_at": true}
+
+// ListServices returns the services matching filter along with the total number of matches
+func (r *Repository) ListServices(ctx context.Context, filter ServiceFilter) ([]models.Service, int, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	args := []interface{}{}
+
+	if filter.OrganizationID != 0 {
+		args = append(args, filter.OrganizationID)
+		conditions = append(conditions, fmt.Sprintf("diagram_id IN (SELECT id FROM diagrams WHERE organization_id = $%d AND deleted_at IS NULL)", len(args)))
+	}
+	if filter.PublicOnly {
+		conditions = append(conditions, "diagram_id IN (SELECT id FROM diagrams WHERE public)")
+	}
+	if filter.DiagramID != 0 {
+		args = append(args, filter.DiagramID)
+		conditions = append(conditions, fmt.Sprintf("diagram_id = $%d", len(args)))
+	}
+	if filter.Name != "" {
+		args = append(args, "%"+escapeLike(filter.Name)+"%")
+		conditions = append(conditions, fmt.Sprintf("(name ILIKE $%d OR host ILIKE $%d)", len(args), len(args)))
+	}
+	if len(filter.Statuses) > 0 {
+		args = append(args, pq.Array(filter.Statuses))
+		conditions = append(conditions, fmt.Sprintf("current_status = ANY($%d)", len(args)))
+	}
+	if len(filter.Types) > 0 {
+		args = append(args, pq.Array(filter.Types))
+		conditions = append(conditions, fmt.Sprintf("service_type = ANY($%d)", len(args)))
+	}
+	if filter.Tag != "" {
+		args = append(args, strings.TrimSpace(filter.Tag))
+		conditions = append(conditions, fmt.Sprintf("tags @> ARRAY[$%d]::text[]", len(args)))
+	}
+	if filter.OwnerUserID != 0 {
+		args = append(args, filter.OwnerUserID)
+		conditions = append(conditions, fmt.Sprintf("COALESCE(owner_user_id, (SELECT d.owner_user_id FROM diagrams d WHERE d.id = services.diagram_id)) = $%d", len(args)))
+	}
+	if filter.OwnerTeam != "" {
+		args = append(args, filter.OwnerTeam)
+		conditions = append(conditions, fmt.Sprintf("COALESCE(NULLIF(owner_team, ''), (SELECT d.owner_team FROM diagrams d WHERE d.id = services.diagram_id)) = $%d", len(args)))
+	}
+	if filter.Environment != "" {
+		// Overlays for the environment stand in for their services, as the environment sees them
+		args = append(args, filter.Environment)
+		conditions = append(conditions, "id IN "+environmentServices(len(args)))
+		conditions = append(conditions, fmt.Sprintf("(overlay_of IS NOT NULL OR NOT EXISTS (SELECT 1 FROM services o WHERE o.overlay_of = services.id AND o.environment = $%d))", len(args)))
+	} else {
+		conditions = append(conditions, "overlay_of IS NULL")
+	}
+	where := strings.Join(conditions, " AND ")
+
+	order, err := filter.orderBy(serviceSortColumns, "id", false)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM services WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	page, args := filter.limitOffset(args)
+	query := `SELECT ` + serviceColumns + ` FROM services WHERE ` + where + ` ` + order + page
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	services := []models.Service{}
+	for rows.Next() {
+		var s models.Service
+		if err := scanService(rows, &s); err != nil {
+			return nil, 0, err
+		}
+		services = append(services, s)
+	}
+	return services, total, rows.Err()
+}
+
+// escapeLike escapes the LIKE wildcards in user input
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// LockoutPolicy locks an account after Threshold failed logins within Window. The first lockout
+// lasts Duration and every further one without a successful login in between twice as long, up
+// to MaxDuration.
+type LockoutPolicy struct {
+	Threshold   int // 0 disables lockout
+	Window      time.Duration
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+// GetLoginLockout returns how much longer a user's account stays locked, or 0 when it is not locked
+func (r *Repository) GetLoginLockout(ctx context.Context, userID int) (time.Duration, error) {
+	query := `SELECT COALESCE(EXTRACT(EPOCH FROM locked_until - CURRENT_TIMESTAMP), 0) FROM users WHERE id = $1`
+	var seconds float64
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&seconds); err != nil {
+		return 0, err
+	}
+	if seconds <= 0 {
+		return 0, nil
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// RecordFailedLogin counts a failed login. When it reaches the policy's threshold the account is
+// locked and the lockout duration is returned; otherwise it returns 0.
+func (r *Repository) RecordFailedLogin(ctx context.Context, userID int, policy LockoutPolicy) (time.Duration, error) {
+	if policy.Threshold <= 0 {
+		return 0, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Failures older than the window start a new count
+	query := `UPDATE users SET
+			failed_login_attempts = CASE WHEN first_failed_login_at IS NULL OR first_failed_login_at <= CURRENT_TIMESTAMP - make_interval(secs => $2)
+				THEN 1 ELSE failed_login_attempts + 1 END,
+			first_failed_login_at = CASE WHEN first_failed_login_at IS NULL OR first_failed_login_at <= CURRENT_TIMESTAMP - make_interval(secs => $2)
+				THEN CURRENT_TIMESTAMP ELSE first_failed_login_at END
+		WHERE id = $1
+		RETURNING failed_login_attempts`
+	var attempts int
+	if err := tx.QueryRowContext(ctx, query, userID, policy.Window.Seconds()).Scan(&attempts); err != nil {
+		return 0, err
+	}
+	if attempts < policy.Threshold {
+		return 0, tx.Commit()
+	}
+
+	query = `UPDATE users SET
+			locked_until = CURRENT_TIMESTAMP + make_interval(secs => LEAST($2 * power(2, lockout_count), $3)),
+			lockout_count = lockout_count + 1,
+			failed_login_attempts = 0,
+			first_failed_login_at = NULL
+		WHERE id = $1
+		RETURNING EXTRACT(EPOCH FROM locked_until - CURRENT_TIMESTAMP)`
+	var seconds float64
+	if err := tx.QueryRowContext(ctx, query, userID, policy.Duration.Seconds(), policy.MaxDuration.Seconds()).Scan(&seconds); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// ClearFailedLogins forgets failed logins and earlier lockouts after a successful login
+func (r *Repository) ClearFailedLogins(ctx context.Context, userID int) error {
+	query := `UPDATE users SET failed_login_attempts = 0, first_failed_login_at = NULL, lockout_count = 0, locked_until = NULL
+		WHERE id = $1 AND (failed_login_attempts > 0 OR lockout_count > 0 OR locked_until IS NOT NULL)`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
+// UnlockUser lifts a lockout and clears the failed login count of a user
+func (r *Repository) UnlockUser(ctx context.Context, userID int) error {
+	query := `UPDATE users SET failed_login_attempts = 0, first_failed_login_at = NULL, lockout_count = 0, locked_until = NULL WHERE id = $1`
+	res, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
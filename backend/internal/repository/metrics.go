@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"service-weaver/internal/models"
+	"time"
+)
+
+// GetResponseTimeMetrics aggregates a service's healthcheck results in [from, to) into buckets of width step
+func (r *Repository) GetResponseTimeMetrics(ctx context.Context, serviceID int, from, to time.Time, step time.Duration) ([]models.MetricsBucket, error) {
+	query := `SELECT to_timestamp(floor(extract(epoch FROM checked_at) / $4) * $4) AS bucket,
+			COUNT(*),
+			COALESCE(AVG(response_time), 0),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY response_time), 0),
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY response_time), 0),
+			COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY response_time), 0),
+			COUNT(*) FILTER (WHERE status = 'alive'),
+			COUNT(*) FILTER (WHERE status = 'degraded'),
+			COUNT(*) FILTER (WHERE status = 'dead')
+		FROM healthcheck_results
+		WHERE service_id = $1 AND checked_at >= $2 AND checked_at < $3
+		GROUP BY bucket
+		ORDER BY bucket`
+	rows, err := r.replica.QueryContext(ctx, query, serviceID, from, to, step.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []models.MetricsBucket{}
+	for rows.Next() {
+		var b models.MetricsBucket
+		err := rows.Scan(&b.Timestamp, &b.Count, &b.AvgResponseTime, &b.P50ResponseTime, &b.P95ResponseTime, &b.P99ResponseTime, &b.AliveCount, &b.DegradedCount, &b.DeadCount)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, nil
+}
+
+// GetDiagramResponseTimes aggregates the healthcheck results of every service in a diagram over [from, to)
+// into a single bucket per service, keyed by service ID
+func (r *Repository) GetDiagramResponseTimes(ctx context.Context, diagramID int, from, to time.Time) (map[int]models.MetricsBucket, error) {
+	query := `SELECT r.service_id,
+			COUNT(*),
+			COALESCE(AVG(r.response_time), 0),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY r.response_time), 0),
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY r.response_time), 0),
+			COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY r.response_time), 0),
+			COUNT(*) FILTER (WHERE r.status = 'alive'),
+			COUNT(*) FILTER (WHERE r.status = 'degraded'),
+			COUNT(*) FILTER (WHERE r.status = 'dead')
+		FROM healthcheck_results r
+		JOIN services s ON s.id = r.service_id
+		WHERE s.diagram_id = $1 AND r.checked_at >= $2 AND r.checked_at < $3
+		GROUP BY r.service_id`
+	rows, err := r.replica.QueryContext(ctx, query, diagramID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := make(map[int]models.MetricsBucket)
+	for rows.Next() {
+		var serviceID int
+		b := models.MetricsBucket{Timestamp: from}
+		err := rows.Scan(&serviceID, &b.Count, &b.AvgResponseTime, &b.P50ResponseTime, &b.P95ResponseTime, &b.P99ResponseTime, &b.AliveCount, &b.DegradedCount, &b.DeadCount)
+		if err != nil {
+			return nil, err
+		}
+		buckets[serviceID] = b
+	}
+	return buckets, nil
+}
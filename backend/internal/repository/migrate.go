@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migrations are SQL scripts named <version>_<name>.up.sql, each with a matching .down.sql that
+// reverts it. Versions are consecutive from 1 and every script runs in its own transaction.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// migrationLockID keys the advisory lock that keeps concurrently starting instances from
+// migrating at the same time
+const migrationLockID = 73_612_904
+
+var (
+	// ErrSchemaOutdated is returned by CheckSchema when migrations are pending
+	ErrSchemaOutdated = errors.New("database schema is outdated; run `service-weaver migrate up` or enable DB_AUTO_MIGRATE")
+	// ErrSchemaTooNew is returned when the database was migrated by a newer version of Service Weaver
+	ErrSchemaTooNew = errors.New("database schema is newer than this version of Service Weaver supports")
+)
+
+// Migration is one step of the schema history
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus describes a migration and when it was applied, if it was
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+var migrations = mustLoadMigrations()
+
+func mustLoadMigrations() []Migration {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		panic(err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			panic(fmt.Sprintf("invalid migration file name %s", entry.Name()))
+		}
+		content, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		version, _ := strconv.Atoi(match[1])
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if match[3] == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	for i, m := range list {
+		if m.Version != i+1 {
+			panic(fmt.Sprintf("migration %d is missing", i+1))
+		}
+		if m.up == "" || m.down == "" {
+			panic(fmt.Sprintf("migration %d needs both an up and a down script", m.Version))
+		}
+	}
+	return list
+}
+
+// LatestSchemaVersion is the schema version this build expects
+func LatestSchemaVersion() int {
+	return len(migrations)
+}
+
+// SchemaVersion returns the version the database was migrated to, 0 for an empty database
+func (r *Repository) SchemaVersion(ctx context.Context) (int, error) {
+	return schemaVersion(ctx, r.db)
+}
+
+func schemaVersion(ctx context.Context, q queryRunner) (int, error) {
+	var exists bool
+	if err := q.QueryRowContext(ctx, `SELECT to_regclass('schema_version') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+	var version int
+	err := q.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
+
+// CheckSchema verifies that the database was migrated to exactly the version this build expects
+func (r *Repository) CheckSchema(ctx context.Context) error {
+	version, err := r.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case version < LatestSchemaVersion():
+		return fmt.Errorf("%w (version %d, expected %d)", ErrSchemaOutdated, version, LatestSchemaVersion())
+	case version > LatestSchemaVersion():
+		return fmt.Errorf("%w (version %d, expected %d)", ErrSchemaTooNew, version, LatestSchemaVersion())
+	}
+	return nil
+}
+
+// Migrate applies or reverts migrations until the schema is at the target version and returns
+// the migrations it ran. Instances migrating concurrently wait for each other, so each migration
+// runs once.
+func (r *Repository) Migrate(ctx context.Context, target int) ([]Migration, error) {
+	if target < 0 || target > LatestSchemaVersion() {
+		return nil, fmt.Errorf("unknown schema version %d, latest is %d", target, LatestSchemaVersion())
+	}
+
+	var ran []Migration
+	for {
+		m, done, err := r.migrateStep(ctx, target)
+		if err != nil {
+			return ran, err
+		}
+		if done {
+			return ran, nil
+		}
+		ran = append(ran, m)
+	}
+}
+
+// migrateStep runs the next migration towards target, reporting done once the target is reached
+func (r *Repository) migrateStep(ctx context.Context, target int) (Migration, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Migration{}, false, err
+	}
+	defer tx.Rollback()
+	if err := withoutStatementTimeout(ctx, tx); err != nil {
+		return Migration{}, false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return Migration{}, false, err
+	}
+	query := `CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return Migration{}, false, err
+	}
+	version, err := schemaVersion(ctx, tx)
+	if err != nil {
+		return Migration{}, false, err
+	}
+	if version > LatestSchemaVersion() {
+		return Migration{}, false, fmt.Errorf("%w (version %d, latest known %d)", ErrSchemaTooNew, version, LatestSchemaVersion())
+	}
+	if version == target {
+		return Migration{}, true, tx.Commit()
+	}
+
+	if version < target {
+		m := migrations[version]
+		if _, err := tx.ExecContext(ctx, m.up); err != nil {
+			return m, false, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_version (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+			return m, false, err
+		}
+		return m, false, tx.Commit()
+	}
+
+	m := migrations[version-1]
+	if _, err := tx.ExecContext(ctx, m.down); err != nil {
+		return m, false, fmt.Errorf("rollback of migration %d (%s) failed: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_version WHERE version = $1`, m.Version); err != nil {
+		return m, false, err
+	}
+	return m, false, tx.Commit()
+}
+
+// MigrationStatuses lists every migration known to this build with the time it was applied
+func (r *Repository) MigrationStatuses(ctx context.Context) ([]MigrationStatus, error) {
+	applied := make(map[int]time.Time)
+	version, err := r.SchemaVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version > 0 {
+		rows, err := r.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_version`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var v int
+			var at sql.NullTime
+			if err := rows.Scan(&v, &at); err != nil {
+				return nil, err
+			}
+			applied[v] = at.Time
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,25 @@
+-- Drops the whole schema, including all data
+
+DROP TABLE IF EXISTS revoked_tokens CASCADE;
+DROP TABLE IF EXISTS sessions CASCADE;
+DROP TABLE IF EXISTS user_identities CASCADE;
+DROP TABLE IF EXISTS refresh_tokens CASCADE;
+DROP TABLE IF EXISTS folders CASCADE;
+DROP TABLE IF EXISTS webhooks CASCADE;
+DROP TABLE IF EXISTS security_events CASCADE;
+DROP TABLE IF EXISTS audit_log CASCADE;
+DROP TABLE IF EXISTS status_events CASCADE;
+DROP TABLE IF EXISTS reports CASCADE;
+DROP TABLE IF EXISTS incidents CASCADE;
+DROP TABLE IF EXISTS maintenance_windows CASCADE;
+DROP TABLE IF EXISTS healthcheck_rollups CASCADE;
+DROP TABLE IF EXISTS notification_channels CASCADE;
+DROP TABLE IF EXISTS healthcheck_results CASCADE;
+DROP TABLE IF EXISTS diagram_versions CASCADE;
+DROP TABLE IF EXISTS connections CASCADE;
+DROP TABLE IF EXISTS services CASCADE;
+DROP TABLE IF EXISTS discovery_sources CASCADE;
+DROP TABLE IF EXISTS diagrams CASCADE;
+DROP TABLE IF EXISTS organization_members CASCADE;
+DROP TABLE IF EXISTS organizations CASCADE;
+DROP TABLE IF EXISTS users CASCADE;
@@ -0,0 +1,531 @@
+-- Schema as created by createTables before versioned migrations. Every statement is idempotent,
+-- so databases created before migrations were introduced are adopted as version 1.
+
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	username VARCHAR(255) UNIQUE NOT NULL,
+	password_hash VARCHAR(255) NOT NULL,
+	email VARCHAR(255) UNIQUE NOT NULL,
+	role VARCHAR(50) NOT NULL DEFAULT 'viewer',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS organizations (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+	organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	role VARCHAR(50) NOT NULL DEFAULT 'member',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_user ON organization_members (user_id);
+
+CREATE TABLE IF NOT EXISTS diagrams (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	description TEXT,
+	public BOOLEAN DEFAULT FALSE,
+	status_page_enabled BOOLEAN DEFAULT FALSE,
+	status_page_slug VARCHAR(255) UNIQUE,
+	status_page_title VARCHAR(255) DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS discovery_sources (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	type VARCHAR(50) NOT NULL,
+	diagram_id INTEGER NOT NULL REFERENCES diagrams(id) ON DELETE CASCADE,
+	config JSONB DEFAULT '{}',
+	sync_interval INTEGER DEFAULT 0,
+	enabled BOOLEAN DEFAULT TRUE,
+	last_synced_at TIMESTAMP,
+	last_error TEXT DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS services (
+	id SERIAL PRIMARY KEY,
+	diagram_id INTEGER NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	description TEXT,
+	service_type VARCHAR(50) NOT NULL,
+	icon TEXT,
+	host VARCHAR(255),
+	port INTEGER,
+	tags TEXT,
+	position_x REAL DEFAULT 0,
+	position_y REAL DEFAULT 0,
+	healthcheck_method VARCHAR(20) DEFAULT 'HTTP',
+	healthcheck_url TEXT,
+	polling_interval INTEGER DEFAULT 30,
+	request_timeout INTEGER DEFAULT 5,
+	expected_status INTEGER DEFAULT 200,
+	status_mapping JSONB DEFAULT '{}',
+	http_method VARCHAR(10) DEFAULT 'GET',
+	headers JSONB DEFAULT '{}',
+	body TEXT,
+	ssl_verify BOOLEAN DEFAULT true,
+	follow_redirects BOOLEAN DEFAULT true,
+	tcp_send_data TEXT,
+	tcp_expect_data TEXT,
+	udp_send_data TEXT,
+	udp_expect_data TEXT,
+	icmp_packet_count INTEGER DEFAULT 3,
+	dns_query_type VARCHAR(10) DEFAULT 'A',
+	dns_expected_result TEXT,
+	kafka_topic TEXT,
+	kafka_client_id VARCHAR(255) DEFAULT 'service-weaver-healthcheck',
+	slo_target REAL DEFAULT 99.9,
+	retention_days INTEGER DEFAULT 0,
+	discovery_source_id INTEGER REFERENCES discovery_sources(id) ON DELETE SET NULL,
+	discovery_key VARCHAR(512) NOT NULL DEFAULT '',
+	discovery_hash VARCHAR(64) NOT NULL DEFAULT '',
+	discovery_deregistered_at TIMESTAMP,
+	current_status VARCHAR(20) DEFAULT 'unknown',
+	last_checked TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (diagram_id) REFERENCES diagrams(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS connections (
+	id SERIAL PRIMARY KEY,
+	diagram_id INTEGER NOT NULL,
+	source_id INTEGER NOT NULL,
+	target_id INTEGER NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (diagram_id) REFERENCES diagrams(id) ON DELETE CASCADE,
+	FOREIGN KEY (source_id) REFERENCES services(id) ON DELETE CASCADE,
+	FOREIGN KEY (target_id) REFERENCES services(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS diagram_versions (
+	id SERIAL PRIMARY KEY,
+	diagram_id INTEGER NOT NULL REFERENCES diagrams(id) ON DELETE CASCADE,
+	version INTEGER NOT NULL,
+	summary VARCHAR(255) NOT NULL DEFAULT '',
+	snapshot JSONB NOT NULL,
+	structure_hash VARCHAR(64) NOT NULL,
+	created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (diagram_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS healthcheck_results (
+	id SERIAL PRIMARY KEY,
+	service_id INTEGER NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	status_code INTEGER,
+	response_time INTEGER,
+	error TEXT,
+	checked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_healthcheck_results_service_checked_at ON healthcheck_results (service_id, checked_at DESC);
+
+CREATE TABLE IF NOT EXISTS notification_channels (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	type VARCHAR(50) NOT NULL,
+	config JSONB DEFAULT '{}',
+	enabled BOOLEAN DEFAULT TRUE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS healthcheck_rollups (
+	id SERIAL PRIMARY KEY,
+	service_id INTEGER NOT NULL,
+	granularity VARCHAR(10) NOT NULL,
+	bucket_start TIMESTAMP NOT NULL,
+	total_checks INTEGER NOT NULL DEFAULT 0,
+	up_checks INTEGER NOT NULL DEFAULT 0,
+	failure_count INTEGER NOT NULL DEFAULT 0,
+	avg_response_time REAL DEFAULT 0,
+	p95_response_time REAL DEFAULT 0,
+	UNIQUE (service_id, granularity, bucket_start),
+	FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+	id SERIAL PRIMARY KEY,
+	service_id INTEGER REFERENCES services(id) ON DELETE CASCADE,
+	diagram_id INTEGER REFERENCES diagrams(id) ON DELETE CASCADE,
+	reason TEXT,
+	starts_at TIMESTAMP NOT NULL,
+	ends_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS incidents (
+	id SERIAL PRIMARY KEY,
+	diagram_id INTEGER NOT NULL,
+	title VARCHAR(255) NOT NULL,
+	message TEXT DEFAULT '',
+	status VARCHAR(20) NOT NULL DEFAULT 'investigating',
+	impact VARCHAR(20) NOT NULL DEFAULT 'minor',
+	started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	resolved_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (diagram_id) REFERENCES diagrams(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS reports (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	diagram_id INTEGER REFERENCES diagrams(id) ON DELETE CASCADE,
+	cadence VARCHAR(20) NOT NULL,
+	window_days INTEGER NOT NULL DEFAULT 7,
+	channel_ids INTEGER[] DEFAULT '{}',
+	enabled BOOLEAN DEFAULT TRUE,
+	last_sent_at TIMESTAMP,
+	next_run_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS status_events (
+	id SERIAL PRIMARY KEY,
+	service_id INTEGER NOT NULL,
+	from_status VARCHAR(20) NOT NULL,
+	to_status VARCHAR(20) NOT NULL,
+	result_id INTEGER,
+	occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_events_service_occurred_at ON status_events (service_id, occurred_at DESC);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER,
+	username VARCHAR(255) NOT NULL DEFAULT '',
+	action VARCHAR(20) NOT NULL,
+	method VARCHAR(10) NOT NULL,
+	route VARCHAR(255) NOT NULL,
+	entity_type VARCHAR(100) NOT NULL,
+	entity_id INTEGER,
+	before_state JSONB,
+	after_state JSONB,
+	status_code INTEGER NOT NULL,
+	ip_address VARCHAR(64) NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log (entity_type, entity_id);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_user ON audit_log (user_id);
+
+CREATE TABLE IF NOT EXISTS security_events (
+	id SERIAL PRIMARY KEY,
+	event_type VARCHAR(50) NOT NULL,
+	user_id INTEGER,
+	username VARCHAR(255) NOT NULL DEFAULT '',
+	actor_id INTEGER,
+	detail TEXT NOT NULL DEFAULT '',
+	ip_address VARCHAR(64) NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_created_at ON security_events (created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_user ON security_events (user_id);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL DEFAULT '',
+	events TEXT[] NOT NULL DEFAULT '{}',
+	diagram_id INTEGER REFERENCES diagrams(id) ON DELETE CASCADE,
+	enabled BOOLEAN DEFAULT TRUE,
+	last_status_code INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	last_delivered_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS folders (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	parent_id INTEGER REFERENCES folders(id),
+	default_public BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	token_hash VARCHAR(64) UNIQUE NOT NULL,
+	family_id VARCHAR(32) NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens (family_id);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	issuer VARCHAR(255) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (issuer, subject)
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	family_id VARCHAR(32) UNIQUE NOT NULL,
+	device VARCHAR(255) NOT NULL DEFAULT '',
+	ip_address VARCHAR(64) NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+	token_id VARCHAR(32) PRIMARY KEY,
+	expires_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'kafka_topic') THEN
+		ALTER TABLE services ADD COLUMN kafka_topic TEXT;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'kafka_client_id') THEN
+		ALTER TABLE services ADD COLUMN kafka_client_id VARCHAR(255) DEFAULT 'service-weaver-healthcheck';
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'diagrams' AND column_name = 'public') THEN
+		ALTER TABLE diagrams ADD COLUMN public BOOLEAN DEFAULT FALSE;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'icon' AND data_type = 'character varying') THEN
+		ALTER TABLE services ALTER COLUMN icon TYPE TEXT;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'slo_target') THEN
+		ALTER TABLE services ADD COLUMN slo_target REAL DEFAULT 99.9;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'retention_days') THEN
+		ALTER TABLE services ADD COLUMN retention_days INTEGER DEFAULT 0;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'diagrams' AND column_name = 'status_page_enabled') THEN
+		ALTER TABLE diagrams ADD COLUMN status_page_enabled BOOLEAN DEFAULT FALSE;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'diagrams' AND column_name = 'status_page_slug') THEN
+		ALTER TABLE diagrams ADD COLUMN status_page_slug VARCHAR(255) UNIQUE;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'diagrams' AND column_name = 'status_page_title') THEN
+		ALTER TABLE diagrams ADD COLUMN status_page_title VARCHAR(255) DEFAULT '';
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'discovery_source_id') THEN
+		ALTER TABLE services ADD COLUMN discovery_source_id INTEGER REFERENCES discovery_sources(id) ON DELETE SET NULL;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'discovery_key') THEN
+		ALTER TABLE services ADD COLUMN discovery_key VARCHAR(512) NOT NULL DEFAULT '';
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'discovery_hash') THEN
+		ALTER TABLE services ADD COLUMN discovery_hash VARCHAR(64) NOT NULL DEFAULT '';
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'discovery_deregistered_at') THEN
+		ALTER TABLE services ADD COLUMN discovery_deregistered_at TIMESTAMP;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'diagrams' AND column_name = 'deleted_at') THEN
+		ALTER TABLE diagrams ADD COLUMN deleted_at TIMESTAMP;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'deleted_at') THEN
+		ALTER TABLE services ADD COLUMN deleted_at TIMESTAMP;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'diagrams' AND column_name = 'folder_id') THEN
+		ALTER TABLE diagrams ADD COLUMN folder_id INTEGER REFERENCES folders(id) ON DELETE SET NULL;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'connections' AND column_name = 'direction') THEN
+		ALTER TABLE connections ADD COLUMN direction VARCHAR(20) NOT NULL DEFAULT 'forward';
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'connections' AND column_name = 'label') THEN
+		ALTER TABLE connections ADD COLUMN label VARCHAR(255) NOT NULL DEFAULT '';
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'connections' AND column_name = 'protocol') THEN
+		ALTER TABLE connections ADD COLUMN protocol VARCHAR(50) NOT NULL DEFAULT '';
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'connections' AND column_name = 'metadata') THEN
+		ALTER TABLE connections ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'child_diagram_id') THEN
+		ALTER TABLE services ADD COLUMN child_diagram_id INTEGER REFERENCES diagrams(id) ON DELETE SET NULL;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'diagrams' AND column_name = 'service_defaults') THEN
+		ALTER TABLE diagrams ADD COLUMN service_defaults JSONB NOT NULL DEFAULT '{}';
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'overrides') THEN
+		ALTER TABLE services ADD COLUMN overrides TEXT[] NOT NULL DEFAULT '{}';
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'tokens_revoked_at') THEN
+		ALTER TABLE users ADD COLUMN tokens_revoked_at TIMESTAMP;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'diagrams' AND column_name = 'organization_id') THEN
+		ALTER TABLE diagrams ADD COLUMN organization_id INTEGER REFERENCES organizations(id);
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'folders' AND column_name = 'organization_id') THEN
+		ALTER TABLE folders ADD COLUMN organization_id INTEGER REFERENCES organizations(id);
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'failed_login_attempts') THEN
+		ALTER TABLE users ADD COLUMN failed_login_attempts INTEGER NOT NULL DEFAULT 0;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'first_failed_login_at') THEN
+		ALTER TABLE users ADD COLUMN first_failed_login_at TIMESTAMP;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'lockout_count') THEN
+		ALTER TABLE users ADD COLUMN lockout_count INTEGER NOT NULL DEFAULT 0;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'locked_until') THEN
+		ALTER TABLE users ADD COLUMN locked_until TIMESTAMP;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'credentials') THEN
+		ALTER TABLE services ADD COLUMN credentials TEXT NOT NULL DEFAULT '';
+	END IF;
+END $$;
@@ -0,0 +1,5 @@
+-- Instances that share the healthchecks in sharding mode, kept alive by their heartbeats
+CREATE TABLE IF NOT EXISTS scheduler_members (
+	instance_id VARCHAR(64) PRIMARY KEY,
+	heartbeat_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- Icons moved to blob storage lose their reference and must be uploaded again
+ALTER TABLE services DROP COLUMN IF EXISTS icon_key;
@@ -0,0 +1,3 @@
+-- Uploaded icons are kept in blob storage under icon_key rather than embedded in icon; the
+-- embedded ones are moved there at startup
+ALTER TABLE services ADD COLUMN IF NOT EXISTS icon_key TEXT NOT NULL DEFAULT '';
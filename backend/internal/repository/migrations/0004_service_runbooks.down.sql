@@ -0,0 +1,2 @@
+ALTER TABLE services DROP COLUMN IF EXISTS notes;
+ALTER TABLE services DROP COLUMN IF EXISTS runbook_url;
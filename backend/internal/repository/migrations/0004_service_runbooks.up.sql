@@ -0,0 +1,3 @@
+-- Remediation context that alerts about a service carry
+ALTER TABLE services ADD COLUMN IF NOT EXISTS runbook_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE services ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+ALTER TABLE services DROP COLUMN IF EXISTS paused;
+
+DROP INDEX IF EXISTS idx_services_tags;
+ALTER TABLE services ADD COLUMN IF NOT EXISTS tag_text TEXT;
+UPDATE services SET tag_text = array_to_string(tags, ',');
+ALTER TABLE services DROP COLUMN tags;
+ALTER TABLE services RENAME COLUMN tag_text TO tags;
@@ -0,0 +1,18 @@
+-- Tags become an array of trimmed, distinct entries instead of comma-separated text, keeping
+-- their order and case
+ALTER TABLE services ADD COLUMN IF NOT EXISTS tag_list TEXT[] NOT NULL DEFAULT '{}';
+UPDATE services SET tag_list = ARRAY(
+	SELECT tag FROM (
+		SELECT trim(raw) AS tag, min(position) AS position
+		FROM unnest(string_to_array(tags, ',')) WITH ORDINALITY AS entries(raw, position)
+		WHERE trim(raw) <> ''
+		GROUP BY trim(raw)
+	) distinct_tags
+	ORDER BY position
+) WHERE COALESCE(tags, '') <> '';
+ALTER TABLE services DROP COLUMN tags;
+ALTER TABLE services RENAME COLUMN tag_list TO tags;
+CREATE INDEX IF NOT EXISTS idx_services_tags ON services USING gin (tags);
+
+-- Paused services keep their definition and last status but are not checked
+ALTER TABLE services ADD COLUMN IF NOT EXISTS paused BOOLEAN NOT NULL DEFAULT FALSE;
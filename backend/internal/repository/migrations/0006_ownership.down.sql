@@ -0,0 +1,7 @@
+ALTER TABLE notification_channels DROP COLUMN IF EXISTS team;
+DROP INDEX IF EXISTS idx_services_owner_team;
+DROP INDEX IF EXISTS idx_services_owner_user_id;
+ALTER TABLE services DROP COLUMN IF EXISTS owner_team;
+ALTER TABLE services DROP COLUMN IF EXISTS owner_user_id;
+ALTER TABLE diagrams DROP COLUMN IF EXISTS owner_team;
+ALTER TABLE diagrams DROP COLUMN IF EXISTS owner_user_id;
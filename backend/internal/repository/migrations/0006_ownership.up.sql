@@ -0,0 +1,11 @@
+-- Services and diagrams name the user and team responsible for them; a service without an owner
+-- falls under the owner of its diagram
+ALTER TABLE diagrams ADD COLUMN IF NOT EXISTS owner_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE diagrams ADD COLUMN IF NOT EXISTS owner_team VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE services ADD COLUMN IF NOT EXISTS owner_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE services ADD COLUMN IF NOT EXISTS owner_team VARCHAR(100) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_services_owner_user_id ON services(owner_user_id);
+CREATE INDEX IF NOT EXISTS idx_services_owner_team ON services(owner_team);
+
+-- Channels assigned to a team receive the alerts of the services it owns instead of every alert
+ALTER TABLE notification_channels ADD COLUMN IF NOT EXISTS team VARCHAR(100) NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+-- Invitations to join an organization. The account is only created once the invitee opens the
+-- emailed link and chooses a password, which verifies the address; only the token's hash is kept.
+CREATE TABLE IF NOT EXISTS invitations (
+	id SERIAL PRIMARY KEY,
+	organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+	email VARCHAR(255) NOT NULL,
+	username VARCHAR(255) NOT NULL,
+	role VARCHAR(50) NOT NULL,
+	token_hash VARCHAR(64) UNIQUE NOT NULL,
+	invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	expires_at TIMESTAMP NOT NULL,
+	accepted_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_invitations_organization ON invitations (organization_id);
@@ -0,0 +1,14 @@
+-- How the alerts of the services a user owns reach them personally; users without a row get
+-- every alert by email
+CREATE TABLE IF NOT EXISTS notification_preferences (
+	user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	channels TEXT[] NOT NULL DEFAULT '{email}',
+	slack_webhook_url TEXT NOT NULL DEFAULT '',
+	ntfy_topic VARCHAR(255) NOT NULL DEFAULT '',
+	min_severity VARCHAR(20) NOT NULL DEFAULT 'info',
+	quiet_hours_start VARCHAR(5) NOT NULL DEFAULT '',
+	quiet_hours_end VARCHAR(5) NOT NULL DEFAULT '',
+	timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+	quiet_hours_allow_critical BOOLEAN NOT NULL DEFAULT FALSE,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
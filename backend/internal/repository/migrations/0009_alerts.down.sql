@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_maintenance_windows_diagram;
+DROP TABLE IF EXISTS alerts;
@@ -0,0 +1,14 @@
+-- Status changes notification channels were alerted of, for the activity feed of diagrams
+CREATE TABLE IF NOT EXISTS alerts (
+	id SERIAL PRIMARY KEY,
+	service_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+	from_status VARCHAR(20) NOT NULL,
+	to_status VARCHAR(20) NOT NULL,
+	error TEXT NOT NULL DEFAULT '',
+	occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_alerts_service_occurred_at ON alerts (service_id, occurred_at DESC);
+
+-- The feed also lists the maintenance windows of a diagram
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_diagram ON maintenance_windows (diagram_id);
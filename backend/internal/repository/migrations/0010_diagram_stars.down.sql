@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS diagram_views;
+DROP TABLE IF EXISTS diagram_stars;
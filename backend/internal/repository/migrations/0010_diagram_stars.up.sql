@@ -0,0 +1,16 @@
+-- Diagrams users starred and the ones they opened last, for quick access
+CREATE TABLE IF NOT EXISTS diagram_stars (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	diagram_id INTEGER NOT NULL REFERENCES diagrams(id) ON DELETE CASCADE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, diagram_id)
+);
+
+CREATE TABLE IF NOT EXISTS diagram_views (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	diagram_id INTEGER NOT NULL REFERENCES diagrams(id) ON DELETE CASCADE,
+	viewed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, diagram_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_diagram_views_user_viewed_at ON diagram_views (user_id, viewed_at DESC);
@@ -0,0 +1,9 @@
+-- Rendered thumbnails of diagram layouts, kept until the layout they were rendered from changes
+CREATE TABLE IF NOT EXISTS diagram_thumbnails (
+	diagram_id INTEGER NOT NULL REFERENCES diagrams(id) ON DELETE CASCADE,
+	format VARCHAR(10) NOT NULL,
+	layout_hash VARCHAR(64) NOT NULL,
+	data BYTEA NOT NULL,
+	rendered_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (diagram_id, format)
+);
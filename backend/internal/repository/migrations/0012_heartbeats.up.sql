@@ -0,0 +1,14 @@
+-- Pings of services checked by heartbeat: jobs report in at their ping key instead of being polled.
+-- Times carry their zone, as the scheduler compares them with its own clock.
+CREATE TABLE IF NOT EXISTS heartbeats (
+	service_id INTEGER PRIMARY KEY REFERENCES services(id) ON DELETE CASCADE,
+	ping_key VARCHAR(36) NOT NULL UNIQUE,
+	period INTEGER NOT NULL DEFAULT 86400,
+	grace INTEGER NOT NULL DEFAULT 3600,
+	last_ping_at TIMESTAMPTZ,
+	last_start_at TIMESTAMPTZ,
+	last_signal VARCHAR(10) NOT NULL DEFAULT '',
+	last_exit_status INTEGER,
+	last_body TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,15 @@
+-- Firing alerts received from Prometheus Alertmanager, one row per alert and matched service.
+-- Resolved alerts are deleted; the scheduler folds the firing ones into the status of services.
+CREATE TABLE IF NOT EXISTS external_alerts (
+	fingerprint VARCHAR(64) NOT NULL,
+	service_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL DEFAULT '',
+	severity VARCHAR(50) NOT NULL DEFAULT '',
+	status VARCHAR(20) NOT NULL,
+	summary TEXT NOT NULL DEFAULT '',
+	starts_at TIMESTAMPTZ,
+	received_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (fingerprint, service_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_alerts_service_id ON external_alerts(service_id);
@@ -0,0 +1,3 @@
+ALTER TABLE healthcheck_results DROP COLUMN IF EXISTS output;
+DROP TABLE IF EXISTS service_scripts;
+DROP TABLE IF EXISTS check_scripts;
@@ -0,0 +1,23 @@
+-- Check scripts uploaded by admins, run by services with the SCRIPT healthcheck method
+CREATE TABLE IF NOT EXISTS check_scripts (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(100) NOT NULL UNIQUE,
+	description TEXT NOT NULL DEFAULT '',
+	interpreter VARCHAR(50) NOT NULL,
+	content TEXT NOT NULL,
+	created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The script a service runs and its arguments; scripts in use cannot be deleted
+CREATE TABLE IF NOT EXISTS service_scripts (
+	service_id INTEGER PRIMARY KEY REFERENCES services(id) ON DELETE CASCADE,
+	script_id INTEGER NOT NULL REFERENCES check_scripts(id) ON DELETE RESTRICT,
+	args TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_scripts_script_id ON service_scripts(script_id);
+
+-- What a check printed, such as the standard output of a script
+ALTER TABLE healthcheck_results ADD COLUMN IF NOT EXISTS output TEXT;
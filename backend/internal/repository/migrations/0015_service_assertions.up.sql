@@ -0,0 +1,2 @@
+-- An expression evaluated against the outcome of a service's HTTP check, giving its final status
+ALTER TABLE services ADD COLUMN IF NOT EXISTS assertion TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+-- TLS certificates served to HTTPS checks, one row per certificate a service presented: the
+-- renewals they record let expiry be forecast
+CREATE TABLE IF NOT EXISTS service_certificates (
+	service_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+	not_after TIMESTAMPTZ NOT NULL,
+	subject TEXT NOT NULL DEFAULT '',
+	issuer TEXT NOT NULL DEFAULT '',
+	first_seen TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (service_id, not_after)
+);
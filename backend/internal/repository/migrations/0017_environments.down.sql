@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_services_environment;
+DROP INDEX IF EXISTS idx_diagrams_environment;
+ALTER TABLE services DROP COLUMN IF EXISTS environment;
+ALTER TABLE diagrams DROP COLUMN IF EXISTS environment;
+DROP TABLE IF EXISTS environments;
@@ -0,0 +1,22 @@
+-- Environments diagrams and services are labelled with, defined by admins. Alerts of the services
+-- of an environment are capped at its max_severity, so that staging never pages, say.
+CREATE TABLE IF NOT EXISTS environments (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(50) NOT NULL UNIQUE,
+	description TEXT NOT NULL DEFAULT '',
+	max_severity VARCHAR(20) NOT NULL DEFAULT 'critical',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO environments (name, description, max_severity) VALUES
+	('prod', 'Production', 'critical'),
+	('staging', 'Staging', 'warning'),
+	('dev', 'Development', 'info')
+ON CONFLICT (name) DO NOTHING;
+
+-- A service without an environment is in the environment of its diagram
+ALTER TABLE diagrams ADD COLUMN IF NOT EXISTS environment VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE services ADD COLUMN IF NOT EXISTS environment VARCHAR(50) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_diagrams_environment ON diagrams(environment);
+CREATE INDEX IF NOT EXISTS idx_services_environment ON services(environment);
@@ -0,0 +1,3 @@
+DELETE FROM services WHERE overlay_of IS NOT NULL;
+DROP INDEX IF EXISTS idx_services_overlay;
+ALTER TABLE services DROP COLUMN IF EXISTS overlay_of;
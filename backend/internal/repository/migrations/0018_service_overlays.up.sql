@@ -0,0 +1,6 @@
+-- Environment overlays: a service's stand-in in another environment, sharing its node, edges and
+-- check settings but with its own host, port, healthcheck URL and credentials. Overlays are rows
+-- of their own so that they are checked, alerted and kept in history like any service; the
+-- settings they share are copied from their service whenever it changes.
+ALTER TABLE services ADD COLUMN IF NOT EXISTS overlay_of INTEGER REFERENCES services(id) ON DELETE CASCADE;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_services_overlay ON services(overlay_of, environment) WHERE overlay_of IS NOT NULL;
@@ -0,0 +1,14 @@
+-- Tokens deployment pipelines register their services with through POST /api/register, scoped to
+-- an organization and optionally to one of its diagrams. Only the token's hash is kept.
+CREATE TABLE IF NOT EXISTS registration_tokens (
+	id SERIAL PRIMARY KEY,
+	organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+	diagram_id INTEGER REFERENCES diagrams(id) ON DELETE CASCADE,
+	name VARCHAR(100) NOT NULL,
+	token_hash VARCHAR(64) UNIQUE NOT NULL,
+	created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	last_used_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_registration_tokens_organization ON registration_tokens (organization_id);
@@ -0,0 +1 @@
+UPDATE reports SET window_days = 30 WHERE cadence = 'monthly' AND window_days = 0;
@@ -0,0 +1,3 @@
+-- Monthly reports without an explicit window used to store a fixed 30 days; a window of 0 now
+-- covers the calendar month before each delivery.
+UPDATE reports SET window_days = 0 WHERE cadence = 'monthly' AND window_days = 30;
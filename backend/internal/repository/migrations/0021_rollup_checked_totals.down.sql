@@ -0,0 +1,2 @@
+-- The results of unknown status left out of total_checks are not known any more, so rollups keep
+-- the totals of the checks that decided uptime.
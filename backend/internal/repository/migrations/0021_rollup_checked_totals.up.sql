@@ -0,0 +1,3 @@
+-- Rollups used to count results of unknown status in total_checks, unlike the uptime of raw
+-- results; the checks that decided uptime are exactly the up and failed ones.
+UPDATE healthcheck_rollups SET total_checks = up_checks + failure_count WHERE total_checks <> up_checks + failure_count;
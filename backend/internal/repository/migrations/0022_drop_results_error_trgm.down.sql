@@ -0,0 +1 @@
+-- Previous versions create the index themselves at startup when pg_trgm is available.
@@ -0,0 +1,3 @@
+-- The trigram index on result errors was built at startup, blocking result writes while it was;
+-- error search filters by service and time instead.
+DROP INDEX IF EXISTS idx_healthcheck_results_error_trgm;
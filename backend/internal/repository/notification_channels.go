@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"service-weaver/internal/models"
+)
+
+// Notification channel operations
+func (r *Repository) CreateNotificationChannel(ctx context.Context, channel *models.NotificationChannel) error {
+	query := `INSERT INTO notification_channels (name, type, config, enabled, team) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`
+	return r.db.QueryRowContext(ctx, query, channel.Name, channel.Type, channel.Config, channel.Enabled, channel.Team).Scan(&channel.ID, &channel.CreatedAt, &channel.UpdatedAt)
+}
+
+func (r *Repository) GetNotificationChannels(ctx context.Context) ([]models.NotificationChannel, error) {
+	query := `SELECT id, name, type, config, enabled, team, created_at, updated_at FROM notification_channels ORDER BY name`
+	return r.queryNotificationChannels(ctx, query)
+}
+
+// GetEnabledNotificationChannels returns the channels alerts should be dispatched to
+func (r *Repository) GetEnabledNotificationChannels(ctx context.Context) ([]models.NotificationChannel, error) {
+	query := `SELECT id, name, type, config, enabled, team, created_at, updated_at FROM notification_channels WHERE enabled = TRUE ORDER BY id`
+	return r.queryNotificationChannels(ctx, query)
+}
+
+func (r *Repository) GetNotificationChannel(ctx context.Context, id int) (*models.NotificationChannel, error) {
+	query := `SELECT id, name, type, config, enabled, team, created_at, updated_at FROM notification_channels WHERE id = $1`
+	var ch models.NotificationChannel
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&ch.ID, &ch.Name, &ch.Type, &ch.Config, &ch.Enabled, &ch.Team, &ch.CreatedAt, &ch.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+func (r *Repository) UpdateNotificationChannel(ctx context.Context, channel *models.NotificationChannel) error {
+	query := `UPDATE notification_channels SET name = $1, type = $2, config = $3, enabled = $4, team = $6, updated_at = CURRENT_TIMESTAMP WHERE id = $5`
+	_, err := r.db.ExecContext(ctx, query, channel.Name, channel.Type, channel.Config, channel.Enabled, channel.ID, channel.Team)
+	return err
+}
+
+func (r *Repository) DeleteNotificationChannel(ctx context.Context, id int) error {
+	query := `DELETE FROM notification_channels WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *Repository) queryNotificationChannels(ctx context.Context, query string, args ...interface{}) ([]models.NotificationChannel, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var channels []models.NotificationChannel
+	for rows.Next() {
+		var ch models.NotificationChannel
+		err := rows.Scan(&ch.ID, &ch.Name, &ch.Type, &ch.Config, &ch.Enabled, &ch.Team, &ch.CreatedAt, &ch.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, ch)
+	}
+	return channels, nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"service-weaver/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// GetNotificationPreferences returns the notification preferences of a user, the defaults when
+// they never set theirs
+func (r *Repository) GetNotificationPreferences(ctx context.Context, userID int) (*models.NotificationPreferences, error) {
+	query := `SELECT channels, slack_webhook_url, ntfy_topic, min_severity, quiet_hours_start, quiet_hours_end,
+			timezone, quiet_hours_allow_critical, updated_at
+		FROM notification_preferences WHERE user_id = $1`
+	var prefs models.NotificationPreferences
+	var channels []string
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(pq.Array(&channels), &prefs.SlackWebhookURL, &prefs.NtfyTopic, &prefs.MinSeverity,
+		&prefs.QuietHoursStart, &prefs.QuietHoursEnd, &prefs.Timezone, &prefs.QuietHoursAllowCritical, &prefs.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		prefs = models.DefaultNotificationPreferences()
+		return &prefs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	prefs.Channels = make([]models.NotificationChannelType, len(channels))
+	for i, channel := range channels {
+		prefs.Channels[i] = models.NotificationChannelType(channel)
+	}
+	return &prefs, nil
+}
+
+// SetNotificationPreferences stores the notification preferences of a user
+func (r *Repository) SetNotificationPreferences(ctx context.Context, userID int, prefs *models.NotificationPreferences) error {
+	channels := make([]string, len(prefs.Channels))
+	for i, channel := range prefs.Channels {
+		channels[i] = string(channel)
+	}
+
+	query := `INSERT INTO notification_preferences (user_id, channels, slack_webhook_url, ntfy_topic, min_severity,
+			quiet_hours_start, quiet_hours_end, timezone, quiet_hours_allow_critical)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (user_id) DO UPDATE SET channels = EXCLUDED.channels, slack_webhook_url = EXCLUDED.slack_webhook_url,
+			ntfy_topic = EXCLUDED.ntfy_topic, min_severity = EXCLUDED.min_severity, quiet_hours_start = EXCLUDED.quiet_hours_start,
+			quiet_hours_end = EXCLUDED.quiet_hours_end, timezone = EXCLUDED.timezone,
+			quiet_hours_allow_critical = EXCLUDED.quiet_hours_allow_critical, updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at`
+	return r.db.QueryRowContext(ctx, query, userID, pq.Array(channels), prefs.SlackWebhookURL, prefs.NtfyTopic, prefs.MinSeverity,
+		prefs.QuietHoursStart, prefs.QuietHoursEnd, prefs.Timezone, prefs.QuietHoursAllowCritical).Scan(&prefs.UpdatedAt)
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// servicesChannel is the Postgres notification channel instances announce service changes on
+const servicesChannel = "sw_services_changed"
+
+// newInstanceID identifies this process in its notifications, so it ignores its own
+func newInstanceID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// notifyListeners calls the functions registered with OnServicesChanged
+func (r *Repository) notifyListeners() {
+	r.listenersMu.Lock()
+	defer r.listenersMu.Unlock()
+	for _, fn := range r.listeners {
+		fn()
+	}
+}
+
+// announce tells the other instances sharing the database that services changed
+func (r *Repository) announce() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := r.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, servicesChannel, r.instance); err != nil {
+		log.Printf("Error announcing service change: %v", err)
+	}
+}
+
+// ChangeListener receives the service changes other instances announce and passes them on to the
+// functions registered with OnServicesChanged
+type ChangeListener struct {
+	listener *pq.Listener
+}
+
+// ListenForChanges opens a connection of its own to connStr and listens for the service changes
+// announced by other instances. After the connection was lost and re-established, the listeners
+// are called as well, since announcements may have been missed meanwhile.
+func (r *Repository) ListenForChanges(connStr string) (*ChangeListener, error) {
+	listener := pq.NewListener(connStr, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Service change listener: %v", err)
+		}
+	})
+	if err := listener.Listen(servicesChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	go func() {
+		for n := range listener.Notify {
+			// pq sends nil after reconnecting
+			if n != nil && n.Extra == r.instance {
+				continue
+			}
+			r.notifyListeners()
+		}
+	}()
+	return &ChangeListener{listener: listener}, nil
+}
+
+// Close stops listening
+func (l *ChangeListener) Close() error {
+	return l.listener.Close()
+}
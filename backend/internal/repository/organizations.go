@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"service-weaver/internal/models"
+)
+
+var (
+	// ErrOrganizationNotEmpty is returned when deleting an organization that still owns diagrams or folders
+	ErrOrganizationNotEmpty = errors.New("organization still contains diagrams or folders, including diagrams in the trash")
+	// ErrLastOrganizationAdmin is returned when a change would leave an organization without an admin
+	ErrLastOrganizationAdmin = errors.New("an organization needs at least one admin")
+)
+
+// entityOrganizationQueries look up the organization owning an entity, including entities in the trash
+var entityOrganizationQueries = map[string]string{
+	"organizations": `SELECT id FROM organizations WHERE id = $1`,
+	"diagrams":      `SELECT organization_id FROM diagrams WHERE id = $1`,
+	"folders":       `SELECT organization_id FROM folders WHERE id = $1`,
+	"services":      `SELECT d.organization_id FROM services s JOIN diagrams d ON d.id = s.diagram_id WHERE s.id = $1`,
+	"connections":   `SELECT d.organization_id FROM connections c JOIN diagrams d ON d.id = c.diagram_id WHERE c.id = $1`,
+	"incidents":     `SELECT d.organization_id FROM incidents i JOIN diagrams d ON d.id = i.diagram_id WHERE i.id = $1`,
+	"maintenance-windows": `SELECT d.organization_id FROM maintenance_windows w
+		JOIN diagrams d ON d.id = COALESCE(w.diagram_id, (SELECT diagram_id FROM services WHERE id = w.service_id))
+		WHERE w.id = $1`,
+}
+
+// bootstrapOrganizations gives deployments from before organizations a default organization
+// holding all existing users, diagrams and folders
+func (r *Repository) bootstrapOrganizations(ctx context.Context) error {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM organizations)`).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		var id int
+		if err := tx.QueryRowContext(ctx, `INSERT INTO organizations (name) VALUES ('Default') RETURNING id`).Scan(&id); err != nil {
+			return err
+		}
+		query := `INSERT INTO organization_members (organization_id, user_id, role)
+			SELECT $1, id, CASE WHEN role = 'admin' THEN 'admin' ELSE 'member' END FROM users`
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	for _, table := range []string{"diagrams", "folders"} {
+		query := `UPDATE ` + table + ` SET organization_id = (SELECT MIN(id) FROM organizations) WHERE organization_id IS NULL`
+		if _, err := r.db.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateOrganization stores a new organization with the user as its first admin
+func (r *Repository) CreateOrganization(ctx context.Context, org *models.Organization, adminID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO organizations (name) VALUES ($1) RETURNING id, created_at, updated_at`
+	if err := tx.QueryRowContext(ctx, query, org.Name).Scan(&org.ID, &org.CreatedAt, &org.UpdatedAt); err != nil {
+		return err
+	}
+	query = `INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, query, org.ID, adminID, models.OrgRoleAdmin); err != nil {
+		return err
+	}
+	org.Role = models.OrgRoleAdmin
+	return tx.Commit()
+}
+
+// GetOrganizations lists the organizations a user belongs to with their role in each. Platform
+// admins see every organization and act as admin where they are not a member.
+func (r *Repository) GetOrganizations(ctx context.Context, userID int) ([]models.Organization, error) {
+	query := `SELECT o.id, o.name, COALESCE(m.role, 'admin'), o.created_at, o.updated_at
+		FROM organizations o
+		JOIN users u ON u.id = $1
+		LEFT JOIN organization_members m ON m.organization_id = o.id AND m.user_id = u.id
+		WHERE m.user_id IS NOT NULL OR u.role = 'admin'
+		ORDER BY o.name, o.id`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []models.Organization{}
+	for rows.Next() {
+		var o models.Organization
+		if err := rows.Scan(&o.ID, &o.Name, &o.Role, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+func (r *Repository) GetOrganization(ctx context.Context, id int) (*models.Organization, error) {
+	query := `SELECT id, name, created_at, updated_at FROM organizations WHERE id = $1`
+	var o models.Organization
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&o.ID, &o.Name, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *Repository) UpdateOrganization(ctx context.Context, org *models.Organization) error {
+	query := `UPDATE organizations SET name = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 RETURNING created_at, updated_at`
+	return r.db.QueryRowContext(ctx, query, org.Name, org.ID).Scan(&org.CreatedAt, &org.UpdatedAt)
+}
+
+// DeleteOrganization removes an organization that no longer owns any diagrams or folders
+func (r *Repository) DeleteOrganization(ctx context.Context, id int) error {
+	var used bool
+	query := `SELECT EXISTS (SELECT 1 FROM diagrams WHERE organization_id = $1)
+		OR EXISTS (SELECT 1 FROM folders WHERE organization_id = $1)`
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&used); err != nil {
+		return err
+	}
+	if used {
+		return ErrOrganizationNotEmpty
+	}
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM organizations WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetOrganizationRole returns the user's role in an organization; platform admins are admins of
+// every organization. It returns sql.ErrNoRows when the user has no access.
+func (r *Repository) GetOrganizationRole(ctx context.Context, userID, orgID int) (models.OrganizationRole, error) {
+	query := `SELECT COALESCE(m.role, CASE WHEN u.role = 'admin' THEN 'admin' END)
+		FROM organizations o
+		JOIN users u ON u.id = $1
+		LEFT JOIN organization_members m ON m.organization_id = o.id AND m.user_id = u.id
+		WHERE o.id = $2`
+	var role sql.NullString
+	if err := r.db.QueryRowContext(ctx, query, userID, orgID).Scan(&role); err != nil {
+		return "", err
+	}
+	if !role.Valid {
+		return "", sql.ErrNoRows
+	}
+	return models.OrganizationRole(role.String), nil
+}
+
+// GetDefaultOrganizationID returns the organization a user acts in when a request names none:
+// their oldest membership, or the oldest organization for platform admins without memberships
+func (r *Repository) GetDefaultOrganizationID(ctx context.Context, userID int) (int, error) {
+	query := `SELECT o.id
+		FROM organizations o
+		JOIN users u ON u.id = $1
+		LEFT JOIN organization_members m ON m.organization_id = o.id AND m.user_id = u.id
+		WHERE m.user_id IS NOT NULL OR u.role = 'admin'
+		ORDER BY m.user_id IS NULL, o.id
+		LIMIT 1`
+	var id int
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&id)
+	return id, err
+}
+
+// GetEntityOrganization returns the organization owning a diagram, folder, service, connection,
+// incident or maintenance window. The entity type is the resource name used in routes.
+func (r *Repository) GetEntityOrganization(ctx context.Context, entityType string, id int) (int, error) {
+	query, ok := entityOrganizationQueries[entityType]
+	if !ok {
+		return 0, fmt.Errorf("%s are not owned by organizations", entityType)
+	}
+	var orgID sql.NullInt64
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&orgID); err != nil {
+		return 0, err
+	}
+	return int(orgID.Int64), nil
+}
+
+// GetOrganizationMembers lists the members of an organization
+func (r *Repository) GetOrganizationMembers(ctx context.Context, orgID int) ([]models.OrganizationMember, error) {
+	query := `SELECT u.id, u.username, u.email, m.role, m.created_at
+		FROM organization_members m JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1
+		ORDER BY u.username`
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.OrganizationMember{}
+	for rows.Next() {
+		var m models.OrganizationMember
+		if err := rows.Scan(&m.UserID, &m.Username, &m.Email, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// SetOrganizationMember adds a user to an organization or changes their role. Demoting the last
+// admin returns ErrLastOrganizationAdmin.
+func (r *Repository) SetOrganizationMember(ctx context.Context, orgID, userID int, role models.OrganizationRole) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if role != models.OrgRoleAdmin {
+		if err := checkRemainingAdmin(ctx, tx, orgID, userID); err != nil {
+			return err
+		}
+	}
+
+	query := `INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+	if _, err := tx.ExecContext(ctx, query, orgID, userID, role); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveOrganizationMember removes a user from an organization. Removing the last admin returns
+// ErrLastOrganizationAdmin and a user who is not a member gives sql.ErrNoRows.
+func (r *Repository) RemoveOrganizationMember(ctx context.Context, orgID, userID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkRemainingAdmin(ctx, tx, orgID, userID); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// checkRemainingAdmin fails when the user is the only admin of the organization. The admin rows
+// are locked so concurrent changes cannot both remove an admin.
+func checkRemainingAdmin(ctx context.Context, q *sql.Tx, orgID, userID int) error {
+	rows, err := q.QueryContext(ctx, `SELECT user_id FROM organization_members WHERE organization_id = $1 AND role = 'admin' FOR UPDATE`, orgID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	others, isAdmin := 0, false
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		if id == userID {
+			isAdmin = true
+		} else {
+			others++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if isAdmin && others == 0 {
+		return ErrLastOrganizationAdmin
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"service-weaver/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrServiceIsOverlay is returned when editing or deleting an environment overlay as a service;
+// overlays are changed through the service they stand in for
+var ErrServiceIsOverlay = errors.New("the service is an environment overlay; change it through its service's overlays")
+
+// overlaySharedColumns are the columns overlays share with their service: everything but the
+// address and credentials they are checked with, their environment, their icon and position,
+// which are those of the service's node, and their own state
+const overlaySharedColumns = `diagram_id, name, description, service_type, tags, runbook_url, notes, owner_user_id, owner_team, healthcheck_method, polling_interval, request_timeout, expected_status, status_mapping, http_method, headers, body, ssl_verify, follow_redirects, tcp_send_data, tcp_expect_data, udp_send_data, udp_expect_data, icmp_packet_count, dns_query_type, dns_expected_result, kafka_topic, kafka_client_id, slo_target, retention_days, assertion, child_diagram_id, overrides, paused, deleted_at`
+
+// syncOverlays copies the shared columns of the services in ids into their overlays. It runs after
+// every change to services that may have overlays, in the same transaction where there is one.
+func syncOverlays(ctx context.Context, q queryRunner, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query := `UPDATE services o SET (` + overlaySharedColumns + `) = (SELECT ` + overlaySharedColumns + ` FROM services b WHERE b.id = o.overlay_of)
+		WHERE o.overlay_of = ANY($1)`
+	_, err := q.ExecContext(ctx, query, pq.Array(ids))
+	return err
+}
+
+// serviceIsOverlay reports whether id is an environment overlay
+func serviceIsOverlay(ctx context.Context, q queryRunner, id int) (bool, error) {
+	var overlay bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM services WHERE id = $1 AND overlay_of IS NOT NULL)`, id).Scan(&overlay)
+	return overlay, err
+}
+
+// GetOverlays returns the overlays of the live services of a diagram, only those for environment
+// unless it is empty
+func (r *Repository) GetOverlays(ctx context.Context, diagramID int, environment string) ([]models.Service, error) {
+	query := `SELECT ` + serviceColumns + ` FROM services
+		WHERE diagram_id = $1 AND overlay_of IS NOT NULL AND deleted_at IS NULL AND ($2 = '' OR environment = $2)
+		ORDER BY overlay_of, environment`
+	return r.queryOverlays(ctx, query, diagramID, environment)
+}
+
+// GetServiceOverlays returns the overlays of a service by environment name
+func (r *Repository) GetServiceOverlays(ctx context.Context, serviceID int) ([]models.Service, error) {
+	query := `SELECT ` + serviceColumns + ` FROM services WHERE overlay_of = $1 AND deleted_at IS NULL ORDER BY environment`
+	return r.queryOverlays(ctx, query, serviceID)
+}
+
+func (r *Repository) queryOverlays(ctx context.Context, query string, args ...interface{}) ([]models.Service, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overlays := []models.Service{}
+	for rows.Next() {
+		var s models.Service
+		if err := scanService(rows, &s); err != nil {
+			return nil, err
+		}
+		overlays = append(overlays, s)
+	}
+	return overlays, rows.Err()
+}
+
+// SetServiceOverlay creates or replaces the overlay of a service for an environment and returns it,
+// reporting whether it was created. A new overlay starts from a copy of the service, paused or not
+// as it is, with its credentials unless others are given. It fails with sql.ErrNoRows when the
+// service does not exist or is in the trash, and ErrServiceIsOverlay when it is an overlay itself.
+func (r *Repository) SetServiceOverlay(ctx context.Context, serviceID int, environment string, request models.ServiceOverlayRequest) (*models.Service, bool, error) {
+	var overlay models.Service
+	var created bool
+	err := r.changed(r.WithTx(ctx, func(tx *sql.Tx) error {
+		var service models.Service
+		query := `SELECT ` + serviceColumns + ` FROM services WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+		if err := scanService(tx.QueryRowContext(ctx, query, serviceID), &service); err != nil {
+			return err
+		}
+		if service.OverlayOf != nil {
+			return ErrServiceIsOverlay
+		}
+
+		var overlayID int
+		err := tx.QueryRowContext(ctx, `SELECT id FROM services WHERE overlay_of = $1 AND environment = $2`, serviceID, environment).Scan(&overlayID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			created = true
+			service.Environment = environment
+			service.Host, service.Port, service.HealthcheckURL = request.Host, request.Port, request.HealthcheckURL
+			service.Credentials = request.Credentials
+			if err := createService(ctx, tx, &service); err != nil {
+				return err
+			}
+			overlayID = service.ID
+			if _, err := tx.ExecContext(ctx, `UPDATE services SET overlay_of = $1 WHERE id = $2`, serviceID, overlayID); err != nil {
+				return err
+			}
+			// The copy holds the service's settings with the diagram's defaults applied; the
+			// stored ones are what the overlay shares
+			if err := syncOverlays(ctx, tx, []int{serviceID}); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			credentials, err := sealCredentials(&models.Service{Credentials: request.Credentials})
+			if err != nil {
+				return err
+			}
+			query := `UPDATE services SET host = $1, port = $2, healthcheck_url = $3, credentials = COALESCE($4, credentials), updated_at = CURRENT_TIMESTAMP
+				WHERE id = $5`
+			if _, err := tx.ExecContext(ctx, query, request.Host, request.Port, request.HealthcheckURL, credentials, overlayID); err != nil {
+				return err
+			}
+		}
+
+		return scanService(tx.QueryRowContext(ctx, `SELECT `+serviceColumns+` FROM services WHERE id = $1`, overlayID), &overlay)
+	}))
+	if err != nil {
+		return nil, false, err
+	}
+	return &overlay, created, nil
+}
+
+// DeleteServiceOverlay deletes the overlay of a service for an environment, with its history. It
+// fails with sql.ErrNoRows when there is none.
+func (r *Repository) DeleteServiceOverlay(ctx context.Context, serviceID int, environment string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM services WHERE overlay_of = $1 AND environment = $2`, serviceID, environment)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return r.changed(nil)
+}
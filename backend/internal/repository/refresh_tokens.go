@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"service-weaver/internal/models"
+	"time"
+)
+
+// ErrRefreshTokenReused is returned when a refresh token is presented after it was already
+// exchanged. Every token of its family is revoked, ending the session on all clients.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// CreateSession starts a session (token family) that lasts for ttl with its first refresh token and
+// returns the session's ID. Expired tokens and sessions of the user are cleaned up on the way.
+func (r *Repository) CreateSession(ctx context.Context, userID int, tokenHash, familyID string, ttl time.Duration, client models.SessionClient) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1 AND expires_at < CURRENT_TIMESTAMP`, userID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1 AND expires_at < CURRENT_TIMESTAMP`, userID); err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO sessions (user_id, family_id, device, ip_address, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP + make_interval(secs => $6))
+		RETURNING id`
+	var sessionID int
+	if err := tx.QueryRowContext(ctx, query, userID, familyID, client.Device, client.IPAddress, client.UserAgent, ttl.Seconds()).Scan(&sessionID); err != nil {
+		return 0, err
+	}
+	query = `INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at)
+		SELECT user_id, $1, family_id, expires_at FROM sessions WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, query, tokenHash, sessionID); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return sessionID, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for its successor in the same family, which keeps
+// the family's expiry, and records the client in the session. It returns the user and the session
+// the token belongs to. Unknown and expired tokens give sql.ErrNoRows; a token that was already
+// exchanged or revoked gives ErrRefreshTokenReused along with the user it belongs to.
+func (r *Repository) RotateRefreshToken(ctx context.Context, tokenHash, newTokenHash string, client models.SessionClient) (int, int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	var id, userID int
+	var familyID string
+	var expired, revoked bool
+	query := `SELECT id, user_id, family_id, expires_at <= CURRENT_TIMESTAMP, revoked_at IS NOT NULL
+		FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, tokenHash).Scan(&id, &userID, &familyID, &expired, &revoked); err != nil {
+		return 0, 0, err
+	}
+
+	if revoked {
+		if err := revokeFamily(ctx, tx, familyID); err != nil {
+			return 0, 0, err
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, 0, err
+		}
+		return userID, 0, ErrRefreshTokenReused
+	}
+	if expired {
+		return 0, 0, sql.ErrNoRows
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1`, id); err != nil {
+		return 0, 0, err
+	}
+	query = `INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at)
+		SELECT user_id, $1, family_id, expires_at FROM refresh_tokens WHERE id = $2`
+	if _, err := tx.ExecContext(ctx, query, newTokenHash, id); err != nil {
+		return 0, 0, err
+	}
+
+	// Sessions started before sessions were tracked get their row on their first refresh
+	query = `INSERT INTO sessions (user_id, family_id, device, ip_address, user_agent, expires_at)
+		SELECT user_id, family_id, $2, $3, $4, expires_at FROM refresh_tokens WHERE id = $1
+		ON CONFLICT (family_id) DO UPDATE SET ip_address = EXCLUDED.ip_address, user_agent = EXCLUDED.user_agent,
+			last_seen_at = CURRENT_TIMESTAMP
+		RETURNING id`
+	var sessionID int
+	if err := tx.QueryRowContext(ctx, query, id, client.Device, client.IPAddress, client.UserAgent).Scan(&sessionID); err != nil {
+		return 0, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return userID, sessionID, nil
+}
+
+// revokeFamily revokes the refresh tokens and the session of a token family
+func revokeFamily(ctx context.Context, tx *sql.Tx, familyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND revoked_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, familyID); err != nil {
+		return err
+	}
+	query = `UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND revoked_at IS NULL`
+	_, err := tx.ExecContext(ctx, query, familyID)
+	return err
+}
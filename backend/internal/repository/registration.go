@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"service-weaver/internal/models"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+const registrationTokenColumns = `id, organization_id, diagram_id, name, created_by, last_used_at, created_at`
+
+func scanRegistrationToken(row rowScanner, t *models.RegistrationToken) error {
+	return row.Scan(&t.ID, &t.OrganizationID, &t.DiagramID, &t.Name, &t.CreatedBy, &t.LastUsedAt, &t.CreatedAt)
+}
+
+// CreateRegistrationToken stores a registration token under the hash of the token
+func (r *Repository) CreateRegistrationToken(ctx context.Context, token *models.RegistrationToken, tokenHash string) error {
+	query := `INSERT INTO registration_tokens (organization_id, diagram_id, name, token_hash, created_by)
+		VALUES ($1, $2, $3, $4, $5) RETURNING ` + registrationTokenColumns
+	return scanRegistrationToken(r.db.QueryRowContext(ctx, query, token.OrganizationID, token.DiagramID, token.Name, tokenHash, token.CreatedBy), token)
+}
+
+// GetRegistrationTokens returns the registration tokens of an organization, the newest first
+func (r *Repository) GetRegistrationTokens(ctx context.Context, orgID int) ([]models.RegistrationToken, error) {
+	query := `SELECT ` + registrationTokenColumns + ` FROM registration_tokens WHERE organization_id = $1 ORDER BY created_at DESC, id DESC`
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.RegistrationToken{}
+	for rows.Next() {
+		var t models.RegistrationToken
+		if err := scanRegistrationToken(rows, &t); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// DeleteRegistrationToken revokes a registration token of the organization, failing with
+// sql.ErrNoRows when it has none with the ID
+func (r *Repository) DeleteRegistrationToken(ctx context.Context, orgID, id int) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM registration_tokens WHERE id = $1 AND organization_id = $2`, id, orgID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UseRegistrationToken returns the registration token with the hash and records that it was used.
+// Unknown tokens give sql.ErrNoRows.
+func (r *Repository) UseRegistrationToken(ctx context.Context, tokenHash string) (*models.RegistrationToken, error) {
+	query := `UPDATE registration_tokens SET last_used_at = CURRENT_TIMESTAMP WHERE token_hash = $1 RETURNING ` + registrationTokenColumns
+	var t models.RegistrationToken
+	if err := scanRegistrationToken(r.db.QueryRowContext(ctx, query, tokenHash), &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RegisterService creates the node named like service in its diagram, or updates the fields a
+// registration sets on the one there is, and reports which. The polling interval is only updated
+// when the registration overrides it, listing it in Overrides. Registrations of a diagram are
+// serialized, so that two registering the same name at once cannot both create it. Updating a
+// node that already matches writes nothing and reports no change. It fails with sql.ErrNoRows
+// when the diagram does not exist or is in the trash.
+func (r *Repository) RegisterService(ctx context.Context, service *models.Service) (created, changed bool, err error) {
+	err = r.changed(r.WithTx(ctx, func(tx *sql.Tx) error {
+		var diagramID int
+		if err := tx.QueryRowContext(ctx, `SELECT id FROM diagrams WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, service.DiagramID).Scan(&diagramID); err != nil {
+			return err
+		}
+
+		var id int
+		query := `SELECT id FROM services WHERE diagram_id = $1 AND name = $2 AND deleted_at IS NULL AND overlay_of IS NULL ORDER BY id LIMIT 1`
+		err := tx.QueryRowContext(ctx, query, service.DiagramID, service.Name).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			created, changed = true, true
+			return createService(ctx, tx, service)
+		}
+		if err != nil {
+			return err
+		}
+
+		// A polling interval set by the registration holds against the diagram's defaults
+		interval := 0
+		if slices.Contains(service.Overrides, "polling_interval") {
+			interval = service.PollingInterval
+		}
+		query = `UPDATE services SET description = $2, service_type = $3, host = $4, port = $5, healthcheck_method = $6, healthcheck_url = $7,
+				polling_interval = COALESCE(NULLIF($8, 0), polling_interval),
+				overrides = CASE WHEN $8 = 0 OR 'polling_interval' = ANY(overrides) THEN overrides ELSE array_append(overrides, 'polling_interval') END,
+				tags = $9, environment = $10, udp_send_data = $11, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND (description, service_type, host, port, healthcheck_method, healthcheck_url, polling_interval, tags, environment, udp_send_data)
+				IS DISTINCT FROM ($2, $3, $4, $5, $6, $7, COALESCE(NULLIF($8, 0), polling_interval), $9, $10, $11)`
+		res, err := tx.ExecContext(ctx, query, id, service.Description, service.ServiceType, service.Host, service.Port, service.HealthcheckMethod,
+			service.HealthcheckURL, interval, pq.Array(models.SplitTags(service.Tags)), service.Environment, service.UDPSendData)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if changed = n > 0; changed {
+			if err := syncOverlays(ctx, tx, []int{id}); err != nil {
+				return err
+			}
+		}
+		return scanService(tx.QueryRowContext(ctx, `SELECT `+serviceColumns+` FROM services WHERE id = $1`, id), service)
+	}))
+	return created, changed, err
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"service-weaver/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const reportColumns = `id, name, diagram_id, cadence, window_days, channel_ids, enabled, last_sent_at, next_run_at, created_at, updated_at`
+
+// Report operations
+func (r *Repository) CreateReport(ctx context.Context, report *models.Report) error {
+	query := `INSERT INTO reports (name, diagram_id, cadence, window_days, channel_ids, enabled, next_run_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`
+	return r.db.QueryRowContext(ctx, query, report.Name, report.DiagramID, report.Cadence, report.WindowDays, pq.Array(report.ChannelIDs), report.Enabled, report.NextRunAt).Scan(&report.ID, &report.CreatedAt, &report.UpdatedAt)
+}
+
+func (r *Repository) GetReports(ctx context.Context) ([]models.Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM reports ORDER BY name`
+	return r.queryReports(ctx, query)
+}
+
+// GetDueReports returns the enabled reports whose next delivery time has passed
+func (r *Repository) GetDueReports(ctx context.Context, now time.Time) ([]models.Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM reports WHERE enabled = TRUE AND next_run_at <= $1 ORDER BY next_run_at`
+	return r.queryReports(ctx, query, now)
+}
+
+func (r *Repository) GetReport(ctx context.Context, id int) (*models.Report, error) {
+	query := `SELECT ` + reportColumns + ` FROM reports WHERE id = $1`
+	var report models.Report
+	if err := scanReport(r.db.QueryRowContext(ctx, query, id), &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *Repository) UpdateReport(ctx context.Context, report *models.Report) error {
+	query := `UPDATE reports SET name = $1, diagram_id = $2, cadence = $3, window_days = $4, channel_ids = $5, enabled = $6, next_run_at = $7, updated_at = CURRENT_TIMESTAMP WHERE id = $8`
+	result, err := r.db.ExecContext(ctx, query, report.Name, report.DiagramID, report.Cadence, report.WindowDays, pq.Array(report.ChannelIDs), report.Enabled, report.NextRunAt, report.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// MarkReportSent records a delivery and schedules the next one
+func (r *Repository) MarkReportSent(ctx context.Context, id int, sentAt, nextRunAt time.Time) error {
+	query := `UPDATE reports SET last_sent_at = $1, next_run_at = $2 WHERE id = $3`
+	_, err := r.db.ExecContext(ctx, query, sentAt, nextRunAt, id)
+	return err
+}
+
+func (r *Repository) DeleteReport(ctx context.Context, id int) error {
+	query := `DELETE FROM reports WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *Repository) queryReports(ctx context.Context, query string, args ...interface{}) ([]models.Report, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []models.Report{}
+	for rows.Next() {
+		var report models.Report
+		if err := scanReport(rows, &report); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func scanReport(row rowScanner, report *models.Report) error {
+	return row.Scan(&report.ID, &report.Name, &report.DiagramID, &report.Cadence, &report.WindowDays, pq.Array(&report.ChannelIDs), &report.Enabled, &report.LastSentAt, &report.NextRunAt, &report.CreatedAt, &report.UpdatedAt)
+}
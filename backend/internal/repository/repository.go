@@ -1,153 +1,154 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"service-weaver/internal/models"
+	"strings"
+	"sync"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/XSAM/otelsql"
+	"github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Repository struct {
 	db *sql.DB
+	// replica serves heavy reads that only feed responses and tolerate replication lag, such as
+	// result history, uptime and diagram listings; it is db while no replica is attached
+	replica *sql.DB
+
+	// listeners are called after services were created, changed or deleted; instance identifies
+	// this process in the announcements of such changes to other instances
+	listenersMu sync.Mutex
+	listeners   []func()
+	instance    string
 }
 
-func New(connStr string) (*Repository, error) {
-	db, err := sql.Open("postgres", connStr)
+// OnServicesChanged registers fn to be called after services were created, changed or deleted,
+// including through changes to their diagram, by this instance or, once ListenForChanges was
+// called, by another one. fn must not block.
+func (r *Repository) OnServicesChanged(fn func()) {
+	r.listenersMu.Lock()
+	r.listeners = append(r.listeners, fn)
+	r.listenersMu.Unlock()
+}
+
+// changed notifies the listeners and the other instances when a write to services succeeded, and
+// returns err
+func (r *Repository) changed(err error) error {
+	if err != nil {
+		return err
+	}
+	r.notifyListeners()
+	r.announce()
+	return nil
+}
+
+// queryRunner is implemented by both the database handle and *sql.Tx, so single-row
+// operations can be shared between standalone and transactional callers
+type queryRunner interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// PoolConfig limits the connection pool of the database. Zero values keep the database/sql
+// defaults, which put no limit on open connections.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// New connects to the database. The schema is left alone; see Migrate, CheckSchema and Prepare.
+func New(connStr string, pool PoolConfig) (*Repository, error) {
+	// Queries are traced by the driver wrapper, so a span lasts until its rows are closed
+	db, err := otelsql.Open("postgres", connStr,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{DisableErrSkip: true, OmitConnResetSession: true}),
+	)
 	if err != nil {
 		return nil, err
 	}
+	if _, err := otelsql.RegisterDBStatsMetrics(db, otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL)); err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
 	// Check if connection is working
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	repo := &Repository{db: db}
-	if err := repo.createTables(); err != nil {
-		return nil, err
+	return &Repository{db: db, replica: db, instance: newInstanceID()}, nil
+}
+
+// AttachReplica routes the heavy reads to a read-only replica of the database
+func (r *Repository) AttachReplica(connStr string, pool PoolConfig) error {
+	replica, err := New(connStr, pool)
+	if err != nil {
+		return fmt.Errorf("replica: %w", err)
 	}
+	r.replica = replica.db
+	return nil
+}
 
-	return repo, nil
-}
-
-func (r *Repository) createTables() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			username VARCHAR(255) UNIQUE NOT NULL,
-			password_hash VARCHAR(255) NOT NULL,
-			email VARCHAR(255) UNIQUE NOT NULL,
-			role VARCHAR(50) NOT NULL DEFAULT 'viewer',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS diagrams (
-			id SERIAL PRIMARY KEY,
-			name VARCHAR(255) NOT NULL,
-			description TEXT,
-			public BOOLEAN DEFAULT FALSE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS services (
-			id SERIAL PRIMARY KEY,
-			diagram_id INTEGER NOT NULL,
-			name VARCHAR(255) NOT NULL,
-			description TEXT,
-			service_type VARCHAR(50) NOT NULL,
-			icon TEXT,
-			host VARCHAR(255),
-			port INTEGER,
-			tags TEXT,
-			position_x REAL DEFAULT 0,
-			position_y REAL DEFAULT 0,
-			healthcheck_method VARCHAR(20) DEFAULT 'HTTP',
-			healthcheck_url TEXT,
-			polling_interval INTEGER DEFAULT 30,
-			request_timeout INTEGER DEFAULT 5,
-			expected_status INTEGER DEFAULT 200,
-			status_mapping JSONB DEFAULT '{}',
-			http_method VARCHAR(10) DEFAULT 'GET',
-			headers JSONB DEFAULT '{}',
-			body TEXT,
-			ssl_verify BOOLEAN DEFAULT true,
-			follow_redirects BOOLEAN DEFAULT true,
-			tcp_send_data TEXT,
-			tcp_expect_data TEXT,
-			udp_send_data TEXT,
-			udp_expect_data TEXT,
-			icmp_packet_count INTEGER DEFAULT 3,
-			dns_query_type VARCHAR(10) DEFAULT 'A',
-			dns_expected_result TEXT,
-			kafka_topic TEXT,
-			kafka_client_id VARCHAR(255) DEFAULT 'service-weaver-healthcheck',
-			current_status VARCHAR(20) DEFAULT 'unknown',
-			last_checked TIMESTAMP,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (diagram_id) REFERENCES diagrams(id) ON DELETE CASCADE
-		)`,
-		`CREATE TABLE IF NOT EXISTS connections (
-			id SERIAL PRIMARY KEY,
-			diagram_id INTEGER NOT NULL,
-			source_id INTEGER NOT NULL,
-			target_id INTEGER NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (diagram_id) REFERENCES diagrams(id) ON DELETE CASCADE,
-			FOREIGN KEY (source_id) REFERENCES services(id) ON DELETE CASCADE,
-			FOREIGN KEY (target_id) REFERENCES services(id) ON DELETE CASCADE
-		)`,
-		`CREATE TABLE IF NOT EXISTS healthcheck_results (
-			id SERIAL PRIMARY KEY,
-			service_id INTEGER NOT NULL,
-			status VARCHAR(20) NOT NULL,
-			status_code INTEGER,
-			response_time INTEGER,
-			error TEXT,
-			checked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
-		)`,
-	}
-
-	for _, query := range queries {
-		if _, err := r.db.Exec(query); err != nil {
-			return fmt.Errorf("failed to create table: %w", err)
-		}
+// PoolStats reports the state of the connection pools by database, primary or replica
+func (r *Repository) PoolStats() map[string]sql.DBStats {
+	stats := map[string]sql.DBStats{"primary": r.db.Stats()}
+	if r.replica != r.db {
+		stats["replica"] = r.replica.Stats()
+	}
+	return stats
+}
+
+// Ping checks that each database, primary and replica, answers within timeout
+func (r *Repository) Ping(timeout time.Duration) map[string]error {
+	ping := func(db *sql.DB) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return db.PingContext(ctx)
+	}
+	errs := map[string]error{"primary": ping(r.db)}
+	if r.replica != r.db {
+		errs["replica"] = ping(r.replica)
+	}
+	return errs
+}
+
+// Prepare runs the idempotent startup tasks that follow migrations: it moves data created before
+// organizations into the default one and creates the optional search indexes
+func (r *Repository) Prepare(ctx context.Context) error {
+	if err := r.bootstrapOrganizations(ctx); err != nil {
+		return fmt.Errorf("failed to set up organizations: %w", err)
 	}
 
-	// Add new columns for Kafka healthcheck if they don't exist
-	alterQueries := []string{
-		`DO $$
-		BEGIN
-			IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'kafka_topic') THEN
-				ALTER TABLE services ADD COLUMN kafka_topic TEXT;
-			END IF;
-		END $$`,
-		`DO $$
-		BEGIN
-			IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'kafka_client_id') THEN
-				ALTER TABLE services ADD COLUMN kafka_client_id VARCHAR(255) DEFAULT 'service-weaver-healthcheck';
-			END IF;
-		END $$`,
-		`DO $$
-		BEGIN
-			IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'diagrams' AND column_name = 'public') THEN
-				ALTER TABLE diagrams ADD COLUMN public BOOLEAN DEFAULT FALSE;
-			END IF;
-		END $$`,
-		`DO $$
-		BEGIN
-			IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'services' AND column_name = 'icon' AND data_type = 'character varying') THEN
-				ALTER TABLE services ALTER COLUMN icon TYPE TEXT;
-			END IF;
-		END $$`,
-	}
-
-	for _, query := range alterQueries {
-		if _, err := r.db.Exec(query); err != nil {
-			return fmt.Errorf("failed to alter table: %w", err)
+	// Trigram indexes speed up search but need the pg_trgm extension, which may require privileges
+	// the application user lacks; search falls back to sequential scans without them
+	if _, err := r.db.ExecContext(ctx, `CREATE EXTENSION IF NOT EXISTS pg_trgm`); err != nil {
+		log.Printf("pg_trgm extension unavailable, search will not be indexed: %v", err)
+	} else {
+		for _, query := range searchIndexQueries {
+			if _, err := r.db.ExecContext(ctx, query); err != nil {
+				return fmt.Errorf("failed to create search index: %w", err)
+			}
 		}
 	}
 
@@ -155,18 +156,26 @@ func (r *Repository) createTables() error {
 }
 
 // Diagram operations
-func (r *Repository) CreateDiagram(diagram *models.Diagram) error {
-	query := `INSERT INTO diagrams (name, description, public) VALUES ($1, $2, $3) RETURNING id`
-	err := r.db.QueryRow(query, diagram.Name, diagram.Description, diagram.Public).Scan(&diagram.ID)
+
+// diagramColumns lists the columns read by scanDiagram, in order
+const diagramColumns = `id, COALESCE(organization_id, 0), name, description, public, folder_id, owner_user_id, owner_team, environment, status_page_enabled, status_page_slug, status_page_title, service_defaults, deleted_at, created_at, updated_at`
+
+func scanDiagram(row rowScanner, d *models.Diagram) error {
+	return row.Scan(&d.ID, &d.OrganizationID, &d.Name, &d.Description, &d.Public, &d.FolderID, &d.OwnerUserID, &d.OwnerTeam, &d.Environment, &d.StatusPageEnabled, &d.StatusPageSlug, &d.StatusPageTitle, &d.ServiceDefaults, &d.DeletedAt, &d.CreatedAt, &d.UpdatedAt)
+}
+
+func (r *Repository) CreateDiagram(ctx context.Context, diagram *models.Diagram) error {
+	query := `INSERT INTO diagrams (organization_id, name, description, public, folder_id, owner_user_id, owner_team, environment) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	err := r.db.QueryRowContext(ctx, query, diagram.OrganizationID, diagram.Name, diagram.Description, diagram.Public, diagram.FolderID, diagram.OwnerUserID, diagram.OwnerTeam, diagram.Environment).Scan(&diagram.ID)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *Repository) GetDiagrams() ([]models.Diagram, error) {
-	query := `SELECT id, name, description, public, created_at, updated_at FROM diagrams ORDER BY updated_at DESC`
-	rows, err := r.db.Query(query)
+func (r *Repository) GetDiagrams(ctx context.Context) ([]models.Diagram, error) {
+	query := `SELECT ` + diagramColumns + ` FROM diagrams WHERE deleted_at IS NULL ORDER BY updated_at DESC`
+	rows, err := r.replica.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -175,7 +184,7 @@ func (r *Repository) GetDiagrams() ([]models.Diagram, error) {
 	var diagrams []models.Diagram
 	for rows.Next() {
 		var d models.Diagram
-		err := rows.Scan(&d.ID, &d.Name, &d.Description, &d.Public, &d.CreatedAt, &d.UpdatedAt)
+		err := scanDiagram(rows, &d)
 		if err != nil {
 			return nil, err
 		}
@@ -184,41 +193,146 @@ func (r *Repository) GetDiagrams() ([]models.Diagram, error) {
 	return diagrams, nil
 }
 
-func (r *Repository) GetDiagram(id int) (*models.Diagram, error) {
-	query := `SELECT id, name, description, public, created_at, updated_at FROM diagrams WHERE id = $1`
+func (r *Repository) GetDiagram(ctx context.Context, id int) (*models.Diagram, error) {
+	query := `SELECT ` + diagramColumns + ` FROM diagrams WHERE id = $1 AND deleted_at IS NULL`
 	var d models.Diagram
-	err := r.db.QueryRow(query, id).Scan(&d.ID, &d.Name, &d.Description, &d.Public, &d.CreatedAt, &d.UpdatedAt)
+	err := scanDiagram(r.db.QueryRowContext(ctx, query, id), &d)
 	if err != nil {
 		return nil, err
 	}
 	return &d, nil
 }
 
-func (r *Repository) UpdateDiagram(diagram *models.Diagram) error {
-	query := `UPDATE diagrams SET name = $1, description = $2, public = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4`
-	_, err := r.db.Exec(query, diagram.Name, diagram.Description, diagram.Public, diagram.ID)
-	return err
+// UpdateServiceDefaults replaces the healthcheck settings a diagram's services inherit
+func (r *Repository) UpdateServiceDefaults(ctx context.Context, diagramID int, defaults models.ServiceDefaults) error {
+	query := `UPDATE diagrams SET service_defaults = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND deleted_at IS NULL`
+	res, err := r.db.ExecContext(ctx, query, defaults, diagramID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return r.changed(nil)
 }
 
-func (r *Repository) DeleteDiagram(id int) error {
-	query := `DELETE FROM diagrams WHERE id = $1`
-	_, err := r.db.Exec(query, id)
+func (r *Repository) UpdateDiagram(ctx context.Context, diagram *models.Diagram) error {
+	query := `UPDATE diagrams SET name = $1, description = $2, public = $3, owner_user_id = $5, owner_team = $6, environment = $7, updated_at = CURRENT_TIMESTAMP WHERE id = $4 AND deleted_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, diagram.Name, diagram.Description, diagram.Public, diagram.ID, diagram.OwnerUserID, diagram.OwnerTeam, diagram.Environment)
 	return err
 }
 
+// DeleteDiagram moves a diagram and its services to the trash. The services share the diagram's
+// deletion time so restoring the diagram brings back exactly the services it took with it.
+func (r *Repository) DeleteDiagram(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	query := `UPDATE diagrams SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL RETURNING deleted_at`
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&deletedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	query = `UPDATE services SET deleted_at = $1 WHERE diagram_id = $2 AND deleted_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, deletedAt, id); err != nil {
+		return err
+	}
+	return r.changed(tx.Commit())
+}
+
 // Service operations
-func (r *Repository) CreateService(service *models.Service) error {
-	query := `INSERT INTO services (diagram_id, name, description, service_type, icon, host, port, tags, position_x, position_y, healthcheck_method, healthcheck_url, polling_interval, request_timeout, expected_status, status_mapping, http_method, headers, body, ssl_verify, follow_redirects, tcp_send_data, tcp_expect_data, udp_send_data, udp_expect_data, icmp_packet_count, dns_query_type, dns_expected_result, kafka_topic, kafka_client_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30) RETURNING id`
-	err := r.db.QueryRow(query, service.DiagramID, service.Name, service.Description, service.ServiceType, service.Icon, service.Host, service.Port, service.Tags, service.PositionX, service.PositionY, service.HealthcheckMethod, service.HealthcheckURL, service.PollingInterval, service.RequestTimeout, service.ExpectedStatus, service.StatusMapping, service.HTTPMethod, service.Headers, service.Body, service.SSLVerify, service.FollowRedirects, service.TCPSendData, service.TCPExpectData, service.UDPSendData, service.UDPExpectData, service.ICMPPacketCount, service.DNSQueryType, service.DNSExpectedResult, service.KafkaTopic, service.KafkaClientID).Scan(&service.ID)
+
+// serviceColumns lists the columns read by scanService, in order. The defaults of the service's
+// diagram are read along so that scanService returns the settings checks actually use; queries
+// must not alias the services table.
+const serviceColumns = `id, diagram_id, name, description, service_type, icon, icon_key, host, port, tags, runbook_url, notes, owner_user_id, owner_team, environment, position_x, position_y, healthcheck_method, healthcheck_url, polling_interval, request_timeout, expected_status, status_mapping, http_method, headers, body, ssl_verify, follow_redirects, tcp_send_data, tcp_expect_data, udp_send_data, udp_expect_data, icmp_packet_count, dns_query_type, dns_expected_result, kafka_topic, kafka_client_id, slo_target, retention_days, assertion, discovery_source_id, discovery_key, discovery_hash, discovery_deregistered_at, child_diagram_id, overlay_of, overrides, credentials, paused, current_status, last_checked, deleted_at, created_at, updated_at,
+	(SELECT d.service_defaults FROM diagrams d WHERE d.id = services.diagram_id)`
+
+func scanService(row rowScanner, s *models.Service) error {
+	var defaults models.ServiceDefaults
+	var tags []string
+	var headers []byte
+	s.Paused = new(bool)
+	err := row.Scan(&s.ID, &s.DiagramID, &s.Name, &s.Description, &s.ServiceType, &s.Icon, &s.IconKey, &s.Host, &s.Port, pq.Array(&tags), &s.RunbookURL, &s.Notes, &s.OwnerUserID, &s.OwnerTeam, &s.Environment, &s.PositionX, &s.PositionY, &s.HealthcheckMethod, &s.HealthcheckURL, &s.PollingInterval, &s.RequestTimeout, &s.ExpectedStatus, &s.StatusMapping, &s.HTTPMethod, &headers, &s.Body, &s.SSLVerify, &s.FollowRedirects, &s.TCPSendData, &s.TCPExpectData, &s.UDPSendData, &s.UDPExpectData, &s.ICMPPacketCount, &s.DNSQueryType, &s.DNSExpectedResult, &s.KafkaTopic, &s.KafkaClientID, &s.SLOTarget, &s.RetentionDays, &s.Assertion, &s.DiscoverySourceID, &s.DiscoveryKey, &s.DiscoveryHash, &s.DiscoveryDeregisteredAt, &s.ChildDiagramID, &s.OverlayOf, pq.Array(&s.Overrides), &s.SealedCredentials, s.Paused, &s.CurrentStatus, &s.LastChecked, &s.DeletedAt, &s.CreatedAt, &s.UpdatedAt, &defaults)
+	if err != nil {
+		return err
+	}
+	if s.Headers, err = openHeaders(headers); err != nil {
+		return fmt.Errorf("service %d: %w", s.ID, err)
+	}
+	s.Tags = strings.Join(tags, ",")
+	defaults.Apply(s)
+	s.HasCredentials = s.SealedCredentials != ""
+	if s.IconKey != "" {
+		s.Icon = iconURL(s.ID, s.IconKey)
+	}
+	return nil
+}
+
+func (r *Repository) CreateService(ctx context.Context, service *models.Service) error {
+	return r.changed(createService(ctx, r.db, service))
+}
+
+func createService(ctx context.Context, q queryRunner, service *models.Service) error {
+	if service.ChildDiagramID != nil {
+		if err := checkChildDiagram(ctx, q, service.DiagramID, *service.ChildDiagramID); err != nil {
+			return err
+		}
+	}
+
+	// Copies of a stored service keep its sealed credentials unless new ones are set
+	credentials := service.SealedCredentials
+	if sealed, err := sealCredentials(service); err != nil {
+		return err
+	} else if sealed != nil {
+		credentials = *sealed
+	}
+	unmasked, err := unmaskHeaders(ctx, q, 0, service.Headers)
 	if err != nil {
 		return err
 	}
+	service.Headers = unmasked
+	headers, err := sealHeaders(service.Headers)
+	if err != nil {
+		return err
+	}
+	icon, iconKey, err := storeIcon(ctx, service.Icon)
+	if err != nil {
+		return err
+	}
+	tags := models.SplitTags(service.Tags)
+	paused := service.Paused != nil && *service.Paused
+
+	query := `INSERT INTO services (diagram_id, name, description, service_type, icon, host, port, tags, position_x, position_y, healthcheck_method, healthcheck_url, polling_interval, request_timeout, expected_status, status_mapping, http_method, headers, body, ssl_verify, follow_redirects, tcp_send_data, tcp_expect_data, udp_send_data, udp_expect_data, icmp_packet_count, dns_query_type, dns_expected_result, kafka_topic, kafka_client_id, slo_target, retention_days, child_diagram_id, overrides, credentials, icon_key, runbook_url, notes, paused, owner_user_id, owner_team, assertion, environment) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33, COALESCE($34, '{}'::text[]), $35, $36, $37, $38, $39, $40, $41, $42, $43) RETURNING id`
+	err = q.QueryRowContext(ctx, query, service.DiagramID, service.Name, service.Description, service.ServiceType, icon, service.Host, service.Port, pq.Array(tags), service.PositionX, service.PositionY, service.HealthcheckMethod, service.HealthcheckURL, service.PollingInterval, service.RequestTimeout, service.ExpectedStatus, service.StatusMapping, service.HTTPMethod, headers, service.Body, service.SSLVerify, service.FollowRedirects, service.TCPSendData, service.TCPExpectData, service.UDPSendData, service.UDPExpectData, service.ICMPPacketCount, service.DNSQueryType, service.DNSExpectedResult, service.KafkaTopic, service.KafkaClientID, service.SLOTarget, service.RetentionDays, service.ChildDiagramID, pq.Array(service.Overrides), credentials, iconKey, service.RunbookURL, service.Notes, paused, service.OwnerUserID, service.OwnerTeam, service.Assertion, service.Environment).Scan(&service.ID)
+	if err != nil {
+		return err
+	}
+	service.Credentials = nil
+	service.SealedCredentials = credentials
+	service.HasCredentials = credentials != ""
+	service.IconKey = iconKey
+	if iconKey != "" {
+		service.Icon = iconURL(service.ID, iconKey)
+	}
+	service.Tags = strings.Join(tags, ",")
+	service.Paused = &paused
 	return nil
 }
 
-func (r *Repository) GetServices(diagramID int) ([]models.Service, error) {
-	query := `SELECT id, diagram_id, name, description, service_type, icon, host, port, tags, position_x, position_y, healthcheck_method, healthcheck_url, polling_interval, request_timeout, expected_status, status_mapping, http_method, headers, body, ssl_verify, follow_redirects, tcp_send_data, tcp_expect_data, udp_send_data, udp_expect_data, icmp_packet_count, dns_query_type, dns_expected_result, kafka_topic, kafka_client_id, current_status, last_checked, created_at, updated_at FROM services WHERE diagram_id = $1`
-	rows, err := r.db.Query(query, diagramID)
+// GetServices returns the nodes of a diagram, leaving out environment overlays (see GetOverlays)
+func (r *Repository) GetServices(ctx context.Context, diagramID int) ([]models.Service, error) {
+	query := `SELECT ` + serviceColumns + ` FROM services WHERE diagram_id = $1 AND deleted_at IS NULL AND overlay_of IS NULL`
+	rows, err := r.db.QueryContext(ctx, query, diagramID)
 	if err != nil {
 		return nil, err
 	}
@@ -227,7 +341,7 @@ func (r *Repository) GetServices(diagramID int) ([]models.Service, error) {
 	var services []models.Service
 	for rows.Next() {
 		var s models.Service
-		err := rows.Scan(&s.ID, &s.DiagramID, &s.Name, &s.Description, &s.ServiceType, &s.Icon, &s.Host, &s.Port, &s.Tags, &s.PositionX, &s.PositionY, &s.HealthcheckMethod, &s.HealthcheckURL, &s.PollingInterval, &s.RequestTimeout, &s.ExpectedStatus, &s.StatusMapping, &s.HTTPMethod, &s.Headers, &s.Body, &s.SSLVerify, &s.FollowRedirects, &s.TCPSendData, &s.TCPExpectData, &s.UDPSendData, &s.UDPExpectData, &s.ICMPPacketCount, &s.DNSQueryType, &s.DNSExpectedResult, &s.KafkaTopic, &s.KafkaClientID, &s.CurrentStatus, &s.LastChecked, &s.CreatedAt, &s.UpdatedAt)
+		err := scanService(rows, &s)
 		if err != nil {
 			return nil, err
 		}
@@ -236,9 +350,9 @@ func (r *Repository) GetServices(diagramID int) ([]models.Service, error) {
 	return services, nil
 }
 
-func (r *Repository) GetAllServices() ([]models.Service, error) {
-	query := `SELECT id, diagram_id, name, description, service_type, icon, host, port, tags, position_x, position_y, healthcheck_method, healthcheck_url, polling_interval, request_timeout, expected_status, status_mapping, http_method, headers, body, ssl_verify, follow_redirects, tcp_send_data, tcp_expect_data, udp_send_data, udp_expect_data, icmp_packet_count, dns_query_type, dns_expected_result, kafka_topic, kafka_client_id, current_status, last_checked, created_at, updated_at FROM services`
-	rows, err := r.db.Query(query)
+func (r *Repository) GetAllServices(ctx context.Context) ([]models.Service, error) {
+	query := `SELECT ` + serviceColumns + ` FROM services WHERE deleted_at IS NULL`
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -247,7 +361,7 @@ func (r *Repository) GetAllServices() ([]models.Service, error) {
 	var services []models.Service
 	for rows.Next() {
 		var s models.Service
-		err := rows.Scan(&s.ID, &s.DiagramID, &s.Name, &s.Description, &s.ServiceType, &s.Icon, &s.Host, &s.Port, &s.Tags, &s.PositionX, &s.PositionY, &s.HealthcheckMethod, &s.HealthcheckURL, &s.PollingInterval, &s.RequestTimeout, &s.ExpectedStatus, &s.StatusMapping, &s.HTTPMethod, &s.Headers, &s.Body, &s.SSLVerify, &s.FollowRedirects, &s.TCPSendData, &s.TCPExpectData, &s.UDPSendData, &s.UDPExpectData, &s.ICMPPacketCount, &s.DNSQueryType, &s.DNSExpectedResult, &s.KafkaTopic, &s.KafkaClientID, &s.CurrentStatus, &s.LastChecked, &s.CreatedAt, &s.UpdatedAt)
+		err := scanService(rows, &s)
 		if err != nil {
 			return nil, err
 		}
@@ -256,47 +370,183 @@ func (r *Repository) GetAllServices() ([]models.Service, error) {
 	return services, nil
 }
 
-func (r *Repository) UpdateService(service *models.Service) error {
-	query := `UPDATE services SET name = $1, description = $2, service_type = $3, icon = $4, host = $5, port = $6, tags = $7, position_x = $8, position_y = $9, healthcheck_method = $10, healthcheck_url = $11, polling_interval = $12, request_timeout = $13, expected_status = $14, status_mapping = $15, http_method = $16, headers = $17, body = $18, ssl_verify = $19, follow_redirects = $20, tcp_send_data = $21, tcp_expect_data = $22, udp_send_data = $23, udp_expect_data = $24, icmp_packet_count = $25, dns_query_type = $26, dns_expected_result = $27, kafka_topic = $28, kafka_client_id = $29, updated_at = CURRENT_TIMESTAMP WHERE id = $30`
-	_, err := r.db.Exec(query, service.Name, service.Description, service.ServiceType, service.Icon, service.Host, service.Port, service.Tags, service.PositionX, service.PositionY, service.HealthcheckMethod, service.HealthcheckURL, service.PollingInterval, service.RequestTimeout, service.ExpectedStatus, service.StatusMapping, service.HTTPMethod, service.Headers, service.Body, service.SSLVerify, service.FollowRedirects, service.TCPSendData, service.TCPExpectData, service.UDPSendData, service.UDPExpectData, service.ICMPPacketCount, service.DNSQueryType, service.DNSExpectedResult, service.KafkaTopic, service.KafkaClientID, service.ID)
-	return err
+func (r *Repository) UpdateService(ctx context.Context, service *models.Service) error {
+	_, err := updateService(ctx, r.db, service)
+	return r.changed(err)
+}
+
+// updateService reports whether a row matched the service ID and carries the change over to the
+// service's overlays; overlays themselves fail with ErrServiceIsOverlay. A nil Overrides keeps the
+// stored list and nil Credentials the stored credentials; a nil Paused keeps the service paused or
+// not.
+func updateService(ctx context.Context, q queryRunner, service *models.Service) (bool, error) {
+	if service.ChildDiagramID != nil {
+		var diagramID int
+		err := q.QueryRowContext(ctx, `SELECT diagram_id FROM services WHERE id = $1 AND deleted_at IS NULL`, service.ID).Scan(&diagramID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if err := checkChildDiagram(ctx, q, diagramID, *service.ChildDiagramID); err != nil {
+			return false, err
+		}
+	}
+
+	query := `UPDATE services SET name = $1, description = $2, service_type = $3, icon = $4, host = $5, port = $6, tags = $7, position_x = $8, position_y = $9, healthcheck_method = $10, healthcheck_url = $11, polling_interval = $12, request_timeout = $13, expected_status = $14, status_mapping = $15, http_method = $16, headers = $17, body = $18, ssl_verify = $19, follow_redirects = $20, tcp_send_data = $21, tcp_expect_data = $22, udp_send_data = $23, udp_expect_data = $24, icmp_packet_count = $25, dns_query_type = $26, dns_expected_result = $27, kafka_topic = $28, kafka_client_id = $29, slo_target = $30, retention_days = $31, child_diagram_id = $32, overrides = COALESCE($33, overrides), credentials = COALESCE($35, credentials), icon_key = $36, runbook_url = $37, notes = $38, paused = COALESCE($39, paused), owner_user_id = $40, owner_team = $41, assertion = $42, environment = $43, updated_at = CURRENT_TIMESTAMP WHERE id = $34 AND deleted_at IS NULL AND overlay_of IS NULL
+		RETURNING credentials <> '', paused`
+	credentials, err := sealCredentials(service)
+	if err != nil {
+		return false, err
+	}
+	if service.Headers, err = unmaskHeaders(ctx, q, service.ID, service.Headers); err != nil {
+		return false, err
+	}
+	headers, err := sealHeaders(service.Headers)
+	if err != nil {
+		return false, err
+	}
+	icon, iconKey, err := storeIcon(ctx, service.Icon)
+	if err != nil {
+		return false, err
+	}
+	tags := models.SplitTags(service.Tags)
+	var paused bool
+	err = q.QueryRowContext(ctx, query, service.Name, service.Description, service.ServiceType, icon, service.Host, service.Port, pq.Array(tags), service.PositionX, service.PositionY, service.HealthcheckMethod, service.HealthcheckURL, service.PollingInterval, service.RequestTimeout, service.ExpectedStatus, service.StatusMapping, service.HTTPMethod, headers, service.Body, service.SSLVerify, service.FollowRedirects, service.TCPSendData, service.TCPExpectData, service.UDPSendData, service.UDPExpectData, service.ICMPPacketCount, service.DNSQueryType, service.DNSExpectedResult, service.KafkaTopic, service.KafkaClientID, service.SLOTarget, service.RetentionDays, service.ChildDiagramID, pq.Array(service.Overrides), service.ID, credentials, iconKey, service.RunbookURL, service.Notes, service.Paused, service.OwnerUserID, service.OwnerTeam, service.Assertion, service.Environment).Scan(&service.HasCredentials, &paused)
+	if errors.Is(err, sql.ErrNoRows) {
+		if overlay, err := serviceIsOverlay(ctx, q, service.ID); err != nil {
+			return false, err
+		} else if overlay {
+			return false, ErrServiceIsOverlay
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := syncOverlays(ctx, q, []int{service.ID}); err != nil {
+		return false, err
+	}
+	service.Credentials = nil
+	service.IconKey = iconKey
+	if iconKey != "" {
+		service.Icon = iconURL(service.ID, iconKey)
+	}
+	service.Tags = strings.Join(tags, ",")
+	service.Paused = &paused
+	return true, nil
 }
 
-func (r *Repository) GetServiceByID(id int) (*models.Service, error) {
-	query := `SELECT id, diagram_id, name, description, service_type, icon, host, port, tags, position_x, position_y, healthcheck_method, healthcheck_url, polling_interval, request_timeout, expected_status, status_mapping, http_method, headers, body, ssl_verify, follow_redirects, tcp_send_data, tcp_expect_data, udp_send_data, udp_expect_data, icmp_packet_count, dns_query_type, dns_expected_result, kafka_topic, kafka_client_id, current_status, last_checked, created_at, updated_at FROM services WHERE id = $1`
+func (r *Repository) GetServiceByID(ctx context.Context, id int) (*models.Service, error) {
+	query := `SELECT ` + serviceColumns + ` FROM services WHERE id = $1 AND deleted_at IS NULL`
 	var s models.Service
-	err := r.db.QueryRow(query, id).Scan(&s.ID, &s.DiagramID, &s.Name, &s.Description, &s.ServiceType, &s.Icon, &s.Host, &s.Port, &s.Tags, &s.PositionX, &s.PositionY, &s.HealthcheckMethod, &s.HealthcheckURL, &s.PollingInterval, &s.RequestTimeout, &s.ExpectedStatus, &s.StatusMapping, &s.HTTPMethod, &s.Headers, &s.Body, &s.SSLVerify, &s.FollowRedirects, &s.TCPSendData, &s.TCPExpectData, &s.UDPSendData, &s.UDPExpectData, &s.ICMPPacketCount, &s.DNSQueryType, &s.DNSExpectedResult, &s.KafkaTopic, &s.KafkaClientID, &s.CurrentStatus, &s.LastChecked, &s.CreatedAt, &s.UpdatedAt)
+	err := scanService(r.db.QueryRowContext(ctx, query, id), &s)
 	if err != nil {
 		return nil, err
 	}
 	return &s, nil
 }
 
-func (r *Repository) UpdateServiceStatus(serviceID int, status models.ServiceStatus) error {
+// GetServiceStatuses returns the current status of every live service by ID
+func (r *Repository) GetServiceStatuses(ctx context.Context) (map[int]models.ServiceStatus, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, current_status FROM services WHERE deleted_at IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := make(map[int]models.ServiceStatus)
+	for rows.Next() {
+		var id int
+		var status models.ServiceStatus
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, err
+		}
+		statuses[id] = status
+	}
+	return statuses, rows.Err()
+}
+
+func (r *Repository) UpdateServiceStatus(ctx context.Context, serviceID int, status models.ServiceStatus) error {
 	query := `UPDATE services SET current_status = $1, last_checked = CURRENT_TIMESTAMP WHERE id = $2`
-	_, err := r.db.Exec(query, status, serviceID)
+	_, err := r.db.ExecContext(ctx, query, status, serviceID)
 	return err
 }
 
-func (r *Repository) DeleteService(id int) error {
-	query := `DELETE FROM services WHERE id = $1`
-	_, err := r.db.Exec(query, id)
-	return err
+// DeleteService moves a service to the trash with its overlays; overlays themselves fail with
+// ErrServiceIsOverlay
+func (r *Repository) DeleteService(ctx context.Context, id int) error {
+	return r.changed(r.WithTx(ctx, func(tx *sql.Tx) error {
+		if overlay, err := serviceIsOverlay(ctx, tx, id); err != nil {
+			return err
+		} else if overlay {
+			return ErrServiceIsOverlay
+		}
+		query := `UPDATE services SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL`
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return err
+		}
+		return syncOverlays(ctx, tx, []int{id})
+	}))
 }
 
 // Connection operations
-func (r *Repository) CreateConnection(connection *models.Connection) error {
-	query := `INSERT INTO connections (diagram_id, source_id, target_id) VALUES ($1, $2, $3) RETURNING id`
-	err := r.db.QueryRow(query, connection.DiagramID, connection.SourceID, connection.TargetID).Scan(&connection.ID)
+func (r *Repository) CreateConnection(ctx context.Context, connection *models.Connection) error {
+	return createConnection(ctx, r.db, connection)
+}
+
+// effectiveStatus selects the status of the service whose ID is in the column appended to it.
+// A service in the middle of a check keeps the result of its previous check so that edges and
+// composite nodes don't flicker on every poll.
+const effectiveStatus = `(SELECT COALESCE(CASE WHEN s.current_status = 'checking'
+		THEN (SELECT r.status FROM healthcheck_results r WHERE r.service_id = s.id ORDER BY r.checked_at DESC LIMIT 1)
+		ELSE s.current_status END, 'unknown') FROM services s WHERE s.id = `
+
+// connectionColumns lists the columns read by scanConnection, in order
+const connectionColumns = `id, diagram_id, source_id, target_id, direction, label, protocol, metadata,
+	` + effectiveStatus + `source_id), ` + effectiveStatus + `target_id), created_at`
+
+func scanConnection(row rowScanner, c *models.Connection) error {
+	var sourceStatus, targetStatus sql.NullString
+	err := row.Scan(&c.ID, &c.DiagramID, &c.SourceID, &c.TargetID, &c.Direction, &c.Label, &c.Protocol, &c.Metadata, &sourceStatus, &targetStatus, &c.CreatedAt)
+	if err != nil {
+		return err
+	}
+	c.Status = models.ConnectionStatusOf(models.ServiceStatus(sourceStatus.String), models.ServiceStatus(targetStatus.String))
+	return nil
+}
+
+// connectionDefaults fills in attributes missing from requests and from snapshots recorded
+// before connections had them
+func connectionDefaults(connection *models.Connection) {
+	if connection.Direction == "" {
+		connection.Direction = models.DirectionForward
+	}
+	if connection.Metadata == nil {
+		connection.Metadata = models.JSON{}
+	}
+}
+
+func createConnection(ctx context.Context, q queryRunner, connection *models.Connection) error {
+	connectionDefaults(connection)
+	query := `INSERT INTO connections (diagram_id, source_id, target_id, direction, label, protocol, metadata) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	err := q.QueryRowContext(ctx, query, connection.DiagramID, connection.SourceID, connection.TargetID, connection.Direction, connection.Label, connection.Protocol, connection.Metadata).Scan(&connection.ID)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *Repository) GetConnections(diagramID int) ([]models.Connection, error) {
-	query := `SELECT id, diagram_id, source_id, target_id, created_at FROM connections WHERE diagram_id = $1`
-	rows, err := r.db.Query(query, diagramID)
+func (r *Repository) GetConnections(ctx context.Context, diagramID int) ([]models.Connection, error) {
+	return getConnections(ctx, r.replica, diagramID)
+}
+
+// getConnections skips connections to services in the trash; they reappear when the service is restored
+func getConnections(ctx context.Context, q queryRunner, diagramID int) ([]models.Connection, error) {
+	query := `SELECT ` + connectionColumns + ` FROM connections c WHERE diagram_id = $1
+		AND NOT EXISTS (SELECT 1 FROM services s WHERE s.id IN (c.source_id, c.target_id) AND s.deleted_at IS NOT NULL)`
+	rows, err := q.QueryContext(ctx, query, diagramID)
 	if err != nil {
 		return nil, err
 	}
@@ -305,7 +555,7 @@ func (r *Repository) GetConnections(diagramID int) ([]models.Connection, error)
 	var connections []models.Connection
 	for rows.Next() {
 		var c models.Connection
-		err := rows.Scan(&c.ID, &c.DiagramID, &c.SourceID, &c.TargetID, &c.CreatedAt)
+		err := scanConnection(rows, &c)
 		if err != nil {
 			return nil, err
 		}
@@ -314,41 +564,108 @@ func (r *Repository) GetConnections(diagramID int) ([]models.Connection, error)
 	return connections, nil
 }
 
-func (r *Repository) DeleteConnection(id int) error {
+func (r *Repository) GetConnection(ctx context.Context, id int) (*models.Connection, error) {
+	query := `SELECT ` + connectionColumns + ` FROM connections WHERE id = $1`
+	var c models.Connection
+	if err := scanConnection(r.db.QueryRowContext(ctx, query, id), &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetServiceConnections returns the connections from or to a service, with their computed status.
+// Connections to services in the trash are skipped, as in getConnections.
+func (r *Repository) GetServiceConnections(ctx context.Context, serviceID int) ([]models.Connection, error) {
+	query := `SELECT ` + connectionColumns + ` FROM connections c WHERE (source_id = $1 OR target_id = $1)
+		AND NOT EXISTS (SELECT 1 FROM services s WHERE s.id IN (c.source_id, c.target_id) AND s.deleted_at IS NOT NULL)`
+	rows, err := r.db.QueryContext(ctx, query, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	connections := []models.Connection{}
+	for rows.Next() {
+		var c models.Connection
+		if err := scanConnection(rows, &c); err != nil {
+			return nil, err
+		}
+		connections = append(connections, c)
+	}
+	return connections, rows.Err()
+}
+
+func (r *Repository) DeleteConnection(ctx context.Context, id int) error {
 	query := `DELETE FROM connections WHERE id = $1`
-	_, err := r.db.Exec(query, id)
+	_, err := r.db.ExecContext(ctx, query, id)
 	return err
 }
 
-func (r *Repository) UpdateConnection(connection *models.Connection) error {
-	query := `UPDATE connections SET source_id = $1, target_id = $2 WHERE id = $3`
-	_, err := r.db.Exec(query, connection.SourceID, connection.TargetID, connection.ID)
+func (r *Repository) UpdateConnection(ctx context.Context, connection *models.Connection) error {
+	connectionDefaults(connection)
+	query := `UPDATE connections SET source_id = $1, target_id = $2, direction = $3, label = $4, protocol = $5, metadata = $6 WHERE id = $7`
+	_, err := r.db.ExecContext(ctx, query, connection.SourceID, connection.TargetID, connection.Direction, connection.Label, connection.Protocol, connection.Metadata, connection.ID)
 	return err
 }
 
 // Healthcheck result operations
-func (r *Repository) CreateHealthcheckResult(result *models.HealthcheckResult) error {
-	query := `INSERT INTO healthcheck_results (service_id, status, status_code, response_time, error) VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.db.Exec(query, result.ServiceID, result.Status, result.StatusCode, result.ResponseTime, result.Error)
+func (r *Repository) CreateHealthcheckResult(ctx context.Context, result *models.HealthcheckResult) error {
+	query := `INSERT INTO healthcheck_results (service_id, status, status_code, response_time, error, output) VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')) RETURNING id`
+	return r.db.QueryRowContext(ctx, query, result.ServiceID, result.Status, result.StatusCode, result.ResponseTime, result.Error, result.Output).Scan(&result.ID)
+}
+
+// CreateHealthcheckResults inserts results in a single statement, which also moves the services'
+// last_checked to their latest result. Results of services deleted in the meantime are skipped
+// rather than failing the others. Unlike CreateHealthcheckResult it keeps their CheckedAt and does
+// not set their IDs.
+func (r *Repository) CreateHealthcheckResults(ctx context.Context, results []models.HealthcheckResult) error {
+	serviceIDs := make([]int, len(results))
+	statuses := make([]string, len(results))
+	statusCodes := make([]int, len(results))
+	responseTimes := make([]int, len(results))
+	errs := make([]string, len(results))
+	outputs := make([]string, len(results))
+	checkedAt := make([]string, len(results))
+	for i, result := range results {
+		serviceIDs[i] = result.ServiceID
+		statuses[i] = string(result.Status)
+		statusCodes[i] = result.StatusCode
+		responseTimes[i] = result.ResponseTime
+		errs[i] = result.Error
+		outputs[i] = result.Output
+		checkedAt[i] = result.CheckedAt.Format(time.RFC3339Nano)
+	}
+	query := `WITH inserted AS (
+			INSERT INTO healthcheck_results (service_id, status, status_code, response_time, error, output, checked_at)
+			SELECT v.service_id, v.status, v.status_code, v.response_time, v.error, NULLIF(v.output, ''), v.checked_at
+			FROM unnest($1::int[], $2::varchar[], $3::int[], $4::int[], $5::text[], $6::text[], $7::timestamptz[])
+				AS v(service_id, status, status_code, response_time, error, output, checked_at)
+			WHERE EXISTS (SELECT 1 FROM services s WHERE s.id = v.service_id)
+			RETURNING service_id, checked_at
+		)
+		UPDATE services s SET last_checked = latest.checked_at
+		FROM (SELECT service_id, MAX(checked_at) AS checked_at FROM inserted GROUP BY service_id) latest
+		WHERE s.id = latest.service_id AND (s.last_checked IS NULL OR s.last_checked < latest.checked_at)`
+	_, err := r.db.ExecContext(ctx, query, pq.Array(serviceIDs), pq.Array(statuses), pq.Array(statusCodes), pq.Array(responseTimes), pq.Array(errs), pq.Array(outputs), pq.Array(checkedAt))
 	return err
 }
 
 // SaveServicePositions updates the positions of services for a given diagram.
-func (r *Repository) SaveServicePositions(diagramID int, positions []models.ServicePosition) error {
-	tx, err := r.db.Begin()
+func (r *Repository) SaveServicePositions(ctx context.Context, diagramID int, positions []models.ServicePosition) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(`UPDATE services SET position_x = $1, position_y = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3 AND diagram_id = $4`)
+	stmt, err := tx.PrepareContext(ctx, `UPDATE services SET position_x = $1, position_y = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3 AND diagram_id = $4`)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
 	for _, pos := range positions {
-		_, err = stmt.Exec(pos.PositionX, pos.PositionY, pos.ServiceID, diagramID)
+		_, err = stmt.ExecContext(ctx, pos.PositionX, pos.PositionY, pos.ServiceID, diagramID)
 		if err != nil {
 			return err
 		}
@@ -362,38 +679,38 @@ func (r *Repository) SaveServicePositions(diagramID int, positions []models.Serv
 }
 
 // User operations
-func (r *Repository) CreateUser(user *models.User) error {
+func (r *Repository) CreateUser(ctx context.Context, user *models.User) error {
 	query := `INSERT INTO users (username, password_hash, email, role) VALUES ($1, $2, $3, $4) RETURNING id`
-	err := r.db.QueryRow(query, user.Username, user.PasswordHash, user.Email, user.Role).Scan(&user.ID)
+	err := r.db.QueryRowContext(ctx, query, user.Username, user.PasswordHash, user.Email, user.Role).Scan(&user.ID)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *Repository) GetUserByUsername(username string) (*models.User, error) {
+func (r *Repository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	query := `SELECT id, username, password_hash, email, role, created_at, updated_at FROM users WHERE username = $1`
 	var u models.User
-	err := r.db.QueryRow(query, username).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Email, &u.Role, &u.CreatedAt, &u.UpdatedAt)
+	err := r.db.QueryRowContext(ctx, query, username).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Email, &u.Role, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return &u, nil
 }
 
-func (r *Repository) GetUserByID(id int) (*models.User, error) {
+func (r *Repository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
 	query := `SELECT id, username, password_hash, email, role, created_at, updated_at FROM users WHERE id = $1`
 	var u models.User
-	err := r.db.QueryRow(query, id).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Email, &u.Role, &u.CreatedAt, &u.UpdatedAt)
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Email, &u.Role, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return &u, nil
 }
 
-func (r *Repository) GetUsers() ([]models.User, error) {
+func (r *Repository) GetUsers(ctx context.Context) ([]models.User, error) {
 	query := `SELECT id, username, password_hash, email, role, created_at, updated_at FROM users ORDER BY created_at DESC`
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -411,7 +728,7 @@ func (r *Repository) GetUsers() ([]models.User, error) {
 	return users, nil
 }
 
-func (r *Repository) UpdateUser(user *models.User) error {
+func (r *Repository) UpdateUser(ctx context.Context, user *models.User) error {
 	var query string
 	var err error
 
@@ -420,30 +737,67 @@ func (r *Repository) UpdateUser(user *models.User) error {
 	// The handler is responsible for ensuring the hash is only present if a new password was provided.
 	if user.PasswordHash != "" {
 		query = `UPDATE users SET email = $1, role = $2, password_hash = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4`
-		_, err = r.db.Exec(query, user.Email, user.Role, user.PasswordHash, user.ID)
+		_, err = r.db.ExecContext(ctx, query, user.Email, user.Role, user.PasswordHash, user.ID)
 	} else {
 		query = `UPDATE users SET email = $1, role = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`
-		_, err = r.db.Exec(query, user.Email, user.Role, user.ID)
+		_, err = r.db.ExecContext(ctx, query, user.Email, user.Role, user.ID)
 	}
 
 	return err
 }
 
-func (r *Repository) DeleteUser(id int) error {
+// UpdateUserEmail changes only the email address of a user, leaving the role to admins
+func (r *Repository) UpdateUserEmail(ctx context.Context, id int, email string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET email = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, email, id)
+	return err
+}
+
+// UpdateUserPassword replaces the password hash of a user
+func (r *Repository) UpdateUserPassword(ctx context.Context, id int, passwordHash string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET password_hash = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, passwordHash, id)
+	return err
+}
+
+func (r *Repository) DeleteUser(ctx context.Context, id int) error {
 	query := `DELETE FROM users WHERE id = $1`
-	_, err := r.db.Exec(query, id)
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// WithTx runs fn in a transaction, committing it when fn returns nil and rolling it back otherwise,
+// so operations spanning several rows either happen completely or not at all
+func (r *Repository) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// withoutStatementTimeout lifts the statement timeout for the rest of tx, for the backup, restore,
+// migration and re-encryption statements that legitimately run long on large databases
+func withoutStatementTimeout(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `SET LOCAL statement_timeout = 0`)
 	return err
 }
 
 func (r *Repository) Close() error {
+	if r.replica != r.db {
+		r.replica.Close()
+	}
 	return r.db.Close()
 }
 
 // CheckFirstRun checks if this is the first run (no users exist)
-func (r *Repository) CheckFirstRun() (bool, error) {
+func (r *Repository) CheckFirstRun(ctx context.Context) (bool, error) {
 	var count int
 	query := `SELECT COUNT(*) FROM users`
-	err := r.db.QueryRow(query).Scan(&count)
+	err := r.db.QueryRowContext(ctx, query).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -451,7 +805,7 @@ func (r *Repository) CheckFirstRun() (bool, error) {
 }
 
 // CreateFirstRunAdmin creates the first admin user
-func (r *Repository) CreateFirstRunAdmin(username, password, email string) (*models.User, error) {
+func (r *Repository) CreateFirstRunAdmin(ctx context.Context, username, password, email string) (*models.User, error) {
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -465,11 +819,27 @@ func (r *Repository) CreateFirstRunAdmin(username, password, email string) (*mod
 		Role:         models.RoleAdmin,
 	}
 
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	query := `INSERT INTO users (username, password_hash, email, role) VALUES ($1, $2, $3, $4) RETURNING id`
-	err = r.db.QueryRow(query, user.Username, user.PasswordHash, user.Email, user.Role).Scan(&user.ID)
+	err = tx.QueryRowContext(ctx, query, user.Username, user.PasswordHash, user.Email, user.Role).Scan(&user.ID)
 	if err != nil {
 		return nil, err
 	}
 
+	// The first admin also administers the organization created at startup
+	query = `INSERT INTO organization_members (organization_id, user_id, role)
+		SELECT id, $1, $2 FROM organizations ORDER BY id LIMIT 1`
+	if _, err := tx.ExecContext(ctx, query, user.ID, models.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	return user, nil
 }
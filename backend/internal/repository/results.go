@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"service-weaver/internal/models"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ResultFilter narrows down a healthcheck results query. Zero values disable the corresponding filter.
+type ResultFilter struct {
+	OrganizationID int    // Only used, and always applied, by StreamHealthcheckResults
+	Environment    string // Only used by StreamHealthcheckResults
+	ServiceID      int
+	From           time.Time
+	To             time.Time
+	Statuses       []string
+	Limit          int
+	Offset         int
+}
+
+// GetHealthcheckResults returns a page of results, newest first, along with the total number of matches
+func (r *Repository) GetHealthcheckResults(ctx context.Context, filter ResultFilter) ([]models.HealthcheckResult, int, error) {
+	conditions := []string{"service_id = $1"}
+	args := []interface{}{filter.ServiceID}
+
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("checked_at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("checked_at < $%d", len(args)))
+	}
+	if len(filter.Statuses) > 0 {
+		args = append(args, pq.Array(filter.Statuses))
+		conditions = append(conditions, fmt.Sprintf("status = ANY($%d)", len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.replica.QueryRowContext(ctx, `SELECT COUNT(*) FROM healthcheck_results WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`SELECT id, service_id, status, COALESCE(status_code, 0), COALESCE(response_time, 0), COALESCE(error, ''), COALESCE(output, ''), checked_at
+		FROM healthcheck_results WHERE %s ORDER BY checked_at DESC, id DESC LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
+	rows, err := r.replica.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	results := []models.HealthcheckResult{}
+	for rows.Next() {
+		var hr models.HealthcheckResult
+		if err := rows.Scan(&hr.ID, &hr.ServiceID, &hr.Status, &hr.StatusCode, &hr.ResponseTime, &hr.Error, &hr.Output, &hr.CheckedAt); err != nil {
+			return nil, 0, err
+		}
+		results = append(results, hr)
+	}
+	return results, total, nil
+}
+
+// StreamHealthcheckResults calls fn for every result matching the filter, oldest first, without
+// buffering the whole set in memory. Limit and Offset are ignored. Only results of the filter's
+// organization are streamed, so a zero OrganizationID matches nothing.
+func (r *Repository) StreamHealthcheckResults(ctx context.Context, filter ResultFilter, fn func(models.HealthcheckResult) error) error {
+	args := []interface{}{filter.OrganizationID}
+	conditions := []string{`service_id IN (SELECT s.id FROM services s
+			JOIN diagrams d ON d.id = s.diagram_id WHERE d.organization_id = $1)`}
+
+	if filter.Environment != "" {
+		args = append(args, filter.Environment)
+		conditions = append(conditions, "service_id IN "+environmentServices(len(args)))
+	}
+	if filter.ServiceID != 0 {
+		args = append(args, filter.ServiceID)
+		conditions = append(conditions, fmt.Sprintf("service_id = $%d", len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("checked_at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("checked_at < $%d", len(args)))
+	}
+	if len(filter.Statuses) > 0 {
+		args = append(args, pq.Array(filter.Statuses))
+		conditions = append(conditions, fmt.Sprintf("status = ANY($%d)", len(args)))
+	}
+
+	query := `SELECT id, service_id, status, COALESCE(status_code, 0), COALESCE(response_time, 0), COALESCE(error, ''), checked_at
+		FROM healthcheck_results WHERE ` + strings.Join(conditions, " AND ") + ` ORDER BY checked_at, id`
+	rows, err := r.replica.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hr models.HealthcheckResult
+		if err := rows.Scan(&hr.ID, &hr.ServiceID, &hr.Status, &hr.StatusCode, &hr.ResponseTime, &hr.Error, &hr.CheckedAt); err != nil {
+			return err
+		}
+		if err := fn(hr); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
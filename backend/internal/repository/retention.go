@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"service-weaver/internal/models"
+	"time"
+)
+
+// pruneBatchSize bounds each DELETE so pruning never holds long locks on the results table
+const pruneBatchSize = 10000
+
+// PruneHealthcheckResults deletes results older than each service's retention period,
+// falling back to defaultRetentionDays for services without an override. It returns the number of rows removed.
+func (r *Repository) PruneHealthcheckResults(ctx context.Context, defaultRetentionDays int) (int64, error) {
+	query := `DELETE FROM healthcheck_results WHERE id IN (
+		SELECT r.id FROM healthcheck_results r
+		JOIN services s ON s.id = r.service_id
+		WHERE r.checked_at < CURRENT_TIMESTAMP - make_interval(days => CASE WHEN s.retention_days > 0 THEN s.retention_days ELSE $1 END)
+		LIMIT $2
+	)`
+
+	var total int64
+	for {
+		res, err := r.db.ExecContext(ctx, query, defaultRetentionDays, pruneBatchSize)
+		if err != nil {
+			return total, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < pruneBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// GetResultsTableStats reports the size of the healthcheck results table. Counting the rows of
+// the largest table would scan all of it, so the row count is the estimate Postgres keeps up to
+// date with autovacuum; the oldest result is found through the index of each service's results.
+func (r *Repository) GetResultsTableStats(ctx context.Context) (*models.ResultsTableStats, error) {
+	query := `SELECT
+			(SELECT COALESCE(st.n_live_tup, GREATEST(c.reltuples, 0)::bigint)
+				FROM pg_class c LEFT JOIN pg_stat_user_tables st ON st.relid = c.oid
+				WHERE c.oid = 'healthcheck_results'::regclass),
+			pg_total_relation_size('healthcheck_results'),
+			pg_size_pretty(pg_total_relation_size('healthcheck_results')),
+			(SELECT MIN(oldest.checked_at) FROM services s CROSS JOIN LATERAL (
+				SELECT checked_at FROM healthcheck_results r WHERE r.service_id = s.id ORDER BY checked_at LIMIT 1
+			) oldest)`
+	var stats models.ResultsTableStats
+	err := r.db.QueryRowContext(ctx, query).Scan(&stats.RowCount, &stats.TotalSizeBytes, &stats.TotalSize, &stats.OldestResult)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// RetentionPruner periodically removes healthcheck results that exceed their retention period
+type RetentionPruner struct {
+	repo          *Repository
+	retentionDays int
+	interval      time.Duration
+	leading       func() bool
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+func NewRetentionPruner(repo *Repository, retentionDays int, interval time.Duration) *RetentionPruner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RetentionPruner{
+		repo:          repo,
+		retentionDays: retentionDays,
+		interval:      interval,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// RetentionDays returns the deployment-wide retention period
+func (p *RetentionPruner) RetentionDays() int {
+	return p.retentionDays
+}
+
+// RunWhen makes the periodic passes run only while leading reports true, so that of several
+// instances sharing the database only the one elected to lead prunes. It must be called before
+// Start; Prune itself always runs.
+func (p *RetentionPruner) RunWhen(leading func() bool) {
+	p.leading = leading
+}
+
+func (p *RetentionPruner) Start() {
+	go p.run()
+}
+
+func (p *RetentionPruner) Stop() {
+	p.cancel()
+}
+
+// Prune runs a single pruning pass. Raw results are rolled up first so that long-term
+// reporting survives their deletion; if the rollup fails nothing is deleted.
+func (p *RetentionPruner) Prune() (int64, error) {
+	if err := p.repo.RollupHealthcheckResults(p.ctx); err != nil {
+		return 0, fmt.Errorf("failed to roll up healthcheck results: %w", err)
+	}
+
+	deleted, err := p.repo.PruneHealthcheckResults(p.ctx, p.retentionDays)
+	if err != nil {
+		return deleted, err
+	}
+
+	if _, err := p.repo.PruneRollups(p.ctx); err != nil {
+		return deleted, fmt.Errorf("failed to prune rollups: %w", err)
+	}
+	return deleted, nil
+}
+
+func (p *RetentionPruner) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if p.leading != nil && !p.leading() {
+				continue
+			}
+			deleted, err := p.Prune()
+			if err != nil {
+				log.Printf("Error pruning healthcheck results: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Pruned %d healthcheck results older than retention", deleted)
+			}
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
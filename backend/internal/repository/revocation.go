@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// RevokeToken adds an access token to the denylist until it expires. Entries of tokens that have
+// expired anyway are cleaned up on the way.
+func (r *Repository) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < CURRENT_TIMESTAMP`); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO revoked_tokens (token_id, expires_at) VALUES ($1, to_timestamp($2))
+		ON CONFLICT (token_id) DO NOTHING`
+	_, err := r.db.ExecContext(ctx, query, tokenID, expiresAt.Unix())
+	return err
+}
+
+// RevokeRefreshToken ends the session a refresh token belongs to. Tokens of other users are left
+// alone, so a user cannot end someone else's session.
+func (r *Repository) RevokeRefreshToken(ctx context.Context, userID int, tokenHash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var familyID string
+	err = tx.QueryRowContext(ctx, `SELECT family_id FROM refresh_tokens WHERE token_hash = $1 AND user_id = $2`, tokenHash, userID).Scan(&familyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := revokeFamily(ctx, tx, familyID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RevokeUserTokens ends every session of a user: all refresh tokens are revoked and every access
+// token issued so far is rejected.
+func (r *Repository) RevokeUserTokens(ctx context.Context, userID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Token issue times have second precision; truncating keeps tokens issued right after valid
+	result, err := tx.ExecContext(ctx, `UPDATE users SET tokens_revoked_at = date_trunc('second', CURRENT_TIMESTAMP) WHERE id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	query := `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return err
+	}
+	query = `UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// IsTokenRevoked reports whether an access token was revoked on its own, with its session (0 for
+// tokens without one) or by ending all sessions of its user
+func (r *Repository) IsTokenRevoked(ctx context.Context, tokenID string, userID, sessionID int, issuedAt time.Time) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE token_id = $1)
+		OR EXISTS (SELECT 1 FROM users WHERE id = $2 AND tokens_revoked_at > to_timestamp($3))
+		OR EXISTS (SELECT 1 FROM sessions WHERE id = $4 AND revoked_at IS NOT NULL)`
+	var revoked bool
+	err := r.db.QueryRowContext(ctx, query, tokenID, userID, issuedAt.Unix(), sessionID).Scan(&revoked)
+	return revoked, err
+}
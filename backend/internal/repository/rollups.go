@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"service-weaver/internal/models"
+	"time"
+)
+
+// Rollups outlive raw results but are still bounded
+const (
+	hourlyRollupRetentionDays = 90
+	dailyRollupRetentionDays  = 730
+)
+
+// RollupHealthcheckResults aggregates completed hours of raw results into hourly rollups and
+// completed days of hourly rollups into daily rollups. The most recent bucket of each
+// granularity is recomputed on every run, so the operation is idempotent. Like uptime, totals
+// leave out results of unknown status.
+func (r *Repository) RollupHealthcheckResults(ctx context.Context) error {
+	hourly := `INSERT INTO healthcheck_rollups (service_id, granularity, bucket_start, total_checks, up_checks, failure_count, avg_response_time, p95_response_time)
+		SELECT service_id, 'hour', date_trunc('hour', checked_at) AS bucket,
+			COUNT(*) FILTER (WHERE status IN ('alive', 'degraded', 'dead')),
+			COUNT(*) FILTER (WHERE status IN ('alive', 'degraded')),
+			COUNT(*) FILTER (WHERE status = 'dead'),
+			COALESCE(AVG(response_time), 0),
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY response_time), 0)
+		FROM healthcheck_results
+		WHERE checked_at < date_trunc('hour', CURRENT_TIMESTAMP)
+			AND checked_at >= COALESCE((SELECT MAX(bucket_start) FROM healthcheck_rollups WHERE granularity = 'hour'), '-infinity')
+		GROUP BY service_id, bucket
+		ON CONFLICT (service_id, granularity, bucket_start) DO UPDATE SET
+			total_checks = EXCLUDED.total_checks,
+			up_checks = EXCLUDED.up_checks,
+			failure_count = EXCLUDED.failure_count,
+			avg_response_time = EXCLUDED.avg_response_time,
+			p95_response_time = EXCLUDED.p95_response_time`
+
+	// Daily p95 is approximated by the worst hourly p95, which keeps it a conservative upper bound
+	daily := `INSERT INTO healthcheck_rollups (service_id, granularity, bucket_start, total_checks, up_checks, failure_count, avg_response_time, p95_response_time)
+		SELECT service_id, 'day', date_trunc('day', bucket_start) AS bucket,
+			SUM(total_checks),
+			SUM(up_checks),
+			SUM(failure_count),
+			COALESCE(SUM(avg_response_time * total_checks) / NULLIF(SUM(total_checks), 0), 0),
+			COALESCE(MAX(p95_response_time), 0)
+		FROM healthcheck_rollups
+		WHERE granularity = 'hour'
+			AND bucket_start < date_trunc('day', CURRENT_TIMESTAMP)
+			AND bucket_start >= COALESCE((SELECT MAX(bucket_start) FROM healthcheck_rollups WHERE granularity = 'day'), '-infinity')
+		GROUP BY service_id, bucket
+		ON CONFLICT (service_id, granularity, bucket_start) DO UPDATE SET
+			total_checks = EXCLUDED.total_checks,
+			up_checks = EXCLUDED.up_checks,
+			failure_count = EXCLUDED.failure_count,
+			avg_response_time = EXCLUDED.avg_response_time,
+			p95_response_time = EXCLUDED.p95_response_time`
+
+	for _, query := range []string{hourly, daily} {
+		if _, err := r.db.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneRollups drops rollups past their retention period
+func (r *Repository) PruneRollups(ctx context.Context) (int64, error) {
+	query := `DELETE FROM healthcheck_rollups
+		WHERE (granularity = 'hour' AND bucket_start < CURRENT_TIMESTAMP - make_interval(days => $1))
+			OR (granularity = 'day' AND bucket_start < CURRENT_TIMESTAMP - make_interval(days => $2))`
+	res, err := r.db.ExecContext(ctx, query, hourlyRollupRetentionDays, dailyRollupRetentionDays)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetRollups returns a service's rollups of the given granularity in [from, to)
+func (r *Repository) GetRollups(ctx context.Context, serviceID int, granularity models.RollupGranularity, from, to time.Time) ([]models.HealthcheckRollup, error) {
+	query := `SELECT service_id, granularity, bucket_start, total_checks, up_checks, failure_count, avg_response_time, p95_response_time
+		FROM healthcheck_rollups
+		WHERE service_id = $1 AND granularity = $2 AND bucket_start >= $3 AND bucket_start < $4
+		ORDER BY bucket_start`
+	rows, err := r.replica.QueryContext(ctx, query, serviceID, granularity, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rollups := []models.HealthcheckRollup{}
+	for rows.Next() {
+		var ru models.HealthcheckRollup
+		err := rows.Scan(&ru.ServiceID, &ru.Granularity, &ru.BucketStart, &ru.TotalChecks, &ru.UpChecks, &ru.FailureCount, &ru.AvgResponseTime, &ru.P95ResponseTime)
+		if err != nil {
+			return nil, err
+		}
+		if ru.TotalChecks > 0 {
+			ru.UptimePercent = float64(ru.UpChecks) / float64(ru.TotalChecks) * 100
+		}
+		rollups = append(rollups, ru)
+	}
+	return rollups, rows.Err()
+}
+
+// GetUnrolledBuckets aggregates raw results from since onwards into buckets of the given granularity.
+// It covers the buckets the rollup job has not yet written, such as the current hour or day.
+func (r *Repository) GetUnrolledBuckets(ctx context.Context, serviceID int, granularity models.RollupGranularity, since time.Time) ([]models.HealthcheckRollup, error) {
+	query := `SELECT service_id, date_trunc($2, checked_at) AS bucket,
+			COUNT(*) FILTER (WHERE status IN ('alive', 'degraded', 'dead')),
+			COUNT(*) FILTER (WHERE status IN ('alive', 'degraded')),
+			COUNT(*) FILTER (WHERE status = 'dead'),
+			COALESCE(AVG(response_time), 0),
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY response_time), 0)
+		FROM healthcheck_results
+		WHERE service_id = $1 AND checked_at >= $3
+		GROUP BY service_id, bucket
+		ORDER BY bucket`
+	rows, err := r.db.QueryContext(ctx, query, serviceID, string(granularity), since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rollups := []models.HealthcheckRollup{}
+	for rows.Next() {
+		ru := models.HealthcheckRollup{Granularity: granularity}
+		err := rows.Scan(&ru.ServiceID, &ru.BucketStart, &ru.TotalChecks, &ru.UpChecks, &ru.FailureCount, &ru.AvgResponseTime, &ru.P95ResponseTime)
+		if err != nil {
+			return nil, err
+		}
+		if ru.TotalChecks > 0 {
+			ru.UptimePercent = float64(ru.UpChecks) / float64(ru.TotalChecks) * 100
+		}
+		rollups = append(rollups, ru)
+	}
+	return rollups, rows.Err()
+}
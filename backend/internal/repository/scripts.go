@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"service-weaver/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrScriptNameTaken is returned when another check script already has the name
+	ErrScriptNameTaken = errors.New("a check script with this name already exists")
+	// ErrScriptInUse is returned when deleting a check script services still run
+	ErrScriptInUse = errors.New("the check script is run by services; give them another script first")
+)
+
+const checkScriptColumns = `id, name, description, interpreter, content, created_by, created_at, updated_at`
+
+func scanCheckScript(row rowScanner, script *models.CheckScript) error {
+	return row.Scan(&script.ID, &script.Name, &script.Description, &script.Interpreter, &script.Content, &script.CreatedBy, &script.CreatedAt, &script.UpdatedAt)
+}
+
+// scriptNameTaken reports whether a check script other than id has the name
+func scriptNameTaken(ctx context.Context, tx *sql.Tx, name string, id int) (bool, error) {
+	var taken bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM check_scripts WHERE LOWER(name) = LOWER($1) AND id <> $2)`, name, id).Scan(&taken)
+	return taken, err
+}
+
+// GetCheckScripts returns every check script by name
+func (r *Repository) GetCheckScripts(ctx context.Context) ([]models.CheckScript, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+checkScriptColumns+` FROM check_scripts ORDER BY LOWER(name)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scripts := []models.CheckScript{}
+	for rows.Next() {
+		var script models.CheckScript
+		if err := scanCheckScript(rows, &script); err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, script)
+	}
+	return scripts, rows.Err()
+}
+
+// GetCheckScript returns a check script, sql.ErrNoRows when it does not exist
+func (r *Repository) GetCheckScript(ctx context.Context, id int) (*models.CheckScript, error) {
+	var script models.CheckScript
+	if err := scanCheckScript(r.db.QueryRowContext(ctx, `SELECT `+checkScriptColumns+` FROM check_scripts WHERE id = $1`, id), &script); err != nil {
+		return nil, err
+	}
+	return &script, nil
+}
+
+// CreateCheckScript stores a new check script, failing with ErrScriptNameTaken for a taken name
+func (r *Repository) CreateCheckScript(ctx context.Context, script *models.CheckScript) error {
+	return r.WithTx(ctx, func(tx *sql.Tx) error {
+		taken, err := scriptNameTaken(ctx, tx, script.Name, 0)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrScriptNameTaken
+		}
+		query := `INSERT INTO check_scripts (name, description, interpreter, content, created_by)
+			VALUES ($1, $2, $3, $4, $5) RETURNING ` + checkScriptColumns
+		return scanCheckScript(tx.QueryRowContext(ctx, query, script.Name, script.Description, script.Interpreter, script.Content, script.CreatedBy), script)
+	})
+}
+
+// UpdateCheckScript replaces a check script, which services running it pick up with their next
+// check. It fails with sql.ErrNoRows when the script does not exist.
+func (r *Repository) UpdateCheckScript(ctx context.Context, script *models.CheckScript) error {
+	return r.WithTx(ctx, func(tx *sql.Tx) error {
+		taken, err := scriptNameTaken(ctx, tx, script.Name, script.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrScriptNameTaken
+		}
+		query := `UPDATE check_scripts SET name = $2, description = $3, interpreter = $4, content = $5, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 RETURNING ` + checkScriptColumns
+		return scanCheckScript(tx.QueryRowContext(ctx, query, script.ID, script.Name, script.Description, script.Interpreter, script.Content), script)
+	})
+}
+
+// DeleteCheckScript deletes a check script no service runs, failing with ErrScriptInUse otherwise
+func (r *Repository) DeleteCheckScript(ctx context.Context, id int) error {
+	return r.WithTx(ctx, func(tx *sql.Tx) error {
+		var inUse bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM service_scripts WHERE script_id = $1)`, id).Scan(&inUse); err != nil {
+			return err
+		}
+		if inUse {
+			return ErrScriptInUse
+		}
+		result, err := tx.ExecContext(ctx, `DELETE FROM check_scripts WHERE id = $1`, id)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// GetServiceScript returns the check script of a service along with its arguments, sql.ErrNoRows
+// until it is set
+func (r *Repository) GetServiceScript(ctx context.Context, serviceID int) (*models.ServiceScript, error) {
+	query := `SELECT ss.service_id, ss.script_id, ss.args,
+			cs.id, cs.name, cs.description, cs.interpreter, cs.content, cs.created_by, cs.created_at, cs.updated_at
+		FROM service_scripts ss
+		JOIN check_scripts cs ON cs.id = ss.script_id
+		WHERE ss.service_id = $1`
+	ss := models.ServiceScript{Script: &models.CheckScript{}}
+	s := ss.Script
+	err := r.db.QueryRowContext(ctx, query, serviceID).Scan(&ss.ServiceID, &ss.ScriptID, pq.Array(&ss.Args),
+		&s.ID, &s.Name, &s.Description, &s.Interpreter, &s.Content, &s.CreatedBy, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &ss, nil
+}
+
+// SetServiceScript sets the check script a service runs and its arguments
+func (r *Repository) SetServiceScript(ctx context.Context, serviceID, scriptID int, args []string) error {
+	if args == nil {
+		args = []string{}
+	}
+	query := `INSERT INTO service_scripts (service_id, script_id, args) VALUES ($1, $2, $3)
+		ON CONFLICT (service_id) DO UPDATE SET script_id = EXCLUDED.script_id, args = EXCLUDED.args`
+	_, err := r.db.ExecContext(ctx, query, serviceID, scriptID, pq.Array(args))
+	return err
+}
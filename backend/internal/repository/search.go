@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"service-weaver/internal/models"
+	"strings"
+)
+
+// searchIndexQueries create the trigram indexes backing Search; they are only run when pg_trgm is
+// available. They cover the small diagrams and services tables only: building one on the results
+// table would block result writes at startup for as long as it takes, so SearchErrors relies on
+// its service and time filters instead.
+var searchIndexQueries = []string{
+	`CREATE INDEX IF NOT EXISTS idx_diagrams_name_trgm ON diagrams USING gin (name gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_diagrams_description_trgm ON diagrams USING gin (description gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_services_name_trgm ON services USING gin (name gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_services_host_trgm ON services USING gin (host gin_trgm_ops)`,
+}
+
+// Search finds diagrams by name or description and services by name, host or tag. Exact matches
+// rank first, then prefix matches, then substring matches. Only content of the organization's
+// diagrams is returned, and only of public diagrams when publicOnly is set.
+func (r *Repository) Search(ctx context.Context, orgID int, term string, publicOnly bool, limit int) ([]models.SearchResult, error) {
+	term = strings.TrimSpace(term)
+	pattern := "%" + escapeLike(term) + "%"
+	prefix := escapeLike(term) + "%"
+
+	query := `SELECT type, id, name, diagram_id, diagram_name, matched_field, snippet, status, service_type FROM (
+			SELECT 'diagram' AS type, d.id, d.name, d.id AS diagram_id, d.name AS diagram_name,
+				CASE WHEN d.name ILIKE $1 THEN 'name' ELSE 'description' END AS matched_field,
+				CASE WHEN d.name ILIKE $1 THEN d.name ELSE COALESCE(d.description, '') END AS snippet,
+				'' AS status, '' AS service_type,
+				CASE WHEN lower(d.name) = lower($2) THEN 0 WHEN d.name ILIKE $3 THEN 1 ELSE 2 END AS rank
+			FROM diagrams d
+			WHERE (d.name ILIKE $1 OR d.description ILIKE $1) AND (NOT $4 OR d.public) AND d.deleted_at IS NULL
+				AND d.organization_id = $6
+			UNION ALL
+			SELECT 'service', s.id, s.name, d.id, d.name,
+				CASE WHEN s.name ILIKE $1 THEN 'name' WHEN s.host ILIKE $1 THEN 'host' ELSE 'tags' END,
+				CASE WHEN s.name ILIKE $1 THEN s.name WHEN s.host ILIKE $1 THEN COALESCE(s.host, '') ELSE array_to_string(s.tags, ', ') END,
+				s.current_status, s.service_type,
+				CASE WHEN lower(s.name) = lower($2) OR lower(s.host) = lower($2) THEN 0
+					WHEN s.name ILIKE $3 OR s.host ILIKE $3 THEN 1 ELSE 2 END
+			FROM services s
+			JOIN diagrams d ON d.id = s.diagram_id
+			WHERE (s.name ILIKE $1 OR s.host ILIKE $1 OR EXISTS (SELECT 1 FROM unnest(s.tags) t WHERE t ILIKE $1)) AND (NOT $4 OR d.public) AND s.deleted_at IS NULL AND s.overlay_of IS NULL
+				AND d.organization_id = $6
+		) matches
+		ORDER BY rank, length(name), name, type
+		LIMIT $5`
+	rows, err := r.replica.QueryContext(ctx, query, pattern, term, prefix, publicOnly, limit, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	for rows.Next() {
+		var sr models.SearchResult
+		if err := rows.Scan(&sr.Type, &sr.ID, &sr.Name, &sr.DiagramID, &sr.DiagramName, &sr.MatchedField, &sr.Snippet, &sr.Status, &sr.ServiceType); err != nil {
+			return nil, err
+		}
+		results = append(results, sr)
+	}
+	return results, rows.Err()
+}
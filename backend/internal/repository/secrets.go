@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"service-weaver/internal/models"
+	"service-weaver/internal/secrets"
+)
+
+// sealCredentials encrypts the credentials set on a service for storage. It returns nil when the
+// service carries no new credentials and an empty string when they are removed. Credentials that
+// only reference Vault secrets hold nothing secret and are stored as is while no key is configured.
+func sealCredentials(service *models.Service) (*string, error) {
+	if service.Credentials == nil {
+		return nil, nil
+	}
+	sealed := ""
+	if !service.Credentials.IsZero() {
+		plaintext, err := json.Marshal(service.Credentials)
+		if err != nil {
+			return nil, err
+		}
+		if !secrets.Default.Enabled() && onlyVaultReferences(service.Credentials) {
+			sealed = string(plaintext)
+		} else if sealed, err = secrets.Default.Seal(plaintext); err != nil {
+			return nil, err
+		}
+	}
+	return &sealed, nil
+}
+
+func onlyVaultReferences(credentials *models.ServiceCredentials) bool {
+	for _, value := range credentials.Fields() {
+		if *value != "" && !secrets.IsVaultReference(*value) {
+			return false
+		}
+	}
+	return true
+}
+
+// sealHeaders returns the value stored for the request headers of a service: the sealed JSON of
+// the headers, stored as a JSON string, or the headers as they are while no key is configured
+func sealHeaders(headers models.Headers) (interface{}, error) {
+	if len(headers) == 0 || !secrets.Default.Enabled() {
+		return models.JSON(headers), nil
+	}
+	plaintext, err := json.Marshal(models.JSON(headers))
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := secrets.Default.Seal(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sealed)
+}
+
+// openHeaders decodes stored request headers, opening them when they were sealed
+func openHeaders(stored []byte) (models.Headers, error) {
+	headers := make(models.Headers)
+	plaintext := stored
+	if sealed, ok := sealedHeaders(stored); ok {
+		if !secrets.IsSealed(sealed) {
+			return headers, nil
+		}
+		var err error
+		if plaintext, err = secrets.Default.Open(sealed); err != nil {
+			return headers, fmt.Errorf("failed to decrypt headers: %w", err)
+		}
+	}
+	if len(plaintext) == 0 || string(plaintext) == "null" {
+		return headers, nil
+	}
+	if err := json.Unmarshal(plaintext, &headers); err != nil {
+		return headers, fmt.Errorf("failed to decode headers: %w", err)
+	}
+	return headers, nil
+}
+
+// unmaskHeaders puts the stored values of a service's request headers in place of the masked
+// ones written back by clients that read the service. Masked headers the service does not store
+// are dropped, all of them when serviceID is 0.
+func unmaskHeaders(ctx context.Context, q queryRunner, serviceID int, headers models.Headers) (models.Headers, error) {
+	masked := false
+	for _, value := range headers {
+		if value == models.MaskedHeaderValue {
+			masked = true
+			break
+		}
+	}
+	if !masked {
+		return headers, nil
+	}
+
+	stored := models.Headers{}
+	if serviceID != 0 {
+		var data []byte
+		err := q.QueryRowContext(ctx, `SELECT headers FROM services WHERE id = $1`, serviceID).Scan(&data)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if err == nil {
+			if stored, err = openHeaders(data); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	unmasked := make(models.Headers, len(headers))
+	for key, value := range headers {
+		if value != models.MaskedHeaderValue {
+			unmasked[key] = value
+		} else if storedValue, ok := stored[key]; ok {
+			unmasked[key] = storedValue
+		}
+	}
+	return unmasked, nil
+}
+
+// sealedHeaders returns the sealed value of stored request headers, which is stored as a JSON
+// string; headers stored in plain text are a JSON object
+func sealedHeaders(stored []byte) (string, bool) {
+	var sealed string
+	return sealed, json.Unmarshal(stored, &sealed) == nil
+}
+
+// ReencryptSecrets seals every stored secret that was sealed with a previous key, or not at all,
+// again with the current one, so previous keys can be retired after a rotation: the credentials
+// and request headers of services. It returns how many were updated.
+func (r *Repository) ReencryptSecrets(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if err := withoutStatementTimeout(ctx, tx); err != nil {
+		return 0, err
+	}
+
+	credentials, err := reencryptColumn(ctx, tx, "credentials", `credentials <> ''`, func(stored []byte) (string, bool) {
+		return string(stored), secrets.IsSealed(string(stored))
+	}, func(sealed string) (interface{}, error) {
+		return sealed, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	headers, err := reencryptColumn(ctx, tx, "headers", `jsonb_typeof(headers) IN ('object', 'string') AND headers <> '{}'::jsonb`, sealedHeaders, func(sealed string) (interface{}, error) {
+		return json.Marshal(sealed)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := r.changed(tx.Commit()); err != nil {
+		return 0, err
+	}
+	return credentials + headers, nil
+}
+
+// reencryptColumn seals the values of a services column matching filter again when they were
+// sealed with a previous key or not at all. sealedOf returns the sealed value held by a stored
+// one, if it holds one; stored values that do not are the plaintext. store turns a sealed value
+// into its stored form. Trashed services are included, as they may still be restored.
+func reencryptColumn(ctx context.Context, tx *sql.Tx, column, filter string, sealedOf func([]byte) (string, bool), store func(string) (interface{}, error)) (int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, `+column+` FROM services WHERE `+filter+` FOR UPDATE`)
+	if err != nil {
+		return 0, err
+	}
+	values := make(map[int][]byte)
+	for rows.Next() {
+		var id int
+		var stored []byte
+		if err := rows.Scan(&id, &stored); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		values[id] = stored
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for id, stored := range values {
+		plaintext := stored
+		if sealed, ok := sealedOf(stored); ok {
+			if !secrets.Default.NeedsRotation(sealed) {
+				continue
+			}
+			if plaintext, err = secrets.Default.Open(sealed); err != nil {
+				return 0, err
+			}
+		}
+		resealed, err := secrets.Default.Seal(plaintext)
+		if err != nil {
+			return 0, err
+		}
+		value, err := store(resealed)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE services SET `+column+` = $1 WHERE id = $2`, value, id); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"service-weaver/internal/models"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const securityEventColumns = `id, event_type, user_id, username, actor_id, detail, ip_address, user_agent, created_at`
+
+// CreateSecurityEvent stores a security event
+func (r *Repository) CreateSecurityEvent(ctx context.Context, event *models.SecurityEvent) error {
+	query := `INSERT INTO security_events (event_type, user_id, username, actor_id, detail, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx, query, event.Type, event.UserID, event.Username, event.ActorID, event.Detail, event.IPAddress, event.UserAgent).
+		Scan(&event.ID, &event.CreatedAt)
+}
+
+// SecurityEventFilter narrows down a security event query. Zero values disable the corresponding filter.
+type SecurityEventFilter struct {
+	UserID    int
+	Username  string
+	Types     []string
+	IPAddress string
+	From      time.Time
+	To        time.Time
+	Limit     int
+	Offset    int
+}
+
+// where builds the WHERE clause of a filtered query and its arguments
+func (f SecurityEventFilter) where() (string, []interface{}) {
+	conditions := []string{"TRUE"}
+	var args []interface{}
+
+	if f.UserID != 0 {
+		args = append(args, f.UserID)
+		conditions = append(conditions, fmt.Sprintf("(user_id = $%d OR actor_id = $%d)", len(args), len(args)))
+	}
+	if f.Username != "" {
+		args = append(args, f.Username)
+		conditions = append(conditions, fmt.Sprintf("username = $%d", len(args)))
+	}
+	if len(f.Types) > 0 {
+		args = append(args, pq.Array(f.Types))
+		conditions = append(conditions, fmt.Sprintf("event_type = ANY($%d)", len(args)))
+	}
+	if f.IPAddress != "" {
+		args = append(args, f.IPAddress)
+		conditions = append(conditions, fmt.Sprintf("ip_address = $%d", len(args)))
+	}
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !f.To.IsZero() {
+		args = append(args, f.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// GetSecurityEvents returns a page of security events, newest first, along with the total number of
+// matches. Events of a user include those the user caused for others.
+func (r *Repository) GetSecurityEvents(ctx context.Context, filter SecurityEventFilter) ([]models.SecurityEvent, int, error) {
+	where, args := filter.where()
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM security_events WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`SELECT `+securityEventColumns+` FROM security_events WHERE %s
+		ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
+	events := []models.SecurityEvent{}
+	err := r.streamSecurityEvents(ctx, query, args, func(e models.SecurityEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// StreamSecurityEvents calls fn for every matching security event, oldest first, ignoring the
+// filter's pagination
+func (r *Repository) StreamSecurityEvents(ctx context.Context, filter SecurityEventFilter, fn func(models.SecurityEvent) error) error {
+	where, args := filter.where()
+	query := `SELECT ` + securityEventColumns + ` FROM security_events WHERE ` + where + ` ORDER BY created_at, id`
+	return r.streamSecurityEvents(ctx, query, args, fn)
+}
+
+func (r *Repository) streamSecurityEvents(ctx context.Context, query string, args []interface{}, fn func(models.SecurityEvent) error) error {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.SecurityEvent
+		if err := rows.Scan(&e.ID, &e.Type, &e.UserID, &e.Username, &e.ActorID, &e.Detail, &e.IPAddress, &e.UserAgent, &e.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"service-weaver/internal/models"
+)
+
+// GetSessions lists the active sessions of a user, most recently used first
+func (r *Repository) GetSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	query := `SELECT id, user_id, device, ip_address, user_agent, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY last_seen_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Device, &s.IPAddress, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession ends an active session of a user: its refresh tokens stop working and so do the
+// access tokens issued for it. Unknown, other users' and inactive sessions give sql.ErrNoRows.
+func (r *Repository) RevokeSession(ctx context.Context, userID, sessionID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var familyID string
+	query := `SELECT family_id FROM sessions
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP`
+	if err := tx.QueryRowContext(ctx, query, sessionID, userID).Scan(&familyID); err != nil {
+		return err
+	}
+	if err := revokeFamily(ctx, tx, familyID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
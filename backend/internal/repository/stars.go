@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+)
+
+// maxRecentDiagrams bounds the recently viewed diagrams remembered per user
+const maxRecentDiagrams = 50
+
+// StarDiagram adds a diagram to the favorites of a user; starring it again changes nothing
+func (r *Repository) StarDiagram(ctx context.Context, userID, diagramID int) error {
+	query := `INSERT INTO diagram_stars (user_id, diagram_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	_, err := r.db.ExecContext(ctx, query, userID, diagramID)
+	return err
+}
+
+// UnstarDiagram removes a diagram from the favorites of a user
+func (r *Repository) UnstarDiagram(ctx context.Context, userID, diagramID int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM diagram_stars WHERE user_id = $1 AND diagram_id = $2`, userID, diagramID)
+	return err
+}
+
+// RecordDiagramView remembers that a user opened a diagram, forgetting the views beyond the
+// latest maxRecentDiagrams
+func (r *Repository) RecordDiagramView(ctx context.Context, userID, diagramID int) error {
+	query := `INSERT INTO diagram_views (user_id, diagram_id) VALUES ($1, $2)
+		ON CONFLICT (user_id, diagram_id) DO UPDATE SET viewed_at = CURRENT_TIMESTAMP`
+	if _, err := r.db.ExecContext(ctx, query, userID, diagramID); err != nil {
+		return err
+	}
+	query = `DELETE FROM diagram_views WHERE user_id = $1 AND diagram_id NOT IN (
+		SELECT diagram_id FROM diagram_views WHERE user_id = $1 ORDER BY viewed_at DESC LIMIT $2)`
+	_, err := r.db.ExecContext(ctx, query, userID, maxRecentDiagrams)
+	return err
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"service-weaver/internal/models"
+	"strings"
+	"time"
+)
+
+// RecordStatusTransition appends a status event when to differs from the service's last recorded
+// status. from is only used for a service's first event. It reports whether an event was written.
+func (r *Repository) RecordStatusTransition(ctx context.Context, serviceID int, from, to models.ServiceStatus, resultID int) (bool, error) {
+	query := `INSERT INTO status_events (service_id, from_status, to_status, result_id)
+		SELECT $1, prev.status, $3, NULLIF($4, 0)
+		FROM (SELECT COALESCE(
+			(SELECT to_status FROM status_events WHERE service_id = $1 ORDER BY occurred_at DESC, id DESC LIMIT 1),
+			$2::VARCHAR) AS status) prev
+		WHERE prev.status <> $3`
+	res, err := r.db.ExecContext(ctx, query, serviceID, from, to, resultID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// EventFilter narrows down a status event query. Zero values disable the corresponding filter.
+type EventFilter struct {
+	ServiceID   int
+	DiagramID   int
+	Environment string // Environment of the service, or of its diagram for services without one
+	From        time.Time
+	To          time.Time
+	Limit       int
+	Offset      int
+}
+
+// GetStatusEvents returns a page of status events, newest first, along with the total number of matches.
+// Each event carries the time the status ended, taken from the service's following event.
+func (r *Repository) GetStatusEvents(ctx context.Context, filter EventFilter) ([]models.StatusEvent, int, error) {
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+
+	if filter.ServiceID != 0 {
+		args = append(args, filter.ServiceID)
+		conditions = append(conditions, fmt.Sprintf("e.service_id = $%d", len(args)))
+	}
+	if filter.DiagramID != 0 {
+		args = append(args, filter.DiagramID)
+		conditions = append(conditions, fmt.Sprintf("e.service_id IN (SELECT id FROM services WHERE diagram_id = $%d)", len(args)))
+	}
+	if filter.Environment != "" {
+		args = append(args, filter.Environment)
+		conditions = append(conditions, "e.service_id IN "+environmentServices(len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("e.occurred_at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("e.occurred_at < $%d", len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.replica.QueryRowContext(ctx, `SELECT COUNT(*) FROM status_events e WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`SELECT id, service_id, from_status, to_status, result_id, occurred_at, ended_at,
+			EXTRACT(EPOCH FROM (COALESCE(ended_at, LOCALTIMESTAMP) - occurred_at))
+		FROM (
+			SELECT e.*, (SELECT n.occurred_at FROM status_events n
+				WHERE n.service_id = e.service_id AND (n.occurred_at, n.id) > (e.occurred_at, e.id)
+				ORDER BY n.occurred_at, n.id LIMIT 1) AS ended_at
+			FROM status_events e WHERE %s ORDER BY e.occurred_at DESC, e.id DESC LIMIT $%d OFFSET $%d
+		) page ORDER BY occurred_at DESC, id DESC`, where, len(args)-1, len(args))
+	rows, err := r.replica.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []models.StatusEvent{}
+	for rows.Next() {
+		var e models.StatusEvent
+		if err := rows.Scan(&e.ID, &e.ServiceID, &e.FromStatus, &e.ToStatus, &e.ResultID, &e.OccurredAt, &e.EndedAt, &e.DurationSeconds); err != nil {
+			return nil, 0, err
+		}
+		events = append(events, e)
+	}
+	return events, total, nil
+}
+
+// GetStatusTimeline returns the status a service held at from (empty when no earlier event exists)
+// followed by its status events in [from, to), oldest first
+func (r *Repository) GetStatusTimeline(ctx context.Context, serviceID int, from, to time.Time) (models.ServiceStatus, []models.StatusEvent, error) {
+	var initial models.ServiceStatus
+	query := `SELECT to_status FROM status_events WHERE service_id = $1 AND occurred_at < $2 ORDER BY occurred_at DESC, id DESC LIMIT 1`
+	err := r.replica.QueryRowContext(ctx, query, serviceID, from).Scan(&initial)
+	if err != nil && err != sql.ErrNoRows {
+		return "", nil, err
+	}
+
+	query = `SELECT id, service_id, from_status, to_status, result_id, occurred_at FROM status_events
+		WHERE service_id = $1 AND occurred_at >= $2 AND occurred_at < $3 ORDER BY occurred_at, id`
+	rows, err := r.replica.QueryContext(ctx, query, serviceID, from, to)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+
+	var events []models.StatusEvent
+	for rows.Next() {
+		var e models.StatusEvent
+		if err := rows.Scan(&e.ID, &e.ServiceID, &e.FromStatus, &e.ToStatus, &e.ResultID, &e.OccurredAt); err != nil {
+			return "", nil, err
+		}
+		events = append(events, e)
+	}
+	return initial, events, rows.Err()
+}
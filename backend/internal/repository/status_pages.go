@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"service-weaver/internal/models"
+)
+
+// Status page operations
+
+// UpdateStatusPage stores the public status page settings of a diagram. An empty slug is stored as NULL
+// so that several diagrams without a page do not collide on the unique constraint.
+func (r *Repository) UpdateStatusPage(ctx context.Context, diagramID int, settings models.StatusPageSettings) error {
+	var slug *string
+	if settings.Slug != "" {
+		slug = &settings.Slug
+	}
+	query := `UPDATE diagrams SET status_page_enabled = $1, status_page_slug = $2, status_page_title = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4`
+	_, err := r.db.ExecContext(ctx, query, settings.Enabled, slug, settings.Title, diagramID)
+	return err
+}
+
+// GetDiagramByStatusPageSlug returns the diagram published under the given slug, only if its status page is enabled
+func (r *Repository) GetDiagramByStatusPageSlug(ctx context.Context, slug string) (*models.Diagram, error) {
+	query := `SELECT ` + diagramColumns + ` FROM diagrams WHERE status_page_slug = $1 AND status_page_enabled = TRUE AND deleted_at IS NULL`
+	var d models.Diagram
+	err := scanDiagram(r.db.QueryRowContext(ctx, query, slug), &d)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Incident operations
+func (r *Repository) CreateIncident(ctx context.Context, incident *models.Incident) error {
+	query := `INSERT INTO incidents (diagram_id, title, message, status, impact, started_at, resolved_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`
+	return r.db.QueryRowContext(ctx, query, incident.DiagramID, incident.Title, incident.Message, incident.Status, incident.Impact, incident.StartedAt, incident.ResolvedAt).Scan(&incident.ID, &incident.CreatedAt, &incident.UpdatedAt)
+}
+
+// GetIncidents returns the incidents of a diagram, newest first. When activeOnly is set resolved incidents are skipped.
+func (r *Repository) GetIncidents(ctx context.Context, diagramID int, activeOnly bool) ([]models.Incident, error) {
+	query := `SELECT id, diagram_id, title, COALESCE(message, ''), status, impact, started_at, resolved_at, created_at, updated_at
+		FROM incidents WHERE diagram_id = $1 AND (NOT $2 OR status <> 'resolved') ORDER BY started_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, diagramID, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incidents []models.Incident
+	for rows.Next() {
+		var i models.Incident
+		err := rows.Scan(&i.ID, &i.DiagramID, &i.Title, &i.Message, &i.Status, &i.Impact, &i.StartedAt, &i.ResolvedAt, &i.CreatedAt, &i.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, i)
+	}
+	return incidents, nil
+}
+
+func (r *Repository) GetIncident(ctx context.Context, id int) (*models.Incident, error) {
+	query := `SELECT id, diagram_id, title, COALESCE(message, ''), status, impact, started_at, resolved_at, created_at, updated_at FROM incidents WHERE id = $1`
+	var i models.Incident
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&i.ID, &i.DiagramID, &i.Title, &i.Message, &i.Status, &i.Impact, &i.StartedAt, &i.ResolvedAt, &i.CreatedAt, &i.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *Repository) UpdateIncident(ctx context.Context, incident *models.Incident) error {
+	query := `UPDATE incidents SET title = $1, message = $2, status = $3, impact = $4, started_at = $5, resolved_at = $6, updated_at = CURRENT_TIMESTAMP WHERE id = $7`
+	_, err := r.db.ExecContext(ctx, query, incident.Title, incident.Message, incident.Status, incident.Impact, incident.StartedAt, incident.ResolvedAt, incident.ID)
+	return err
+}
+
+func (r *Repository) DeleteIncident(ctx context.Context, id int) error {
+	query := `DELETE FROM incidents WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"service-weaver/internal/models"
+)
+
+// ErrUnknownTagAction is returned by ApplyTagAction for actions other than those of models.TagAction*
+var ErrUnknownTagAction = errors.New("unknown tag action")
+
+// ListTags returns the tags of the organization's services starting with prefix, compared
+// case-insensitively, with the number of services carrying each; the most used come first. Only
+// the services of public diagrams count when publicOnly is set.
+func (r *Repository) ListTags(ctx context.Context, orgID int, prefix string, publicOnly bool, limit int) ([]models.TagCount, error) {
+	query := `SELECT t.tag, COUNT(*) FROM services s
+			JOIN diagrams d ON d.id = s.diagram_id
+			CROSS JOIN unnest(s.tags) AS t(tag)
+		WHERE d.organization_id = $1 AND s.deleted_at IS NULL AND s.overlay_of IS NULL AND d.deleted_at IS NULL AND (NOT $3 OR d.public)
+			AND t.tag ILIKE $2
+		GROUP BY t.tag
+		ORDER BY COUNT(*) DESC, t.tag
+		LIMIT $4`
+	rows, err := r.replica.QueryContext(ctx, query, orgID, escapeLike(prefix)+"%", publicOnly, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.TagCount{}
+	for rows.Next() {
+		var tc models.TagCount
+		if err := rows.Scan(&tc.Tag, &tc.Services); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tc)
+	}
+	return tags, rows.Err()
+}
+
+// ApplyTagAction pauses, resumes or sets the polling interval of every service of the organization
+// carrying tag, only of public diagrams when publicOnly is set, and returns the services it
+// changed. A polling interval set this way is added to the overrides of the services, so it holds
+// against the defaults of their diagrams.
+func (r *Repository) ApplyTagAction(ctx context.Context, orgID int, tag string, publicOnly bool, action models.TagActionRequest) ([]models.Service, error) {
+	var set string
+	args := []interface{}{orgID, tag, publicOnly}
+	switch action.Action {
+	case models.TagActionPause:
+		set = `paused = TRUE`
+	case models.TagActionResume:
+		set = `paused = FALSE`
+	case models.TagActionSetInterval:
+		set = `polling_interval = $4, overrides = CASE WHEN 'polling_interval' = ANY(overrides) THEN overrides ELSE array_append(overrides, 'polling_interval') END`
+		args = append(args, action.PollingInterval)
+	default:
+		return nil, ErrUnknownTagAction
+	}
+
+	query := `UPDATE services SET ` + set + `, updated_at = CURRENT_TIMESTAMP
+		WHERE tags @> ARRAY[$2]::text[] AND deleted_at IS NULL
+			AND diagram_id IN (SELECT id FROM diagrams WHERE organization_id = $1 AND (NOT $3 OR public))
+		RETURNING ` + serviceColumns
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	services := []models.Service{}
+	for rows.Next() {
+		var s models.Service
+		if err := scanService(rows, &s); err != nil {
+			return nil, err
+		}
+		services = append(services, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return services, r.changed(nil)
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// GetDiagramThumbnail returns the cached thumbnail of a diagram in a format when it was rendered
+// from the layout with the hash, or nil when it needs rendering again
+func (r *Repository) GetDiagramThumbnail(ctx context.Context, diagramID int, format, layoutHash string) ([]byte, error) {
+	query := `SELECT data FROM diagram_thumbnails WHERE diagram_id = $1 AND format = $2 AND layout_hash = $3`
+	var data []byte
+	err := r.db.QueryRowContext(ctx, query, diagramID, format, layoutHash).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// SetDiagramThumbnail caches the thumbnail of a diagram in a format, replacing the one rendered
+// from an earlier layout
+func (r *Repository) SetDiagramThumbnail(ctx context.Context, diagramID int, format, layoutHash string, data []byte) error {
+	query := `INSERT INTO diagram_thumbnails (diagram_id, format, layout_hash, data) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (diagram_id, format) DO UPDATE SET layout_hash = EXCLUDED.layout_hash, data = EXCLUDED.data,
+			rendered_at = CURRENT_TIMESTAMP`
+	_, err := r.db.ExecContext(ctx, query, diagramID, format, layoutHash, data)
+	return err
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"service-weaver/internal/models"
+	"time"
+)
+
+// ErrDiagramInTrash is returned when restoring a service whose diagram is itself in the trash
+var ErrDiagramInTrash = errors.New("the service's diagram is in the trash, restore it first")
+
+// Trash operations
+
+// GetTrash lists an organization's trashed diagrams and the services trashed on their own, most
+// recently deleted first. Services that went to the trash with their diagram are restored with it
+// and not listed separately.
+func (r *Repository) GetTrash(ctx context.Context, orgID int) (*models.Trash, error) {
+	trash := &models.Trash{Diagrams: []models.Diagram{}, Services: []models.Service{}}
+
+	query := `SELECT ` + diagramColumns + ` FROM diagrams WHERE deleted_at IS NOT NULL AND organization_id = $1 ORDER BY deleted_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d models.Diagram
+		if err := scanDiagram(rows, &d); err != nil {
+			return nil, err
+		}
+		trash.Diagrams = append(trash.Diagrams, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `SELECT ` + serviceColumns + ` FROM services WHERE deleted_at IS NOT NULL AND overlay_of IS NULL
+		AND diagram_id IN (SELECT id FROM diagrams WHERE deleted_at IS NULL AND organization_id = $1) ORDER BY deleted_at DESC`
+	serviceRows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer serviceRows.Close()
+	for serviceRows.Next() {
+		var s models.Service
+		if err := scanService(serviceRows, &s); err != nil {
+			return nil, err
+		}
+		trash.Services = append(trash.Services, s)
+	}
+	return trash, serviceRows.Err()
+}
+
+// RestoreDiagram takes a diagram out of the trash along with the services deleted with it.
+// It returns sql.ErrNoRows when the diagram is not in the trash.
+func (r *Repository) RestoreDiagram(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	query := `SELECT deleted_at FROM diagrams WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&deletedAt); err != nil {
+		return err
+	}
+
+	query = `UPDATE diagrams SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, id); err != nil {
+		return err
+	}
+
+	query = `UPDATE services SET deleted_at = NULL WHERE diagram_id = $1 AND deleted_at = $2`
+	if _, err := tx.ExecContext(ctx, query, id, deletedAt); err != nil {
+		return err
+	}
+	return r.changed(tx.Commit())
+}
+
+// RestoreService takes a service out of the trash. It returns sql.ErrNoRows when the service is
+// not in the trash and ErrDiagramInTrash when its diagram has to be restored instead.
+func (r *Repository) RestoreService(ctx context.Context, id int) error {
+	var diagramDeleted bool
+	query := `SELECT d.deleted_at IS NOT NULL FROM services s JOIN diagrams d ON d.id = s.diagram_id
+		WHERE s.id = $1 AND s.deleted_at IS NOT NULL`
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&diagramDeleted); err != nil {
+		return err
+	}
+	if diagramDeleted {
+		return ErrDiagramInTrash
+	}
+
+	return r.changed(r.WithTx(ctx, func(tx *sql.Tx) error {
+		query := `UPDATE services SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NOT NULL AND overlay_of IS NULL`
+		res, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return sql.ErrNoRows
+		}
+		return syncOverlays(ctx, tx, []int{id})
+	}))
+}
+
+// PurgeTrash permanently deletes diagrams and services that have been in the trash longer than
+// retentionDays, along with their results and connections. It returns the number of rows removed.
+func (r *Repository) PurgeTrash(ctx context.Context, retentionDays int) (int64, error) {
+	var total int64
+	for _, query := range []string{
+		`DELETE FROM diagrams WHERE deleted_at < CURRENT_TIMESTAMP - make_interval(days => $1)`,
+		`DELETE FROM services WHERE deleted_at < CURRENT_TIMESTAMP - make_interval(days => $1)`,
+	} {
+		res, err := r.db.ExecContext(ctx, query, retentionDays)
+		if err != nil {
+			return total, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// TrashPurger periodically empties the trash of entries older than the retention period
+type TrashPurger struct {
+	repo          *Repository
+	retentionDays int
+	interval      time.Duration
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+func NewTrashPurger(repo *Repository, retentionDays int, interval time.Duration) *TrashPurger {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TrashPurger{
+		repo:          repo,
+		retentionDays: retentionDays,
+		interval:      interval,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+func (p *TrashPurger) Start() {
+	go p.run()
+}
+
+func (p *TrashPurger) Stop() {
+	p.cancel()
+}
+
+func (p *TrashPurger) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			purged, err := p.repo.PurgeTrash(p.ctx, p.retentionDays)
+			if err != nil {
+				log.Printf("Error purging trash: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Purged %d diagrams and services from the trash", purged)
+			}
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
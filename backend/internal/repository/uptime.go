@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"service-weaver/internal/models"
+	"time"
+)
+
+// Maintenance window operations
+func (r *Repository) CreateMaintenanceWindow(ctx context.Context, window *models.MaintenanceWindow) error {
+	query := `INSERT INTO maintenance_windows (service_id, diagram_id, reason, starts_at, ends_at) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx, query, window.ServiceID, window.DiagramID, window.Reason, window.StartsAt, window.EndsAt).Scan(&window.ID, &window.CreatedAt)
+}
+
+// GetMaintenanceWindows returns an organization's maintenance windows, optionally restricted to a
+// service or diagram (0 means no filter)
+func (r *Repository) GetMaintenanceWindows(ctx context.Context, orgID, serviceID, diagramID int) ([]models.MaintenanceWindow, error) {
+	query := `SELECT w.id, w.service_id, w.diagram_id, COALESCE(w.reason, ''), w.starts_at, w.ends_at, w.created_at
+		FROM maintenance_windows w
+		JOIN diagrams d ON d.id = COALESCE(w.diagram_id, (SELECT diagram_id FROM services WHERE id = w.service_id))
+		WHERE d.organization_id = $3 AND ($1 = 0 OR w.service_id = $1) AND ($2 = 0 OR w.diagram_id = $2)
+		ORDER BY w.starts_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, serviceID, diagramID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []models.MaintenanceWindow
+	for rows.Next() {
+		var w models.MaintenanceWindow
+		err := rows.Scan(&w.ID, &w.ServiceID, &w.DiagramID, &w.Reason, &w.StartsAt, &w.EndsAt, &w.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func (r *Repository) DeleteMaintenanceWindow(ctx context.Context, id int) error {
+	query := `DELETE FROM maintenance_windows WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// Uptime operations
+
+// uptimeCountsQuery counts up (alive/degraded) and down (dead) results per service, skipping
+// results that fall inside a maintenance window for the service or its diagram.
+const uptimeCountsQuery = `SELECT s.id, s.name, COALESCE(s.slo_target, 0),
+		COUNT(r.id) FILTER (WHERE r.status IN ('alive', 'degraded')),
+		COUNT(r.id) FILTER (WHERE r.status = 'dead')
+	FROM services s
+	LEFT JOIN healthcheck_results r ON r.service_id = s.id
+		AND r.checked_at >= $2 AND r.checked_at < $3
+		AND NOT EXISTS (
+			SELECT 1 FROM maintenance_windows m
+			WHERE (m.service_id = s.id OR m.diagram_id = s.diagram_id)
+				AND r.checked_at >= m.starts_at AND r.checked_at < m.ends_at
+		)
+	WHERE %s AND s.deleted_at IS NULL
+	GROUP BY s.id, s.name, s.slo_target
+	ORDER BY s.id`
+
+// GetServiceUptime returns the raw up/down check counts for a service in [from, to)
+func (r *Repository) GetServiceUptime(ctx context.Context, serviceID int, from, to time.Time) (*models.UptimeSummary, error) {
+	summaries, err := r.queryUptime(ctx, "s.id = $1", serviceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &summaries[0], nil
+}
+
+// GetDiagramUptime returns the raw up/down check counts for every node in a diagram in [from, to);
+// environment overlays are left out
+func (r *Repository) GetDiagramUptime(ctx context.Context, diagramID int, from, to time.Time) ([]models.UptimeSummary, error) {
+	return r.queryUptime(ctx, "s.diagram_id = $1 AND s.overlay_of IS NULL", diagramID, from, to)
+}
+
+// queryUptime counts the results of the services matching condition, which compares to id as $1
+func (r *Repository) queryUptime(ctx context.Context, condition string, id int, from, to time.Time) ([]models.UptimeSummary, error) {
+	rows, err := r.replica.QueryContext(ctx, fmt.Sprintf(uptimeCountsQuery, condition), id, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []models.UptimeSummary
+	for rows.Next() {
+		u := models.UptimeSummary{From: from, To: to}
+		if err := rows.Scan(&u.ServiceID, &u.ServiceName, &u.SLOTarget, &u.UpChecks, &u.DownChecks); err != nil {
+			return nil, err
+		}
+		u.TotalChecks = u.UpChecks + u.DownChecks
+		summaries = append(summaries, u)
+	}
+	return summaries, nil
+}
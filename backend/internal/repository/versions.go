@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"service-weaver/internal/models"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const maxVersionSummaryLength = 255
+
+// RecordDiagramVersion snapshots the current structure of a diagram. No version is recorded (and nil is
+// returned) when the structure matches the latest version; position-only changes don't count as structural.
+func (r *Repository) RecordDiagramVersion(ctx context.Context, diagramID int, userID *int, summary string) (*models.DiagramVersion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	version, err := recordDiagramVersion(ctx, tx, diagramID, userID, summary)
+	if err != nil {
+		return nil, err
+	}
+	return version, tx.Commit()
+}
+
+func recordDiagramVersion(ctx context.Context, q queryRunner, diagramID int, userID *int, summary string) (*models.DiagramVersion, error) {
+	snapshot, err := loadDiagramSnapshot(ctx, q, diagramID)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := structureHash(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastVersion int
+	var lastHash string
+	query := `SELECT version, structure_hash FROM diagram_versions WHERE diagram_id = $1 ORDER BY version DESC LIMIT 1`
+	err = q.QueryRowContext(ctx, query, diagramID).Scan(&lastVersion, &lastHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if lastHash == hash {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if runes := []rune(summary); len(runes) > maxVersionSummaryLength {
+		summary = string(runes[:maxVersionSummaryLength])
+	}
+
+	version := &models.DiagramVersion{
+		DiagramID:       diagramID,
+		Version:         lastVersion + 1,
+		Summary:         summary,
+		ServiceCount:    len(snapshot.Services),
+		ConnectionCount: len(snapshot.Connections),
+		CreatedBy:       userID,
+	}
+	query = `INSERT INTO diagram_versions (diagram_id, version, summary, snapshot, structure_hash, created_by) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
+	err = q.QueryRowContext(ctx, query, diagramID, version.Version, summary, data, hash, userID).Scan(&version.ID, &version.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// loadDiagramSnapshot reads a diagram's structure, locking the diagram row so concurrent versions are numbered in order
+func loadDiagramSnapshot(ctx context.Context, q queryRunner, diagramID int) (*models.DiagramSnapshot, error) {
+	snapshot := &models.DiagramSnapshot{Services: []models.Service{}, Connections: []models.Connection{}}
+	query := `SELECT name, COALESCE(description, '') FROM diagrams WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	if err := q.QueryRowContext(ctx, query, diagramID).Scan(&snapshot.Name, &snapshot.Description); err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT `+serviceColumns+` FROM services WHERE diagram_id = $1 AND deleted_at IS NULL AND overlay_of IS NULL ORDER BY id`, diagramID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s models.Service
+		if err := scanService(rows, &s); err != nil {
+			return nil, err
+		}
+		// Runtime state is not part of the structure
+		s.CurrentStatus = ""
+		s.LastChecked = nil
+		s.DiscoveryDeregisteredAt = nil
+		s.DeletedAt = nil
+		s.CreatedAt = time.Time{}
+		s.UpdatedAt = time.Time{}
+		snapshot.Services = append(snapshot.Services, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	connections, err := getConnections(ctx, q, diagramID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(connections, func(i, j int) bool { return connections[i].ID < connections[j].ID })
+	for _, c := range connections {
+		c.Status = ""
+		c.CreatedAt = time.Time{}
+		snapshot.Connections = append(snapshot.Connections, c)
+	}
+	return snapshot, nil
+}
+
+// structureHash fingerprints a snapshot ignoring node positions
+func structureHash(snapshot *models.DiagramSnapshot) (string, error) {
+	structure := *snapshot
+	structure.Services = make([]models.Service, len(snapshot.Services))
+	for i, s := range snapshot.Services {
+		s.PositionX, s.PositionY = 0, 0
+		structure.Services[i] = s
+	}
+
+	data, err := json.Marshal(structure)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+const diagramVersionColumns = `id, diagram_id, version, summary,
+	jsonb_array_length(COALESCE(snapshot->'services', '[]'::jsonb)), jsonb_array_length(COALESCE(snapshot->'connections', '[]'::jsonb)),
+	created_by, created_at`
+
+// GetDiagramVersions lists the versions of a diagram, newest first, without their snapshots
+func (r *Repository) GetDiagramVersions(ctx context.Context, diagramID int) ([]models.DiagramVersion, error) {
+	query := `SELECT ` + diagramVersionColumns + ` FROM diagram_versions WHERE diagram_id = $1 ORDER BY version DESC`
+	rows, err := r.db.QueryContext(ctx, query, diagramID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []models.DiagramVersion{}
+	for rows.Next() {
+		var v models.DiagramVersion
+		if err := rows.Scan(&v.ID, &v.DiagramID, &v.Version, &v.Summary, &v.ServiceCount, &v.ConnectionCount, &v.CreatedBy, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// GetDiagramVersion returns a single version including its snapshot
+func (r *Repository) GetDiagramVersion(ctx context.Context, diagramID, version int) (*models.DiagramVersion, error) {
+	return getDiagramVersion(ctx, r.db, diagramID, version)
+}
+
+func getDiagramVersion(ctx context.Context, q queryRunner, diagramID, version int) (*models.DiagramVersion, error) {
+	query := `SELECT ` + diagramVersionColumns + `, snapshot FROM diagram_versions WHERE diagram_id = $1 AND version = $2`
+	var v models.DiagramVersion
+	var data []byte
+	err := q.QueryRowContext(ctx, query, diagramID, version).Scan(&v.ID, &v.DiagramID, &v.Version, &v.Summary, &v.ServiceCount, &v.ConnectionCount, &v.CreatedBy, &v.CreatedAt, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	v.Snapshot = &models.DiagramSnapshot{}
+	if err := json.Unmarshal(data, v.Snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot for version %d: %w", version, err)
+	}
+	return &v, nil
+}
+
+// RollbackDiagram restores a diagram to the structure of a previous version and records the result as a new version.
+// Services that still exist are updated in place so their history is kept; deleted services are recreated with new IDs.
+func (r *Repository) RollbackDiagram(ctx context.Context, diagramID, version int, userID *int) (*models.DiagramVersion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the diagram before reading anything so the rollback can't interleave with another version
+	if _, err := loadDiagramSnapshot(ctx, tx, diagramID); err != nil {
+		return nil, err
+	}
+	target, err := getDiagramVersion(ctx, tx, diagramID, version)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := target.Snapshot
+
+	current := make(map[int]bool)
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM services WHERE diagram_id = $1 AND overlay_of IS NULL`, diagramID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		current[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM connections WHERE diagram_id = $1`, diagramID); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE diagrams SET name = $1, description = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, query, snapshot.Name, snapshot.Description, diagramID); err != nil {
+		return nil, err
+	}
+
+	// Maps service IDs in the snapshot to their IDs after the rollback
+	ids := make(map[int]int, len(snapshot.Services))
+	kept := []int{}
+	for _, s := range snapshot.Services {
+		oldID := s.ID
+		s.DiagramID = diagramID
+		if current[oldID] {
+			// Services trashed since the version was taken come back with it
+			if _, err := tx.ExecContext(ctx, `UPDATE services SET deleted_at = NULL WHERE id = $1`, oldID); err != nil {
+				return nil, err
+			}
+			if _, err := updateService(ctx, tx, &s); err != nil {
+				return nil, err
+			}
+		} else {
+			if err := createService(ctx, tx, &s); err != nil {
+				return nil, err
+			}
+			if s.DiscoverySourceID != nil {
+				query := `UPDATE services SET discovery_source_id = $1, discovery_key = $2 WHERE id = $3`
+				if _, err := tx.ExecContext(ctx, query, *s.DiscoverySourceID, s.DiscoveryKey, s.ID); err != nil {
+					return nil, err
+				}
+			}
+		}
+		ids[oldID] = s.ID
+		kept = append(kept, s.ID)
+	}
+
+	query = `UPDATE services SET deleted_at = CURRENT_TIMESTAMP WHERE diagram_id = $1 AND deleted_at IS NULL AND overlay_of IS NULL AND NOT (id = ANY($2))`
+	if _, err := tx.ExecContext(ctx, query, diagramID, pq.Array(kept)); err != nil {
+		return nil, err
+	}
+	// Overlays follow their services back, or into the trash
+	if err := syncOverlays(ctx, tx, slices.Collect(maps.Keys(current))); err != nil {
+		return nil, err
+	}
+
+	for _, c := range snapshot.Connections {
+		c.DiagramID = diagramID
+		c.SourceID, c.TargetID = ids[c.SourceID], ids[c.TargetID]
+		if c.SourceID == 0 || c.TargetID == 0 {
+			continue
+		}
+		if err := createConnection(ctx, tx, &c); err != nil {
+			return nil, err
+		}
+	}
+
+	restored, err := recordDiagramVersion(ctx, tx, diagramID, userID, fmt.Sprintf("Rolled back to version %d", version))
+	if err != nil {
+		return nil, err
+	}
+	return restored, r.changed(tx.Commit())
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"service-weaver/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const webhookColumns = `id, name, url, secret, events, diagram_id, enabled, last_status_code, last_error, last_delivered_at, created_at, updated_at`
+
+// Webhook operations
+func (r *Repository) CreateWebhook(ctx context.Context, hook *models.Webhook) error {
+	query := `INSERT INTO webhooks (name, url, secret, events, diagram_id, enabled) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`
+	if err := r.db.QueryRowContext(ctx, query, hook.Name, hook.URL, hook.Secret, pq.Array(hook.Events), hook.DiagramID, hook.Enabled).Scan(&hook.ID, &hook.CreatedAt, &hook.UpdatedAt); err != nil {
+		return err
+	}
+	hook.HasSecret = hook.Secret != ""
+	return nil
+}
+
+func (r *Repository) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks ORDER BY name`
+	return r.queryWebhooks(ctx, query)
+}
+
+// GetEnabledWebhooks returns the webhooks change events should be delivered to
+func (r *Repository) GetEnabledWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE enabled = TRUE ORDER BY id`
+	return r.queryWebhooks(ctx, query)
+}
+
+func (r *Repository) GetWebhook(ctx context.Context, id int) (*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+	var hook models.Webhook
+	if err := scanWebhook(r.db.QueryRowContext(ctx, query, id), &hook); err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// UpdateWebhook saves a webhook. An empty secret keeps the stored one.
+func (r *Repository) UpdateWebhook(ctx context.Context, hook *models.Webhook) error {
+	query := `UPDATE webhooks SET name = $1, url = $2, secret = CASE WHEN $3::text = '' THEN secret ELSE $3::text END, events = $4, diagram_id = $5, enabled = $6, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $7 RETURNING secret <> '', created_at, updated_at`
+	return r.db.QueryRowContext(ctx, query, hook.Name, hook.URL, hook.Secret, pq.Array(hook.Events), hook.DiagramID, hook.Enabled, hook.ID).Scan(&hook.HasSecret, &hook.CreatedAt, &hook.UpdatedAt)
+}
+
+// RecordWebhookDelivery stores the outcome of the latest delivery attempt
+func (r *Repository) RecordWebhookDelivery(ctx context.Context, id, statusCode int, deliveryErr string, at time.Time) error {
+	query := `UPDATE webhooks SET last_status_code = $1, last_error = $2, last_delivered_at = $3 WHERE id = $4`
+	_, err := r.db.ExecContext(ctx, query, statusCode, deliveryErr, at, id)
+	return err
+}
+
+func (r *Repository) DeleteWebhook(ctx context.Context, id int) error {
+	query := `DELETE FROM webhooks WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *Repository) queryWebhooks(ctx context.Context, query string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []models.Webhook{}
+	for rows.Next() {
+		var hook models.Webhook
+		if err := scanWebhook(rows, &hook); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func scanWebhook(row rowScanner, hook *models.Webhook) error {
+	if err := row.Scan(&hook.ID, &hook.Name, &hook.URL, &hook.Secret, pq.Array(&hook.Events), &hook.DiagramID, &hook.Enabled, &hook.LastStatusCode, &hook.LastError, &hook.LastDeliveredAt, &hook.CreatedAt, &hook.UpdatedAt); err != nil {
+		return err
+	}
+	hook.HasSecret = hook.Secret != ""
+	return nil
+}
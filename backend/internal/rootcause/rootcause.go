@@ -0,0 +1,177 @@
+// Package rootcause proposes which of several services that failed together caused the others to
+// fail, from the dependencies the connections of their diagram describe and the order they failed in
+package rootcause
+
+import (
+	"fmt"
+	"math"
+	"service-weaver/internal/models"
+	"slices"
+	"time"
+)
+
+// Weights of what makes a failed service the probable root cause, adding up to 1
+const (
+	independentWeight = 0.4 // it calls no other failed service
+	impactWeight      = 0.4 // share of the other failed services calling it, directly or not
+	orderWeight       = 0.2 // how early it failed
+)
+
+// Failure is a service going dead or degraded
+type Failure struct {
+	ServiceID int
+	Status    models.ServiceStatus
+	At        time.Time
+}
+
+// Dependencies maps every service to the services it calls along the connections of a diagram.
+// Undirected connections say nothing of which end depends on the other and are left out.
+func Dependencies(connections []models.Connection) map[int][]int {
+	deps := make(map[int][]int)
+	for _, c := range connections {
+		switch c.Direction {
+		case "", models.DirectionForward:
+			deps[c.SourceID] = append(deps[c.SourceID], c.TargetID)
+		case models.DirectionBackward:
+			deps[c.TargetID] = append(deps[c.TargetID], c.SourceID)
+		case models.DirectionBidirectional:
+			deps[c.SourceID] = append(deps[c.SourceID], c.TargetID)
+			deps[c.TargetID] = append(deps[c.TargetID], c.SourceID)
+		}
+	}
+	return deps
+}
+
+// Analyze ranks the failed services, most probable root cause first. A service is more likely the
+// root cause when it calls no other failed service, when many of the failed services call it,
+// directly or through other failed services, and when it failed early. Only the first failure of
+// each service counts; failures of services missing from services are ignored.
+func Analyze(services []models.Service, connections []models.Connection, failures []Failure) []models.RootCauseCandidate {
+	names := make(map[int]string, len(services))
+	for _, s := range services {
+		names[s.ID] = s.Name
+	}
+	first := make(map[int]Failure)
+	for _, f := range failures {
+		if _, ok := names[f.ServiceID]; !ok {
+			continue
+		}
+		if previous, ok := first[f.ServiceID]; !ok || f.At.Before(previous.At) {
+			first[f.ServiceID] = f
+		}
+	}
+	if len(first) == 0 {
+		return []models.RootCauseCandidate{}
+	}
+
+	// Dependents among the failed services, the reverse of their dependencies
+	deps := Dependencies(connections)
+	dependents := make(map[int][]int)
+	for id := range first {
+		for _, dep := range deps[id] {
+			if _, failed := first[dep]; failed && dep != id {
+				dependents[dep] = append(dependents[dep], id)
+			}
+		}
+	}
+
+	earliest, latest := timeRange(first)
+	candidates := make([]models.RootCauseCandidate, 0, len(first))
+	for id, f := range first {
+		candidate := models.RootCauseCandidate{
+			ServiceID:   id,
+			ServiceName: names[id],
+			Status:      f.Status,
+			FailedAt:    f.At,
+			Impacted:    impacted(id, dependents),
+			Reasons:     []string{},
+		}
+
+		var failedDeps int
+		for _, dep := range deps[id] {
+			if _, failed := first[dep]; failed && dep != id {
+				failedDeps++
+			}
+		}
+		if failedDeps == 0 {
+			candidate.Score += independentWeight
+			candidate.Reasons = append(candidate.Reasons, "calls no other failed service")
+		} else {
+			candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("calls %d failed %s", failedDeps, plural(failedDeps, "service")))
+		}
+
+		if others := len(first) - 1; others > 0 {
+			candidate.Score += impactWeight * float64(len(candidate.Impacted)) / float64(others)
+			if n := len(candidate.Impacted); n > 0 {
+				candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("%d of the %d other failed %s depend on it", n, others, plural(others, "service")))
+			}
+		}
+
+		if spread := latest.Sub(earliest); spread > 0 {
+			candidate.Score += orderWeight * float64(latest.Sub(f.At)) / float64(spread)
+		} else {
+			candidate.Score += orderWeight
+		}
+		if f.At.Equal(earliest) {
+			candidate.Reasons = append(candidate.Reasons, "failed first")
+		}
+
+		candidate.Score = math.Round(candidate.Score*100) / 100
+		candidates = append(candidates, candidate)
+	}
+
+	slices.SortFunc(candidates, func(a, b models.RootCauseCandidate) int {
+		switch {
+		case a.Score != b.Score:
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		case !a.FailedAt.Equal(b.FailedAt):
+			return a.FailedAt.Compare(b.FailedAt)
+		}
+		return a.ServiceID - b.ServiceID
+	})
+	return candidates
+}
+
+// impacted returns the failed services depending on a service, directly or through other failed
+// services, in ascending order
+func impacted(id int, dependents map[int][]int) []int {
+	seen := map[int]bool{id: true}
+	queue := []int{id}
+	result := []int{}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range dependents[current] {
+			if seen[dependent] {
+				continue
+			}
+			seen[dependent] = true
+			result = append(result, dependent)
+			queue = append(queue, dependent)
+		}
+	}
+	slices.Sort(result)
+	return result
+}
+
+func timeRange(failures map[int]Failure) (earliest, latest time.Time) {
+	for _, f := range failures {
+		if earliest.IsZero() || f.At.Before(earliest) {
+			earliest = f.At
+		}
+		if f.At.After(latest) {
+			latest = f.At
+		}
+	}
+	return earliest, latest
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
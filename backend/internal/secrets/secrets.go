@@ -0,0 +1,196 @@
+// Package secrets encrypts sensitive values before they are stored. Every value is encrypted with
+// its own random data key (AES-256-GCM), and the data key is wrapped by a key encryption key that
+// never touches the database: a local key from the environment or a key held by a KMS.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// sealedPrefix starts every sealed value, followed by "<key id>:<wrapped data key>:<ciphertext>"
+const sealedPrefix = "enc:v1:"
+
+// maxCachedDataKeys bounds the unwrapped data keys a keyring keeps. Every sealed value has its
+// own data key, so the cache would otherwise grow with the number of stored secrets.
+const maxCachedDataKeys = 4096
+
+// ErrNoKey is returned when a value must be sealed but no key encryption key is configured
+var ErrNoKey = errors.New("secrets encryption is not configured; set SECRETS_KEY or SECRETS_KMS")
+
+// Default seals the secrets of this process; main configures it at startup
+var Default = &Keyring{}
+
+// KeyEncrypter wraps and unwraps data keys with a key encryption key. ID identifies the key in
+// sealed values and must not contain colons.
+type KeyEncrypter interface {
+	ID() string
+	WrapKey(key []byte) ([]byte, error)
+	UnwrapKey(wrapped []byte) ([]byte, error)
+}
+
+// Keyring seals values with its current key encryption key and opens values sealed with the
+// current or a previous one, so keys can be rotated without making stored secrets unreadable
+type Keyring struct {
+	mu       sync.RWMutex
+	current  KeyEncrypter
+	keys     map[string]KeyEncrypter
+	dataKeys map[string][]byte // unwrapped data keys by wrapped form, saving KMS round trips; at most maxCachedDataKeys
+}
+
+// Configure sets the key new values are sealed with and the previous keys that can still open
+// older values. A nil current key disables sealing.
+func (k *Keyring) Configure(current KeyEncrypter, previous ...KeyEncrypter) {
+	keys := make(map[string]KeyEncrypter)
+	for _, key := range previous {
+		keys[key.ID()] = key
+	}
+	if current != nil {
+		keys[current.ID()] = current
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.current = current
+	k.keys = keys
+	k.dataKeys = make(map[string][]byte)
+}
+
+// Enabled reports whether a key encryption key is configured
+func (k *Keyring) Enabled() bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current != nil
+}
+
+// Seal encrypts plaintext under a fresh data key wrapped by the current key
+func (k *Keyring) Seal(plaintext []byte) (string, error) {
+	k.mu.RLock()
+	current := k.current
+	k.mu.RUnlock()
+	if current == nil {
+		return "", ErrNoKey
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	ciphertext, err := encrypt(dataKey, plaintext)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := current.WrapKey(dataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key with %s: %w", current.ID(), err)
+	}
+	return sealedPrefix + current.ID() + ":" + base64.RawURLEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+// Open decrypts a value returned by Seal
+func (k *Keyring) Open(sealed string) ([]byte, error) {
+	keyID, wrapped, ciphertext, err := parseSealed(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.RLock()
+	key, ok := k.keys[keyID]
+	dataKey, cached := k.dataKeys[wrapped]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("secret was sealed with unknown key %q", keyID)
+	}
+
+	if !cached {
+		raw, err := base64.RawURLEncoding.DecodeString(wrapped)
+		if err != nil {
+			return nil, errors.New("malformed sealed secret")
+		}
+		if dataKey, err = key.UnwrapKey(raw); err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key with %s: %w", keyID, err)
+		}
+		k.mu.Lock()
+		if len(k.dataKeys) >= maxCachedDataKeys {
+			// Evict an arbitrary entry; map iteration order is random
+			for evicted := range k.dataKeys {
+				delete(k.dataKeys, evicted)
+				break
+			}
+		}
+		k.dataKeys[wrapped] = dataKey
+		k.mu.Unlock()
+	}
+	return decrypt(dataKey, ciphertext)
+}
+
+// IsSealed reports whether a stored value was sealed, as opposed to stored in plain text
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// NeedsRotation reports whether a sealed value was sealed with a key other than the current one
+func (k *Keyring) NeedsRotation(sealed string) bool {
+	keyID, _, _, err := parseSealed(sealed)
+	if err != nil {
+		return false
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current != nil && keyID != k.current.ID()
+}
+
+func parseSealed(sealed string) (keyID, wrapped string, ciphertext []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(sealed, sealedPrefix), ":")
+	if !strings.HasPrefix(sealed, sealedPrefix) || len(parts) != 3 {
+		return "", "", nil, errors.New("malformed sealed secret")
+	}
+	ciphertext, err = base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", "", nil, errors.New("malformed sealed secret")
+	}
+	return parts[0], parts[1], ciphertext, nil
+}
+
+// encrypt seals plaintext with AES-GCM, prefixing the random nonce
+func encrypt(key, plaintext []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func decrypt(key, ciphertext []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("malformed sealed secret")
+	}
+	plaintext, err := aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt secret")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,212 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// vaultReferencePrefix marks credential values that name a Vault secret instead of holding it,
+// e.g. "vault:secret/data/db#password"
+const vaultReferencePrefix = "vault:"
+
+// vaultCacheTTL is how long a secret read from Vault is reused, unless its lease is shorter
+const vaultCacheTTL = time.Minute
+
+// ErrVaultNotConfigured is returned when a Vault reference is resolved without a Vault client
+var ErrVaultNotConfigured = errors.New("credentials reference Vault, but VAULT_ADDR is not set")
+
+// Vault resolves Vault references of this process; nil while Vault is not configured
+var Vault *VaultClient
+
+// IsVaultReference reports whether a value names a Vault secret
+func IsVaultReference(value string) bool {
+	return strings.HasPrefix(value, vaultReferencePrefix)
+}
+
+// VaultClient talks to a HashiCorp Vault server with a token it keeps renewed
+type VaultClient struct {
+	addr      string
+	token     string
+	namespace string
+	client    *http.Client
+
+	mu    sync.RWMutex
+	cache map[string]vaultSecret // secrets by path
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type vaultSecret struct {
+	data    map[string]interface{}
+	expires time.Time
+}
+
+// NewVaultClient connects to the Vault server at addr with token. namespace is only needed for
+// Vault Enterprise namespaces.
+func NewVaultClient(addr, token, namespace string) (*VaultClient, error) {
+	if addr == "" || token == "" {
+		return nil, errors.New("the Vault address and token are required")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &VaultClient{
+		addr:      strings.TrimSuffix(addr, "/"),
+		namespace: namespace,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		token:     token,
+		cache:     make(map[string]vaultSecret),
+		ctx:       ctx,
+		cancel:    cancel,
+	}, nil
+}
+
+// Start keeps the token renewed in the background
+func (v *VaultClient) Start() {
+	go v.renewToken()
+}
+
+func (v *VaultClient) Stop() {
+	v.cancel()
+}
+
+// Resolve returns the value a Vault reference points to. References name the path of a KV secret
+// and a field of it; KV version 2 paths include the data segment, e.g. "vault:secret/data/db#password".
+func (v *VaultClient) Resolve(reference string) (string, error) {
+	path, field, ok := strings.Cut(strings.TrimPrefix(reference, vaultReferencePrefix), "#")
+	path = strings.Trim(path, "/")
+	if !IsVaultReference(reference) || !ok || path == "" || field == "" {
+		return "", fmt.Errorf("invalid Vault reference %q, must be vault:<path>#<field>", reference)
+	}
+
+	data, err := v.read(path)
+	if err != nil {
+		return "", err
+	}
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("Vault secret %s has no field %q", path, field)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+// read returns the fields of the secret at path, from the cache while it is fresh
+func (v *VaultClient) read(path string) (map[string]interface{}, error) {
+	v.mu.RLock()
+	cached, ok := v.cache[path]
+	v.mu.RUnlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.data, nil
+	}
+
+	var resp struct {
+		LeaseDuration int                    `json:"lease_duration"`
+		Data          map[string]interface{} `json:"data"`
+	}
+	if err := v.do(http.MethodGet, "/v1/"+path, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to read Vault secret %s: %w", path, err)
+	}
+	data := resp.Data
+	// KV version 2 nests the fields next to the secret's metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, versioned := data["metadata"]; versioned {
+			data = nested
+		}
+	}
+
+	ttl := vaultCacheTTL
+	if lease := time.Duration(resp.LeaseDuration) * time.Second; lease > 0 && lease < ttl {
+		ttl = lease
+	}
+	v.mu.Lock()
+	v.cache[path] = vaultSecret{data: data, expires: time.Now().Add(ttl)}
+	v.mu.Unlock()
+	return data, nil
+}
+
+// renewToken renews the token at two thirds of its TTL for as long as Vault allows. Tokens
+// without a TTL, such as root tokens, need no renewal.
+func (v *VaultClient) renewToken() {
+	for {
+		var lookup struct {
+			Data struct {
+				TTL       int  `json:"ttl"`
+				Renewable bool `json:"renewable"`
+			} `json:"data"`
+		}
+		wait := time.Minute
+		if err := v.do(http.MethodGet, "/v1/auth/token/lookup-self", nil, &lookup); err != nil {
+			log.Printf("Vault: failed to look up token: %v", err)
+		} else if lookup.Data.TTL == 0 {
+			return
+		} else if !lookup.Data.Renewable {
+			log.Printf("Vault: token is not renewable and expires in %ds", lookup.Data.TTL)
+			return
+		} else {
+			wait = time.Duration(lookup.Data.TTL) * time.Second * 2 / 3
+		}
+
+		select {
+		case <-v.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if lookup.Data.Renewable {
+			if err := v.do(http.MethodPost, "/v1/auth/token/renew-self", map[string]string{}, nil); err != nil {
+				log.Printf("Vault: failed to renew token: %v", err)
+			}
+		}
+	}
+}
+
+func (v *VaultClient) do(method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(v.ctx, method, v.addr+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var failure struct {
+			Errors []string `json:"errors"`
+		}
+		json.NewDecoder(resp.Body).Decode(&failure)
+		return fmt.Errorf("vault returned %s: %s", resp.Status, strings.Join(failure.Errors, "; "))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
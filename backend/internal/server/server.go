@@ -0,0 +1,141 @@
+// Package server runs the HTTP API, over TLS when a certificate is configured or obtained from
+// Let's Encrypt, with an optional plain HTTP listener that redirects to it.
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// TLSConfig selects how the API is served over HTTPS: with the certificate and key files, or with
+// certificates for AutocertDomains obtained from Let's Encrypt. Both empty serves plain HTTP.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+
+	AutocertDomains  []string
+	AutocertCacheDir string // keeps certificates across restarts
+	AutocertEmail    string // contact for expiry notices, optional
+}
+
+// Server is the API server
+type Server struct {
+	http     *http.Server
+	redirect *http.Server
+	certs    *certificate // nil unless serving certificate files
+	tls      bool
+}
+
+// New validates the TLS configuration and prepares a server for handler on addr. With
+// redirectAddr set, plain HTTP requests on that address are redirected to HTTPS; with autocert it
+// also answers Let's Encrypt HTTP-01 challenges.
+func New(addr string, handler http.Handler, cfg TLSConfig, redirectAddr string) (*Server, error) {
+	s := &Server{http: &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}}
+
+	var redirect http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, httpsURL(r, addr), http.StatusMovedPermanently)
+	})
+	switch {
+	case cfg.CertFile != "" && len(cfg.AutocertDomains) > 0:
+		return nil, errors.New("configure either a certificate file or autocert domains, not both")
+	case (cfg.CertFile == "") != (cfg.KeyFile == ""):
+		return nil, errors.New("the TLS certificate and key must be configured together")
+	case cfg.CertFile != "":
+		s.certs = &certificate{certFile: cfg.CertFile, keyFile: cfg.KeyFile}
+		if err := s.certs.load(); err != nil {
+			return nil, err
+		}
+		s.http.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: s.certs.get}
+		s.tls = true
+	case len(cfg.AutocertDomains) > 0:
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.AutocertDomains...),
+			Cache:      autocert.DirCache(cfg.AutocertCacheDir),
+			Email:      cfg.AutocertEmail,
+		}
+		s.http.TLSConfig = manager.TLSConfig()
+		s.http.TLSConfig.MinVersion = tls.VersionTLS12
+		redirect = manager.HTTPHandler(redirect)
+		s.tls = true
+	}
+
+	if redirectAddr != "" {
+		if !s.tls {
+			return nil, errors.New("redirecting to HTTPS requires a TLS certificate or autocert domains")
+		}
+		s.redirect = &http.Server{Addr: redirectAddr, Handler: redirect, ReadHeaderTimeout: 10 * time.Second}
+	}
+	return s, nil
+}
+
+// ListenAndServe serves until the server fails
+func (s *Server) ListenAndServe() error {
+	if s.redirect != nil {
+		go func() {
+			log.Printf("Redirecting HTTP on %s to HTTPS", s.redirect.Addr)
+			if err := s.redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("HTTP redirect server stopped: %v", err)
+			}
+		}()
+	}
+	if s.tls {
+		log.Printf("Server starting on %s (HTTPS)", s.http.Addr)
+		return s.http.ListenAndServeTLS("", "")
+	}
+	log.Printf("Server starting on %s", s.http.Addr)
+	return s.http.ListenAndServe()
+}
+
+// ReloadCertificate reads the certificate files again, so renewed certificates are served without
+// a restart. It does nothing without certificate files.
+func (s *Server) ReloadCertificate() error {
+	if s.certs == nil {
+		return nil
+	}
+	return s.certs.load()
+}
+
+// certificate holds the key pair loaded from files
+type certificate struct {
+	certFile, keyFile string
+	mu                sync.RWMutex
+	current           *tls.Certificate
+}
+
+func (c *certificate) load() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	c.mu.Lock()
+	c.current = &cert
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *certificate) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.current, nil
+}
+
+// httpsURL is the HTTPS address of a request received over plain HTTP, on the port of addr
+func httpsURL(r *http.Request, addr string) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if _, port, err := net.SplitHostPort(addr); err == nil && port != "" && port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+	return "https://" + host + r.URL.RequestURI()
+}
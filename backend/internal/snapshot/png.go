@@ -0,0 +1,112 @@
+package snapshot
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"service-weaver/internal/models"
+	"strconv"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// PNG rasterizes the same picture as SVG. Text uses a fixed bitmap font and corners are square,
+// which keeps the renderer free of native dependencies.
+func PNG(diagram *models.Diagram, services []models.Service, connections []models.Connection) ([]byte, error) {
+	l := newLayout(diagram, services, connections)
+	img := image.NewRGBA(image.Rect(0, 0, l.width, l.height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(parseColor(backgroundColor)), image.Point{}, draw.Src)
+
+	drawText(img, padding, padding/2+12, l.title, textColor)
+
+	for _, e := range l.edges {
+		c := parseColor(connectionColor(e.status))
+		drawLine(img, e.x1, e.y1, e.x2, e.y2, 2, c)
+		switch e.direction {
+		case models.DirectionBackward:
+			drawArrowhead(img, e.x2, e.y2, e.x1, e.y1, c)
+		case models.DirectionBidirectional:
+			drawArrowhead(img, e.x1, e.y1, e.x2, e.y2, c)
+			drawArrowhead(img, e.x2, e.y2, e.x1, e.y1, c)
+		case models.DirectionNone:
+			// No arrowheads
+		default:
+			drawArrowhead(img, e.x1, e.y1, e.x2, e.y2, c)
+		}
+		if e.label != "" {
+			width := len(e.label) * basicfont.Face7x13.Advance
+			drawText(img, int((e.x1+e.x2)/2)-width/2, int((e.y1+e.y2)/2)-4, e.label, mutedTextColor)
+		}
+	}
+
+	for _, n := range l.nodes {
+		status := parseColor(statusColor(n.status))
+		box := image.Rect(int(n.x), int(n.y), int(n.x)+nodeWidth, int(n.y)+nodeHeight)
+		draw.Draw(img, box, image.NewUniform(status), image.Point{}, draw.Src)
+		draw.Draw(img, box.Inset(2), image.NewUniform(parseColor(nodeFillColor)), image.Point{}, draw.Src)
+		fillCircle(img, n.x+16, n.y+nodeHeight/2, 5, status)
+		drawText(img, int(n.x)+30, int(n.y)+24, n.name, textColor)
+		drawText(img, int(n.x)+30, int(n.y)+41, n.kind, mutedTextColor)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseColor converts a #rrggbb color
+func parseColor(hex string) color.RGBA {
+	v, _ := strconv.ParseUint(hex[1:], 16, 32)
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+}
+
+// drawText writes text with its baseline at y
+func drawText(img *image.RGBA, x, y int, text, hex string) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(parseColor(hex)),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+// drawLine draws a line of the given width by stamping squares along it
+func drawLine(img *image.RGBA, x1, y1, x2, y2 float64, width int, c color.RGBA) {
+	steps := int(math.Max(math.Abs(x2-x1), math.Abs(y2-y1)))
+	if steps == 0 {
+		steps = 1
+	}
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		x := int(math.Round(x1 + (x2-x1)*t))
+		y := int(math.Round(y1 + (y2-y1)*t))
+		draw.Draw(img, image.Rect(x-width/2, y-width/2, x-width/2+width, y-width/2+width), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+}
+
+// drawArrowhead draws an arrowhead at (x2, y2) pointing away from (x1, y1)
+func drawArrowhead(img *image.RGBA, x1, y1, x2, y2 float64, c color.RGBA) {
+	const length, spread = 10.0, 0.45
+	angle := math.Atan2(y2-y1, x2-x1)
+	for _, side := range []float64{-spread, spread} {
+		drawLine(img, x2, y2, x2-length*math.Cos(angle+side), y2-length*math.Sin(angle+side), 2, c)
+	}
+}
+
+func fillCircle(img *image.RGBA, cx, cy, r float64, c color.RGBA) {
+	for y := int(cy - r); y <= int(cy+r); y++ {
+		for x := int(cx - r); x <= int(cx+r); x++ {
+			if dx, dy := float64(x)-cx, float64(y)-cy; dx*dx+dy*dy <= r*r {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Package snapshot renders a diagram's topology and current statuses as SVG or PNG images
+package snapshot
+
+import (
+	"math"
+	"service-weaver/internal/models"
+)
+
+// Node dimensions and spacing, close to how the editor draws nodes
+const (
+	nodeWidth   = 180
+	nodeHeight  = 56
+	padding     = 40
+	titleHeight = 36
+	maxNameLen  = 22
+)
+
+// Colors shared with the frontend theme
+const (
+	backgroundColor = "#0a0a0f"
+	nodeFillColor   = "#111118"
+	textColor       = "#e5e7eb"
+	mutedTextColor  = "#9ca3af"
+)
+
+func statusColor(status models.ServiceStatus) string {
+	switch status {
+	case models.StatusAlive:
+		return "#00ff88"
+	case models.StatusDead:
+		return "#ff3366"
+	case models.StatusDegraded:
+		return "#ffaa00"
+	case models.StatusChecking:
+		return "#00aaff"
+	default:
+		return "#888899"
+	}
+}
+
+func connectionColor(status models.ConnectionStatus) string {
+	switch status {
+	case models.ConnectionHealthy:
+		return "#00ff88"
+	case models.ConnectionBroken:
+		return "#ff3366"
+	case models.ConnectionAtRisk:
+		return "#ffaa00"
+	default:
+		return "#888899"
+	}
+}
+
+// node is a service placed on the canvas
+type node struct {
+	x, y   float64 // top-left corner
+	name   string
+	kind   string
+	status models.ServiceStatus
+}
+
+func (n node) center() (float64, float64) {
+	return n.x + nodeWidth/2, n.y + nodeHeight/2
+}
+
+// edge is a connection clipped to the borders of its nodes
+type edge struct {
+	x1, y1, x2, y2 float64
+	status         models.ConnectionStatus
+	direction      models.ConnectionDirection
+	label          string
+}
+
+// layout positions every node and edge of a diagram in image coordinates
+type layout struct {
+	width, height int
+	title         string
+	nodes         []node
+	edges         []edge
+}
+
+func newLayout(diagram *models.Diagram, services []models.Service, connections []models.Connection) *layout {
+	l := &layout{title: diagram.Name}
+	if len(services) == 0 {
+		l.width, l.height = 2*padding+nodeWidth, titleHeight+2*padding
+		return l
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, s := range services {
+		minX, minY = math.Min(minX, s.PositionX), math.Min(minY, s.PositionY)
+		maxX, maxY = math.Max(maxX, s.PositionX), math.Max(maxY, s.PositionY)
+	}
+
+	// Shift the diagram so its top-left node sits at the padding, below the title
+	offsetX, offsetY := padding-minX, titleHeight+padding-minY
+	byID := make(map[int]node, len(services))
+	for _, s := range services {
+		n := node{
+			x:      s.PositionX + offsetX,
+			y:      s.PositionY + offsetY,
+			name:   truncate(s.Name, maxNameLen),
+			kind:   truncate(s.ServiceType, maxNameLen),
+			status: s.CurrentStatus,
+		}
+		byID[s.ID] = n
+		l.nodes = append(l.nodes, n)
+	}
+	l.width = int(math.Ceil(maxX-minX)) + nodeWidth + 2*padding
+	l.height = int(math.Ceil(maxY-minY)) + nodeHeight + titleHeight + 2*padding
+
+	for _, c := range connections {
+		source, ok := byID[c.SourceID]
+		if !ok {
+			continue
+		}
+		target, ok := byID[c.TargetID]
+		if !ok || c.SourceID == c.TargetID {
+			continue
+		}
+		sx, sy := source.center()
+		tx, ty := target.center()
+		x1, y1 := clip(source, tx, ty)
+		x2, y2 := clip(target, sx, sy)
+		label := c.Label
+		if label == "" {
+			label = c.Protocol
+		}
+		status := c.Status
+		if status == "" {
+			status = models.ConnectionUnknown
+		}
+		l.edges = append(l.edges, edge{
+			x1: x1, y1: y1, x2: x2, y2: y2,
+			status:    status,
+			direction: c.Direction,
+			label:     truncate(label, maxNameLen),
+		})
+	}
+	return l
+}
+
+// clip returns where the line from the center of n towards (tx, ty) leaves the node
+func clip(n node, tx, ty float64) (float64, float64) {
+	cx, cy := n.center()
+	dx, dy := tx-cx, ty-cy
+	if dx == 0 && dy == 0 {
+		return cx, cy
+	}
+	scale := math.Min(
+		(nodeWidth/2)/math.Max(math.Abs(dx), 1e-9),
+		(nodeHeight/2)/math.Max(math.Abs(dy), 1e-9),
+	)
+	return cx + dx*scale, cy + dy*scale
+}
+
+// truncate shortens s to at most max runes, marking the cut with an ellipsis
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-3]) + "..."
+}
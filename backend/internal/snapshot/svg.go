@@ -0,0 +1,61 @@
+package snapshot
+
+import (
+	"fmt"
+	"html"
+	"service-weaver/internal/models"
+	"strings"
+)
+
+// SVG renders the diagram with nodes colored by service status and edges by connection status
+func SVG(diagram *models.Diagram, services []models.Service, connections []models.Connection) []byte {
+	l := newLayout(diagram, services, connections)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif">`+"\n",
+		l.width, l.height, l.width, l.height)
+
+	// One arrowhead per connection status so markers match their line
+	b.WriteString("<defs>\n")
+	for _, status := range []models.ConnectionStatus{models.ConnectionHealthy, models.ConnectionAtRisk, models.ConnectionBroken, models.ConnectionUnknown} {
+		fmt.Fprintf(&b, `<marker id="arrow-%s" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M0,0 L10,5 L0,10 z" fill="%s"/></marker>`+"\n",
+			status, connectionColor(status))
+	}
+	b.WriteString("</defs>\n")
+
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", backgroundColor)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="16" font-weight="bold" fill="%s">%s</text>`+"\n",
+		padding, padding/2+12, textColor, html.EscapeString(l.title))
+
+	for _, e := range l.edges {
+		marker := "arrow-" + string(e.status)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="2"`, e.x1, e.y1, e.x2, e.y2, connectionColor(e.status))
+		switch e.direction {
+		case models.DirectionBackward:
+			fmt.Fprintf(&b, ` marker-start="url(#%s)"`, marker)
+		case models.DirectionBidirectional:
+			fmt.Fprintf(&b, ` marker-start="url(#%s)" marker-end="url(#%s)"`, marker, marker)
+		case models.DirectionNone:
+			// No arrowheads
+		default:
+			fmt.Fprintf(&b, ` marker-end="url(#%s)"`, marker)
+		}
+		b.WriteString("/>\n")
+		if e.label != "" {
+			fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="11" text-anchor="middle" fill="%s">%s</text>`+"\n",
+				(e.x1+e.x2)/2, (e.y1+e.y2)/2-4, mutedTextColor, html.EscapeString(e.label))
+		}
+	}
+
+	for _, n := range l.nodes {
+		color := statusColor(n.status)
+		fmt.Fprintf(&b, `<g><rect x="%.1f" y="%.1f" width="%d" height="%d" rx="10" fill="%s" stroke="%s" stroke-width="2"/>`,
+			n.x, n.y, nodeWidth, nodeHeight, nodeFillColor, color)
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="5" fill="%s"/>`, n.x+16, n.y+nodeHeight/2, color)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="13" fill="%s">%s</text>`, n.x+30, n.y+24, textColor, html.EscapeString(n.name))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="11" fill="%s">%s</text></g>`+"\n", n.x+30, n.y+41, mutedTextColor, html.EscapeString(n.kind))
+	}
+
+	b.WriteString("</svg>\n")
+	return []byte(b.String())
+}
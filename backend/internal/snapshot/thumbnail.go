@@ -0,0 +1,127 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"service-weaver/internal/models"
+	"sort"
+	"strings"
+)
+
+// Thumbnail dimensions, sized for the cards of the diagram list
+const (
+	thumbnailWidth   = 320
+	thumbnailHeight  = 200
+	thumbnailPadding = 12
+)
+
+// Thumbnails draw the layout only, in neutral colors, so they stay valid while statuses change
+const (
+	thumbnailNodeColor = "#9ca3af"
+	thumbnailEdgeColor = "#4b5563"
+)
+
+// thumbnail is a layout scaled down to fit the thumbnail, centered in it
+type thumbnail struct {
+	scale            float64
+	offsetX, offsetY float64
+	nodes            []node
+	edges            []edge
+}
+
+func newThumbnail(services []models.Service, connections []models.Connection) *thumbnail {
+	l := newLayout(&models.Diagram{}, services, connections)
+	t := &thumbnail{scale: 1, nodes: l.nodes, edges: l.edges}
+	if len(l.nodes) == 0 {
+		return t
+	}
+
+	// Without a title, the drawing spans from the padding around the nodes to the far corner
+	width := float64(l.width - 2*padding)
+	height := float64(l.height - titleHeight - 2*padding)
+	t.scale = math.Min(1, math.Min(
+		(thumbnailWidth-2*thumbnailPadding)/width,
+		(thumbnailHeight-2*thumbnailPadding)/height,
+	))
+	t.offsetX = (thumbnailWidth-width*t.scale)/2 - padding*t.scale
+	t.offsetY = (thumbnailHeight-height*t.scale)/2 - (titleHeight+padding)*t.scale
+	return t
+}
+
+// point maps layout coordinates to thumbnail coordinates
+func (t *thumbnail) point(x, y float64) (float64, float64) {
+	return x*t.scale + t.offsetX, y*t.scale + t.offsetY
+}
+
+// LayoutHash fingerprints what a thumbnail draws: the positions of the services and the pairs of
+// them connected. Thumbnails only need rendering again when it changes.
+func LayoutHash(services []models.Service, connections []models.Connection) string {
+	sorted := append([]models.Service(nil), services...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	pairs := make([]string, 0, len(connections))
+	for _, c := range connections {
+		pairs = append(pairs, fmt.Sprintf("%d-%d", c.SourceID, c.TargetID))
+	}
+	sort.Strings(pairs)
+
+	h := sha256.New()
+	for _, s := range sorted {
+		binary.Write(h, binary.BigEndian, []float64{float64(s.ID), s.PositionX, s.PositionY})
+	}
+	h.Write([]byte(strings.Join(pairs, ",")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ThumbnailSVG renders a small picture of the diagram's layout, without labels or statuses
+func ThumbnailSVG(services []models.Service, connections []models.Connection) []byte {
+	t := newThumbnail(services, connections)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		thumbnailWidth, thumbnailHeight, thumbnailWidth, thumbnailHeight)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", backgroundColor)
+	for _, e := range t.edges {
+		x1, y1 := t.point(e.x1, e.y1)
+		x2, y2 := t.point(e.x2, e.y2)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="1.5"/>`+"\n", x1, y1, x2, y2, thumbnailEdgeColor)
+	}
+	for _, n := range t.nodes {
+		x, y := t.point(n.x, n.y)
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="%.1f" fill="%s" stroke="%s" stroke-width="1.5"/>`+"\n",
+			x, y, nodeWidth*t.scale, nodeHeight*t.scale, 10*t.scale, nodeFillColor, thumbnailNodeColor)
+	}
+	b.WriteString("</svg>\n")
+	return []byte(b.String())
+}
+
+// ThumbnailPNG rasterizes the same picture as ThumbnailSVG
+func ThumbnailPNG(services []models.Service, connections []models.Connection) ([]byte, error) {
+	t := newThumbnail(services, connections)
+	img := image.NewRGBA(image.Rect(0, 0, thumbnailWidth, thumbnailHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(parseColor(backgroundColor)), image.Point{}, draw.Src)
+
+	for _, e := range t.edges {
+		x1, y1 := t.point(e.x1, e.y1)
+		x2, y2 := t.point(e.x2, e.y2)
+		drawLine(img, x1, y1, x2, y2, 1, parseColor(thumbnailEdgeColor))
+	}
+	for _, n := range t.nodes {
+		x, y := t.point(n.x, n.y)
+		box := image.Rect(int(x), int(y), int(math.Round(x+nodeWidth*t.scale)), int(math.Round(y+nodeHeight*t.scale)))
+		draw.Draw(img, box, image.NewUniform(parseColor(thumbnailNodeColor)), image.Point{}, draw.Src)
+		draw.Draw(img, box.Inset(1), image.NewUniform(parseColor(nodeFillColor)), image.Point{}, draw.Src)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
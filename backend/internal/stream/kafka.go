@@ -0,0 +1,63 @@
+package stream
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/Shopify/sarama"
+)
+
+// KafkaConfig addresses the topic events are published to. SASL/PLAIN is used when Username is set.
+type KafkaConfig struct {
+	Brokers  []string
+	Topic    string
+	Username string
+	Password string
+	TLS      bool
+}
+
+// kafkaPublisher publishes to a Kafka topic, waiting for the partition leader to acknowledge
+type kafkaPublisher struct {
+	producer sarama.SyncProducer
+	topic    string
+}
+
+// NewKafkaPublisher connects to the brokers of config
+func NewKafkaPublisher(config KafkaConfig) (Publisher, error) {
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.ClientID = "service-weaver-results"
+	saramaConfig.Producer.RequiredAcks = sarama.WaitForLocal
+	saramaConfig.Producer.Return.Successes = true
+	if config.Username != "" {
+		saramaConfig.Net.SASL.Enable = true
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		saramaConfig.Net.SASL.User = config.Username
+		saramaConfig.Net.SASL.Password = config.Password
+	}
+	if config.TLS {
+		saramaConfig.Net.TLS.Enable = true
+		saramaConfig.Net.TLS.Config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	producer, err := sarama.NewSyncProducer(config.Brokers, saramaConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaPublisher{producer: producer, topic: config.Topic}, nil
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, messages []Message) error {
+	batch := make([]*sarama.ProducerMessage, len(messages))
+	for i, message := range messages {
+		batch[i] = &sarama.ProducerMessage{
+			Topic: p.topic,
+			Key:   sarama.StringEncoder(message.Key),
+			Value: sarama.ByteEncoder(message.Value),
+		}
+	}
+	return p.producer.SendMessages(batch)
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.producer.Close()
+}
@@ -0,0 +1,185 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSConfig addresses the subject events are published to. URL is nats://host:4222, or tls:// to
+// connect with TLS, and may carry user:password or a token as its user.
+type NATSConfig struct {
+	URL     string
+	Subject string
+}
+
+const natsTimeout = 10 * time.Second
+
+// natsPublisher speaks the text protocol of NATS core over one connection, re-established when it
+// breaks. Every batch ends with a PING, so Publish returns only once the server processed it.
+type natsPublisher struct {
+	config NATSConfig
+	url    *url.URL
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewNATSPublisher connects to the server of config
+func NewNATSPublisher(config NATSConfig) (Publisher, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("unsupported NATS URL scheme %q, use nats:// or tls://", u.Scheme)
+	}
+	if config.Subject == "" || strings.ContainsAny(config.Subject, " \t\r\n") {
+		return nil, errors.New("NATS subject must be set and must not contain whitespace")
+	}
+
+	p := &natsPublisher{config: config, url: u}
+	ctx, cancel := context.WithTimeout(context.Background(), natsTimeout)
+	defer cancel()
+	if err := p.connect(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *natsPublisher) connect(ctx context.Context) error {
+	host := p.url.Host
+	if p.url.Port() == "" {
+		host = net.JoinHostPort(p.url.Hostname(), "4222")
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	if p.url.Scheme == "tls" {
+		conn = tls.Client(conn, &tls.Config{ServerName: p.url.Hostname(), MinVersion: tls.VersionTLS12})
+	}
+	p.conn = conn
+	p.reader = bufio.NewReader(conn)
+	p.setDeadline(ctx)
+
+	// The server greets with INFO; CONNECT authenticates and the PONG confirms it was accepted
+	if _, err := p.readLine(); err != nil {
+		p.disconnect()
+		return err
+	}
+	options := map[string]interface{}{"verbose": false, "pedantic": false, "name": "service-weaver", "lang": "go", "version": "1.0"}
+	if user := p.url.User; user != nil {
+		if password, ok := user.Password(); ok {
+			options["user"] = user.Username()
+			options["pass"] = password
+		} else {
+			options["auth_token"] = user.Username()
+		}
+	}
+	connect, _ := json.Marshal(options)
+	if _, err := fmt.Fprintf(p.conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		p.disconnect()
+		return err
+	}
+	if err := p.awaitPong(); err != nil {
+		p.disconnect()
+		return err
+	}
+	return nil
+}
+
+func (p *natsPublisher) disconnect() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+		p.reader = nil
+	}
+}
+
+func (p *natsPublisher) setDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(natsTimeout)
+	}
+	p.conn.SetDeadline(deadline)
+}
+
+func (p *natsPublisher) readLine() (string, error) {
+	line, err := p.reader.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// awaitPong reads until the server answers the PING sent last, answering its own PINGs meanwhile
+func (p *natsPublisher) awaitPong() error {
+	for {
+		line, err := p.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS server: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, messages []Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// A connection that broke while idle is only noticed on use, so retry once on a fresh one
+	err := p.publish(ctx, messages)
+	if err != nil && ctx.Err() == nil {
+		err = p.publish(ctx, messages)
+	}
+	return err
+}
+
+func (p *natsPublisher) publish(ctx context.Context, messages []Message) error {
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+	p.setDeadline(ctx)
+
+	w := bufio.NewWriter(p.conn)
+	for _, message := range messages {
+		fmt.Fprintf(w, "PUB %s %d\r\n", p.config.Subject, len(message.Value))
+		w.Write(message.Value)
+		w.WriteString("\r\n")
+	}
+	w.WriteString("PING\r\n")
+	err := w.Flush()
+	if err == nil {
+		err = p.awaitPong()
+	}
+	if err != nil {
+		p.disconnect()
+	}
+	return err
+}
+
+func (p *natsPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.disconnect()
+	return nil
+}
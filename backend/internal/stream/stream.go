@@ -0,0 +1,176 @@
+// Package stream publishes healthcheck results and status transitions to a Kafka topic or a NATS
+// subject, for consumers that build their own analytics on them.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"service-weaver/internal/models"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	queueSize = 10000
+	batchSize = 500
+)
+
+// Publisher sends messages to a broker. Keys keep the messages of a service in order where the
+// broker partitions by key.
+type Publisher interface {
+	Publish(ctx context.Context, messages []Message) error
+	Close() error
+}
+
+// Message is an encoded event and the key it is published under
+type Message struct {
+	Key   string
+	Value []byte
+}
+
+// Event is the JSON document published for every healthcheck result and status transition
+type Event struct {
+	Type         string               `json:"type"` // "result" or "transition"
+	ServiceID    int                  `json:"service_id"`
+	ServiceName  string               `json:"service_name"`
+	DiagramID    int                  `json:"diagram_id"`
+	Method       string               `json:"healthcheck_method,omitempty"`
+	Status       models.ServiceStatus `json:"status"`
+	FromStatus   models.ServiceStatus `json:"from_status,omitempty"` // transitions only
+	StatusCode   int                  `json:"status_code,omitempty"`
+	ResponseTime int                  `json:"response_time,omitempty"`
+	Error        string               `json:"error,omitempty"`
+	Timestamp    time.Time            `json:"timestamp"`
+}
+
+// Exporter queues events and publishes them in batches on a goroutine of its own, so a slow or
+// unreachable broker never holds up healthchecks. Events are dropped while the queue is full.
+type Exporter struct {
+	publisher Publisher
+	queue     chan Event
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	mu      sync.Mutex
+	dropped int // events dropped since the last batch was published
+}
+
+func NewExporter(publisher Publisher) *Exporter {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Exporter{
+		publisher: publisher,
+		queue:     make(chan Event, queueSize),
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+}
+
+func (e *Exporter) Start() {
+	go e.run()
+}
+
+// Stop publishes the events still queued, waiting up to five seconds, and closes the publisher
+func (e *Exporter) Stop() {
+	e.cancel()
+	<-e.done
+	if err := e.publisher.Close(); err != nil {
+		log.Printf("Error closing result stream: %v", err)
+	}
+}
+
+// ExportResult queues the event of a healthcheck result
+func (e *Exporter) ExportResult(service models.Service, result models.HealthcheckResult) {
+	e.enqueue(Event{
+		Type:         "result",
+		ServiceID:    service.ID,
+		ServiceName:  service.Name,
+		DiagramID:    service.DiagramID,
+		Method:       service.HealthcheckMethod,
+		Status:       result.Status,
+		StatusCode:   result.StatusCode,
+		ResponseTime: result.ResponseTime,
+		Error:        result.Error,
+		Timestamp:    result.CheckedAt,
+	})
+}
+
+// ExportTransition queues the event of a service changing its status
+func (e *Exporter) ExportTransition(service models.Service, from, to models.ServiceStatus, at time.Time) {
+	e.enqueue(Event{
+		Type:        "transition",
+		ServiceID:   service.ID,
+		ServiceName: service.Name,
+		DiagramID:   service.DiagramID,
+		Method:      service.HealthcheckMethod,
+		Status:      to,
+		FromStatus:  from,
+		Timestamp:   at,
+	})
+}
+
+func (e *Exporter) enqueue(event Event) {
+	select {
+	case e.queue <- event:
+	default:
+		e.mu.Lock()
+		e.dropped++
+		e.mu.Unlock()
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+	for {
+		select {
+		case event := <-e.queue:
+			e.publish(e.ctx, e.batch(event))
+		case <-e.ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			for len(e.queue) > 0 && ctx.Err() == nil {
+				e.publish(ctx, e.batch(<-e.queue))
+			}
+			return
+		}
+	}
+}
+
+// batch collects first and the events queued behind it, up to batchSize
+func (e *Exporter) batch(first Event) []Event {
+	events := []Event{first}
+	for len(events) < batchSize {
+		select {
+		case event := <-e.queue:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+	return events
+}
+
+func (e *Exporter) publish(ctx context.Context, events []Event) {
+	e.mu.Lock()
+	dropped := e.dropped
+	e.dropped = 0
+	e.mu.Unlock()
+	if dropped > 0 {
+		log.Printf("Result stream queue full, dropped %d events", dropped)
+	}
+
+	messages := make([]Message, 0, len(events))
+	for _, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		messages = append(messages, Message{Key: strconv.Itoa(event.ServiceID), Value: value})
+	}
+	if err := e.publisher.Publish(ctx, messages); err != nil {
+		log.Printf("Error publishing %d events to the result stream: %v", len(messages), err)
+	}
+}
@@ -0,0 +1,110 @@
+// Package telemetry sets up the OpenTelemetry SDK. Spans and metrics are exported with OTLP/HTTP,
+// so any OpenTelemetry Collector (or compatible backend) can ingest them. Instrumented code uses
+// the global providers, which stay no-ops until Init configures an endpoint.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// Config controls the OTLP exporters
+type Config struct {
+	// Endpoint is the base OTLP/HTTP URL, e.g. http://otel-collector:4318. Empty disables export.
+	Endpoint    string
+	ServiceName string
+	// Headers are sent with every export request (e.g. authentication for hosted backends)
+	Headers         map[string]string
+	MetricsInterval time.Duration
+}
+
+// Provider owns the span and metric pipelines
+type Provider struct {
+	traces  *sdktrace.TracerProvider
+	metrics *sdkmetric.MeterProvider
+}
+
+// ParseHeaders parses the OTEL_EXPORTER_OTLP_HEADERS format ("key1=value1,key2=value2")
+func ParseHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		if ok && strings.TrimSpace(key) != "" {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+	}
+	return headers
+}
+
+// Init installs the process-wide tracer and meter providers and the W3C trace context
+// propagator. With an empty endpoint, instrumentation stays a no-op and Init returns nil.
+func Init(config Config) (*Provider, error) {
+	if config.Endpoint == "" {
+		return nil, nil
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = "service-weaver"
+	}
+	if config.MetricsInterval <= 0 {
+		config.MetricsInterval = 30 * time.Second
+	}
+	endpoint := strings.TrimRight(config.Endpoint, "/")
+
+	ctx := context.Background()
+	spans, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(endpoint+"/v1/traces"),
+		otlptracehttp.WithHeaders(config.Headers),
+	)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := otlpmetrichttp.New(ctx,
+		otlpmetrichttp.WithEndpointURL(endpoint+"/v1/metrics"),
+		otlpmetrichttp.WithHeaders(config.Headers),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res := resource.NewSchemaless(semconv.ServiceName(config.ServiceName))
+	p := &Provider{
+		traces: sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(spans),
+			sdktrace.WithResource(res),
+		),
+		metrics: sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metrics, sdkmetric.WithInterval(config.MetricsInterval))),
+			sdkmetric.WithResource(res),
+		),
+	}
+	otel.SetTracerProvider(p.traces)
+	otel.SetMeterProvider(p.metrics)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	log.Printf("OpenTelemetry export enabled to %s", endpoint)
+	return p, nil
+}
+
+// Shutdown flushes pending telemetry and stops the exporters
+func (p *Provider) Shutdown() {
+	if p == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := errors.Join(p.traces.Shutdown(ctx), p.metrics.Shutdown(ctx)); err != nil {
+		log.Printf("Error flushing telemetry: %v", err)
+	}
+}
@@ -0,0 +1,301 @@
+// Package webhooks delivers configuration change events for diagrams, services and connections
+// to external HTTP endpoints.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"service-weaver/internal/models"
+	"service-weaver/internal/repository"
+	"strings"
+	"time"
+)
+
+const (
+	queueSize   = 256
+	maxAttempts = 3
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Service-Weaver-Event"
+	HeaderDelivery  = "X-Service-Weaver-Delivery"
+	HeaderSignature = "X-Service-Weaver-Signature"
+)
+
+// changeRoutes maps the HTTP routes that change topology to the event they emit. Other
+// mutations, such as saving node positions, are not reported.
+var changeRoutes = map[string]string{
+	"POST /api/diagrams":                       "diagram.created",
+	"PUT /api/diagrams/:id":                    "diagram.updated",
+	"DELETE /api/diagrams/:id":                 "diagram.deleted",
+	"POST /api/diagrams/:id/rollback/:version": "diagram.updated",
+	"POST /api/diagrams/:id/restore":           "diagram.restored",
+	"PUT /api/diagrams/:id/folder":             "diagram.updated",
+	"PUT /api/diagrams/:id/service-defaults":   "diagram.updated",
+	"POST /api/services":                       "service.created",
+	"POST /api/services/bulk":                  "service.created",
+	"PUT /api/services/bulk":                   "service.updated",
+	"POST /api/services/copy":                  "service.created",
+	"POST /api/services/move":                  "service.updated",
+	"POST /api/services/delete":                "service.deleted",
+	"POST /api/services/pause":                 "service.updated",
+	"POST /api/services/resume":                "service.updated",
+	"PUT /api/services/:id":                    "service.updated",
+	"DELETE /api/services/:id":                 "service.deleted",
+	"POST /api/services/:id/icon":              "service.updated",
+	"POST /api/services/:id/restore":           "service.restored",
+	"POST /api/services/:id/copy":              "service.created",
+	"POST /api/services/:id/move":              "service.updated",
+	"POST /api/services/:id/clone":             "service.created",
+	"POST /api/tags/:tag/actions":              "service.updated",
+	"POST /api/connections":                    "connection.created",
+	"PUT /api/connections/:id":                 "connection.updated",
+	"DELETE /api/connections/:id":              "connection.deleted",
+}
+
+// Dispatcher turns audit log entries into change events and delivers them, in order, to every
+// enabled webhook subscribed to them
+type Dispatcher struct {
+	repo    *repository.Repository
+	client  *http.Client
+	backoff time.Duration
+	queue   chan models.AuditEntry
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+func NewDispatcher(repo *repository.Repository) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		repo:    repo,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		backoff: time.Second,
+		queue:   make(chan models.AuditEntry, queueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (d *Dispatcher) Start() {
+	go d.run()
+}
+
+func (d *Dispatcher) Stop() {
+	d.cancel()
+}
+
+// AuditRecorded queues the events of an audit entry without blocking the request
+func (d *Dispatcher) AuditRecorded(entry models.AuditEntry) {
+	if eventName(entry) == "" {
+		return
+	}
+	select {
+	case d.queue <- entry:
+	default:
+		log.Printf("Webhook queue full, dropping events of audit entry %d", entry.ID)
+	}
+}
+
+func (d *Dispatcher) run() {
+	for {
+		select {
+		case entry := <-d.queue:
+			d.dispatch(Events(entry))
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(events []models.ConfigChangeEvent) {
+	if len(events) == 0 {
+		return
+	}
+	hooks, err := d.repo.GetEnabledWebhooks(d.ctx)
+	if err != nil {
+		log.Printf("Error loading webhooks: %v", err)
+		return
+	}
+
+	for _, event := range events {
+		for _, hook := range hooks {
+			if !Subscribed(hook, event) {
+				continue
+			}
+			statusCode, err := d.deliver(hook, event)
+			errMsg := ""
+			if err != nil {
+				errMsg = err.Error()
+				log.Printf("Error delivering %s to webhook %d (%s): %v", event.Event, hook.ID, hook.Name, err)
+			}
+			if err := d.repo.RecordWebhookDelivery(d.ctx, hook.ID, statusCode, errMsg, time.Now()); err != nil {
+				log.Printf("Error recording delivery of webhook %d: %v", hook.ID, err)
+			}
+		}
+	}
+}
+
+// SendTest delivers a sample event to a single webhook and reports the outcome
+func (d *Dispatcher) SendTest(hook models.Webhook) error {
+	event := models.ConfigChangeEvent{
+		Event:      "webhook.test",
+		EntityType: "webhooks",
+		EntityID:   &hook.ID,
+		OccurredAt: time.Now().UTC(),
+	}
+	_, err := d.send(hook, event)
+	return err
+}
+
+// deliver sends an event, retrying network errors and 5xx/429 responses with a linear backoff
+func (d *Dispatcher) deliver(hook models.Webhook, event models.ConfigChangeEvent) (int, error) {
+	var statusCode int
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		statusCode, err = d.send(hook, event)
+		if err == nil || (statusCode != 0 && statusCode < 500 && statusCode != http.StatusTooManyRequests) {
+			return statusCode, err
+		}
+		if attempt < maxAttempts {
+			select {
+			case <-time.After(time.Duration(attempt) * d.backoff):
+			case <-d.ctx.Done():
+				return statusCode, err
+			}
+		}
+	}
+	return statusCode, err
+}
+
+func (d *Dispatcher) send(hook models.Webhook, event models.ConfigChangeEvent) (int, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "service-weaver-webhooks")
+	req.Header.Set(HeaderEvent, event.Event)
+	req.Header.Set(HeaderDelivery, fmt.Sprint(event.ID))
+	if hook.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(hook.Secret, payload))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value of a payload: "sha256=" followed by the hex HMAC-SHA256
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Subscribed reports whether a webhook wants an event
+func Subscribed(hook models.Webhook, event models.ConfigChangeEvent) bool {
+	if hook.DiagramID != nil && (event.DiagramID == nil || *event.DiagramID != *hook.DiagramID) {
+		return false
+	}
+	if len(hook.Events) == 0 {
+		return true
+	}
+	for _, name := range hook.Events {
+		if name == event.Event {
+			return true
+		}
+	}
+	return false
+}
+
+// eventName returns the change event an audit entry represents, or "" when it is not a
+// successful topology change
+func eventName(entry models.AuditEntry) string {
+	if entry.StatusCode >= http.StatusBadRequest {
+		return ""
+	}
+	if entry.Method == "GRPC" {
+		switch entry.EntityType {
+		case "diagrams", "services", "connections":
+			return strings.TrimSuffix(entry.EntityType, "s") + "." + string(entry.Action) + "d"
+		}
+		return ""
+	}
+	return changeRoutes[entry.Method+" "+entry.Route]
+}
+
+// Events converts an audit entry into change events. Bulk requests produce one event per item.
+func Events(entry models.AuditEntry) []models.ConfigChangeEvent {
+	name := eventName(entry)
+	if name == "" {
+		return nil
+	}
+
+	base := models.ConfigChangeEvent{
+		ID:         entry.ID,
+		Event:      name,
+		EntityType: entry.EntityType,
+		Username:   entry.Username,
+		OccurredAt: entry.CreatedAt,
+	}
+
+	var items []json.RawMessage
+	if entry.EntityID == nil && json.Unmarshal(entry.After, &items) == nil {
+		events := make([]models.ConfigChangeEvent, 0, len(items))
+		for _, item := range items {
+			event := base
+			event.After = item
+			event.EntityID = jsonInt(item, "id")
+			event.DiagramID = jsonInt(item, "diagram_id")
+			events = append(events, event)
+		}
+		return events
+	}
+
+	event := base
+	event.EntityID = entry.EntityID
+	event.Before = entry.Before
+	event.After = entry.After
+	if entry.EntityType == "diagrams" {
+		event.DiagramID = entry.EntityID
+	} else if event.DiagramID = jsonInt(entry.After, "diagram_id"); event.DiagramID == nil {
+		event.DiagramID = jsonInt(entry.Before, "diagram_id")
+	}
+	return []models.ConfigChangeEvent{event}
+}
+
+// jsonInt reads an integer field from a JSON object
+func jsonInt(data json.RawMessage, key string) *int {
+	var object map[string]interface{}
+	if len(data) == 0 || json.Unmarshal(data, &object) != nil {
+		return nil
+	}
+	value, ok := object[key].(float64)
+	if !ok {
+		return nil
+	}
+	id := int(value)
+	return &id
+}
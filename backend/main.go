@@ -1,78 +1,569 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"service-weaver/internal/anomaly"
 	"service-weaver/internal/api"
+	"service-weaver/internal/backup"
+	"service-weaver/internal/blobstore"
+	"service-weaver/internal/config"
+	"service-weaver/internal/discovery"
+	"service-weaver/internal/grpcapi"
 	"service-weaver/internal/middleware"
+	"service-weaver/internal/models"
 	"service-weaver/internal/monitoring"
+	"service-weaver/internal/notification"
+	"service-weaver/internal/oidc"
+	"service-weaver/internal/openapi"
+	"service-weaver/internal/reports"
 	"service-weaver/internal/repository"
+	"service-weaver/internal/secrets"
+	"service-weaver/internal/server"
+	"service-weaver/internal/stream"
+	"service-weaver/internal/telemetry"
+	"service-weaver/internal/webhooks"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata" // time zones of notification preferences, also on hosts without zoneinfo
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
-	// Get database connection parameters from environment variables
-	dbHost := getEnv("DB_HOST", "localhost")
-	dbPort := getEnv("DB_PORT", "5430")
-	dbUser := getEnv("DB_USER", "postgres")
-	dbPassword := getEnv("DB_PASSWORD", "password")
-	dbName := getEnv("DB_NAME", "service_weaver")
+	// Settings come from the environment, optionally with defaults from a YAML or TOML file
+	configPath := flag.String("config", getEnv("CONFIG_FILE", ""), "YAML or TOML configuration file; environment variables override it")
+	flag.Parse()
+	args := flag.Args()
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.Apply(); err != nil {
+		log.Fatal("Failed to apply configuration: ", err)
+	}
+	if cfg.Path != "" {
+		log.Printf("Loaded configuration from %s", cfg.Path)
+	}
+
+	// Initialize OpenTelemetry export (disabled unless an OTLP endpoint is configured)
+	serviceName := getEnv("OTEL_SERVICE_NAME", "service-weaver")
+	tracing, err := telemetry.Init(telemetry.Config{
+		Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		ServiceName: serviceName,
+		Headers:     telemetry.ParseHeaders(getEnv("OTEL_EXPORTER_OTLP_HEADERS", "")),
+	})
+	if err != nil {
+		log.Fatal("Failed to initialize OpenTelemetry: ", err)
+	}
+	defer tracing.Shutdown()
+
+	// Service credentials may reference Vault secrets, which checks resolve with a token kept renewed
+	if addr := getEnv("VAULT_ADDR", ""); addr != "" {
+		vault, err := secrets.NewVaultClient(addr, getEnv("VAULT_TOKEN", ""), getEnv("VAULT_NAMESPACE", ""))
+		if err != nil {
+			log.Fatal("Invalid Vault configuration: ", err)
+		}
+		vault.Start()
+		defer vault.Stop()
+		secrets.Vault = vault
+	}
+
+	// Service credentials and headers are sealed with SECRETS_KEY or a Vault transit key (SECRETS_KMS=vault-transit);
+	// SECRETS_PREVIOUS_KEYS still open values sealed before a rotation
+	if err := configureSecrets(); err != nil {
+		log.Fatal("Invalid secrets configuration: ", err)
+	}
+
+	// Get database connection parameters from environment variables; DATABASE_URL replaces the
+	// individual DB_* parameters, while the DB_SSL* settings apply to both
+	dbConfig := repository.ConnectionConfig{
+		URL:         getEnv("DATABASE_URL", ""),
+		Host:        getEnv("DB_HOST", "localhost"),
+		Port:        getEnv("DB_PORT", "5430"),
+		User:        getEnv("DB_USER", "postgres"),
+		Password:    getEnv("DB_PASSWORD", "password"),
+		Name:        getEnv("DB_NAME", "service_weaver"),
+		SSLMode:     getEnv("DB_SSLMODE", "disable"),
+		SSLRootCert: getEnv("DB_SSLROOTCERT", ""),
+		SSLCert:     getEnv("DB_SSLCERT", ""),
+		SSLKey:      getEnv("DB_SSLKEY", ""),
+	}
+	statementTimeout, err := time.ParseDuration(getEnv("DB_STATEMENT_TIMEOUT", "30s"))
+	if err != nil || statementTimeout < 0 {
+		log.Fatal("Invalid DB_STATEMENT_TIMEOUT: must be a duration, 0 for no timeout")
+	}
+	dbConfig.StatementTimeout = statementTimeout
+	connStr, err := dbConfig.ConnectionString()
+	if err != nil {
+		log.Fatal("Invalid database configuration: ", err)
+	}
 
 	// Initialize repository with PostgreSQL connection string
-	connStr := buildConnectionString(dbHost, dbPort, dbUser, dbPassword, dbName)
-	repo, err := repository.New(connStr)
+	pool, err := loadPoolConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	repo, err := repository.New(connStr, pool)
 	if err != nil {
 		log.Fatal("Failed to initialize repository:", err)
 	}
 	defer repo.Close()
 
+	// Heavy reads go to a read-only replica when DB_REPLICA_URL is set; it shares the DB_SSL* settings
+	if replicaURL := getEnv("DB_REPLICA_URL", ""); replicaURL != "" {
+		replicaConfig := dbConfig
+		replicaConfig.URL = replicaURL
+		replicaConnStr, err := replicaConfig.ConnectionString()
+		if err != nil {
+			log.Fatal("Invalid DB_REPLICA_URL: ", err)
+		}
+		if err := repo.AttachReplica(replicaConnStr, pool); err != nil {
+			log.Fatal("Failed to connect to the database replica: ", err)
+		}
+	}
+
+	// `migrate [up|down [steps]|goto <version>|status]` manages the schema version and exits
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(repo, args[1:]); err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+		return
+	}
+
+	// Pending migrations run at startup unless DB_AUTO_MIGRATE=false, in which case the schema must
+	// already be up to date
+	if getEnv("DB_AUTO_MIGRATE", "true") == "true" {
+		ran, err := repo.Migrate(context.Background(), repository.LatestSchemaVersion())
+		if err != nil {
+			log.Fatal("Failed to migrate database: ", err)
+		}
+		for _, m := range ran {
+			log.Printf("Applied migration %d (%s)", m.Version, m.Name)
+		}
+	} else if err := repo.CheckSchema(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	if err := repo.Prepare(context.Background()); err != nil {
+		log.Fatal("Failed to prepare database: ", err)
+	}
+
+	// `reencrypt-secrets` seals stored secrets again with the current key and exits, after which
+	// the previous keys can be removed
+	if len(args) > 0 && args[0] == "reencrypt-secrets" {
+		if !secrets.Default.Enabled() {
+			log.Fatal(secrets.ErrNoKey)
+		}
+		count, err := repo.ReencryptSecrets(context.Background())
+		if err != nil {
+			log.Fatal("Failed to re-encrypt secrets: ", err)
+		}
+		log.Printf("Re-encrypted %d secrets", count)
+		return
+	}
+
+	// Uploaded service icons are kept in ICON_S3_BUCKET when it is set and under ICON_DIR otherwise;
+	// icons embedded in the services table by earlier versions are moved there
+	iconStore, err := loadIconStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	repository.IconStore = iconStore
+	if moved, err := repo.MoveEmbeddedIcons(context.Background()); err != nil {
+		log.Printf("Failed to move embedded icons to %s: %v", iconStore, err)
+	} else if moved > 0 {
+		log.Printf("Moved %d embedded icons to %s", moved, iconStore)
+	}
+
+	// Initialize healthcheck result retention
+	retentionDays, err := strconv.Atoi(getEnv("RESULTS_RETENTION_DAYS", "30"))
+	if err != nil || retentionDays <= 0 {
+		log.Fatal("Invalid RESULTS_RETENTION_DAYS: must be a positive number of days")
+	}
+	pruneInterval, err := time.ParseDuration(getEnv("RESULTS_PRUNE_INTERVAL", "1h"))
+	if err != nil || pruneInterval <= 0 {
+		log.Fatal("Invalid RESULTS_PRUNE_INTERVAL: must be a positive duration")
+	}
+	pruner := repository.NewRetentionPruner(repo, retentionDays, pruneInterval)
+
+	// Initialize trash purging
+	trashRetentionDays, err := strconv.Atoi(getEnv("TRASH_RETENTION_DAYS", "30"))
+	if err != nil || trashRetentionDays <= 0 {
+		log.Fatal("Invalid TRASH_RETENTION_DAYS: must be a positive number of days")
+	}
+	trashPurgeInterval, err := time.ParseDuration(getEnv("TRASH_PURGE_INTERVAL", "1h"))
+	if err != nil || trashPurgeInterval <= 0 {
+		log.Fatal("Invalid TRASH_PURGE_INTERVAL: must be a positive duration")
+	}
+	trashPurger := repository.NewTrashPurger(repo, trashRetentionDays, trashPurgeInterval)
+	trashPurger.Start()
+	defer trashPurger.Stop()
+
+	// Initialize scheduled backups
+	backupScheduler, err := loadBackupScheduler(repo)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if backupScheduler != nil {
+		backupScheduler.Start()
+		defer backupScheduler.Stop()
+	}
+
+	// Initialize notification dispatcher; failures of a diagram within the group window are sent
+	// together, naming their probable root cause
+	alertGroupWindow, err := time.ParseDuration(getEnv("ALERT_GROUP_WINDOW", "0"))
+	if err != nil || alertGroupWindow < 0 {
+		log.Fatal("Invalid ALERT_GROUP_WINDOW: must be a duration, 0 to send each alert at once")
+	}
+	notifier := notification.NewDispatcher(repo, alertGroupWindow)
+
 	// Initialize healthcheck scheduler
-	scheduler := monitoring.NewHealthcheckScheduler(repo)
+	checkPool, err := loadCheckPool()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Healthcheck results are buffered and inserted in batches
+	var resultBatch monitoring.BatchConfig
+	if resultBatch.Size, err = strconv.Atoi(getEnv("RESULT_BATCH_SIZE", strconv.Itoa(monitoring.DefaultBatchConfig.Size))); err != nil || resultBatch.Size <= 0 {
+		log.Fatal("Invalid RESULT_BATCH_SIZE: must be a positive number of results")
+	}
+	if resultBatch.Interval, err = time.ParseDuration(getEnv("RESULT_FLUSH_INTERVAL", monitoring.DefaultBatchConfig.Interval.String())); err != nil || resultBatch.Interval <= 0 {
+		log.Fatal("Invalid RESULT_FLUSH_INTERVAL: must be a positive duration")
+	}
+	// With leader election, of several instances sharing the database only one runs healthchecks
+	leaderElection, err := strconv.ParseBool(getEnv("SCHEDULER_LEADER_ELECTION", "true"))
+	if err != nil {
+		log.Fatal("Invalid SCHEDULER_LEADER_ELECTION: must be true or false")
+	}
+	// With sharding, they all run healthchecks instead, each for its share of the services
+	sharding, err := strconv.ParseBool(getEnv("SCHEDULER_SHARDING", "false"))
+	if err != nil {
+		log.Fatal("Invalid SCHEDULER_SHARDING: must be true or false")
+	}
+	var leader monitoring.Leader
+	if leaderElection && !sharding {
+		leader = repo.SessionLock(repository.SchedulerLockID)
+	}
+	scheduler := monitoring.NewHealthcheckScheduler(repo, notifier, checkPool, resultBatch, leader)
+	broadcastChecking, err := strconv.ParseBool(getEnv("SCHEDULER_BROADCAST_CHECKING", "true"))
+	if err != nil {
+		log.Fatal("Invalid SCHEDULER_BROADCAST_CHECKING: must be true or false")
+	}
+	scheduler.BroadcastChecking(broadcastChecking)
+	if sharding {
+		scheduler.ShardWith(repo.Membership(monitoring.StallTimeout))
+	}
+	scripts, err := loadScriptConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(scripts.Interpreters) > 0 {
+		scheduler.RunScriptsWith(scripts)
+		api.ScriptInterpreters = scripts.Interpreters
+	}
+	repo.OnServicesChanged(scheduler.ServicesChanged)
+	resultStream, err := loadResultStream()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if resultStream != nil {
+		scheduler.ExportTo(resultStream)
+		resultStream.Start()
+		defer resultStream.Stop()
+	}
+	// Reload service definitions as soon as another instance changes them, not only on the periodic resync
+	if changes, err := repo.ListenForChanges(connStr); err != nil {
+		log.Printf("Failed to listen for service changes, relying on the periodic reload: %v", err)
+	} else {
+		defer changes.Close()
+	}
 	scheduler.Start()
 	defer scheduler.Stop()
+	// Pruning and rolling up results is left to the instance leading the healthchecks
+	pruner.RunWhen(scheduler.Leading)
+	pruner.Start()
+	defer pruner.Stop()
+
+	// Initialize scheduled report delivery
+	reportInterval, err := time.ParseDuration(getEnv("REPORTS_CHECK_INTERVAL", "5m"))
+	if err != nil || reportInterval <= 0 {
+		log.Fatal("Invalid REPORTS_CHECK_INTERVAL: must be a positive duration")
+	}
+	reporter := reports.NewScheduler(repo, notifier, reportInterval)
+	reporter.Start()
+	defer reporter.Stop()
+
+	// Initialize latency anomaly detection
+	anomalyInterval, err := time.ParseDuration(getEnv("ANOMALY_CHECK_INTERVAL", "5m"))
+	if err != nil || anomalyInterval < 0 {
+		log.Fatal("Invalid ANOMALY_CHECK_INTERVAL: must be a duration, 0 to disable")
+	}
+	anomalyThreshold, err := strconv.ParseFloat(getEnv("ANOMALY_THRESHOLD", "3"), 64)
+	if err != nil || anomalyThreshold <= 0 {
+		log.Fatal("Invalid ANOMALY_THRESHOLD: must be a positive number of standard deviations")
+	}
+	if anomalyInterval > 0 {
+		detector := anomaly.NewDetector(repo, anomalyInterval, anomalyThreshold)
+		detector.Start()
+		defer detector.Stop()
+	}
+
+	// Initialize service discovery sync
+	discoveryInterval, err := time.ParseDuration(getEnv("DISCOVERY_CHECK_INTERVAL", "1m"))
+	if err != nil || discoveryInterval <= 0 {
+		log.Fatal("Invalid DISCOVERY_CHECK_INTERVAL: must be a positive duration")
+	}
+	syncer := discovery.NewSyncer(repo, discoveryInterval)
+	syncer.Start()
+	defer syncer.Stop()
+
+	// Initialize webhook delivery of configuration changes
+	webhookDispatcher := webhooks.NewDispatcher(repo)
+	webhookDispatcher.Start()
+	defer webhookDispatcher.Stop()
+
+	// Token signing: HS256 with JWT_SECRET, or RS256/EdDSA with JWT_SIGNING_KEY_FILE. Keys from
+	// JWT_PREVIOUS_KEY_FILES still verify tokens after a rotation; SIGHUP reloads all key files.
+	if secret := getEnv("JWT_SECRET", ""); secret != "" {
+		middleware.JwtKey = []byte(secret)
+	}
+	loadSigningKeys := func() error {
+		return middleware.Keys.Load(getEnv("JWT_SIGNING_KEY_FILE", ""), strings.FieldsFunc(getEnv("JWT_PREVIOUS_KEY_FILES", ""), func(r rune) bool {
+			return r == ','
+		}))
+	}
+	if err := loadSigningKeys(); err != nil {
+		log.Fatal("Invalid JWT signing keys: ", err)
+	}
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := loadSigningKeys(); err != nil {
+				log.Printf("Failed to reload JWT signing keys, keeping the current ones: %v", err)
+				continue
+			}
+			log.Println("Reloaded JWT signing keys")
+		}
+	}()
+
+	// Token lifetimes: short-lived access tokens renewed through rotating refresh tokens
+	for _, ttl := range []struct {
+		env   string
+		value *time.Duration
+	}{
+		{"ACCESS_TOKEN_TTL", &middleware.AccessTokenTTL},
+		{"REFRESH_TOKEN_TTL", &middleware.RefreshTokenTTL},
+		{"REMEMBER_ME_TTL", &middleware.RememberMeTTL},
+		{"INVITATION_TTL", &middleware.InvitationTTL},
+	} {
+		d, err := time.ParseDuration(getEnv(ttl.env, ttl.value.String()))
+		if err != nil || d <= 0 {
+			log.Fatalf("Invalid %s: must be a positive duration", ttl.env)
+		}
+		*ttl.value = d
+	}
+
+	// Links in invitation emails open the frontend here
+	api.PublicURL = getEnv("PUBLIC_URL", "")
+
+	// Alertmanager alerts apply to the services their labels match
+	alertLabels, err := api.ParseAlertLabelMapping(getEnv("ALERTMANAGER_LABELS", "sw_service_id=id,service=name"))
+	if err != nil {
+		log.Fatal("Invalid ALERTMANAGER_LABELS: ", err)
+	}
+	api.AlertmanagerLabels = alertLabels
+
+	// Account lockout after repeated failed logins
+	lockoutThreshold, err := strconv.Atoi(getEnv("LOCKOUT_THRESHOLD", strconv.Itoa(middleware.LockoutThreshold)))
+	if err != nil || lockoutThreshold < 0 {
+		log.Fatal("Invalid LOCKOUT_THRESHOLD: must be a number of failed logins, or 0 to disable lockout")
+	}
+	middleware.LockoutThreshold = lockoutThreshold
+	for _, setting := range []struct {
+		env   string
+		value *time.Duration
+	}{
+		{"LOCKOUT_WINDOW", &middleware.LockoutWindow},
+		{"LOCKOUT_DURATION", &middleware.LockoutDuration},
+		{"LOCKOUT_MAX_DURATION", &middleware.LockoutMaxDuration},
+	} {
+		d, err := time.ParseDuration(getEnv(setting.env, setting.value.String()))
+		if err != nil || d <= 0 {
+			log.Fatalf("Invalid %s: must be a positive duration", setting.env)
+		}
+		*setting.value = d
+	}
+
+	// Initialize OpenID Connect single sign-on
+	roleMapping, err := oidc.ParseRoleMapping(getEnv("OIDC_ROLE_MAPPING", ""))
+	if err != nil {
+		log.Fatal("Invalid OIDC_ROLE_MAPPING: ", err)
+	}
+	sso, err := oidc.New(oidc.Config{
+		Issuer:            getEnv("OIDC_ISSUER", ""),
+		ClientID:          getEnv("OIDC_CLIENT_ID", ""),
+		ClientSecret:      getEnv("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:       getEnv("OIDC_REDIRECT_URL", ""),
+		PostLoginURL:      getEnv("OIDC_POST_LOGIN_URL", "/"),
+		Scopes:            strings.Fields(getEnv("OIDC_SCOPES", "openid profile email")),
+		DisplayName:       getEnv("OIDC_DISPLAY_NAME", ""),
+		UsernameClaim:     getEnv("OIDC_USERNAME_CLAIM", "preferred_username"),
+		RoleClaim:         getEnv("OIDC_ROLE_CLAIM", ""),
+		RoleMapping:       roleMapping,
+		DefaultRole:       models.UserRole(getEnv("OIDC_DEFAULT_ROLE", string(models.RoleViewer))),
+		LinkExistingUsers: getEnv("OIDC_LINK_EXISTING_USERS", "false") == "true",
+	})
+	if err != nil {
+		log.Fatal("Invalid OIDC configuration: ", err)
+	}
+
+	// Rate limiting, shared between instances through Redis when RATE_LIMIT_REDIS_URL is set
+	var rateLimits middleware.RateLimitStore = middleware.NewMemoryRateLimitStore()
+	if redisURL := getEnv("RATE_LIMIT_REDIS_URL", ""); redisURL != "" {
+		store, err := middleware.NewRedisRateLimitStore(redisURL)
+		if err != nil {
+			log.Fatal("Failed to connect to the rate limit Redis: ", err)
+		}
+		defer store.Close()
+		rateLimits = store
+	}
+	limits := make(map[string]*middleware.RateLimit)
+	for env, value := range map[string]string{"RATE_LIMIT": "300/m", "USER_RATE_LIMIT": "600/m", "LOGIN_RATE_LIMIT": "10/m"} {
+		limit, err := middleware.ParseRateLimit(getEnv(env, value))
+		if err != nil {
+			log.Fatalf("Invalid %s: %v", env, err)
+		}
+		limits[env] = limit
+	}
+	loginLimit := middleware.RateLimitByIP(rateLimits, "login", limits["LOGIN_RATE_LIMIT"])
 
 	// Initialize handlers
-	handlers := api.NewHandlers(repo, scheduler)
+	handlers := api.NewHandlers(repo, scheduler, notifier, pruner, reporter, syncer, webhookDispatcher, sso)
+	handlers.AllowWebSocketOrigins(strings.FieldsFunc(getEnv("WS_ALLOWED_ORIGINS", ""), func(r rune) bool { return r == ',' }))
 
 	// Setup Gin router
 	r := gin.Default()
+	// Client IPs drive rate limits, login throttling and the audit log, so X-Forwarded-For is only
+	// believed from the reverse proxies named in TRUSTED_PROXIES
+	if err := r.SetTrustedProxies(strings.FieldsFunc(getEnv("TRUSTED_PROXIES", ""), func(r rune) bool { return r == ',' || r == ' ' })); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	r.Use(otelgin.Middleware(serviceName))
 
 	// CORS middleware
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.OrganizationHeader},
+		ExposeHeaders:    []string{"X-Total-Count", "Retry-After"},
 		AllowCredentials: true,
 	}))
 
 	// WebSocket endpoint
 	r.GET("/ws", handlers.HandleWebSocket)
 
+	// Public keys access tokens are signed with, for verification by other services
+	r.GET("/.well-known/jwks.json", handlers.JWKS)
+
+	// Liveness and readiness probes for load balancers and orchestrators
+	r.GET("/healthz", handlers.Healthz)
+	r.GET("/readyz", handlers.Readyz)
+
+	// Pings of heartbeat services, compatible with healthchecks.io
+	ping := r.Group("/ping", middleware.RateLimitByIP(rateLimits, "ping", limits["RATE_LIMIT"]))
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodHead} {
+		ping.Handle(method, "/:key", handlers.Ping)
+		ping.Handle(method, "/:key/:signal", handlers.Ping)
+	}
+
+	// Prometheus metrics endpoint; it names the services of every organization, so it is only served
+	// with a bearer token to protect it
+	if token := getEnv("METRICS_TOKEN", ""); token != "" {
+		r.GET("/metrics", middleware.RequireBearerToken(token), handlers.Metrics)
+	}
+
+	// Grafana JSON datasource, protected by the same token and likewise only served with one
+	if token := getEnv("METRICS_TOKEN", ""); token != "" {
+		grafana := r.Group("/grafana", middleware.RequireBearerToken(token))
+		grafana.GET("/", handlers.GrafanaTest)
+		grafana.POST("/search", handlers.GrafanaSearch)
+		grafana.POST("/query", handlers.GrafanaQuery)
+		grafana.POST("/annotations", handlers.GrafanaAnnotations)
+	}
+
 	// API routes
 	api := r.Group("/api")
+	api.Use(middleware.RateLimitByIP(rateLimits, "api", limits["RATE_LIMIT"]))
 	{
 		// Authentication routes (no auth required)
-		api.POST("/login", handlers.Login)
-		api.POST("/first-run-admin", handlers.FirstRunAdmin)
+		api.POST("/login", loginLimit, handlers.Login)
+		api.POST("/token/refresh", handlers.RefreshToken)
+		api.GET("/auth/oidc", handlers.GetOIDCConfig)
+		api.GET("/auth/oidc/login", handlers.OIDCLogin)
+		api.GET("/auth/oidc/callback", handlers.OIDCCallback)
+		api.POST("/first-run-admin", loginLimit, handlers.FirstRunAdmin)
+		api.GET("/invitations/accept", loginLimit, handlers.GetInvitation)
+		api.POST("/invitations/accept", loginLimit, handlers.AcceptInvitation)
 
-		// Public monitoring routes (no auth required for read-only access)
-		public := api.Group("/")
+		// Prometheus Alertmanager webhook receiver, only enabled with a token to authenticate it
+		if token := getEnv("ALERTMANAGER_TOKEN", ""); token != "" {
+			api.POST("/alertmanager", middleware.RequireBearerToken(token), handlers.ReceiveAlertmanager)
+		}
+
+		// Deployment pipelines registering their services, authenticated with a registration token
+		api.POST("/register", handlers.RegisterService)
+
+		// OpenAPI description of every route
+		api.GET("/openapi.json", openapi.Handler(r.Routes))
+
+		// Types of the messages sent over /ws
+		api.GET("/ws/message-types", handlers.GetWebSocketMessageTypes)
+
+		// Public monitoring routes: public diagrams need no auth, private ones a member's token
+		public := api.Group("/", middleware.OptionalAuth(repo))
 		{
 			// Public diagram access for monitoring
 			public.GET("/diagrams/:id", handlers.GetDiagram)
+			public.GET("/diagrams/:id/snapshot.svg", handlers.GetDiagramSnapshotSVG)
+			public.GET("/diagrams/:id/snapshot.png", handlers.GetDiagramSnapshotPNG)
+			public.GET("/diagrams/:id/thumbnail", handlers.GetDiagramThumbnail)
+			public.GET("/services/:id/icon", handlers.GetServiceIcon)
 			public.GET("/services/diagram/:diagramId", handlers.GetServices)
 			public.GET("/connections/diagram/:diagramId", handlers.GetConnections)
+			public.GET("/status-pages/:slug", handlers.GetStatusPage)
 		}
 
 		// Protected routes (require authentication)
 		protected := api.Group("/")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.AuthMiddleware(repo), middleware.RateLimitByUser(rateLimits, "api", limits["USER_RATE_LIMIT"]), middleware.Organization(repo), middleware.Audit(repo, webhookDispatcher))
 		{
 			// User routes
 			protected.GET("/user/me", handlers.GetCurrentUser)
+			protected.PUT("/user/me", handlers.UpdateProfile)
+			protected.POST("/user/me/password", handlers.ChangePassword)
+			protected.GET("/user/me/notification-preferences", handlers.GetNotificationPreferences)
+			protected.PUT("/user/me/notification-preferences", handlers.UpdateNotificationPreferences)
+			protected.POST("/logout", handlers.Logout)
+			protected.GET("/user/sessions", handlers.GetSessions)
+			protected.DELETE("/user/sessions/:id", handlers.RevokeSession)
+			protected.GET("/search", handlers.Search)
+			protected.GET("/errors", handlers.SearchErrors)
+
+			// Organization routes
+			protected.GET("/organizations", handlers.GetOrganizations)
+			protected.GET("/organizations/:id/members", handlers.GetOrganizationMembers)
 
 			// Admin-only routes
 			admin := protected.Group("/")
@@ -83,44 +574,536 @@ func main() {
 				admin.GET("/users", handlers.GetUsers)
 				admin.PUT("/users/:id", handlers.UpdateUser)
 				admin.DELETE("/users/:id", handlers.DeleteUser)
+				admin.POST("/users/:id/revoke-sessions", handlers.RevokeUserSessions)
+				admin.GET("/users/:id/sessions", handlers.GetUserSessions)
+				admin.DELETE("/users/:id/sessions/:sessionId", handlers.RevokeUserSession)
+				admin.POST("/users/:id/unlock", handlers.UnlockUser)
+				admin.POST("/invitations", handlers.CreateInvitation)
+				admin.GET("/invitations", handlers.GetInvitations)
+				admin.POST("/invitations/:id/resend", handlers.ResendInvitation)
+				admin.DELETE("/invitations/:id", handlers.DeleteInvitation)
+
+				// Organization management routes (admin only)
+				admin.POST("/organizations", handlers.CreateOrganization)
+				admin.DELETE("/organizations/:id", handlers.DeleteOrganization)
+
+				// Notification channel routes (admin only)
+				admin.POST("/notification-channels", handlers.CreateNotificationChannel)
+				admin.GET("/notification-channels", handlers.GetNotificationChannels)
+				admin.PUT("/notification-channels/:id", handlers.UpdateNotificationChannel)
+				admin.DELETE("/notification-channels/:id", handlers.DeleteNotificationChannel)
+				admin.POST("/notification-channels/:id/test", handlers.TestNotificationChannel)
+
+				// Healthcheck result retention routes (admin only)
+				admin.GET("/admin/results/stats", handlers.GetResultsStats)
+				admin.POST("/admin/results/prune", handlers.PruneResults)
+
+				// Scheduled report routes
+				admin.POST("/reports", handlers.CreateReport)
+				admin.GET("/reports", handlers.GetReports)
+				admin.PUT("/reports/:id", handlers.UpdateReport)
+				admin.DELETE("/reports/:id", handlers.DeleteReport)
+				admin.GET("/reports/:id/preview", handlers.PreviewReport)
+				admin.POST("/reports/:id/send", handlers.SendReport)
+
+				// System health and the effective configuration
+				admin.GET("/system/health", handlers.GetSystemHealth)
+				admin.GET("/system/scheduler", handlers.GetSchedulerStats)
+				admin.GET("/system/config", config.Handler(cfg))
+
+				// Check script routes; scripts run on the server, so only admins upload them
+				admin.POST("/check-scripts", handlers.CreateCheckScript)
+				admin.PUT("/check-scripts/:id", handlers.UpdateCheckScript)
+				admin.DELETE("/check-scripts/:id", handlers.DeleteCheckScript)
+
+				// Environment routes
+				admin.POST("/environments", handlers.CreateEnvironment)
+				admin.PUT("/environments/:id", handlers.UpdateEnvironment)
+				admin.DELETE("/environments/:id", handlers.DeleteEnvironment)
+
+				// Backup routes
+				admin.GET("/backup", handlers.GetBackup)
+				admin.POST("/restore", handlers.RestoreBackup)
+
+				// Audit log routes
+				admin.GET("/audit-log", handlers.GetAuditLog)
+				admin.GET("/security-events", handlers.GetSecurityEvents)
+				admin.GET("/security-events/export", handlers.ExportSecurityEvents)
+
+				// Service discovery routes
+				admin.POST("/discovery-sources", handlers.CreateDiscoverySource)
+				admin.GET("/discovery-sources", handlers.GetDiscoverySources)
+				admin.PUT("/discovery-sources/:id", handlers.UpdateDiscoverySource)
+				admin.DELETE("/discovery-sources/:id", handlers.DeleteDiscoverySource)
+				admin.GET("/discovery-sources/:id/preview", handlers.PreviewDiscoverySource)
+				admin.POST("/discovery-sources/:id/sync", handlers.SyncDiscoverySource)
+
+				// Webhook routes
+				admin.POST("/webhooks", handlers.CreateWebhook)
+				admin.GET("/webhooks", handlers.GetWebhooks)
+				admin.GET("/webhooks/:id", handlers.GetWebhook)
+				admin.PUT("/webhooks/:id", handlers.UpdateWebhook)
+				admin.DELETE("/webhooks/:id", handlers.DeleteWebhook)
+				admin.POST("/webhooks/:id/test", handlers.TestWebhook)
+			}
+
+			// Organization admin routes
+			orgAdmin := protected.Group("/")
+			orgAdmin.Use(middleware.RequireOrganizationAdmin())
+			{
+				orgAdmin.PUT("/organizations/:id", handlers.UpdateOrganization)
+				orgAdmin.PUT("/organizations/:id/members/:userId", handlers.SetOrganizationMember)
+				orgAdmin.DELETE("/organizations/:id/members/:userId", handlers.RemoveOrganizationMember)
+
+				// Registration tokens of deployment pipelines
+				orgAdmin.POST("/registration-tokens", handlers.CreateRegistrationToken)
+				orgAdmin.GET("/registration-tokens", handlers.GetRegistrationTokens)
+				orgAdmin.DELETE("/registration-tokens/:id", handlers.DeleteRegistrationToken)
+
+				// Folder management routes
+				orgAdmin.POST("/folders", handlers.CreateFolder)
+				orgAdmin.PUT("/folders/:id", handlers.UpdateFolder)
+				orgAdmin.DELETE("/folders/:id", handlers.DeleteFolder)
 			}
 
 			// Diagram routes
 			protected.POST("/diagrams", handlers.CreateDiagram)
+			protected.POST("/diagrams/import", handlers.ImportDiagram)
+			protected.POST("/import/uptime-kuma", handlers.ImportUptimeKuma)
+			protected.POST("/import/uptime-robot", handlers.ImportUptimeRobot)
 			protected.GET("/diagrams", handlers.GetDiagrams)
 			protected.PUT("/diagrams/:id", handlers.UpdateDiagram)
+			protected.PATCH("/diagrams/:id", handlers.PatchDiagram)
 			protected.DELETE("/diagrams/:id", handlers.DeleteDiagram)
 			protected.POST("/diagrams/:id/positions", handlers.SavePositions)
+			protected.GET("/diagrams/:id/uptime", handlers.GetDiagramUptime)
+			protected.PUT("/diagrams/:id/status-page", handlers.UpdateStatusPage)
+			protected.GET("/diagrams/:id/events", handlers.GetDiagramEvents)
+			protected.GET("/diagrams/:id/activity", handlers.GetDiagramActivity)
+			protected.POST("/diagrams/:id/star", handlers.StarDiagram)
+			protected.DELETE("/diagrams/:id/star", handlers.UnstarDiagram)
+			protected.GET("/diagrams/:id/versions", handlers.GetDiagramVersions)
+			protected.GET("/diagrams/:id/versions/diff", handlers.DiffDiagramVersions)
+			protected.GET("/diagrams/:id/versions/:version", handlers.GetDiagramVersion)
+			protected.POST("/diagrams/:id/rollback/:version", handlers.RollbackDiagram)
+			protected.POST("/diagrams/:id/restore", handlers.RestoreDiagram)
+			protected.PUT("/diagrams/:id/folder", handlers.MoveDiagram)
+			protected.PUT("/diagrams/:id/service-defaults", handlers.UpdateServiceDefaults)
+			protected.GET("/diagrams/:id/overlays", handlers.GetDiagramOverlays)
 
 			// Service routes
+			protected.GET("/services", handlers.ListServices)
 			protected.POST("/services", handlers.CreateService)
+			protected.POST("/services/bulk", handlers.CreateServicesBulk)
+			protected.PUT("/services/bulk", handlers.UpdateServicesBulk)
+			protected.POST("/services/copy", handlers.CopyServicesBulk)
+			protected.POST("/services/move", handlers.MoveServicesBulk)
+			protected.POST("/services/delete", handlers.DeleteServicesBulk)
+			protected.POST("/services/pause", handlers.PauseServicesBulk)
+			protected.POST("/services/resume", handlers.ResumeServicesBulk)
 			protected.PUT("/services/:id", handlers.UpdateService)
+			protected.PATCH("/services/:id", handlers.PatchService)
 			protected.DELETE("/services/:id", handlers.DeleteService)
 			protected.POST("/services/:id/icon", handlers.UploadServiceIcon)
+			protected.POST("/services/:id/restore", handlers.RestoreService)
+			protected.POST("/services/:id/copy", handlers.CopyService)
+			protected.POST("/services/:id/clone", handlers.CloneService)
+			protected.GET("/services/:id/heartbeat", handlers.GetServiceHeartbeat)
+			protected.PUT("/services/:id/heartbeat", handlers.SetServiceHeartbeat)
+			protected.GET("/services/:id/script", handlers.GetServiceScript)
+			protected.PUT("/services/:id/script", handlers.SetServiceScript)
+			protected.GET("/services/:id/overlays", handlers.GetServiceOverlays)
+			protected.PUT("/services/:id/overlays/:environment", handlers.SetServiceOverlay)
+			protected.DELETE("/services/:id/overlays/:environment", handlers.DeleteServiceOverlay)
+			protected.GET("/check-scripts", handlers.GetCheckScripts)
+			protected.GET("/environments", handlers.GetEnvironments)
+			protected.POST("/services/:id/move", handlers.MoveService)
+			protected.GET("/services/:id/uptime", handlers.GetServiceUptime)
+			protected.GET("/services/:id/metrics", handlers.GetServiceMetrics)
+			protected.GET("/services/:id/results", handlers.GetServiceResults)
+			protected.GET("/services/:id/rollups", handlers.GetServiceRollups)
+			protected.GET("/services/:id/heatmap", handlers.GetServiceHeatmap)
+			protected.GET("/services/:id/events", handlers.GetServiceEvents)
+			protected.GET("/services/:id/stats", handlers.GetServiceStats)
+			protected.GET("/services/:id/forecast", handlers.GetServiceForecast)
+
+			// Tag routes
+			protected.GET("/tags", handlers.GetTags)
+			protected.POST("/tags/:tag/actions", handlers.ApplyTagAction)
+
+			// Export routes
+			protected.GET("/export/results", handlers.ExportResults)
+			protected.GET("/export/uptime", handlers.ExportUptime)
+			protected.GET("/export/diagrams/:id", handlers.ExportDiagram)
+
+			// Maintenance window routes
+			protected.POST("/maintenance-windows", handlers.CreateMaintenanceWindow)
+			protected.GET("/maintenance-windows", handlers.GetMaintenanceWindows)
+			protected.DELETE("/maintenance-windows/:id", handlers.DeleteMaintenanceWindow)
+
+			// Incident routes
+			protected.POST("/diagrams/:id/incidents", handlers.CreateIncident)
+			protected.GET("/diagrams/:id/incidents", handlers.GetIncidents)
+			protected.PUT("/incidents/:id", handlers.UpdateIncident)
+			protected.DELETE("/incidents/:id", handlers.DeleteIncident)
+			protected.GET("/incidents/:id/root-cause", handlers.GetIncidentRootCause)
 
 			// Connection routes
 			protected.POST("/connections", handlers.CreateConnection)
 			protected.PUT("/connections/:id", handlers.UpdateConnection)
 			protected.DELETE("/connections/:id", handlers.DeleteConnection)
+
+			// Folder routes
+			protected.GET("/folders", handlers.GetFolders)
+
+			// Trash routes
+			protected.GET("/trash", handlers.GetTrash)
+		}
+	}
+
+	// gRPC API for programmatic integrations
+	if grpcAddr := getEnv("GRPC_ADDR", ":9090"); grpcAddr != "" {
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatal("Failed to listen for gRPC:", err)
 		}
+		grpcServer := grpcapi.NewServer(repo, scheduler, webhookDispatcher)
+		go func() {
+			log.Printf("gRPC server starting on %s", grpcAddr)
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Printf("gRPC server stopped: %v", err)
+			}
+		}()
+		defer grpcServer.GracefulStop()
 	}
 
-	log.Println("Server starting on :8080")
-	if err := r.Run(":8080"); err != nil {
+	// HTTPS with TLS_CERT_FILE and TLS_KEY_FILE, reloaded on SIGHUP, or with certificates from
+	// Let's Encrypt for TLS_AUTOCERT_DOMAINS
+	tlsConfig := server.TLSConfig{
+		CertFile:         getEnv("TLS_CERT_FILE", ""),
+		KeyFile:          getEnv("TLS_KEY_FILE", ""),
+		AutocertDomains:  strings.FieldsFunc(getEnv("TLS_AUTOCERT_DOMAINS", ""), func(r rune) bool { return r == ',' }),
+		AutocertCacheDir: getEnv("TLS_AUTOCERT_CACHE_DIR", "data/autocert"),
+		AutocertEmail:    getEnv("TLS_AUTOCERT_EMAIL", ""),
+	}
+	srv, err := server.New(listenAddr(), r, tlsConfig, getEnv("HTTP_REDIRECT_ADDR", ""))
+	if err != nil {
+		log.Fatal("Invalid TLS configuration: ", err)
+	}
+	reloadCert := make(chan os.Signal, 1)
+	signal.Notify(reloadCert, syscall.SIGHUP)
+	go func() {
+		for range reloadCert {
+			if err := srv.ReloadCertificate(); err != nil {
+				log.Printf("Failed to reload the TLS certificate, keeping the current one: %v", err)
+			}
+		}
+	}()
+	if err := srv.ListenAndServe(); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
 }
 
+// listenAddr is the address of the HTTP API: HTTP_ADDR, or all interfaces on PORT as set by
+// platforms that assign the port
+func listenAddr() string {
+	if addr, ok := os.LookupEnv("HTTP_ADDR"); ok {
+		return addr
+	}
+	if port := getEnv("PORT", ""); port != "" {
+		return ":" + port
+	}
+	return ":8080"
+}
+
 // Helper function to get environment variable with default value
 func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
+	if value, exists := config.LookupEnv(key); exists {
 		return value
 	}
 	return defaultValue
 }
 
-// Helper function to build PostgreSQL connection string
-func buildConnectionString(host, port, user, password, dbname string) string {
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
+// loadPoolConfig reads the database connection pool limits. Open connections are capped by default,
+// as unlimited connections exhaust max_connections of Postgres under heavy result writes.
+func loadPoolConfig() (repository.PoolConfig, error) {
+	var pool repository.PoolConfig
+	var err error
+	if pool.MaxOpenConns, err = strconv.Atoi(getEnv("DB_MAX_OPEN_CONNS", "25")); err != nil || pool.MaxOpenConns < 0 {
+		return pool, errors.New("Invalid DB_MAX_OPEN_CONNS: must be a number of connections, 0 for no limit")
+	}
+	if pool.MaxIdleConns, err = strconv.Atoi(getEnv("DB_MAX_IDLE_CONNS", "10")); err != nil || pool.MaxIdleConns < 0 {
+		return pool, errors.New("Invalid DB_MAX_IDLE_CONNS: must be a number of connections")
+	}
+	if pool.MaxOpenConns > 0 && pool.MaxIdleConns > pool.MaxOpenConns {
+		return pool, errors.New("Invalid DB_MAX_IDLE_CONNS: must not exceed DB_MAX_OPEN_CONNS")
+	}
+	if pool.ConnMaxLifetime, err = time.ParseDuration(getEnv("DB_CONN_MAX_LIFETIME", "30m")); err != nil || pool.ConnMaxLifetime < 0 {
+		return pool, errors.New("Invalid DB_CONN_MAX_LIFETIME: must be a duration, 0 to keep connections forever")
+	}
+	if pool.ConnMaxIdleTime, err = time.ParseDuration(getEnv("DB_CONN_MAX_IDLE_TIME", "5m")); err != nil || pool.ConnMaxIdleTime < 0 {
+		return pool, errors.New("Invalid DB_CONN_MAX_IDLE_TIME: must be a duration, 0 to keep idle connections forever")
+	}
+	return pool, nil
+}
+
+// loadCheckPool reads the size of the worker pool running healthchecks and the per-host and
+// per-method limits, the latter given as SCHEDULER_TYPE_LIMITS=ICMP=10,KAFKA=5
+func loadCheckPool() (monitoring.PoolConfig, error) {
+	var pool monitoring.PoolConfig
+	var err error
+	if pool.Workers, err = strconv.Atoi(getEnv("SCHEDULER_WORKERS", strconv.Itoa(monitoring.DefaultPoolConfig.Workers))); err != nil || pool.Workers <= 0 {
+		return pool, errors.New("Invalid SCHEDULER_WORKERS: must be a positive number of workers")
+	}
+	if pool.QueueSize, err = strconv.Atoi(getEnv("SCHEDULER_QUEUE_SIZE", strconv.Itoa(monitoring.DefaultPoolConfig.QueueSize))); err != nil || pool.QueueSize <= 0 {
+		return pool, errors.New("Invalid SCHEDULER_QUEUE_SIZE: must be a positive number of checks")
+	}
+	if pool.HostLimit, err = strconv.Atoi(getEnv("SCHEDULER_HOST_LIMIT", "0")); err != nil || pool.HostLimit < 0 {
+		return pool, errors.New("Invalid SCHEDULER_HOST_LIMIT: must be a number of checks, 0 for no limit")
+	}
+	pool.TypeLimits = make(map[string]int)
+	for _, entry := range strings.Split(getEnv("SCHEDULER_TYPE_LIMITS", ""), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		method, value, _ := strings.Cut(entry, "=")
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit <= 0 || strings.TrimSpace(method) == "" {
+			return pool, errors.New("Invalid SCHEDULER_TYPE_LIMITS: must be comma-separated METHOD=limit pairs")
+		}
+		pool.TypeLimits[strings.ToUpper(strings.TrimSpace(method))] = limit
+	}
+	return pool, nil
+}
+
+// loadScriptConfig reads the sandbox of check scripts, which are disabled unless
+// SCRIPT_INTERPRETERS allows interpreters, such as SCRIPT_INTERPRETERS=sh,python3
+func loadScriptConfig() (monitoring.ScriptConfig, error) {
+	var config monitoring.ScriptConfig
+	var err error
+	for _, entry := range strings.Split(getEnv("SCRIPT_INTERPRETERS", ""), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			config.Interpreters = append(config.Interpreters, entry)
+		}
+	}
+	if config.User = getEnv("SCRIPT_USER", "nobody"); config.User == "" || config.User == "root" {
+		return config, errors.New("Invalid SCRIPT_USER: must be an unprivileged account")
+	}
+	if config.MemoryLimit, err = strconv.Atoi(getEnv("SCRIPT_MEMORY_LIMIT", "256")); err != nil || config.MemoryLimit <= 0 {
+		return config, errors.New("Invalid SCRIPT_MEMORY_LIMIT: must be a positive number of megabytes")
+	}
+	return config, nil
+}
+
+// loadBackupScheduler reads the scheduled backup settings. Backups are off unless BACKUP_INTERVAL
+// is set; they go to BACKUP_S3_BUCKET when it is set and to BACKUP_DIR otherwise.
+func loadBackupScheduler(repo *repository.Repository) (*backup.Scheduler, error) {
+	if getEnv("BACKUP_INTERVAL", "") == "" {
+		return nil, nil
+	}
+	interval, err := time.ParseDuration(getEnv("BACKUP_INTERVAL", ""))
+	if err != nil || interval <= 0 {
+		return nil, errors.New("Invalid BACKUP_INTERVAL: must be a positive duration")
+	}
+	includeSecrets, err := strconv.ParseBool(getEnv("BACKUP_INCLUDE_SECRETS", "false"))
+	if err != nil {
+		return nil, errors.New("Invalid BACKUP_INCLUDE_SECRETS: must be true or false")
+	}
+
+	var target backup.Target
+	if bucket := getEnv("BACKUP_S3_BUCKET", ""); bucket != "" {
+		s3 := backup.S3{
+			Endpoint:        getEnv("BACKUP_S3_ENDPOINT", ""),
+			Region:          getEnv("BACKUP_S3_REGION", "us-east-1"),
+			Bucket:          bucket,
+			Prefix:          getEnv("BACKUP_S3_PREFIX", ""),
+			AccessKeyID:     getEnv("BACKUP_S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnv("BACKUP_S3_SECRET_ACCESS_KEY", ""),
+		}
+		if s3.Endpoint == "" {
+			s3.Endpoint = "https://s3." + s3.Region + ".amazonaws.com"
+		}
+		if s3.AccessKeyID == "" || s3.SecretAccessKey == "" {
+			return nil, errors.New("Invalid BACKUP_S3_BUCKET: BACKUP_S3_ACCESS_KEY_ID and BACKUP_S3_SECRET_ACCESS_KEY are required")
+		}
+		target = s3
+	} else {
+		keep, err := strconv.Atoi(getEnv("BACKUP_KEEP", "7"))
+		if err != nil || keep < 0 {
+			return nil, errors.New("Invalid BACKUP_KEEP: must be a number of backups, 0 to keep all")
+		}
+		target = backup.Dir{Path: getEnv("BACKUP_DIR", "data/backups"), Keep: keep}
+	}
+	return backup.NewScheduler(repo, target, interval, includeSecrets), nil
+}
+
+// loadIconStore reads where uploaded service icons are kept: ICON_S3_BUCKET when it is set,
+// ICON_DIR otherwise
+func loadIconStore() (blobstore.Store, error) {
+	bucket := getEnv("ICON_S3_BUCKET", "")
+	if bucket == "" {
+		return blobstore.Dir{Path: getEnv("ICON_DIR", "data/icons")}, nil
+	}
+	s3 := blobstore.S3{
+		Endpoint:        getEnv("ICON_S3_ENDPOINT", ""),
+		Region:          getEnv("ICON_S3_REGION", "us-east-1"),
+		Bucket:          bucket,
+		Prefix:          getEnv("ICON_S3_PREFIX", ""),
+		AccessKeyID:     getEnv("ICON_S3_ACCESS_KEY_ID", ""),
+		SecretAccessKey: getEnv("ICON_S3_SECRET_ACCESS_KEY", ""),
+	}
+	if s3.Endpoint == "" {
+		s3.Endpoint = "https://s3." + s3.Region + ".amazonaws.com"
+	}
+	if s3.AccessKeyID == "" || s3.SecretAccessKey == "" {
+		return nil, errors.New("Invalid ICON_S3_BUCKET: ICON_S3_ACCESS_KEY_ID and ICON_S3_SECRET_ACCESS_KEY are required")
+	}
+	return s3, nil
+}
+
+// loadResultStream reads where healthcheck results and status transitions are published. Nothing is
+// published unless RESULT_STREAM_KAFKA_BROKERS or RESULT_STREAM_NATS_URL is set.
+func loadResultStream() (*stream.Exporter, error) {
+	brokers := getEnv("RESULT_STREAM_KAFKA_BROKERS", "")
+	natsURL := getEnv("RESULT_STREAM_NATS_URL", "")
+	topic := getEnv("RESULT_STREAM_TOPIC", "service-weaver.results")
+
+	var publisher stream.Publisher
+	var err error
+	switch {
+	case brokers != "" && natsURL != "":
+		return nil, errors.New("Invalid RESULT_STREAM_NATS_URL: set either RESULT_STREAM_KAFKA_BROKERS or RESULT_STREAM_NATS_URL")
+	case brokers != "":
+		useTLS, err := strconv.ParseBool(getEnv("RESULT_STREAM_KAFKA_TLS", "false"))
+		if err != nil {
+			return nil, errors.New("Invalid RESULT_STREAM_KAFKA_TLS: must be true or false")
+		}
+		publisher, err = stream.NewKafkaPublisher(stream.KafkaConfig{
+			Brokers:  strings.Split(brokers, ","),
+			Topic:    topic,
+			Username: getEnv("RESULT_STREAM_KAFKA_USERNAME", ""),
+			Password: getEnv("RESULT_STREAM_KAFKA_PASSWORD", ""),
+			TLS:      useTLS,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to the result stream brokers: %w", err)
+		}
+	case natsURL != "":
+		if publisher, err = stream.NewNATSPublisher(stream.NATSConfig{URL: natsURL, Subject: topic}); err != nil {
+			return nil, fmt.Errorf("failed to connect to the result stream NATS server: %w", err)
+		}
+	default:
+		return nil, nil
+	}
+	return stream.NewExporter(publisher), nil
+}
+
+// runMigrate implements the migrate command: up (the default) applies all pending migrations,
+// down reverts the given number of migrations (1 by default), goto moves to a specific version and
+// status lists the migrations and when they were applied
+func runMigrate(repo *repository.Repository, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	var target int
+	switch command {
+	case "up":
+		target = repository.LatestSchemaVersion()
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		current, err := repo.SchemaVersion(context.Background())
+		if err != nil {
+			return err
+		}
+		target = max(current-steps, 0)
+	case "goto":
+		if len(args) < 2 {
+			return errors.New("usage: migrate goto <version>")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		target = version
+	case "status":
+		statuses, err := repo.MigrationStatuses(context.Background())
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-40s %s\n", status.Version, status.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, must be up, down, goto or status", command)
+	}
+
+	ran, err := repo.Migrate(context.Background(), target)
+	for _, m := range ran {
+		if m.Version > target {
+			log.Printf("Reverted migration %d (%s)", m.Version, m.Name)
+		} else {
+			log.Printf("Applied migration %d (%s)", m.Version, m.Name)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Database schema is at version %d", target)
+	return nil
+}
+
+// configureSecrets sets up the key encryption keys of secrets.Default from the environment
+func configureSecrets() error {
+	var current secrets.KeyEncrypter
+	switch kms := getEnv("SECRETS_KMS", ""); kms {
+	case "":
+		if value := getEnv("SECRETS_KEY", ""); value != "" {
+			key, err := secrets.ParseLocalKey(value)
+			if err != nil {
+				return fmt.Errorf("SECRETS_KEY: %w", err)
+			}
+			current = key
+		}
+	case "vault-transit":
+		if secrets.Vault == nil {
+			return errors.New("SECRETS_KMS=vault-transit requires VAULT_ADDR and VAULT_TOKEN")
+		}
+		key, err := secrets.NewVaultTransitKey(secrets.Vault, getEnv("SECRETS_VAULT_TRANSIT_MOUNT", "transit"),
+			getEnv("SECRETS_VAULT_TRANSIT_KEY", "service-weaver"))
+		if err != nil {
+			return fmt.Errorf("SECRETS_KMS: %w", err)
+		}
+		current = key
+	default:
+		return fmt.Errorf("unknown SECRETS_KMS %q, must be empty or vault-transit", kms)
+	}
+
+	var previous []secrets.KeyEncrypter
+	for _, value := range strings.FieldsFunc(getEnv("SECRETS_PREVIOUS_KEYS", ""), func(r rune) bool { return r == ',' }) {
+		key, err := secrets.ParseLocalKey(value)
+		if err != nil {
+			return fmt.Errorf("SECRETS_PREVIOUS_KEYS: %w", err)
+		}
+		previous = append(previous, key)
+	}
+
+	if current == nil {
+		log.Println("Secrets encryption is not configured; services can only store Vault references as credentials until SECRETS_KEY is set")
+	}
+	secrets.Default.Configure(current, previous...)
+	return nil
 }